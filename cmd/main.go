@@ -9,9 +9,13 @@ import (
 	"time"
 
 	"trusioo_api/config"
+	"trusioo_api/internal/account"
+	"trusioo_api/internal/apikeys"
 	"trusioo_api/internal/router"
 	"trusioo_api/pkg/database"
 	"trusioo_api/pkg/logger"
+	"trusioo_api/pkg/mailer"
+	"trusioo_api/pkg/r2storage"
 	"trusioo_api/pkg/redis"
 )
 
@@ -38,6 +42,34 @@ func main() {
 	}
 	defer redis.CloseRedis()
 
+	// 启动邮件发件箱投递
+	mailConfig := mailer.NewConfigFromApp(config.AppConfig)
+	mailSender, err := mailer.NewSender(mailConfig)
+	if err != nil {
+		logger.Fatalf("Failed to initialize mail sender: %v", err)
+	}
+	mailDispatcher := mailer.NewDispatcher(mailer.NewOutbox(database.DB), mailSender, mailConfig)
+	mailDispatcher.Start()
+	defer mailDispatcher.Stop()
+	logger.Infof("Mail dispatcher started (driver: %s)", mailConfig.Driver)
+
+	// 启动账户注销清理任务
+	accountConfig := account.NewConfigFromApp(config.AppConfig)
+	accountPurger := account.NewPurger(account.NewRepository(database.DB), r2storage.NewClientFromApp(config.AppConfig), accountConfig)
+	accountPurger.Start()
+	defer accountPurger.Stop()
+	logger.Infof("Account purger started (grace period: %s)", accountConfig.DeletionGrace)
+
+	// 加密旧版本以明文保存的API密钥签名密钥，失败时不启动以免签名校验读取到明文
+	apiKeyService := apikeys.NewService(apikeys.NewRepository(database.DB), apikeys.NewConfigFromApp(config.AppConfig))
+	encrypted, err := apiKeyService.EncryptSigningSecrets(context.Background())
+	if err != nil {
+		logger.Fatalf("Failed to encrypt api key signing secrets: %v", err)
+	}
+	if encrypted > 0 {
+		logger.Infof("Encrypted %d api key signing secrets", encrypted)
+	}
+
 	// 设置路由
 	r := router.SetupRouter()
 
@@ -21,6 +21,25 @@ type Config struct {
 	ThirdParty ThirdPartyConfig
 	R2Storage R2StorageConfig
 	Performance PerformanceConfig
+	Verification VerificationConfig
+	Mail     MailConfig
+	SMS      SMSConfig
+	LoginRisk LoginRiskConfig
+	TrustedDevice TrustedDeviceConfig
+	Account  AccountConfig
+	AdminInvite AdminInviteConfig
+	Impersonation ImpersonationConfig
+	APIKey   APIKeyConfig
+	KYC      KYCConfig
+	Feature  FeatureConfig
+	Audit    AuditConfig
+	StepUp   StepUpConfig
+	LoginLockout LoginLockoutConfig
+	Challenge ChallengeConfig
+	PasswordPolicy PasswordPolicyConfig
+	SessionLimit SessionLimitConfig
+	AdminCookie  AdminCookieConfig
+	Referral     ReferralConfig
 }
 
 type DatabaseConfig struct {
@@ -56,8 +75,9 @@ type JWTConfig struct {
 }
 
 type ServerConfig struct {
-	Port string
-	Env  string
+	Port   string
+	Env    string
+	AppKey string // 应用密钥，加密数据库中需要读取原文的敏感字段，为空时使用 JWT_SECRET
 }
 
 type CORSConfig struct {
@@ -144,6 +164,147 @@ type PerformanceConfig struct {
 	MetricsInterval      int
 }
 
+type VerificationConfig struct {
+	CodeExpire    int // 验证码有效期（秒）
+	SendCooldown  int // 同一目标同一类型的发送冷却时间（秒）
+	MaxAttempts   int // 锁定前允许的最大错误次数
+	BlockDuration int // 错误次数计数窗口/锁定时长（秒）
+}
+
+type MailConfig struct {
+	Driver        string // smtp | file | memory
+	SMTPHost      string
+	SMTPPort      string
+	SMTPUsername  string
+	SMTPPassword  string
+	From          string
+	FromName      string
+	FileDir       string // file 驱动的输出目录
+	DefaultLocale string
+	// 发件箱投递配置
+	OutboxPollInterval int // 秒
+	OutboxBatchSize    int
+	OutboxMaxAttempts  int
+}
+
+type SMSConfig struct {
+	Driver             string // log | memory
+	DefaultCountryCode string // 未带国家码的号码使用的默认国家码
+	AllowedCountries   string // 允许发送的国家码，逗号分隔，为空表示不限制
+	HourlyLimit        int    // 每个号码每小时最多发送条数
+	DailyLimit         int    // 每个号码每天最多发送条数
+}
+
+type LoginRiskConfig struct {
+	Enabled           bool
+	ChallengeScore    int // 达到此分数需要额外验证
+	BlockScore        int // 达到此分数拒绝登录并邮件通知用户
+	MaxTravelSpeedKmh int // 两次登录间超过此速度视为不可能的移动
+	HistorySize       int // 参与对比的历史成功登录条数
+}
+
+type TrustedDeviceConfig struct {
+	TTLDays int // 设备信任有效期（天）
+}
+
+type AccountConfig struct {
+	DeletionGraceDays     int // 注销冷静期（天），期间可撤销
+	ExportLinkTTLMinutes  int // 数据导出下载链接有效期（分钟）
+	ExportCooldownMinutes int // 两次数据导出的最小间隔（分钟）
+	PurgeIntervalMinutes  int // 注销清理任务的轮询间隔（分钟）
+}
+
+type AdminInviteConfig struct {
+	TTLHours int // 管理员邀请链接有效期（小时）
+}
+
+type ImpersonationConfig struct {
+	TTLMinutes int // 管理员代登录令牌有效期（分钟）
+}
+
+type APIKeyConfig struct {
+	RateLimitPerMinute      int // API密钥默认每分钟请求数，用户只能调低，管理员可单独设置
+	MaxPerUser              int // 每个用户可同时持有的有效密钥数
+	SignatureMaxSkewSeconds int // 签名请求的时间戳与服务器时间允许的最大偏差（秒）
+}
+
+type KYCConfig struct {
+	DocumentURLTTLMinutes int // 审核时证件照片限时链接的有效期（分钟）
+}
+
+type FeatureConfig struct {
+	CacheTTLSeconds int // 功能开关在 Redis 中的缓存时间（秒），管理员修改后立即失效
+}
+
+type AuditConfig struct {
+	ExportMaxRows int // 审计记录单次导出的最大条数
+}
+
+type StepUpConfig struct {
+	TokenTTLMinutes int // 重新验证身份后签发的提升令牌有效期（分钟）
+	MaxAgeMinutes   int // 敏感操作要求的最近一次验证身份距今的最长时间（分钟）
+}
+
+type LoginLockoutConfig struct {
+	Enabled             bool
+	WindowMinutes       int // 失败次数的统计窗口（分钟）
+	FreeAttempts        int // 不需要等待的失败次数，超过后等待时间按次数指数增长
+	BaseDelaySeconds    int // 第一次需要等待的时间（秒）
+	MaxDelaySeconds     int // 等待时间上限（秒）
+	AccountThreshold    int // 同一账户失败达到此次数后锁定账户并邮件通知
+	IPThreshold         int // 同一IP失败达到此次数后锁定该IP
+	LockDurationMinutes int // 锁定时长（分钟），同时是解锁链接的有效期
+}
+
+type ChallengeConfig struct {
+	Enabled          bool
+	Secret           string // 挑战签名密钥，为空时使用 JWT_SECRET
+	TTLSeconds       int    // 挑战有效期（秒）
+	WindowMinutes    int    // 请求与失败次数的统计窗口（分钟）
+	IPThreshold      int    // 同一IP达到此分数后要求完成挑战
+	SubnetThreshold  int    // 同一网段（IPv4 /24、IPv6 /64）达到此分数后要求完成挑战
+	BaseDifficulty   int    // 工作量证明的起始难度（前导零比特数）
+	MaxDifficulty    int    // 工作量证明的最高难度
+	CaptchaVerifyURL string // 兼容 reCAPTCHA / hCaptcha / Turnstile siteverify 接口的校验地址，为空时只支持工作量证明
+	CaptchaSecret    string
+	CaptchaSiteKey   string
+}
+
+type PasswordPolicyConfig struct {
+	MinLength        int
+	MaxLength        int // bcrypt 只使用前 72 字节
+	RequireUppercase bool
+	RequireLowercase bool
+	RequireDigit     bool
+	RequireSymbol    bool
+	ForbidEmail      bool   // 不允许包含邮箱地址或邮箱用户名
+	HistorySize      int    // 不允许重复使用最近几次的密码，0 表示不检查
+	BreachedListPath string // 泄露密码列表：SHA-1 前缀目录（k-anonymity range 文件）或 SHA-1 哈希列表文件，为空时不检查
+}
+
+type SessionLimitConfig struct {
+	Enabled          bool
+	MaxUserSessions  int    // 每个用户同时有效的刷新令牌数量，0 表示不限制
+	MaxAdminSessions int    // 每个管理员同时有效的刷新令牌数量，0 表示不限制
+	Policy           string // 超过上限时的处理：evict_oldest 踢出最早的会话，reject 拒绝新登录
+}
+
+// AdminCookieConfig 管理后台网页使用 Cookie 保存令牌时的 Cookie 属性
+type AdminCookieConfig struct {
+	Domain   string
+	Secure   bool
+	SameSite string // strict、lax 或 none
+}
+
+type ReferralConfig struct {
+	QualifyingAction      string // 被邀请人完成该操作后发放奖励：kyc_approved 或 email_verified
+	ReferrerReward        int    // 邀请人获得的奖励数量，0 表示不发放
+	RefereeReward         int    // 被邀请人获得的奖励数量，0 表示不发放
+	RewardUnit            string // 奖励单位，例如 points
+	MaxRewardsPerReferrer int    // 每个邀请人最多获得奖励的次数，0 表示不限制
+	ClusterThreshold      int    // 同一IP或设备注册的被邀请人达到该数量时标记为可疑，0 表示不检查
+}
+
 var AppConfig *Config
 
 func LoadConfig() error {
@@ -184,8 +345,9 @@ func LoadConfig() error {
 			RefreshExpire: getEnvAsInt("JWT_REFRESH_EXPIRE", 604800),
 		},
 		Server: ServerConfig{
-			Port: getEnv("PORT", "8080"),
-			Env:  getEnv("ENV", "development"),
+			Port:   getEnv("PORT", "8080"),
+			Env:    getEnv("ENV", "development"),
+			AppKey: getEnv("APP_KEY", ""),
 		},
 		CORS: CORSConfig{
 			Origins:  strings.Split(getEnv("CORS_ORIGINS", "http://localhost:3000"), ","),
@@ -263,6 +425,126 @@ func LoadConfig() error {
 			EnableMetrics:   getEnvAsBool("PERF_ENABLE_METRICS", true),
 			MetricsInterval: getEnvAsInt("PERF_METRICS_INTERVAL", 60),
 		},
+		Verification: VerificationConfig{
+			CodeExpire:    getEnvAsInt("VERIFICATION_CODE_EXPIRE", 600),    // 10分钟
+			SendCooldown:  getEnvAsInt("VERIFICATION_SEND_COOLDOWN", 60),   // 1分钟
+			MaxAttempts:   getEnvAsInt("VERIFICATION_MAX_ATTEMPTS", 5),
+			BlockDuration: getEnvAsInt("VERIFICATION_BLOCK_DURATION", 900), // 15分钟
+		},
+		Mail: MailConfig{
+			Driver:             getEnv("MAIL_DRIVER", "file"),
+			SMTPHost:           getEnv("SMTP_HOST", ""),
+			SMTPPort:           getEnv("SMTP_PORT", "587"),
+			SMTPUsername:       getEnv("SMTP_USERNAME", ""),
+			SMTPPassword:       getEnv("SMTP_PASSWORD", ""),
+			From:               getEnv("SMTP_FROM", "noreply@trusioo.com"),
+			FromName:           getEnv("SMTP_FROM_NAME", "Trusioo"),
+			FileDir:            getEnv("MAIL_FILE_DIR", "tmp/mail"),
+			DefaultLocale:      getEnv("MAIL_DEFAULT_LOCALE", "en"),
+			OutboxPollInterval: getEnvAsInt("MAIL_OUTBOX_POLL_INTERVAL", 5),
+			OutboxBatchSize:    getEnvAsInt("MAIL_OUTBOX_BATCH_SIZE", 20),
+			OutboxMaxAttempts:  getEnvAsInt("MAIL_OUTBOX_MAX_ATTEMPTS", 8),
+		},
+		SMS: SMSConfig{
+			Driver:             getEnv("SMS_DRIVER", "log"),
+			DefaultCountryCode: getEnv("SMS_DEFAULT_COUNTRY_CODE", "86"),
+			AllowedCountries:   getEnv("SMS_ALLOWED_COUNTRIES", ""),
+			HourlyLimit:        getEnvAsInt("SMS_HOURLY_LIMIT", 5),
+			DailyLimit:         getEnvAsInt("SMS_DAILY_LIMIT", 10),
+		},
+		LoginRisk: LoginRiskConfig{
+			Enabled:           getEnvAsBool("LOGIN_RISK_ENABLED", true),
+			ChallengeScore:    getEnvAsInt("LOGIN_RISK_CHALLENGE_SCORE", 40),
+			BlockScore:        getEnvAsInt("LOGIN_RISK_BLOCK_SCORE", 80),
+			MaxTravelSpeedKmh: getEnvAsInt("LOGIN_RISK_MAX_TRAVEL_SPEED", 900),
+			HistorySize:       getEnvAsInt("LOGIN_RISK_HISTORY_SIZE", 20),
+		},
+		TrustedDevice: TrustedDeviceConfig{
+			TTLDays: getEnvAsInt("TRUSTED_DEVICE_TTL_DAYS", 30),
+		},
+		Account: AccountConfig{
+			DeletionGraceDays:     getEnvAsInt("ACCOUNT_DELETION_GRACE_DAYS", 30),
+			ExportLinkTTLMinutes:  getEnvAsInt("ACCOUNT_EXPORT_LINK_TTL_MINUTES", 60),
+			ExportCooldownMinutes: getEnvAsInt("ACCOUNT_EXPORT_COOLDOWN_MINUTES", 60),
+			PurgeIntervalMinutes:  getEnvAsInt("ACCOUNT_PURGE_INTERVAL_MINUTES", 60),
+		},
+		AdminInvite: AdminInviteConfig{
+			TTLHours: getEnvAsInt("ADMIN_INVITE_TTL_HOURS", 72),
+		},
+		Impersonation: ImpersonationConfig{
+			TTLMinutes: getEnvAsInt("IMPERSONATION_TTL_MINUTES", 15),
+		},
+		APIKey: APIKeyConfig{
+			RateLimitPerMinute:      getEnvAsInt("API_KEY_RATE_LIMIT_PER_MINUTE", 60),
+			MaxPerUser:              getEnvAsInt("API_KEY_MAX_PER_USER", 10),
+			SignatureMaxSkewSeconds: getEnvAsInt("API_SIGNATURE_MAX_SKEW_SECONDS", 300),
+		},
+		KYC: KYCConfig{
+			DocumentURLTTLMinutes: getEnvAsInt("KYC_DOCUMENT_URL_TTL_MINUTES", 10),
+		},
+		Feature: FeatureConfig{
+			CacheTTLSeconds: getEnvAsInt("FEATURE_FLAG_CACHE_TTL_SECONDS", 60),
+		},
+		Audit: AuditConfig{
+			ExportMaxRows: getEnvAsInt("AUDIT_EXPORT_MAX_ROWS", 10000),
+		},
+		StepUp: StepUpConfig{
+			TokenTTLMinutes: getEnvAsInt("STEP_UP_TOKEN_TTL_MINUTES", 10),
+			MaxAgeMinutes:   getEnvAsInt("STEP_UP_MAX_AGE_MINUTES", 5),
+		},
+		LoginLockout: LoginLockoutConfig{
+			Enabled:             getEnvAsBool("LOGIN_LOCKOUT_ENABLED", true),
+			WindowMinutes:       getEnvAsInt("LOGIN_LOCKOUT_WINDOW_MINUTES", 15),
+			FreeAttempts:        getEnvAsInt("LOGIN_LOCKOUT_FREE_ATTEMPTS", 3),
+			BaseDelaySeconds:    getEnvAsInt("LOGIN_LOCKOUT_BASE_DELAY_SECONDS", 2),
+			MaxDelaySeconds:     getEnvAsInt("LOGIN_LOCKOUT_MAX_DELAY_SECONDS", 300),
+			AccountThreshold:    getEnvAsInt("LOGIN_LOCKOUT_ACCOUNT_THRESHOLD", 10),
+			IPThreshold:         getEnvAsInt("LOGIN_LOCKOUT_IP_THRESHOLD", 50),
+			LockDurationMinutes: getEnvAsInt("LOGIN_LOCKOUT_DURATION_MINUTES", 30),
+		},
+		Challenge: ChallengeConfig{
+			Enabled:          getEnvAsBool("CHALLENGE_ENABLED", true),
+			Secret:           getEnv("CHALLENGE_SECRET", ""),
+			TTLSeconds:       getEnvAsInt("CHALLENGE_TTL_SECONDS", 120),
+			WindowMinutes:    getEnvAsInt("CHALLENGE_WINDOW_MINUTES", 10),
+			IPThreshold:      getEnvAsInt("CHALLENGE_IP_THRESHOLD", 10),
+			SubnetThreshold:  getEnvAsInt("CHALLENGE_SUBNET_THRESHOLD", 40),
+			BaseDifficulty:   getEnvAsInt("CHALLENGE_BASE_DIFFICULTY", 16),
+			MaxDifficulty:    getEnvAsInt("CHALLENGE_MAX_DIFFICULTY", 24),
+			CaptchaVerifyURL: getEnv("CHALLENGE_CAPTCHA_VERIFY_URL", ""),
+			CaptchaSecret:    getEnv("CHALLENGE_CAPTCHA_SECRET", ""),
+			CaptchaSiteKey:   getEnv("CHALLENGE_CAPTCHA_SITE_KEY", ""),
+		},
+		PasswordPolicy: PasswordPolicyConfig{
+			MinLength:        getEnvAsInt("PASSWORD_MIN_LENGTH", 8),
+			MaxLength:        getEnvAsInt("PASSWORD_MAX_LENGTH", 72),
+			RequireUppercase: getEnvAsBool("PASSWORD_REQUIRE_UPPERCASE", false),
+			RequireLowercase: getEnvAsBool("PASSWORD_REQUIRE_LOWERCASE", true),
+			RequireDigit:     getEnvAsBool("PASSWORD_REQUIRE_DIGIT", true),
+			RequireSymbol:    getEnvAsBool("PASSWORD_REQUIRE_SYMBOL", false),
+			ForbidEmail:      getEnvAsBool("PASSWORD_FORBID_EMAIL", true),
+			HistorySize:      getEnvAsInt("PASSWORD_HISTORY_SIZE", 5),
+			BreachedListPath: getEnv("PASSWORD_BREACHED_LIST_PATH", ""),
+		},
+		SessionLimit: SessionLimitConfig{
+			Enabled:          getEnvAsBool("SESSION_LIMIT_ENABLED", true),
+			MaxUserSessions:  getEnvAsInt("SESSION_LIMIT_MAX_USER_SESSIONS", 10),
+			MaxAdminSessions: getEnvAsInt("SESSION_LIMIT_MAX_ADMIN_SESSIONS", 5),
+			Policy:           getEnv("SESSION_LIMIT_POLICY", "evict_oldest"),
+		},
+		AdminCookie: AdminCookieConfig{
+			Domain:   getEnv("ADMIN_COOKIE_DOMAIN", ""),
+			Secure:   getEnvAsBool("ADMIN_COOKIE_SECURE", true),
+			SameSite: getEnv("ADMIN_COOKIE_SAMESITE", "strict"),
+		},
+		Referral: ReferralConfig{
+			QualifyingAction:      getEnv("REFERRAL_QUALIFYING_ACTION", "kyc_approved"),
+			ReferrerReward:        getEnvAsInt("REFERRAL_REFERRER_REWARD", 100),
+			RefereeReward:         getEnvAsInt("REFERRAL_REFEREE_REWARD", 50),
+			RewardUnit:            getEnv("REFERRAL_REWARD_UNIT", "points"),
+			MaxRewardsPerReferrer: getEnvAsInt("REFERRAL_MAX_REWARDS_PER_REFERRER", 50),
+			ClusterThreshold:      getEnvAsInt("REFERRAL_CLUSTER_THRESHOLD", 3),
+		},
 	}
 
 	return nil
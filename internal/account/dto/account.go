@@ -0,0 +1,23 @@
+package dto
+
+import "time"
+
+// ExportResponse 数据导出响应
+type ExportResponse struct {
+	DownloadURL string    `json:"download_url"`
+	Size        int64     `json:"size"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// DeleteAccountRequest 注销账户请求
+type DeleteAccountRequest struct {
+	Password string `json:"password,omitempty"` // 已设置密码的账户必填
+	Reason   string `json:"reason,omitempty" binding:"max=500"`
+}
+
+// DeletionResponse 注销请求状态
+type DeletionResponse struct {
+	Message    string    `json:"message"`
+	Status     string    `json:"status"`
+	PurgeAfter time.Time `json:"purge_after"`
+}
@@ -0,0 +1,45 @@
+package entities
+
+import "time"
+
+// 注销请求状态
+const (
+	DeletionStatusPending    = "pending"    // 冷静期内，可撤销
+	DeletionStatusProcessing = "processing" // 清理任务执行中
+	DeletionStatusCancelled  = "cancelled"
+	DeletionStatusCompleted  = "completed"
+)
+
+// DeletionRequest 账户注销请求
+type DeletionRequest struct {
+	ID            int64      `json:"id" db:"id"`
+	UserID        int64      `json:"user_id" db:"user_id"`
+	Status        string     `json:"status" db:"status"`
+	Reason        string     `json:"reason" db:"reason"`
+	PurgeAfter    time.Time  `json:"purge_after" db:"purge_after"`
+	NextAttemptAt time.Time  `json:"-" db:"next_attempt_at"`
+	Attempts      int        `json:"-" db:"attempts"`
+	LastError     string     `json:"-" db:"last_error"`
+	CancelledAt   *time.Time `json:"cancelled_at,omitempty" db:"cancelled_at"`
+	CompletedAt   *time.Time `json:"completed_at,omitempty" db:"completed_at"`
+	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// DataExport 个人数据导出记录
+type DataExport struct {
+	ID        int64      `json:"id" db:"id"`
+	UserID    int64      `json:"user_id" db:"user_id"`
+	Bucket    string     `json:"-" db:"bucket"`
+	ObjectKey string     `json:"-" db:"object_key"`
+	Size      int64      `json:"size" db:"size"`
+	ExpiresAt time.Time  `json:"expires_at" db:"expires_at"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty" db:"deleted_at"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+}
+
+// StoredObject 需要在注销时删除的存储对象
+type StoredObject struct {
+	Bucket string `db:"bucket"`
+	Key    string `db:"key"`
+}
@@ -0,0 +1,161 @@
+package account
+
+import (
+	"io"
+
+	"trusioo_api/internal/account/dto"
+	"trusioo_api/internal/common"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// Export 导出个人数据
+// @Summary 导出个人数据
+// @Description 将资料、登录记录、图片和KYC申请元数据打包为ZIP保存到私有存储，返回限时下载链接
+// @Tags 账户
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} common.Response{data=dto.ExportResponse} "导出成功"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 429 {object} common.Response "导出过于频繁"
+// @Failure 500 {object} common.Response "服务器错误"
+// @Router /api/v1/account/export [post]
+func (h *Handler) Export(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		common.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	resp, err := h.service.Export(c.Request.Context(), userID.(int64))
+	if err != nil {
+		switch err {
+		case common.ErrUserNotFound:
+			common.NotFound(c, "User not found")
+		case common.ErrExportTooFrequent:
+			common.TooManyRequests(c, "Data export requested too frequently, please try again later")
+		default:
+			common.ServerError(c, err)
+		}
+		return
+	}
+
+	common.Success(c, resp)
+}
+
+// DeleteAccount 注销账户
+// @Summary 注销账户
+// @Description 提交注销申请，冷静期结束后匿名化账户、删除存储的文件并使所有令牌失效；冷静期内可撤销
+// @Tags 账户
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body dto.DeleteAccountRequest false "注销请求参数"
+// @Success 200 {object} common.Response{data=dto.DeletionResponse} "申请成功"
+// @Failure 400 {object} common.Response "参数错误或密码错误"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 500 {object} common.Response "服务器错误"
+// @Router /api/v1/account [delete]
+func (h *Handler) DeleteAccount(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		common.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	// 未设置密码的账户可以不带请求体
+	var req dto.DeleteAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil && err != io.EOF {
+		common.ValidationError(c, err.Error())
+		return
+	}
+
+	resp, err := h.service.RequestDeletion(c.Request.Context(), userID.(int64), &req)
+	if err != nil {
+		switch err {
+		case common.ErrUserNotFound:
+			common.NotFound(c, "User not found")
+		case common.ErrInvalidCredentials:
+			common.ValidationError(c, "Current password is incorrect")
+		default:
+			common.ServerError(c, err)
+		}
+		return
+	}
+
+	common.Success(c, resp)
+}
+
+// GetDeletion 查询注销状态
+// @Summary 查询注销状态
+// @Description 查询进行中的注销申请及计划执行时间
+// @Tags 账户
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} common.Response{data=dto.DeletionResponse} "获取成功"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 404 {object} common.Response "没有进行中的注销申请"
+// @Failure 500 {object} common.Response "服务器错误"
+// @Router /api/v1/account/deletion [get]
+func (h *Handler) GetDeletion(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		common.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	resp, err := h.service.GetDeletion(c.Request.Context(), userID.(int64))
+	if err != nil {
+		switch err {
+		case common.ErrNotFound:
+			common.NotFound(c, "No pending account deletion")
+		default:
+			common.ServerError(c, err)
+		}
+		return
+	}
+
+	common.Success(c, resp)
+}
+
+// CancelDeletion 撤销注销
+// @Summary 撤销注销
+// @Description 冷静期内撤销注销申请
+// @Tags 账户
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} common.Response "撤销成功"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 404 {object} common.Response "没有可撤销的注销申请"
+// @Failure 500 {object} common.Response "服务器错误"
+// @Router /api/v1/account/deletion/cancel [post]
+func (h *Handler) CancelDeletion(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		common.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	if err := h.service.CancelDeletion(c.Request.Context(), userID.(int64)); err != nil {
+		switch err {
+		case common.ErrNotFound:
+			common.NotFound(c, "No pending account deletion to cancel")
+		default:
+			common.ServerError(c, err)
+		}
+		return
+	}
+
+	common.SuccessWithMessage(c, "Account deletion cancelled", nil)
+}
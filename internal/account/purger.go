@@ -0,0 +1,124 @@
+package account
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"trusioo_api/pkg/logger"
+)
+
+// Purger 后台执行到期的账户注销并清理过期的数据导出文件
+type Purger struct {
+	repo    Repository
+	storage ObjectStorage
+	cfg     *Config
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewPurger 创建清理任务，cfg 为空时使用默认配置
+func NewPurger(repo Repository, storage ObjectStorage, cfg *Config) *Purger {
+	if cfg == nil {
+		cfg = DefaultConfig()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Purger{
+		repo:    repo,
+		storage: storage,
+		cfg:     cfg,
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+}
+
+// Start 启动后台清理
+func (p *Purger) Start() {
+	p.wg.Add(1)
+	go p.run()
+}
+
+// Stop 停止后台清理并等待当前批次完成
+func (p *Purger) Stop() {
+	p.cancel()
+	p.wg.Wait()
+}
+
+func (p *Purger) run() {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(p.cfg.PurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := p.PurgeOnce(p.ctx); err != nil {
+			logger.WithError(err).Warn("Account purge failed")
+		}
+		if err := p.CleanupExports(p.ctx); err != nil {
+			logger.WithError(err).Warn("Data export cleanup failed")
+		}
+
+		select {
+		case <-p.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// PurgeOnce 执行一批到期的注销请求，返回完成的数量
+// 先删除存储对象再匿名化数据库记录；对象删除失败时保留请求，租约到期后重试
+func (p *Purger) PurgeOnce(ctx context.Context) (int, error) {
+	requests, err := p.repo.ClaimDueDeletions(ctx, p.cfg.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, req := range requests {
+		if err := p.deleteObjects(ctx, req.UserID); err != nil {
+			p.repo.MarkDeletionRetry(ctx, req.ID, err.Error())
+			logger.WithError(err).Warnf("Failed to delete stored objects for user %d", req.UserID)
+			continue
+		}
+		if err := p.repo.PurgeUser(ctx, req); err != nil {
+			p.repo.MarkDeletionRetry(ctx, req.ID, err.Error())
+			logger.WithError(err).Warnf("Failed to anonymize user %d", req.UserID)
+			continue
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+func (p *Purger) deleteObjects(ctx context.Context, userID int64) error {
+	objects, err := p.repo.ListStoredObjects(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, obj := range objects {
+		if err := p.storage.DeleteFile(ctx, obj.Bucket, obj.Key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CleanupExports 删除下载链接已过期的导出文件
+func (p *Purger) CleanupExports(ctx context.Context) error {
+	exports, err := p.repo.ListExpiredExports(ctx, p.cfg.BatchSize)
+	if err != nil {
+		return err
+	}
+	for _, export := range exports {
+		if err := p.storage.DeleteFile(ctx, export.Bucket, export.ObjectKey); err != nil {
+			return err
+		}
+		if err := p.repo.MarkExportDeleted(ctx, export.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
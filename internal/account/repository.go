@@ -0,0 +1,274 @@
+package account
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"trusioo_api/internal/account/entities"
+	userEntities "trusioo_api/internal/auth/user_auth/entities"
+	imageEntities "trusioo_api/internal/images/entities"
+	kycEntities "trusioo_api/internal/kyc/entities"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// claimLease 清理任务领取注销请求后的租约时长，进程崩溃时请求会在租约到期后被重新领取
+const claimLease = 15 * time.Minute
+
+// Repository 账户数据请求的数据访问接口
+type Repository interface {
+	// 数据导出
+	GetUser(ctx context.Context, userID int64) (*userEntities.User, error)
+	ListLoginSessions(ctx context.Context, userID int64) ([]*userEntities.LoginSession, error)
+	ListImages(ctx context.Context, userID int64) ([]*imageEntities.Image, error)
+	ListKYCSubmissions(ctx context.Context, userID int64) ([]*kycEntities.Submission, error)
+	ListKYCDocuments(ctx context.Context, userID int64) ([]*kycEntities.Document, error)
+	GetLatestExport(ctx context.Context, userID int64) (*entities.DataExport, error)
+	CreateExport(ctx context.Context, export *entities.DataExport) error
+	ListExpiredExports(ctx context.Context, limit int) ([]*entities.DataExport, error)
+	MarkExportDeleted(ctx context.Context, id int64) error
+
+	// 账户注销
+	GetActiveDeletion(ctx context.Context, userID int64) (*entities.DeletionRequest, error)
+	CreateDeletion(ctx context.Context, req *entities.DeletionRequest) error
+	CancelDeletion(ctx context.Context, userID int64) error
+	ClaimDueDeletions(ctx context.Context, limit int) ([]*entities.DeletionRequest, error)
+	ListStoredObjects(ctx context.Context, userID int64) ([]entities.StoredObject, error)
+	MarkDeletionRetry(ctx context.Context, id int64, lastErr string) error
+	PurgeUser(ctx context.Context, req *entities.DeletionRequest) error
+}
+
+type repository struct {
+	db *sqlx.DB
+}
+
+// NewRepository 创建账户数据请求仓库
+func NewRepository(db *sqlx.DB) Repository {
+	return &repository{db: db}
+}
+
+func (r *repository) GetUser(ctx context.Context, userID int64) (*userEntities.User, error) {
+	var user userEntities.User
+	if err := r.db.GetContext(ctx, &user, "SELECT * FROM users WHERE id = $1", userID); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *repository) ListLoginSessions(ctx context.Context, userID int64) ([]*userEntities.LoginSession, error) {
+	sessions := []*userEntities.LoginSession{}
+	query := `SELECT * FROM user_login_sessions WHERE user_id = $1 ORDER BY created_at DESC`
+	if err := r.db.SelectContext(ctx, &sessions, query, userID); err != nil {
+		return nil, fmt.Errorf("failed to list login sessions: %w", err)
+	}
+	return sessions, nil
+}
+
+func (r *repository) ListImages(ctx context.Context, userID int64) ([]*imageEntities.Image, error) {
+	images := []*imageEntities.Image{}
+	query := `
+		SELECT id, user_id, file_name, original_name, key, bucket, url, public_url, content_type, size, is_public, folder, created_at, updated_at
+		FROM images
+		WHERE user_id = $1
+		ORDER BY created_at DESC`
+	if err := r.db.SelectContext(ctx, &images, query, userID); err != nil {
+		return nil, fmt.Errorf("failed to list images: %w", err)
+	}
+	return images, nil
+}
+
+func (r *repository) ListKYCSubmissions(ctx context.Context, userID int64) ([]*kycEntities.Submission, error) {
+	submissions := []*kycEntities.Submission{}
+	query := `SELECT * FROM kyc_submissions WHERE user_id = $1 ORDER BY created_at DESC`
+	if err := r.db.SelectContext(ctx, &submissions, query, userID); err != nil {
+		return nil, fmt.Errorf("failed to list kyc submissions: %w", err)
+	}
+	return submissions, nil
+}
+
+func (r *repository) ListKYCDocuments(ctx context.Context, userID int64) ([]*kycEntities.Document, error) {
+	documents := []*kycEntities.Document{}
+	query := `
+		SELECT d.* FROM kyc_documents d
+		JOIN kyc_submissions s ON s.id = d.submission_id
+		WHERE s.user_id = $1
+		ORDER BY d.created_at DESC`
+	if err := r.db.SelectContext(ctx, &documents, query, userID); err != nil {
+		return nil, fmt.Errorf("failed to list kyc documents: %w", err)
+	}
+	return documents, nil
+}
+
+// GetLatestExport 获取用户最近一次导出，没有时返回 sql.ErrNoRows
+func (r *repository) GetLatestExport(ctx context.Context, userID int64) (*entities.DataExport, error) {
+	var export entities.DataExport
+	query := `SELECT * FROM user_data_exports WHERE user_id = $1 ORDER BY created_at DESC LIMIT 1`
+	if err := r.db.GetContext(ctx, &export, query, userID); err != nil {
+		return nil, err
+	}
+	return &export, nil
+}
+
+func (r *repository) CreateExport(ctx context.Context, export *entities.DataExport) error {
+	query := `
+		INSERT INTO user_data_exports (user_id, bucket, object_key, size, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at`
+	return r.db.QueryRowContext(ctx, query,
+		export.UserID,
+		export.Bucket,
+		export.ObjectKey,
+		export.Size,
+		export.ExpiresAt,
+	).Scan(&export.ID, &export.CreatedAt)
+}
+
+func (r *repository) ListExpiredExports(ctx context.Context, limit int) ([]*entities.DataExport, error) {
+	exports := []*entities.DataExport{}
+	query := `
+		SELECT * FROM user_data_exports
+		WHERE deleted_at IS NULL AND expires_at <= NOW()
+		ORDER BY expires_at
+		LIMIT $1`
+	if err := r.db.SelectContext(ctx, &exports, query, limit); err != nil {
+		return nil, fmt.Errorf("failed to list expired exports: %w", err)
+	}
+	return exports, nil
+}
+
+func (r *repository) MarkExportDeleted(ctx context.Context, id int64) error {
+	_, err := r.db.ExecContext(ctx, "UPDATE user_data_exports SET deleted_at = NOW() WHERE id = $1", id)
+	return err
+}
+
+// GetActiveDeletion 获取待执行或执行中的注销请求，没有时返回 sql.ErrNoRows
+func (r *repository) GetActiveDeletion(ctx context.Context, userID int64) (*entities.DeletionRequest, error) {
+	var req entities.DeletionRequest
+	query := `
+		SELECT * FROM user_deletion_requests
+		WHERE user_id = $1 AND status IN ($2, $3)`
+	if err := r.db.GetContext(ctx, &req, query, userID, entities.DeletionStatusPending, entities.DeletionStatusProcessing); err != nil {
+		return nil, err
+	}
+	return &req, nil
+}
+
+func (r *repository) CreateDeletion(ctx context.Context, req *entities.DeletionRequest) error {
+	query := `
+		INSERT INTO user_deletion_requests (user_id, status, reason, purge_after, next_attempt_at)
+		VALUES ($1, $2, $3, $4, $4)
+		RETURNING id, created_at, updated_at`
+	return r.db.QueryRowContext(ctx, query,
+		req.UserID,
+		req.Status,
+		req.Reason,
+		req.PurgeAfter,
+	).Scan(&req.ID, &req.CreatedAt, &req.UpdatedAt)
+}
+
+// CancelDeletion 撤销冷静期内的注销请求，没有可撤销的请求时返回 sql.ErrNoRows
+func (r *repository) CancelDeletion(ctx context.Context, userID int64) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE user_deletion_requests
+		SET status = $2, cancelled_at = NOW(), updated_at = NOW()
+		WHERE user_id = $1 AND status = $3`,
+		userID, entities.DeletionStatusCancelled, entities.DeletionStatusPending)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// ClaimDueDeletions 领取冷静期已结束的注销请求，领取后不可再撤销
+func (r *repository) ClaimDueDeletions(ctx context.Context, limit int) ([]*entities.DeletionRequest, error) {
+	query := `
+		UPDATE user_deletion_requests
+		SET status = $2, attempts = attempts + 1, next_attempt_at = $3, updated_at = NOW()
+		WHERE id IN (
+			SELECT id FROM user_deletion_requests
+			WHERE (status = $4 AND purge_after <= NOW())
+				OR (status = $2 AND next_attempt_at <= NOW())
+			ORDER BY purge_after
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`
+
+	requests := []*entities.DeletionRequest{}
+	if err := r.db.SelectContext(ctx, &requests, query, limit,
+		entities.DeletionStatusProcessing, time.Now().Add(claimLease), entities.DeletionStatusPending); err != nil {
+		return nil, fmt.Errorf("failed to claim deletion requests: %w", err)
+	}
+	return requests, nil
+}
+
+// ListStoredObjects 列出用户在存储桶中的全部对象：上传的图片、KYC证件照片和未清理的数据导出
+func (r *repository) ListStoredObjects(ctx context.Context, userID int64) ([]entities.StoredObject, error) {
+	objects := []entities.StoredObject{}
+	query := `
+		SELECT bucket, key FROM images WHERE user_id = $1
+		UNION ALL
+		SELECT d.bucket, d.object_key AS key FROM kyc_documents d
+			JOIN kyc_submissions s ON s.id = d.submission_id WHERE s.user_id = $1
+		UNION ALL
+		SELECT bucket, object_key AS key FROM user_data_exports WHERE user_id = $1 AND deleted_at IS NULL`
+	if err := r.db.SelectContext(ctx, &objects, query, userID); err != nil {
+		return nil, fmt.Errorf("failed to list stored objects: %w", err)
+	}
+	return objects, nil
+}
+
+func (r *repository) MarkDeletionRetry(ctx context.Context, id int64, lastErr string) error {
+	_, err := r.db.ExecContext(ctx, "UPDATE user_deletion_requests SET last_error = $2, updated_at = NOW() WHERE id = $1", id, lastErr)
+	return err
+}
+
+// PurgeUser 在一个事务中匿名化用户并清理关联数据，KYC申请连同证件记录一并删除
+// 登录记录和注销请求作为安全与合规记录保留，不包含可直接识别用户的账户字段
+func (r *repository) PurgeUser(ctx context.Context, req *entities.DeletionRequest) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	statements := []struct {
+		query string
+		args  []interface{}
+	}{
+		{`UPDATE users
+			SET name = '', email = $2, password = '', phone = NULL, image_key = '', status = 'deleted',
+				email_verified = false, phone_verified = false, kyc_level = 0, updated_at = NOW()
+			WHERE id = $1`, []interface{}{req.UserID, anonymizedEmail(req.UserID)}},
+		{`UPDATE user_refresh_tokens SET is_valid = false WHERE user_id = $1`, []interface{}{req.UserID}},
+		{`DELETE FROM user_trusted_devices WHERE user_id = $1`, []interface{}{req.UserID}},
+		{`UPDATE api_keys SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL`, []interface{}{req.UserID}},
+		{`DELETE FROM images WHERE user_id = $1`, []interface{}{req.UserID}},
+		{`DELETE FROM kyc_submissions WHERE user_id = $1`, []interface{}{req.UserID}},
+		{`UPDATE user_data_exports SET deleted_at = NOW() WHERE user_id = $1 AND deleted_at IS NULL`, []interface{}{req.UserID}},
+		{`UPDATE user_deletion_requests
+			SET status = 'completed', completed_at = NOW(), last_error = '', updated_at = NOW()
+			WHERE id = $1`, []interface{}{req.ID}},
+	}
+	for _, stmt := range statements {
+		if _, err := tx.ExecContext(ctx, stmt.query, stmt.args...); err != nil {
+			return fmt.Errorf("failed to purge user %d: %w", req.UserID, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// anonymizedEmail 匿名化后的占位邮箱，保持 users.email 唯一约束
+func anonymizedEmail(userID int64) string {
+	return fmt.Sprintf("deleted-%d@deleted.invalid", userID)
+}
@@ -0,0 +1,24 @@
+package account
+
+import (
+	"trusioo_api/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterRoutes(router *gin.RouterGroup, handler *Handler) {
+	accountRoutes := router.Group("/account")
+	accountRoutes.Use(middleware.AuthMiddleware())
+	{
+		accountRoutes.GET("/deletion", handler.GetDeletion) // 查询注销状态
+	}
+
+	// 导出与注销 - 拒绝管理员代登录令牌
+	sensitive := router.Group("/account")
+	sensitive.Use(middleware.AuthMiddleware(), middleware.RejectImpersonation())
+	{
+		sensitive.POST("/export", handler.Export)                  // 导出个人数据，返回限时下载链接
+		sensitive.DELETE("", handler.DeleteAccount)                // 申请注销，冷静期后执行
+		sensitive.POST("/deletion/cancel", handler.CancelDeletion) // 冷静期内撤销注销
+	}
+}
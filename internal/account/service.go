@@ -0,0 +1,253 @@
+package account
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"trusioo_api/config"
+	"trusioo_api/internal/account/dto"
+	"trusioo_api/internal/account/entities"
+	"trusioo_api/internal/common"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ObjectStorage 对象存储接口，由 r2storage.Client 实现
+type ObjectStorage interface {
+	PutObject(ctx context.Context, bucket, key string, body []byte, contentType string) error
+	GeneratePresignedURL(ctx context.Context, bucket, key string, expiration time.Duration) (string, error)
+	DeleteFile(ctx context.Context, bucket, key string) error
+	PrivateBucket() string
+}
+
+// Config 注销与导出配置
+type Config struct {
+	DeletionGrace  time.Duration
+	ExportLinkTTL  time.Duration
+	ExportCooldown time.Duration
+	PurgeInterval  time.Duration
+	BatchSize      int
+}
+
+// DefaultConfig 默认配置
+func DefaultConfig() *Config {
+	return &Config{
+		DeletionGrace:  30 * 24 * time.Hour,
+		ExportLinkTTL:  time.Hour,
+		ExportCooldown: time.Hour,
+		PurgeInterval:  time.Hour,
+		BatchSize:      20,
+	}
+}
+
+// NewConfigFromApp 从应用配置创建注销与导出配置
+func NewConfigFromApp(appConfig *config.Config) *Config {
+	cfg := DefaultConfig()
+	if appConfig == nil {
+		return cfg
+	}
+
+	ac := appConfig.Account
+	if ac.DeletionGraceDays > 0 {
+		cfg.DeletionGrace = time.Duration(ac.DeletionGraceDays) * 24 * time.Hour
+	}
+	if ac.ExportLinkTTLMinutes > 0 {
+		cfg.ExportLinkTTL = time.Duration(ac.ExportLinkTTLMinutes) * time.Minute
+	}
+	if ac.ExportCooldownMinutes >= 0 {
+		cfg.ExportCooldown = time.Duration(ac.ExportCooldownMinutes) * time.Minute
+	}
+	if ac.PurgeIntervalMinutes > 0 {
+		cfg.PurgeInterval = time.Duration(ac.PurgeIntervalMinutes) * time.Minute
+	}
+	return cfg
+}
+
+// Service 个人数据导出与账户注销
+type Service struct {
+	repo    Repository
+	storage ObjectStorage
+	cfg     *Config
+}
+
+// NewService 创建服务，cfg 为空时使用默认配置
+func NewService(repo Repository, storage ObjectStorage, cfg *Config) *Service {
+	if cfg == nil {
+		cfg = DefaultConfig()
+	}
+	return &Service{repo: repo, storage: storage, cfg: cfg}
+}
+
+// Export 将资料、登录记录、图片和KYC申请元数据打包为ZIP写入私有存储桶，返回限时下载链接
+func (s *Service) Export(ctx context.Context, userID int64) (*dto.ExportResponse, error) {
+	latest, err := s.repo.GetLatestExport(ctx, userID)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	if latest != nil && time.Since(latest.CreatedAt) < s.cfg.ExportCooldown {
+		return nil, common.ErrExportTooFrequent
+	}
+
+	archive, err := s.buildArchive(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	export := &entities.DataExport{
+		UserID:    userID,
+		Bucket:    s.storage.PrivateBucket(),
+		ObjectKey: fmt.Sprintf("exports/%d/%s-%s.zip", userID, now.UTC().Format("20060102T150405Z"), uuid.New().String()),
+		Size:      int64(len(archive)),
+		ExpiresAt: now.Add(s.cfg.ExportLinkTTL),
+	}
+
+	if err := s.storage.PutObject(ctx, export.Bucket, export.ObjectKey, archive, "application/zip"); err != nil {
+		return nil, err
+	}
+	if err := s.repo.CreateExport(ctx, export); err != nil {
+		s.storage.DeleteFile(ctx, export.Bucket, export.ObjectKey)
+		return nil, err
+	}
+
+	url, err := s.storage.GeneratePresignedURL(ctx, export.Bucket, export.ObjectKey, s.cfg.ExportLinkTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.ExportResponse{
+		DownloadURL: url,
+		Size:        export.Size,
+		ExpiresAt:   export.ExpiresAt,
+	}, nil
+}
+
+// buildArchive 每类数据写入一个JSON文件
+func (s *Service) buildArchive(ctx context.Context, userID int64) ([]byte, error) {
+	user, err := s.repo.GetUser(ctx, userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, common.ErrUserNotFound
+		}
+		return nil, err
+	}
+	sessions, err := s.repo.ListLoginSessions(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	images, err := s.repo.ListImages(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	kycSubmissions, err := s.repo.ListKYCSubmissions(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	kycDocuments, err := s.repo.ListKYCDocuments(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []struct {
+		name string
+		data interface{}
+	}{
+		{"profile.json", user},
+		{"login_sessions.json", sessions},
+		{"images.json", images},
+		{"kyc_submissions.json", kycSubmissions},
+		{"kyc_documents.json", kycDocuments},
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, f := range files {
+		w, err := zw.Create(f.name)
+		if err != nil {
+			return nil, err
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(f.data); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// RequestDeletion 申请注销账户，冷静期结束后由 Purger 执行，重复申请返回已有请求
+func (s *Service) RequestDeletion(ctx context.Context, userID int64, req *dto.DeleteAccountRequest) (*dto.DeletionResponse, error) {
+	user, err := s.repo.GetUser(ctx, userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, common.ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	// 验证码登录自动注册、尚未设置密码的账户只能依赖登录态
+	if user.PasswordSet {
+		if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
+			return nil, common.ErrInvalidCredentials
+		}
+	}
+
+	existing, err := s.repo.GetActiveDeletion(ctx, userID)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	if existing != nil {
+		return deletionResponse("账户已在注销流程中", existing), nil
+	}
+
+	deletion := &entities.DeletionRequest{
+		UserID:     userID,
+		Status:     entities.DeletionStatusPending,
+		Reason:     strings.TrimSpace(req.Reason),
+		PurgeAfter: time.Now().Add(s.cfg.DeletionGrace),
+	}
+	if err := s.repo.CreateDeletion(ctx, deletion); err != nil {
+		return nil, err
+	}
+
+	return deletionResponse("注销申请已提交，冷静期结束前可撤销", deletion), nil
+}
+
+// GetDeletion 查询进行中的注销请求
+func (s *Service) GetDeletion(ctx context.Context, userID int64) (*dto.DeletionResponse, error) {
+	deletion, err := s.repo.GetActiveDeletion(ctx, userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, common.ErrNotFound
+		}
+		return nil, err
+	}
+	return deletionResponse("账户注销处理中", deletion), nil
+}
+
+// CancelDeletion 冷静期内撤销注销
+func (s *Service) CancelDeletion(ctx context.Context, userID int64) error {
+	err := s.repo.CancelDeletion(ctx, userID)
+	if err == sql.ErrNoRows {
+		return common.ErrNotFound
+	}
+	return err
+}
+
+func deletionResponse(message string, deletion *entities.DeletionRequest) *dto.DeletionResponse {
+	return &dto.DeletionResponse{
+		Message:    message,
+		Status:     deletion.Status,
+		PurgeAfter: deletion.PurgeAfter,
+	}
+}
@@ -0,0 +1,319 @@
+package account
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+
+	"trusioo_api/internal/account/dto"
+	"trusioo_api/internal/account/entities"
+	userEntities "trusioo_api/internal/auth/user_auth/entities"
+	"trusioo_api/internal/common"
+	imageEntities "trusioo_api/internal/images/entities"
+	kycEntities "trusioo_api/internal/kyc/entities"
+	"trusioo_api/internal/testutil"
+)
+
+func TestMain(m *testing.M) {
+	testutil.DiscardLogs()
+	os.Exit(m.Run())
+}
+
+// fakeRepository 内存版仓库
+type fakeRepository struct {
+	user      *userEntities.User
+	sessions  []*userEntities.LoginSession
+	images    []*imageEntities.Image
+	kyc       []*kycEntities.Submission
+	documents []*kycEntities.Document
+	exports   []*entities.DataExport
+	deletions []*entities.DeletionRequest
+	objects   []entities.StoredObject
+	purged    []int64
+	retries   map[int64]string
+}
+
+func (f *fakeRepository) GetUser(ctx context.Context, userID int64) (*userEntities.User, error) {
+	if f.user == nil || f.user.ID != userID {
+		return nil, sql.ErrNoRows
+	}
+	return f.user, nil
+}
+
+func (f *fakeRepository) ListLoginSessions(ctx context.Context, userID int64) ([]*userEntities.LoginSession, error) {
+	return f.sessions, nil
+}
+
+func (f *fakeRepository) ListImages(ctx context.Context, userID int64) ([]*imageEntities.Image, error) {
+	return f.images, nil
+}
+
+func (f *fakeRepository) ListKYCSubmissions(ctx context.Context, userID int64) ([]*kycEntities.Submission, error) {
+	return f.kyc, nil
+}
+
+func (f *fakeRepository) ListKYCDocuments(ctx context.Context, userID int64) ([]*kycEntities.Document, error) {
+	return f.documents, nil
+}
+
+func (f *fakeRepository) GetLatestExport(ctx context.Context, userID int64) (*entities.DataExport, error) {
+	if len(f.exports) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return f.exports[len(f.exports)-1], nil
+}
+
+func (f *fakeRepository) CreateExport(ctx context.Context, export *entities.DataExport) error {
+	export.ID = int64(len(f.exports) + 1)
+	export.CreatedAt = time.Now()
+	f.exports = append(f.exports, export)
+	return nil
+}
+
+func (f *fakeRepository) ListExpiredExports(ctx context.Context, limit int) ([]*entities.DataExport, error) {
+	var expired []*entities.DataExport
+	for _, e := range f.exports {
+		if e.DeletedAt == nil && !e.ExpiresAt.After(time.Now()) {
+			expired = append(expired, e)
+		}
+	}
+	return expired, nil
+}
+
+func (f *fakeRepository) MarkExportDeleted(ctx context.Context, id int64) error {
+	now := time.Now()
+	f.exports[id-1].DeletedAt = &now
+	return nil
+}
+
+func (f *fakeRepository) GetActiveDeletion(ctx context.Context, userID int64) (*entities.DeletionRequest, error) {
+	for _, d := range f.deletions {
+		if d.UserID == userID && (d.Status == entities.DeletionStatusPending || d.Status == entities.DeletionStatusProcessing) {
+			return d, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (f *fakeRepository) CreateDeletion(ctx context.Context, req *entities.DeletionRequest) error {
+	req.ID = int64(len(f.deletions) + 1)
+	f.deletions = append(f.deletions, req)
+	return nil
+}
+
+func (f *fakeRepository) CancelDeletion(ctx context.Context, userID int64) error {
+	for _, d := range f.deletions {
+		if d.UserID == userID && d.Status == entities.DeletionStatusPending {
+			d.Status = entities.DeletionStatusCancelled
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
+
+func (f *fakeRepository) ClaimDueDeletions(ctx context.Context, limit int) ([]*entities.DeletionRequest, error) {
+	var claimed []*entities.DeletionRequest
+	for _, d := range f.deletions {
+		if d.Status == entities.DeletionStatusPending && !d.PurgeAfter.After(time.Now()) {
+			d.Status = entities.DeletionStatusProcessing
+			claimed = append(claimed, d)
+		}
+	}
+	return claimed, nil
+}
+
+func (f *fakeRepository) ListStoredObjects(ctx context.Context, userID int64) ([]entities.StoredObject, error) {
+	return f.objects, nil
+}
+
+func (f *fakeRepository) MarkDeletionRetry(ctx context.Context, id int64, lastErr string) error {
+	if f.retries == nil {
+		f.retries = map[int64]string{}
+	}
+	f.retries[id] = lastErr
+	return nil
+}
+
+func (f *fakeRepository) PurgeUser(ctx context.Context, req *entities.DeletionRequest) error {
+	req.Status = entities.DeletionStatusCompleted
+	f.purged = append(f.purged, req.UserID)
+	return nil
+}
+
+// fakeStorage 内存版对象存储
+type fakeStorage struct {
+	objects map[string][]byte
+	deleted []string
+	failing bool
+}
+
+func newFakeStorage() *fakeStorage {
+	return &fakeStorage{objects: map[string][]byte{}}
+}
+
+func (f *fakeStorage) PutObject(ctx context.Context, bucket, key string, body []byte, contentType string) error {
+	f.objects[bucket+"/"+key] = body
+	return nil
+}
+
+func (f *fakeStorage) GeneratePresignedURL(ctx context.Context, bucket, key string, expiration time.Duration) (string, error) {
+	return "https://r2.example.com/" + bucket + "/" + key + "?signature=x", nil
+}
+
+func (f *fakeStorage) DeleteFile(ctx context.Context, bucket, key string) error {
+	if f.failing {
+		return errors.New("failed to delete file from R2: timeout")
+	}
+	f.deleted = append(f.deleted, bucket+"/"+key)
+	return nil
+}
+
+func (f *fakeStorage) PrivateBucket() string {
+	return "private"
+}
+
+func TestService_Export(t *testing.T) {
+	phone := "+8613800138000"
+	userID := 1
+	repo := &fakeRepository{
+		user:      &userEntities.User{ID: 1, Email: "test@example.com", Password: "hashed-secret", Phone: &phone},
+		sessions:  []*userEntities.LoginSession{{ID: 1, UserID: 1, IP: "1.2.3.4", Status: "success"}},
+		images:    []*imageEntities.Image{{ID: 3, UserID: &userID, Key: "avatars/me.png", Bucket: "public"}},
+		kyc:       []*kycEntities.Submission{{ID: 5, UserID: 1, Status: kycEntities.StatusApproved, FullName: "Test User", DocumentNumber: "E12345678"}},
+		documents: []*kycEntities.Document{{ID: 7, SubmissionID: 5, Type: "id_front", Bucket: "private", ObjectKey: "kyc/1/front.jpg"}},
+	}
+	storage := newFakeStorage()
+	service := NewService(repo, storage, &Config{ExportLinkTTL: time.Hour, ExportCooldown: time.Hour})
+
+	resp, err := service.Export(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Contains(t, resp.DownloadURL, "private/exports/1/")
+	assert.WithinDuration(t, time.Now().Add(time.Hour), resp.ExpiresAt, time.Minute)
+
+	// ZIP 写入私有存储桶，包含资料、登录记录、图片和KYC申请元数据
+	require.Len(t, repo.exports, 1)
+	archive := storage.objects["private/"+repo.exports[0].ObjectKey]
+	require.NotEmpty(t, archive)
+	assert.Equal(t, int64(len(archive)), resp.Size)
+
+	zr, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	require.NoError(t, err)
+	contents := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		require.NoError(t, err)
+		data, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		rc.Close()
+		contents[f.Name] = string(data)
+	}
+	assert.Contains(t, contents["profile.json"], "test@example.com")
+	assert.NotContains(t, contents["profile.json"], "hashed-secret")
+	assert.Contains(t, contents["login_sessions.json"], "1.2.3.4")
+	assert.Contains(t, contents["images.json"], "avatars/me.png")
+	assert.Contains(t, contents["kyc_submissions.json"], "E12345678")
+	assert.Contains(t, contents["kyc_documents.json"], "id_front")
+
+	// 冷静时间内不能重复导出
+	_, err = service.Export(context.Background(), 1)
+	assert.Equal(t, common.ErrExportTooFrequent, err)
+}
+
+func TestService_RequestDeletion(t *testing.T) {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.DefaultCost)
+	require.NoError(t, err)
+
+	newService := func() (*Service, *fakeRepository) {
+		repo := &fakeRepository{user: &userEntities.User{ID: 1, Password: string(hashedPassword), PasswordSet: true}}
+		return NewService(repo, newFakeStorage(), &Config{DeletionGrace: 7 * 24 * time.Hour}), repo
+	}
+
+	t.Run("密码错误", func(t *testing.T) {
+		service, repo := newService()
+		_, err := service.RequestDeletion(context.Background(), 1, &dto.DeleteAccountRequest{Password: "wrong"})
+		assert.Equal(t, common.ErrInvalidCredentials, err)
+		assert.Empty(t, repo.deletions)
+	})
+
+	t.Run("申请后冷静期内可撤销", func(t *testing.T) {
+		service, repo := newService()
+		resp, err := service.RequestDeletion(context.Background(), 1, &dto.DeleteAccountRequest{Password: "password123", Reason: " 不再使用 "})
+		require.NoError(t, err)
+		assert.Equal(t, entities.DeletionStatusPending, resp.Status)
+		assert.WithinDuration(t, time.Now().Add(7*24*time.Hour), resp.PurgeAfter, time.Minute)
+		assert.Equal(t, "不再使用", repo.deletions[0].Reason)
+
+		// 重复申请返回已有请求
+		_, err = service.RequestDeletion(context.Background(), 1, &dto.DeleteAccountRequest{Password: "password123"})
+		require.NoError(t, err)
+		assert.Len(t, repo.deletions, 1)
+
+		assert.NoError(t, service.CancelDeletion(context.Background(), 1))
+		assert.Equal(t, common.ErrNotFound, service.CancelDeletion(context.Background(), 1))
+		_, err = service.GetDeletion(context.Background(), 1)
+		assert.Equal(t, common.ErrNotFound, err)
+	})
+}
+
+func TestPurger_PurgeOnce(t *testing.T) {
+	newRepo := func() *fakeRepository {
+		return &fakeRepository{
+			deletions: []*entities.DeletionRequest{
+				{ID: 1, UserID: 1, Status: entities.DeletionStatusPending, PurgeAfter: time.Now().Add(-time.Minute)},
+				{ID: 2, UserID: 2, Status: entities.DeletionStatusPending, PurgeAfter: time.Now().Add(time.Hour)},
+			},
+			objects: []entities.StoredObject{{Bucket: "public", Key: "avatars/me.png"}, {Bucket: "private", Key: "exports/1/a.zip"}},
+		}
+	}
+
+	t.Run("冷静期结束后删除文件并匿名化", func(t *testing.T) {
+		repo := newRepo()
+		storage := newFakeStorage()
+		purger := NewPurger(repo, storage, &Config{BatchSize: 10})
+
+		purged, err := purger.PurgeOnce(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, 1, purged)
+		assert.Equal(t, []int64{1}, repo.purged)
+		assert.Equal(t, []string{"public/avatars/me.png", "private/exports/1/a.zip"}, storage.deleted)
+		assert.Equal(t, entities.DeletionStatusPending, repo.deletions[1].Status)
+	})
+
+	t.Run("文件删除失败时不匿名化并记录错误", func(t *testing.T) {
+		repo := newRepo()
+		storage := newFakeStorage()
+		storage.failing = true
+		purger := NewPurger(repo, storage, &Config{BatchSize: 10})
+
+		purged, err := purger.PurgeOnce(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, 0, purged)
+		assert.Empty(t, repo.purged)
+		assert.Contains(t, repo.retries[1], "timeout")
+		assert.Equal(t, entities.DeletionStatusProcessing, repo.deletions[0].Status)
+	})
+}
+
+func TestPurger_CleanupExports(t *testing.T) {
+	repo := &fakeRepository{exports: []*entities.DataExport{
+		{ID: 1, Bucket: "private", ObjectKey: "exports/1/old.zip", ExpiresAt: time.Now().Add(-time.Minute)},
+		{ID: 2, Bucket: "private", ObjectKey: "exports/1/new.zip", ExpiresAt: time.Now().Add(time.Hour)},
+	}}
+	storage := newFakeStorage()
+	purger := NewPurger(repo, storage, &Config{BatchSize: 10})
+
+	require.NoError(t, purger.CleanupExports(context.Background()))
+	assert.Equal(t, []string{"private/exports/1/old.zip"}, storage.deleted)
+	assert.NotNil(t, repo.exports[0].DeletedAt)
+	assert.Nil(t, repo.exports[1].DeletedAt)
+}
@@ -0,0 +1,26 @@
+package dto
+
+import (
+	"time"
+
+	"trusioo_api/internal/apikeys/entities"
+)
+
+// CreateAPIKeyRequest 创建API密钥请求
+// allowed_ips 可以是单个IP或CIDR，为空时不限制来源；rate_limit_per_minute 为空时使用默认限额
+type CreateAPIKeyRequest struct {
+	Name               string     `json:"name" binding:"required,max=100"`
+	Scopes             []string   `json:"scopes" binding:"required,min=1,dive,required"`
+	AllowedIPs         []string   `json:"allowed_ips" binding:"omitempty,max=20,dive,required"`
+	ExpiresAt          *time.Time `json:"expires_at"`
+	RateLimitPerMinute int        `json:"rate_limit_per_minute" binding:"omitempty,min=1,max=10000"`
+	RequireSignature   bool       `json:"require_signature"`
+}
+
+// CreateAPIKeyResponse 创建API密钥响应，key 和 signing_secret 只返回这一次
+// signing_secret 用于计算 X-Signature 请求签名，见 pkg/requestsign
+type CreateAPIKeyResponse struct {
+	Key           string           `json:"key"`
+	SigningSecret string           `json:"signing_secret"`
+	APIKey        *entities.APIKey `json:"api_key"`
+}
@@ -0,0 +1,52 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// APIKey 合作方与机器客户端使用的API密钥
+// 完整密钥为 tk_<prefix>_<secret>，只在创建时返回一次；数据库保存前缀和密钥的 SHA-256 哈希
+type APIKey struct {
+	ID                 int64          `json:"id" db:"id"`
+	UserID             int64          `json:"user_id" db:"user_id"`
+	Name               string         `json:"name" db:"name"`
+	Prefix             string         `json:"prefix" db:"prefix"`
+	SecretHash         string         `json:"-" db:"secret_hash"`
+	SigningSecret      string         `json:"-" db:"signing_secret"`
+	RequireSignature   bool           `json:"require_signature" db:"require_signature"`
+	Scopes             pq.StringArray `json:"scopes" db:"scopes"`
+	AllowedIPs         pq.StringArray `json:"allowed_ips" db:"allowed_ips"`
+	RateLimitPerMinute int            `json:"rate_limit_per_minute" db:"rate_limit_per_minute"`
+	ExpiresAt          *time.Time     `json:"expires_at,omitempty" db:"expires_at"`
+	LastUsedAt         *time.Time     `json:"last_used_at,omitempty" db:"last_used_at"`
+	LastUsedIP         string         `json:"last_used_ip,omitempty" db:"last_used_ip"`
+	CreatedByAdminID   *int64         `json:"created_by_admin_id,omitempty" db:"created_by_admin_id"`
+	RevokedAt          *time.Time     `json:"revoked_at,omitempty" db:"revoked_at"`
+	CreatedAt          time.Time      `json:"created_at" db:"created_at"`
+}
+
+// Active 密钥未撤销且未过期
+func (k *APIKey) Active(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
+
+// KeyOwner 密钥所属用户的账户状态
+type KeyOwner struct {
+	ID             int64      `db:"id"`
+	Email          string     `db:"email"`
+	Status         string     `db:"status"`
+	SuspendedUntil *time.Time `db:"suspended_until"`
+}
+
+// Principal API密钥校验通过后的调用方身份，由 middleware 保存到请求上下文
+type Principal struct {
+	KeyID              int64
+	UserID             int64
+	Email              string
+	Scopes             []string
+	RateLimitPerMinute int
+	SigningSecret      string // 请求签名密钥，SignatureMiddleware 使用
+	RequireSignature   bool   // 为 true 时该密钥的请求必须签名
+}
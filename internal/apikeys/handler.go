@@ -0,0 +1,253 @@
+package apikeys
+
+import (
+	"strconv"
+
+	"trusioo_api/internal/apikeys/dto"
+	"trusioo_api/internal/audit"
+	"trusioo_api/internal/common"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// ListKeys 获取我的API密钥
+// @Summary 获取我的API密钥
+// @Description 获取当前用户的全部API密钥，包括已撤销和已过期的密钥；不返回密钥本身
+// @Tags API密钥
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} common.Response{data=[]entities.APIKey} "获取成功"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 500 {object} common.Response "服务器错误"
+// @Router /api/v1/api-keys [get]
+func (h *Handler) ListKeys(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		common.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	keys, err := h.service.ListKeys(c.Request.Context(), userID.(int64))
+	if err != nil {
+		common.ServerError(c, err)
+		return
+	}
+
+	common.Success(c, keys)
+}
+
+// CreateKey 创建API密钥
+// @Summary 创建API密钥
+// @Description 创建带范围、过期时间和IP白名单的API密钥，完整密钥和签名密钥只在响应中返回一次。调用方使用 "Authorization: ApiKey <key>" 访问，可用签名密钥计算 X-Signature 请求签名
+// @Tags API密钥
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body dto.CreateAPIKeyRequest true "密钥信息"
+// @Success 200 {object} common.Response{data=dto.CreateAPIKeyResponse} "创建成功"
+// @Failure 400 {object} common.Response "参数错误或密钥数量已达上限"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "代登录令牌不能创建密钥"
+// @Failure 500 {object} common.Response "服务器错误"
+// @Router /api/v1/api-keys [post]
+func (h *Handler) CreateKey(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		common.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	var req dto.CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ValidationError(c, err.Error())
+		return
+	}
+
+	resp, err := h.service.CreateKey(c.Request.Context(), userID.(int64), nil, &req)
+	if err != nil {
+		handleKeyError(c, err)
+		return
+	}
+
+	common.Success(c, resp)
+}
+
+// RevokeKey 撤销API密钥
+// @Summary 撤销API密钥
+// @Description 撤销当前用户的API密钥，立即生效
+// @Tags API密钥
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "密钥ID"
+// @Success 200 {object} common.Response "撤销成功"
+// @Failure 400 {object} common.Response "参数错误"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "代登录令牌不能撤销密钥"
+// @Failure 404 {object} common.Response "密钥不存在"
+// @Failure 500 {object} common.Response "服务器错误"
+// @Router /api/v1/api-keys/{id} [delete]
+func (h *Handler) RevokeKey(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		common.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	keyID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		common.ValidationError(c, "Invalid API key ID")
+		return
+	}
+
+	if err := h.service.RevokeKey(c.Request.Context(), userID.(int64), keyID); err != nil {
+		handleKeyError(c, err)
+		return
+	}
+
+	common.SuccessWithMessage(c, "API key revoked", nil)
+}
+
+// AdminListKeys 获取用户的API密钥
+// @Summary 获取用户的API密钥
+// @Description 管理员查看指定用户的全部API密钥及最近使用情况
+// @Tags 管理员-用户管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "用户ID"
+// @Success 200 {object} common.Response{data=[]entities.APIKey} "获取成功"
+// @Failure 400 {object} common.Response "参数错误"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足"
+// @Failure 500 {object} common.Response "服务器错误"
+// @Router /api/v1/admin/users/{id}/api-keys [get]
+func (h *Handler) AdminListKeys(c *gin.Context) {
+	_, userID, ok := adminKeyIDs(c)
+	if !ok {
+		return
+	}
+
+	keys, err := h.service.ListKeys(c.Request.Context(), userID)
+	if err != nil {
+		common.ServerError(c, err)
+		return
+	}
+
+	common.Success(c, keys)
+}
+
+// AdminCreateKey 为用户创建API密钥
+// @Summary 为用户创建API密钥
+// @Description 管理员为合作方用户创建API密钥，可以单独设置每分钟请求限额；完整密钥只在响应中返回一次
+// @Tags 管理员-用户管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "用户ID"
+// @Param request body dto.CreateAPIKeyRequest true "密钥信息"
+// @Success 200 {object} common.Response{data=dto.CreateAPIKeyResponse} "创建成功"
+// @Failure 400 {object} common.Response "参数错误或密钥数量已达上限"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足"
+// @Failure 404 {object} common.Response "用户不存在"
+// @Failure 500 {object} common.Response "服务器错误"
+// @Router /api/v1/admin/users/{id}/api-keys [post]
+func (h *Handler) AdminCreateKey(c *gin.Context) {
+	adminID, userID, ok := adminKeyIDs(c)
+	if !ok {
+		return
+	}
+
+	var req dto.CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ValidationError(c, err.Error())
+		return
+	}
+
+	resp, err := h.service.CreateKey(audit.Context(c), userID, &adminID, &req)
+	if err != nil {
+		handleKeyError(c, err)
+		return
+	}
+
+	common.Success(c, resp)
+}
+
+// AdminRevokeKey 撤销用户的API密钥
+// @Summary 撤销用户的API密钥
+// @Description 管理员撤销指定用户的API密钥，立即生效
+// @Tags 管理员-用户管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "用户ID"
+// @Param keyId path int true "密钥ID"
+// @Success 200 {object} common.Response "撤销成功"
+// @Failure 400 {object} common.Response "参数错误"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足"
+// @Failure 404 {object} common.Response "密钥不存在"
+// @Failure 500 {object} common.Response "服务器错误"
+// @Router /api/v1/admin/users/{id}/api-keys/{keyId} [delete]
+func (h *Handler) AdminRevokeKey(c *gin.Context) {
+	adminID, userID, ok := adminKeyIDs(c)
+	if !ok {
+		return
+	}
+
+	keyID, err := strconv.ParseInt(c.Param("keyId"), 10, 64)
+	if err != nil {
+		common.ValidationError(c, "Invalid API key ID")
+		return
+	}
+
+	if err := h.service.AdminRevokeKey(audit.Context(c), adminID, userID, keyID); err != nil {
+		handleKeyError(c, err)
+		return
+	}
+
+	common.SuccessWithMessage(c, "API key revoked", nil)
+}
+
+// adminKeyIDs 获取当前管理员ID和路径中的用户ID，失败时已写入响应
+func adminKeyIDs(c *gin.Context) (int64, int64, bool) {
+	adminID, exists := c.Get("user_id")
+	if !exists {
+		common.Unauthorized(c, "Admin not authenticated")
+		return 0, 0, false
+	}
+
+	userID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		common.ValidationError(c, "Invalid user ID")
+		return 0, 0, false
+	}
+
+	return adminID.(int64), userID, true
+}
+
+// handleKeyError 密钥操作的通用错误响应
+func handleKeyError(c *gin.Context, err error) {
+	switch err {
+	case common.ErrValidation:
+		common.ValidationError(c, "Scopes must be defined, allowed IPs must be IP addresses or CIDR ranges and expiry must be in the future")
+	case common.ErrAPIKeyLimitExceeded:
+		common.ValidationError(c, "API key limit reached, revoke an existing key first")
+	case common.ErrUserNotFound:
+		common.NotFound(c, "User not found")
+	case common.ErrNotFound:
+		common.NotFound(c, "API key not found")
+	default:
+		common.ServerError(c, err)
+	}
+}
@@ -0,0 +1,150 @@
+package apikeys
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"trusioo_api/internal/apikeys/entities"
+	"trusioo_api/internal/audit"
+	auditEntities "trusioo_api/internal/audit/entities"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Repository API密钥的数据访问接口
+type Repository interface {
+	CreateKey(ctx context.Context, key *entities.APIKey, entry *auditEntities.Log) error
+	GetKeyByPrefix(ctx context.Context, prefix string) (*entities.APIKey, error)
+	ListKeys(ctx context.Context, userID int64) ([]*entities.APIKey, error)
+	CountActiveKeys(ctx context.Context, userID int64) (int, error)
+	RevokeKey(ctx context.Context, userID, keyID int64, entry *auditEntities.Log) error
+	TouchKey(ctx context.Context, keyID int64, ip string) error
+	ListPlaintextSigningSecrets(ctx context.Context) ([]*entities.APIKey, error)
+	UpdateSigningSecret(ctx context.Context, keyID int64, old, sealed string) error
+
+	GetOwner(ctx context.Context, userID int64) (*entities.KeyOwner, error)
+}
+
+type repository struct {
+	db *sqlx.DB
+}
+
+// NewRepository 创建API密钥仓库
+func NewRepository(db *sqlx.DB) Repository {
+	return &repository{db: db}
+}
+
+// CreateKey 保存密钥，entry 不为空时审计记录在同一事务中写入，审计目标为插入后的密钥ID
+func (r *repository) CreateKey(ctx context.Context, key *entities.APIKey, entry *auditEntities.Log) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO api_keys (user_id, name, prefix, secret_hash, signing_secret, require_signature,
+			scopes, allowed_ips, rate_limit_per_minute, expires_at, created_by_admin_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING id, created_at`
+	if err := tx.QueryRowContext(ctx, query,
+		key.UserID, key.Name, key.Prefix, key.SecretHash, key.SigningSecret, key.RequireSignature,
+		key.Scopes, key.AllowedIPs, key.RateLimitPerMinute, key.ExpiresAt, key.CreatedByAdminID,
+	).Scan(&key.ID, &key.CreatedAt); err != nil {
+		return fmt.Errorf("failed to create api key: %w", err)
+	}
+
+	if entry != nil {
+		entry.TargetID = strconv.FormatInt(key.ID, 10)
+		if err := audit.Append(ctx, tx, entry); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GetKeyByPrefix 按前缀获取密钥，不存在时返回 sql.ErrNoRows
+func (r *repository) GetKeyByPrefix(ctx context.Context, prefix string) (*entities.APIKey, error) {
+	var key entities.APIKey
+	if err := r.db.GetContext(ctx, &key, "SELECT * FROM api_keys WHERE prefix = $1", prefix); err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// ListKeys 获取用户的全部密钥，包括已撤销和已过期的密钥
+func (r *repository) ListKeys(ctx context.Context, userID int64) ([]*entities.APIKey, error) {
+	keys := []*entities.APIKey{}
+	query := `SELECT * FROM api_keys WHERE user_id = $1 ORDER BY created_at DESC`
+	if err := r.db.SelectContext(ctx, &keys, query, userID); err != nil {
+		return nil, fmt.Errorf("failed to list api keys: %w", err)
+	}
+	return keys, nil
+}
+
+func (r *repository) CountActiveKeys(ctx context.Context, userID int64) (int, error) {
+	var count int
+	query := `
+		SELECT COUNT(*) FROM api_keys
+		WHERE user_id = $1 AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > NOW())`
+	if err := r.db.GetContext(ctx, &count, query, userID); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// RevokeKey 撤销用户的密钥，entry 不为空时审计记录在同一事务中写入
+// 密钥不存在或已撤销时返回 sql.ErrNoRows
+func (r *repository) RevokeKey(ctx context.Context, userID, keyID int64, entry *auditEntities.Log) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE api_keys SET revoked_at = NOW() WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL RETURNING id`
+	var id int64
+	if err := tx.QueryRowContext(ctx, query, keyID, userID).Scan(&id); err != nil {
+		return err
+	}
+
+	if entry != nil {
+		if err := audit.Append(ctx, tx, entry); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// TouchKey 记录最近使用时间和来源IP
+func (r *repository) TouchKey(ctx context.Context, keyID int64, ip string) error {
+	_, err := r.db.ExecContext(ctx, "UPDATE api_keys SET last_used_at = NOW(), last_used_ip = $2 WHERE id = $1", keyID, ip)
+	return err
+}
+
+// ListPlaintextSigningSecrets 获取签名密钥仍以明文保存的密钥
+func (r *repository) ListPlaintextSigningSecrets(ctx context.Context) ([]*entities.APIKey, error) {
+	keys := []*entities.APIKey{}
+	query := `SELECT * FROM api_keys WHERE signing_secret <> '' AND signing_secret NOT LIKE 'v1:%'`
+	if err := r.db.SelectContext(ctx, &keys, query); err != nil {
+		return nil, fmt.Errorf("failed to list plaintext signing secrets: %w", err)
+	}
+	return keys, nil
+}
+
+// UpdateSigningSecret 保存加密后的签名密钥，仅在仍为 old 时更新，多个实例同时启动时不会重复加密
+func (r *repository) UpdateSigningSecret(ctx context.Context, keyID int64, old, sealed string) error {
+	_, err := r.db.ExecContext(ctx, "UPDATE api_keys SET signing_secret = $3 WHERE id = $1 AND signing_secret = $2", keyID, old, sealed)
+	return err
+}
+
+// GetOwner 获取密钥所属用户的账户状态，不存在时返回 sql.ErrNoRows
+func (r *repository) GetOwner(ctx context.Context, userID int64) (*entities.KeyOwner, error) {
+	var owner entities.KeyOwner
+	query := `SELECT id, email, status, suspended_until FROM users WHERE id = $1`
+	if err := r.db.GetContext(ctx, &owner, query, userID); err != nil {
+		return nil, err
+	}
+	return &owner, nil
+}
@@ -0,0 +1,36 @@
+package apikeys
+
+import (
+	"trusioo_api/internal/auth/rbac"
+	"trusioo_api/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterRoutes(router *gin.RouterGroup, handler *Handler) {
+	// 密钥管理只接受用户访问令牌，API密钥不能管理密钥
+	keys := router.Group("/api-keys")
+	keys.Use(middleware.AuthMiddleware())
+	{
+		keys.GET("", handler.ListKeys) // 我的API密钥
+	}
+
+	// 创建与撤销 - 拒绝管理员代登录令牌
+	manage := router.Group("/api-keys")
+	manage.Use(middleware.AuthMiddleware(), middleware.RejectImpersonation())
+	{
+		manage.POST("", handler.CreateKey)       // 创建密钥，完整密钥只返回一次
+		manage.DELETE("/:id", handler.RevokeKey) // 撤销密钥
+	}
+
+	// 管理员为合作方用户管理密钥
+	admin := router.Group("/admin/users/:id/api-keys")
+	admin.Use(middleware.AdminAuthMiddleware())
+	{
+		canRead := middleware.RequirePermission(rbac.PermUsersRead)
+		canUpdate := middleware.RequirePermission(rbac.PermUsersUpdate)
+		admin.GET("", canRead, handler.AdminListKeys)
+		admin.POST("", canUpdate, handler.AdminCreateKey)
+		admin.DELETE("/:keyId", canUpdate, handler.AdminRevokeKey)
+	}
+}
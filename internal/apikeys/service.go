@@ -0,0 +1,393 @@
+package apikeys
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"trusioo_api/config"
+	"trusioo_api/internal/apikeys/dto"
+	"trusioo_api/internal/apikeys/entities"
+	"trusioo_api/internal/audit"
+	auditEntities "trusioo_api/internal/audit/entities"
+	"trusioo_api/internal/common"
+	"trusioo_api/pkg/logger"
+	"trusioo_api/pkg/secretbox"
+)
+
+// 密钥可授予的范围，API密钥只能访问路由上声明了对应范围的接口
+const (
+	ScopeImagesRead  = "images.read"
+	ScopeImagesWrite = "images.write"
+)
+
+// KnownScopes 全部可授予的范围
+var KnownScopes = []string{ScopeImagesRead, ScopeImagesWrite}
+
+// keyPrefix 密钥标识，便于在日志和代码仓库中识别泄露的密钥
+const keyPrefix = "tk"
+
+// Config API密钥配置
+type Config struct {
+	RateLimitPerMinute int
+	MaxPerUser         int
+	TouchInterval      time.Duration // 最近使用时间的最小更新间隔，避免每个请求都写数据库
+	AppKey             string        // 加密签名密钥的应用密钥
+}
+
+// DefaultConfig 默认配置
+func DefaultConfig() *Config {
+	return &Config{
+		RateLimitPerMinute: 60,
+		MaxPerUser:         10,
+		TouchInterval:      time.Minute,
+	}
+}
+
+// NewConfigFromApp 从应用配置创建API密钥配置
+func NewConfigFromApp(appConfig *config.Config) *Config {
+	cfg := DefaultConfig()
+	if appConfig == nil {
+		return cfg
+	}
+	if appConfig.APIKey.RateLimitPerMinute > 0 {
+		cfg.RateLimitPerMinute = appConfig.APIKey.RateLimitPerMinute
+	}
+	if appConfig.APIKey.MaxPerUser > 0 {
+		cfg.MaxPerUser = appConfig.APIKey.MaxPerUser
+	}
+	cfg.AppKey = appConfig.Server.AppKey
+	if cfg.AppKey == "" {
+		cfg.AppKey = appConfig.JWT.Secret
+	}
+	return cfg
+}
+
+// Service API密钥的创建、撤销与校验
+type Service struct {
+	repo Repository
+	cfg  *Config
+	box  *secretbox.Box
+}
+
+// NewService 创建服务，cfg 为空时使用默认配置
+func NewService(repo Repository, cfg *Config) *Service {
+	if cfg == nil {
+		cfg = DefaultConfig()
+	}
+	return &Service{repo: repo, cfg: cfg, box: secretbox.New(cfg.AppKey)}
+}
+
+// CreateKey 为用户创建密钥，createdBy 为代为创建的管理员ID
+// 用户只能使用不高于默认值的限额，管理员可以为合作方单独设置限额
+func (s *Service) CreateKey(ctx context.Context, userID int64, createdBy *int64, req *dto.CreateAPIKeyRequest) (*dto.CreateAPIKeyResponse, error) {
+	owner, err := s.repo.GetOwner(ctx, userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, common.ErrUserNotFound
+		}
+		return nil, err
+	}
+	if owner.Status == "deleted" {
+		return nil, common.ErrUserNotFound
+	}
+
+	scopes, err := normalizeScopes(req.Scopes)
+	if err != nil {
+		return nil, err
+	}
+	allowedIPs, err := normalizeAllowedIPs(req.AllowedIPs)
+	if err != nil {
+		return nil, err
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return nil, common.ErrValidation
+	}
+
+	rateLimit := s.cfg.RateLimitPerMinute
+	if req.RateLimitPerMinute > 0 && (createdBy != nil || req.RateLimitPerMinute < rateLimit) {
+		rateLimit = req.RateLimitPerMinute
+	}
+
+	count, err := s.repo.CountActiveKeys(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if count >= s.cfg.MaxPerUser {
+		return nil, common.ErrAPIKeyLimitExceeded
+	}
+
+	prefix, secret, err := newKeyParts()
+	if err != nil {
+		return nil, err
+	}
+	signingSecret, err := randomHex(32)
+	if err != nil {
+		return nil, err
+	}
+	sealedSigningSecret, err := s.box.Seal(signingSecret)
+	if err != nil {
+		return nil, err
+	}
+	key := &entities.APIKey{
+		UserID:             userID,
+		Name:               strings.TrimSpace(req.Name),
+		Prefix:             prefix,
+		SecretHash:         hashSecret(secret),
+		SigningSecret:      sealedSigningSecret,
+		RequireSignature:   req.RequireSignature,
+		Scopes:             scopes,
+		AllowedIPs:         allowedIPs,
+		RateLimitPerMinute: rateLimit,
+		ExpiresAt:          req.ExpiresAt,
+		CreatedByAdminID:   createdBy,
+	}
+	// 管理员代为创建时写入审计记录，不记录密钥和签名密钥
+	var entry *auditEntities.Log
+	if createdBy != nil {
+		entry = audit.NewLog(ctx, *createdBy, "api_key.create", auditEntities.TargetAPIKey, "")
+		entry.Changes.Set("user_id", nil, userID)
+		entry.Changes.Set("name", nil, key.Name)
+		entry.Changes.Set("scopes", nil, []string(key.Scopes))
+		entry.Changes.Set("allowed_ips", nil, []string(key.AllowedIPs))
+		entry.Changes.Set("rate_limit_per_minute", nil, key.RateLimitPerMinute)
+		entry.Changes.Set("require_signature", nil, key.RequireSignature)
+		entry.Changes.Set("expires_at", nil, key.ExpiresAt)
+	}
+	if err := s.repo.CreateKey(ctx, key, entry); err != nil {
+		return nil, err
+	}
+
+	return &dto.CreateAPIKeyResponse{
+		Key:           formatKey(prefix, secret),
+		SigningSecret: signingSecret,
+		APIKey:        key,
+	}, nil
+}
+
+// ListKeys 获取用户的全部密钥，不包含密钥本身
+func (s *Service) ListKeys(ctx context.Context, userID int64) ([]*entities.APIKey, error) {
+	return s.repo.ListKeys(ctx, userID)
+}
+
+// RevokeKey 撤销用户的密钥，立即生效
+func (s *Service) RevokeKey(ctx context.Context, userID, keyID int64) error {
+	return s.revokeKey(ctx, userID, keyID, nil)
+}
+
+// AdminRevokeKey 管理员撤销用户的密钥，审计记录与撤销在同一事务中写入
+func (s *Service) AdminRevokeKey(ctx context.Context, adminID, userID, keyID int64) error {
+	entry := audit.NewLog(ctx, adminID, "api_key.revoke", auditEntities.TargetAPIKey, strconv.FormatInt(keyID, 10))
+	entry.Changes.Set("user_id", nil, userID)
+	entry.Changes.Set("revoked", false, true)
+	return s.revokeKey(ctx, userID, keyID, entry)
+}
+
+func (s *Service) revokeKey(ctx context.Context, userID, keyID int64, entry *auditEntities.Log) error {
+	if err := s.repo.RevokeKey(ctx, userID, keyID, entry); err != nil {
+		if err == sql.ErrNoRows {
+			return common.ErrNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// ValidateAPIKey 校验密钥及来源IP，实现 middleware.APIKeyValidator
+// 所属用户被暂停或注销后密钥随之失效
+func (s *Service) ValidateAPIKey(ctx context.Context, rawKey, clientIP string) (*entities.Principal, error) {
+	prefix, secret, ok := parseKey(rawKey)
+	if !ok {
+		return nil, common.ErrAPIKeyInvalid
+	}
+
+	key, err := s.repo.GetKeyByPrefix(ctx, prefix)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, common.ErrAPIKeyInvalid
+		}
+		return nil, err
+	}
+	now := time.Now()
+	if !hmac.Equal([]byte(hashSecret(secret)), []byte(key.SecretHash)) || !key.Active(now) {
+		return nil, common.ErrAPIKeyInvalid
+	}
+
+	owner, err := s.repo.GetOwner(ctx, key.UserID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, common.ErrAPIKeyInvalid
+		}
+		return nil, err
+	}
+	if !ownerActive(owner, now) {
+		return nil, common.ErrAPIKeyInvalid
+	}
+
+	if !ipAllowed(key.AllowedIPs, clientIP) {
+		return nil, common.ErrAPIKeyIPNotAllowed
+	}
+
+	signingSecret, err := s.box.Open(key.SigningSecret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt signing secret of api key %d: %w", key.ID, err)
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= s.cfg.TouchInterval || key.LastUsedIP != clientIP {
+		if err := s.repo.TouchKey(ctx, key.ID, clientIP); err != nil {
+			logger.WithError(err).Warnf("Failed to record usage of api key %d", key.ID)
+		}
+	}
+
+	return &entities.Principal{
+		KeyID:              key.ID,
+		UserID:             owner.ID,
+		Email:              owner.Email,
+		Scopes:             key.Scopes,
+		RateLimitPerMinute: key.RateLimitPerMinute,
+		SigningSecret:      signingSecret,
+		RequireSignature:   key.RequireSignature,
+	}, nil
+}
+
+// EncryptSigningSecrets 加密旧版本以明文保存的签名密钥，启动时执行，返回加密的数量
+func (s *Service) EncryptSigningSecrets(ctx context.Context) (int, error) {
+	keys, err := s.repo.ListPlaintextSigningSecrets(ctx)
+	if err != nil {
+		return 0, err
+	}
+	for _, key := range keys {
+		sealed, err := s.box.Seal(key.SigningSecret)
+		if err != nil {
+			return 0, err
+		}
+		if err := s.repo.UpdateSigningSecret(ctx, key.ID, key.SigningSecret, sealed); err != nil {
+			return 0, err
+		}
+	}
+	return len(keys), nil
+}
+
+// ownerActive 与用户登录的状态规则一致，临时暂停到期后视为已恢复
+func ownerActive(owner *entities.KeyOwner, now time.Time) bool {
+	switch owner.Status {
+	case "active":
+		return true
+	case "suspended":
+		return owner.SuspendedUntil != nil && !owner.SuspendedUntil.After(now)
+	default:
+		return false
+	}
+}
+
+// normalizeScopes 范围必须是已定义的范围，去除重复项
+func normalizeScopes(scopes []string) ([]string, error) {
+	known := make(map[string]bool, len(KnownScopes))
+	for _, scope := range KnownScopes {
+		known[scope] = true
+	}
+
+	seen := make(map[string]bool, len(scopes))
+	normalized := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		if !known[scope] {
+			return nil, common.ErrValidation
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			normalized = append(normalized, scope)
+		}
+	}
+	return normalized, nil
+}
+
+// normalizeAllowedIPs 白名单条目必须是IP或CIDR，统一为规范格式
+func normalizeAllowedIPs(entries []string) ([]string, error) {
+	normalized := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if strings.Contains(entry, "/") {
+			_, network, err := net.ParseCIDR(entry)
+			if err != nil {
+				return nil, common.ErrValidation
+			}
+			normalized = append(normalized, network.String())
+			continue
+		}
+		ip := net.ParseIP(entry)
+		if ip == nil {
+			return nil, common.ErrValidation
+		}
+		normalized = append(normalized, ip.String())
+	}
+	return normalized, nil
+}
+
+// ipAllowed 白名单为空时不限制来源
+func ipAllowed(allowed []string, clientIP string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	ip := net.ParseIP(clientIP)
+	if ip == nil {
+		return false
+	}
+	for _, entry := range allowed {
+		if strings.Contains(entry, "/") {
+			if _, network, err := net.ParseCIDR(entry); err == nil && network.Contains(ip) {
+				return true
+			}
+			continue
+		}
+		if allowedIP := net.ParseIP(entry); allowedIP != nil && allowedIP.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// newKeyParts 生成公开前缀与随机密钥
+func newKeyParts() (string, string, error) {
+	prefix, err := randomHex(6)
+	if err != nil {
+		return "", "", err
+	}
+	secret, err := randomHex(32)
+	if err != nil {
+		return "", "", err
+	}
+	return prefix, secret, nil
+}
+
+func randomHex(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+func formatKey(prefix, secret string) string {
+	return keyPrefix + "_" + prefix + "_" + secret
+}
+
+func parseKey(key string) (string, string, bool) {
+	parts := strings.Split(key, "_")
+	if len(parts) != 3 || parts[0] != keyPrefix || parts[1] == "" || parts[2] == "" {
+		return "", "", false
+	}
+	return parts[1], parts[2], true
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,384 @@
+package apikeys
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"trusioo_api/internal/apikeys/dto"
+	"trusioo_api/internal/apikeys/entities"
+	auditEntities "trusioo_api/internal/audit/entities"
+	"trusioo_api/internal/common"
+	"trusioo_api/pkg/secretbox"
+)
+
+// fakeRepository 内存版仓库
+type fakeRepository struct {
+	keys    []*entities.APIKey
+	owners  map[int64]*entities.KeyOwner
+	touches int
+	audits  []*auditEntities.Log
+}
+
+func newFakeRepository() *fakeRepository {
+	return &fakeRepository{owners: map[int64]*entities.KeyOwner{
+		1: {ID: 1, Email: "partner@example.com", Status: "active"},
+	}}
+}
+
+func (f *fakeRepository) CreateKey(ctx context.Context, key *entities.APIKey, entry *auditEntities.Log) error {
+	key.ID = int64(len(f.keys) + 1)
+	key.CreatedAt = time.Now()
+	f.keys = append(f.keys, key)
+	if entry != nil {
+		entry.TargetID = strconv.FormatInt(key.ID, 10)
+		f.audits = append(f.audits, entry)
+	}
+	return nil
+}
+
+func (f *fakeRepository) GetKeyByPrefix(ctx context.Context, prefix string) (*entities.APIKey, error) {
+	for _, k := range f.keys {
+		if k.Prefix == prefix {
+			return k, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (f *fakeRepository) ListKeys(ctx context.Context, userID int64) ([]*entities.APIKey, error) {
+	keys := []*entities.APIKey{}
+	for _, k := range f.keys {
+		if k.UserID == userID {
+			keys = append(keys, k)
+		}
+	}
+	return keys, nil
+}
+
+func (f *fakeRepository) CountActiveKeys(ctx context.Context, userID int64) (int, error) {
+	count := 0
+	for _, k := range f.keys {
+		if k.UserID == userID && k.Active(time.Now()) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (f *fakeRepository) RevokeKey(ctx context.Context, userID, keyID int64, entry *auditEntities.Log) error {
+	for _, k := range f.keys {
+		if k.ID == keyID && k.UserID == userID && k.RevokedAt == nil {
+			now := time.Now()
+			k.RevokedAt = &now
+			if entry != nil {
+				f.audits = append(f.audits, entry)
+			}
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
+
+func (f *fakeRepository) TouchKey(ctx context.Context, keyID int64, ip string) error {
+	f.touches++
+	for _, k := range f.keys {
+		if k.ID == keyID {
+			now := time.Now()
+			k.LastUsedAt = &now
+			k.LastUsedIP = ip
+		}
+	}
+	return nil
+}
+
+func (f *fakeRepository) ListPlaintextSigningSecrets(ctx context.Context) ([]*entities.APIKey, error) {
+	keys := []*entities.APIKey{}
+	for _, k := range f.keys {
+		if k.SigningSecret != "" && !secretbox.IsSealed(k.SigningSecret) {
+			copied := *k
+			keys = append(keys, &copied)
+		}
+	}
+	return keys, nil
+}
+
+func (f *fakeRepository) UpdateSigningSecret(ctx context.Context, keyID int64, old, sealed string) error {
+	for _, k := range f.keys {
+		if k.ID == keyID && k.SigningSecret == old {
+			k.SigningSecret = sealed
+		}
+	}
+	return nil
+}
+
+func (f *fakeRepository) GetOwner(ctx context.Context, userID int64) (*entities.KeyOwner, error) {
+	owner, ok := f.owners[userID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return owner, nil
+}
+
+func TestService_CreateKey(t *testing.T) {
+	ctx := context.Background()
+	past := time.Now().Add(-time.Hour)
+	adminID := int64(9)
+
+	tests := []struct {
+		name      string
+		userID    int64
+		createdBy *int64
+		req       dto.CreateAPIKeyRequest
+		wantErr   error
+		wantRate  int
+	}{
+		{
+			name:     "创建成功",
+			userID:   1,
+			req:      dto.CreateAPIKeyRequest{Name: "backend", Scopes: []string{ScopeImagesRead, ScopeImagesRead}, AllowedIPs: []string{"10.0.0.0/8", "203.0.113.7"}},
+			wantRate: 60,
+		},
+		{
+			name:    "未定义的范围",
+			userID:  1,
+			req:     dto.CreateAPIKeyRequest{Name: "backend", Scopes: []string{"admin.all"}},
+			wantErr: common.ErrValidation,
+		},
+		{
+			name:    "无效的IP白名单",
+			userID:  1,
+			req:     dto.CreateAPIKeyRequest{Name: "backend", Scopes: []string{ScopeImagesRead}, AllowedIPs: []string{"not-an-ip"}},
+			wantErr: common.ErrValidation,
+		},
+		{
+			name:    "过期时间已过",
+			userID:  1,
+			req:     dto.CreateAPIKeyRequest{Name: "backend", Scopes: []string{ScopeImagesRead}, ExpiresAt: &past},
+			wantErr: common.ErrValidation,
+		},
+		{
+			name:    "用户不存在",
+			userID:  2,
+			req:     dto.CreateAPIKeyRequest{Name: "backend", Scopes: []string{ScopeImagesRead}},
+			wantErr: common.ErrUserNotFound,
+		},
+		{
+			name:     "用户不能调高限额",
+			userID:   1,
+			req:      dto.CreateAPIKeyRequest{Name: "backend", Scopes: []string{ScopeImagesRead}, RateLimitPerMinute: 600},
+			wantRate: 60,
+		},
+		{
+			name:      "管理员可以调高限额",
+			userID:    1,
+			createdBy: &adminID,
+			req:       dto.CreateAPIKeyRequest{Name: "backend", Scopes: []string{ScopeImagesRead}, RateLimitPerMinute: 600},
+			wantRate:  600,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newFakeRepository()
+			service := NewService(repo, nil)
+
+			resp, err := service.CreateKey(ctx, tt.userID, tt.createdBy, &tt.req)
+			if tt.wantErr != nil {
+				assert.Equal(t, tt.wantErr, err)
+				assert.Empty(t, repo.keys)
+				return
+			}
+			require.NoError(t, err)
+			assert.Regexp(t, `^tk_[0-9a-f]{12}_[0-9a-f]{64}$`, resp.Key)
+			assert.NotContains(t, resp.Key, resp.APIKey.SecretHash)
+			assert.Regexp(t, `^[0-9a-f]{64}$`, resp.SigningSecret)
+			assert.NotContains(t, resp.Key, resp.SigningSecret)
+
+			// 签名密钥加密保存
+			assert.True(t, secretbox.IsSealed(resp.APIKey.SigningSecret))
+			stored, err := service.box.Open(resp.APIKey.SigningSecret)
+			require.NoError(t, err)
+			assert.Equal(t, resp.SigningSecret, stored)
+			assert.Equal(t, tt.wantRate, resp.APIKey.RateLimitPerMinute)
+			assert.Equal(t, tt.createdBy, resp.APIKey.CreatedByAdminID)
+
+			// 只有管理员代为创建时写入审计记录
+			if tt.createdBy == nil {
+				assert.Empty(t, repo.audits)
+				return
+			}
+			require.Len(t, repo.audits, 1)
+			assert.Equal(t, "api_key.create", repo.audits[0].Action)
+			assert.Equal(t, *tt.createdBy, repo.audits[0].AdminID)
+			assert.Equal(t, strconv.FormatInt(resp.APIKey.ID, 10), repo.audits[0].TargetID)
+			assert.Equal(t, tt.wantRate, repo.audits[0].Changes["rate_limit_per_minute"].After)
+		})
+	}
+
+	t.Run("去除重复范围并规范化白名单", func(t *testing.T) {
+		service := NewService(newFakeRepository(), nil)
+		resp, err := service.CreateKey(ctx, 1, nil, &dto.CreateAPIKeyRequest{
+			Name: "backend", Scopes: []string{ScopeImagesRead, ScopeImagesRead}, AllowedIPs: []string{"10.1.2.3/8"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{ScopeImagesRead}, []string(resp.APIKey.Scopes))
+		assert.Equal(t, []string{"10.0.0.0/8"}, []string(resp.APIKey.AllowedIPs))
+	})
+
+	t.Run("密钥数量达到上限", func(t *testing.T) {
+		service := NewService(newFakeRepository(), &Config{RateLimitPerMinute: 60, MaxPerUser: 1, TouchInterval: time.Minute})
+		req := &dto.CreateAPIKeyRequest{Name: "backend", Scopes: []string{ScopeImagesRead}}
+		_, err := service.CreateKey(ctx, 1, nil, req)
+		require.NoError(t, err)
+		_, err = service.CreateKey(ctx, 1, nil, req)
+		assert.Equal(t, common.ErrAPIKeyLimitExceeded, err)
+	})
+}
+
+func TestService_AdminRevokeKey(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeRepository()
+	service := NewService(repo, nil)
+	_, err := service.CreateKey(ctx, 1, nil, &dto.CreateAPIKeyRequest{Name: "backend", Scopes: []string{ScopeImagesRead}})
+	require.NoError(t, err)
+	keyID := repo.keys[0].ID
+
+	require.NoError(t, service.AdminRevokeKey(ctx, 9, 1, keyID))
+	assert.NotNil(t, repo.keys[0].RevokedAt)
+	require.Len(t, repo.audits, 1)
+	assert.Equal(t, "api_key.revoke", repo.audits[0].Action)
+	assert.Equal(t, int64(9), repo.audits[0].AdminID)
+	assert.Equal(t, strconv.FormatInt(keyID, 10), repo.audits[0].TargetID)
+
+	// 已撤销或不属于该用户的密钥不写入审计记录
+	assert.Equal(t, common.ErrNotFound, service.AdminRevokeKey(ctx, 9, 1, keyID))
+	assert.Equal(t, common.ErrNotFound, service.AdminRevokeKey(ctx, 9, 2, keyID))
+	assert.Len(t, repo.audits, 1)
+}
+
+func TestService_ValidateAPIKey(t *testing.T) {
+	ctx := context.Background()
+
+	setup := func(t *testing.T, req *dto.CreateAPIKeyRequest) (*fakeRepository, *Service, string) {
+		repo := newFakeRepository()
+		service := NewService(repo, nil)
+		resp, err := service.CreateKey(ctx, 1, nil, req)
+		require.NoError(t, err)
+		return repo, service, resp.Key
+	}
+	basic := &dto.CreateAPIKeyRequest{Name: "backend", Scopes: []string{ScopeImagesRead}}
+
+	t.Run("有效密钥", func(t *testing.T) {
+		repo, service, key := setup(t, basic)
+		principal, err := service.ValidateAPIKey(ctx, key, "198.51.100.1")
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), principal.UserID)
+		assert.Equal(t, "partner@example.com", principal.Email)
+		assert.Equal(t, []string{ScopeImagesRead}, principal.Scopes)
+		assert.Equal(t, "198.51.100.1", repo.keys[0].LastUsedIP)
+		assert.Regexp(t, `^[0-9a-f]{64}$`, principal.SigningSecret)
+		assert.NotEqual(t, repo.keys[0].SigningSecret, principal.SigningSecret)
+		assert.False(t, principal.RequireSignature)
+	})
+
+	t.Run("要求签名的密钥", func(t *testing.T) {
+		_, service, key := setup(t, &dto.CreateAPIKeyRequest{Name: "backend", Scopes: []string{ScopeImagesRead}, RequireSignature: true})
+		principal, err := service.ValidateAPIKey(ctx, key, "198.51.100.1")
+		require.NoError(t, err)
+		assert.True(t, principal.RequireSignature)
+	})
+
+	t.Run("最近使用时间按间隔更新", func(t *testing.T) {
+		repo, service, key := setup(t, basic)
+		for i := 0; i < 3; i++ {
+			_, err := service.ValidateAPIKey(ctx, key, "198.51.100.1")
+			require.NoError(t, err)
+		}
+		assert.Equal(t, 1, repo.touches)
+	})
+
+	t.Run("无效密钥", func(t *testing.T) {
+		_, service, key := setup(t, basic)
+		for _, bad := range []string{"", "garbage", key[:len(key)-1] + "0", "tk_000000000000_" + key[16:]} {
+			_, err := service.ValidateAPIKey(ctx, bad, "198.51.100.1")
+			assert.Equal(t, common.ErrAPIKeyInvalid, err, bad)
+		}
+	})
+
+	t.Run("已撤销的密钥", func(t *testing.T) {
+		repo, service, key := setup(t, basic)
+		require.NoError(t, service.RevokeKey(ctx, 1, repo.keys[0].ID))
+		_, err := service.ValidateAPIKey(ctx, key, "198.51.100.1")
+		assert.Equal(t, common.ErrAPIKeyInvalid, err)
+		assert.Equal(t, common.ErrNotFound, service.RevokeKey(ctx, 1, repo.keys[0].ID))
+	})
+
+	t.Run("已过期的密钥", func(t *testing.T) {
+		repo, service, key := setup(t, basic)
+		past := time.Now().Add(-time.Minute)
+		repo.keys[0].ExpiresAt = &past
+		_, err := service.ValidateAPIKey(ctx, key, "198.51.100.1")
+		assert.Equal(t, common.ErrAPIKeyInvalid, err)
+	})
+
+	t.Run("用户被暂停", func(t *testing.T) {
+		repo, service, key := setup(t, basic)
+		repo.owners[1].Status = "suspended"
+		_, err := service.ValidateAPIKey(ctx, key, "198.51.100.1")
+		assert.Equal(t, common.ErrAPIKeyInvalid, err)
+	})
+
+	t.Run("IP白名单", func(t *testing.T) {
+		_, service, key := setup(t, &dto.CreateAPIKeyRequest{
+			Name: "backend", Scopes: []string{ScopeImagesRead}, AllowedIPs: []string{"10.0.0.0/8", "203.0.113.7"},
+		})
+		for ip, wantErr := range map[string]error{
+			"10.20.30.40": nil,
+			"203.0.113.7": nil,
+			"203.0.113.8": common.ErrAPIKeyIPNotAllowed,
+			"not-an-ip":   common.ErrAPIKeyIPNotAllowed,
+		} {
+			_, err := service.ValidateAPIKey(ctx, key, ip)
+			assert.Equal(t, wantErr, err, ip)
+		}
+	})
+
+	t.Run("应用密钥不同时无法解密签名密钥", func(t *testing.T) {
+		repo, _, key := setup(t, basic)
+		other := NewService(repo, &Config{RateLimitPerMinute: 60, MaxPerUser: 10, TouchInterval: time.Minute, AppKey: "other-key"})
+		_, err := other.ValidateAPIKey(ctx, key, "198.51.100.1")
+		assert.ErrorIs(t, err, secretbox.ErrMalformed)
+	})
+}
+
+func TestService_EncryptSigningSecrets(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeRepository()
+	service := NewService(repo, nil)
+	resp, err := service.CreateKey(ctx, 1, nil, &dto.CreateAPIKeyRequest{Name: "backend", Scopes: []string{ScopeImagesRead}})
+	require.NoError(t, err)
+
+	// 旧版本以明文保存的签名密钥
+	repo.keys[0].SigningSecret = resp.SigningSecret
+	repo.keys = append(repo.keys, &entities.APIKey{ID: 2, UserID: 1, Prefix: "unsigned"})
+
+	count, err := service.EncryptSigningSecrets(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.True(t, secretbox.IsSealed(repo.keys[0].SigningSecret))
+	assert.Empty(t, repo.keys[1].SigningSecret)
+
+	principal, err := service.ValidateAPIKey(ctx, resp.Key, "198.51.100.1")
+	require.NoError(t, err)
+	assert.Equal(t, resp.SigningSecret, principal.SigningSecret)
+
+	count, err = service.EncryptSigningSecrets(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, count)
+}
@@ -0,0 +1,157 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"trusioo_api/internal/audit/entities"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// GenesisHash 第一条审计记录的 PrevHash
+const GenesisHash = "0000000000000000000000000000000000000000000000000000000000000000"
+
+// chainLockKey 写入审计记录时持有的事务级咨询锁，保证哈希链按提交顺序连续
+const chainLockKey int64 = 0x61756469740001
+
+// Append 在调用方的事务中追加审计记录，与被审计的修改一起提交或回滚
+// 持有咨询锁直到事务结束，读取链尾哈希后计算本条哈希
+func Append(ctx context.Context, tx *sqlx.Tx, log *entities.Log) error {
+	if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1)", chainLockKey); err != nil {
+		return err
+	}
+
+	prevHash := GenesisHash
+	err := tx.GetContext(ctx, &prevHash, "SELECT hash FROM admin_audit_logs ORDER BY id DESC LIMIT 1")
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+
+	log.PrevHash = prevHash
+	log.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+	if log.Changes == nil {
+		log.Changes = entities.Changes{}
+	}
+	if log.Hash, err = ComputeHash(log); err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO admin_audit_logs (
+			admin_id, action, target_type, target_id, changes, ip, request_id, reason, prev_hash, hash, created_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING id`
+	return tx.QueryRowxContext(ctx, query,
+		log.AdminID,
+		log.Action,
+		log.TargetType,
+		log.TargetID,
+		log.Changes,
+		log.IP,
+		log.RequestID,
+		log.Reason,
+		log.PrevHash,
+		log.Hash,
+		log.CreatedAt,
+	).Scan(&log.ID)
+}
+
+// Record 在独立事务中追加审计记录，用于查看数据等没有数据修改的操作
+func Record(ctx context.Context, db *sqlx.DB, log *entities.Log) error {
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := Append(ctx, tx, log); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ComputeHash 计算审计记录的哈希：以 JSON 数组编码各字段，避免字段内容中的分隔符造成歧义
+func ComputeHash(log *entities.Log) (string, error) {
+	changes, err := canonicalChanges(log.Changes)
+	if err != nil {
+		return "", err
+	}
+
+	payload, err := json.Marshal([]string{
+		log.PrevHash,
+		strconv.FormatInt(log.AdminID, 10),
+		log.Action,
+		log.TargetType,
+		log.TargetID,
+		changes,
+		log.IP,
+		log.RequestID,
+		log.Reason,
+		log.CreatedAt.UTC().Format(time.RFC3339Nano),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// canonicalChanges 将修改内容编码为稳定的 JSON：先按 JSONB 读回的形式解码再编码，
+// 使写入时与从数据库读出后计算的哈希一致
+func canonicalChanges(changes entities.Changes) (string, error) {
+	if changes == nil {
+		changes = entities.Changes{}
+	}
+	data, err := json.Marshal(changes)
+	if err != nil {
+		return "", err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var normalized entities.Changes
+	if err := decoder.Decode(&normalized); err != nil {
+		return "", err
+	}
+
+	data, err = json.Marshal(normalized)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// chainVerifier 按ID顺序逐条校验哈希链
+type chainVerifier struct {
+	prevHash string
+	checked  int64
+}
+
+func newChainVerifier() *chainVerifier {
+	return &chainVerifier{prevHash: GenesisHash}
+}
+
+// check 校验一条记录，返回失败原因，通过时返回空
+func (v *chainVerifier) check(log *entities.Log) (string, error) {
+	if log.PrevHash != v.prevHash {
+		return "prev_hash does not match the previous record", nil
+	}
+	hash, err := ComputeHash(log)
+	if err != nil {
+		return "", err
+	}
+	if hash != log.Hash {
+		return "hash does not match the record content", nil
+	}
+	v.prevHash = log.Hash
+	v.checked++
+	return "", nil
+}
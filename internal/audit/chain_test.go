@@ -0,0 +1,154 @@
+package audit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"trusioo_api/internal/audit/entities"
+)
+
+func sampleLog() *entities.Log {
+	changes := entities.Changes{}
+	changes.Set("status", "active", "suspended")
+	changes.Set("level", 0, 2)
+	return &entities.Log{
+		AdminID:    1,
+		Action:     "user.suspend",
+		TargetType: entities.TargetUser,
+		TargetID:   "10",
+		Changes:    changes,
+		IP:         "203.0.113.5",
+		RequestID:  "req-1",
+		Reason:     "违规发布内容",
+		PrevHash:   GenesisHash,
+		CreatedAt:  time.Date(2025, 10, 30, 8, 0, 0, 123456000, time.UTC),
+	}
+}
+
+func TestComputeHash(t *testing.T) {
+	base := sampleLog()
+	hash, err := ComputeHash(base)
+	require.NoError(t, err)
+	assert.Len(t, hash, 64)
+
+	t.Run("相同内容哈希相同", func(t *testing.T) {
+		again, err := ComputeHash(sampleLog())
+		require.NoError(t, err)
+		assert.Equal(t, hash, again)
+
+		// 时区不影响哈希
+		log := sampleLog()
+		log.CreatedAt = log.CreatedAt.In(time.FixedZone("CST", 8*3600))
+		local, err := ComputeHash(log)
+		require.NoError(t, err)
+		assert.Equal(t, hash, local)
+	})
+
+	t.Run("从数据库读回后哈希不变", func(t *testing.T) {
+		value, err := base.Changes.Value()
+		require.NoError(t, err)
+
+		var scanned entities.Changes
+		require.NoError(t, scanned.Scan(value))
+		log := sampleLog()
+		log.Changes = scanned
+
+		readBack, err := ComputeHash(log)
+		require.NoError(t, err)
+		assert.Equal(t, hash, readBack)
+	})
+
+	tests := []struct {
+		name   string
+		modify func(log *entities.Log)
+	}{
+		{"修改原因", func(log *entities.Log) { log.Reason = "其他原因" }},
+		{"修改操作人", func(log *entities.Log) { log.AdminID = 2 }},
+		{"修改差异", func(log *entities.Log) { log.Changes["status"] = entities.Change{Before: "active", After: "active"} }},
+		{"修改时间", func(log *entities.Log) { log.CreatedAt = log.CreatedAt.Add(time.Microsecond) }},
+		{"修改上一条哈希", func(log *entities.Log) { log.PrevHash = hash }},
+		// 字段边界移动时不会得到相同的哈希
+		{"字段拼接歧义", func(log *entities.Log) { log.TargetType, log.TargetID = "user1", "0" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := sampleLog()
+			tt.modify(log)
+			modified, err := ComputeHash(log)
+			require.NoError(t, err)
+			assert.NotEqual(t, hash, modified)
+		})
+	}
+}
+
+func TestChanges_Set(t *testing.T) {
+	changes := entities.Changes{}
+	changes.Set("status", "active", "active")
+	changes.Set("rules", []string{"ios"}, []string{"ios"})
+	assert.Empty(t, changes)
+
+	changes.Set("enabled", true, false)
+	assert.Equal(t, entities.Changes{"enabled": {Before: true, After: false}}, changes)
+}
+
+func TestChainVerifier(t *testing.T) {
+	chain := func(t *testing.T, n int) []*entities.Log {
+		logs := make([]*entities.Log, 0, n)
+		prev := GenesisHash
+		for i := 0; i < n; i++ {
+			log := sampleLog()
+			log.ID = int64(i + 1)
+			log.PrevHash = prev
+			hash, err := ComputeHash(log)
+			require.NoError(t, err)
+			log.Hash = hash
+			prev = hash
+			logs = append(logs, log)
+		}
+		return logs
+	}
+
+	verify := func(logs []*entities.Log) (int64, string) {
+		verifier := newChainVerifier()
+		for _, log := range logs {
+			reason, err := verifier.check(log)
+			require.NoError(t, err)
+			if reason != "" {
+				return log.ID, reason
+			}
+		}
+		return 0, ""
+	}
+
+	t.Run("完整的链校验通过", func(t *testing.T) {
+		brokenAt, reason := verify(chain(t, 3))
+		assert.Zero(t, brokenAt)
+		assert.Empty(t, reason)
+	})
+
+	t.Run("篡改内容", func(t *testing.T) {
+		logs := chain(t, 3)
+		logs[1].Reason = "篡改"
+		brokenAt, reason := verify(logs)
+		assert.Equal(t, int64(2), brokenAt)
+		assert.Equal(t, "hash does not match the record content", reason)
+	})
+
+	t.Run("删除中间记录", func(t *testing.T) {
+		logs := chain(t, 3)
+		brokenAt, reason := verify([]*entities.Log{logs[0], logs[2]})
+		assert.Equal(t, int64(3), brokenAt)
+		assert.Equal(t, "prev_hash does not match the previous record", reason)
+	})
+
+	t.Run("篡改后重新计算本条哈希", func(t *testing.T) {
+		logs := chain(t, 3)
+		logs[0].Reason = "篡改"
+		logs[0].Hash, _ = ComputeHash(logs[0])
+		brokenAt, _ := verify(logs)
+		assert.Equal(t, int64(2), brokenAt)
+	})
+}
@@ -0,0 +1,64 @@
+package audit
+
+import (
+	"context"
+	"strings"
+
+	"trusioo_api/internal/audit/entities"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Meta 审计记录的请求信息
+type Meta struct {
+	AdminID   int64
+	IP        string
+	RequestID string
+}
+
+type metaKey struct{}
+
+// Context 返回携带当前请求审计信息的 context，管理员处理器调用服务时使用
+func Context(c *gin.Context) context.Context {
+	meta := Meta{
+		IP:        c.ClientIP(),
+		RequestID: c.GetString("request_id"),
+	}
+	if c.GetString("user_type") == "admin" {
+		if adminID, exists := c.Get("user_id"); exists {
+			meta.AdminID, _ = adminID.(int64)
+		}
+	}
+	return WithMeta(c.Request.Context(), meta)
+}
+
+// WithMeta 将审计信息存入 context
+func WithMeta(ctx context.Context, meta Meta) context.Context {
+	return context.WithValue(ctx, metaKey{}, meta)
+}
+
+// MetaFrom 读取 context 中的审计信息，没有时返回零值
+func MetaFrom(ctx context.Context) Meta {
+	meta, _ := ctx.Value(metaKey{}).(Meta)
+	return meta
+}
+
+// NewLog 创建审计记录，IP 和请求ID取自 context
+func NewLog(ctx context.Context, adminID int64, action, targetType, targetID string) *entities.Log {
+	meta := MetaFrom(ctx)
+	return &entities.Log{
+		AdminID:    adminID,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Changes:    entities.Changes{},
+		IP:         meta.IP,
+		RequestID:  meta.RequestID,
+	}
+}
+
+// WithReason 设置操作原因
+func WithReason(log *entities.Log, reason string) *entities.Log {
+	log.Reason = strings.TrimSpace(reason)
+	return log
+}
@@ -0,0 +1,41 @@
+package dto
+
+import (
+	"time"
+
+	"trusioo_api/internal/audit/entities"
+)
+
+// SearchRequest 审计记录查询条件，时间为 RFC3339 格式
+type SearchRequest struct {
+	AdminID    int64     `form:"admin_id" binding:"omitempty,min=1"`
+	Action     string    `form:"action" binding:"omitempty,max=100"`
+	TargetType string    `form:"target_type" binding:"omitempty,max=50"`
+	TargetID   string    `form:"target_id" binding:"omitempty,max=100"`
+	From       time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00"`
+	To         time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`
+	Page       int       `form:"page" binding:"omitempty,min=1"`
+	PageSize   int       `form:"page_size" binding:"omitempty,min=1,max=100"`
+}
+
+// SearchResponse 审计记录列表，按时间从新到旧排序
+type SearchResponse struct {
+	Total int64           `json:"total"`
+	Page  int             `json:"page"`
+	Size  int             `json:"size"`
+	Logs  []*entities.Log `json:"logs"`
+}
+
+// ExportRequest 导出审计记录，导出操作本身也会记录审计
+type ExportRequest struct {
+	SearchRequest
+	Reason string `form:"reason" binding:"omitempty,max=255"`
+}
+
+// VerifyResponse 哈希链校验结果
+type VerifyResponse struct {
+	Valid      bool   `json:"valid"`
+	Checked    int64  `json:"checked"`
+	BrokenAtID *int64 `json:"broken_at_id,omitempty"`
+	Reason     string `json:"reason,omitempty"`
+}
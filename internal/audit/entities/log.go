@@ -0,0 +1,91 @@
+package entities
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// 审计目标类型
+const (
+	TargetUser        = "user"
+	TargetImage       = "image"
+	TargetFeatureFlag = "feature_flag"
+	TargetKYC         = "kyc_submission"
+	TargetReferral    = "referral"
+	TargetAuditLog    = "audit_log"
+	TargetAdmin       = "admin"
+	TargetRole        = "admin_role"
+	TargetInvitation  = "admin_invitation"
+	TargetAPIKey      = "api_key"
+)
+
+// Log 管理员审计记录，只追加不修改
+// PrevHash 为上一条记录的哈希，Hash 覆盖本条全部字段和 PrevHash，任一记录被改动或删除都会使哈希链断开
+type Log struct {
+	ID         int64     `json:"id" db:"id"`
+	AdminID    int64     `json:"admin_id" db:"admin_id"`
+	Action     string    `json:"action" db:"action"`
+	TargetType string    `json:"target_type" db:"target_type"`
+	TargetID   string    `json:"target_id" db:"target_id"`
+	Changes    Changes   `json:"changes" db:"changes"`
+	IP         string    `json:"ip" db:"ip"`
+	RequestID  string    `json:"request_id" db:"request_id"`
+	Reason     string    `json:"reason" db:"reason"`
+	PrevHash   string    `json:"prev_hash" db:"prev_hash"`
+	Hash       string    `json:"hash" db:"hash"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+}
+
+// Change 字段修改前后的值
+type Change struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// Changes 按字段记录的修改，以 JSONB 存储
+type Changes map[string]Change
+
+// Set 记录字段修改，前后值相同时忽略
+func (c Changes) Set(field string, before, after interface{}) Changes {
+	if !reflect.DeepEqual(before, after) {
+		c[field] = Change{Before: before, After: after}
+	}
+	return c
+}
+
+// Value 实现 driver.Valuer
+func (c Changes) Value() (driver.Value, error) {
+	if c == nil {
+		return []byte("{}"), nil
+	}
+	return json.Marshal(c)
+}
+
+// Scan 实现 sql.Scanner，数字保留原始文本以便重新计算哈希
+func (c *Changes) Scan(src interface{}) error {
+	var data []byte
+	switch v := src.(type) {
+	case nil:
+		*c = Changes{}
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("cannot scan %T into Changes", src)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	changes := Changes{}
+	if err := decoder.Decode(&changes); err != nil {
+		return err
+	}
+	*c = changes
+	return nil
+}
@@ -0,0 +1,171 @@
+package audit
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"trusioo_api/internal/audit/dto"
+	"trusioo_api/internal/audit/entities"
+	"trusioo_api/internal/common"
+
+	"github.com/gin-gonic/gin"
+)
+
+// csvHeader 导出文件的列
+var csvHeader = []string{
+	"id", "created_at", "admin_id", "action", "target_type", "target_id",
+	"changes", "ip", "request_id", "reason", "prev_hash", "hash",
+}
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// SearchLogs 查询审计记录
+// @Summary 查询审计记录
+// @Description 按管理员、操作、目标和时间范围查询管理员审计记录，按时间从新到旧排序
+// @Tags 管理员-审计
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param admin_id query int false "管理员ID"
+// @Param action query string false "操作，例如 user.suspend"
+// @Param target_type query string false "目标类型，例如 user、image"
+// @Param target_id query string false "目标ID"
+// @Param from query string false "开始时间（RFC3339，含）"
+// @Param to query string false "结束时间（RFC3339，不含）"
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Success 200 {object} common.Response{data=dto.SearchResponse} "获取成功"
+// @Failure 400 {object} common.Response "参数错误"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足"
+// @Failure 500 {object} common.Response "服务器错误"
+// @Router /api/v1/admin/audit-logs [get]
+func (h *Handler) SearchLogs(c *gin.Context) {
+	var req dto.SearchRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		common.ValidationError(c, err.Error())
+		return
+	}
+
+	resp, err := h.service.Search(c.Request.Context(), &req)
+	if err != nil {
+		common.ServerError(c, err)
+		return
+	}
+
+	common.Success(c, resp)
+}
+
+// ExportLogs 导出审计记录
+// @Summary 导出审计记录
+// @Description 按查询条件导出审计记录为 CSV，导出操作本身也会记录审计
+// @Tags 管理员-审计
+// @Accept json
+// @Produce text/csv
+// @Security ApiKeyAuth
+// @Param admin_id query int false "管理员ID"
+// @Param action query string false "操作"
+// @Param target_type query string false "目标类型"
+// @Param target_id query string false "目标ID"
+// @Param from query string false "开始时间（RFC3339，含）"
+// @Param to query string false "结束时间（RFC3339，不含）"
+// @Param reason query string false "导出原因"
+// @Success 200 {file} file "CSV 文件"
+// @Failure 400 {object} common.Response "参数错误"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足"
+// @Failure 500 {object} common.Response "服务器错误"
+// @Router /api/v1/admin/audit-logs/export [get]
+func (h *Handler) ExportLogs(c *gin.Context) {
+	adminID, exists := c.Get("user_id")
+	if !exists {
+		common.Unauthorized(c, "Admin not authenticated")
+		return
+	}
+
+	var req dto.ExportRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		common.ValidationError(c, err.Error())
+		return
+	}
+
+	logs, err := h.service.Export(Context(c), adminID.(int64), &req)
+	if err != nil {
+		common.ServerError(c, err)
+		return
+	}
+
+	filename := fmt.Sprintf("audit-logs-%s.csv", time.Now().UTC().Format("20060102-150405"))
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Status(http.StatusOK)
+
+	// UTF-8 BOM，便于表格软件正确显示中文原因
+	c.Writer.WriteString("\xEF\xBB\xBF")
+	writer := csv.NewWriter(c.Writer)
+	writer.Write(csvHeader)
+	for _, log := range logs {
+		writer.Write(csvRecord(log))
+	}
+	writer.Flush()
+}
+
+// VerifyChain 校验审计记录哈希链
+// @Summary 校验审计记录哈希链
+// @Description 按写入顺序重新计算全部审计记录的哈希，返回第一条被篡改或链接断开的记录
+// @Tags 管理员-审计
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} common.Response{data=dto.VerifyResponse} "校验完成"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足"
+// @Failure 500 {object} common.Response "服务器错误"
+// @Router /api/v1/admin/audit-logs/verify [get]
+func (h *Handler) VerifyChain(c *gin.Context) {
+	resp, err := h.service.Verify(c.Request.Context())
+	if err != nil {
+		common.ServerError(c, err)
+		return
+	}
+
+	common.Success(c, resp)
+}
+
+// csvRecord 将审计记录转换为 CSV 行
+func csvRecord(log *entities.Log) []string {
+	changes, _ := json.Marshal(log.Changes)
+	return []string{
+		strconv.FormatInt(log.ID, 10),
+		log.CreatedAt.UTC().Format(time.RFC3339Nano),
+		strconv.FormatInt(log.AdminID, 10),
+		csvSafe(log.Action),
+		csvSafe(log.TargetType),
+		csvSafe(log.TargetID),
+		csvSafe(string(changes)),
+		csvSafe(log.IP),
+		csvSafe(log.RequestID),
+		csvSafe(log.Reason),
+		log.PrevHash,
+		log.Hash,
+	}
+}
+
+// csvSafe 以公式字符开头的值加单引号前缀，防止在表格软件中被当作公式执行
+func csvSafe(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
@@ -0,0 +1,90 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"trusioo_api/internal/audit/dto"
+	"trusioo_api/internal/audit/entities"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Repository 审计记录的数据访问接口
+type Repository interface {
+	Record(ctx context.Context, log *entities.Log) error
+	Search(ctx context.Context, req *dto.SearchRequest, limit, offset int) ([]*entities.Log, int64, error)
+	ListAfter(ctx context.Context, afterID int64, limit int) ([]*entities.Log, error)
+}
+
+type repository struct {
+	db *sqlx.DB
+}
+
+// NewRepository 创建审计仓库
+func NewRepository(db *sqlx.DB) Repository {
+	return &repository{db: db}
+}
+
+// Record 在独立事务中追加审计记录
+func (r *repository) Record(ctx context.Context, log *entities.Log) error {
+	return Record(ctx, r.db, log)
+}
+
+// Search 按条件查询审计记录，按ID从新到旧排序
+func (r *repository) Search(ctx context.Context, req *dto.SearchRequest, limit, offset int) ([]*entities.Log, int64, error) {
+	var conditions []string
+	var args []interface{}
+	add := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if req.AdminID > 0 {
+		add("admin_id = $%d", req.AdminID)
+	}
+	if req.Action != "" {
+		add("action = $%d", req.Action)
+	}
+	if req.TargetType != "" {
+		add("target_type = $%d", req.TargetType)
+	}
+	if req.TargetID != "" {
+		add("target_id = $%d", req.TargetID)
+	}
+	if !req.From.IsZero() {
+		add("created_at >= $%d", req.From)
+	}
+	if !req.To.IsZero() {
+		add("created_at < $%d", req.To)
+	}
+
+	whereClause := ""
+	if len(conditions) > 0 {
+		whereClause = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int64
+	if err := r.db.GetContext(ctx, &total, "SELECT COUNT(*) FROM admin_audit_logs"+whereClause, args...); err != nil {
+		return nil, 0, err
+	}
+
+	logs := []*entities.Log{}
+	query := "SELECT * FROM admin_audit_logs" + whereClause +
+		fmt.Sprintf(" ORDER BY id DESC LIMIT $%d OFFSET $%d", len(args)+1, len(args)+2)
+	if err := r.db.SelectContext(ctx, &logs, query, append(args, limit, offset)...); err != nil {
+		return nil, 0, fmt.Errorf("failed to search audit logs: %w", err)
+	}
+	return logs, total, nil
+}
+
+// ListAfter 按ID顺序获取 afterID 之后的记录，用于分批校验哈希链
+func (r *repository) ListAfter(ctx context.Context, afterID int64, limit int) ([]*entities.Log, error) {
+	logs := []*entities.Log{}
+	query := `SELECT * FROM admin_audit_logs WHERE id > $1 ORDER BY id ASC LIMIT $2`
+	if err := r.db.SelectContext(ctx, &logs, query, afterID, limit); err != nil {
+		return nil, fmt.Errorf("failed to list audit logs: %w", err)
+	}
+	return logs, nil
+}
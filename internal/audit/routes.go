@@ -0,0 +1,22 @@
+package audit
+
+import (
+	"trusioo_api/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// readPermission 查询审计记录所需的权限，与 rbac.PermAuditRead 一致
+// rbac 的角色变更要写入审计记录，审计包不能反向依赖 rbac
+const readPermission = "audit.read"
+
+func RegisterRoutes(router *gin.RouterGroup, handler *Handler) {
+	// 审计记录 - 默认只有超级管理员拥有 audit.read
+	admin := router.Group("/admin/audit-logs")
+	admin.Use(middleware.AdminAuthMiddleware(), middleware.RequirePermission(readPermission))
+	{
+		admin.GET("", handler.SearchLogs)
+		admin.GET("/export", handler.ExportLogs) // CSV
+		admin.GET("/verify", handler.VerifyChain)
+	}
+}
@@ -0,0 +1,134 @@
+package audit
+
+import (
+	"context"
+
+	"trusioo_api/config"
+	"trusioo_api/internal/audit/dto"
+	"trusioo_api/internal/audit/entities"
+)
+
+// verifyBatchSize 校验哈希链时每批读取的记录数
+const verifyBatchSize = 1000
+
+// Config 审计配置
+type Config struct {
+	ExportMaxRows int
+}
+
+// DefaultConfig 默认配置
+func DefaultConfig() *Config {
+	return &Config{ExportMaxRows: 10000}
+}
+
+// NewConfigFromApp 从应用配置创建审计配置
+func NewConfigFromApp(appConfig *config.Config) *Config {
+	cfg := DefaultConfig()
+	if appConfig != nil && appConfig.Audit.ExportMaxRows > 0 {
+		cfg.ExportMaxRows = appConfig.Audit.ExportMaxRows
+	}
+	return cfg
+}
+
+// Service 审计记录的查询、导出与校验
+// 审计记录由各业务仓库通过 Append 在修改数据的事务中写入
+type Service struct {
+	repo Repository
+	cfg  *Config
+}
+
+// NewService 创建服务，cfg 为空时使用默认配置
+func NewService(repo Repository, cfg *Config) *Service {
+	if cfg == nil {
+		cfg = DefaultConfig()
+	}
+	return &Service{repo: repo, cfg: cfg}
+}
+
+// Search 分页查询审计记录
+func (s *Service) Search(ctx context.Context, req *dto.SearchRequest) (*dto.SearchResponse, error) {
+	page := req.Page
+	if page < 1 {
+		page = 1
+	}
+	size := req.PageSize
+	if size < 1 {
+		size = 20
+	}
+
+	logs, total, err := s.repo.Search(ctx, req, size, (page-1)*size)
+	if err != nil {
+		return nil, err
+	}
+	return &dto.SearchResponse{
+		Total: total,
+		Page:  page,
+		Size:  size,
+		Logs:  logs,
+	}, nil
+}
+
+// Export 按条件导出审计记录，最多 ExportMaxRows 条；导出前先记录本次导出
+func (s *Service) Export(ctx context.Context, adminID int64, req *dto.ExportRequest) ([]*entities.Log, error) {
+	log := WithReason(NewLog(ctx, adminID, "audit.export", entities.TargetAuditLog, ""), req.Reason)
+	log.Changes.Set("filter", nil, exportFilter(&req.SearchRequest))
+	if err := s.repo.Record(ctx, log); err != nil {
+		return nil, err
+	}
+
+	logs, _, err := s.repo.Search(ctx, &req.SearchRequest, s.cfg.ExportMaxRows, 0)
+	if err != nil {
+		return nil, err
+	}
+	return logs, nil
+}
+
+// Verify 按ID顺序校验整条哈希链，返回第一条校验失败的记录
+func (s *Service) Verify(ctx context.Context) (*dto.VerifyResponse, error) {
+	verifier := newChainVerifier()
+	var afterID int64
+	for {
+		logs, err := s.repo.ListAfter(ctx, afterID, verifyBatchSize)
+		if err != nil {
+			return nil, err
+		}
+		for _, log := range logs {
+			reason, err := verifier.check(log)
+			if err != nil {
+				return nil, err
+			}
+			if reason != "" {
+				brokenAt := log.ID
+				return &dto.VerifyResponse{Checked: verifier.checked, BrokenAtID: &brokenAt, Reason: reason}, nil
+			}
+			afterID = log.ID
+		}
+		if len(logs) < verifyBatchSize {
+			return &dto.VerifyResponse{Valid: true, Checked: verifier.checked}, nil
+		}
+	}
+}
+
+// exportFilter 导出条件中已设置的字段
+func exportFilter(req *dto.SearchRequest) map[string]interface{} {
+	filter := map[string]interface{}{}
+	if req.AdminID > 0 {
+		filter["admin_id"] = req.AdminID
+	}
+	if req.Action != "" {
+		filter["action"] = req.Action
+	}
+	if req.TargetType != "" {
+		filter["target_type"] = req.TargetType
+	}
+	if req.TargetID != "" {
+		filter["target_id"] = req.TargetID
+	}
+	if !req.From.IsZero() {
+		filter["from"] = req.From
+	}
+	if !req.To.IsZero() {
+		filter["to"] = req.To
+	}
+	return filter
+}
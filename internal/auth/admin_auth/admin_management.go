@@ -0,0 +1,323 @@
+package admin
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"trusioo_api/config"
+	"trusioo_api/internal/audit"
+	auditEntities "trusioo_api/internal/audit/entities"
+	"trusioo_api/internal/auth/admin_auth/dto"
+	"trusioo_api/internal/auth/admin_auth/entities"
+	"trusioo_api/internal/auth/rbac"
+	"trusioo_api/internal/common"
+	"trusioo_api/pkg/mailer"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// defaultInviteTTL 未配置邀请有效期时使用
+const defaultInviteTTL = 72 * time.Hour
+
+// InviteAdmin 邀请管理员：生成一次性邀请链接并发送到邮箱，被邀请人接受邀请时设置密码
+func (s *Service) InviteAdmin(ctx context.Context, actorID int64, req *dto.InviteAdminRequest) (*entities.AdminInvitation, error) {
+	if err := s.authorize(ctx, actorID, rbac.PermAdminsManage); err != nil {
+		return nil, err
+	}
+
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+	if _, err := s.adminRepo.GetByEmail(email); err == nil {
+		return nil, common.ErrAdminEmailExists
+	} else if err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	// 只能邀请为自己权限范围内的角色，避免通过自己控制的邮箱提升权限
+	if err := s.permissions.AuthorizeRoleGrant(ctx, actorID, req.Role); err != nil {
+		return nil, err
+	}
+
+	ttl := s.inviteTTL
+	if ttl <= 0 {
+		ttl = defaultInviteTTL
+	}
+	expiresAt := time.Now().Add(ttl)
+	token, err := newInviteToken(expiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	invitation := &entities.AdminInvitation{
+		Email:     email,
+		Name:      strings.TrimSpace(req.Name),
+		Role:      req.Role,
+		TokenHash: hashInviteToken(token),
+		InvitedBy: &actorID,
+		ExpiresAt: expiresAt,
+	}
+	entry := audit.NewLog(ctx, actorID, "admin_invitation.create", auditEntities.TargetInvitation, "")
+	entry.Changes.Set("email", nil, invitation.Email)
+	entry.Changes.Set("role", nil, invitation.Role)
+	entry.Changes.Set("expires_at", nil, invitation.ExpiresAt)
+	if err := s.adminRepo.CreateInvitation(ctx, invitation, entry); err != nil {
+		return nil, err
+	}
+
+	if err := s.sendInvitationEmail(invitation, token, ttl); err != nil {
+		return nil, err
+	}
+	return invitation, nil
+}
+
+// AcceptInvitation 接受邀请：校验邀请链接，设置密码并创建激活状态的管理员
+// 邀请发送到被邀请人邮箱，接受邀请即视为邮箱已验证
+func (s *Service) AcceptInvitation(req *dto.AcceptInvitationRequest) (*dto.AcceptInvitationResponse, error) {
+	if err := verifyInviteToken(req.Token, time.Now()); err != nil {
+		return nil, err
+	}
+
+	invitation, err := s.adminRepo.GetInvitationByTokenHash(hashInviteToken(req.Token))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, common.ErrInvitationInvalid
+		}
+		return nil, err
+	}
+	if invitation.AcceptedAt != nil || invitation.RevokedAt != nil {
+		return nil, common.ErrInvitationInvalid
+	}
+	if time.Now().After(invitation.ExpiresAt) {
+		return nil, common.ErrTokenExpired
+	}
+
+	if _, err := s.adminRepo.GetByEmail(invitation.Email); err == nil {
+		return nil, common.ErrAdminEmailExists
+	} else if err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	// 新管理员的密码同样需要满足密码策略
+	if err := s.identityCore().ValidatePassword(0, "", invitation.Email, req.Password); err != nil {
+		return nil, err
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		name = invitation.Name
+	}
+	admin := &entities.Admin{
+		Name:          name,
+		Email:         invitation.Email,
+		Password:      string(hashedPassword),
+		Role:          invitation.Role,
+		Status:        "active",
+		EmailVerified: true,
+	}
+	if err := s.adminRepo.AcceptInvitation(invitation.ID, admin); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, common.ErrInvitationInvalid
+		}
+		return nil, err
+	}
+	s.identityCore().RecordPasswordHistory(admin.ID, admin.Password)
+
+	return &dto.AcceptInvitationResponse{
+		Message: "邀请已接受，请使用邮箱和密码登录",
+		Admin:   admin,
+	}, nil
+}
+
+// ListInvitations 获取待接受的邀请
+func (s *Service) ListInvitations(ctx context.Context, actorID int64) ([]*entities.AdminInvitation, error) {
+	if err := s.authorize(ctx, actorID, rbac.PermAdminsManage); err != nil {
+		return nil, err
+	}
+	return s.adminRepo.ListPendingInvitations()
+}
+
+// RevokeInvitation 撤销尚未接受的邀请
+func (s *Service) RevokeInvitation(ctx context.Context, actorID, invitationID int64) error {
+	if err := s.authorize(ctx, actorID, rbac.PermAdminsManage); err != nil {
+		return err
+	}
+	entry := audit.NewLog(ctx, actorID, "admin_invitation.revoke", auditEntities.TargetInvitation, strconv.FormatInt(invitationID, 10))
+	if err := s.adminRepo.RevokeInvitation(ctx, invitationID, entry); err != nil {
+		if err == sql.ErrNoRows {
+			return common.ErrNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// ListAdmins 获取管理员列表，status 为空时返回全部
+func (s *Service) ListAdmins(ctx context.Context, actorID int64, req *dto.AdminListRequest) ([]*entities.Admin, error) {
+	if err := s.authorize(ctx, actorID, rbac.PermAdminsManage); err != nil {
+		return nil, err
+	}
+	return s.adminRepo.ListAdmins(req.Status)
+}
+
+// DeactivateAdmin 停用管理员并使其刷新令牌失效
+// 不能停用自己，也不能停用最后一个激活的超级管理员
+func (s *Service) DeactivateAdmin(ctx context.Context, actorID, adminID int64) (*dto.AdminActionResponse, error) {
+	admin, err := s.prepareAdminAction(ctx, actorID, adminID)
+	if err != nil {
+		return nil, err
+	}
+	if admin.Status != "active" {
+		return nil, common.ErrValidation
+	}
+
+	if err := s.adminRepo.DeactivateAdmin(ctx, adminID, newAdminAuditLog(ctx, actorID, "admin.deactivate", admin, "inactive")); err != nil {
+		return nil, err
+	}
+	s.permissions.InvalidateAdmin(ctx, adminID)
+
+	return s.adminActionResponse("管理员已停用", adminID)
+}
+
+// ReactivateAdmin 恢复被停用的管理员
+func (s *Service) ReactivateAdmin(ctx context.Context, actorID, adminID int64) (*dto.AdminActionResponse, error) {
+	admin, err := s.prepareAdminAction(ctx, actorID, adminID)
+	if err != nil {
+		return nil, err
+	}
+	if admin.Status == "active" {
+		return nil, common.ErrValidation
+	}
+
+	if err := s.adminRepo.ReactivateAdmin(ctx, adminID, newAdminAuditLog(ctx, actorID, "admin.reactivate", admin, "active")); err != nil {
+		return nil, err
+	}
+	s.permissions.InvalidateAdmin(ctx, adminID)
+
+	return s.adminActionResponse("管理员已恢复", adminID)
+}
+
+// prepareAdminAction 校验管理员账户管理权限并获取目标管理员，不能操作自己的账户
+// 目标管理员的角色必须是操作者可以授予的角色，避免停用或恢复权限更高的管理员
+func (s *Service) prepareAdminAction(ctx context.Context, actorID, adminID int64) (*entities.Admin, error) {
+	if err := s.authorize(ctx, actorID, rbac.PermAdminsManage); err != nil {
+		return nil, err
+	}
+	if actorID == adminID {
+		return nil, common.ErrValidation
+	}
+	admin, err := s.GetAdminByID(adminID)
+	if err != nil {
+		return nil, err
+	}
+
+	role := admin.Role
+	if admin.IsSuper {
+		role = rbac.SuperAdminRole
+	}
+	// 角色已不存在的管理员没有任何权限，不会高于操作者
+	if err := s.permissions.AuthorizeRoleGrant(ctx, actorID, role); err != nil && err != common.ErrNotFound {
+		return nil, err
+	}
+	return admin, nil
+}
+
+// newAdminAuditLog 创建管理员账户状态变更的审计记录
+func newAdminAuditLog(ctx context.Context, actorID int64, action string, admin *entities.Admin, status string) *auditEntities.Log {
+	entry := audit.NewLog(ctx, actorID, action, auditEntities.TargetAdmin, strconv.FormatInt(admin.ID, 10))
+	entry.Changes.Set("status", admin.Status, status)
+	return entry
+}
+
+// adminActionResponse 返回操作后的管理员信息
+func (s *Service) adminActionResponse(message string, adminID int64) (*dto.AdminActionResponse, error) {
+	admin, err := s.GetAdminByID(adminID)
+	if err != nil {
+		return nil, err
+	}
+	return &dto.AdminActionResponse{
+		Message: message,
+		Admin:   admin,
+	}, nil
+}
+
+// sendInvitationEmail 发送邀请邮件，链接指向管理后台的接受邀请页面
+func (s *Service) sendInvitationEmail(invitation *entities.AdminInvitation, token string, ttl time.Duration) error {
+	if s.mailOutbox == nil {
+		log.Printf("未配置邮件发件箱，邀请 %d 未发送", invitation.ID)
+		return nil
+	}
+
+	link := strings.TrimRight(config.AppConfig.Frontend.AdminURL, "/") + "/invite?token=" + url.QueryEscape(token)
+	msg, err := mailer.Render(invitation.Email, mailer.TemplateAdminInvitation, "", s.mailLocale, mailer.TemplateData{
+		Email:          invitation.Email,
+		Link:           link,
+		Role:           invitation.Role,
+		ExpiresInHours: int(ttl / time.Hour),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to render admin invitation: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return s.mailOutbox.Enqueue(ctx, msg)
+}
+
+// newInviteToken 生成邀请令牌：过期时间、随机数与HMAC签名
+// 签名使请求在查询数据库前即可拒绝伪造或过期的令牌，数据库中只保存令牌哈希
+func newInviteToken(expiresAt time.Time) (string, error) {
+	nonce := make([]byte, 24)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	payload := strconv.FormatInt(expiresAt.Unix(), 10) + "." + base64.RawURLEncoding.EncodeToString(nonce)
+	return payload + "." + signInvitePayload(payload), nil
+}
+
+// verifyInviteToken 校验邀请令牌的签名与有效期
+func verifyInviteToken(token string, now time.Time) error {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return common.ErrInvitationInvalid
+	}
+
+	payload := parts[0] + "." + parts[1]
+	if !hmac.Equal([]byte(parts[2]), []byte(signInvitePayload(payload))) {
+		return common.ErrInvitationInvalid
+	}
+
+	expiresAt, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return common.ErrInvitationInvalid
+	}
+	if now.Unix() > expiresAt {
+		return common.ErrTokenExpired
+	}
+	return nil
+}
+
+func signInvitePayload(payload string) string {
+	mac := hmac.New(sha256.New, []byte("admin-invite:"+config.AppConfig.JWT.Secret))
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func hashInviteToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
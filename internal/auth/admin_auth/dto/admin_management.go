@@ -0,0 +1,34 @@
+package dto
+
+import "trusioo_api/internal/auth/admin_auth/entities"
+
+// InviteAdminRequest 邀请管理员请求，同一邮箱重复邀请时旧邀请失效
+type InviteAdminRequest struct {
+	Email string `json:"email" binding:"required,email"`
+	Name  string `json:"name" binding:"required,max=100"`
+	Role  string `json:"role" binding:"required,max=50"`
+}
+
+// AcceptInvitationRequest 接受邀请并设置密码，不填姓名时使用邀请中的姓名
+type AcceptInvitationRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,min=6"`
+	Name     string `json:"name" binding:"omitempty,max=100"`
+}
+
+// AcceptInvitationResponse 接受邀请响应，管理员随后通过正常登录流程登录
+type AcceptInvitationResponse struct {
+	Message string          `json:"message"`
+	Admin   *entities.Admin `json:"admin"`
+}
+
+// AdminListRequest 管理员列表请求
+type AdminListRequest struct {
+	Status string `form:"status" binding:"omitempty,oneof=active inactive"`
+}
+
+// AdminActionResponse 管理员账户操作响应
+type AdminActionResponse struct {
+	Message string          `json:"message"`
+	Admin   *entities.Admin `json:"admin"`
+}
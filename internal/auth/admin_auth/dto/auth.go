@@ -18,6 +18,8 @@ func (r *AdminLoginRequest) VerificationType() string {
 type AdminLoginVerifyRequest struct {
 	Email string `json:"email" binding:"required,email"`
 	Code  string `json:"code" binding:"required,len=6"`
+	// UseCookies 管理后台网页登录时为 true：令牌写入 HttpOnly Cookie 而不在响应体中返回
+	UseCookies bool `json:"use_cookies"`
 }
 
 // VerificationType 返回此DTO对应的验证类型
@@ -30,6 +32,9 @@ type AdminLoginCodeResponse struct {
 	Message   string `json:"message"`
 	LoginCode string `json:"login_code"`
 	ExpiresIn int    `json:"expires_in"` // 秒
+
+	// 登录失败次数过多时需要等待的秒数
+	RetryAfter int `json:"retry_after,omitempty"`
 }
 
 // AdminLoginResponse 管理员登录响应
@@ -40,6 +45,7 @@ type AdminLoginResponse struct {
 	TokenType    string                 `json:"token_type"`
 	Admin        entities.Admin         `json:"admin"`
 	LoginSession *AdminLoginSessionInfo `json:"login_session,omitempty"`
+	CSRFToken    string                 `json:"csrf_token,omitempty"` // Cookie 会话中修改数据的请求需要放入 X-CSRF-Token 请求头
 }
 
 // AdminLoginSessionInfo 管理员登录会话信息
@@ -59,6 +65,11 @@ type RefreshTokenRequest struct {
 	RefreshToken string `json:"refresh_token" binding:"required"`
 }
 
+// AdminLogoutRequest 管理员退出登录请求，使用 Cookie 会话时可省略刷新令牌
+type AdminLogoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
 // AdminForgotPasswordRequest 管理员忘记密码请求
 type AdminForgotPasswordRequest struct {
 	Email string `json:"email" binding:"required,email"`
@@ -81,3 +92,40 @@ type AdminResetPasswordRequest struct {
 type AdminResetPasswordResponse struct {
 	Message string `json:"message"`
 }
+
+// AdminReauthCodeRequest 管理员重新验证身份 - 向管理员邮箱发送验证码
+type AdminReauthCodeRequest struct {
+	Locale string `json:"locale,omitempty"`
+}
+
+// VerificationType 返回管理员重新验证身份的验证类型
+func (r *AdminReauthCodeRequest) VerificationType() string {
+	return "admin_reauth"
+}
+
+// AdminReauthCodeResponse 管理员重新验证身份验证码发送响应
+type AdminReauthCodeResponse struct {
+	Message   string `json:"message"`
+	ExpiresIn int    `json:"expires_in"` // 秒
+}
+
+// AdminReauthRequest 管理员重新验证身份请求，password 与 code 二选一
+type AdminReauthRequest struct {
+	Password string `json:"password,omitempty"`
+	Code     string `json:"code,omitempty" binding:"omitempty,len=6"` // 管理员邮箱收到的验证码
+}
+
+// VerificationType 返回管理员重新验证身份的验证类型
+func (r *AdminReauthRequest) VerificationType() string {
+	return "admin_reauth"
+}
+
+// AdminElevatedTokenResponse 管理员提升令牌响应，调用查看用户详情、批量删除图片等敏感接口时作为 Bearer 令牌使用
+type AdminElevatedTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+	AuthTime    int64  `json:"auth_time"`             // 验证身份的时间（Unix 秒）
+	ACR         string `json:"acr"`                   // 验证方式：password 或 email_code
+	RetryAfter  int    `json:"retry_after,omitempty"` // 密码错误次数过多时需要等待的秒数
+}
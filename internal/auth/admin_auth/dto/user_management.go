@@ -1,12 +1,16 @@
 package dto
 
-import "trusioo_api/internal/auth/admin_auth/entities"
+import (
+	"time"
+
+	"trusioo_api/internal/auth/admin_auth/entities"
+)
 
 // UserListRequest 获取用户列表请求
 type UserListRequest struct {
 	Page     int    `form:"page" binding:"omitempty,min=1"`
 	PageSize int    `form:"page_size" binding:"omitempty,min=1,max=100"`
-	Status   string `form:"status" binding:"omitempty,oneof=active inactive all"`
+	Status   string `form:"status" binding:"omitempty,oneof=active inactive suspended all"`
 	Email    string `form:"email" binding:"omitempty,email"`
 	Phone    string `form:"phone" binding:"omitempty"`
 }
@@ -16,6 +20,7 @@ type UserStats struct {
 	TotalUsers         int64 `json:"total_users"`
 	ActiveUsers        int64 `json:"active_users"`
 	InactiveUsers      int64 `json:"inactive_users"`
+	SuspendedUsers     int64 `json:"suspended_users"`
 	RegisteredToday    int64 `json:"registered_today"`
 	RegisteredThisWeek int64 `json:"registered_this_week"`
 	RegisteredThisMonth int64 `json:"registered_this_month"`
@@ -27,4 +32,42 @@ type UserListResponse struct {
 	Page  int                  `json:"page"`
 	Size  int                  `json:"size"`
 	Users []entities.UserInfo  `json:"users"`
-}
\ No newline at end of file
+}
+
+// SuspendUserRequest 暂停用户请求，不填到期时间为无限期暂停
+type SuspendUserRequest struct {
+	Reason string     `json:"reason" binding:"required,max=500"`
+	Until  *time.Time `json:"until,omitempty"`
+}
+
+// UserActionRequest 用户管理操作请求，所有操作都必须填写原因
+type UserActionRequest struct {
+	Reason string `json:"reason" binding:"required,max=500"`
+}
+
+// VerifyUserContactRequest 手动标记邮箱或手机号已验证
+type VerifyUserContactRequest struct {
+	Field  string `json:"field" binding:"required,oneof=email phone"`
+	Reason string `json:"reason" binding:"required,max=500"`
+}
+
+// UserActionResponse 用户管理操作响应
+type UserActionResponse struct {
+	Message string             `json:"message"`
+	User    *entities.UserInfo `json:"user"`
+}
+
+// ImpersonateUserRequest 管理员代登录请求，默认签发只读令牌
+type ImpersonateUserRequest struct {
+	Reason      string `json:"reason" binding:"required,max=500"`
+	AllowWrites bool   `json:"allow_writes"` // 允许写操作，需要额外的 users.update 权限；修改密码、资料等敏感操作始终拒绝
+}
+
+// ImpersonationResponse 代登录令牌，不签发刷新令牌
+type ImpersonationResponse struct {
+	AccessToken string             `json:"access_token"`
+	TokenType   string             `json:"token_type"`
+	ExpiresIn   int64              `json:"expires_in"`
+	ReadOnly    bool               `json:"read_only"`
+	User        *entities.UserInfo `json:"user"`
+}
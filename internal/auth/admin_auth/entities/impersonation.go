@@ -0,0 +1,14 @@
+package entities
+
+// ImpersonationSession 管理员代登录记录，写入用户登录记录表，用户可以看到
+type ImpersonationSession struct {
+	UserID     int64
+	AdminID    int64
+	IP         string
+	UserAgent  string
+	DeviceType string
+	OS         string
+	Browser    string
+	Platform   string
+	Reason     string
+}
@@ -0,0 +1,17 @@
+package entities
+
+import "time"
+
+// AdminInvitation 管理员邀请
+type AdminInvitation struct {
+	ID         int64      `json:"id" db:"id"`
+	Email      string     `json:"email" db:"email"`
+	Name       string     `json:"name" db:"name"`
+	Role       string     `json:"role" db:"role"`
+	TokenHash  string     `json:"-" db:"token_hash"`
+	InvitedBy  *int64     `json:"invited_by,omitempty" db:"invited_by"`
+	ExpiresAt  time.Time  `json:"expires_at" db:"expires_at"`
+	AcceptedAt *time.Time `json:"accepted_at,omitempty" db:"accepted_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+}
@@ -21,5 +21,8 @@ type AdminLoginSession struct {
 	Platform     string    `json:"platform" db:"platform"`
 	Status       string    `json:"status" db:"status"`
 	Reason       string    `json:"reason" db:"reason"`
+	RiskScore    int       `json:"risk_score" db:"risk_score"`
+	RiskDecision string    `json:"risk_decision" db:"risk_decision"` // allow | challenge | block，未评估时为空
+	RiskReasons  string    `json:"risk_reasons" db:"risk_reasons"`   // 逗号分隔的风险原因
 	CreatedAt    time.Time `json:"created_at" db:"created_at"`
 }
\ No newline at end of file
@@ -0,0 +1,28 @@
+package entities
+
+import "time"
+
+// 用户管理操作类型
+const (
+	UserActionSuspend       = "suspend"
+	UserActionReactivate    = "reactivate"
+	UserActionForceLogout   = "force_logout"
+	UserActionPasswordReset = "password_reset"
+	UserActionVerifyEmail   = "verify_email"
+	UserActionVerifyPhone   = "verify_phone"
+	UserActionImpersonate   = "impersonate"
+	UserActionUnlock        = "unlock"
+)
+
+// UserStatusHistory 用户状态变更与管理操作记录
+type UserStatusHistory struct {
+	ID         int64      `json:"id" db:"id"`
+	UserID     int64      `json:"user_id" db:"user_id"`
+	AdminID    *int64     `json:"admin_id,omitempty" db:"admin_id"`
+	Action     string     `json:"action" db:"action"`
+	FromStatus string     `json:"from_status" db:"from_status"`
+	ToStatus   string     `json:"to_status" db:"to_status"`
+	Reason     string     `json:"reason" db:"reason"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty" db:"expires_at"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+}
@@ -3,8 +3,11 @@ package admin
 import (
 	"strconv"
 
+	"trusioo_api/internal/audit"
 	"trusioo_api/internal/auth/admin_auth/dto"
+	"trusioo_api/internal/auth/password"
 	"trusioo_api/internal/common"
+	"trusioo_api/internal/middleware"
 
 	"github.com/gin-gonic/gin"
 )
@@ -26,6 +29,8 @@ func NewHandler(service *Service) *Handler {
 // @Param request body AdminLoginRequest true "登录请求参数"
 // @Success 200 {object} common.Response{data=dto.AdminLoginCodeResponse} "验证码发送成功"
 // @Failure 400 {object} common.Response "参数错误或登录失败"
+// @Failure 428 {object} common.Response "请求过多，需要完成挑战后重试，见 POST /api/v1/auth/challenge"
+// @Failure 429 {object} common.Response "登录失败次数过多，响应头 Retry-After 为需要等待的秒数"
 // @Failure 500 {object} common.Response "服务器错误"
 // @Router /api/v1/admin/auth/login [post]
 func (h *Handler) Login(c *gin.Context) {
@@ -41,12 +46,17 @@ func (h *Handler) Login(c *gin.Context) {
 	resp, err := h.service.Login(&req, clientIP, userAgent)
 	if err != nil {
 		switch err {
-		case common.ErrAdminNotFound:
-			common.ValidationError(c, "Admin does not exist")
-		case common.ErrInvalidAdminCredentials:
+		case common.ErrInvalidCredentials:
+			// 管理员不存在与密码错误返回相同的错误，避免枚举管理员账户
 			common.ValidationError(c, "Email or password is incorrect")
+		case common.ErrLoginLocked:
+			common.TooManyRequestsRetryAfter(c, "Too many failed sign-in attempts, please try again later", resp.RetryAfter)
 		case common.ErrAdminInactive:
 			common.ValidationError(c, "Account not activated")
+		case common.ErrCodeTooFrequent:
+			common.TooManyRequests(c, "Verification code requested too frequently, please try again later")
+		case common.ErrCodeBlocked:
+			common.TooManyRequests(c, "Too many failed verification attempts, please try again later")
 		default:
 			common.ServerError(c, err)
 		}
@@ -58,13 +68,14 @@ func (h *Handler) Login(c *gin.Context) {
 
 // LoginVerify 管理员登录第二步
 // @Summary 管理员登录第二步
-// @Description 验证管理员登录验证码并返回访问令牌
+// @Description 验证管理员登录验证码并返回访问令牌；use_cookies 为 true 时令牌写入 HttpOnly Cookie，响应体只返回 csrf_token
 // @Tags 管理员
 // @Accept json
 // @Produce json
 // @Param request body AdminLoginVerifyRequest true "登录验证请求参数"
 // @Success 200 {object} common.Response{data=dto.AdminLoginResponse} "登录成功"
 // @Failure 400 {object} common.Response "参数错误或验证失败"
+// @Failure 403 {object} common.Response "同时登录的设备数量已达上限，或可疑登录已被拦截"
 // @Failure 500 {object} common.Response "服务器错误"
 // @Router /api/v1/admin/auth/login/verify [post]
 func (h *Handler) LoginVerify(c *gin.Context) {
@@ -80,33 +91,54 @@ func (h *Handler) LoginVerify(c *gin.Context) {
 	resp, err := h.service.LoginVerify(&req, clientIP, userAgent)
 	if err != nil {
 		switch err {
-		case common.ErrAdminNotFound:
-			common.ValidationError(c, "Admin does not exist")
-		case common.ErrInvalidCode:
+		case common.ErrAdminNotFound, common.ErrInvalidCode:
 			common.ValidationError(c, "Invalid or expired verification code")
+		case common.ErrCodeExpired:
+			common.ValidationError(c, "Verification code expired, please request a new one")
+		case common.ErrCodeBlocked:
+			common.TooManyRequests(c, "Too many failed verification attempts, please try again later")
+		case common.ErrSessionLimitReached:
+			common.Forbidden(c, "Maximum number of signed-in devices reached, please sign out on another device first")
+		case common.ErrLoginBlocked:
+			common.Forbidden(c, "Login blocked due to suspicious activity, please check your email")
 		default:
 			common.ServerError(c, err)
 		}
 		return
 	}
 
+	if req.UseCookies {
+		csrfToken, err := middleware.SetAdminSessionCookies(c, resp.AccessToken, resp.RefreshToken)
+		if err != nil {
+			common.ServerError(c, err)
+			return
+		}
+		resp.AccessToken, resp.RefreshToken = "", ""
+		resp.CSRFToken = csrfToken
+	}
+
 	common.Success(c, resp)
 }
 
 // RefreshToken 刷新访问令牌
 // @Summary 刷新访问令牌
-// @Description 使用刷新令牌获取新的访问令牌
+// @Description 使用刷新令牌获取新的访问令牌；使用 Cookie 会话时从 Cookie 读取刷新令牌并写入新的访问令牌 Cookie，需要 X-CSRF-Token 请求头
 // @Tags 管理员
 // @Accept json
 // @Produce json
-// @Param request body RefreshTokenRequest true "刷新令牌请求参数"
+// @Param request body RefreshTokenRequest false "刷新令牌请求参数，使用 Cookie 会话时省略"
 // @Success 200 {object} common.Response{data=dto.AdminLoginResponse} "刷新成功"
 // @Failure 400 {object} common.Response "参数错误或令牌无效"
+// @Failure 403 {object} common.Response "CSRF 令牌无效"
+// @Failure 401 {object} common.Response "刷新令牌无效，或会话因其他设备登录超过上限已被踢出"
 // @Failure 500 {object} common.Response "服务器错误"
 // @Router /api/v1/admin/auth/refresh [post]
 func (h *Handler) RefreshToken(c *gin.Context) {
 	var req dto.RefreshTokenRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	cookieToken := middleware.AdminRefreshCookieValue(c)
+	if cookieToken != "" {
+		req.RefreshToken = cookieToken
+	} else if err := c.ShouldBindJSON(&req); err != nil {
 		common.ValidationError(c, err.Error())
 		return
 	}
@@ -116,6 +148,8 @@ func (h *Handler) RefreshToken(c *gin.Context) {
 		switch err {
 		case common.ErrTokenInvalid, common.ErrRefreshTokenInvalid:
 			common.Unauthorized(c, "Invalid refresh token")
+		case common.ErrSessionEvicted:
+			common.Unauthorized(c, "Signed out because the account signed in on another device")
 		case common.ErrAdminNotFound:
 			common.ValidationError(c, "Admin not found")
 		case common.ErrAdminInactive:
@@ -126,9 +160,44 @@ func (h *Handler) RefreshToken(c *gin.Context) {
 		return
 	}
 
+	if cookieToken != "" {
+		middleware.SetAdminAccessCookie(c, resp.AccessToken)
+		resp.AccessToken, resp.RefreshToken = "", ""
+	}
+
 	common.Success(c, resp)
 }
 
+// Logout 管理员退出登录
+// @Summary 管理员退出登录
+// @Description 使刷新令牌失效并清除 Cookie 会话；使用 Cookie 会话时从 Cookie 读取刷新令牌，需要 X-CSRF-Token 请求头
+// @Tags 管理员
+// @Accept json
+// @Produce json
+// @Param request body AdminLogoutRequest false "退出登录请求参数，使用 Cookie 会话时省略"
+// @Success 200 {object} common.Response "退出成功"
+// @Failure 400 {object} common.Response "参数错误"
+// @Failure 403 {object} common.Response "CSRF 令牌无效"
+// @Failure 500 {object} common.Response "服务器错误"
+// @Router /api/v1/admin/auth/logout [post]
+func (h *Handler) Logout(c *gin.Context) {
+	var req dto.AdminLogoutRequest
+	if cookieToken := middleware.AdminRefreshCookieValue(c); cookieToken != "" {
+		req.RefreshToken = cookieToken
+	} else if err := c.ShouldBindJSON(&req); err != nil || req.RefreshToken == "" {
+		common.ValidationError(c, "Refresh token required")
+		return
+	}
+
+	if err := h.service.Logout(req.RefreshToken); err != nil {
+		common.ServerError(c, err)
+		return
+	}
+
+	middleware.ClearAdminSessionCookies(c)
+	common.SuccessWithMessage(c, "Logged out successfully", nil)
+}
+
 // GetProfile 获取管理员资料
 // @Summary 获取管理员资料
 // @Description 获取当前登录管理员的个人资料
@@ -161,6 +230,113 @@ func (h *Handler) GetProfile(c *gin.Context) {
 	common.Success(c, admin)
 }
 
+// SendReauthCode 管理员重新验证身份 - 发送邮箱验证码
+// @Summary 发送管理员重新验证身份的验证码
+// @Description 向当前管理员邮箱发送验证码，用于通过验证码重新验证身份
+// @Tags 管理员
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body dto.AdminReauthCodeRequest false "重新验证身份验证码请求参数"
+// @Success 200 {object} common.Response{data=dto.AdminReauthCodeResponse} "验证码发送成功"
+// @Failure 400 {object} common.Response "账户未激活"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 429 {object} common.Response "请求过于频繁"
+// @Failure 500 {object} common.Response "服务器错误"
+// @Router /api/v1/admin/auth/reauth/code [post]
+func (h *Handler) SendReauthCode(c *gin.Context) {
+	adminID, exists := c.Get("user_id")
+	if !exists {
+		common.Unauthorized(c, "Admin not authenticated")
+		return
+	}
+
+	var req dto.AdminReauthCodeRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			common.ValidationError(c, err.Error())
+			return
+		}
+	}
+
+	resp, err := h.service.SendReauthCode(adminID.(int64), &req)
+	if err != nil {
+		handleReauthError(c, err)
+		return
+	}
+
+	common.Success(c, resp)
+}
+
+// Reauthenticate 管理员重新验证身份
+// @Summary 管理员重新验证身份
+// @Description 校验当前密码或管理员邮箱验证码（二选一），签发携带 auth_time 和 acr 的短期提升令牌；查看用户详情、批量删除图片等敏感接口返回 401 和 reauth_required 时使用此令牌重试；使用 Cookie 会话时提升令牌写入 HttpOnly Cookie，响应中不返回令牌
+// @Tags 管理员
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body dto.AdminReauthRequest true "当前密码或验证码"
+// @Success 200 {object} common.Response{data=dto.AdminElevatedTokenResponse} "验证成功"
+// @Failure 400 {object} common.Response "参数错误、密码错误或验证码错误"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 429 {object} common.Response "验证失败次数过多或密码错误次数过多"
+// @Failure 500 {object} common.Response "服务器错误"
+// @Router /api/v1/admin/auth/reauth [post]
+func (h *Handler) Reauthenticate(c *gin.Context) {
+	adminID, exists := c.Get("user_id")
+	if !exists {
+		common.Unauthorized(c, "Admin not authenticated")
+		return
+	}
+
+	var req dto.AdminReauthRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ValidationError(c, err.Error())
+		return
+	}
+
+	resp, err := h.service.Reauthenticate(adminID.(int64), &req, c.ClientIP())
+	if err != nil {
+		if err == common.ErrLoginLocked {
+			common.TooManyRequestsRetryAfter(c, "Too many failed sign-in attempts, please try again later", resp.RetryAfter)
+			return
+		}
+		handleReauthError(c, err)
+		return
+	}
+
+	if middleware.UsingAdminCookieSession(c) {
+		middleware.SetAdminElevatedCookie(c, resp.AccessToken, int(resp.ExpiresIn))
+		resp.AccessToken = ""
+	}
+
+	common.Success(c, resp)
+}
+
+// handleReauthError 管理员重新验证身份的通用错误响应
+func handleReauthError(c *gin.Context, err error) {
+	switch err {
+	case common.ErrValidation:
+		common.ValidationError(c, "Provide either password or code")
+	case common.ErrAdminNotFound:
+		common.NotFound(c, "Admin not found")
+	case common.ErrAdminInactive:
+		common.ValidationError(c, "Account not activated")
+	case common.ErrInvalidAdminCredentials:
+		common.ValidationError(c, "Current password is incorrect")
+	case common.ErrInvalidCode:
+		common.ValidationError(c, "Invalid or expired verification code")
+	case common.ErrCodeExpired:
+		common.ValidationError(c, "Verification code expired, please request a new one")
+	case common.ErrCodeTooFrequent:
+		common.TooManyRequests(c, "Verification code requested too frequently, please try again later")
+	case common.ErrCodeBlocked:
+		common.TooManyRequests(c, "Too many failed verification attempts, please try again later")
+	default:
+		common.ServerError(c, err)
+	}
+}
+
 // GetUserStats 获取用户统计
 // @Summary 获取用户统计
 // @Description 获取用户统计数据
@@ -217,7 +393,7 @@ func (h *Handler) GetUserList(c *gin.Context) {
 
 // GetUserDetail 获取用户详情
 // @Summary 获取用户详情
-// @Description 根据用户ID获取用户详细信息
+// @Description 根据用户ID获取用户详细信息，每次查看都会写入审计记录
 // @Tags 管理员-用户管理
 // @Accept json
 // @Produce json
@@ -225,19 +401,17 @@ func (h *Handler) GetUserList(c *gin.Context) {
 // @Param id path int true "用户ID"
 // @Success 200 {object} common.Response{data=entities.UserInfo} "获取成功"
 // @Failure 400 {object} common.Response "参数错误"
-// @Failure 401 {object} common.Response "未授权"
+// @Failure 401 {object} common.Response "未授权或需要重新验证身份（reauth_required）"
 // @Failure 404 {object} common.Response "用户不存在"
 // @Failure 500 {object} common.Response "服务器错误"
 // @Router /api/v1/admin/users/{id} [get]
 func (h *Handler) GetUserDetail(c *gin.Context) {
-	userIDStr := c.Param("id")
-	userID, err := strconv.ParseInt(userIDStr, 10, 64)
-	if err != nil {
-		common.ValidationError(c, "Invalid user ID")
+	adminID, userID, ok := userActionIDs(c)
+	if !ok {
 		return
 	}
 
-	user, err := h.service.GetUserByID(userID)
+	user, err := h.service.ViewUserDetail(audit.Context(c), adminID, userID)
 	if err != nil {
 		switch err {
 		case common.ErrUserNotFound:
@@ -271,7 +445,14 @@ func (h *Handler) ForgotPassword(c *gin.Context) {
 
 	resp, err := h.service.ForgotPassword(&req)
 	if err != nil {
-		common.ServerError(c, err)
+		switch err {
+		case common.ErrCodeTooFrequent:
+			common.TooManyRequests(c, "Verification code requested too frequently, please try again later")
+		case common.ErrCodeBlocked:
+			common.TooManyRequests(c, "Too many failed verification attempts, please try again later")
+		default:
+			common.ServerError(c, err)
+		}
 		return
 	}
 
@@ -280,7 +461,7 @@ func (h *Handler) ForgotPassword(c *gin.Context) {
 
 // ResetPassword 管理员忘记密码第二步 - 验证验证码并重置密码
 // @Summary 管理员忘记密码第二步
-// @Description 验证重置密码验证码并设置新密码
+// @Description 验证重置密码验证码并设置新密码，新密码需要满足密码策略且不能与最近使用过的密码相同
 // @Tags 管理员
 // @Accept json
 // @Produce json
@@ -303,11 +484,645 @@ func (h *Handler) ResetPassword(c *gin.Context) {
 			common.ValidationError(c, "Admin not found")
 		case common.ErrInvalidCode:
 			common.ValidationError(c, "Invalid or expired verification code")
+		case common.ErrCodeExpired:
+			common.ValidationError(c, "Verification code expired, please request a new one")
+		case common.ErrCodeBlocked:
+			common.TooManyRequests(c, "Too many failed verification attempts, please try again later")
 		default:
-			common.ServerError(c, err)
+			if reasons, ok := password.Violations(err); ok {
+				common.PasswordRejected(c, reasons)
+			} else {
+				common.ServerError(c, err)
+			}
+		}
+		return
+	}
+
+	common.Success(c, resp)
+}
+// SuspendUser 暂停用户
+// @Summary 暂停用户
+// @Description 暂停用户账户并使其全部刷新令牌失效，可设置到期时间实现临时暂停
+// @Tags 管理员-用户管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "用户ID"
+// @Param request body dto.SuspendUserRequest true "暂停原因与到期时间"
+// @Success 200 {object} common.Response{data=dto.UserActionResponse} "暂停成功"
+// @Failure 400 {object} common.Response "参数错误"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足"
+// @Failure 404 {object} common.Response "用户不存在"
+// @Failure 500 {object} common.Response "服务器错误"
+// @Router /api/v1/admin/users/{id}/suspend [post]
+func (h *Handler) SuspendUser(c *gin.Context) {
+	adminID, userID, ok := userActionIDs(c)
+	if !ok {
+		return
+	}
+
+	var req dto.SuspendUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ValidationError(c, err.Error())
+		return
+	}
+
+	resp, err := h.service.SuspendUser(audit.Context(c), adminID, userID, &req)
+	if err != nil {
+		if err == common.ErrValidation {
+			common.ValidationError(c, "Reason is required and suspension end time must be in the future")
+			return
+		}
+		handleUserActionError(c, err)
+		return
+	}
+
+	common.Success(c, resp)
+}
+
+// ReactivateUser 恢复用户
+// @Summary 恢复用户
+// @Description 恢复被暂停或停用的用户账户
+// @Tags 管理员-用户管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "用户ID"
+// @Param request body dto.UserActionRequest true "操作原因"
+// @Success 200 {object} common.Response{data=dto.UserActionResponse} "恢复成功"
+// @Failure 400 {object} common.Response "参数错误或用户已是激活状态"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足"
+// @Failure 404 {object} common.Response "用户不存在"
+// @Failure 500 {object} common.Response "服务器错误"
+// @Router /api/v1/admin/users/{id}/reactivate [post]
+func (h *Handler) ReactivateUser(c *gin.Context) {
+	adminID, userID, ok := userActionIDs(c)
+	if !ok {
+		return
+	}
+
+	var req dto.UserActionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ValidationError(c, err.Error())
+		return
+	}
+
+	resp, err := h.service.ReactivateUser(audit.Context(c), adminID, userID, &req)
+	if err != nil {
+		if err == common.ErrValidation {
+			common.ValidationError(c, "Reason is required and user must not already be active")
+			return
+		}
+		handleUserActionError(c, err)
+		return
+	}
+
+	common.Success(c, resp)
+}
+
+// ForceLogoutUser 强制用户退出登录
+// @Summary 强制用户退出登录
+// @Description 使用户全部刷新令牌失效，所有设备需要重新登录
+// @Tags 管理员-用户管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "用户ID"
+// @Param request body dto.UserActionRequest true "操作原因"
+// @Success 200 {object} common.Response{data=dto.UserActionResponse} "操作成功"
+// @Failure 400 {object} common.Response "参数错误"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足"
+// @Failure 404 {object} common.Response "用户不存在"
+// @Failure 500 {object} common.Response "服务器错误"
+// @Router /api/v1/admin/users/{id}/logout [post]
+func (h *Handler) ForceLogoutUser(c *gin.Context) {
+	adminID, userID, ok := userActionIDs(c)
+	if !ok {
+		return
+	}
+
+	var req dto.UserActionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ValidationError(c, err.Error())
+		return
+	}
+
+	resp, err := h.service.ForceLogoutUser(audit.Context(c), adminID, userID, &req)
+	if err != nil {
+		handleUserActionError(c, err)
+		return
+	}
+
+	common.Success(c, resp)
+}
+
+// UnlockUser 解除用户登录锁定
+// @Summary 解除用户登录锁定
+// @Description 清除用户因密码登录失败次数过多产生的锁定和等待时间，不影响按IP的锁定
+// @Tags 管理员-用户管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "用户ID"
+// @Param request body dto.UserActionRequest true "操作原因"
+// @Success 200 {object} common.Response{data=dto.UserActionResponse} "操作成功"
+// @Failure 400 {object} common.Response "参数错误"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足"
+// @Failure 404 {object} common.Response "用户不存在"
+// @Failure 500 {object} common.Response "服务器错误"
+// @Router /api/v1/admin/users/{id}/unlock [post]
+func (h *Handler) UnlockUser(c *gin.Context) {
+	adminID, userID, ok := userActionIDs(c)
+	if !ok {
+		return
+	}
+
+	var req dto.UserActionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ValidationError(c, err.Error())
+		return
+	}
+
+	resp, err := h.service.UnlockUser(audit.Context(c), adminID, userID, &req)
+	if err != nil {
+		handleUserActionError(c, err)
+		return
+	}
+
+	common.Success(c, resp)
+}
+
+// SendUserPasswordReset 发送重置密码邮件
+// @Summary 发送重置密码邮件
+// @Description 向用户邮箱发送重置密码验证码
+// @Tags 管理员-用户管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "用户ID"
+// @Param request body dto.UserActionRequest true "操作原因"
+// @Success 200 {object} common.Response{data=dto.UserActionResponse} "发送成功"
+// @Failure 400 {object} common.Response "参数错误"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足"
+// @Failure 404 {object} common.Response "用户不存在"
+// @Failure 429 {object} common.Response "发送过于频繁"
+// @Failure 500 {object} common.Response "服务器错误"
+// @Router /api/v1/admin/users/{id}/password-reset [post]
+func (h *Handler) SendUserPasswordReset(c *gin.Context) {
+	adminID, userID, ok := userActionIDs(c)
+	if !ok {
+		return
+	}
+
+	var req dto.UserActionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ValidationError(c, err.Error())
+		return
+	}
+
+	resp, err := h.service.SendUserPasswordReset(audit.Context(c), adminID, userID, &req)
+	if err != nil {
+		switch err {
+		case common.ErrCodeTooFrequent:
+			common.TooManyRequests(c, "Verification code requested too frequently, please try again later")
+		case common.ErrCodeBlocked:
+			common.TooManyRequests(c, "Too many failed verification attempts, please try again later")
+		default:
+			handleUserActionError(c, err)
+		}
+		return
+	}
+
+	common.Success(c, resp)
+}
+
+// VerifyUserContact 手动标记邮箱或手机号已验证
+// @Summary 手动标记邮箱或手机号已验证
+// @Description 将用户邮箱或手机号标记为已验证
+// @Tags 管理员-用户管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "用户ID"
+// @Param request body dto.VerifyUserContactRequest true "验证字段与原因"
+// @Success 200 {object} common.Response{data=dto.UserActionResponse} "操作成功"
+// @Failure 400 {object} common.Response "参数错误或用户未绑定手机号"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足"
+// @Failure 404 {object} common.Response "用户不存在"
+// @Failure 500 {object} common.Response "服务器错误"
+// @Router /api/v1/admin/users/{id}/verify [post]
+func (h *Handler) VerifyUserContact(c *gin.Context) {
+	adminID, userID, ok := userActionIDs(c)
+	if !ok {
+		return
+	}
+
+	var req dto.VerifyUserContactRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ValidationError(c, err.Error())
+		return
+	}
+
+	resp, err := h.service.VerifyUserContact(audit.Context(c), adminID, userID, &req)
+	if err != nil {
+		if err == common.ErrInvalidPhone {
+			common.ValidationError(c, "User has no phone number")
+			return
+		}
+		handleUserActionError(c, err)
+		return
+	}
+
+	common.Success(c, resp)
+}
+
+// GetUserStatusHistory 获取用户状态变更记录
+// @Summary 获取用户状态变更记录
+// @Description 获取用户的状态变更与管理操作记录，包含操作管理员、原因和时间
+// @Tags 管理员-用户管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "用户ID"
+// @Success 200 {object} common.Response{data=[]entities.UserStatusHistory} "获取成功"
+// @Failure 400 {object} common.Response "参数错误"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足"
+// @Failure 404 {object} common.Response "用户不存在"
+// @Failure 500 {object} common.Response "服务器错误"
+// @Router /api/v1/admin/users/{id}/status-history [get]
+func (h *Handler) GetUserStatusHistory(c *gin.Context) {
+	adminID, userID, ok := userActionIDs(c)
+	if !ok {
+		return
+	}
+
+	history, err := h.service.GetUserStatusHistory(c.Request.Context(), adminID, userID)
+	if err != nil {
+		handleUserActionError(c, err)
+		return
+	}
+
+	common.Success(c, history)
+}
+
+// ImpersonateUser 管理员代登录
+// @Summary 管理员代登录
+// @Description 以用户身份签发短期访问令牌用于排查问题。默认只读，修改密码、资料等敏感接口始终拒绝代登录令牌；代登录会出现在用户的登录记录中
+// @Tags 管理员-用户管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "用户ID"
+// @Param request body dto.ImpersonateUserRequest true "代登录原因"
+// @Success 200 {object} common.Response{data=dto.ImpersonationResponse} "签发成功"
+// @Failure 400 {object} common.Response "参数错误"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足"
+// @Failure 404 {object} common.Response "用户不存在"
+// @Failure 500 {object} common.Response "服务器错误"
+// @Router /api/v1/admin/users/{id}/impersonate [post]
+func (h *Handler) ImpersonateUser(c *gin.Context) {
+	adminID, userID, ok := userActionIDs(c)
+	if !ok {
+		return
+	}
+
+	var req dto.ImpersonateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ValidationError(c, err.Error())
+		return
+	}
+
+	resp, err := h.service.ImpersonateUser(audit.Context(c), adminID, userID, &req, c.ClientIP(), c.GetHeader("User-Agent"))
+	if err != nil {
+		handleUserActionError(c, err)
+		return
+	}
+
+	common.Success(c, resp)
+}
+
+// userActionIDs 获取当前管理员ID和路径中的用户ID，失败时已写入响应
+func userActionIDs(c *gin.Context) (int64, int64, bool) {
+	adminID, exists := c.Get("user_id")
+	if !exists {
+		common.Unauthorized(c, "Admin not authenticated")
+		return 0, 0, false
+	}
+
+	userID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		common.ValidationError(c, "Invalid user ID")
+		return 0, 0, false
+	}
+
+	return adminID.(int64), userID, true
+}
+
+// handleUserActionError 用户管理操作的通用错误响应
+func handleUserActionError(c *gin.Context, err error) {
+	switch err {
+	case common.ErrInsufficientPermissions:
+		common.Forbidden(c, "Insufficient permissions")
+	case common.ErrUserNotFound:
+		common.NotFound(c, "User not found")
+	case common.ErrValidation:
+		common.ValidationError(c, "Reason is required")
+	default:
+		common.ServerError(c, err)
+	}
+}
+
+// AcceptInvitation 接受管理员邀请
+// @Summary 接受管理员邀请
+// @Description 使用邀请邮件中的令牌设置密码并创建管理员账户，之后通过正常登录流程登录
+// @Tags 管理员
+// @Accept json
+// @Produce json
+// @Param request body dto.AcceptInvitationRequest true "邀请令牌和密码"
+// @Success 200 {object} common.Response{data=dto.AcceptInvitationResponse} "接受成功"
+// @Failure 400 {object} common.Response "参数错误、邀请无效、已过期或密码不满足密码策略（password_rejected）"
+// @Failure 500 {object} common.Response "服务器错误"
+// @Router /api/v1/admin/auth/invitations/accept [post]
+func (h *Handler) AcceptInvitation(c *gin.Context) {
+	var req dto.AcceptInvitationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ValidationError(c, err.Error())
+		return
+	}
+
+	resp, err := h.service.AcceptInvitation(&req)
+	if err != nil {
+		switch err {
+		case common.ErrInvitationInvalid:
+			common.ValidationError(c, "Invitation is invalid or has already been used")
+		case common.ErrTokenExpired:
+			common.ValidationError(c, "Invitation has expired")
+		case common.ErrAdminEmailExists:
+			common.ValidationError(c, "Admin email already exists")
+		default:
+			if reasons, ok := password.Violations(err); ok {
+				common.PasswordRejected(c, reasons)
+			} else {
+				common.ServerError(c, err)
+			}
 		}
 		return
 	}
 
 	common.Success(c, resp)
-}
\ No newline at end of file
+}
+
+// InviteAdmin 邀请管理员
+// @Summary 邀请管理员
+// @Description 向邮箱发送一次性邀请链接，同一邮箱此前未使用的邀请同时失效；只能邀请为自己权限范围内的角色
+// @Tags 管理员-账户管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body dto.InviteAdminRequest true "被邀请人信息"
+// @Success 200 {object} common.Response{data=entities.AdminInvitation} "邀请已发送"
+// @Failure 400 {object} common.Response "参数错误或邮箱已注册"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足"
+// @Failure 404 {object} common.Response "角色不存在"
+// @Failure 500 {object} common.Response "服务器错误"
+// @Router /api/v1/admin/admins/invitations [post]
+func (h *Handler) InviteAdmin(c *gin.Context) {
+	actorID, exists := c.Get("user_id")
+	if !exists {
+		common.Unauthorized(c, "Admin not authenticated")
+		return
+	}
+
+	var req dto.InviteAdminRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ValidationError(c, err.Error())
+		return
+	}
+
+	invitation, err := h.service.InviteAdmin(audit.Context(c), actorID.(int64), &req)
+	if err != nil {
+		switch err {
+		case common.ErrAdminEmailExists:
+			common.ValidationError(c, "Admin email already exists")
+		case common.ErrNotFound:
+			common.NotFound(c, "Role not found")
+		default:
+			handleAdminActionError(c, err)
+		}
+		return
+	}
+
+	common.Success(c, invitation)
+}
+
+// ListInvitations 获取待接受的邀请
+// @Summary 获取待接受的邀请
+// @Description 获取尚未接受、撤销或过期的管理员邀请
+// @Tags 管理员-账户管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} common.Response{data=[]entities.AdminInvitation} "获取成功"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足"
+// @Failure 500 {object} common.Response "服务器错误"
+// @Router /api/v1/admin/admins/invitations [get]
+func (h *Handler) ListInvitations(c *gin.Context) {
+	actorID, exists := c.Get("user_id")
+	if !exists {
+		common.Unauthorized(c, "Admin not authenticated")
+		return
+	}
+
+	invitations, err := h.service.ListInvitations(c.Request.Context(), actorID.(int64))
+	if err != nil {
+		handleAdminActionError(c, err)
+		return
+	}
+
+	common.Success(c, invitations)
+}
+
+// RevokeInvitation 撤销邀请
+// @Summary 撤销邀请
+// @Description 撤销尚未接受的管理员邀请，邀请链接立即失效
+// @Tags 管理员-账户管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "邀请ID"
+// @Success 200 {object} common.Response "撤销成功"
+// @Failure 400 {object} common.Response "参数错误"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足"
+// @Failure 404 {object} common.Response "邀请不存在或已使用"
+// @Failure 500 {object} common.Response "服务器错误"
+// @Router /api/v1/admin/admins/invitations/{id} [delete]
+func (h *Handler) RevokeInvitation(c *gin.Context) {
+	actorID, exists := c.Get("user_id")
+	if !exists {
+		common.Unauthorized(c, "Admin not authenticated")
+		return
+	}
+
+	invitationID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		common.ValidationError(c, "Invalid invitation ID")
+		return
+	}
+
+	if err := h.service.RevokeInvitation(audit.Context(c), actorID.(int64), invitationID); err != nil {
+		if err == common.ErrNotFound {
+			common.NotFound(c, "Invitation not found")
+			return
+		}
+		handleAdminActionError(c, err)
+		return
+	}
+
+	common.SuccessWithMessage(c, "Invitation revoked", nil)
+}
+
+// ListAdmins 获取管理员列表
+// @Summary 获取管理员列表
+// @Description 获取全部管理员，可按状态筛选
+// @Tags 管理员-账户管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param status query string false "状态筛选" Enums(active, inactive)
+// @Success 200 {object} common.Response{data=[]entities.Admin} "获取成功"
+// @Failure 400 {object} common.Response "参数错误"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足"
+// @Failure 500 {object} common.Response "服务器错误"
+// @Router /api/v1/admin/admins [get]
+func (h *Handler) ListAdmins(c *gin.Context) {
+	actorID, exists := c.Get("user_id")
+	if !exists {
+		common.Unauthorized(c, "Admin not authenticated")
+		return
+	}
+
+	var req dto.AdminListRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		common.ValidationError(c, err.Error())
+		return
+	}
+
+	admins, err := h.service.ListAdmins(c.Request.Context(), actorID.(int64), &req)
+	if err != nil {
+		handleAdminActionError(c, err)
+		return
+	}
+
+	common.Success(c, admins)
+}
+
+// DeactivateAdmin 停用管理员
+// @Summary 停用管理员
+// @Description 停用管理员账户并使其刷新令牌失效；不能停用自己或最后一个激活的超级管理员
+// @Tags 管理员-账户管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "管理员ID"
+// @Success 200 {object} common.Response{data=dto.AdminActionResponse} "停用成功"
+// @Failure 400 {object} common.Response "参数错误、已停用或不能停用最后一个超级管理员"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足"
+// @Failure 404 {object} common.Response "管理员不存在"
+// @Failure 500 {object} common.Response "服务器错误"
+// @Router /api/v1/admin/admins/{id}/deactivate [post]
+func (h *Handler) DeactivateAdmin(c *gin.Context) {
+	actorID, adminID, ok := adminActionIDs(c)
+	if !ok {
+		return
+	}
+
+	resp, err := h.service.DeactivateAdmin(audit.Context(c), actorID, adminID)
+	if err != nil {
+		if err == common.ErrValidation {
+			common.ValidationError(c, "Cannot deactivate yourself or an inactive admin")
+			return
+		}
+		handleAdminActionError(c, err)
+		return
+	}
+
+	common.Success(c, resp)
+}
+
+// ReactivateAdmin 恢复管理员
+// @Summary 恢复管理员
+// @Description 恢复被停用的管理员账户
+// @Tags 管理员-账户管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "管理员ID"
+// @Success 200 {object} common.Response{data=dto.AdminActionResponse} "恢复成功"
+// @Failure 400 {object} common.Response "参数错误或管理员已是激活状态"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足"
+// @Failure 404 {object} common.Response "管理员不存在"
+// @Failure 500 {object} common.Response "服务器错误"
+// @Router /api/v1/admin/admins/{id}/reactivate [post]
+func (h *Handler) ReactivateAdmin(c *gin.Context) {
+	actorID, adminID, ok := adminActionIDs(c)
+	if !ok {
+		return
+	}
+
+	resp, err := h.service.ReactivateAdmin(audit.Context(c), actorID, adminID)
+	if err != nil {
+		if err == common.ErrValidation {
+			common.ValidationError(c, "Cannot reactivate yourself or an active admin")
+			return
+		}
+		handleAdminActionError(c, err)
+		return
+	}
+
+	common.Success(c, resp)
+}
+
+// adminActionIDs 获取当前管理员ID和路径中的目标管理员ID
+func adminActionIDs(c *gin.Context) (int64, int64, bool) {
+	actorID, exists := c.Get("user_id")
+	if !exists {
+		common.Unauthorized(c, "Admin not authenticated")
+		return 0, 0, false
+	}
+
+	adminID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		common.ValidationError(c, "Invalid admin ID")
+		return 0, 0, false
+	}
+
+	return actorID.(int64), adminID, true
+}
+
+// handleAdminActionError 管理员账户管理操作的通用错误响应
+func handleAdminActionError(c *gin.Context, err error) {
+	switch err {
+	case common.ErrInsufficientPermissions:
+		common.Forbidden(c, "Insufficient permissions")
+	case common.ErrAdminNotFound:
+		common.NotFound(c, "Admin not found")
+	case common.ErrLastSuperAdmin:
+		common.ValidationError(c, "Cannot remove the last active super admin")
+	default:
+		common.ServerError(c, err)
+	}
+}
@@ -0,0 +1,160 @@
+package admin
+
+import (
+	"trusioo_api/internal/auth/admin_auth/entities"
+	"trusioo_api/internal/auth/identity"
+)
+
+// identityCore 管理员的认证核心，令牌、会话上限、密码登录失败锁定、登录风险评估、IP定位、密码策略和重置密码与用户共用同一实现
+// 没有通过 NewService 创建的 Service 按当前依赖临时创建
+func (s *Service) identityCore() *identity.Core {
+	if s.identity != nil {
+		return s.identity
+	}
+	return s.newIdentityCore()
+}
+
+func (s *Service) newIdentityCore() *identity.Core {
+	return identity.New(identity.PrincipalAdmin, identityStore{repo: s.adminRepo}, s.verificationService, s.ipinfoClient, identity.Options{
+		SessionLimit:    s.sessionLimiter,
+		LoginGuard:      s.loginGuard,
+		RiskEvaluator:   s.riskEvaluator,
+		RiskHistorySize: s.riskHistorySize,
+		PasswordPolicy:  s.passwordPolicy,
+	})
+}
+
+// identityAccount 签发令牌所需的账户信息
+func identityAccount(admin *entities.Admin) identity.Account {
+	return identity.Account{ID: admin.ID, Email: admin.Email, Role: admin.Role}
+}
+
+// identityStore 将管理员仓储适配为 identity.Store
+type identityStore struct {
+	repo AdminRepository
+}
+
+func (s identityStore) CreateRefreshToken(token *identity.RefreshToken) error {
+	return s.repo.CreateRefreshToken(&entities.AdminRefreshToken{
+		AdminID:    token.AccountID,
+		Token:      token.Token,
+		IsValid:    true,
+		ExpiresAt:  token.ExpiresAt,
+		DeviceInfo: token.DeviceInfo,
+		CreatedAt:  token.CreatedAt,
+	})
+}
+
+func (s identityStore) GetValidRefreshToken(token string) (*identity.RefreshToken, error) {
+	refreshToken, err := s.repo.GetValidRefreshToken(token)
+	if err != nil {
+		return nil, err
+	}
+	return toIdentityRefreshToken(refreshToken), nil
+}
+
+func (s identityStore) ListActiveRefreshTokens(adminID int64) ([]*identity.RefreshToken, error) {
+	tokens, err := s.repo.ListActiveRefreshTokens(adminID)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*identity.RefreshToken, len(tokens))
+	for i, token := range tokens {
+		result[i] = toIdentityRefreshToken(token)
+	}
+	return result, nil
+}
+
+func (s identityStore) EvictRefreshTokens(adminID int64, ids []int64) error {
+	return s.repo.EvictRefreshTokens(adminID, ids)
+}
+
+func (s identityStore) IsRefreshTokenEvicted(token string) (bool, error) {
+	return s.repo.IsRefreshTokenEvicted(token)
+}
+
+func (s identityStore) InvalidateAllRefreshTokens(adminID int64) error {
+	return s.repo.InvalidateAllRefreshTokens(adminID)
+}
+
+func (s identityStore) UpdatePassword(adminID int64, passwordHash string) error {
+	return s.repo.UpdatePassword(adminID, passwordHash)
+}
+
+func (s identityStore) ListPasswordHistory(adminID int64, limit int) ([]string, error) {
+	return s.repo.ListPasswordHistory(adminID, limit)
+}
+
+func (s identityStore) AddPasswordHistory(adminID int64, passwordHash string, keep int) error {
+	return s.repo.AddPasswordHistory(adminID, passwordHash, keep)
+}
+
+func (s identityStore) CreateLoginSession(session *identity.LoginSession) error {
+	return s.repo.CreateLoginSession(&entities.AdminLoginSession{
+		AdminID:      session.AccountID,
+		IP:           session.IP,
+		Country:      session.Country,
+		City:         session.City,
+		Region:       session.Region,
+		Timezone:     session.Timezone,
+		Organization: session.Organization,
+		Location:     session.Coordinates,
+		UserAgent:    session.UserAgent,
+		DeviceType:   session.DeviceType,
+		OS:           session.OS,
+		Browser:      session.Browser,
+		Platform:     session.Platform,
+		Status:       session.Status,
+		Reason:       session.Reason,
+		IsTrusted:    session.IsTrusted,
+		RiskScore:    session.RiskScore,
+		RiskDecision: session.RiskDecision,
+		RiskReasons:  session.RiskReasons,
+		CreatedAt:    session.CreatedAt,
+	})
+}
+
+func (s identityStore) GetRecentLoginSessions(adminID int64, limit int) ([]*identity.LoginSession, error) {
+	sessions, err := s.repo.GetRecentLoginSessions(adminID, limit)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*identity.LoginSession, len(sessions))
+	for i, session := range sessions {
+		result[i] = &identity.LoginSession{
+			AccountID: session.AdminID,
+			IP:        session.IP,
+			Location: identity.Location{
+				Country:      session.Country,
+				City:         session.City,
+				Region:       session.Region,
+				Timezone:     session.Timezone,
+				Organization: session.Organization,
+				Coordinates:  session.Location,
+			},
+			UserAgent: session.UserAgent,
+			Device: identity.Device{
+				DeviceType: session.DeviceType,
+				OS:         session.OS,
+				Browser:    session.Browser,
+				Platform:   session.Platform,
+			},
+			Status:    session.Status,
+			Reason:    session.Reason,
+			IsTrusted: session.IsTrusted,
+			CreatedAt: session.CreatedAt,
+		}
+	}
+	return result, nil
+}
+
+func toIdentityRefreshToken(token *entities.AdminRefreshToken) *identity.RefreshToken {
+	return &identity.RefreshToken{
+		ID:         token.ID,
+		AccountID:  token.AdminID,
+		Token:      token.Token,
+		ExpiresAt:  token.ExpiresAt,
+		DeviceInfo: token.DeviceInfo,
+		CreatedAt:  token.CreatedAt,
+	}
+}
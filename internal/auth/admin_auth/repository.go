@@ -1,12 +1,20 @@
 package admin
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"strconv"
+	"time"
 
+	"trusioo_api/internal/audit"
+	auditEntities "trusioo_api/internal/audit/entities"
 	"trusioo_api/internal/auth/admin_auth/dto"
 	"trusioo_api/internal/auth/admin_auth/entities"
+	"trusioo_api/internal/common"
 	"trusioo_api/pkg/database"
+
+	"github.com/lib/pq"
 )
 
 // AdminRepository 管理员数据访问接口
@@ -20,19 +28,51 @@ type AdminRepository interface {
 	UpdatePassword(id int64, password string) error
 	UpdateEmailVerified(id int64, verified bool) error
 
+	// PasswordHistory相关
+	ListPasswordHistory(adminID int64, limit int) ([]string, error)
+	AddPasswordHistory(adminID int64, passwordHash string, keep int) error
+
 	// RefreshToken相关
 	CreateRefreshToken(token *entities.AdminRefreshToken) error
 	GetValidRefreshToken(token string) (*entities.AdminRefreshToken, error)
 	InvalidateRefreshToken(token string) error
 	InvalidateAllRefreshTokens(adminID int64) error
+	ListActiveRefreshTokens(adminID int64) ([]*entities.AdminRefreshToken, error)
+	EvictRefreshTokens(adminID int64, ids []int64) error
+	IsRefreshTokenEvicted(token string) (bool, error)
 
 	// LoginSession相关
 	CreateLoginSession(session *entities.AdminLoginSession) error
+	GetRecentLoginSessions(adminID int64, limit int) ([]*entities.AdminLoginSession, error)
 
 	// 用户管理相关
 	GetUserStats() (*dto.UserStats, error)
 	GetUserList(req *dto.UserListRequest) (*dto.UserListResponse, error)
 	GetUserByID(id int64) (*entities.UserInfo, error)
+
+	// 用户管理操作 - 变更、操作记录与审计记录在同一事务中写入
+	UpdateUserStatus(ctx context.Context, status string, suspendedUntil *time.Time, history *entities.UserStatusHistory, entry *auditEntities.Log) error
+	ForceLogoutUser(ctx context.Context, history *entities.UserStatusHistory, entry *auditEntities.Log) error
+	MarkUserEmailVerified(ctx context.Context, history *entities.UserStatusHistory, entry *auditEntities.Log) error
+	MarkUserPhoneVerified(ctx context.Context, history *entities.UserStatusHistory, entry *auditEntities.Log) error
+	CreateUserStatusHistory(ctx context.Context, history *entities.UserStatusHistory, entry *auditEntities.Log) error
+	GetUserStatusHistory(userID int64) ([]*entities.UserStatusHistory, error)
+	RecordImpersonation(ctx context.Context, session *entities.ImpersonationSession, history *entities.UserStatusHistory, entry *auditEntities.Log) error
+	// RecordAudit 单独写入审计记录，用于查看用户详情等不修改数据的操作
+	RecordAudit(ctx context.Context, entry *auditEntities.Log) error
+
+	// 管理员账户管理
+	ListAdmins(status string) ([]*entities.Admin, error)
+	// 管理员账户管理与邀请 - 变更与审计记录在同一事务中写入
+	DeactivateAdmin(ctx context.Context, id int64, entry *auditEntities.Log) error
+	ReactivateAdmin(ctx context.Context, id int64, entry *auditEntities.Log) error
+
+	// 管理员邀请
+	CreateInvitation(ctx context.Context, invitation *entities.AdminInvitation, entry *auditEntities.Log) error
+	GetInvitationByTokenHash(tokenHash string) (*entities.AdminInvitation, error)
+	ListPendingInvitations() ([]*entities.AdminInvitation, error)
+	RevokeInvitation(ctx context.Context, id int64, entry *auditEntities.Log) error
+	AcceptInvitation(invitationID int64, admin *entities.Admin) error
 }
 
 // adminRepository Repository接口的实现
@@ -177,13 +217,48 @@ func (r *adminRepository) InvalidateAllRefreshTokens(adminID int64) error {
 	return err
 }
 
+// ListActiveRefreshTokens 按创建时间正序返回未失效且未过期的刷新令牌
+func (r *adminRepository) ListActiveRefreshTokens(adminID int64) ([]*entities.AdminRefreshToken, error) {
+	tokens := []*entities.AdminRefreshToken{}
+	query := `
+		SELECT * FROM admin_refresh_tokens
+		WHERE admin_id = $1 AND is_valid = true AND expires_at > NOW()
+		ORDER BY created_at ASC, id ASC`
+
+	err := database.DB.Select(&tokens, query, adminID)
+	if err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// EvictRefreshTokens 使超过会话上限的刷新令牌失效并记录踢出时间
+func (r *adminRepository) EvictRefreshTokens(adminID int64, ids []int64) error {
+	_, err := database.DB.Exec(
+		"UPDATE admin_refresh_tokens SET is_valid = false, evicted_at = NOW() WHERE admin_id = $1 AND id = ANY($2) AND is_valid = true",
+		adminID, pq.Array(ids),
+	)
+	return err
+}
+
+// IsRefreshTokenEvicted 刷新令牌是否因超过会话上限被踢出
+func (r *adminRepository) IsRefreshTokenEvicted(token string) (bool, error) {
+	var evicted bool
+	err := database.DB.Get(&evicted,
+		"SELECT EXISTS (SELECT 1 FROM admin_refresh_tokens WHERE token = $1 AND evicted_at IS NOT NULL)",
+		token,
+	)
+	return evicted, err
+}
+
 // LoginSession相关方法
 func (r *adminRepository) CreateLoginSession(session *entities.AdminLoginSession) error {
 	query := `
 		INSERT INTO admin_login_sessions (
 			admin_id, ip, country, city, region, timezone, organization, location,
-			user_agent, device_type, os, browser, is_trusted, platform, status, reason
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+			user_agent, device_type, os, browser, is_trusted, platform, status, reason,
+			risk_score, risk_decision, risk_reasons
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19)
 		RETURNING id, created_at`
 	
 	return database.DB.QueryRow(query,
@@ -203,9 +278,73 @@ func (r *adminRepository) CreateLoginSession(session *entities.AdminLoginSession
 		session.Platform,
 		session.Status,
 		session.Reason,
+		session.RiskScore,
+		session.RiskDecision,
+		session.RiskReasons,
 	).Scan(&session.ID, &session.CreatedAt)
 }
 
+// GetRecentLoginSessions 获取管理员最近的成功登录记录，按时间倒序
+func (r *adminRepository) GetRecentLoginSessions(adminID int64, limit int) ([]*entities.AdminLoginSession, error) {
+	var sessions []*entities.AdminLoginSession
+	query := `
+		SELECT * FROM admin_login_sessions
+		WHERE admin_id = $1 AND status = 'success'
+		ORDER BY created_at DESC
+		LIMIT $2`
+
+	err := database.DB.Select(&sessions, query, adminID, limit)
+	if err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// PasswordHistory相关方法
+
+// ListPasswordHistory 按时间倒序返回最近 limit 个密码哈希
+func (r *adminRepository) ListPasswordHistory(adminID int64, limit int) ([]string, error) {
+	hashes := []string{}
+	query := `
+		SELECT password_hash FROM admin_password_history
+		WHERE admin_id = $1
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2`
+
+	err := database.DB.Select(&hashes, query, adminID, limit)
+	if err != nil {
+		return nil, err
+	}
+	return hashes, nil
+}
+
+// AddPasswordHistory 记录新密码哈希，只保留最近 keep 条
+func (r *adminRepository) AddPasswordHistory(adminID int64, passwordHash string, keep int) error {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(
+		"INSERT INTO admin_password_history (admin_id, password_hash) VALUES ($1, $2)",
+		adminID, passwordHash,
+	); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`
+		DELETE FROM admin_password_history
+		WHERE admin_id = $1 AND id NOT IN (
+			SELECT id FROM admin_password_history
+			WHERE admin_id = $1
+			ORDER BY created_at DESC, id DESC
+			LIMIT $2
+		)`, adminID, keep); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
 // 用户管理相关方法
 func (r *adminRepository) GetUserStats() (*dto.UserStats, error) {
 	var stats dto.UserStats
@@ -228,6 +367,12 @@ func (r *adminRepository) GetUserStats() (*dto.UserStats, error) {
 		return nil, err
 	}
 	
+	// 获取暂停中的用户数
+	err = database.DB.Get(&stats.SuspendedUsers, "SELECT COUNT(*) FROM users WHERE status = 'suspended'")
+	if err != nil {
+		return nil, err
+	}
+	
 	// 获取今日注册用户数
 	err = database.DB.Get(&stats.RegisteredToday, 
 		"SELECT COUNT(*) FROM users WHERE DATE(created_at) = CURRENT_DATE")
@@ -288,8 +433,8 @@ func (r *adminRepository) GetUserList(req *dto.UserListRequest) (*dto.UserListRe
 	}
 	
 	// 构建完整查询
-	baseQuery := `SELECT id, name, email, phone, image_key, status, 
-					email_verified, phone_verified, auto_registered, profile_completed, 
+	baseQuery := `SELECT id, name, email, phone, image_key, status, suspended_until,
+					email_verified, phone_verified, auto_registered, profile_completed, kyc_level,
 					last_login_at, created_at FROM users`
 	
 	whereClause := ""
@@ -328,8 +473,8 @@ func (r *adminRepository) GetUserList(req *dto.UserListRequest) (*dto.UserListRe
 
 func (r *adminRepository) GetUserByID(id int64) (*entities.UserInfo, error) {
 	var user entities.UserInfo
-	query := `SELECT id, name, email, phone, image_key, status, 
-				email_verified, phone_verified, auto_registered, profile_completed, 
+	query := `SELECT id, name, email, phone, image_key, status, suspended_until,
+				email_verified, phone_verified, auto_registered, profile_completed, kyc_level,
 				last_login_at, created_at 
 			  FROM users WHERE id = $1`
 	
@@ -341,4 +486,303 @@ func (r *adminRepository) GetUserByID(id int64) (*entities.UserInfo, error) {
 		return nil, err
 	}
 	return &user, nil
-}
\ No newline at end of file
+}
+
+// userStatement 用户管理操作中的一条更新语句
+type userStatement struct {
+	query string
+	args  []interface{}
+}
+
+// UpdateUserStatus 修改用户状态；暂停时同时使全部刷新令牌失效
+func (r *adminRepository) UpdateUserStatus(ctx context.Context, status string, suspendedUntil *time.Time, history *entities.UserStatusHistory, entry *auditEntities.Log) error {
+	statements := []userStatement{
+		{"UPDATE users SET status = $2, suspended_until = $3, updated_at = NOW() WHERE id = $1",
+			[]interface{}{history.UserID, status, suspendedUntil}},
+	}
+	if status != "active" {
+		statements = append(statements, userStatement{
+			"UPDATE user_refresh_tokens SET is_valid = false WHERE user_id = $1", []interface{}{history.UserID}})
+	}
+	return r.applyUserAction(ctx, history, entry, statements...)
+}
+
+// ForceLogoutUser 使用户全部刷新令牌失效
+func (r *adminRepository) ForceLogoutUser(ctx context.Context, history *entities.UserStatusHistory, entry *auditEntities.Log) error {
+	return r.applyUserAction(ctx, history, entry, userStatement{
+		"UPDATE user_refresh_tokens SET is_valid = false WHERE user_id = $1", []interface{}{history.UserID}})
+}
+
+func (r *adminRepository) MarkUserEmailVerified(ctx context.Context, history *entities.UserStatusHistory, entry *auditEntities.Log) error {
+	return r.applyUserAction(ctx, history, entry, userStatement{
+		"UPDATE users SET email_verified = true, updated_at = NOW() WHERE id = $1", []interface{}{history.UserID}})
+}
+
+func (r *adminRepository) MarkUserPhoneVerified(ctx context.Context, history *entities.UserStatusHistory, entry *auditEntities.Log) error {
+	return r.applyUserAction(ctx, history, entry, userStatement{
+		"UPDATE users SET phone_verified = true, updated_at = NOW() WHERE id = $1", []interface{}{history.UserID}})
+}
+
+func (r *adminRepository) CreateUserStatusHistory(ctx context.Context, history *entities.UserStatusHistory, entry *auditEntities.Log) error {
+	return r.applyUserAction(ctx, history, entry)
+}
+
+// RecordImpersonation 记录管理员代登录：写入用户登录记录和操作记录
+func (r *adminRepository) RecordImpersonation(ctx context.Context, session *entities.ImpersonationSession, history *entities.UserStatusHistory, entry *auditEntities.Log) error {
+	return r.applyUserAction(ctx, history, entry, userStatement{`
+		INSERT INTO user_login_sessions (
+			user_id, ip, country, city, region, timezone, organization, location,
+			user_agent, device_type, os, browser, is_trusted, login_method, platform, status, reason,
+			risk_score, risk_decision, risk_reasons, impersonator_id
+		) VALUES ($1, $2, '', '', '', '', '', '', $3, $4, $5, $6, false, 'impersonation', $7, 'success', $8, 0, '', '', $9)`,
+		[]interface{}{session.UserID, session.IP, session.UserAgent, session.DeviceType, session.OS,
+			session.Browser, session.Platform, session.Reason, session.AdminID}})
+}
+
+func (r *adminRepository) GetUserStatusHistory(userID int64) ([]*entities.UserStatusHistory, error) {
+	history := []*entities.UserStatusHistory{}
+	query := `SELECT * FROM user_status_history WHERE user_id = $1 ORDER BY created_at DESC, id DESC`
+	if err := database.DB.Select(&history, query, userID); err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
+func (r *adminRepository) RecordAudit(ctx context.Context, entry *auditEntities.Log) error {
+	return audit.Record(ctx, database.DB, entry)
+}
+
+// applyUserAction 在一个事务中执行更新并写入操作记录和审计记录
+func (r *adminRepository) applyUserAction(ctx context.Context, history *entities.UserStatusHistory, entry *auditEntities.Log, statements ...userStatement) error {
+	tx, err := database.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, stmt := range statements {
+		if _, err := tx.ExecContext(ctx, stmt.query, stmt.args...); err != nil {
+			return fmt.Errorf("failed to apply %s to user %d: %w", history.Action, history.UserID, err)
+		}
+	}
+
+	query := `
+		INSERT INTO user_status_history (user_id, admin_id, action, from_status, to_status, reason, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at`
+	err = tx.QueryRowContext(ctx, query,
+		history.UserID,
+		history.AdminID,
+		history.Action,
+		history.FromStatus,
+		history.ToStatus,
+		history.Reason,
+		history.ExpiresAt,
+	).Scan(&history.ID, &history.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	if err := audit.Append(ctx, tx, entry); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *adminRepository) ListAdmins(status string) ([]*entities.Admin, error) {
+	admins := []*entities.Admin{}
+	query := "SELECT * FROM admins"
+	args := []interface{}{}
+	if status != "" {
+		query += " WHERE status = $1"
+		args = append(args, status)
+	}
+	query += " ORDER BY id"
+	if err := database.DB.Select(&admins, query, args...); err != nil {
+		return nil, err
+	}
+	return admins, nil
+}
+
+// DeactivateAdmin 停用管理员并使其全部刷新令牌失效
+// 锁定全部激活的超级管理员后再检查，避免并发停用导致系统中没有超级管理员
+func (r *adminRepository) DeactivateAdmin(ctx context.Context, id int64, entry *auditEntities.Log) error {
+	tx, err := database.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	superIDs := []int64{}
+	query := `SELECT id FROM admins WHERE status = 'active' AND (is_super OR role = 'super_admin') FOR UPDATE`
+	if err := tx.SelectContext(ctx, &superIDs, query); err != nil {
+		return err
+	}
+	if len(superIDs) == 1 && superIDs[0] == id {
+		return common.ErrLastSuperAdmin
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE admins SET status = 'inactive', updated_at = NOW() WHERE id = $1", id); err != nil {
+		return fmt.Errorf("failed to deactivate admin %d: %w", id, err)
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE admin_refresh_tokens SET is_valid = false WHERE admin_id = $1", id); err != nil {
+		return fmt.Errorf("failed to invalidate refresh tokens of admin %d: %w", id, err)
+	}
+
+	if err := audit.Append(ctx, tx, entry); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *adminRepository) ReactivateAdmin(ctx context.Context, id int64, entry *auditEntities.Log) error {
+	tx, err := database.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "UPDATE admins SET status = 'active', updated_at = NOW() WHERE id = $1", id); err != nil {
+		return fmt.Errorf("failed to reactivate admin %d: %w", id, err)
+	}
+
+	if err := audit.Append(ctx, tx, entry); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// CreateInvitation 创建邀请，同一邮箱尚未使用的旧邀请同时撤销
+func (r *adminRepository) CreateInvitation(ctx context.Context, invitation *entities.AdminInvitation, entry *auditEntities.Log) error {
+	tx, err := database.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	revoke := `
+		UPDATE admin_invitations SET revoked_at = NOW()
+		WHERE email = $1 AND accepted_at IS NULL AND revoked_at IS NULL`
+	if _, err := tx.ExecContext(ctx, revoke, invitation.Email); err != nil {
+		return fmt.Errorf("failed to revoke previous invitations for %s: %w", invitation.Email, err)
+	}
+
+	query := `
+		INSERT INTO admin_invitations (email, name, role, token_hash, invited_by, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at`
+	err = tx.QueryRowContext(ctx, query,
+		invitation.Email,
+		invitation.Name,
+		invitation.Role,
+		invitation.TokenHash,
+		invitation.InvitedBy,
+		invitation.ExpiresAt,
+	).Scan(&invitation.ID, &invitation.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	entry.TargetID = strconv.FormatInt(invitation.ID, 10)
+	if err := audit.Append(ctx, tx, entry); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetInvitationByTokenHash 按令牌哈希获取邀请，不存在时返回 sql.ErrNoRows
+func (r *adminRepository) GetInvitationByTokenHash(tokenHash string) (*entities.AdminInvitation, error) {
+	var invitation entities.AdminInvitation
+	if err := database.DB.Get(&invitation, "SELECT * FROM admin_invitations WHERE token_hash = $1", tokenHash); err != nil {
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+// ListPendingInvitations 获取尚未接受、撤销或过期的邀请
+func (r *adminRepository) ListPendingInvitations() ([]*entities.AdminInvitation, error) {
+	invitations := []*entities.AdminInvitation{}
+	query := `
+		SELECT * FROM admin_invitations
+		WHERE accepted_at IS NULL AND revoked_at IS NULL AND expires_at > NOW()
+		ORDER BY created_at DESC`
+	if err := database.DB.Select(&invitations, query); err != nil {
+		return nil, err
+	}
+	return invitations, nil
+}
+
+// RevokeInvitation 撤销尚未使用的邀请，邀请不存在或已使用时返回 sql.ErrNoRows
+func (r *adminRepository) RevokeInvitation(ctx context.Context, id int64, entry *auditEntities.Log) error {
+	tx, err := database.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE admin_invitations SET revoked_at = NOW()
+		WHERE id = $1 AND accepted_at IS NULL AND revoked_at IS NULL`
+	result, err := tx.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+	if rows, err := result.RowsAffected(); err != nil {
+		return err
+	} else if rows == 0 {
+		return sql.ErrNoRows
+	}
+
+	if err := audit.Append(ctx, tx, entry); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// AcceptInvitation 在同一事务中标记邀请已接受并创建管理员
+// 邀请已被使用或撤销时返回 sql.ErrNoRows，保证同一邀请只能创建一个管理员
+func (r *adminRepository) AcceptInvitation(invitationID int64, admin *entities.Admin) error {
+	tx, err := database.DB.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	accept := `
+		UPDATE admin_invitations SET accepted_at = NOW()
+		WHERE id = $1 AND accepted_at IS NULL AND revoked_at IS NULL AND expires_at > NOW()`
+	result, err := tx.Exec(accept, invitationID)
+	if err != nil {
+		return err
+	}
+	if rows, err := result.RowsAffected(); err != nil {
+		return err
+	} else if rows == 0 {
+		return sql.ErrNoRows
+	}
+
+	query := `
+		INSERT INTO admins (name, email, password, phone, image_key, role, is_super, status,
+			email_verified, phone_verified, profile_completed)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING id, created_at, updated_at`
+	err = tx.QueryRow(query,
+		admin.Name,
+		admin.Email,
+		admin.Password,
+		admin.Phone,
+		admin.ImageKey,
+		admin.Role,
+		admin.IsSuper,
+		admin.Status,
+		admin.EmailVerified,
+		admin.PhoneVerified,
+		admin.ProfileCompleted,
+	).Scan(&admin.ID, &admin.CreatedAt, &admin.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
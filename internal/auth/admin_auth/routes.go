@@ -1,22 +1,32 @@
 package admin
 
 import (
+	"trusioo_api/internal/auth/challenge"
+	"trusioo_api/internal/auth/rbac"
 	"trusioo_api/internal/middleware"
 
 	"github.com/gin-gonic/gin"
 )
 
-func RegisterRoutes(router *gin.RouterGroup, handler *Handler) {
+// RegisterRoutes 注册管理员路由，auth 为 /admin/auth 路由组，由调用方挂载与用户认证相同的认证速率限制
+func RegisterRoutes(router *gin.RouterGroup, auth *gin.RouterGroup, handler *Handler) {
 	admin := router.Group("/admin")
 	{
 		// 管理员认证相关路由 - 不需要认证
-		auth := admin.Group("/auth")
+		// 登录在同一IP或网段请求过多时要求完成挑战，见 POST /auth/challenge
 		{
-			auth.POST("/login", handler.Login)
+			auth.POST("/login", middleware.RequireChallenge(challenge.ActionAdminLogin), handler.Login)
 			auth.POST("/login/verify", handler.LoginVerify)
 			auth.POST("/forgot-password", handler.ForgotPassword) // 管理员忘记密码：发送重置验证码
 			auth.POST("/reset-password", handler.ResetPassword)   // 管理员重置密码：验证码+新密码
-			auth.POST("/refresh", handler.RefreshToken)
+			// 刷新和退出登录支持 Cookie 会话，使用 Cookie 时校验 CSRF 令牌
+			auth.POST("/refresh", middleware.AdminCSRFMiddleware(), handler.RefreshToken)
+			auth.POST("/logout", middleware.AdminCSRFMiddleware(), handler.Logout)
+			auth.POST("/invitations/accept", handler.AcceptInvitation) // 接受邀请：设置密码并创建管理员账户
+
+			// 重新验证身份 - 签发短期提升令牌，用于需要近期验证身份的敏感操作
+			auth.POST("/reauth/code", middleware.AdminAuthMiddleware(), handler.SendReauthCode)
+			auth.POST("/reauth", middleware.AdminAuthMiddleware(), handler.Reauthenticate)
 		}
 
 		// 需要管理员认证的路由
@@ -29,9 +39,40 @@ func RegisterRoutes(router *gin.RouterGroup, handler *Handler) {
 			// 用户管理
 			users := adminRoutes.Group("/users")
 			{
-				users.GET("/stats", handler.GetUserStats)
-				users.GET("", handler.GetUserList)
-				users.GET("/:id", handler.GetUserDetail)
+				canRead := middleware.RequirePermission(rbac.PermUsersRead)
+				users.GET("/stats", canRead, handler.GetUserStats)
+				users.GET("", canRead, handler.GetUserList)
+				recentAuth := middleware.RequireRecentAuth(middleware.StepUpMaxAge())
+				users.GET("/:id", canRead, recentAuth, handler.GetUserDetail) // 查看用户详情需要提升令牌
+				users.GET("/:id/status-history", canRead, handler.GetUserStatusHistory)
+
+				// 用户管理操作 - 需要填写原因，并记录到用户状态变更记录
+				canSuspend := middleware.RequirePermission(rbac.PermUsersSuspend)
+				users.POST("/:id/suspend", canSuspend, handler.SuspendUser)
+				users.POST("/:id/reactivate", canSuspend, handler.ReactivateUser)
+				users.POST("/:id/logout", canSuspend, handler.ForceLogoutUser)
+				users.POST("/:id/unlock", canSuspend, handler.UnlockUser) // 解除登录失败锁定
+
+				canUpdate := middleware.RequirePermission(rbac.PermUsersUpdate)
+				users.POST("/:id/password-reset", canUpdate, handler.SendUserPasswordReset)
+				users.POST("/:id/verify", canUpdate, handler.VerifyUserContact)
+
+				// 代登录 - 签发的令牌默认只读，每个请求都记录管理员和用户两个身份
+				canImpersonate := middleware.RequirePermission(rbac.PermUsersImpersonate)
+				users.POST("/:id/impersonate", canImpersonate, handler.ImpersonateUser)
+			}
+
+			// 管理员账户管理 - 默认仅超级管理员拥有 admins.manage 权限
+			admins := adminRoutes.Group("/admins")
+			admins.Use(middleware.RequirePermission(rbac.PermAdminsManage))
+			{
+				admins.GET("", handler.ListAdmins)
+				admins.POST("/:id/deactivate", handler.DeactivateAdmin)
+				admins.POST("/:id/reactivate", handler.ReactivateAdmin)
+
+				admins.GET("/invitations", handler.ListInvitations)
+				admins.POST("/invitations", handler.InviteAdmin)
+				admins.DELETE("/invitations/:id", handler.RevokeInvitation)
 			}
 		}
 	}
@@ -10,52 +10,124 @@ import (
 	"trusioo_api/config"
 	"trusioo_api/internal/auth/admin_auth/dto"
 	"trusioo_api/internal/auth/admin_auth/entities"
+	"trusioo_api/internal/auth/identity"
+	"trusioo_api/internal/auth/lockout"
+	"trusioo_api/internal/auth/loginrisk"
+	"trusioo_api/internal/auth/password"
+	"trusioo_api/internal/auth/sessionlimit"
 	"trusioo_api/internal/auth/verification"
 	verificationDto "trusioo_api/internal/auth/verification/dto"
 	"trusioo_api/internal/common"
-	"trusioo_api/pkg/auth"
+	"trusioo_api/pkg/database"
 	"trusioo_api/pkg/ipinfo"
-
-	"golang.org/x/crypto/bcrypt"
+	"trusioo_api/pkg/mailer"
+	"trusioo_api/pkg/redis"
 )
 
+// VerificationCodeService 验证码服务接口
+type VerificationCodeService interface {
+	SendVerificationCode(req *verificationDto.SendVerificationRequest) (*verificationDto.SendVerificationResponse, error)
+	VerifyCode(req *verificationDto.VerifyCodeRequest) (*verificationDto.VerifyCodeResponse, error)
+}
+
+// EmailOutbox 邮件发件箱接口，由 mailer.Outbox 实现
+type EmailOutbox interface {
+	Enqueue(ctx context.Context, msg *mailer.Message) error
+}
+
+// AccountUnlocker 解除用户登录锁定，由 lockout.Guard 实现
+type AccountUnlocker interface {
+	Reset(ctx context.Context, account string) error
+}
+
 // Service 管理员业务逻辑服务
 type Service struct {
 	adminRepo           AdminRepository
-	verificationService *verification.Service
+	verificationService VerificationCodeService
 	ipinfoClient        ipinfo.Client
+	permissions         AccessControl
+	mailOutbox          EmailOutbox
+	mailLocale          string
+	inviteTTL           time.Duration
+	impersonationTTL    time.Duration
+	loginLockout        AccountUnlocker
+
+	// 管理员登录和重新验证身份时密码错误的退避与锁定，为空时不限制
+	loginGuard identity.LoginGuard
+
+	// 登录风险评估，为空时不评估
+	riskEvaluator   *loginrisk.Evaluator
+	riskHistorySize int
+
+	// 设置和重置密码时的密码策略与密码历史，为空时不校验
+	passwordPolicy *password.Policy
+
+	// 同时登录会话上限，为空时不限制
+	sessionLimiter *sessionlimit.Limiter
+
+	// 与用户共用的认证核心，由 NewService 根据上面的依赖创建
+	identity *identity.Core
 }
 
-// NewService 创建新的Service实例
-func NewService() *Service {
+// NewService 创建新的Service实例，用户与管理员账户管理操作通过 permissions 校验管理员权限
+func NewService(permissions AccessControl) *Service {
 	ipinfoConfig := ipinfo.LoadConfigFromEnv()
 	ipinfoClient := ipinfo.NewClient(ipinfoConfig)
 
-	return &Service{
+	service := &Service{
 		adminRepo:           NewAdminRepository(),
 		verificationService: verification.NewService(),
 		ipinfoClient:        ipinfoClient,
-	}
+		permissions:         permissions,
+		mailOutbox:          mailer.NewOutbox(database.DB),
+		mailLocale:          config.AppConfig.Mail.DefaultLocale,
+		inviteTTL:           time.Duration(config.AppConfig.AdminInvite.TTLHours) * time.Hour,
+		impersonationTTL:    time.Duration(config.AppConfig.Impersonation.TTLMinutes) * time.Minute,
+		loginLockout:        lockout.NewGuard(redis.LockoutCache, lockout.NewConfigFromApp(config.AppConfig)),
+		riskHistorySize:     config.AppConfig.LoginRisk.HistorySize,
+		passwordPolicy:      password.NewPolicyFromApp(config.AppConfig),
+	}
+	if riskConfig := loginrisk.NewConfigFromApp(config.AppConfig); riskConfig != nil {
+		service.riskEvaluator = loginrisk.NewEvaluator(riskConfig)
+	}
+	if lockoutConfig := lockout.NewConfigFromApp(config.AppConfig); lockoutConfig != nil {
+		service.loginGuard = lockout.NewGuard(redis.AdminLockoutCache, lockoutConfig)
+	}
+	if sessionConfig := sessionlimit.NewConfigFromApp(config.AppConfig); sessionConfig != nil {
+		service.sessionLimiter = sessionlimit.NewLimiter(sessionConfig)
+	}
+	service.identity = service.newIdentityCore()
+	return service
 }
 
 // Login 管理员登录第一步 - 验证email+password并发送登录验证码
+// 密码错误按管理员和IP计数，超过阈值后退避并锁定，锁定期间返回 common.ErrLoginLocked 和需要等待的秒数
 func (s *Service) Login(req *dto.AdminLoginRequest, clientIP, userAgent string) (*dto.AdminLoginCodeResponse, error) {
-	// 1. 验证email+password
+	// 0. 失败次数过多的账户或IP需要等待，账户是否存在返回相同的结果
+	if wait := s.identityCore().LoginRetryAfter(req.Email, clientIP); wait > 0 {
+		s.recordLoginSession(0, clientIP, userAgent, "failed", "登录失败次数过多")
+		return &dto.AdminLoginCodeResponse{RetryAfter: identity.RetryAfterSeconds(wait)}, common.ErrLoginLocked
+	}
+
+	// 1. 验证email+password，管理员不存在与密码错误返回相同的错误，避免枚举管理员账户
 	admin, err := s.adminRepo.GetByEmail(req.Email)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			s.recordLoginSession(0, clientIP, userAgent, "failed", "管理员不存在")
-			return nil, common.ErrAdminNotFound
-		}
+	if err != nil && err != sql.ErrNoRows {
 		return nil, err
 	}
-
-	// 验证密码
-	err = bcrypt.CompareHashAndPassword([]byte(admin.Password), []byte(req.Password))
-	if err != nil {
-		s.recordLoginSession(admin.ID, clientIP, userAgent, "failed", "密码错误")
-		return nil, common.ErrInvalidAdminCredentials
+	passwordHash := ""
+	if admin != nil {
+		passwordHash = admin.Password
+	}
+	if !identity.ComparePassword(passwordHash, req.Password) {
+		s.identityCore().RecordLoginFailure(req.Email, clientIP)
+		if admin == nil {
+			s.recordLoginSession(0, clientIP, userAgent, "failed", "管理员不存在")
+		} else {
+			s.recordLoginSession(admin.ID, clientIP, userAgent, "failed", "密码错误")
+		}
+		return nil, common.ErrInvalidCredentials
 	}
+	s.identityCore().ResetLoginFailures(req.Email)
 
 	// 检查管理员状态 - 必须是激活状态才能登录
 	if admin.Status != "active" {
@@ -76,7 +148,7 @@ func (s *Service) Login(req *dto.AdminLoginRequest, clientIP, userAgent string)
 	return &dto.AdminLoginCodeResponse{
 		Message:   "管理员登录验证码已发送",
 		LoginCode: "已发送到邮箱",
-		ExpiresIn: 600, // 10分钟
+		ExpiresIn: verification.CodeExpiresIn(),
 	}, nil
 }
 
@@ -91,45 +163,29 @@ func (s *Service) LoginVerify(req *dto.AdminLoginVerifyRequest, clientIP, userAg
 		return nil, err
 	}
 
-	// 2. 验证验证码
-	verifyReq := &verificationDto.VerifyCodeRequest{
-		Target: req.Email,
-		Code:   req.Code,
-		Type:   req.VerificationType(),
-	}
-	verifyResp, err := s.verificationService.VerifyCode(verifyReq)
-	if err != nil {
-		s.recordLoginSession(admin.ID, clientIP, userAgent, "failed", "验证码错误")
+	// 2. 验证验证码，未通过时记录失败的登录会话
+	attempt := identity.Attempt{IP: clientIP, UserAgent: userAgent}
+	if err := s.identityCore().VerifyLoginCode(admin.ID, attempt, req.Email, req.Code, req.VerificationType()); err != nil {
 		return nil, err
 	}
-	if !verifyResp.Valid {
-		s.recordLoginSession(admin.ID, clientIP, userAgent, "failed", "验证码无效")
-		return nil, common.ErrInvalidCode
-	}
 
-	// 3. 生成访问令牌
-	accessToken, err := auth.GenerateAccessToken(admin.ID, admin.Email, admin.Role, "admin")
-	if err != nil {
-		return nil, err
+	// 3. 风险评估：管理员登录本身已经使用了密码和邮箱验证码，没有其他验证渠道，
+	// 中风险与高风险一样拒绝并邮件通知管理员
+	ipInfo := s.identityCore().LookupIP(clientIP)
+	assessment := s.identityCore().AssessLoginRisk(admin.ID, ipInfo, userAgent)
+	if assessment != nil && assessment.Decision != loginrisk.DecisionAllow {
+		s.writeLoginSession(admin.ID, clientIP, userAgent, "failed", "可疑登录已拦截", ipInfo, assessment)
+		s.sendLoginBlockedEmail(admin, clientIP, ipInfo)
+		return nil, common.ErrLoginBlocked
 	}
 
-	// 4. 生成刷新令牌
-	refreshTokenStr, err := auth.GenerateRefreshToken(admin.ID, admin.Email, admin.Role, "admin")
-	if err != nil {
+	// 4. 执行同时登录会话上限，被踢出的会话邮件通知管理员
+	if err := s.admitSession(admin, userAgent); err != nil {
 		return nil, err
 	}
 
-	// 5. 保存刷新令牌到数据库
-	refreshToken := &entities.AdminRefreshToken{
-		AdminID:    admin.ID,
-		Token:      refreshTokenStr,
-		IsValid:    true,
-		ExpiresAt:  time.Now().Add(time.Duration(config.AppConfig.JWT.RefreshExpire) * time.Second),
-		DeviceInfo: userAgent,
-		CreatedAt:  time.Now(),
-	}
-
-	err = s.adminRepo.CreateRefreshToken(refreshToken)
+	// 5. 生成并保存访问令牌与刷新令牌
+	tokens, err := s.identityCore().IssueTokens(identityAccount(admin), userAgent)
 	if err != nil {
 		return nil, err
 	}
@@ -146,12 +202,12 @@ func (s *Service) LoginVerify(req *dto.AdminLoginVerifyRequest, clientIP, userAg
 		return nil, err
 	}
 
-	// 7. 记录登录会话并获取位置信息
-	sessionInfo := s.recordLoginSessionWithIPInfo(admin.ID, clientIP, userAgent, "success", "登录成功")
+	// 8. 记录登录会话并获取位置信息
+	sessionInfo := s.writeLoginSession(admin.ID, clientIP, userAgent, "success", "登录成功", ipInfo, assessment)
 
 	return &dto.AdminLoginResponse{
-		AccessToken:  accessToken,
-		RefreshToken: refreshTokenStr,
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
 		ExpiresIn:    int64(config.AppConfig.JWT.AccessExpire),
 		TokenType:    "Bearer",
 		Admin:        *admin,
@@ -161,28 +217,14 @@ func (s *Service) LoginVerify(req *dto.AdminLoginVerifyRequest, clientIP, userAg
 
 // RefreshToken 刷新访问令牌
 func (s *Service) RefreshToken(req *dto.RefreshTokenRequest) (*dto.AdminLoginResponse, error) {
-	// 验证刷新令牌
-	claims, err := auth.ValidateRefreshToken(req.RefreshToken)
-	if err != nil {
-		return nil, common.ErrTokenInvalid
-	}
-
-	// 检查刷新令牌是否在数据库中且有效
-	refreshToken, err := s.adminRepo.GetValidRefreshToken(req.RefreshToken)
+	// 验证刷新令牌在数据库中有效且属于令牌中的管理员
+	refreshToken, err := s.identityCore().ValidateRefreshToken(req.RefreshToken)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, common.ErrRefreshTokenInvalid
-		}
 		return nil, err
 	}
 
-	// 验证token所属用户ID
-	if refreshToken.AdminID != claims.UserID {
-		return nil, common.ErrRefreshTokenInvalid
-	}
-
 	// 获取管理员信息
-	admin, err := s.adminRepo.GetByID(claims.UserID)
+	admin, err := s.adminRepo.GetByID(refreshToken.AccountID)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, common.ErrAdminNotFound
@@ -196,7 +238,7 @@ func (s *Service) RefreshToken(req *dto.RefreshTokenRequest) (*dto.AdminLoginRes
 	}
 
 	// 生成新的访问令牌
-	accessToken, err := auth.GenerateAccessToken(admin.ID, admin.Email, admin.Role, "admin")
+	accessToken, err := s.identityCore().AccessToken(identityAccount(admin))
 	if err != nil {
 		return nil, err
 	}
@@ -210,6 +252,11 @@ func (s *Service) RefreshToken(req *dto.RefreshTokenRequest) (*dto.AdminLoginRes
 	}, nil
 }
 
+// Logout 退出登录，使刷新令牌失效
+func (s *Service) Logout(refreshToken string) error {
+	return s.adminRepo.InvalidateRefreshToken(refreshToken)
+}
+
 // GetAdminByID 根据ID获取管理员信息
 func (s *Service) GetAdminByID(adminID int64) (*entities.Admin, error) {
 	admin, err := s.adminRepo.GetByID(adminID)
@@ -246,102 +293,54 @@ func (s *Service) GetUserByID(userID int64) (*entities.UserInfo, error) {
 
 // recordLoginSession 记录登录会话
 func (s *Service) recordLoginSession(adminID int64, ip, userAgent, status, reason string) {
-	s.recordLoginSessionWithIPInfo(adminID, ip, userAgent, status, reason)
+	s.identityCore().RecordLoginSession(adminID, identity.Attempt{IP: ip, UserAgent: userAgent}, status, reason)
 }
 
-func (s *Service) recordLoginSessionWithIPInfo(adminID int64, ip, userAgent, status, reason string) *dto.AdminLoginSessionInfo {
-	session := &entities.AdminLoginSession{
-		AdminID:   adminID,
-		IP:        ip,
-		UserAgent: userAgent,
-		Status:    status,
-		Reason:    reason,
-		CreatedAt: time.Now(),
+// writeLoginSession 记录带风险评估结果的登录会话，登录成功时返回会话的位置信息给客户端
+func (s *Service) writeLoginSession(adminID int64, ip, userAgent, status, reason string, ipInfo *ipinfo.IPInfo, assessment *loginrisk.Assessment) *dto.AdminLoginSessionInfo {
+	session := identity.NewLoginSession(adminID, identity.Attempt{IP: ip, UserAgent: userAgent}, ipInfo, status, reason)
+	session.SetRisk(assessment)
+	if !s.identityCore().SaveLoginSession(session) || status != "success" {
+		return nil
 	}
-
-	// 获取IP地理位置信息
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	if ipInfo, err := s.ipinfoClient.GetIPInfo(ctx, ip); err == nil {
-		session.Country = ipInfo.Country
-		session.City = ipInfo.City
-		session.Region = ipInfo.Region
-		session.Timezone = ipInfo.Timezone
-		session.Organization = ipInfo.Org
-		session.Location = ipInfo.Loc
-	} else {
-		log.Printf("获取IP信息失败 %s: %v", ip, err)
+	return &dto.AdminLoginSessionInfo{
+		IP:           session.IP,
+		Country:      session.Country,
+		City:         session.City,
+		Region:       session.Region,
+		Timezone:     session.Timezone,
+		Organization: session.Organization,
+		Location:     session.Coordinates,
+		IsTrusted:    session.IsTrusted,
 	}
+}
 
-	// 解析User-Agent获取设备信息
-	s.parseUserAgent(session, userAgent)
+// sendLoginBlockedEmail 通知管理员可疑登录已被拦截
+func (s *Service) sendLoginBlockedEmail(admin *entities.Admin, ip string, ipInfo *ipinfo.IPInfo) {
+	if s.mailOutbox == nil {
+		return
+	}
 
-	err := s.adminRepo.CreateLoginSession(session)
-	if err != nil {
-		log.Printf("记录管理员登录会话失败: %v", err)
-		return nil
+	location := "Unknown"
+	if ipInfo != nil && ipInfo.Country != "" {
+		location = strings.Trim(ipInfo.City+", "+ipInfo.Country, ", ")
 	}
 
-	// 返回会话信息给客户端
-	if status == "success" {
-		return &dto.AdminLoginSessionInfo{
-			IP:           session.IP,
-			Country:      session.Country,
-			City:         session.City,
-			Region:       session.Region,
-			Timezone:     session.Timezone,
-			Organization: session.Organization,
-			Location:     session.Location,
-			IsTrusted:    session.IsTrusted,
-		}
+	msg, err := mailer.Render(admin.Email, mailer.TemplateSuspiciousLogin, "", s.mailLocale, mailer.TemplateData{
+		Email:    admin.Email,
+		IP:       ip,
+		Location: location,
+		Time:     time.Now().UTC().Format("2006-01-02 15:04:05 UTC"),
+	})
+	if err != nil {
+		log.Printf("渲染可疑登录通知失败: %v", err)
+		return
 	}
-	return nil
-}
 
-func (s *Service) parseUserAgent(session *entities.AdminLoginSession, userAgent string) {
-	ua := strings.ToLower(userAgent)
-
-	// 检测设备类型
-	if strings.Contains(ua, "mobile") || strings.Contains(ua, "android") || strings.Contains(ua, "iphone") {
-		session.DeviceType = "mobile"
-	} else if strings.Contains(ua, "tablet") || strings.Contains(ua, "ipad") {
-		session.DeviceType = "tablet"
-	} else {
-		session.DeviceType = "desktop"
-	}
-
-	// 检测操作系统
-	if strings.Contains(ua, "windows") {
-		session.OS = "Windows"
-	} else if strings.Contains(ua, "mac") || strings.Contains(ua, "darwin") {
-		session.OS = "macOS"
-	} else if strings.Contains(ua, "linux") {
-		session.OS = "Linux"
-	} else if strings.Contains(ua, "android") {
-		session.OS = "Android"
-	} else if strings.Contains(ua, "iphone") || strings.Contains(ua, "ipad") || strings.Contains(ua, "ios") {
-		session.OS = "iOS"
-	}
-
-	// 检测浏览器
-	if strings.Contains(ua, "chrome") && !strings.Contains(ua, "edge") {
-		session.Browser = "Chrome"
-	} else if strings.Contains(ua, "firefox") {
-		session.Browser = "Firefox"
-	} else if strings.Contains(ua, "safari") && !strings.Contains(ua, "chrome") {
-		session.Browser = "Safari"
-	} else if strings.Contains(ua, "edge") {
-		session.Browser = "Edge"
-	} else if strings.Contains(ua, "opera") {
-		session.Browser = "Opera"
-	}
-
-	// 检测平台
-	if strings.Contains(ua, "mobile") || strings.Contains(ua, "android") || strings.Contains(ua, "iphone") {
-		session.Platform = "mobile"
-	} else {
-		session.Platform = "web"
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.mailOutbox.Enqueue(ctx, msg); err != nil {
+		log.Printf("发送可疑登录通知失败 admin %d: %v", admin.ID, err)
 	}
 }
 
@@ -354,7 +353,7 @@ func (s *Service) ForgotPassword(req *dto.AdminForgotPasswordRequest) (*dto.Admi
 			// 为了安全，即使管理员不存在也返回成功，避免暴露管理员是否存在
 			return &dto.AdminForgotPasswordResponse{
 				Message:   "如果该邮箱已注册为管理员，重置密码验证码已发送",
-				ExpiresIn: 600,
+				ExpiresIn: verification.CodeExpiresIn(),
 			}, nil
 		}
 		return nil, err
@@ -365,23 +364,18 @@ func (s *Service) ForgotPassword(req *dto.AdminForgotPasswordRequest) (*dto.Admi
 		// 为了安全，不暴露账户状态信息
 		return &dto.AdminForgotPasswordResponse{
 			Message:   "如果该邮箱已注册为管理员，重置密码验证码已发送",
-			ExpiresIn: 600,
+			ExpiresIn: verification.CodeExpiresIn(),
 		}, nil
 	}
 
 	// 3. 发送重置密码验证码
-	sendReq := &verificationDto.SendVerificationRequest{
-		Target: req.Email,
-		Type:   "admin_forgot_password",
-	}
-	_, err = s.verificationService.SendVerificationCode(sendReq)
-	if err != nil {
+	if err := s.identityCore().SendPasswordReset(req.Email); err != nil {
 		return nil, err
 	}
 
 	return &dto.AdminForgotPasswordResponse{
 		Message:   "管理员重置密码验证码已发送到您的邮箱",
-		ExpiresIn: 600, // 10分钟
+		ExpiresIn: verification.CodeExpiresIn(),
 	}, nil
 }
 
@@ -396,38 +390,18 @@ func (s *Service) ResetPassword(req *dto.AdminResetPasswordRequest) (*dto.AdminR
 		return nil, err
 	}
 
-	// 2. 验证重置密码验证码
-	verifyReq := &verificationDto.VerifyCodeRequest{
-		Target: req.Email,
-		Code:   req.Code,
-		Type:   "admin_forgot_password",
-	}
-	verifyResp, err := s.verificationService.VerifyCode(verifyReq)
+	// 2. 验证重置密码验证码，新密码通过密码策略和密码历史校验后更新密码，并使所有refresh token失效
+	core := s.identityCore()
+	hashedPassword, err := core.ResetPassword(admin.ID, req.Email, req.Code, req.Password, func(password string) error {
+		return core.ValidatePassword(admin.ID, admin.Password, admin.Email, password)
+	})
 	if err != nil {
 		return nil, err
 	}
-	if !verifyResp.Valid {
-		return nil, common.ErrInvalidCode
-	}
+	core.RecordPasswordHistory(admin.ID, hashedPassword)
 
-	// 3. 加密新密码
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
-	if err != nil {
-		return nil, err
-	}
-
-	// 4. 更新密码
-	err = s.adminRepo.UpdatePassword(admin.ID, string(hashedPassword))
-	if err != nil {
-		return nil, err
-	}
-
-	// 5. 可选：使所有refresh token失效，强制重新登录
-	err = s.adminRepo.InvalidateAllRefreshTokens(admin.ID)
-	if err != nil {
-		log.Printf("Failed to invalidate refresh tokens for admin %d: %v", admin.ID, err)
-		// 不返回错误，因为密码已经重置成功
-	}
+	// 3. 证明了邮箱所有权，清除登录失败次数和锁定；管理员没有受信任设备，不需要撤销
+	core.ResetLoginFailures(admin.Email)
 
 	return &dto.AdminResetPasswordResponse{
 		Message: "管理员密码重置成功，请使用新密码登录",
@@ -3,17 +3,29 @@ package admin
 import (
 	"context"
 	"database/sql"
+	"net/url"
+	"strings"
 	"testing"
+	"time"
 
 	"golang.org/x/crypto/bcrypt"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
+	"trusioo_api/internal/audit"
+	auditEntities "trusioo_api/internal/audit/entities"
 	"trusioo_api/internal/auth/admin_auth/dto"
 	"trusioo_api/internal/auth/admin_auth/entities"
+	"trusioo_api/internal/auth/lockout"
+	"trusioo_api/internal/auth/loginrisk"
+	"trusioo_api/internal/auth/password"
+	"trusioo_api/internal/auth/sessionlimit"
 	verificationDto "trusioo_api/internal/auth/verification/dto"
 	"trusioo_api/internal/common"
+	"trusioo_api/internal/testutil"
+	"trusioo_api/pkg/auth"
 	"trusioo_api/pkg/ipinfo"
+	"trusioo_api/pkg/mailer"
 )
 
 // MockAdminRepository 实现 AdminRepository 接口的 mock
@@ -85,11 +97,50 @@ func (m *MockAdminRepository) InvalidateAllRefreshTokens(adminID int64) error {
 	return args.Error(0)
 }
 
+func (m *MockAdminRepository) ListActiveRefreshTokens(adminID int64) ([]*entities.AdminRefreshToken, error) {
+	args := m.Called(adminID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*entities.AdminRefreshToken), args.Error(1)
+}
+
+func (m *MockAdminRepository) EvictRefreshTokens(adminID int64, ids []int64) error {
+	args := m.Called(adminID, ids)
+	return args.Error(0)
+}
+
+func (m *MockAdminRepository) IsRefreshTokenEvicted(token string) (bool, error) {
+	args := m.Called(token)
+	return args.Bool(0), args.Error(1)
+}
+
 func (m *MockAdminRepository) CreateLoginSession(session *entities.AdminLoginSession) error {
 	args := m.Called(session)
 	return args.Error(0)
 }
 
+func (m *MockAdminRepository) GetRecentLoginSessions(adminID int64, limit int) ([]*entities.AdminLoginSession, error) {
+	args := m.Called(adminID, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*entities.AdminLoginSession), args.Error(1)
+}
+
+func (m *MockAdminRepository) ListPasswordHistory(adminID int64, limit int) ([]string, error) {
+	args := m.Called(adminID, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockAdminRepository) AddPasswordHistory(adminID int64, passwordHash string, keep int) error {
+	args := m.Called(adminID, passwordHash, keep)
+	return args.Error(0)
+}
+
 func (m *MockAdminRepository) GetUserStats() (*dto.UserStats, error) {
 	args := m.Called()
 	if args.Get(0) == nil {
@@ -114,6 +165,98 @@ func (m *MockAdminRepository) GetUserByID(id int64) (*entities.UserInfo, error)
 	return args.Get(0).(*entities.UserInfo), args.Error(1)
 }
 
+func (m *MockAdminRepository) UpdateUserStatus(ctx context.Context, status string, suspendedUntil *time.Time, history *entities.UserStatusHistory, entry *auditEntities.Log) error {
+	args := m.Called(status, suspendedUntil, history, entry)
+	return args.Error(0)
+}
+
+func (m *MockAdminRepository) ForceLogoutUser(ctx context.Context, history *entities.UserStatusHistory, entry *auditEntities.Log) error {
+	args := m.Called(history, entry)
+	return args.Error(0)
+}
+
+func (m *MockAdminRepository) MarkUserEmailVerified(ctx context.Context, history *entities.UserStatusHistory, entry *auditEntities.Log) error {
+	args := m.Called(history, entry)
+	return args.Error(0)
+}
+
+func (m *MockAdminRepository) MarkUserPhoneVerified(ctx context.Context, history *entities.UserStatusHistory, entry *auditEntities.Log) error {
+	args := m.Called(history, entry)
+	return args.Error(0)
+}
+
+func (m *MockAdminRepository) CreateUserStatusHistory(ctx context.Context, history *entities.UserStatusHistory, entry *auditEntities.Log) error {
+	args := m.Called(history, entry)
+	return args.Error(0)
+}
+
+func (m *MockAdminRepository) GetUserStatusHistory(userID int64) ([]*entities.UserStatusHistory, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*entities.UserStatusHistory), args.Error(1)
+}
+
+func (m *MockAdminRepository) RecordImpersonation(ctx context.Context, session *entities.ImpersonationSession, history *entities.UserStatusHistory, entry *auditEntities.Log) error {
+	args := m.Called(session, history, entry)
+	return args.Error(0)
+}
+
+func (m *MockAdminRepository) RecordAudit(ctx context.Context, entry *auditEntities.Log) error {
+	args := m.Called(entry)
+	return args.Error(0)
+}
+
+func (m *MockAdminRepository) ListAdmins(status string) ([]*entities.Admin, error) {
+	args := m.Called(status)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*entities.Admin), args.Error(1)
+}
+
+func (m *MockAdminRepository) DeactivateAdmin(ctx context.Context, id int64, entry *auditEntities.Log) error {
+	args := m.Called(id, entry)
+	return args.Error(0)
+}
+
+func (m *MockAdminRepository) ReactivateAdmin(ctx context.Context, id int64, entry *auditEntities.Log) error {
+	args := m.Called(id, entry)
+	return args.Error(0)
+}
+
+func (m *MockAdminRepository) CreateInvitation(ctx context.Context, invitation *entities.AdminInvitation, entry *auditEntities.Log) error {
+	args := m.Called(invitation, entry)
+	return args.Error(0)
+}
+
+func (m *MockAdminRepository) GetInvitationByTokenHash(tokenHash string) (*entities.AdminInvitation, error) {
+	args := m.Called(tokenHash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entities.AdminInvitation), args.Error(1)
+}
+
+func (m *MockAdminRepository) ListPendingInvitations() ([]*entities.AdminInvitation, error) {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*entities.AdminInvitation), args.Error(1)
+}
+
+func (m *MockAdminRepository) RevokeInvitation(ctx context.Context, id int64, entry *auditEntities.Log) error {
+	args := m.Called(id, entry)
+	return args.Error(0)
+}
+
+func (m *MockAdminRepository) AcceptInvitation(invitationID int64, admin *entities.Admin) error {
+	args := m.Called(invitationID, admin)
+	return args.Error(0)
+}
+
 // MockIPInfoClient 实现 ipinfo.Client 接口的 mock
 type MockIPInfoClient struct {
 	mock.Mock
@@ -321,4 +464,778 @@ func TestService_Login(t *testing.T) {
 			mockIPInfo.AssertExpectations(t)
 		})
 	}
-}
\ No newline at end of file
+}
+
+// fakePermissionChecker 按管理员ID返回固定权限
+type fakePermissionChecker map[int64][]string
+
+func (f fakePermissionChecker) HasPermission(ctx context.Context, adminID int64, permission string) (bool, error) {
+	for _, p := range f[adminID] {
+		if p == permission {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// fakeRolePermissions 测试用角色及其权限
+var fakeRolePermissions = map[string][]string{
+	"super_admin": {"admins.manage", "roles.manage", "users.read", "users.suspend", "users.update"},
+	"admin":       {"users.read", "users.suspend", "users.update"},
+	"support":     {"users.read"},
+}
+
+func (f fakePermissionChecker) AuthorizeRoleGrant(ctx context.Context, actorID int64, role string) error {
+	permissions, ok := fakeRolePermissions[role]
+	if !ok {
+		return common.ErrNotFound
+	}
+	for _, p := range permissions {
+		if allowed, _ := f.HasPermission(ctx, actorID, p); !allowed {
+			return common.ErrInsufficientPermissions
+		}
+	}
+	return nil
+}
+
+func (f fakePermissionChecker) InvalidateAdmin(ctx context.Context, adminID int64) {}
+
+func TestService_UserManagement(t *testing.T) {
+	phone := "+8613800138000"
+	ctx := audit.WithMeta(context.Background(), audit.Meta{AdminID: 1, IP: "203.0.113.5", RequestID: "req-1"})
+	newService := func() (*Service, *MockAdminRepository, *MockVerificationService) {
+		mockRepo := new(MockAdminRepository)
+		mockVerificationService := new(MockVerificationService)
+		mockRepo.On("GetUserByID", int64(10)).Return(&entities.UserInfo{ID: 10, Email: "user@example.com", Status: "active"}, nil).Maybe()
+		mockRepo.On("GetUserByID", int64(11)).Return(&entities.UserInfo{ID: 11, Email: "suspended@example.com", Phone: &phone, Status: "suspended"}, nil).Maybe()
+		mockRepo.On("GetUserByID", int64(12)).Return(&entities.UserInfo{ID: 12, Status: "deleted"}, nil).Maybe()
+		// 管理员1拥有用户管理权限，管理员2只能查看
+		permissions := fakePermissionChecker{
+			1: {"users.read", "users.suspend", "users.update"},
+			2: {"users.read"},
+		}
+		return &Service{adminRepo: mockRepo, verificationService: mockVerificationService, permissions: permissions}, mockRepo, mockVerificationService
+	}
+
+	t.Run("临时暂停并记录操作人和原因", func(t *testing.T) {
+		service, mockRepo, _ := newService()
+		until := time.Now().Add(24 * time.Hour)
+		mockRepo.On("UpdateUserStatus", "suspended", &until, mock.MatchedBy(func(h *entities.UserStatusHistory) bool {
+			return h.UserID == 10 && *h.AdminID == 1 && h.Action == entities.UserActionSuspend &&
+				h.FromStatus == "active" && h.ToStatus == "suspended" && h.Reason == "违规发布内容" && h.ExpiresAt == &until
+		}), mock.MatchedBy(func(e *auditEntities.Log) bool {
+			return e.AdminID == 1 && e.Action == "user.suspend" && e.TargetType == auditEntities.TargetUser && e.TargetID == "10" &&
+				e.Reason == "违规发布内容" && e.IP == "203.0.113.5" && e.RequestID == "req-1" &&
+				e.Changes["status"] == auditEntities.Change{Before: "active", After: "suspended"}
+		})).Return(nil)
+
+		resp, err := service.SuspendUser(ctx, 1, 10, &dto.SuspendUserRequest{Reason: " 违规发布内容 ", Until: &until})
+		require.NoError(t, err)
+		require.Equal(t, int64(10), resp.User.ID)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("暂停到期时间必须在未来", func(t *testing.T) {
+		service, mockRepo, _ := newService()
+		past := time.Now().Add(-time.Hour)
+		_, err := service.SuspendUser(ctx, 1, 10, &dto.SuspendUserRequest{Reason: "违规", Until: &past})
+		require.Equal(t, common.ErrValidation, err)
+		mockRepo.AssertNotCalled(t, "UpdateUserStatus", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("没有对应权限的管理员被拒绝", func(t *testing.T) {
+		service, mockRepo, _ := newService()
+		_, err := service.ForceLogoutUser(ctx, 2, 10, &dto.UserActionRequest{Reason: "安全事件"})
+		require.Equal(t, common.ErrInsufficientPermissions, err)
+		_, err = service.GetUserStatusHistory(context.Background(), 3, 10)
+		require.Equal(t, common.ErrInsufficientPermissions, err)
+		mockRepo.AssertNotCalled(t, "ForceLogoutUser", mock.Anything, mock.Anything)
+	})
+
+	t.Run("原因不能为空", func(t *testing.T) {
+		service, _, _ := newService()
+		_, err := service.ForceLogoutUser(ctx, 1, 10, &dto.UserActionRequest{Reason: "   "})
+		require.Equal(t, common.ErrValidation, err)
+	})
+
+	t.Run("已注销用户视为不存在", func(t *testing.T) {
+		service, _, _ := newService()
+		_, err := service.ForceLogoutUser(ctx, 1, 12, &dto.UserActionRequest{Reason: "安全事件"})
+		require.Equal(t, common.ErrUserNotFound, err)
+	})
+
+	t.Run("恢复暂停用户", func(t *testing.T) {
+		service, mockRepo, _ := newService()
+		mockRepo.On("UpdateUserStatus", "active", (*time.Time)(nil), mock.MatchedBy(func(h *entities.UserStatusHistory) bool {
+			return h.FromStatus == "suspended" && h.ToStatus == "active" && h.Action == entities.UserActionReactivate
+		}), mock.Anything).Return(nil)
+
+		_, err := service.ReactivateUser(ctx, 1, 11, &dto.UserActionRequest{Reason: "申诉通过"})
+		require.NoError(t, err)
+
+		// 激活状态的用户无需恢复
+		_, err = service.ReactivateUser(ctx, 1, 10, &dto.UserActionRequest{Reason: "申诉通过"})
+		require.Equal(t, common.ErrValidation, err)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("发送重置密码验证码后记录操作", func(t *testing.T) {
+		service, mockRepo, mockVerificationService := newService()
+		mockVerificationService.On("SendVerificationCode", mock.MatchedBy(func(req *verificationDto.SendVerificationRequest) bool {
+			return req.Target == "user@example.com" && req.Type == "forgot_password"
+		})).Return(&verificationDto.SendVerificationResponse{Message: "验证码已发送"}, nil)
+		mockRepo.On("CreateUserStatusHistory", mock.MatchedBy(func(h *entities.UserStatusHistory) bool {
+			return h.Action == entities.UserActionPasswordReset && h.FromStatus == h.ToStatus
+		}), mock.MatchedBy(func(e *auditEntities.Log) bool {
+			// 状态未变化时不记录状态差异
+			return e.Action == "user.password_reset" && len(e.Changes) == 0
+		})).Return(nil)
+
+		_, err := service.SendUserPasswordReset(ctx, 1, 10, &dto.UserActionRequest{Reason: "用户来电申请"})
+		require.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+		mockVerificationService.AssertExpectations(t)
+	})
+
+	t.Run("手动验证手机号", func(t *testing.T) {
+		service, mockRepo, _ := newService()
+		mockRepo.On("MarkUserPhoneVerified", mock.MatchedBy(func(h *entities.UserStatusHistory) bool {
+			return h.UserID == 11 && h.Action == entities.UserActionVerifyPhone
+		}), mock.MatchedBy(func(e *auditEntities.Log) bool {
+			return e.Changes["phone_verified"] == auditEntities.Change{Before: false, After: true}
+		})).Return(nil)
+
+		_, err := service.VerifyUserContact(ctx, 1, 11, &dto.VerifyUserContactRequest{Field: "phone", Reason: "人工核实"})
+		require.NoError(t, err)
+
+		// 未绑定手机号的用户不能标记
+		_, err = service.VerifyUserContact(ctx, 1, 10, &dto.VerifyUserContactRequest{Field: "phone", Reason: "人工核实"})
+		require.Equal(t, common.ErrInvalidPhone, err)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("解除登录锁定并记录操作", func(t *testing.T) {
+		service, mockRepo, _ := newService()
+		unlocker := &fakeAccountUnlocker{}
+		service.loginLockout = unlocker
+		mockRepo.On("CreateUserStatusHistory", mock.MatchedBy(func(h *entities.UserStatusHistory) bool {
+			return h.UserID == 10 && h.Action == entities.UserActionUnlock && h.Reason == "用户来电核实身份"
+		}), mock.MatchedBy(func(e *auditEntities.Log) bool {
+			return e.Action == "user.unlock" && e.TargetID == "10"
+		})).Return(nil)
+
+		_, err := service.UnlockUser(ctx, 1, 10, &dto.UserActionRequest{Reason: "用户来电核实身份"})
+		require.NoError(t, err)
+		require.Equal(t, []string{"user@example.com"}, unlocker.accounts)
+
+		// 只读管理员不能解锁
+		_, err = service.UnlockUser(ctx, 2, 10, &dto.UserActionRequest{Reason: "用户来电核实身份"})
+		require.Equal(t, common.ErrInsufficientPermissions, err)
+		require.Len(t, unlocker.accounts, 1)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("查看用户详情写入审计记录", func(t *testing.T) {
+		service, mockRepo, _ := newService()
+		mockRepo.On("RecordAudit", mock.MatchedBy(func(e *auditEntities.Log) bool {
+			return e.AdminID == 2 && e.Action == "user.view" && e.TargetID == "10" && e.IP == "203.0.113.5"
+		})).Return(nil).Once()
+
+		user, err := service.ViewUserDetail(ctx, 2, 10)
+		require.NoError(t, err)
+		require.Equal(t, int64(10), user.ID)
+
+		// 审计记录写入失败时不返回用户数据
+		mockRepo.On("RecordAudit", mock.Anything).Return(sql.ErrConnDone).Once()
+		user, err = service.ViewUserDetail(ctx, 2, 10)
+		require.Equal(t, sql.ErrConnDone, err)
+		require.Nil(t, user)
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+// fakeAccountUnlocker 记录被解锁的账户
+type fakeAccountUnlocker struct {
+	accounts []string
+}
+
+func (f *fakeAccountUnlocker) Reset(ctx context.Context, account string) error {
+	f.accounts = append(f.accounts, account)
+	return nil
+}
+
+// fakeOutbox 记录入队的邮件
+type fakeOutbox struct {
+	messages []*mailer.Message
+}
+
+func (f *fakeOutbox) Enqueue(ctx context.Context, msg *mailer.Message) error {
+	f.messages = append(f.messages, msg)
+	return nil
+}
+
+func TestService_AdminInvitation(t *testing.T) {
+	testutil.MockJWTConfig()
+	// 管理员1可以管理管理员账户并拥有 support 角色的权限，管理员2没有管理员账户管理权限
+	permissions := fakePermissionChecker{
+		1: {"admins.manage", "users.read"},
+		2: {"users.read"},
+	}
+	newService := func() (*Service, *MockAdminRepository, *fakeOutbox) {
+		mockRepo := new(MockAdminRepository)
+		outbox := &fakeOutbox{}
+		return &Service{adminRepo: mockRepo, permissions: permissions, mailOutbox: outbox, mailLocale: "en", inviteTTL: 72 * time.Hour}, mockRepo, outbox
+	}
+
+	// invite 发送邀请并从邀请邮件中取出令牌
+	invite := func(t *testing.T, service *Service, mockRepo *MockAdminRepository, outbox *fakeOutbox) (string, *entities.AdminInvitation) {
+		mockRepo.On("GetByEmail", "new@example.com").Return(nil, sql.ErrNoRows).Once()
+		mockRepo.On("CreateInvitation", mock.MatchedBy(func(inv *entities.AdminInvitation) bool {
+			return inv.Email == "new@example.com" && inv.Role == "support" && *inv.InvitedBy == 1 && len(inv.TokenHash) == 64
+		}), mock.MatchedBy(func(e *auditEntities.Log) bool {
+			return e.Action == "admin_invitation.create" && e.AdminID == 1 && e.Changes["email"].After == "new@example.com"
+		})).Return(nil).Once()
+
+		invitation, err := service.InviteAdmin(context.Background(), 1, &dto.InviteAdminRequest{Email: " New@Example.com ", Name: "New Admin", Role: "support"})
+		require.NoError(t, err)
+		require.Len(t, outbox.messages, 1)
+		require.Equal(t, "new@example.com", outbox.messages[0].To)
+
+		body := outbox.messages[0].TextBody
+		start := strings.Index(body, "token=")
+		require.NotEqual(t, -1, start)
+		token, err := url.QueryUnescape(strings.Fields(body[start+len("token="):])[0])
+		require.NoError(t, err)
+		require.Equal(t, invitation.TokenHash, hashInviteToken(token))
+		return token, invitation
+	}
+
+	t.Run("邀请并接受后创建激活的管理员", func(t *testing.T) {
+		service, mockRepo, outbox := newService()
+		token, invitation := invite(t, service, mockRepo, outbox)
+
+		invitation.ID = 5
+		mockRepo.On("GetInvitationByTokenHash", invitation.TokenHash).Return(invitation, nil)
+		mockRepo.On("GetByEmail", "new@example.com").Return(nil, sql.ErrNoRows).Once()
+		mockRepo.On("AcceptInvitation", int64(5), mock.MatchedBy(func(admin *entities.Admin) bool {
+			return admin.Email == "new@example.com" && admin.Name == "New Admin" && admin.Role == "support" &&
+				admin.Status == "active" && admin.EmailVerified && !admin.IsSuper &&
+				bcrypt.CompareHashAndPassword([]byte(admin.Password), []byte("s3cret-pass")) == nil
+		})).Return(nil)
+
+		resp, err := service.AcceptInvitation(&dto.AcceptInvitationRequest{Token: token, Password: "s3cret-pass"})
+		require.NoError(t, err)
+		require.Equal(t, "support", resp.Admin.Role)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("密码不满足密码策略", func(t *testing.T) {
+		service, mockRepo, outbox := newService()
+		service.passwordPolicy = password.NewPolicy(password.DefaultConfig(), nil)
+		token, invitation := invite(t, service, mockRepo, outbox)
+
+		mockRepo.On("GetInvitationByTokenHash", invitation.TokenHash).Return(invitation, nil)
+		mockRepo.On("GetByEmail", "new@example.com").Return(nil, sql.ErrNoRows)
+
+		_, err := service.AcceptInvitation(&dto.AcceptInvitationRequest{Token: token, Password: "password"})
+		violations, ok := password.Violations(err)
+		require.True(t, ok)
+		require.Equal(t, password.ReasonMissingDigit, violations[0].Code)
+		mockRepo.AssertNotCalled(t, "AcceptInvitation", mock.Anything, mock.Anything)
+	})
+
+	t.Run("已使用或撤销的邀请", func(t *testing.T) {
+		service, mockRepo, outbox := newService()
+		token, invitation := invite(t, service, mockRepo, outbox)
+
+		revokedAt := time.Now()
+		invitation.RevokedAt = &revokedAt
+		mockRepo.On("GetInvitationByTokenHash", invitation.TokenHash).Return(invitation, nil)
+
+		_, err := service.AcceptInvitation(&dto.AcceptInvitationRequest{Token: token, Password: "s3cret-pass"})
+		require.Equal(t, common.ErrInvitationInvalid, err)
+	})
+
+	t.Run("伪造或过期的邀请令牌", func(t *testing.T) {
+		service, _, _ := newService()
+		_, err := service.AcceptInvitation(&dto.AcceptInvitationRequest{Token: "1.forged.signature", Password: "s3cret-pass"})
+		require.Equal(t, common.ErrInvitationInvalid, err)
+
+		expired, err := newInviteToken(time.Now().Add(-time.Minute))
+		require.NoError(t, err)
+		_, err = service.AcceptInvitation(&dto.AcceptInvitationRequest{Token: expired, Password: "s3cret-pass"})
+		require.Equal(t, common.ErrTokenExpired, err)
+	})
+
+	t.Run("邀请校验", func(t *testing.T) {
+		service, mockRepo, outbox := newService()
+		_, err := service.InviteAdmin(context.Background(), 2, &dto.InviteAdminRequest{Email: "new@example.com", Name: "New", Role: "support"})
+		require.Equal(t, common.ErrInsufficientPermissions, err)
+
+		mockRepo.On("GetByEmail", "admin@example.com").Return(&entities.Admin{ID: 3}, nil)
+		_, err = service.InviteAdmin(context.Background(), 1, &dto.InviteAdminRequest{Email: "admin@example.com", Name: "Dup", Role: "support"})
+		require.Equal(t, common.ErrAdminEmailExists, err)
+
+		mockRepo.On("GetByEmail", "new@example.com").Return(nil, sql.ErrNoRows)
+		_, err = service.InviteAdmin(context.Background(), 1, &dto.InviteAdminRequest{Email: "new@example.com", Name: "New", Role: "missing"})
+		require.Equal(t, common.ErrNotFound, err)
+
+		// 不能邀请超出自己权限的角色
+		_, err = service.InviteAdmin(context.Background(), 1, &dto.InviteAdminRequest{Email: "new@example.com", Name: "New", Role: "super_admin"})
+		require.Equal(t, common.ErrInsufficientPermissions, err)
+		_, err = service.InviteAdmin(context.Background(), 1, &dto.InviteAdminRequest{Email: "new@example.com", Name: "New", Role: "admin"})
+		require.Equal(t, common.ErrInsufficientPermissions, err)
+		require.Empty(t, outbox.messages)
+		mockRepo.AssertNotCalled(t, "CreateInvitation", mock.Anything, mock.Anything)
+	})
+
+	t.Run("撤销邀请", func(t *testing.T) {
+		service, mockRepo, _ := newService()
+		mockRepo.On("RevokeInvitation", int64(5), mock.MatchedBy(func(e *auditEntities.Log) bool {
+			return e.Action == "admin_invitation.revoke" && e.AdminID == 1 && e.TargetID == "5"
+		})).Return(nil)
+		mockRepo.On("RevokeInvitation", int64(6), mock.Anything).Return(sql.ErrNoRows)
+
+		require.NoError(t, service.RevokeInvitation(context.Background(), 1, 5))
+		require.Equal(t, common.ErrNotFound, service.RevokeInvitation(context.Background(), 1, 6))
+		require.Equal(t, common.ErrInsufficientPermissions, service.RevokeInvitation(context.Background(), 2, 5))
+		mockRepo.AssertNumberOfCalls(t, "RevokeInvitation", 2)
+	})
+}
+
+func TestService_AdminAccountStatus(t *testing.T) {
+	permissions := fakePermissionChecker{
+		1: fakeRolePermissions["super_admin"],
+		2: {"users.read"},
+		7: {"admins.manage", "users.read", "users.suspend", "users.update"},
+	}
+	newService := func() (*Service, *MockAdminRepository) {
+		mockRepo := new(MockAdminRepository)
+		return &Service{adminRepo: mockRepo, permissions: permissions}, mockRepo
+	}
+
+	t.Run("停用管理员", func(t *testing.T) {
+		service, mockRepo := newService()
+		mockRepo.On("GetByID", int64(3)).Return(&entities.Admin{ID: 3, Status: "active"}, nil).Once()
+		mockRepo.On("DeactivateAdmin", int64(3), mock.MatchedBy(func(e *auditEntities.Log) bool {
+			return e.Action == "admin.deactivate" && e.AdminID == 1 && e.TargetID == "3" &&
+				e.Changes["status"] == auditEntities.Change{Before: "active", After: "inactive"}
+		})).Return(nil)
+		mockRepo.On("GetByID", int64(3)).Return(&entities.Admin{ID: 3, Status: "inactive"}, nil).Once()
+
+		resp, err := service.DeactivateAdmin(context.Background(), 1, 3)
+		require.NoError(t, err)
+		require.Equal(t, "inactive", resp.Admin.Status)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("不能停用最后一个超级管理员", func(t *testing.T) {
+		service, mockRepo := newService()
+		mockRepo.On("GetByID", int64(4)).Return(&entities.Admin{ID: 4, Role: "super_admin", Status: "active"}, nil)
+		mockRepo.On("DeactivateAdmin", int64(4), mock.Anything).Return(common.ErrLastSuperAdmin)
+
+		_, err := service.DeactivateAdmin(context.Background(), 1, 4)
+		require.Equal(t, common.ErrLastSuperAdmin, err)
+	})
+
+	t.Run("不能停用自己且需要权限", func(t *testing.T) {
+		service, mockRepo := newService()
+		_, err := service.DeactivateAdmin(context.Background(), 1, 1)
+		require.Equal(t, common.ErrValidation, err)
+		_, err = service.DeactivateAdmin(context.Background(), 2, 3)
+		require.Equal(t, common.ErrInsufficientPermissions, err)
+		mockRepo.AssertNotCalled(t, "DeactivateAdmin", mock.Anything, mock.Anything)
+	})
+
+	t.Run("不能停用权限更高的管理员", func(t *testing.T) {
+		service, mockRepo := newService()
+		mockRepo.On("GetByID", int64(4)).Return(&entities.Admin{ID: 4, Role: "super_admin", Status: "active"}, nil)
+		mockRepo.On("GetByID", int64(5)).Return(&entities.Admin{ID: 5, Role: "admin", IsSuper: true, Status: "active"}, nil)
+		mockRepo.On("GetByID", int64(8)).Return(&entities.Admin{ID: 8, Role: "super_admin", Status: "inactive"}, nil)
+
+		_, err := service.DeactivateAdmin(context.Background(), 7, 4)
+		require.Equal(t, common.ErrInsufficientPermissions, err)
+		_, err = service.DeactivateAdmin(context.Background(), 7, 5)
+		require.Equal(t, common.ErrInsufficientPermissions, err)
+		_, err = service.ReactivateAdmin(context.Background(), 7, 8)
+		require.Equal(t, common.ErrInsufficientPermissions, err)
+		mockRepo.AssertNotCalled(t, "DeactivateAdmin", mock.Anything, mock.Anything)
+		mockRepo.AssertNotCalled(t, "ReactivateAdmin", mock.Anything, mock.Anything)
+
+		// 权限范围内的管理员可以停用
+		mockRepo.On("GetByID", int64(3)).Return(&entities.Admin{ID: 3, Role: "support", Status: "active"}, nil)
+		mockRepo.On("DeactivateAdmin", int64(3), mock.Anything).Return(nil)
+		_, err = service.DeactivateAdmin(context.Background(), 7, 3)
+		require.NoError(t, err)
+	})
+
+	t.Run("恢复管理员", func(t *testing.T) {
+		service, mockRepo := newService()
+		mockRepo.On("GetByID", int64(3)).Return(&entities.Admin{ID: 3, Status: "inactive"}, nil).Once()
+		mockRepo.On("ReactivateAdmin", int64(3), mock.MatchedBy(func(e *auditEntities.Log) bool {
+			return e.Action == "admin.reactivate" && e.Changes["status"] == auditEntities.Change{Before: "inactive", After: "active"}
+		})).Return(nil)
+		mockRepo.On("GetByID", int64(3)).Return(&entities.Admin{ID: 3, Status: "active"}, nil).Once()
+
+		resp, err := service.ReactivateAdmin(context.Background(), 1, 3)
+		require.NoError(t, err)
+		require.Equal(t, "active", resp.Admin.Status)
+
+		// 已是激活状态
+		mockRepo.On("GetByID", int64(5)).Return(&entities.Admin{ID: 5, Status: "active"}, nil)
+		_, err = service.ReactivateAdmin(context.Background(), 1, 5)
+		require.Equal(t, common.ErrValidation, err)
+	})
+}
+
+func TestService_ImpersonateUser(t *testing.T) {
+	testutil.MockJWTConfig()
+	// 管理员1可以代登录并允许写操作，管理员2只能只读代登录，管理员3没有代登录权限
+	permissions := fakePermissionChecker{
+		1: {"users.impersonate", "users.update"},
+		2: {"users.impersonate"},
+		3: {"users.read"},
+	}
+	newService := func() (*Service, *MockAdminRepository) {
+		mockRepo := new(MockAdminRepository)
+		mockRepo.On("GetUserByID", int64(10)).Return(&entities.UserInfo{ID: 10, Email: "user@example.com", Status: "active"}, nil).Maybe()
+		return &Service{adminRepo: mockRepo, permissions: permissions, impersonationTTL: 10 * time.Minute}, mockRepo
+	}
+	userAgent := "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 Chrome/120.0 Safari/537.36"
+	ctx := context.Background()
+
+	t.Run("默认签发只读令牌并记录到用户登录记录", func(t *testing.T) {
+		service, mockRepo := newService()
+		mockRepo.On("RecordImpersonation",
+			mock.MatchedBy(func(s *entities.ImpersonationSession) bool {
+				return s.UserID == 10 && s.AdminID == 2 && s.IP == "10.0.0.1" && s.Browser == "Chrome" && s.Reason == "排查订单问题"
+			}),
+			mock.MatchedBy(func(h *entities.UserStatusHistory) bool {
+				return h.UserID == 10 && *h.AdminID == 2 && h.Action == entities.UserActionImpersonate
+			}),
+			mock.MatchedBy(func(e *auditEntities.Log) bool {
+				return e.Action == "user.impersonate" && e.Changes["read_only"] == auditEntities.Change{Before: nil, After: true}
+			})).Return(nil)
+
+		resp, err := service.ImpersonateUser(ctx, 2, 10, &dto.ImpersonateUserRequest{Reason: "排查订单问题"}, "10.0.0.1", userAgent)
+		require.NoError(t, err)
+		require.True(t, resp.ReadOnly)
+		require.Equal(t, int64(600), resp.ExpiresIn)
+
+		claims, err := auth.ValidateAccessToken(resp.AccessToken)
+		require.NoError(t, err)
+		require.Equal(t, int64(10), claims.UserID)
+		require.Equal(t, "user", claims.UserType)
+		require.Equal(t, int64(2), claims.ImpersonatorID)
+		require.True(t, claims.ReadOnly)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("允许写操作需要额外权限", func(t *testing.T) {
+		service, mockRepo := newService()
+		_, err := service.ImpersonateUser(ctx, 2, 10, &dto.ImpersonateUserRequest{Reason: "协助修改设置", AllowWrites: true}, "10.0.0.1", userAgent)
+		require.Equal(t, common.ErrInsufficientPermissions, err)
+
+		mockRepo.On("RecordImpersonation", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		resp, err := service.ImpersonateUser(ctx, 1, 10, &dto.ImpersonateUserRequest{Reason: "协助修改设置", AllowWrites: true}, "10.0.0.1", userAgent)
+		require.NoError(t, err)
+		require.False(t, resp.ReadOnly)
+	})
+
+	t.Run("没有代登录权限", func(t *testing.T) {
+		service, mockRepo := newService()
+		_, err := service.ImpersonateUser(ctx, 3, 10, &dto.ImpersonateUserRequest{Reason: "排查"}, "10.0.0.1", userAgent)
+		require.Equal(t, common.ErrInsufficientPermissions, err)
+		mockRepo.AssertNotCalled(t, "RecordImpersonation", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+// fakeLoginGuard 记录调用的登录失败锁定
+type fakeLoginGuard struct {
+	wait   time.Duration
+	fails  []string
+	resets []string
+}
+
+func (f *fakeLoginGuard) Check(ctx context.Context, account, ip string) (time.Duration, error) {
+	return f.wait, nil
+}
+
+func (f *fakeLoginGuard) Fail(ctx context.Context, account, ip string) (*lockout.Result, error) {
+	f.fails = append(f.fails, account+"|"+ip)
+	return &lockout.Result{}, nil
+}
+
+func (f *fakeLoginGuard) Reset(ctx context.Context, account string) error {
+	f.resets = append(f.resets, account)
+	return nil
+}
+
+func TestService_Reauthenticate(t *testing.T) {
+	testutil.MockJWTConfig()
+	hashed, err := bcrypt.GenerateFromPassword([]byte("current-password"), bcrypt.MinCost)
+	require.NoError(t, err)
+	newService := func(status string) (*Service, *MockAdminRepository, *MockVerificationService) {
+		mockRepo := new(MockAdminRepository)
+		mockVerification := new(MockVerificationService)
+		mockRepo.On("GetByID", int64(1)).Return(&entities.Admin{ID: 1, Email: "admin@example.com", Password: string(hashed), Role: "admin", Status: status}, nil)
+		return &Service{adminRepo: mockRepo, verificationService: mockVerification}, mockRepo, mockVerification
+	}
+
+	t.Run("密码验证签发提升令牌", func(t *testing.T) {
+		service, _, _ := newService("active")
+		resp, err := service.Reauthenticate(1, &dto.AdminReauthRequest{Password: "current-password"}, "10.0.0.1")
+		require.NoError(t, err)
+		require.Equal(t, auth.ACRPassword, resp.ACR)
+
+		claims, err := auth.ValidateAccessToken(resp.AccessToken)
+		require.NoError(t, err)
+		require.Equal(t, "admin", claims.UserType)
+		require.Equal(t, resp.AuthTime, claims.AuthTime)
+		require.True(t, claims.AuthenticatedWithin(time.Minute))
+	})
+
+	t.Run("验证码验证签发提升令牌", func(t *testing.T) {
+		service, _, mockVerification := newService("active")
+		mockVerification.On("VerifyCode", &verificationDto.VerifyCodeRequest{Target: "admin@example.com", Code: "123456", Type: "admin_reauth"}).
+			Return(&verificationDto.VerifyCodeResponse{Valid: true}, nil)
+
+		resp, err := service.Reauthenticate(1, &dto.AdminReauthRequest{Code: "123456"}, "10.0.0.1")
+		require.NoError(t, err)
+		require.Equal(t, auth.ACREmailCode, resp.ACR)
+		mockVerification.AssertExpectations(t)
+	})
+
+	t.Run("密码错误", func(t *testing.T) {
+		service, _, _ := newService("active")
+		_, err := service.Reauthenticate(1, &dto.AdminReauthRequest{Password: "wrong-password"}, "10.0.0.1")
+		require.Equal(t, common.ErrInvalidAdminCredentials, err)
+	})
+
+	t.Run("密码错误计数，锁定期间返回等待时间", func(t *testing.T) {
+		service, _, _ := newService("active")
+		guard := &fakeLoginGuard{}
+		service.loginGuard = guard
+
+		_, err := service.Reauthenticate(1, &dto.AdminReauthRequest{Password: "wrong-password"}, "10.0.0.1")
+		require.Equal(t, common.ErrInvalidAdminCredentials, err)
+		require.Equal(t, []string{"admin@example.com|10.0.0.1"}, guard.fails)
+
+		_, err = service.Reauthenticate(1, &dto.AdminReauthRequest{Password: "current-password"}, "10.0.0.1")
+		require.NoError(t, err)
+		require.Equal(t, []string{"admin@example.com"}, guard.resets)
+
+		// 锁定期间不校验密码
+		guard.wait = 90500 * time.Millisecond
+		resp, err := service.Reauthenticate(1, &dto.AdminReauthRequest{Password: "current-password"}, "10.0.0.1")
+		require.Equal(t, common.ErrLoginLocked, err)
+		require.Equal(t, 91, resp.RetryAfter)
+	})
+
+	t.Run("密码和验证码必须二选一", func(t *testing.T) {
+		service, mockRepo, _ := newService("active")
+		_, err := service.Reauthenticate(1, &dto.AdminReauthRequest{}, "10.0.0.1")
+		require.Equal(t, common.ErrValidation, err)
+		_, err = service.Reauthenticate(1, &dto.AdminReauthRequest{Password: "current-password", Code: "123456"}, "10.0.0.1")
+		require.Equal(t, common.ErrValidation, err)
+		mockRepo.AssertNotCalled(t, "GetByID", mock.Anything)
+	})
+
+	t.Run("已停用的管理员", func(t *testing.T) {
+		service, _, _ := newService("inactive")
+		_, err := service.Reauthenticate(1, &dto.AdminReauthRequest{Password: "current-password"}, "10.0.0.1")
+		require.Equal(t, common.ErrAdminInactive, err)
+	})
+}
+
+func TestService_SessionLimit(t *testing.T) {
+	testutil.MockJWTConfig()
+	admin := &entities.Admin{ID: 1, Email: "admin@example.com", Role: "admin", Status: "active"}
+	active := []*entities.AdminRefreshToken{
+		{ID: 21, AdminID: 1, CreatedAt: time.Now().Add(-2 * time.Hour)},
+		{ID: 22, AdminID: 1, CreatedAt: time.Now().Add(-time.Hour)},
+	}
+
+	t.Run("拒绝策略下登录失败且不签发令牌", func(t *testing.T) {
+		mockRepo := new(MockAdminRepository)
+		mockVerification := new(MockVerificationService)
+		mockRepo.On("GetByEmail", "admin@example.com").Return(admin, nil)
+		mockVerification.On("VerifyCode", mock.Anything).Return(&verificationDto.VerifyCodeResponse{Valid: true}, nil)
+		mockRepo.On("ListActiveRefreshTokens", int64(1)).Return(active, nil)
+
+		service := &Service{
+			adminRepo:           mockRepo,
+			verificationService: mockVerification,
+			sessionLimiter:      sessionlimit.NewLimiter(&sessionlimit.Config{MaxAdminSessions: 2, Policy: sessionlimit.PolicyReject}),
+		}
+		_, err := service.LoginVerify(&dto.AdminLoginVerifyRequest{Email: "admin@example.com", Code: "123456"}, "10.0.0.1", "test-agent")
+
+		require.Equal(t, common.ErrSessionLimitReached, err)
+		mockRepo.AssertNotCalled(t, "CreateRefreshToken", mock.Anything)
+	})
+
+	t.Run("踢出最早的会话，被踢出的令牌刷新时返回明确错误", func(t *testing.T) {
+		mockRepo := new(MockAdminRepository)
+		mockRepo.On("ListActiveRefreshTokens", int64(1)).Return(active, nil)
+		mockRepo.On("EvictRefreshTokens", int64(1), []int64{21}).Return(nil)
+		mockRepo.On("IsRefreshTokenEvicted", "evicted-token").Return(true, nil)
+
+		service := &Service{
+			adminRepo:      mockRepo,
+			sessionLimiter: sessionlimit.NewLimiter(&sessionlimit.Config{MaxAdminSessions: 2, Policy: sessionlimit.PolicyEvictOldest}),
+		}
+		require.NoError(t, service.admitSession(admin, "test-agent"))
+		require.Equal(t, common.ErrSessionEvicted, service.refreshTokenError("evicted-token"))
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("被踢出的会话邮件通知管理员", func(t *testing.T) {
+		mockRepo := new(MockAdminRepository)
+		mockRepo.On("ListActiveRefreshTokens", int64(1)).Return(active, nil)
+		mockRepo.On("EvictRefreshTokens", int64(1), []int64{21}).Return(nil)
+
+		outbox := &fakeOutbox{}
+		service := &Service{
+			adminRepo:      mockRepo,
+			mailOutbox:     outbox,
+			mailLocale:     "en",
+			sessionLimiter: sessionlimit.NewLimiter(&sessionlimit.Config{MaxAdminSessions: 2, Policy: sessionlimit.PolicyEvictOldest}),
+		}
+		require.NoError(t, service.admitSession(admin, "Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:121.0) Gecko/20100101 Firefox/121.0"))
+
+		require.Len(t, outbox.messages, 1)
+		require.Equal(t, "admin@example.com", outbox.messages[0].To)
+		require.Contains(t, outbox.messages[0].TextBody, "Firefox / Windows")
+	})
+}
+
+func TestService_LoginProtection(t *testing.T) {
+	testutil.MockJWTConfig()
+	hashed, err := bcrypt.GenerateFromPassword([]byte("current-password"), bcrypt.MinCost)
+	require.NoError(t, err)
+	admin := &entities.Admin{ID: 1, Email: "admin@example.com", Password: string(hashed), Role: "admin", Status: "active"}
+	userAgent := "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 Chrome/120.0 Safari/537.36"
+
+	t.Run("管理员不存在与密码错误返回相同的错误并计数", func(t *testing.T) {
+		mockRepo := new(MockAdminRepository)
+		mockRepo.On("GetByEmail", "admin@example.com").Return(admin, nil)
+		mockRepo.On("GetByEmail", "nobody@example.com").Return(nil, sql.ErrNoRows)
+		mockRepo.On("CreateLoginSession", mock.AnythingOfType("*entities.AdminLoginSession")).Return(nil)
+		guard := &fakeLoginGuard{}
+		service := &Service{adminRepo: mockRepo, loginGuard: guard}
+
+		_, wrongPassword := service.Login(&dto.AdminLoginRequest{Email: "admin@example.com", Password: "wrong-password"}, "10.0.0.1", userAgent)
+		_, unknownAdmin := service.Login(&dto.AdminLoginRequest{Email: "nobody@example.com", Password: "wrong-password"}, "10.0.0.1", userAgent)
+
+		require.Equal(t, common.ErrInvalidCredentials, wrongPassword)
+		require.Equal(t, common.ErrInvalidCredentials, unknownAdmin)
+		require.Equal(t, []string{"admin@example.com|10.0.0.1", "nobody@example.com|10.0.0.1"}, guard.fails)
+	})
+
+	t.Run("锁定期间不校验密码并返回等待时间", func(t *testing.T) {
+		mockRepo := new(MockAdminRepository)
+		mockRepo.On("CreateLoginSession", mock.AnythingOfType("*entities.AdminLoginSession")).Return(nil)
+		service := &Service{adminRepo: mockRepo, loginGuard: &fakeLoginGuard{wait: 30 * time.Second}}
+
+		resp, err := service.Login(&dto.AdminLoginRequest{Email: "admin@example.com", Password: "current-password"}, "10.0.0.1", userAgent)
+
+		require.Equal(t, common.ErrLoginLocked, err)
+		require.Equal(t, 30, resp.RetryAfter)
+		mockRepo.AssertNotCalled(t, "GetByEmail", mock.Anything)
+	})
+
+	t.Run("密码正确时清除失败次数并发送验证码", func(t *testing.T) {
+		mockRepo := new(MockAdminRepository)
+		mockVerification := new(MockVerificationService)
+		mockRepo.On("GetByEmail", "admin@example.com").Return(admin, nil)
+		mockVerification.On("SendVerificationCode", mock.Anything).Return(&verificationDto.SendVerificationResponse{}, nil)
+		guard := &fakeLoginGuard{}
+		service := &Service{adminRepo: mockRepo, verificationService: mockVerification, loginGuard: guard}
+
+		_, err := service.Login(&dto.AdminLoginRequest{Email: "admin@example.com", Password: "current-password"}, "10.0.0.1", userAgent)
+
+		require.NoError(t, err)
+		require.Equal(t, []string{"admin@example.com"}, guard.resets)
+	})
+
+	t.Run("可疑登录拒绝并邮件通知管理员", func(t *testing.T) {
+		mockRepo := new(MockAdminRepository)
+		mockVerification := new(MockVerificationService)
+		mockIPInfo := new(MockIPInfoClient)
+		mockRepo.On("GetByEmail", "admin@example.com").Return(admin, nil)
+		mockVerification.On("VerifyCode", mock.Anything).Return(&verificationDto.VerifyCodeResponse{Valid: true}, nil)
+		mockIPInfo.On("GetIPInfo", mock.Anything, "10.0.0.1").Return(&ipinfo.IPInfo{Country: "US", City: "San Francisco", Loc: "37.7749,-122.4194"}, nil)
+		mockRepo.On("GetRecentLoginSessions", int64(1), 20).Return([]*entities.AdminLoginSession{{
+			AdminID: 1, Country: "CN", Location: "31.2304,121.4737", DeviceType: "desktop", OS: "macOS", Browser: "Chrome",
+			Status: "success", CreatedAt: time.Now().Add(-time.Hour),
+		}}, nil)
+		mockRepo.On("CreateLoginSession", mock.MatchedBy(func(session *entities.AdminLoginSession) bool {
+			return session.Status == "failed" && session.RiskDecision == "block"
+		})).Return(nil)
+		outbox := &fakeOutbox{}
+		service := &Service{
+			adminRepo:           mockRepo,
+			verificationService: mockVerification,
+			ipinfoClient:        mockIPInfo,
+			riskEvaluator:       loginrisk.NewEvaluator(nil),
+			mailOutbox:          outbox,
+			mailLocale:          "en",
+		}
+
+		resp, err := service.LoginVerify(&dto.AdminLoginVerifyRequest{Email: "admin@example.com", Code: "123456"}, "10.0.0.1", userAgent)
+
+		require.Equal(t, common.ErrLoginBlocked, err)
+		require.Nil(t, resp)
+		mockRepo.AssertNotCalled(t, "CreateRefreshToken", mock.Anything)
+		require.Len(t, outbox.messages, 1)
+		require.Equal(t, "admin@example.com", outbox.messages[0].To)
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func TestService_ResetPassword(t *testing.T) {
+	testutil.MockJWTConfig()
+	hashed, err := bcrypt.GenerateFromPassword([]byte("current-password1"), bcrypt.MinCost)
+	require.NoError(t, err)
+	admin := &entities.Admin{ID: 1, Email: "admin@example.com", Password: string(hashed), Role: "admin", Status: "active"}
+	request := func(newPassword string) *dto.AdminResetPasswordRequest {
+		return &dto.AdminResetPasswordRequest{Email: "admin@example.com", Code: "123456", Password: newPassword}
+	}
+	newService := func() (*Service, *MockAdminRepository, *fakeLoginGuard) {
+		mockRepo := new(MockAdminRepository)
+		mockVerification := new(MockVerificationService)
+		mockRepo.On("GetByEmail", "admin@example.com").Return(admin, nil)
+		mockRepo.On("ListPasswordHistory", int64(1), 5).Return([]string{}, nil)
+		mockVerification.On("VerifyCode", mock.Anything).Return(&verificationDto.VerifyCodeResponse{Valid: true}, nil)
+		guard := &fakeLoginGuard{}
+		return &Service{
+			adminRepo:           mockRepo,
+			verificationService: mockVerification,
+			loginGuard:          guard,
+			passwordPolicy:      password.NewPolicy(&password.Config{MinLength: 10, RequireDigit: true, HistorySize: 5}, nil),
+		}, mockRepo, guard
+	}
+
+	t.Run("新密码不满足密码策略或与当前密码相同", func(t *testing.T) {
+		for _, plain := range []string{"short1", "current-password1"} {
+			service, mockRepo, _ := newService()
+			_, err := service.ResetPassword(request(plain))
+			_, ok := password.Violations(err)
+			require.True(t, ok, plain)
+			mockRepo.AssertNotCalled(t, "UpdatePassword", mock.Anything, mock.Anything)
+		}
+	})
+
+	t.Run("重置成功后记录密码历史并清除登录锁定", func(t *testing.T) {
+		service, mockRepo, guard := newService()
+		mockRepo.On("UpdatePassword", int64(1), mock.Anything).Return(nil)
+		mockRepo.On("InvalidateAllRefreshTokens", int64(1)).Return(nil)
+		mockRepo.On("AddPasswordHistory", int64(1), mock.Anything, 5).Return(nil)
+
+		_, err := service.ResetPassword(request("brand-new-password1"))
+
+		require.NoError(t, err)
+		require.Equal(t, []string{"admin@example.com"}, guard.resets)
+		mockRepo.AssertExpectations(t)
+	})
+}
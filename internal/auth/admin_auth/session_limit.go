@@ -0,0 +1,47 @@
+package admin
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"trusioo_api/internal/auth/admin_auth/entities"
+	"trusioo_api/internal/auth/sessionlimit"
+)
+
+// admitSession 签发新的刷新令牌前执行同时登录会话上限
+// 按策略拒绝新登录，或使最早的会话失效并邮件通知管理员
+func (s *Service) admitSession(admin *entities.Admin, userAgent string) error {
+	evicted, err := s.identityCore().AdmitSession(admin.ID)
+	if err != nil {
+		return err
+	}
+	if len(evicted) > 0 {
+		s.sendSessionEvictedEmail(admin, evicted, userAgent)
+	}
+	return nil
+}
+
+// refreshTokenError 刷新令牌无效时区分是否因新登录被踢出
+func (s *Service) refreshTokenError(token string) error {
+	return s.identityCore().RefreshTokenError(token)
+}
+
+// sendSessionEvictedEmail 通知管理员哪些设备因新登录被踢出
+func (s *Service) sendSessionEvictedEmail(admin *entities.Admin, evicted []sessionlimit.Session, userAgent string) {
+	if s.mailOutbox == nil {
+		return
+	}
+
+	msg, err := s.identityCore().SessionEvictedMessage(admin.Email, s.mailLocale, evicted, userAgent)
+	if err != nil {
+		log.Printf("渲染管理员会话踢出通知失败: %v", err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.mailOutbox.Enqueue(ctx, msg); err != nil {
+		log.Printf("发送管理员会话踢出通知失败 %d: %v", admin.ID, err)
+	}
+}
@@ -0,0 +1,302 @@
+package admin
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"trusioo_api/internal/audit"
+	auditEntities "trusioo_api/internal/audit/entities"
+	"trusioo_api/internal/auth/admin_auth/dto"
+	"trusioo_api/internal/auth/admin_auth/entities"
+	"trusioo_api/internal/auth/identity"
+	"trusioo_api/internal/auth/rbac"
+	verificationDto "trusioo_api/internal/auth/verification/dto"
+	verificationEntities "trusioo_api/internal/auth/verification/entities"
+	"trusioo_api/internal/common"
+	"trusioo_api/pkg/auth"
+)
+
+// AccessControl 管理员权限与角色查询接口，由 rbac.Service 实现
+type AccessControl interface {
+	HasPermission(ctx context.Context, adminID int64, permission string) (bool, error)
+	// AuthorizeRoleGrant 校验管理员可以授予角色，角色不存在时返回 common.ErrNotFound
+	AuthorizeRoleGrant(ctx context.Context, actorID int64, role string) error
+	// InvalidateAdmin 管理员状态变化后清除其权限缓存
+	InvalidateAdmin(ctx context.Context, adminID int64)
+}
+
+// SuspendUser 暂停用户并使其全部刷新令牌失效，until 为空时无限期暂停
+func (s *Service) SuspendUser(ctx context.Context, adminID, userID int64, req *dto.SuspendUserRequest) (*dto.UserActionResponse, error) {
+	user, err := s.prepareUserAction(ctx, adminID, userID, rbac.PermUsersSuspend, req.Reason)
+	if err != nil {
+		return nil, err
+	}
+	if req.Until != nil && !req.Until.After(time.Now()) {
+		return nil, common.ErrValidation
+	}
+
+	history := newUserActionHistory(adminID, user, entities.UserActionSuspend, req.Reason)
+	history.ToStatus = "suspended"
+	history.ExpiresAt = req.Until
+	entry := newUserAuditLog(ctx, history)
+	if req.Until != nil {
+		entry.Changes.Set("suspended_until", nil, req.Until.UTC().Format(time.RFC3339))
+	}
+	if err := s.adminRepo.UpdateUserStatus(ctx, "suspended", req.Until, history, entry); err != nil {
+		return nil, err
+	}
+
+	return s.userActionResponse("用户已暂停", userID)
+}
+
+// ReactivateUser 恢复被暂停或停用的用户
+func (s *Service) ReactivateUser(ctx context.Context, adminID, userID int64, req *dto.UserActionRequest) (*dto.UserActionResponse, error) {
+	user, err := s.prepareUserAction(ctx, adminID, userID, rbac.PermUsersSuspend, req.Reason)
+	if err != nil {
+		return nil, err
+	}
+	if user.Status == "active" {
+		return nil, common.ErrValidation
+	}
+
+	history := newUserActionHistory(adminID, user, entities.UserActionReactivate, req.Reason)
+	history.ToStatus = "active"
+	if err := s.adminRepo.UpdateUserStatus(ctx, "active", nil, history, newUserAuditLog(ctx, history)); err != nil {
+		return nil, err
+	}
+
+	return s.userActionResponse("用户已恢复", userID)
+}
+
+// ForceLogoutUser 强制用户在所有设备上退出登录
+// 已签发的访问令牌在过期前仍然有效，刷新令牌立即失效
+func (s *Service) ForceLogoutUser(ctx context.Context, adminID, userID int64, req *dto.UserActionRequest) (*dto.UserActionResponse, error) {
+	user, err := s.prepareUserAction(ctx, adminID, userID, rbac.PermUsersSuspend, req.Reason)
+	if err != nil {
+		return nil, err
+	}
+
+	history := newUserActionHistory(adminID, user, entities.UserActionForceLogout, req.Reason)
+	if err := s.adminRepo.ForceLogoutUser(ctx, history, newUserAuditLog(ctx, history)); err != nil {
+		return nil, err
+	}
+
+	return s.userActionResponse("用户已在所有设备上退出登录", userID)
+}
+
+// UnlockUser 解除用户因登录失败次数过多产生的锁定和等待
+func (s *Service) UnlockUser(ctx context.Context, adminID, userID int64, req *dto.UserActionRequest) (*dto.UserActionResponse, error) {
+	user, err := s.prepareUserAction(ctx, adminID, userID, rbac.PermUsersSuspend, req.Reason)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.loginLockout != nil {
+		if err := s.loginLockout.Reset(ctx, user.Email); err != nil {
+			return nil, err
+		}
+	}
+
+	history := newUserActionHistory(adminID, user, entities.UserActionUnlock, req.Reason)
+	if err := s.adminRepo.CreateUserStatusHistory(ctx, history, newUserAuditLog(ctx, history)); err != nil {
+		return nil, err
+	}
+
+	return s.userActionResponse("用户登录锁定已解除", userID)
+}
+
+// SendUserPasswordReset 向用户邮箱发送重置密码验证码，用户通过忘记密码流程完成重置
+func (s *Service) SendUserPasswordReset(ctx context.Context, adminID, userID int64, req *dto.UserActionRequest) (*dto.UserActionResponse, error) {
+	user, err := s.prepareUserAction(ctx, adminID, userID, rbac.PermUsersUpdate, req.Reason)
+	if err != nil {
+		return nil, err
+	}
+
+	sendReq := &verificationDto.SendVerificationRequest{
+		Target: user.Email,
+		Type:   verificationEntities.TypeForgotPassword,
+	}
+	if _, err := s.verificationService.SendVerificationCode(sendReq); err != nil {
+		return nil, err
+	}
+
+	history := newUserActionHistory(adminID, user, entities.UserActionPasswordReset, req.Reason)
+	if err := s.adminRepo.CreateUserStatusHistory(ctx, history, newUserAuditLog(ctx, history)); err != nil {
+		return nil, err
+	}
+
+	return s.userActionResponse("重置密码验证码已发送到用户邮箱", userID)
+}
+
+// VerifyUserContact 手动标记用户邮箱或手机号为已验证
+func (s *Service) VerifyUserContact(ctx context.Context, adminID, userID int64, req *dto.VerifyUserContactRequest) (*dto.UserActionResponse, error) {
+	user, err := s.prepareUserAction(ctx, adminID, userID, rbac.PermUsersUpdate, req.Reason)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Field == "phone" {
+		if user.Phone == nil || *user.Phone == "" {
+			return nil, common.ErrInvalidPhone
+		}
+		history := newUserActionHistory(adminID, user, entities.UserActionVerifyPhone, req.Reason)
+		entry := newUserAuditLog(ctx, history)
+		entry.Changes.Set("phone_verified", user.PhoneVerified, true)
+		if err := s.adminRepo.MarkUserPhoneVerified(ctx, history, entry); err != nil {
+			return nil, err
+		}
+		return s.userActionResponse("手机号已标记为已验证", userID)
+	}
+
+	history := newUserActionHistory(adminID, user, entities.UserActionVerifyEmail, req.Reason)
+	entry := newUserAuditLog(ctx, history)
+	entry.Changes.Set("email_verified", user.EmailVerified, true)
+	if err := s.adminRepo.MarkUserEmailVerified(ctx, history, entry); err != nil {
+		return nil, err
+	}
+	return s.userActionResponse("邮箱已标记为已验证", userID)
+}
+
+// defaultImpersonationTTL 未配置代登录令牌有效期时使用
+const defaultImpersonationTTL = 15 * time.Minute
+
+// ImpersonateUser 以用户身份签发短期访问令牌，令牌中记录代登录的管理员
+// 默认只读，允许写操作需要额外的 users.update 权限；代登录记录写入用户的登录记录
+func (s *Service) ImpersonateUser(ctx context.Context, adminID, userID int64, req *dto.ImpersonateUserRequest, clientIP, userAgent string) (*dto.ImpersonationResponse, error) {
+	user, err := s.prepareUserAction(ctx, adminID, userID, rbac.PermUsersImpersonate, req.Reason)
+	if err != nil {
+		return nil, err
+	}
+	if req.AllowWrites {
+		if err := s.authorize(ctx, adminID, rbac.PermUsersUpdate); err != nil {
+			return nil, err
+		}
+	}
+
+	ttl := s.impersonationTTL
+	if ttl <= 0 {
+		ttl = defaultImpersonationTTL
+	}
+	readOnly := !req.AllowWrites
+	token, err := auth.GenerateImpersonationToken(user.ID, user.Email, adminID, readOnly, ttl)
+	if err != nil {
+		return nil, err
+	}
+
+	device := identity.ParseUserAgent(userAgent)
+	session := &entities.ImpersonationSession{
+		UserID:     user.ID,
+		AdminID:    adminID,
+		IP:         clientIP,
+		UserAgent:  userAgent,
+		DeviceType: device.DeviceType,
+		OS:         device.OS,
+		Browser:    device.Browser,
+		Platform:   device.Platform,
+		Reason:     strings.TrimSpace(req.Reason),
+	}
+	history := newUserActionHistory(adminID, user, entities.UserActionImpersonate, req.Reason)
+	entry := newUserAuditLog(ctx, history)
+	entry.Changes.Set("read_only", nil, readOnly)
+	if err := s.adminRepo.RecordImpersonation(ctx, session, history, entry); err != nil {
+		return nil, err
+	}
+
+	return &dto.ImpersonationResponse{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(ttl / time.Second),
+		ReadOnly:    readOnly,
+		User:        user,
+	}, nil
+}
+
+// ViewUserDetail 获取用户详情并记录查看操作，审计记录写入失败时不返回用户数据
+func (s *Service) ViewUserDetail(ctx context.Context, adminID, userID int64) (*entities.UserInfo, error) {
+	user, err := s.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	entry := audit.NewLog(ctx, adminID, "user.view", auditEntities.TargetUser, strconv.FormatInt(userID, 10))
+	if err := s.adminRepo.RecordAudit(ctx, entry); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// GetUserStatusHistory 获取用户的状态变更与管理操作记录
+func (s *Service) GetUserStatusHistory(ctx context.Context, adminID, userID int64) ([]*entities.UserStatusHistory, error) {
+	if err := s.authorize(ctx, adminID, rbac.PermUsersRead); err != nil {
+		return nil, err
+	}
+	if _, err := s.GetUserByID(userID); err != nil {
+		return nil, err
+	}
+	return s.adminRepo.GetUserStatusHistory(userID)
+}
+
+// authorize 校验管理员拥有指定权限，未配置权限服务时拒绝
+func (s *Service) authorize(ctx context.Context, adminID int64, permission string) error {
+	if s.permissions == nil {
+		return common.ErrInsufficientPermissions
+	}
+	allowed, err := s.permissions.HasPermission(ctx, adminID, permission)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return common.ErrInsufficientPermissions
+	}
+	return nil
+}
+
+// prepareUserAction 校验操作权限和原因并获取目标用户，已注销的用户视为不存在
+func (s *Service) prepareUserAction(ctx context.Context, adminID, userID int64, permission, reason string) (*entities.UserInfo, error) {
+	if err := s.authorize(ctx, adminID, permission); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(reason) == "" {
+		return nil, common.ErrValidation
+	}
+	user, err := s.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.Status == "deleted" {
+		return nil, common.ErrUserNotFound
+	}
+	return user, nil
+}
+
+// userActionResponse 返回操作后的用户信息
+func (s *Service) userActionResponse(message string, userID int64) (*dto.UserActionResponse, error) {
+	user, err := s.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	return &dto.UserActionResponse{
+		Message: message,
+		User:    user,
+	}, nil
+}
+
+// newUserActionHistory 创建操作记录，不改变状态的操作前后状态相同
+func newUserActionHistory(adminID int64, user *entities.UserInfo, action, reason string) *entities.UserStatusHistory {
+	return &entities.UserStatusHistory{
+		UserID:     user.ID,
+		AdminID:    &adminID,
+		Action:     action,
+		FromStatus: user.Status,
+		ToStatus:   user.Status,
+		Reason:     strings.TrimSpace(reason),
+	}
+}
+
+// newUserAuditLog 根据操作记录创建审计记录，action 为 user.<操作>
+func newUserAuditLog(ctx context.Context, history *entities.UserStatusHistory) *auditEntities.Log {
+	entry := audit.NewLog(ctx, *history.AdminID, "user."+history.Action, auditEntities.TargetUser, strconv.FormatInt(history.UserID, 10))
+	entry.Changes.Set("status", history.FromStatus, history.ToStatus)
+	return audit.WithReason(entry, history.Reason)
+}
@@ -0,0 +1,86 @@
+package challenge
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"trusioo_api/config"
+)
+
+// CaptchaVerifier 校验客户端提交的验证码响应
+type CaptchaVerifier interface {
+	Verify(ctx context.Context, response, remoteIP string) (bool, error)
+}
+
+// SiteVerifyClient 通过 siteverify 接口校验验证码，reCAPTCHA、hCaptcha 和 Cloudflare Turnstile 使用相同的请求格式
+type SiteVerifyClient struct {
+	verifyURL  string
+	secret     string
+	httpClient *http.Client
+}
+
+// NewSiteVerifyClient 创建验证码校验客户端
+func NewSiteVerifyClient(verifyURL, secret string) *SiteVerifyClient {
+	return &SiteVerifyClient{
+		verifyURL:  verifyURL,
+		secret:     secret,
+		httpClient: &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+// NewCaptchaVerifierFromApp 未配置校验地址时返回 nil，此时只支持工作量证明
+func NewCaptchaVerifierFromApp(appConfig *config.Config) CaptchaVerifier {
+	if appConfig == nil || appConfig.Challenge.CaptchaVerifyURL == "" {
+		return nil
+	}
+	return NewSiteVerifyClient(appConfig.Challenge.CaptchaVerifyURL, appConfig.Challenge.CaptchaSecret)
+}
+
+// Verify 提交验证码响应，服务返回 success 为 true 时通过
+func (c *SiteVerifyClient) Verify(ctx context.Context, response, remoteIP string) (bool, error) {
+	form := url.Values{
+		"secret":   {c.secret},
+		"response": {response},
+	}
+	if remoteIP != "" {
+		form.Set("remoteip", remoteIP)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.verifyURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("captcha siteverify returned status %d", resp.StatusCode)
+	}
+	var result struct {
+		Success bool `json:"success"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return false, err
+	}
+	return result.Success, nil
+}
+
+// FakeVerifier 测试与本地开发使用，只接受 Valid 指定的响应
+type FakeVerifier struct {
+	Valid string
+}
+
+// Verify 响应与 Valid 相同时通过
+func (f *FakeVerifier) Verify(ctx context.Context, response, remoteIP string) (bool, error) {
+	return f.Valid != "" && response == f.Valid, nil
+}
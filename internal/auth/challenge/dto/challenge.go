@@ -0,0 +1,20 @@
+package dto
+
+import "time"
+
+// IssueRequest 申请挑战，action 为挑战要保护的操作
+type IssueRequest struct {
+	Action string `json:"action" binding:"required,oneof=register login login_code login_phone forgot_password admin_login"`
+}
+
+// ChallengeResponse 工作量证明挑战
+// 客户端寻找 solution，使 SHA-256(token + ":" + solution) 的前导零比特数不少于 difficulty，
+// 然后在请求头 X-Challenge-Token 和 X-Challenge-Solution 中提交；配置了验证码时也可以提交 X-Captcha-Response
+type ChallengeResponse struct {
+	Token          string    `json:"token"`
+	Algorithm      string    `json:"algorithm"`
+	Difficulty     int       `json:"difficulty"`
+	ExpiresAt      time.Time `json:"expires_at"`
+	ExpiresIn      int       `json:"expires_in"`                 // 秒
+	CaptchaSiteKey string    `json:"captcha_site_key,omitempty"` // 配置了验证码服务时返回
+}
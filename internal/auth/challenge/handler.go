@@ -0,0 +1,45 @@
+package challenge
+
+import (
+	"trusioo_api/internal/auth/challenge/dto"
+	"trusioo_api/internal/common"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// IssueChallenge 申请挑战
+// @Summary 申请注册与登录挑战
+// @Description 注册、登录、忘记密码返回 428 和 challenge_required 时申请工作量证明挑战，难度随该IP和网段最近的请求与失败次数增长；
+// @Description 客户端找到使 SHA-256(token + ":" + solution) 前导零比特数不少于 difficulty 的 solution 后，在 X-Challenge-Token 和 X-Challenge-Solution 请求头中提交；
+// @Description 返回 captcha_site_key 时也可以完成验证码后在 X-Captcha-Response 请求头中提交
+// @Tags 认证
+// @Accept json
+// @Produce json
+// @Param request body dto.IssueRequest true "要保护的操作"
+// @Success 200 {object} common.Response{data=dto.ChallengeResponse} "签发成功"
+// @Failure 400 {object} common.Response "参数错误"
+// @Failure 500 {object} common.Response "服务器错误"
+// @Router /api/v1/auth/challenge [post]
+func (h *Handler) IssueChallenge(c *gin.Context) {
+	var req dto.IssueRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ValidationError(c, err.Error())
+		return
+	}
+
+	resp, err := h.service.Issue(c.Request.Context(), req.Action, c.ClientIP())
+	if err != nil {
+		common.ServerError(c, err)
+		return
+	}
+
+	common.Success(c, resp)
+}
@@ -0,0 +1,10 @@
+package challenge
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterRoutes 注册挑战路由，router 为 /auth 路由组
+func RegisterRoutes(router *gin.RouterGroup, handler *Handler) {
+	router.POST("/challenge", handler.IssueChallenge) // 申请工作量证明挑战，无需认证
+}
@@ -0,0 +1,310 @@
+package challenge
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"math"
+	"math/bits"
+	"net"
+	"strings"
+	"time"
+
+	"trusioo_api/config"
+	"trusioo_api/internal/auth/challenge/dto"
+	"trusioo_api/internal/common"
+)
+
+// 需要挑战的操作，新增操作时同步修改 dto.IssueRequest 的 oneof 校验
+const (
+	ActionRegister       = "register"
+	ActionLogin          = "login"
+	ActionLoginCode      = "login_code"
+	ActionLoginPhone     = "login_phone"
+	ActionForgotPassword = "forgot_password"
+	ActionAdminLogin     = "admin_login"
+)
+
+// Algorithm 工作量证明使用的哈希算法
+const Algorithm = "sha256"
+
+// Store 请求计数与已使用的挑战，由 redis.CacheService 实现
+type Store interface {
+	Increment(ctx context.Context, key string, expiration time.Duration) (int64, error)
+	Count(ctx context.Context, key string) (int64, error)
+	SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) (bool, error)
+}
+
+// Config 挑战配置
+type Config struct {
+	Secret          []byte
+	TTL             time.Duration
+	Window          time.Duration // 请求与失败次数的统计窗口
+	IPThreshold     int           // 同一IP达到此分数后要求挑战
+	SubnetThreshold int           // 同一网段达到此分数后要求挑战
+	BaseDifficulty  int           // 前导零比特数，分数每超过阈值一倍加 1
+	MaxDifficulty   int
+	CaptchaSiteKey  string
+}
+
+// DefaultConfig 默认配置
+func DefaultConfig() *Config {
+	return &Config{
+		TTL:             2 * time.Minute,
+		Window:          10 * time.Minute,
+		IPThreshold:     10,
+		SubnetThreshold: 40,
+		BaseDifficulty:  16,
+		MaxDifficulty:   24,
+	}
+}
+
+// NewConfigFromApp 从应用配置创建，未启用时返回 nil；未配置签名密钥时使用 JWT 密钥
+func NewConfigFromApp(appConfig *config.Config) *Config {
+	cfg := DefaultConfig()
+	if appConfig == nil {
+		return cfg
+	}
+	if !appConfig.Challenge.Enabled {
+		return nil
+	}
+
+	cc := appConfig.Challenge
+	cfg.Secret = []byte(cc.Secret)
+	if len(cfg.Secret) == 0 {
+		cfg.Secret = []byte(appConfig.JWT.Secret)
+	}
+	if cc.TTLSeconds > 0 {
+		cfg.TTL = time.Duration(cc.TTLSeconds) * time.Second
+	}
+	if cc.WindowMinutes > 0 {
+		cfg.Window = time.Duration(cc.WindowMinutes) * time.Minute
+	}
+	if cc.IPThreshold > 0 {
+		cfg.IPThreshold = cc.IPThreshold
+	}
+	if cc.SubnetThreshold > 0 {
+		cfg.SubnetThreshold = cc.SubnetThreshold
+	}
+	if cc.BaseDifficulty > 0 {
+		cfg.BaseDifficulty = cc.BaseDifficulty
+	}
+	if cc.MaxDifficulty > 0 {
+		cfg.MaxDifficulty = cc.MaxDifficulty
+	}
+	if cfg.MaxDifficulty < cfg.BaseDifficulty {
+		cfg.MaxDifficulty = cfg.BaseDifficulty
+	}
+	cfg.CaptchaSiteKey = cc.CaptchaSiteKey
+	return cfg
+}
+
+// Service 注册、登录、忘记密码的工作量证明与验证码挑战
+// 按IP和网段统计最近的请求与失败次数，超过阈值后要求挑战，难度随分数增长；挑战由服务端签名，校验时不需要查询签发记录
+type Service struct {
+	store   Store
+	captcha CaptchaVerifier
+	cfg     *Config
+}
+
+// NewService 创建服务，captcha 为空时只支持工作量证明，cfg 为空时使用默认配置
+func NewService(store Store, captcha CaptchaVerifier, cfg *Config) *Service {
+	if cfg == nil {
+		cfg = DefaultConfig()
+	}
+	return &Service{store: store, captcha: captcha, cfg: cfg}
+}
+
+// Difficulty 返回IP当前需要的工作量证明难度，0 表示不需要挑战
+func (s *Service) Difficulty(ctx context.Context, ip string) (int, error) {
+	ipScore, err := s.store.Count(ctx, "ip:"+ip)
+	if err != nil {
+		return 0, err
+	}
+	subnetScore, err := s.store.Count(ctx, "net:"+Subnet(ip))
+	if err != nil {
+		return 0, err
+	}
+
+	ratio := math.Max(
+		float64(ipScore)/float64(s.cfg.IPThreshold),
+		float64(subnetScore)/float64(s.cfg.SubnetThreshold),
+	)
+	if ratio < 1 {
+		return 0, nil
+	}
+	difficulty := s.cfg.BaseDifficulty + int(math.Log2(ratio))
+	if difficulty > s.cfg.MaxDifficulty {
+		difficulty = s.cfg.MaxDifficulty
+	}
+	return difficulty, nil
+}
+
+// Record 记录一次请求，失败的请求额外计 1 分
+func (s *Service) Record(ctx context.Context, ip string, failed bool) error {
+	points := 1
+	if failed {
+		points = 2
+	}
+	for _, key := range []string{"ip:" + ip, "net:" + Subnet(ip)} {
+		for i := 0; i < points; i++ {
+			if _, err := s.store.Increment(ctx, key, s.cfg.Window); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Issue 签发挑战，难度不低于起始难度；挑战绑定操作和客户端网段
+func (s *Service) Issue(ctx context.Context, action, ip string) (*dto.ChallengeResponse, error) {
+	difficulty, err := s.Difficulty(ctx, ip)
+	if err != nil {
+		return nil, err
+	}
+	if difficulty < s.cfg.BaseDifficulty {
+		difficulty = s.cfg.BaseDifficulty
+	}
+
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	expiresAt := time.Now().Add(s.cfg.TTL)
+	token, err := s.sign(&claims{
+		Action:     action,
+		Subnet:     Subnet(ip),
+		Difficulty: difficulty,
+		ExpiresAt:  expiresAt.Unix(),
+		Nonce:      base64.RawURLEncoding.EncodeToString(nonce),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &dto.ChallengeResponse{
+		Token:      token,
+		Algorithm:  Algorithm,
+		Difficulty: difficulty,
+		ExpiresAt:  expiresAt,
+		ExpiresIn:  int(s.cfg.TTL / time.Second),
+	}
+	if s.captcha != nil {
+		resp.CaptchaSiteKey = s.cfg.CaptchaSiteKey
+	}
+	return resp, nil
+}
+
+// Verify 校验已完成的挑战，minDifficulty 为当前要求的难度
+// 提交了验证码响应且配置了验证码服务时以验证码为准，否则校验工作量证明；每个挑战只能使用一次
+func (s *Service) Verify(ctx context.Context, action, ip string, minDifficulty int, token, solution, captcha string) error {
+	if captcha != "" && s.captcha != nil {
+		ok, err := s.captcha.Verify(ctx, captcha, ip)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return common.ErrChallengeInvalid
+		}
+		return nil
+	}
+	if token == "" || solution == "" {
+		return common.ErrChallengeRequired
+	}
+
+	c, err := s.parse(token)
+	if err != nil {
+		return err
+	}
+	remaining := time.Until(time.Unix(c.ExpiresAt, 0))
+	if remaining <= 0 || c.Action != action || c.Subnet != Subnet(ip) || c.Difficulty < minDifficulty {
+		return common.ErrChallengeInvalid
+	}
+	if LeadingZeroBits(token, solution) < c.Difficulty {
+		return common.ErrChallengeInvalid
+	}
+
+	sum := sha256.Sum256([]byte(token))
+	fresh, err := s.store.SetNX(ctx, "used:"+hex.EncodeToString(sum[:]), 1, remaining)
+	if err != nil {
+		return err
+	}
+	if !fresh {
+		return common.ErrChallengeInvalid
+	}
+	return nil
+}
+
+// LeadingZeroBits SHA-256(token + ":" + solution) 的前导零比特数
+func LeadingZeroBits(token, solution string) int {
+	sum := sha256.Sum256([]byte(token + ":" + solution))
+	zeros := 0
+	for _, b := range sum {
+		if b != 0 {
+			return zeros + bits.LeadingZeros8(b)
+		}
+		zeros += 8
+	}
+	return zeros
+}
+
+// Subnet IPv4 取 /24、IPv6 取 /64 网段，无法解析时返回原值
+func Subnet(ip string) string {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return ip
+	}
+	if v4 := parsed.To4(); v4 != nil {
+		return v4.Mask(net.CIDRMask(24, 32)).String() + "/24"
+	}
+	return parsed.Mask(net.CIDRMask(64, 128)).String() + "/64"
+}
+
+// claims 挑战令牌内容
+type claims struct {
+	Action     string `json:"a"`
+	Subnet     string `json:"s"`
+	Difficulty int    `json:"d"`
+	ExpiresAt  int64  `json:"e"`
+	Nonce      string `json:"n"`
+}
+
+// sign 令牌格式为 base64url(JSON).base64url(HMAC-SHA256)
+func (s *Service) sign(c *claims) (string, error) {
+	payload, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(s.mac(encoded)), nil
+}
+
+func (s *Service) parse(token string) (*claims, error) {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, common.ErrChallengeInvalid
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(sig, s.mac(encoded)) {
+		return nil, common.ErrChallengeInvalid
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, common.ErrChallengeInvalid
+	}
+	var c claims
+	if err := json.Unmarshal(payload, &c); err != nil {
+		return nil, common.ErrChallengeInvalid
+	}
+	return &c, nil
+}
+
+func (s *Service) mac(payload string) []byte {
+	h := hmac.New(sha256.New, s.cfg.Secret)
+	h.Write([]byte(payload))
+	return h.Sum(nil)
+}
@@ -0,0 +1,221 @@
+package challenge
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"trusioo_api/internal/auth/challenge/dto"
+	"trusioo_api/internal/common"
+)
+
+// fakeStore 内存计数，不处理过期
+type fakeStore struct {
+	counts map[string]int64
+	used   map[string]bool
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{counts: map[string]int64{}, used: map[string]bool{}}
+}
+
+func (f *fakeStore) Increment(ctx context.Context, key string, expiration time.Duration) (int64, error) {
+	f.counts[key]++
+	return f.counts[key], nil
+}
+
+func (f *fakeStore) Count(ctx context.Context, key string) (int64, error) {
+	return f.counts[key], nil
+}
+
+func (f *fakeStore) SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) (bool, error) {
+	if f.used[key] {
+		return false, nil
+	}
+	f.used[key] = true
+	return true, nil
+}
+
+func testConfig() *Config {
+	return &Config{
+		Secret:          []byte("challenge-secret"),
+		TTL:             time.Minute,
+		Window:          10 * time.Minute,
+		IPThreshold:     4,
+		SubnetThreshold: 10,
+		BaseDifficulty:  4,
+		MaxDifficulty:   6,
+	}
+}
+
+// solve 暴力寻找满足难度的答案
+func solve(t *testing.T, token string, difficulty int) string {
+	for i := 0; i < 1<<20; i++ {
+		solution := strconv.Itoa(i)
+		if LeadingZeroBits(token, solution) >= difficulty {
+			return solution
+		}
+	}
+	t.Fatalf("未找到难度 %d 的答案", difficulty)
+	return ""
+}
+
+func TestSubnet(t *testing.T) {
+	assert.Equal(t, "10.1.2.0/24", Subnet("10.1.2.3"))
+	assert.Equal(t, "2001:db8:1:2::/64", Subnet("2001:db8:1:2:3:4:5:6"))
+	assert.Equal(t, "unknown", Subnet("unknown"))
+}
+
+// 路由使用的每个操作都可以申请挑战
+func TestIssueRequest_Actions(t *testing.T) {
+	for _, action := range []string{ActionRegister, ActionLogin, ActionLoginCode, ActionLoginPhone, ActionForgotPassword, ActionAdminLogin} {
+		assert.NoError(t, binding.Validator.ValidateStruct(&dto.IssueRequest{Action: action}), action)
+	}
+	assert.Error(t, binding.Validator.ValidateStruct(&dto.IssueRequest{Action: "unknown"}))
+}
+
+func TestService_Difficulty(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("低于阈值不需要挑战", func(t *testing.T) {
+		service := NewService(newFakeStore(), nil, testConfig())
+		require.NoError(t, service.Record(ctx, "10.0.0.1", false))
+		difficulty, err := service.Difficulty(ctx, "10.0.0.1")
+		require.NoError(t, err)
+		assert.Zero(t, difficulty)
+	})
+
+	t.Run("失败请求使难度增长并有上限", func(t *testing.T) {
+		service := NewService(newFakeStore(), nil, testConfig())
+		for i := 0; i < 2; i++ {
+			require.NoError(t, service.Record(ctx, "10.0.0.1", true))
+		}
+		difficulty, err := service.Difficulty(ctx, "10.0.0.1")
+		require.NoError(t, err)
+		assert.Equal(t, 4, difficulty)
+
+		for i := 0; i < 2; i++ {
+			require.NoError(t, service.Record(ctx, "10.0.0.1", true))
+		}
+		difficulty, err = service.Difficulty(ctx, "10.0.0.1")
+		require.NoError(t, err)
+		assert.Equal(t, 5, difficulty, "分数为阈值两倍时难度加 1")
+
+		for i := 0; i < 20; i++ {
+			require.NoError(t, service.Record(ctx, "10.0.0.1", true))
+		}
+		difficulty, err = service.Difficulty(ctx, "10.0.0.1")
+		require.NoError(t, err)
+		assert.Equal(t, 6, difficulty)
+	})
+
+	t.Run("同一网段的其他IP也需要挑战", func(t *testing.T) {
+		service := NewService(newFakeStore(), nil, testConfig())
+		for i := 0; i < 10; i++ {
+			require.NoError(t, service.Record(ctx, "10.0.0."+strconv.Itoa(i), false))
+		}
+		difficulty, err := service.Difficulty(ctx, "10.0.0.200")
+		require.NoError(t, err)
+		assert.Equal(t, 4, difficulty)
+
+		difficulty, err = service.Difficulty(ctx, "10.0.1.1")
+		require.NoError(t, err)
+		assert.Zero(t, difficulty)
+	})
+}
+
+func TestService_Verify(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("完成的工作量证明只能使用一次", func(t *testing.T) {
+		service := NewService(newFakeStore(), nil, testConfig())
+		challenge, err := service.Issue(ctx, ActionLogin, "10.0.0.1")
+		require.NoError(t, err)
+		assert.Equal(t, 4, challenge.Difficulty)
+		assert.Equal(t, Algorithm, challenge.Algorithm)
+		assert.Empty(t, challenge.CaptchaSiteKey)
+
+		solution := solve(t, challenge.Token, challenge.Difficulty)
+		assert.NoError(t, service.Verify(ctx, ActionLogin, "10.0.0.9", 4, challenge.Token, solution, ""), "同一网段可以使用")
+		assert.Equal(t, common.ErrChallengeInvalid, service.Verify(ctx, ActionLogin, "10.0.0.1", 4, challenge.Token, solution, ""))
+	})
+
+	t.Run("拒绝不匹配或无效的挑战", func(t *testing.T) {
+		service := NewService(newFakeStore(), nil, testConfig())
+		challenge, err := service.Issue(ctx, ActionRegister, "10.0.0.1")
+		require.NoError(t, err)
+		solution := solve(t, challenge.Token, challenge.Difficulty)
+
+		other := NewService(newFakeStore(), nil, &Config{Secret: []byte("other-secret"), TTL: time.Minute, IPThreshold: 1, SubnetThreshold: 1, BaseDifficulty: 4, MaxDifficulty: 4})
+		expired := NewService(newFakeStore(), nil, &Config{Secret: []byte("challenge-secret"), TTL: -time.Second, IPThreshold: 1, SubnetThreshold: 1, BaseDifficulty: 4, MaxDifficulty: 4})
+		expiredChallenge, err := expired.Issue(ctx, ActionRegister, "10.0.0.1")
+		require.NoError(t, err)
+
+		payload, _, _ := strings.Cut(challenge.Token, ".")
+		tests := []struct {
+			name     string
+			service  *Service
+			action   string
+			ip       string
+			min      int
+			token    string
+			solution string
+			err      error
+		}{
+			{"未提交挑战", service, ActionRegister, "10.0.0.1", 4, "", "", common.ErrChallengeRequired},
+			{"操作不匹配", service, ActionLogin, "10.0.0.1", 4, challenge.Token, solution, common.ErrChallengeInvalid},
+			{"网段不匹配", service, ActionRegister, "10.0.1.1", 4, challenge.Token, solution, common.ErrChallengeInvalid},
+			{"难度低于当前要求", service, ActionRegister, "10.0.0.1", 5, challenge.Token, solution, common.ErrChallengeInvalid},
+			{"签名密钥不同", other, ActionRegister, "10.0.0.1", 4, challenge.Token, solution, common.ErrChallengeInvalid},
+			{"签名被篡改", service, ActionRegister, "10.0.0.1", 4, payload + ".AAAA", solution, common.ErrChallengeInvalid},
+			{"挑战已过期", service, ActionRegister, "10.0.0.1", 4, expiredChallenge.Token, solve(t, expiredChallenge.Token, 4), common.ErrChallengeInvalid},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				assert.Equal(t, tt.err, tt.service.Verify(ctx, tt.action, tt.ip, tt.min, tt.token, tt.solution, ""))
+			})
+		}
+	})
+
+	t.Run("验证码响应代替工作量证明", func(t *testing.T) {
+		service := NewService(newFakeStore(), &FakeVerifier{Valid: "passed"}, &Config{Secret: []byte("s"), TTL: time.Minute, BaseDifficulty: 4, MaxDifficulty: 4, CaptchaSiteKey: "site-key"})
+		challenge, err := service.Issue(ctx, ActionForgotPassword, "10.0.0.1")
+		require.NoError(t, err)
+		assert.Equal(t, "site-key", challenge.CaptchaSiteKey)
+
+		assert.NoError(t, service.Verify(ctx, ActionForgotPassword, "10.0.0.1", 4, "", "", "passed"))
+		assert.Equal(t, common.ErrChallengeInvalid, service.Verify(ctx, ActionForgotPassword, "10.0.0.1", 4, "", "", "failed"))
+	})
+}
+
+func TestSiteVerifyClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "secret", r.PostForm.Get("secret"))
+		assert.Equal(t, "10.0.0.1", r.PostForm.Get("remoteip"))
+		w.Header().Set("Content-Type", "application/json")
+		if r.PostForm.Get("response") == "passed" {
+			w.Write([]byte(`{"success":true}`))
+			return
+		}
+		w.Write([]byte(`{"success":false,"error-codes":["invalid-input-response"]}`))
+	}))
+	defer server.Close()
+
+	client := NewSiteVerifyClient(server.URL, "secret")
+	ok, err := client.Verify(context.Background(), "passed", "10.0.0.1")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = client.Verify(context.Background(), "failed", "10.0.0.1")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
@@ -0,0 +1,190 @@
+package identity
+
+import (
+	"database/sql"
+	"log"
+	"time"
+
+	"trusioo_api/config"
+	"trusioo_api/internal/auth/loginrisk"
+	"trusioo_api/internal/auth/password"
+	"trusioo_api/internal/auth/sessionlimit"
+	verificationDto "trusioo_api/internal/auth/verification/dto"
+	"trusioo_api/internal/common"
+	"trusioo_api/pkg/auth"
+	"trusioo_api/pkg/ipinfo"
+)
+
+// RefreshToken 与账户类型无关的刷新令牌记录
+type RefreshToken struct {
+	ID         int64
+	AccountID  int64
+	Token      string
+	ExpiresAt  time.Time
+	DeviceInfo string
+	CreatedAt  time.Time
+}
+
+// Store 刷新令牌、密码与登录记录的存储，由用户和管理员仓储分别适配
+// GetValidRefreshToken 在令牌不存在或已失效时返回 sql.ErrNoRows
+type Store interface {
+	CreateRefreshToken(token *RefreshToken) error
+	GetValidRefreshToken(token string) (*RefreshToken, error)
+	ListActiveRefreshTokens(accountID int64) ([]*RefreshToken, error)
+	EvictRefreshTokens(accountID int64, ids []int64) error
+	IsRefreshTokenEvicted(token string) (bool, error)
+	InvalidateAllRefreshTokens(accountID int64) error
+	UpdatePassword(accountID int64, passwordHash string) error
+	ListPasswordHistory(accountID int64, limit int) ([]string, error)
+	AddPasswordHistory(accountID int64, passwordHash string, keep int) error
+	CreateLoginSession(session *LoginSession) error
+	GetRecentLoginSessions(accountID int64, limit int) ([]*LoginSession, error)
+}
+
+// VerificationCodeService 验证码服务接口
+type VerificationCodeService interface {
+	SendVerificationCode(req *verificationDto.SendVerificationRequest) (*verificationDto.SendVerificationResponse, error)
+	VerifyCode(req *verificationDto.VerifyCodeRequest) (*verificationDto.VerifyCodeResponse, error)
+}
+
+// Options 认证核心的可选依赖，为空时不启用对应的功能
+type Options struct {
+	SessionLimit    *sessionlimit.Limiter // 同时登录会话上限
+	LoginGuard      LoginGuard            // 密码登录失败的退避与锁定
+	RiskEvaluator   *loginrisk.Evaluator  // 登录风险评估
+	RiskHistorySize int                   // 风险评估对比的最近成功登录数量，默认20
+	PasswordPolicy  *password.Policy      // 新密码的策略与密码历史
+}
+
+// Core 用户和管理员共用的认证核心：令牌签发与刷新、同时登录会话上限、密码登录失败锁定、登录风险评估、
+// 登录验证码与登录记录、IP定位、密码策略和重置密码
+type Core struct {
+	principal       Principal
+	store           Store
+	codes           VerificationCodeService
+	ipinfo          ipinfo.Client
+	sessionLimit    *sessionlimit.Limiter
+	loginGuard      LoginGuard
+	riskEvaluator   *loginrisk.Evaluator
+	riskHistorySize int
+	passwordPolicy  *password.Policy
+}
+
+// New 创建认证核心，ipinfoClient 为空时不查询IP位置
+func New(principal Principal, store Store, codes VerificationCodeService, ipinfoClient ipinfo.Client, opts Options) *Core {
+	return &Core{
+		principal:       principal,
+		store:           store,
+		codes:           codes,
+		ipinfo:          ipinfoClient,
+		sessionLimit:    opts.SessionLimit,
+		loginGuard:      opts.LoginGuard,
+		riskEvaluator:   opts.RiskEvaluator,
+		riskHistorySize: opts.RiskHistorySize,
+		passwordPolicy:  opts.PasswordPolicy,
+	}
+}
+
+// Principal 账户类型
+func (c *Core) Principal() Principal {
+	return c.principal
+}
+
+// Tokens 登录签发的令牌对
+type Tokens struct {
+	AccessToken  string
+	RefreshToken string
+}
+
+// IssueTokens 签发访问令牌与刷新令牌并保存刷新令牌，调用前先通过 AdmitSession 执行同时登录会话上限
+func (c *Core) IssueTokens(account Account, userAgent string) (*Tokens, error) {
+	accessToken, err := c.AccessToken(account)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshTokenStr, err := auth.GenerateRefreshToken(account.ID, account.Email, account.Role, string(c.principal))
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if err := c.store.CreateRefreshToken(&RefreshToken{
+		AccountID:  account.ID,
+		Token:      refreshTokenStr,
+		ExpiresAt:  now.Add(time.Duration(config.AppConfig.JWT.RefreshExpire) * time.Second),
+		DeviceInfo: userAgent,
+		CreatedAt:  now,
+	}); err != nil {
+		return nil, err
+	}
+
+	return &Tokens{AccessToken: accessToken, RefreshToken: refreshTokenStr}, nil
+}
+
+// AccessToken 签发访问令牌
+func (c *Core) AccessToken(account Account) (string, error) {
+	return auth.GenerateAccessToken(account.ID, account.Email, account.Role, string(c.principal))
+}
+
+// ValidateRefreshToken 校验刷新令牌仍然有效、签名正确且属于本账户类型，返回令牌记录
+func (c *Core) ValidateRefreshToken(token string) (*RefreshToken, error) {
+	record, err := c.store.GetValidRefreshToken(token)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, c.RefreshTokenError(token)
+		}
+		return nil, err
+	}
+
+	claims, err := auth.ValidateRefreshToken(token)
+	if err != nil {
+		return nil, common.ErrTokenInvalid
+	}
+	if claims.UserID != record.AccountID || claims.UserType != string(c.principal) {
+		return nil, common.ErrRefreshTokenInvalid
+	}
+	return record, nil
+}
+
+// AdmitSession 签发新的刷新令牌前执行同时登录会话上限，按策略拒绝新登录或使最早的会话失效
+// 返回因超过上限被踢出的会话，供调用方通知账户
+func (c *Core) AdmitSession(accountID int64) ([]sessionlimit.Session, error) {
+	if c.sessionLimit == nil {
+		return nil, nil
+	}
+
+	tokens, err := c.store.ListActiveRefreshTokens(accountID)
+	if err != nil {
+		return nil, err
+	}
+	sessions := make([]sessionlimit.Session, len(tokens))
+	for i, token := range tokens {
+		sessions[i] = sessionlimit.Session{ID: token.ID, DeviceInfo: token.DeviceInfo, CreatedAt: token.CreatedAt}
+	}
+
+	evicted, err := c.sessionLimit.Admit(string(c.principal), sessions)
+	if err != nil || len(evicted) == 0 {
+		return nil, err
+	}
+	if err := c.store.EvictRefreshTokens(accountID, sessionlimit.IDs(evicted)); err != nil {
+		return nil, err
+	}
+	return evicted, nil
+}
+
+// RefreshTokenError 刷新令牌无效时区分是否因新登录被踢出，让客户端提示账户
+func (c *Core) RefreshTokenError(token string) error {
+	if c.sessionLimit == nil {
+		return common.ErrRefreshTokenInvalid
+	}
+	evicted, err := c.store.IsRefreshTokenEvicted(token)
+	if err != nil {
+		log.Printf("查询刷新令牌踢出状态失败: %v", err)
+		return common.ErrRefreshTokenInvalid
+	}
+	if evicted {
+		return common.ErrSessionEvicted
+	}
+	return common.ErrRefreshTokenInvalid
+}
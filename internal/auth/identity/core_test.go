@@ -0,0 +1,473 @@
+package identity
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+
+	"trusioo_api/internal/auth/lockout"
+	"trusioo_api/internal/auth/loginrisk"
+	"trusioo_api/internal/auth/password"
+	"trusioo_api/internal/auth/sessionlimit"
+	verificationDto "trusioo_api/internal/auth/verification/dto"
+	"trusioo_api/internal/common"
+	"trusioo_api/internal/testutil"
+	"trusioo_api/pkg/auth"
+	"trusioo_api/pkg/ipinfo"
+)
+
+// fakeStore 内存中的刷新令牌、密码与登录记录存储
+type fakeStore struct {
+	tokens    []*RefreshToken
+	evicted   map[string]bool
+	passwords map[int64]string
+	history   map[int64][]string
+	sessions  []*LoginSession
+	nextID    int64
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{evicted: map[string]bool{}, passwords: map[int64]string{}, history: map[int64][]string{}}
+}
+
+func (s *fakeStore) CreateRefreshToken(token *RefreshToken) error {
+	s.nextID++
+	token.ID = s.nextID
+	s.tokens = append(s.tokens, token)
+	return nil
+}
+
+func (s *fakeStore) GetValidRefreshToken(token string) (*RefreshToken, error) {
+	for _, t := range s.tokens {
+		if t.Token == token {
+			return t, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (s *fakeStore) ListActiveRefreshTokens(accountID int64) ([]*RefreshToken, error) {
+	var result []*RefreshToken
+	for _, t := range s.tokens {
+		if t.AccountID == accountID {
+			result = append(result, t)
+		}
+	}
+	return result, nil
+}
+
+func (s *fakeStore) EvictRefreshTokens(accountID int64, ids []int64) error {
+	kept := s.tokens[:0]
+	for _, t := range s.tokens {
+		evict := false
+		for _, id := range ids {
+			if t.AccountID == accountID && t.ID == id {
+				evict = true
+			}
+		}
+		if evict {
+			s.evicted[t.Token] = true
+			continue
+		}
+		kept = append(kept, t)
+	}
+	s.tokens = kept
+	return nil
+}
+
+func (s *fakeStore) IsRefreshTokenEvicted(token string) (bool, error) {
+	return s.evicted[token], nil
+}
+
+func (s *fakeStore) InvalidateAllRefreshTokens(accountID int64) error {
+	kept := s.tokens[:0]
+	for _, t := range s.tokens {
+		if t.AccountID != accountID {
+			kept = append(kept, t)
+		}
+	}
+	s.tokens = kept
+	return nil
+}
+
+func (s *fakeStore) UpdatePassword(accountID int64, passwordHash string) error {
+	s.passwords[accountID] = passwordHash
+	return nil
+}
+
+func (s *fakeStore) ListPasswordHistory(accountID int64, limit int) ([]string, error) {
+	hashes := s.history[accountID]
+	if len(hashes) > limit {
+		hashes = hashes[:limit]
+	}
+	return hashes, nil
+}
+
+func (s *fakeStore) AddPasswordHistory(accountID int64, passwordHash string, keep int) error {
+	hashes := append([]string{passwordHash}, s.history[accountID]...)
+	if len(hashes) > keep {
+		hashes = hashes[:keep]
+	}
+	s.history[accountID] = hashes
+	return nil
+}
+
+func (s *fakeStore) CreateLoginSession(session *LoginSession) error {
+	s.sessions = append(s.sessions, session)
+	return nil
+}
+
+func (s *fakeStore) GetRecentLoginSessions(accountID int64, limit int) ([]*LoginSession, error) {
+	var result []*LoginSession
+	for i := len(s.sessions) - 1; i >= 0 && len(result) < limit; i-- {
+		if s.sessions[i].AccountID == accountID && s.sessions[i].Status == "success" {
+			result = append(result, s.sessions[i])
+		}
+	}
+	return result, nil
+}
+
+// fakeGuard 按账户计数的登录失败锁定，达到 threshold 次后锁定
+type fakeGuard struct {
+	threshold int
+	failures  map[string]int
+}
+
+func (g *fakeGuard) Check(ctx context.Context, account, ip string) (time.Duration, error) {
+	if g.failures[account] >= g.threshold {
+		return time.Minute, nil
+	}
+	return 0, nil
+}
+
+func (g *fakeGuard) Fail(ctx context.Context, account, ip string) (*lockout.Result, error) {
+	g.failures[account]++
+	return &lockout.Result{AccountLocked: g.failures[account] == g.threshold}, nil
+}
+
+func (g *fakeGuard) Reset(ctx context.Context, account string) error {
+	delete(g.failures, account)
+	return nil
+}
+
+// fakeCodes 只接受 validCode 的验证码服务，记录最后一次请求的验证码类型
+type fakeCodes struct {
+	validCode string
+	lastType  string
+}
+
+func (c *fakeCodes) SendVerificationCode(req *verificationDto.SendVerificationRequest) (*verificationDto.SendVerificationResponse, error) {
+	c.lastType = req.Type
+	return &verificationDto.SendVerificationResponse{}, nil
+}
+
+func (c *fakeCodes) VerifyCode(req *verificationDto.VerifyCodeRequest) (*verificationDto.VerifyCodeResponse, error) {
+	c.lastType = req.Type
+	return &verificationDto.VerifyCodeResponse{Valid: req.Code == c.validCode}, nil
+}
+
+var testAccount = Account{ID: 1, Email: "someone@example.com", Role: "user"}
+
+func TestParseUserAgent(t *testing.T) {
+	tests := []struct {
+		name      string
+		userAgent string
+		expected  Device
+	}{
+		{"macOS Chrome", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0 Safari/537.36", Device{"desktop", "macOS", "Chrome", "web"}},
+		{"Windows Firefox", "Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:121.0) Gecko/20100101 Firefox/121.0", Device{"desktop", "Windows", "Firefox", "web"}},
+		{"Linux Firefox", "Mozilla/5.0 (X11; Linux x86_64; rv:121.0) Gecko/20100101 Firefox/121.0", Device{"desktop", "Linux", "Firefox", "web"}},
+		{"Android 手机", "Mozilla/5.0 (Android 14; Mobile; rv:121.0) Gecko/121.0 Firefox/121.0", Device{"mobile", "Android", "Firefox", "mobile"}},
+		{"无法识别", "curl/8.4.0", Device{"desktop", "", "", "web"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, ParseUserAgent(tt.userAgent))
+		})
+	}
+}
+
+func TestLocationOf(t *testing.T) {
+	assert.Equal(t, Location{}, LocationOf(nil))
+	assert.Equal(t, Location{Country: "US", City: "San Francisco", Organization: "AS15169", Coordinates: "37.77,-122.41"},
+		LocationOf(&ipinfo.IPInfo{Country: "US", City: "San Francisco", Org: "AS15169", Loc: "37.77,-122.41"}))
+
+	// 未配置IP查询客户端时不查询
+	assert.Nil(t, New(PrincipalUser, newFakeStore(), nil, nil, Options{}).LookupIP("8.8.8.8"))
+}
+
+func TestCore_Tokens(t *testing.T) {
+	testutil.MockJWTConfig()
+
+	t.Run("签发的令牌带有账户类型并可刷新", func(t *testing.T) {
+		core := New(PrincipalAdmin, newFakeStore(), nil, nil, Options{})
+		tokens, err := core.IssueTokens(testAccount, "test-agent")
+		require.NoError(t, err)
+
+		claims, err := auth.ValidateAccessToken(tokens.AccessToken)
+		require.NoError(t, err)
+		assert.Equal(t, "admin", claims.UserType)
+
+		record, err := core.ValidateRefreshToken(tokens.RefreshToken)
+		require.NoError(t, err)
+		assert.Equal(t, testAccount.ID, record.AccountID)
+		assert.Equal(t, "test-agent", record.DeviceInfo)
+	})
+
+	t.Run("其他账户类型签发的刷新令牌无效", func(t *testing.T) {
+		store := newFakeStore()
+		refreshToken, err := auth.GenerateRefreshToken(testAccount.ID, testAccount.Email, testAccount.Role, "user")
+		require.NoError(t, err)
+		require.NoError(t, store.CreateRefreshToken(&RefreshToken{AccountID: testAccount.ID, Token: refreshToken}))
+
+		_, err = New(PrincipalAdmin, store, nil, nil, Options{}).ValidateRefreshToken(refreshToken)
+		assert.Equal(t, common.ErrRefreshTokenInvalid, err)
+	})
+
+	t.Run("不属于令牌中账户的记录无效", func(t *testing.T) {
+		store := newFakeStore()
+		refreshToken, err := auth.GenerateRefreshToken(2, "other@example.com", "user", "user")
+		require.NoError(t, err)
+		require.NoError(t, store.CreateRefreshToken(&RefreshToken{AccountID: testAccount.ID, Token: refreshToken}))
+
+		_, err = New(PrincipalUser, store, nil, nil, Options{}).ValidateRefreshToken(refreshToken)
+		assert.Equal(t, common.ErrRefreshTokenInvalid, err)
+	})
+
+	t.Run("超过会话上限时踢出最早的会话", func(t *testing.T) {
+		store := newFakeStore()
+		limiter := sessionlimit.NewLimiter(&sessionlimit.Config{MaxUserSessions: 1, MaxAdminSessions: 5, Policy: sessionlimit.PolicyEvictOldest})
+		core := New(PrincipalUser, store, nil, nil, Options{SessionLimit: limiter})
+
+		require.NoError(t, store.CreateRefreshToken(&RefreshToken{AccountID: testAccount.ID, Token: "old-token", DeviceInfo: "old-agent"}))
+		evicted, err := core.AdmitSession(testAccount.ID)
+		require.NoError(t, err)
+		require.Len(t, evicted, 1)
+		assert.Equal(t, "old-agent", evicted[0].DeviceInfo)
+		assert.Empty(t, store.tokens)
+
+		_, err = core.ValidateRefreshToken("old-token")
+		assert.Equal(t, common.ErrSessionEvicted, err)
+		_, err = core.ValidateRefreshToken("unknown-token")
+		assert.Equal(t, common.ErrRefreshTokenInvalid, err)
+	})
+}
+
+func TestCore_ResetPassword(t *testing.T) {
+	testutil.MockJWTConfig()
+
+	t.Run("用户和管理员使用不同的验证码类型", func(t *testing.T) {
+		codes := &fakeCodes{}
+		require.NoError(t, New(PrincipalUser, newFakeStore(), codes, nil, Options{}).SendPasswordReset(testAccount.Email))
+		assert.Equal(t, "forgot_password", codes.lastType)
+		require.NoError(t, New(PrincipalAdmin, newFakeStore(), codes, nil, Options{}).SendPasswordReset(testAccount.Email))
+		assert.Equal(t, "admin_forgot_password", codes.lastType)
+	})
+
+	t.Run("验证码错误", func(t *testing.T) {
+		store := newFakeStore()
+		_, err := New(PrincipalUser, store, &fakeCodes{validCode: "123456"}, nil, Options{}).ResetPassword(1, testAccount.Email, "000000", "new-password", nil)
+		assert.Equal(t, common.ErrInvalidCode, err)
+		assert.Empty(t, store.passwords)
+	})
+
+	t.Run("新密码未通过校验", func(t *testing.T) {
+		store := newFakeStore()
+		rejected := errors.New("rejected")
+		_, err := New(PrincipalUser, store, &fakeCodes{validCode: "123456"}, nil, Options{}).ResetPassword(1, testAccount.Email, "123456", "new-password", func(string) error {
+			return rejected
+		})
+		assert.Equal(t, rejected, err)
+		assert.Empty(t, store.passwords)
+	})
+
+	t.Run("重置成功后刷新令牌全部失效", func(t *testing.T) {
+		store := newFakeStore()
+		core := New(PrincipalAdmin, store, &fakeCodes{validCode: "123456"}, nil, Options{})
+		tokens, err := core.IssueTokens(testAccount, "test-agent")
+		require.NoError(t, err)
+
+		hash, err := core.ResetPassword(testAccount.ID, testAccount.Email, "123456", "new-password", nil)
+		require.NoError(t, err)
+		assert.Equal(t, hash, store.passwords[testAccount.ID])
+		assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(hash), []byte("new-password")))
+
+		_, err = core.ValidateRefreshToken(tokens.RefreshToken)
+		assert.Equal(t, common.ErrRefreshTokenInvalid, err)
+	})
+}
+
+func TestCore_AdmitSession(t *testing.T) {
+	testutil.MockJWTConfig()
+
+	t.Run("未配置会话上限时不限制", func(t *testing.T) {
+		store := newFakeStore()
+		core := New(PrincipalUser, store, nil, nil, Options{})
+		for i := 0; i < 3; i++ {
+			evicted, err := core.AdmitSession(testAccount.ID)
+			require.NoError(t, err)
+			assert.Empty(t, evicted)
+			_, err = core.IssueTokens(testAccount, "agent")
+			require.NoError(t, err)
+		}
+		assert.Len(t, store.tokens, 3)
+	})
+
+	t.Run("拒绝策略下超过上限返回错误", func(t *testing.T) {
+		store := newFakeStore()
+		limiter := sessionlimit.NewLimiter(&sessionlimit.Config{MaxAdminSessions: 1, Policy: sessionlimit.PolicyReject})
+		core := New(PrincipalAdmin, store, nil, nil, Options{SessionLimit: limiter})
+
+		_, err := core.AdmitSession(testAccount.ID)
+		require.NoError(t, err)
+		_, err = core.IssueTokens(testAccount, "first")
+		require.NoError(t, err)
+
+		_, err = core.AdmitSession(testAccount.ID)
+		assert.Equal(t, common.ErrSessionLimitReached, err)
+		assert.Len(t, store.tokens, 1)
+	})
+}
+
+func TestCore_SessionEvictedMessage(t *testing.T) {
+	limiter := sessionlimit.NewLimiter(&sessionlimit.Config{MaxUserSessions: 3, MaxAdminSessions: 2, Policy: sessionlimit.PolicyEvictOldest})
+	evicted := []sessionlimit.Session{{ID: 1, DeviceInfo: "curl/8.4.0", CreatedAt: time.Date(2025, 1, 2, 3, 4, 0, 0, time.UTC)}}
+
+	msg, err := New(PrincipalAdmin, newFakeStore(), nil, nil, Options{SessionLimit: limiter}).SessionEvictedMessage("admin@example.com", "en", evicted,
+		"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0 Safari/537.36")
+	require.NoError(t, err)
+	assert.Equal(t, "admin@example.com", msg.To)
+	assert.Contains(t, msg.TextBody, "Chrome / macOS")
+	assert.Contains(t, msg.TextBody, "curl/8.4.0（2025-01-02 03:04 UTC）")
+	assert.Contains(t, msg.TextBody, "at most 2 devices")
+}
+
+func TestDeviceLabel(t *testing.T) {
+	assert.Equal(t, "unknown device", DeviceLabel(" "))
+	assert.Equal(t, "Firefox / Linux", DeviceLabel("Mozilla/5.0 (X11; Linux x86_64; rv:121.0) Gecko/20100101 Firefox/121.0"))
+	assert.Equal(t, "curl/8.4.0", DeviceLabel("curl/8.4.0"))
+}
+
+func TestCore_VerifyLoginCode(t *testing.T) {
+	attempt := Attempt{IP: "10.0.0.1", UserAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:121.0) Gecko/20100101 Firefox/121.0", Method: "email"}
+
+	t.Run("验证码正确时不记录登录会话", func(t *testing.T) {
+		store := newFakeStore()
+		core := New(PrincipalUser, store, &fakeCodes{validCode: "123456"}, nil, Options{})
+		require.NoError(t, core.VerifyLoginCode(testAccount.ID, attempt, testAccount.Email, "123456", "login"))
+		assert.Equal(t, "login", core.codes.(*fakeCodes).lastType)
+		assert.Empty(t, store.sessions)
+	})
+
+	t.Run("验证码无效时记录失败的登录会话", func(t *testing.T) {
+		store := newFakeStore()
+		core := New(PrincipalAdmin, store, &fakeCodes{validCode: "123456"}, nil, Options{})
+		err := core.VerifyLoginCode(testAccount.ID, attempt, testAccount.Email, "000000", "admin_login")
+		assert.Equal(t, common.ErrInvalidCode, err)
+
+		require.Len(t, store.sessions, 1)
+		session := store.sessions[0]
+		assert.Equal(t, testAccount.ID, session.AccountID)
+		assert.Equal(t, "failed", session.Status)
+		assert.Equal(t, "验证码无效", session.Reason)
+		assert.Equal(t, "email", session.Method)
+		assert.Equal(t, "Firefox", session.Browser)
+		assert.Equal(t, "Windows", session.OS)
+	})
+}
+
+func TestCore_RecordLoginSession(t *testing.T) {
+	store := newFakeStore()
+	core := New(PrincipalUser, store, nil, nil, Options{})
+
+	session := core.RecordLoginSession(testAccount.ID, Attempt{IP: "10.0.0.1", UserAgent: "curl/8.4.0"}, "success", "登录成功")
+	require.NotNil(t, session)
+	assert.Equal(t, []*LoginSession{session}, store.sessions)
+	assert.Equal(t, Location{}, session.Location, "未配置IP查询客户端时位置为空")
+	assert.Equal(t, "desktop", session.DeviceType)
+	assert.WithinDuration(t, time.Now(), session.CreatedAt, 5*time.Second)
+}
+
+func TestCore_LoginFailures(t *testing.T) {
+	guard := &fakeGuard{threshold: 2, failures: map[string]int{}}
+	core := New(PrincipalAdmin, newFakeStore(), nil, nil, Options{LoginGuard: guard})
+
+	assert.Zero(t, core.LoginRetryAfter(testAccount.Email, "10.0.0.1"))
+	assert.False(t, core.RecordLoginFailure(testAccount.Email, "10.0.0.1"))
+	assert.True(t, core.RecordLoginFailure(testAccount.Email, "10.0.0.1"), "达到阈值的失败使账户锁定")
+	assert.Equal(t, time.Minute, core.LoginRetryAfter(testAccount.Email, "10.0.0.1"))
+
+	core.ResetLoginFailures(testAccount.Email)
+	assert.Zero(t, core.LoginRetryAfter(testAccount.Email, "10.0.0.1"))
+
+	// 未配置锁定时不限制
+	unguarded := New(PrincipalUser, newFakeStore(), nil, nil, Options{})
+	assert.False(t, unguarded.RecordLoginFailure(testAccount.Email, "10.0.0.1"))
+	assert.Zero(t, unguarded.LoginRetryAfter(testAccount.Email, "10.0.0.1"))
+}
+
+func TestComparePassword(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("correct-password"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	assert.True(t, ComparePassword(string(hash), "correct-password"))
+	assert.False(t, ComparePassword(string(hash), "wrong-password"))
+	assert.False(t, ComparePassword("", "correct-password"), "账户不存在时与虚拟哈希比较")
+}
+
+func TestCore_ValidatePassword(t *testing.T) {
+	store := newFakeStore()
+	policy := password.NewPolicy(&password.Config{MinLength: 8, HistorySize: 2}, nil)
+	core := New(PrincipalAdmin, store, nil, nil, Options{PasswordPolicy: policy})
+
+	current, err := bcrypt.GenerateFromPassword([]byte("current-password"), bcrypt.MinCost)
+	require.NoError(t, err)
+	previous, err := bcrypt.GenerateFromPassword([]byte("previous-password"), bcrypt.MinCost)
+	require.NoError(t, err)
+	core.RecordPasswordHistory(testAccount.ID, string(previous))
+
+	assert.Error(t, core.ValidatePassword(testAccount.ID, string(current), testAccount.Email, "short"))
+	assert.Error(t, core.ValidatePassword(testAccount.ID, string(current), testAccount.Email, "current-password"), "当前密码不在历史记录中也不能重复使用")
+	assert.Error(t, core.ValidatePassword(testAccount.ID, string(current), testAccount.Email, "previous-password"))
+	assert.NoError(t, core.ValidatePassword(testAccount.ID, string(current), testAccount.Email, "brand-new-password"))
+	assert.NoError(t, core.ValidatePassword(0, "", testAccount.Email, "previous-password"), "注册时不比较历史密码")
+
+	// 未配置密码策略时不校验
+	assert.NoError(t, New(PrincipalUser, store, nil, nil, Options{}).ValidatePassword(testAccount.ID, "", testAccount.Email, "x"))
+}
+
+func TestCore_AssessLoginRisk(t *testing.T) {
+	store := newFakeStore()
+	userAgent := "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0 Safari/537.36"
+	core := New(PrincipalAdmin, store, nil, nil, Options{RiskEvaluator: loginrisk.NewEvaluator(nil)})
+
+	session := NewLoginSession(testAccount.ID, Attempt{IP: "10.0.0.1", UserAgent: userAgent},
+		&ipinfo.IPInfo{Country: "CN", Loc: "31.2304,121.4737"}, "success", "登录成功")
+	session.CreatedAt = time.Now().Add(-time.Hour)
+	require.True(t, core.SaveLoginSession(session))
+
+	assessment := core.AssessLoginRisk(testAccount.ID, &ipinfo.IPInfo{Country: "CN", Loc: "31.2304,121.4737"}, userAgent)
+	require.NotNil(t, assessment)
+	assert.Equal(t, loginrisk.DecisionAllow, assessment.Decision)
+
+	// 一小时内从上海到旧金山
+	assessment = core.AssessLoginRisk(testAccount.ID, &ipinfo.IPInfo{Country: "US", Loc: "37.7749,-122.4194"}, userAgent)
+	require.NotNil(t, assessment)
+	assert.Equal(t, loginrisk.DecisionBlock, assessment.Decision)
+
+	failed := NewLoginSession(testAccount.ID, Attempt{}, nil, "failed", "可疑登录已拦截")
+	failed.SetRisk(assessment)
+	assert.Equal(t, assessment.Score, failed.RiskScore)
+	assert.Equal(t, loginrisk.DecisionBlock, failed.RiskDecision)
+	assert.NotEmpty(t, failed.RiskReasons)
+
+	// 未配置风险评估时不评估
+	assert.Nil(t, New(PrincipalUser, store, nil, nil, Options{}).AssessLoginRisk(testAccount.ID, nil, userAgent))
+}
@@ -0,0 +1,61 @@
+package identity
+
+import "strings"
+
+// Device 从 User-Agent 解析出的设备信息
+type Device struct {
+	DeviceType string // mobile | tablet | desktop
+	OS         string
+	Browser    string
+	Platform   string // mobile | web
+}
+
+// ParseUserAgent 解析 User-Agent，无法识别的操作系统和浏览器留空
+func ParseUserAgent(userAgent string) Device {
+	ua := strings.ToLower(userAgent)
+	var device Device
+
+	// 检测设备类型
+	if strings.Contains(ua, "mobile") || strings.Contains(ua, "android") || strings.Contains(ua, "iphone") {
+		device.DeviceType = "mobile"
+	} else if strings.Contains(ua, "tablet") || strings.Contains(ua, "ipad") {
+		device.DeviceType = "tablet"
+	} else {
+		device.DeviceType = "desktop"
+	}
+
+	// 检测操作系统
+	if strings.Contains(ua, "windows") {
+		device.OS = "Windows"
+	} else if strings.Contains(ua, "mac") || strings.Contains(ua, "darwin") {
+		device.OS = "macOS"
+	} else if strings.Contains(ua, "linux") {
+		device.OS = "Linux"
+	} else if strings.Contains(ua, "android") {
+		device.OS = "Android"
+	} else if strings.Contains(ua, "iphone") || strings.Contains(ua, "ipad") || strings.Contains(ua, "ios") {
+		device.OS = "iOS"
+	}
+
+	// 检测浏览器
+	if strings.Contains(ua, "chrome") && !strings.Contains(ua, "edge") {
+		device.Browser = "Chrome"
+	} else if strings.Contains(ua, "firefox") {
+		device.Browser = "Firefox"
+	} else if strings.Contains(ua, "safari") && !strings.Contains(ua, "chrome") {
+		device.Browser = "Safari"
+	} else if strings.Contains(ua, "edge") {
+		device.Browser = "Edge"
+	} else if strings.Contains(ua, "opera") {
+		device.Browser = "Opera"
+	}
+
+	// 检测平台
+	if strings.Contains(ua, "mobile") || strings.Contains(ua, "android") || strings.Contains(ua, "iphone") {
+		device.Platform = "mobile"
+	} else {
+		device.Platform = "web"
+	}
+
+	return device
+}
@@ -0,0 +1,51 @@
+package identity
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"trusioo_api/pkg/ipinfo"
+)
+
+// Location 登录IP的地理位置，写入登录会话记录
+type Location struct {
+	Country      string
+	City         string
+	Region       string
+	Timezone     string
+	Organization string
+	Coordinates  string // 经纬度，对应会话记录的 location 字段
+}
+
+// LocationOf 从IP信息中取出地理位置，info 为空时返回零值
+func LocationOf(info *ipinfo.IPInfo) Location {
+	if info == nil {
+		return Location{}
+	}
+	return Location{
+		Country:      info.Country,
+		City:         info.City,
+		Region:       info.Region,
+		Timezone:     info.Timezone,
+		Organization: info.Org,
+		Coordinates:  info.Loc,
+	}
+}
+
+// LookupIP 获取IP地理位置信息，未配置客户端或查询失败时返回nil
+func (c *Core) LookupIP(ip string) *ipinfo.IPInfo {
+	if c.ipinfo == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	info, err := c.ipinfo.GetIPInfo(ctx, ip)
+	if err != nil {
+		log.Printf("获取IP信息失败 %s: %v", ip, err)
+		return nil
+	}
+	return info
+}
@@ -0,0 +1,84 @@
+package identity
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"trusioo_api/internal/auth/lockout"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// LoginGuard 密码登录失败的退避与锁定，由 lockout.Guard 实现
+type LoginGuard interface {
+	Check(ctx context.Context, account, ip string) (time.Duration, error)
+	Fail(ctx context.Context, account, ip string) (*lockout.Result, error)
+	Reset(ctx context.Context, account string) error
+}
+
+// dummyPasswordHash 账户不存在时同样执行一次 bcrypt 比较，使响应时间与密码错误一致
+var dummyPasswordHash = sync.OnceValue(func() []byte {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("dummy-password"), bcrypt.DefaultCost)
+	return hash
+})
+
+// ComparePassword 校验密码，hash 为空表示账户不存在，此时与虚拟哈希比较后返回 false
+func ComparePassword(hash, plain string) bool {
+	if hash == "" {
+		bcrypt.CompareHashAndPassword(dummyPasswordHash(), []byte(plain))
+		return false
+	}
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(plain)) == nil
+}
+
+// LoginRetryAfter 返回账户或IP需要等待的时间，未启用或 Redis 不可用时不阻止登录
+func (c *Core) LoginRetryAfter(account, ip string) time.Duration {
+	if c.loginGuard == nil {
+		return 0
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	wait, err := c.loginGuard.Check(ctx, account, ip)
+	if err != nil {
+		log.Printf("查询%s登录锁定状态失败: %v", c.principal, err)
+		return 0
+	}
+	return wait
+}
+
+// RecordLoginFailure 记录一次密码错误，账户不存在时同样计数；返回账户是否因此被锁定，由调用方通知账户
+func (c *Core) RecordLoginFailure(account, ip string) bool {
+	if c.loginGuard == nil {
+		return false
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	result, err := c.loginGuard.Fail(ctx, account, ip)
+	if err != nil {
+		log.Printf("记录%s登录失败次数失败: %v", c.principal, err)
+		return false
+	}
+	return result.AccountLocked
+}
+
+// ResetLoginFailures 密码验证通过后清除账户的失败次数
+func (c *Core) ResetLoginFailures(account string) {
+	if c.loginGuard == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	if err := c.loginGuard.Reset(ctx, account); err != nil {
+		log.Printf("清除%s登录失败次数失败: %v", c.principal, err)
+	}
+}
+
+// RetryAfterSeconds 需要等待的时间向上取整为秒，用于 Retry-After
+func RetryAfterSeconds(wait time.Duration) int {
+	return int((wait + time.Second - 1) / time.Second)
+}
@@ -0,0 +1,87 @@
+package identity
+
+import (
+	"log"
+	"time"
+
+	verificationDto "trusioo_api/internal/auth/verification/dto"
+	"trusioo_api/internal/common"
+	"trusioo_api/pkg/ipinfo"
+)
+
+// Attempt 一次登录请求的来源与登录方式
+type Attempt struct {
+	IP        string
+	UserAgent string
+	Method    string // 登录方式，管理员登录为空
+}
+
+// LoginSession 与账户类型无关的登录记录，IP位置与设备信息由 NewLoginSession 填充
+type LoginSession struct {
+	AccountID int64
+	IP        string
+	Location
+	UserAgent string
+	Device
+	Method    string
+	Status    string // success | failed
+	Reason    string
+	IsTrusted bool
+	CreatedAt time.Time
+
+	// 登录风险评估结果，未启用风险评估时为零值
+	RiskScore    int
+	RiskDecision string
+	RiskReasons  string
+}
+
+// NewLoginSession 根据IP位置与User-Agent生成登录记录，ipInfo 为空时位置留空
+func NewLoginSession(accountID int64, attempt Attempt, ipInfo *ipinfo.IPInfo, status, reason string) *LoginSession {
+	return &LoginSession{
+		AccountID: accountID,
+		IP:        attempt.IP,
+		Location:  LocationOf(ipInfo),
+		UserAgent: attempt.UserAgent,
+		Device:    ParseUserAgent(attempt.UserAgent),
+		Method:    attempt.Method,
+		Status:    status,
+		Reason:    reason,
+		CreatedAt: time.Now(),
+	}
+}
+
+// SaveLoginSession 保存登录记录，失败时只记录日志并返回 false，不影响登录结果
+func (c *Core) SaveLoginSession(session *LoginSession) bool {
+	if err := c.store.CreateLoginSession(session); err != nil {
+		log.Printf("记录%s登录会话失败: %v", c.principal, err)
+		return false
+	}
+	return true
+}
+
+// RecordLoginSession 查询IP位置后保存登录记录，保存失败时返回 nil
+func (c *Core) RecordLoginSession(accountID int64, attempt Attempt, status, reason string) *LoginSession {
+	session := NewLoginSession(accountID, attempt, c.LookupIP(attempt.IP), status, reason)
+	if !c.SaveLoginSession(session) {
+		return nil
+	}
+	return session
+}
+
+// VerifyLoginCode 校验登录验证码，未通过时记录失败的登录会话，验证码无效时返回 common.ErrInvalidCode
+func (c *Core) VerifyLoginCode(accountID int64, attempt Attempt, target, code, codeType string) error {
+	verifyResp, err := c.codes.VerifyCode(&verificationDto.VerifyCodeRequest{
+		Target: target,
+		Code:   code,
+		Type:   codeType,
+	})
+	if err != nil {
+		c.RecordLoginSession(accountID, attempt, "failed", "验证码错误")
+		return err
+	}
+	if !verifyResp.Valid {
+		c.RecordLoginSession(accountID, attempt, "failed", "验证码无效")
+		return common.ErrInvalidCode
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package identity
+
+import (
+	"log"
+
+	verificationDto "trusioo_api/internal/auth/verification/dto"
+	"trusioo_api/internal/common"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ValidatePassword 按密码策略校验新密码，accountID 为 0 表示注册，此时不比较历史密码
+// currentHash 为账户当前的密码哈希，启用密码历史之前设置的密码不在历史记录中，同样不允许重复使用
+func (c *Core) ValidatePassword(accountID int64, currentHash, email, plain string) error {
+	if c.passwordPolicy == nil {
+		return nil
+	}
+
+	var previous []string
+	if accountID != 0 && c.passwordPolicy.HistorySize() > 0 {
+		hashes, err := c.store.ListPasswordHistory(accountID, c.passwordPolicy.HistorySize())
+		if err != nil {
+			log.Printf("查询%s %d 密码历史失败: %v", c.principal, accountID, err)
+		}
+		if currentHash != "" && (len(hashes) == 0 || hashes[0] != currentHash) {
+			previous = append(previous, currentHash)
+		}
+		previous = append(previous, hashes...)
+	}
+	return c.passwordPolicy.Validate(plain, email, previous)
+}
+
+// RecordPasswordHistory 保存新密码哈希，失败时只记录日志
+func (c *Core) RecordPasswordHistory(accountID int64, passwordHash string) {
+	if c.passwordPolicy == nil || c.passwordPolicy.HistorySize() <= 0 {
+		return
+	}
+	if err := c.store.AddPasswordHistory(accountID, passwordHash, c.passwordPolicy.HistorySize()); err != nil {
+		log.Printf("保存%s %d 密码历史失败: %v", c.principal, accountID, err)
+	}
+}
+
+// SendPasswordReset 发送重置密码验证码，账户是否存在及状态由调用方在此之前判断
+func (c *Core) SendPasswordReset(email string) error {
+	_, err := c.codes.SendVerificationCode(&verificationDto.SendVerificationRequest{
+		Target: email,
+		Type:   c.principal.ResetCodeType(),
+	})
+	return err
+}
+
+// ResetPassword 校验重置密码验证码，新密码通过 check 后加密保存，并使账户的全部刷新令牌失效
+// check 为空时不做额外校验，返回新密码哈希供调用方记录密码历史
+func (c *Core) ResetPassword(accountID int64, email, code, password string, check func(password string) error) (string, error) {
+	verifyResp, err := c.codes.VerifyCode(&verificationDto.VerifyCodeRequest{
+		Target: email,
+		Code:   code,
+		Type:   c.principal.ResetCodeType(),
+	})
+	if err != nil {
+		return "", err
+	}
+	if !verifyResp.Valid {
+		return "", common.ErrInvalidCode
+	}
+
+	if check != nil {
+		if err := check(password); err != nil {
+			return "", err
+		}
+	}
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+
+	if err := c.store.UpdatePassword(accountID, string(hashedPassword)); err != nil {
+		return "", err
+	}
+
+	// 使所有refresh token失效，强制重新登录；密码已经重置成功，失败时只记录日志
+	if err := c.store.InvalidateAllRefreshTokens(accountID); err != nil {
+		log.Printf("Failed to invalidate refresh tokens for %s %d: %v", c.principal, accountID, err)
+	}
+
+	return string(hashedPassword), nil
+}
@@ -0,0 +1,29 @@
+package identity
+
+import (
+	"trusioo_api/internal/auth/sessionlimit"
+	verificationEntities "trusioo_api/internal/auth/verification/entities"
+)
+
+// Principal 账户类型，与令牌中的 user_type 一致
+type Principal string
+
+const (
+	PrincipalUser  Principal = sessionlimit.UserTypeUser
+	PrincipalAdmin Principal = sessionlimit.UserTypeAdmin
+)
+
+// ResetCodeType 重置密码验证码类型，用户和管理员的验证码互不通用
+func (p Principal) ResetCodeType() string {
+	if p == PrincipalAdmin {
+		return "admin_forgot_password"
+	}
+	return verificationEntities.TypeForgotPassword
+}
+
+// Account 签发令牌所需的账户信息
+type Account struct {
+	ID    int64
+	Email string
+	Role  string
+}
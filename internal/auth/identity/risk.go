@@ -0,0 +1,59 @@
+package identity
+
+import (
+	"log"
+	"strings"
+	"time"
+
+	"trusioo_api/internal/auth/loginrisk"
+	"trusioo_api/pkg/ipinfo"
+)
+
+// AssessLoginRisk 将本次登录与账户最近的成功登录对比并评分，未启用或查询失败时返回nil
+func (c *Core) AssessLoginRisk(accountID int64, ipInfo *ipinfo.IPInfo, userAgent string) *loginrisk.Assessment {
+	if c.riskEvaluator == nil {
+		return nil
+	}
+
+	limit := c.riskHistorySize
+	if limit <= 0 {
+		limit = 20
+	}
+	sessions, err := c.store.GetRecentLoginSessions(accountID, limit)
+	if err != nil {
+		log.Printf("获取%s %d 登录历史失败，跳过风险评估: %v", c.principal, accountID, err)
+		return nil
+	}
+
+	history := make([]loginrisk.PastLogin, 0, len(sessions))
+	for _, session := range sessions {
+		history = append(history, loginrisk.PastLogin{
+			Country:    session.Country,
+			Location:   session.Coordinates,
+			DeviceType: session.DeviceType,
+			OS:         session.OS,
+			Browser:    session.Browser,
+			At:         session.CreatedAt,
+		})
+	}
+
+	device := ParseUserAgent(userAgent)
+
+	return c.riskEvaluator.Evaluate(loginrisk.Attempt{
+		IPInfo:     ipInfo,
+		DeviceType: device.DeviceType,
+		OS:         device.OS,
+		Browser:    device.Browser,
+		At:         time.Now(),
+	}, history)
+}
+
+// SetRisk 在登录记录中保存风险评估结果，assessment 为空时不修改
+func (s *LoginSession) SetRisk(assessment *loginrisk.Assessment) {
+	if assessment == nil {
+		return
+	}
+	s.RiskScore = assessment.Score
+	s.RiskDecision = assessment.Decision
+	s.RiskReasons = strings.Join(assessment.Reasons, ",")
+}
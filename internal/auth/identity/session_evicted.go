@@ -0,0 +1,41 @@
+package identity
+
+import (
+	"strings"
+	"time"
+
+	"trusioo_api/internal/auth/sessionlimit"
+	"trusioo_api/pkg/mailer"
+)
+
+// SessionEvictedMessage 生成会话踢出通知邮件，列出因新登录被踢出的设备
+func (c *Core) SessionEvictedMessage(email, locale string, evicted []sessionlimit.Session, userAgent string) (*mailer.Message, error) {
+	devices := make([]string, len(evicted))
+	for i, session := range evicted {
+		devices[i] = DeviceLabel(session.DeviceInfo) + "（" + session.CreatedAt.UTC().Format("2006-01-02 15:04 UTC") + "）"
+	}
+
+	maxSessions := 0
+	if c.sessionLimit != nil {
+		maxSessions = c.sessionLimit.Max(string(c.principal))
+	}
+	return mailer.Render(email, mailer.TemplateSessionEvicted, "", locale, mailer.TemplateData{
+		Email:       email,
+		Time:        time.Now().UTC().Format("2006-01-02 15:04:05 UTC"),
+		Device:      strings.Join(devices, "; "),
+		NewDevice:   DeviceLabel(userAgent),
+		MaxSessions: maxSessions,
+	})
+}
+
+// DeviceLabel 刷新令牌只记录了 User-Agent，能识别时显示浏览器和操作系统
+func DeviceLabel(userAgent string) string {
+	if strings.TrimSpace(userAgent) == "" {
+		return "unknown device"
+	}
+	parsed := ParseUserAgent(userAgent)
+	if parsed.Browser != "" && parsed.OS != "" {
+		return parsed.Browser + " / " + parsed.OS
+	}
+	return userAgent
+}
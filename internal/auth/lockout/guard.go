@@ -0,0 +1,270 @@
+package lockout
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"trusioo_api/config"
+	"trusioo_api/internal/common"
+)
+
+// Store 失败计数与锁定状态存储，由 redis.CacheService 实现
+type Store interface {
+	Increment(ctx context.Context, key string, expiration time.Duration) (int64, error)
+	TTL(ctx context.Context, key string) (time.Duration, error)
+	Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error
+	Get(ctx context.Context, key string) (string, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// Config 退避与锁定阈值
+type Config struct {
+	Window           time.Duration // 失败次数的统计窗口
+	FreeAttempts     int           // 不需要等待的失败次数
+	BaseDelay        time.Duration // 第一次需要等待的时间，之后每次失败翻倍
+	MaxDelay         time.Duration
+	AccountThreshold int // 同一账户失败达到此次数后锁定账户
+	IPThreshold      int // 同一IP失败达到此次数后锁定该IP
+	LockDuration     time.Duration
+}
+
+// DefaultConfig 默认配置
+func DefaultConfig() *Config {
+	return &Config{
+		Window:           15 * time.Minute,
+		FreeAttempts:     3,
+		BaseDelay:        2 * time.Second,
+		MaxDelay:         5 * time.Minute,
+		AccountThreshold: 10,
+		IPThreshold:      50,
+		LockDuration:     30 * time.Minute,
+	}
+}
+
+// NewConfigFromApp 从应用配置创建，未启用时返回 nil
+func NewConfigFromApp(appConfig *config.Config) *Config {
+	cfg := DefaultConfig()
+	if appConfig == nil {
+		return cfg
+	}
+	if !appConfig.LoginLockout.Enabled {
+		return nil
+	}
+
+	lc := appConfig.LoginLockout
+	if lc.WindowMinutes > 0 {
+		cfg.Window = time.Duration(lc.WindowMinutes) * time.Minute
+	}
+	if lc.FreeAttempts >= 0 {
+		cfg.FreeAttempts = lc.FreeAttempts
+	}
+	if lc.BaseDelaySeconds > 0 {
+		cfg.BaseDelay = time.Duration(lc.BaseDelaySeconds) * time.Second
+	}
+	if lc.MaxDelaySeconds > 0 {
+		cfg.MaxDelay = time.Duration(lc.MaxDelaySeconds) * time.Second
+	}
+	if lc.AccountThreshold > 0 {
+		cfg.AccountThreshold = lc.AccountThreshold
+	}
+	if lc.IPThreshold > 0 {
+		cfg.IPThreshold = lc.IPThreshold
+	}
+	if lc.LockDurationMinutes > 0 {
+		cfg.LockDuration = time.Duration(lc.LockDurationMinutes) * time.Minute
+	}
+	return cfg
+}
+
+// Result 一次失败后的状态
+type Result struct {
+	RetryAfter    time.Duration // 下一次尝试前需要等待的时间
+	AccountLocked bool          // 本次失败使账户进入锁定，调用方发送解锁邮件
+}
+
+// Guard 按账户和IP统计密码登录失败次数
+// 超过免等待次数后每次失败的等待时间翻倍，达到阈值后锁定；账户不存在时同样计数和锁定，避免通过响应区分账户是否存在
+type Guard struct {
+	store  Store
+	config *Config
+}
+
+// NewGuard 创建登录失败锁定
+func NewGuard(store Store, cfg *Config) *Guard {
+	if cfg == nil {
+		cfg = DefaultConfig()
+	}
+	return &Guard{store: store, config: cfg}
+}
+
+// Check 返回账户或IP需要等待的时间，0 表示允许尝试
+func (g *Guard) Check(ctx context.Context, account, ip string) (time.Duration, error) {
+	var wait time.Duration
+	for _, key := range g.blockKeys(account, ip) {
+		ttl, err := g.store.TTL(ctx, key)
+		if err != nil {
+			return 0, err
+		}
+		if ttl > wait {
+			wait = ttl
+		}
+	}
+	return wait, nil
+}
+
+// Fail 记录一次失败，返回下一次尝试前需要等待的时间
+func (g *Guard) Fail(ctx context.Context, account, ip string) (*Result, error) {
+	result := &Result{}
+
+	accountKey := accountKey(account)
+	count, err := g.store.Increment(ctx, "fail:"+accountKey, g.config.Window)
+	if err != nil {
+		return nil, err
+	}
+	if count >= int64(g.config.AccountThreshold) {
+		if err := g.lock(ctx, accountKey); err != nil {
+			return nil, err
+		}
+		result.AccountLocked = true
+		result.RetryAfter = g.config.LockDuration
+	} else if err := g.backoff(ctx, accountKey, count, result); err != nil {
+		return nil, err
+	}
+
+	if ip == "" {
+		return result, nil
+	}
+	ipKey := "ip:" + ip
+	count, err = g.store.Increment(ctx, "fail:"+ipKey, g.config.Window)
+	if err != nil {
+		return nil, err
+	}
+	if count >= int64(g.config.IPThreshold) {
+		if err := g.lock(ctx, ipKey); err != nil {
+			return nil, err
+		}
+		if g.config.LockDuration > result.RetryAfter {
+			result.RetryAfter = g.config.LockDuration
+		}
+		return result, nil
+	}
+	if err := g.backoff(ctx, ipKey, count, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Reset 清除账户的失败次数、等待和锁定，用于登录成功和管理员解锁
+func (g *Guard) Reset(ctx context.Context, account string) error {
+	key := accountKey(account)
+	for _, k := range []string{"fail:" + key, "wait:" + key, "lock:" + key} {
+		if err := g.store.Delete(ctx, k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// IssueUnlockToken 生成解锁链接令牌，有效期与锁定时长相同，只保存令牌的哈希
+func (g *Guard) IssueUnlockToken(ctx context.Context, account string) (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+	if err := g.store.Set(ctx, unlockKey(token), normalizeAccount(account), g.config.LockDuration); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// Unlock 使用解锁令牌解除账户锁定，令牌只能使用一次，返回被解锁的账户
+func (g *Guard) Unlock(ctx context.Context, token string) (string, error) {
+	key := unlockKey(token)
+	account, err := g.store.Get(ctx, key)
+	if err != nil || account == "" {
+		return "", common.ErrUnlockTokenInvalid
+	}
+	if err := g.store.Delete(ctx, key); err != nil {
+		return "", err
+	}
+	if err := g.Reset(ctx, account); err != nil {
+		return "", err
+	}
+	return account, nil
+}
+
+// LockDuration 锁定时长
+func (g *Guard) LockDuration() time.Duration {
+	return g.config.LockDuration
+}
+
+// Delay 第 failures 次失败后需要等待的时间
+func (c *Config) Delay(failures int64) time.Duration {
+	exceeded := failures - int64(c.FreeAttempts)
+	if exceeded <= 0 {
+		return 0
+	}
+	delay := c.BaseDelay
+	for i := int64(1); i < exceeded; i++ {
+		delay *= 2
+		if delay >= c.MaxDelay {
+			return c.MaxDelay
+		}
+	}
+	if delay > c.MaxDelay {
+		return c.MaxDelay
+	}
+	return delay
+}
+
+func (g *Guard) backoff(ctx context.Context, key string, failures int64, result *Result) error {
+	delay := g.config.Delay(failures)
+	if delay <= 0 {
+		return nil
+	}
+	if err := g.store.Set(ctx, "wait:"+key, failures, delay); err != nil {
+		return err
+	}
+	if delay > result.RetryAfter {
+		result.RetryAfter = delay
+	}
+	return nil
+}
+
+// lock 锁定并清除失败次数，锁定结束后重新计数
+func (g *Guard) lock(ctx context.Context, key string) error {
+	if err := g.store.Set(ctx, "lock:"+key, time.Now().Unix(), g.config.LockDuration); err != nil {
+		return err
+	}
+	return g.store.Delete(ctx, "fail:"+key)
+}
+
+func (g *Guard) blockKeys(account, ip string) []string {
+	key := accountKey(account)
+	keys := []string{"lock:" + key, "wait:" + key}
+	if ip != "" {
+		keys = append(keys, "lock:ip:"+ip, "wait:ip:"+ip)
+	}
+	return keys
+}
+
+func normalizeAccount(account string) string {
+	return strings.ToLower(strings.TrimSpace(account))
+}
+
+// accountKey Redis 键中使用邮箱的哈希，不保存明文邮箱
+func accountKey(account string) string {
+	sum := sha256.Sum256([]byte(normalizeAccount(account)))
+	return "acct:" + hex.EncodeToString(sum[:16])
+}
+
+func unlockKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return "unlock:" + hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,191 @@
+package lockout
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"trusioo_api/internal/common"
+)
+
+// fakeStore 内存存储，过期时间以 ttl 字段记录，不随时间减少
+type fakeStore struct {
+	values map[string]string
+	ttls   map[string]time.Duration
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{values: map[string]string{}, ttls: map[string]time.Duration{}}
+}
+
+func (f *fakeStore) Increment(ctx context.Context, key string, expiration time.Duration) (int64, error) {
+	var count int64
+	fmt.Sscanf(f.values[key], "%d", &count)
+	count++
+	f.values[key] = fmt.Sprint(count)
+	if count == 1 {
+		f.ttls[key] = expiration
+	}
+	return count, nil
+}
+
+func (f *fakeStore) TTL(ctx context.Context, key string) (time.Duration, error) {
+	if _, ok := f.values[key]; !ok {
+		return -2, nil
+	}
+	return f.ttls[key], nil
+}
+
+func (f *fakeStore) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
+	f.values[key] = fmt.Sprint(value)
+	f.ttls[key] = expiration
+	return nil
+}
+
+func (f *fakeStore) Get(ctx context.Context, key string) (string, error) {
+	value, ok := f.values[key]
+	if !ok {
+		return "", errors.New("key does not exist")
+	}
+	return value, nil
+}
+
+func (f *fakeStore) Delete(ctx context.Context, key string) error {
+	delete(f.values, key)
+	delete(f.ttls, key)
+	return nil
+}
+
+func testConfig() *Config {
+	return &Config{
+		Window:           15 * time.Minute,
+		FreeAttempts:     2,
+		BaseDelay:        time.Second,
+		MaxDelay:         5 * time.Second,
+		AccountThreshold: 6,
+		IPThreshold:      8,
+		LockDuration:     30 * time.Minute,
+	}
+}
+
+func TestConfig_Delay(t *testing.T) {
+	cfg := testConfig()
+	tests := []struct {
+		failures int64
+		delay    time.Duration
+	}{
+		{1, 0},
+		{2, 0},
+		{3, time.Second},
+		{4, 2 * time.Second},
+		{5, 4 * time.Second},
+		{6, 5 * time.Second},
+		{40, 5 * time.Second},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.delay, cfg.Delay(tt.failures), "第 %d 次失败", tt.failures)
+	}
+}
+
+func TestGuard(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("免等待次数内不需要等待", func(t *testing.T) {
+		guard := NewGuard(newFakeStore(), testConfig())
+		for i := 0; i < 2; i++ {
+			result, err := guard.Fail(ctx, "user@example.com", "10.0.0.1")
+			require.NoError(t, err)
+			assert.Zero(t, result.RetryAfter)
+		}
+		wait, err := guard.Check(ctx, "user@example.com", "10.0.0.1")
+		require.NoError(t, err)
+		assert.Zero(t, wait)
+	})
+
+	t.Run("超过免等待次数后等待时间翻倍", func(t *testing.T) {
+		guard := NewGuard(newFakeStore(), testConfig())
+		var last *Result
+		for i := 0; i < 4; i++ {
+			result, err := guard.Fail(ctx, "user@example.com", "10.0.0.1")
+			require.NoError(t, err)
+			last = result
+		}
+		assert.Equal(t, 2*time.Second, last.RetryAfter)
+
+		wait, err := guard.Check(ctx, "USER@example.com ", "10.0.0.2")
+		require.NoError(t, err)
+		assert.Equal(t, 2*time.Second, wait, "邮箱大小写和空格不影响计数")
+	})
+
+	t.Run("达到阈值锁定账户并可通过解锁令牌解锁", func(t *testing.T) {
+		store := newFakeStore()
+		guard := NewGuard(store, testConfig())
+		var last *Result
+		for i := 0; i < 6; i++ {
+			result, err := guard.Fail(ctx, "user@example.com", fmt.Sprintf("10.0.0.%d", i))
+			require.NoError(t, err)
+			last = result
+		}
+		assert.True(t, last.AccountLocked)
+		assert.Equal(t, 30*time.Minute, last.RetryAfter)
+
+		wait, err := guard.Check(ctx, "user@example.com", "10.0.1.1")
+		require.NoError(t, err)
+		assert.Equal(t, 30*time.Minute, wait)
+
+		token, err := guard.IssueUnlockToken(ctx, "User@example.com")
+		require.NoError(t, err)
+		for key := range store.values {
+			assert.NotContains(t, key, token, "不保存明文令牌")
+			assert.NotContains(t, key, "user@example.com", "键中不包含明文邮箱")
+		}
+
+		account, err := guard.Unlock(ctx, token)
+		require.NoError(t, err)
+		assert.Equal(t, "user@example.com", account)
+		wait, err = guard.Check(ctx, "user@example.com", "10.0.1.1")
+		require.NoError(t, err)
+		assert.Zero(t, wait)
+
+		_, err = guard.Unlock(ctx, token)
+		assert.Equal(t, common.ErrUnlockTokenInvalid, err, "解锁令牌只能使用一次")
+	})
+
+	t.Run("同一IP尝试多个账户达到阈值锁定IP", func(t *testing.T) {
+		guard := NewGuard(newFakeStore(), testConfig())
+		var last *Result
+		for i := 0; i < 8; i++ {
+			result, err := guard.Fail(ctx, fmt.Sprintf("user%d@example.com", i), "10.0.0.1")
+			require.NoError(t, err)
+			last = result
+		}
+		assert.False(t, last.AccountLocked)
+		assert.Equal(t, 30*time.Minute, last.RetryAfter)
+
+		wait, err := guard.Check(ctx, "other@example.com", "10.0.0.1")
+		require.NoError(t, err)
+		assert.Equal(t, 30*time.Minute, wait)
+		wait, err = guard.Check(ctx, "other@example.com", "10.0.0.2")
+		require.NoError(t, err)
+		assert.Zero(t, wait)
+	})
+
+	t.Run("登录成功清除账户计数", func(t *testing.T) {
+		guard := NewGuard(newFakeStore(), testConfig())
+		for i := 0; i < 5; i++ {
+			_, err := guard.Fail(ctx, "user@example.com", "")
+			require.NoError(t, err)
+		}
+		require.NoError(t, guard.Reset(ctx, "user@example.com"))
+
+		result, err := guard.Fail(ctx, "user@example.com", "")
+		require.NoError(t, err)
+		assert.Zero(t, result.RetryAfter)
+		assert.False(t, result.AccountLocked)
+	})
+}
@@ -0,0 +1,229 @@
+package loginrisk
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"trusioo_api/config"
+	"trusioo_api/pkg/ipinfo"
+)
+
+// Decision 风险评估结论
+const (
+	DecisionAllow     = "allow"     // 正常登录
+	DecisionChallenge = "challenge" // 需要额外验证
+	DecisionBlock     = "block"     // 拒绝登录并通知用户
+)
+
+// Reason 风险原因
+const (
+	ReasonNewCountry       = "new_country"
+	ReasonImpossibleTravel = "impossible_travel"
+	ReasonTor              = "tor"
+	ReasonAnonymizer       = "anonymizer" // VPN、代理或中继
+	ReasonHosting          = "hosting"    // 机房/云服务商网络
+	ReasonNewDevice        = "new_device"
+)
+
+// Config 评分权重与阈值
+type Config struct {
+	ChallengeScore    int     // 达到此分数需要额外验证
+	BlockScore        int     // 达到此分数拒绝登录
+	MaxTravelSpeedKmh float64 // 超过此速度视为不可能的移动
+	MinTravelKm       float64 // 小于此距离不判定不可能移动，容忍IP定位误差
+
+	Weights map[string]int
+}
+
+// DefaultConfig 默认配置
+func DefaultConfig() *Config {
+	return &Config{
+		ChallengeScore:    40,
+		BlockScore:        80,
+		MaxTravelSpeedKmh: 900,
+		MinTravelKm:       500,
+		Weights: map[string]int{
+			ReasonTor:              60,
+			ReasonImpossibleTravel: 50,
+			ReasonNewCountry:       30,
+			ReasonHosting:          25,
+			ReasonAnonymizer:       15,
+			ReasonNewDevice:        15,
+		},
+	}
+}
+
+// Attempt 本次登录的信号
+type Attempt struct {
+	IPInfo     *ipinfo.IPInfo // 可能为空（查询失败）
+	DeviceType string
+	OS         string
+	Browser    string
+	At         time.Time
+}
+
+// PastLogin 历史成功登录记录
+type PastLogin struct {
+	Country    string
+	Location   string // "lat,lon"
+	DeviceType string
+	OS         string
+	Browser    string
+	At         time.Time
+}
+
+// Assessment 评估结果
+type Assessment struct {
+	Score    int      `json:"score"`
+	Decision string   `json:"decision"`
+	Reasons  []string `json:"reasons"`
+}
+
+// Evaluator 登录风险评估器
+type Evaluator struct {
+	cfg *Config
+}
+
+// NewEvaluator 创建评估器，cfg 为空时使用默认配置
+func NewEvaluator(cfg *Config) *Evaluator {
+	if cfg == nil {
+		cfg = DefaultConfig()
+	}
+	return &Evaluator{cfg: cfg}
+}
+
+// Evaluate 将本次登录与历史记录对比并打分，history 按时间倒序排列
+// 没有历史记录时（首次登录）不计算新国家和新设备
+func (e *Evaluator) Evaluate(attempt Attempt, history []PastLogin) *Assessment {
+	a := &Assessment{Reasons: []string{}}
+	info := attempt.IPInfo
+
+	if info != nil && info.Privacy != nil {
+		if info.Privacy.Tor {
+			e.add(a, ReasonTor)
+		} else if info.Privacy.VPN || info.Privacy.Proxy || info.Privacy.Relay {
+			e.add(a, ReasonAnonymizer)
+		}
+		if info.Privacy.Hosting {
+			e.add(a, ReasonHosting)
+		}
+	} else if info != nil && info.ASN != nil && info.ASN.Type == "hosting" {
+		e.add(a, ReasonHosting)
+	}
+
+	if len(history) > 0 {
+		if info != nil && info.Country != "" && !seenCountry(history, info.Country) {
+			e.add(a, ReasonNewCountry)
+		}
+		if info != nil && e.impossibleTravel(info.Loc, attempt.At, history[0]) {
+			e.add(a, ReasonImpossibleTravel)
+		}
+		if !seenDevice(history, attempt) {
+			e.add(a, ReasonNewDevice)
+		}
+	}
+
+	switch {
+	case a.Score >= e.cfg.BlockScore:
+		a.Decision = DecisionBlock
+	case a.Score >= e.cfg.ChallengeScore:
+		a.Decision = DecisionChallenge
+	default:
+		a.Decision = DecisionAllow
+	}
+	return a
+}
+
+func (e *Evaluator) add(a *Assessment, reason string) {
+	a.Score += e.cfg.Weights[reason]
+	a.Reasons = append(a.Reasons, reason)
+}
+
+// impossibleTravel 与上一次登录位置比较，判断所需移动速度是否超出合理范围
+func (e *Evaluator) impossibleTravel(loc string, at time.Time, last PastLogin) bool {
+	lat1, lon1, ok1 := parseLocation(loc)
+	lat2, lon2, ok2 := parseLocation(last.Location)
+	if !ok1 || !ok2 {
+		return false
+	}
+
+	distance := haversineKm(lat1, lon1, lat2, lon2)
+	if distance < e.cfg.MinTravelKm {
+		return false
+	}
+
+	hours := at.Sub(last.At).Hours()
+	if hours <= 0 {
+		return true
+	}
+	return distance/hours > e.cfg.MaxTravelSpeedKmh
+}
+
+func seenCountry(history []PastLogin, country string) bool {
+	for _, h := range history {
+		if h.Country == country {
+			return true
+		}
+	}
+	return false
+}
+
+func seenDevice(history []PastLogin, attempt Attempt) bool {
+	for _, h := range history {
+		if h.DeviceType == attempt.DeviceType && h.OS == attempt.OS && h.Browser == attempt.Browser {
+			return true
+		}
+	}
+	return false
+}
+
+// parseLocation 解析 ipinfo 的 "lat,lon" 格式
+func parseLocation(loc string) (float64, float64, bool) {
+	parts := strings.Split(loc, ",")
+	if len(parts) != 2 {
+		return 0, 0, false
+	}
+	lat, err1 := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+	lon, err2 := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+	if err1 != nil || err2 != nil {
+		return 0, 0, false
+	}
+	return lat, lon, true
+}
+
+// haversineKm 计算两点间的球面距离（公里）
+func haversineKm(lat1, lon1, lat2, lon2 float64) float64 {
+	const earthRadiusKm = 6371.0
+	toRad := func(d float64) float64 { return d * math.Pi / 180 }
+
+	dLat := toRad(lat2 - lat1)
+	dLon := toRad(lon2 - lon1)
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRad(lat1))*math.Cos(toRad(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(h))
+}
+
+// NewConfigFromApp 从应用配置创建评估配置，未启用时返回 nil
+func NewConfigFromApp(appConfig *config.Config) *Config {
+	cfg := DefaultConfig()
+	if appConfig == nil {
+		return cfg
+	}
+	if !appConfig.LoginRisk.Enabled {
+		return nil
+	}
+
+	rc := appConfig.LoginRisk
+	if rc.ChallengeScore > 0 {
+		cfg.ChallengeScore = rc.ChallengeScore
+	}
+	if rc.BlockScore > 0 {
+		cfg.BlockScore = rc.BlockScore
+	}
+	if rc.MaxTravelSpeedKmh > 0 {
+		cfg.MaxTravelSpeedKmh = float64(rc.MaxTravelSpeedKmh)
+	}
+	return cfg
+}
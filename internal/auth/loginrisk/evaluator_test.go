@@ -0,0 +1,105 @@
+package loginrisk
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"trusioo_api/pkg/ipinfo"
+)
+
+func TestEvaluator_Evaluate(t *testing.T) {
+	now := time.Now()
+	shanghai := PastLogin{
+		Country:    "CN",
+		Location:   "31.2304,121.4737",
+		DeviceType: "desktop",
+		OS:         "macOS",
+		Browser:    "Chrome",
+		At:         now.Add(-2 * time.Hour),
+	}
+	macChrome := func(info *ipinfo.IPInfo) Attempt {
+		return Attempt{IPInfo: info, DeviceType: "desktop", OS: "macOS", Browser: "Chrome", At: now}
+	}
+
+	tests := []struct {
+		name     string
+		attempt  Attempt
+		history  []PastLogin
+		decision string
+		reasons  []string
+	}{
+		{
+			name:     "首次登录不计算新国家和新设备",
+			attempt:  macChrome(&ipinfo.IPInfo{Country: "US", Loc: "37.7749,-122.4194"}),
+			history:  nil,
+			decision: DecisionAllow,
+			reasons:  []string{},
+		},
+		{
+			name:     "常用地点和设备",
+			attempt:  macChrome(&ipinfo.IPInfo{Country: "CN", Loc: "31.2000,121.5000"}),
+			history:  []PastLogin{shanghai},
+			decision: DecisionAllow,
+			reasons:  []string{},
+		},
+		{
+			name:     "新设备",
+			attempt:  Attempt{IPInfo: &ipinfo.IPInfo{Country: "CN", Loc: "31.2304,121.4737"}, DeviceType: "mobile", OS: "Android", Browser: "Chrome", At: now},
+			history:  []PastLogin{shanghai},
+			decision: DecisionAllow,
+			reasons:  []string{ReasonNewDevice},
+		},
+		{
+			name:     "两小时内从上海到旧金山",
+			attempt:  macChrome(&ipinfo.IPInfo{Country: "US", Loc: "37.7749,-122.4194"}),
+			history:  []PastLogin{shanghai},
+			decision: DecisionBlock,
+			reasons:  []string{ReasonNewCountry, ReasonImpossibleTravel},
+		},
+		{
+			name:     "仅新国家不足以触发额外验证",
+			attempt:  macChrome(&ipinfo.IPInfo{Country: "JP", Loc: "35.6762,139.6503"}),
+			history:  []PastLogin{shanghai},
+			decision: DecisionAllow,
+			reasons:  []string{ReasonNewCountry},
+		},
+		{
+			name:     "机房网络加新国家",
+			attempt:  macChrome(&ipinfo.IPInfo{Country: "SG", Loc: "", Privacy: &ipinfo.Privacy{Hosting: true}}),
+			history:  []PastLogin{shanghai},
+			decision: DecisionChallenge,
+			reasons:  []string{ReasonHosting, ReasonNewCountry},
+		},
+		{
+			name:     "Tor出口节点",
+			attempt:  macChrome(&ipinfo.IPInfo{Country: "CN", Privacy: &ipinfo.Privacy{Tor: true, VPN: true}}),
+			history:  []PastLogin{shanghai},
+			decision: DecisionChallenge,
+			reasons:  []string{ReasonTor},
+		},
+		{
+			name:     "IP信息查询失败只比较设备",
+			attempt:  Attempt{DeviceType: "desktop", OS: "Windows", Browser: "Edge", At: now},
+			history:  []PastLogin{shanghai},
+			decision: DecisionAllow,
+			reasons:  []string{ReasonNewDevice},
+		},
+	}
+
+	evaluator := NewEvaluator(nil)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := evaluator.Evaluate(tt.attempt, tt.history)
+			assert.Equal(t, tt.decision, result.Decision, "score=%d", result.Score)
+			assert.Equal(t, tt.reasons, result.Reasons)
+		})
+	}
+}
+
+func TestHaversineKm(t *testing.T) {
+	// 上海到北京约1070公里
+	d := haversineKm(31.2304, 121.4737, 39.9042, 116.4074)
+	assert.InDelta(t, 1067, d, 20)
+}
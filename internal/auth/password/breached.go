@@ -0,0 +1,172 @@
+package password
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// BreachedChecker 检查密码是否出现在泄露密码列表中
+type BreachedChecker interface {
+	Contains(plain string) (bool, error)
+}
+
+// LoadBreachedList 按路径类型加载泄露密码列表：目录按 SHA-1 前缀 range 文件查询，文件加载为布隆过滤器
+func LoadBreachedList(path string) (BreachedChecker, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return NewRangeDir(path), nil
+	}
+	return LoadBloomFilter(path, 0.001)
+}
+
+// sha1Hex 密码 SHA-1 的大写十六进制
+func sha1Hex(plain string) string {
+	sum := sha1.Sum([]byte(plain))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+// RangeDir k-anonymity 格式的泄露密码目录
+// 文件按 SHA-1 前 5 位十六进制命名（可带 .txt 后缀），每行为剩余 35 位和出现次数 "SUFFIX:COUNT"，与 Pwned Passwords range 接口格式相同；
+// 查询时只读取对应前缀的文件，不需要把整个列表加载到内存
+type RangeDir struct {
+	dir string
+}
+
+// NewRangeDir 创建 range 目录查询
+func NewRangeDir(dir string) *RangeDir {
+	return &RangeDir{dir: dir}
+}
+
+// Contains 读取前缀文件查找剩余部分，前缀文件不存在表示未泄露
+func (r *RangeDir) Contains(plain string) (bool, error) {
+	hash := sha1Hex(plain)
+	prefix, suffix := hash[:5], hash[5:]
+
+	file, err := os.Open(filepath.Join(r.dir, prefix))
+	if os.IsNotExist(err) {
+		file, err = os.Open(filepath.Join(r.dir, prefix+".txt"))
+	}
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if strings.EqualFold(line, suffix) {
+			return true, nil
+		}
+	}
+	return false, scanner.Err()
+}
+
+// BloomFilter 由 SHA-1 哈希构建的布隆过滤器，存在误判但不会漏判
+type BloomFilter struct {
+	bits   []uint64
+	size   uint64
+	hashes int
+}
+
+// NewBloomFilter 按预计元素数和误判率创建布隆过滤器
+func NewBloomFilter(expected int, falsePositiveRate float64) *BloomFilter {
+	if expected < 1 {
+		expected = 1
+	}
+	size := uint64(math.Ceil(-float64(expected) * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2)))
+	if size < 64 {
+		size = 64
+	}
+	hashes := int(math.Round(float64(size) / float64(expected) * math.Ln2))
+	if hashes < 1 {
+		hashes = 1
+	}
+	return &BloomFilter{
+		bits:   make([]uint64, (size+63)/64),
+		size:   size,
+		hashes: hashes,
+	}
+}
+
+// LoadBloomFilter 读取每行一个 SHA-1 十六进制哈希的文件（可带 ":COUNT"），构建布隆过滤器
+func LoadBloomFilter(path string, falsePositiveRate float64) (*BloomFilter, error) {
+	hashes, err := readHashList(path)
+	if err != nil {
+		return nil, err
+	}
+	filter := NewBloomFilter(len(hashes), falsePositiveRate)
+	for _, hash := range hashes {
+		filter.add(hash)
+	}
+	return filter, nil
+}
+
+func readHashList(path string) ([][]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var hashes [][]byte
+	scanner := bufio.NewScanner(file)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line, _, _ := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		hash, err := hex.DecodeString(line)
+		if err != nil || len(hash) != sha1.Size {
+			return nil, fmt.Errorf("%s:%d: invalid SHA-1 hash", path, lineNo)
+		}
+		hashes = append(hashes, hash)
+	}
+	return hashes, scanner.Err()
+}
+
+// Add 加入明文密码
+func (b *BloomFilter) Add(plain string) {
+	sum := sha1.Sum([]byte(plain))
+	b.add(sum[:])
+}
+
+// Contains 密码可能在列表中时返回 true
+func (b *BloomFilter) Contains(plain string) (bool, error) {
+	sum := sha1.Sum([]byte(plain))
+	for _, i := range b.positions(sum[:]) {
+		if b.bits[i/64]&(1<<(i%64)) == 0 {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (b *BloomFilter) add(hash []byte) {
+	for _, i := range b.positions(hash) {
+		b.bits[i/64] |= 1 << (i % 64)
+	}
+}
+
+// positions SHA-1 本身分布均匀，取前 16 字节做双重哈希
+func (b *BloomFilter) positions(hash []byte) []uint64 {
+	h1 := binary.BigEndian.Uint64(hash[0:8])
+	h2 := binary.BigEndian.Uint64(hash[8:16]) | 1
+	positions := make([]uint64, b.hashes)
+	for i := range positions {
+		positions[i] = (h1 + uint64(i)*h2) % b.size
+	}
+	return positions
+}
@@ -0,0 +1,70 @@
+package password
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRangeDir(t *testing.T) {
+	dir := t.TempDir()
+	hash := sha1Hex("password123")
+	content := fmt.Sprintf("0000000000000000000000000000000000A:3\r\n%s:251682\n", hash[5:])
+	require.NoError(t, os.WriteFile(filepath.Join(dir, hash[:5]+".txt"), []byte(content), 0o644))
+
+	checker, err := LoadBreachedList(dir)
+	require.NoError(t, err)
+	assert.IsType(t, &RangeDir{}, checker)
+
+	breached, err := checker.Contains("password123")
+	require.NoError(t, err)
+	assert.True(t, breached)
+
+	breached, err = checker.Contains("not-in-the-list")
+	require.NoError(t, err)
+	assert.False(t, breached, "前缀文件不存在表示未泄露")
+}
+
+func TestBloomFilter(t *testing.T) {
+	t.Run("从哈希列表文件加载", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "breached.txt")
+		content := "# SHA-1 哈希列表\n" + sha1Hex("123456") + ":37359195\n" + sha1Hex("qwerty") + "\n"
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+
+		checker, err := LoadBreachedList(path)
+		require.NoError(t, err)
+		for _, plain := range []string{"123456", "qwerty"} {
+			breached, err := checker.Contains(plain)
+			require.NoError(t, err)
+			assert.True(t, breached, plain)
+		}
+		breached, err := checker.Contains("Unbreached-9x")
+		require.NoError(t, err)
+		assert.False(t, breached)
+	})
+
+	t.Run("文件格式错误", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "breached.txt")
+		require.NoError(t, os.WriteFile(path, []byte("not-a-hash\n"), 0o644))
+		_, err := LoadBreachedList(path)
+		assert.Error(t, err)
+	})
+
+	t.Run("误判率接近设定值", func(t *testing.T) {
+		filter := NewBloomFilter(1000, 0.01)
+		for i := 0; i < 1000; i++ {
+			filter.Add(fmt.Sprintf("member-%d", i))
+		}
+		falsePositives := 0
+		for i := 0; i < 10000; i++ {
+			if ok, _ := filter.Contains(fmt.Sprintf("other-%d", i)); ok {
+				falsePositives++
+			}
+		}
+		assert.Less(t, falsePositives, 300)
+	})
+}
@@ -0,0 +1,226 @@
+package password
+
+import (
+	"log"
+	"strings"
+	"unicode"
+
+	"trusioo_api/config"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// 拒绝原因代码
+const (
+	ReasonTooShort         = "too_short"
+	ReasonTooLong          = "too_long"
+	ReasonMissingUppercase = "missing_uppercase"
+	ReasonMissingLowercase = "missing_lowercase"
+	ReasonMissingDigit     = "missing_digit"
+	ReasonMissingSymbol    = "missing_symbol"
+	ReasonContainsEmail    = "contains_email"
+	ReasonBreached         = "breached"
+	ReasonReused           = "reused"
+)
+
+// Violation 密码不满足的一条策略
+type Violation struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// PolicyError 密码被策略拒绝，包含全部不满足的原因
+type PolicyError struct {
+	Violations []Violation
+}
+
+func (e *PolicyError) Error() string {
+	codes := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		codes[i] = v.Code
+	}
+	return "password rejected by policy: " + strings.Join(codes, ", ")
+}
+
+// Config 密码策略
+type Config struct {
+	MinLength        int
+	MaxLength        int // 字节数，bcrypt 只使用前 72 字节
+	RequireUppercase bool
+	RequireLowercase bool
+	RequireDigit     bool
+	RequireSymbol    bool
+	ForbidEmail      bool
+	HistorySize      int // 不允许重复使用最近几次的密码
+}
+
+// DefaultConfig 默认配置
+func DefaultConfig() *Config {
+	return &Config{
+		MinLength:        8,
+		MaxLength:        72,
+		RequireLowercase: true,
+		RequireDigit:     true,
+		ForbidEmail:      true,
+		HistorySize:      5,
+	}
+}
+
+// NewConfigFromApp 从应用配置创建
+func NewConfigFromApp(appConfig *config.Config) *Config {
+	cfg := DefaultConfig()
+	if appConfig == nil {
+		return cfg
+	}
+
+	pc := appConfig.PasswordPolicy
+	if pc.MinLength > 0 {
+		cfg.MinLength = pc.MinLength
+	}
+	if pc.MaxLength > 0 {
+		cfg.MaxLength = pc.MaxLength
+	}
+	cfg.RequireUppercase = pc.RequireUppercase
+	cfg.RequireLowercase = pc.RequireLowercase
+	cfg.RequireDigit = pc.RequireDigit
+	cfg.RequireSymbol = pc.RequireSymbol
+	cfg.ForbidEmail = pc.ForbidEmail
+	if pc.HistorySize >= 0 {
+		cfg.HistorySize = pc.HistorySize
+	}
+	return cfg
+}
+
+// Policy 校验新密码的长度、字符类别、是否包含邮箱、是否出现在泄露密码列表中以及是否与历史密码重复
+type Policy struct {
+	cfg      *Config
+	breached BreachedChecker
+}
+
+// NewPolicy 创建密码策略，breached 为空时不检查泄露密码，cfg 为空时使用默认配置
+func NewPolicy(cfg *Config, breached BreachedChecker) *Policy {
+	if cfg == nil {
+		cfg = DefaultConfig()
+	}
+	return &Policy{cfg: cfg, breached: breached}
+}
+
+// NewPolicyFromApp 从应用配置创建，泄露密码列表加载失败时记录日志并跳过该检查
+func NewPolicyFromApp(appConfig *config.Config) *Policy {
+	var breached BreachedChecker
+	if appConfig != nil && appConfig.PasswordPolicy.BreachedListPath != "" {
+		checker, err := LoadBreachedList(appConfig.PasswordPolicy.BreachedListPath)
+		if err != nil {
+			log.Printf("加载泄露密码列表失败: %v", err)
+		} else {
+			breached = checker
+		}
+	}
+	return NewPolicy(NewConfigFromApp(appConfig), breached)
+}
+
+// HistorySize 需要保留的历史密码数量
+func (p *Policy) HistorySize() int {
+	return p.cfg.HistorySize
+}
+
+// Validate 校验新密码，email 为账户邮箱，previousHashes 为当前和最近使用过的密码哈希，HistorySize 为 0 时不比较
+// 不满足时返回 *PolicyError，泄露密码列表不可读时返回其错误
+func (p *Policy) Validate(plain, email string, previousHashes []string) error {
+	violations := p.check(plain, email)
+
+	if p.breached != nil {
+		breached, err := p.breached.Contains(plain)
+		if err != nil {
+			return err
+		}
+		if breached {
+			violations = append(violations, Violation{ReasonBreached, "Password has appeared in a data breach"})
+		}
+	}
+
+	if p.cfg.HistorySize > 0 && reused(plain, previousHashes) {
+		violations = append(violations, Violation{ReasonReused, "Password was used recently"})
+	}
+
+	if len(violations) > 0 {
+		return &PolicyError{Violations: violations}
+	}
+	return nil
+}
+
+// check 不需要外部数据的规则
+func (p *Policy) check(plain, email string) []Violation {
+	var violations []Violation
+
+	if len([]rune(plain)) < p.cfg.MinLength {
+		violations = append(violations, Violation{ReasonTooShort, "Password is too short"})
+	}
+	if p.cfg.MaxLength > 0 && len(plain) > p.cfg.MaxLength {
+		violations = append(violations, Violation{ReasonTooLong, "Password is too long"})
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range plain {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			symbol = true
+		}
+	}
+	if p.cfg.RequireUppercase && !upper {
+		violations = append(violations, Violation{ReasonMissingUppercase, "Password must contain an uppercase letter"})
+	}
+	if p.cfg.RequireLowercase && !lower {
+		violations = append(violations, Violation{ReasonMissingLowercase, "Password must contain a lowercase letter"})
+	}
+	if p.cfg.RequireDigit && !digit {
+		violations = append(violations, Violation{ReasonMissingDigit, "Password must contain a digit"})
+	}
+	if p.cfg.RequireSymbol && !symbol {
+		violations = append(violations, Violation{ReasonMissingSymbol, "Password must contain a symbol"})
+	}
+
+	if p.cfg.ForbidEmail && containsEmail(plain, email) {
+		violations = append(violations, Violation{ReasonContainsEmail, "Password must not contain your email address"})
+	}
+	return violations
+}
+
+// containsEmail 不区分大小写地检查密码是否包含邮箱或邮箱用户名，过短的用户名不检查
+func containsEmail(plain, email string) bool {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email == "" {
+		return false
+	}
+	plain = strings.ToLower(plain)
+	if strings.Contains(plain, email) {
+		return true
+	}
+	local, _, _ := strings.Cut(email, "@")
+	return len(local) >= 3 && strings.Contains(plain, local)
+}
+
+// reused 与历史密码哈希逐一比较
+func reused(plain string, hashes []string) bool {
+	for _, hash := range hashes {
+		if hash != "" && bcrypt.CompareHashAndPassword([]byte(hash), []byte(plain)) == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// Violations 从错误中取出策略拒绝原因，不是 *PolicyError 时返回 false
+func Violations(err error) ([]Violation, bool) {
+	policyErr, ok := err.(*PolicyError)
+	if !ok {
+		return nil, false
+	}
+	return policyErr.Violations, true
+}
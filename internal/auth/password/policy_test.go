@@ -0,0 +1,88 @@
+package password
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func codes(err error) []string {
+	violations, ok := Violations(err)
+	if !ok {
+		return nil
+	}
+	result := make([]string, len(violations))
+	for i, v := range violations {
+		result[i] = v.Code
+	}
+	return result
+}
+
+func TestPolicy_Validate(t *testing.T) {
+	policy := NewPolicy(&Config{
+		MinLength:        8,
+		MaxLength:        72,
+		RequireUppercase: true,
+		RequireLowercase: true,
+		RequireDigit:     true,
+		RequireSymbol:    true,
+		ForbidEmail:      true,
+		HistorySize:      3,
+	}, nil)
+
+	tests := []struct {
+		name     string
+		password string
+		email    string
+		codes    []string
+	}{
+		{"满足全部规则", "Str0ng!pass", "alice@example.com", nil},
+		{"过短且缺少字符类别", "abc", "alice@example.com", []string{ReasonTooShort, ReasonMissingUppercase, ReasonMissingDigit, ReasonMissingSymbol}},
+		{"超过最大长度", "Aa1!" + strings.Repeat("x", 80), "", []string{ReasonTooLong}},
+		{"包含邮箱用户名", "Alice#2024x", "alice@example.com", []string{ReasonContainsEmail}},
+		{"包含完整邮箱", "X1!alice@example.com", "Alice@Example.com", []string{ReasonContainsEmail}},
+		{"过短的邮箱用户名不检查", "Ab1!cdefgh", "ab@example.com", nil},
+		{"多字节字符按字符计算长度", "密码Aa1!安全", "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := policy.Validate(tt.password, tt.email, nil)
+			if tt.codes == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.Equal(t, tt.codes, codes(err))
+		})
+	}
+}
+
+func TestPolicy_History(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("Old-passw0rd"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	t.Run("拒绝重复使用历史密码", func(t *testing.T) {
+		policy := NewPolicy(&Config{MinLength: 8, HistorySize: 3}, nil)
+		err := policy.Validate("Old-passw0rd", "", []string{string(hash)})
+		assert.Equal(t, []string{ReasonReused}, codes(err))
+		assert.NoError(t, policy.Validate("New-passw0rd", "", []string{string(hash)}))
+	})
+
+	t.Run("未启用密码历史时不比较", func(t *testing.T) {
+		policy := NewPolicy(&Config{MinLength: 8}, nil)
+		assert.NoError(t, policy.Validate("Old-passw0rd", "", []string{string(hash)}))
+	})
+}
+
+func TestPolicy_Breached(t *testing.T) {
+	filter := NewBloomFilter(10, 0.001)
+	filter.Add("Password1")
+	policy := NewPolicy(&Config{MinLength: 8}, filter)
+
+	err := policy.Validate("Password1", "", nil)
+	assert.Equal(t, []string{ReasonBreached}, codes(err))
+	assert.Contains(t, err.Error(), ReasonBreached)
+	assert.NoError(t, policy.Validate("Unbreached-9x", "", nil))
+}
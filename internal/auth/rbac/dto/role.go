@@ -0,0 +1,27 @@
+package dto
+
+// CreateRoleRequest 创建角色请求
+type CreateRoleRequest struct {
+	Name        string   `json:"name" binding:"required,max=50"`
+	Description string   `json:"description" binding:"max=255"`
+	Permissions []string `json:"permissions" binding:"required,dive,required"`
+}
+
+// UpdateRoleRequest 更新角色请求，字段为空时保持不变
+type UpdateRoleRequest struct {
+	Description *string  `json:"description" binding:"omitempty,max=255"`
+	Permissions []string `json:"permissions" binding:"omitempty,dive,required"`
+}
+
+// AssignRoleRequest 为管理员分配角色
+type AssignRoleRequest struct {
+	Role string `json:"role" binding:"required,max=50"`
+}
+
+// AdminPermissionsResponse 管理员的有效权限
+type AdminPermissionsResponse struct {
+	AdminID     int64    `json:"admin_id"`
+	Role        string   `json:"role"`
+	IsSuper     bool     `json:"is_super"`
+	Permissions []string `json:"permissions"`
+}
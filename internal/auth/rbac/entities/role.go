@@ -0,0 +1,35 @@
+package entities
+
+import "time"
+
+// Permission 权限定义
+type Permission struct {
+	Code        string    `json:"code" db:"code"`
+	Description string    `json:"description" db:"description"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+}
+
+// Role 管理员角色，管理员通过 admins.role 关联角色名
+type Role struct {
+	ID          int64     `json:"id" db:"id"`
+	Name        string    `json:"name" db:"name"`
+	Description string    `json:"description" db:"description"`
+	IsSystem    bool      `json:"is_system" db:"is_system"`
+	Permissions []string  `json:"permissions" db:"-"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// RolePermission 角色与权限的关联
+type RolePermission struct {
+	RoleID         int64  `db:"role_id"`
+	PermissionCode string `db:"permission_code"`
+}
+
+// AdminAccess 计算管理员权限所需的账户信息
+type AdminAccess struct {
+	ID      int64  `db:"id"`
+	Role    string `db:"role"`
+	IsSuper bool   `db:"is_super"`
+	Status  string `db:"status"`
+}
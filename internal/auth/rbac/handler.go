@@ -0,0 +1,291 @@
+package rbac
+
+import (
+	"strconv"
+
+	"trusioo_api/internal/audit"
+	"trusioo_api/internal/auth/rbac/dto"
+	"trusioo_api/internal/common"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// GetMyPermissions 获取当前管理员的权限
+// @Summary 获取当前管理员的权限
+// @Description 获取当前登录管理员的角色和有效权限
+// @Tags 管理员-权限
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} common.Response{data=dto.AdminPermissionsResponse} "获取成功"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 500 {object} common.Response "服务器错误"
+// @Router /api/v1/admin/profile/permissions [get]
+func (h *Handler) GetMyPermissions(c *gin.Context) {
+	adminID, exists := c.Get("user_id")
+	if !exists {
+		common.Unauthorized(c, "Admin not authenticated")
+		return
+	}
+
+	resp, err := h.service.GetAdminPermissions(c.Request.Context(), adminID.(int64))
+	if err != nil {
+		switch err {
+		case common.ErrAdminNotFound:
+			common.NotFound(c, "Admin not found")
+		default:
+			common.ServerError(c, err)
+		}
+		return
+	}
+
+	common.Success(c, resp)
+}
+
+// ListPermissions 获取权限定义
+// @Summary 获取权限定义
+// @Description 获取全部可分配的权限
+// @Tags 管理员-权限
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} common.Response{data=[]entities.Permission} "获取成功"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足"
+// @Failure 500 {object} common.Response "服务器错误"
+// @Router /api/v1/admin/permissions [get]
+func (h *Handler) ListPermissions(c *gin.Context) {
+	permissions, err := h.service.ListPermissions(c.Request.Context())
+	if err != nil {
+		common.ServerError(c, err)
+		return
+	}
+
+	common.Success(c, permissions)
+}
+
+// ListRoles 获取角色列表
+// @Summary 获取角色列表
+// @Description 获取全部角色及其权限
+// @Tags 管理员-权限
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} common.Response{data=[]entities.Role} "获取成功"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足"
+// @Failure 500 {object} common.Response "服务器错误"
+// @Router /api/v1/admin/roles [get]
+func (h *Handler) ListRoles(c *gin.Context) {
+	roles, err := h.service.ListRoles(c.Request.Context())
+	if err != nil {
+		common.ServerError(c, err)
+		return
+	}
+
+	common.Success(c, roles)
+}
+
+// CreateRole 创建角色
+// @Summary 创建角色
+// @Description 创建自定义角色并设置权限，权限不能超出当前管理员自己的权限
+// @Tags 管理员-权限
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body dto.CreateRoleRequest true "角色信息"
+// @Success 200 {object} common.Response{data=entities.Role} "创建成功"
+// @Failure 400 {object} common.Response "参数错误或角色已存在"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足"
+// @Failure 500 {object} common.Response "服务器错误"
+// @Router /api/v1/admin/roles [post]
+func (h *Handler) CreateRole(c *gin.Context) {
+	actorID, exists := c.Get("user_id")
+	if !exists {
+		common.Unauthorized(c, "Admin not authenticated")
+		return
+	}
+
+	var req dto.CreateRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ValidationError(c, err.Error())
+		return
+	}
+
+	role, err := h.service.CreateRole(audit.Context(c), actorID.(int64), &req)
+	if err != nil {
+		switch err {
+		case common.ErrValidation:
+			common.ValidationError(c, "Role name must be lowercase letters, digits or underscores and permissions must be defined")
+		case common.ErrRoleExists:
+			common.ValidationError(c, "Role already exists")
+		case common.ErrInsufficientPermissions:
+			common.Forbidden(c, "Cannot grant permissions you do not hold")
+		default:
+			common.ServerError(c, err)
+		}
+		return
+	}
+
+	common.Success(c, role)
+}
+
+// UpdateRole 更新角色
+// @Summary 更新角色
+// @Description 修改角色描述或整体替换角色权限，超级管理员角色不可修改；新权限不能超出当前管理员自己的权限，非超级管理员不能修改自己当前的角色
+// @Tags 管理员-权限
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "角色ID"
+// @Param request body dto.UpdateRoleRequest true "角色信息"
+// @Success 200 {object} common.Response{data=entities.Role} "更新成功"
+// @Failure 400 {object} common.Response "参数错误"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足或角色不可修改"
+// @Failure 404 {object} common.Response "角色不存在"
+// @Failure 500 {object} common.Response "服务器错误"
+// @Router /api/v1/admin/roles/{id} [put]
+func (h *Handler) UpdateRole(c *gin.Context) {
+	actorID, exists := c.Get("user_id")
+	if !exists {
+		common.Unauthorized(c, "Admin not authenticated")
+		return
+	}
+
+	roleID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		common.ValidationError(c, "Invalid role ID")
+		return
+	}
+
+	var req dto.UpdateRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ValidationError(c, err.Error())
+		return
+	}
+
+	role, err := h.service.UpdateRole(audit.Context(c), actorID.(int64), roleID, &req)
+	if err != nil {
+		switch err {
+		case common.ErrValidation:
+			common.ValidationError(c, "Unknown permission")
+		case common.ErrNotFound:
+			common.NotFound(c, "Role not found")
+		case common.ErrRoleProtected:
+			common.Forbidden(c, "Super admin role cannot be modified")
+		case common.ErrInsufficientPermissions:
+			common.Forbidden(c, "Cannot modify your own role or grant permissions you do not hold")
+		default:
+			common.ServerError(c, err)
+		}
+		return
+	}
+
+	common.Success(c, role)
+}
+
+// DeleteRole 删除角色
+// @Summary 删除角色
+// @Description 删除未分配给任何管理员的自定义角色
+// @Tags 管理员-权限
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "角色ID"
+// @Success 200 {object} common.Response "删除成功"
+// @Failure 400 {object} common.Response "参数错误或角色仍在使用"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足或系统角色不可删除"
+// @Failure 404 {object} common.Response "角色不存在"
+// @Failure 500 {object} common.Response "服务器错误"
+// @Router /api/v1/admin/roles/{id} [delete]
+func (h *Handler) DeleteRole(c *gin.Context) {
+	roleID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		common.ValidationError(c, "Invalid role ID")
+		return
+	}
+
+	if err := h.service.DeleteRole(audit.Context(c), roleID); err != nil {
+		switch err {
+		case common.ErrNotFound:
+			common.NotFound(c, "Role not found")
+		case common.ErrRoleProtected:
+			common.Forbidden(c, "System roles cannot be deleted")
+		case common.ErrRoleInUse:
+			common.ValidationError(c, "Role is still assigned to admins")
+		default:
+			common.ServerError(c, err)
+		}
+		return
+	}
+
+	common.SuccessWithMessage(c, "Role deleted", nil)
+}
+
+// AssignRole 为管理员分配角色
+// @Summary 为管理员分配角色
+// @Description 修改管理员的角色，新权限立即生效；不能修改自己的角色，只能分配自己权限范围内的角色，超级管理员角色只能由超级管理员分配
+// @Tags 管理员-权限
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "管理员ID"
+// @Param request body dto.AssignRoleRequest true "角色名"
+// @Success 200 {object} common.Response{data=dto.AdminPermissionsResponse} "分配成功"
+// @Failure 400 {object} common.Response "参数错误或不能移除最后一个超级管理员"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足"
+// @Failure 404 {object} common.Response "管理员或角色不存在"
+// @Failure 500 {object} common.Response "服务器错误"
+// @Router /api/v1/admin/admins/{id}/role [put]
+func (h *Handler) AssignRole(c *gin.Context) {
+	actorID, exists := c.Get("user_id")
+	if !exists {
+		common.Unauthorized(c, "Admin not authenticated")
+		return
+	}
+
+	adminID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		common.ValidationError(c, "Invalid admin ID")
+		return
+	}
+
+	var req dto.AssignRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ValidationError(c, err.Error())
+		return
+	}
+
+	resp, err := h.service.AssignRole(audit.Context(c), actorID.(int64), adminID, &req)
+	if err != nil {
+		switch err {
+		case common.ErrValidation:
+			common.ValidationError(c, "Cannot change your own role")
+		case common.ErrNotFound:
+			common.NotFound(c, "Role not found")
+		case common.ErrAdminNotFound:
+			common.NotFound(c, "Admin not found")
+		case common.ErrLastSuperAdmin:
+			common.ValidationError(c, "Cannot remove the last active super admin")
+		case common.ErrInsufficientPermissions:
+			common.Forbidden(c, "Cannot assign a role with permissions you do not hold")
+		default:
+			common.ServerError(c, err)
+		}
+		return
+	}
+
+	common.Success(c, resp)
+}
@@ -0,0 +1,230 @@
+package rbac
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"trusioo_api/internal/audit"
+	auditEntities "trusioo_api/internal/audit/entities"
+	"trusioo_api/internal/auth/rbac/entities"
+	"trusioo_api/internal/common"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Repository 角色与权限的数据访问接口
+type Repository interface {
+	// 权限定义
+	ListPermissions(ctx context.Context) ([]*entities.Permission, error)
+
+	// 角色
+	ListRoles(ctx context.Context) ([]*entities.Role, error)
+	GetRole(ctx context.Context, id int64) (*entities.Role, error)
+	GetRoleByName(ctx context.Context, name string) (*entities.Role, error)
+	// 角色与管理员角色的修改和审计记录在同一事务中写入
+	CreateRole(ctx context.Context, role *entities.Role, entry *auditEntities.Log) error
+	UpdateRole(ctx context.Context, role *entities.Role, entry *auditEntities.Log) error
+	DeleteRole(ctx context.Context, id int64, entry *auditEntities.Log) error
+
+	// 管理员角色
+	GetAdminAccess(ctx context.Context, adminID int64) (*entities.AdminAccess, error)
+	ListAdminIDsByRole(ctx context.Context, role string) ([]int64, error)
+	UpdateAdminRole(ctx context.Context, adminID int64, role string, entry *auditEntities.Log) error
+}
+
+type repository struct {
+	db *sqlx.DB
+}
+
+// NewRepository 创建角色与权限仓库
+func NewRepository(db *sqlx.DB) Repository {
+	return &repository{db: db}
+}
+
+func (r *repository) ListPermissions(ctx context.Context) ([]*entities.Permission, error) {
+	permissions := []*entities.Permission{}
+	if err := r.db.SelectContext(ctx, &permissions, "SELECT * FROM admin_permissions ORDER BY code"); err != nil {
+		return nil, fmt.Errorf("failed to list permissions: %w", err)
+	}
+	return permissions, nil
+}
+
+func (r *repository) ListRoles(ctx context.Context) ([]*entities.Role, error) {
+	roles := []*entities.Role{}
+	if err := r.db.SelectContext(ctx, &roles, "SELECT * FROM admin_roles ORDER BY id"); err != nil {
+		return nil, fmt.Errorf("failed to list roles: %w", err)
+	}
+
+	links := []entities.RolePermission{}
+	query := `SELECT role_id, permission_code FROM admin_role_permissions ORDER BY permission_code`
+	if err := r.db.SelectContext(ctx, &links, query); err != nil {
+		return nil, fmt.Errorf("failed to list role permissions: %w", err)
+	}
+
+	byID := make(map[int64]*entities.Role, len(roles))
+	for _, role := range roles {
+		role.Permissions = []string{}
+		byID[role.ID] = role
+	}
+	for _, link := range links {
+		if role, ok := byID[link.RoleID]; ok {
+			role.Permissions = append(role.Permissions, link.PermissionCode)
+		}
+	}
+	return roles, nil
+}
+
+// GetRole 按ID获取角色及其权限，不存在时返回 sql.ErrNoRows
+func (r *repository) GetRole(ctx context.Context, id int64) (*entities.Role, error) {
+	return r.getRole(ctx, "SELECT * FROM admin_roles WHERE id = $1", id)
+}
+
+// GetRoleByName 按名称获取角色及其权限，不存在时返回 sql.ErrNoRows
+func (r *repository) GetRoleByName(ctx context.Context, name string) (*entities.Role, error) {
+	return r.getRole(ctx, "SELECT * FROM admin_roles WHERE name = $1", name)
+}
+
+func (r *repository) getRole(ctx context.Context, query string, arg interface{}) (*entities.Role, error) {
+	var role entities.Role
+	if err := r.db.GetContext(ctx, &role, query, arg); err != nil {
+		return nil, err
+	}
+
+	role.Permissions = []string{}
+	permQuery := `SELECT permission_code FROM admin_role_permissions WHERE role_id = $1 ORDER BY permission_code`
+	if err := r.db.SelectContext(ctx, &role.Permissions, permQuery, role.ID); err != nil {
+		return nil, fmt.Errorf("failed to get role permissions: %w", err)
+	}
+	return &role, nil
+}
+
+func (r *repository) CreateRole(ctx context.Context, role *entities.Role, entry *auditEntities.Log) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO admin_roles (name, description, is_system)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at, updated_at`
+	if err := tx.QueryRowContext(ctx, query, role.Name, role.Description, role.IsSystem).
+		Scan(&role.ID, &role.CreatedAt, &role.UpdatedAt); err != nil {
+		return err
+	}
+	if err := insertRolePermissions(ctx, tx, role); err != nil {
+		return err
+	}
+
+	entry.TargetID = strconv.FormatInt(role.ID, 10)
+	if err := audit.Append(ctx, tx, entry); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// UpdateRole 更新描述并整体替换角色权限
+func (r *repository) UpdateRole(ctx context.Context, role *entities.Role, entry *auditEntities.Log) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE admin_roles SET description = $2, updated_at = NOW() WHERE id = $1 RETURNING updated_at`
+	if err := tx.QueryRowContext(ctx, query, role.ID, role.Description).Scan(&role.UpdatedAt); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM admin_role_permissions WHERE role_id = $1", role.ID); err != nil {
+		return err
+	}
+	if err := insertRolePermissions(ctx, tx, role); err != nil {
+		return err
+	}
+
+	if err := audit.Append(ctx, tx, entry); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func insertRolePermissions(ctx context.Context, tx *sqlx.Tx, role *entities.Role) error {
+	for _, code := range role.Permissions {
+		query := `INSERT INTO admin_role_permissions (role_id, permission_code) VALUES ($1, $2) ON CONFLICT DO NOTHING`
+		if _, err := tx.ExecContext(ctx, query, role.ID, code); err != nil {
+			return fmt.Errorf("failed to grant %s to role %s: %w", code, role.Name, err)
+		}
+	}
+	return nil
+}
+
+func (r *repository) DeleteRole(ctx context.Context, id int64, entry *auditEntities.Log) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM admin_roles WHERE id = $1", id); err != nil {
+		return err
+	}
+
+	if err := audit.Append(ctx, tx, entry); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetAdminAccess 获取管理员的角色和状态，不存在时返回 sql.ErrNoRows
+func (r *repository) GetAdminAccess(ctx context.Context, adminID int64) (*entities.AdminAccess, error) {
+	var access entities.AdminAccess
+	query := `SELECT id, role, is_super, status FROM admins WHERE id = $1`
+	if err := r.db.GetContext(ctx, &access, query, adminID); err != nil {
+		return nil, err
+	}
+	return &access, nil
+}
+
+func (r *repository) ListAdminIDsByRole(ctx context.Context, role string) ([]int64, error) {
+	ids := []int64{}
+	if err := r.db.SelectContext(ctx, &ids, "SELECT id FROM admins WHERE role = $1", role); err != nil {
+		return nil, fmt.Errorf("failed to list admins with role %s: %w", role, err)
+	}
+	return ids, nil
+}
+
+// UpdateAdminRole 修改管理员角色，修改后没有激活的超级管理员时返回 common.ErrLastSuperAdmin
+// 锁定全部激活的超级管理员后再修改和检查，避免并发降级导致系统中没有超级管理员
+func (r *repository) UpdateAdminRole(ctx context.Context, adminID int64, role string, entry *auditEntities.Log) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	superIDs := []int64{}
+	query := `SELECT id FROM admins WHERE status = 'active' AND (is_super OR role = $1) FOR UPDATE`
+	if err := tx.SelectContext(ctx, &superIDs, query, SuperAdminRole); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE admins SET role = $2, updated_at = NOW() WHERE id = $1", adminID, role); err != nil {
+		return fmt.Errorf("failed to update role of admin %d: %w", adminID, err)
+	}
+
+	var remaining int
+	countQuery := `SELECT COUNT(*) FROM admins WHERE status = 'active' AND (is_super OR role = $1)`
+	if err := tx.GetContext(ctx, &remaining, countQuery, SuperAdminRole); err != nil {
+		return err
+	}
+	if len(superIDs) > 0 && remaining == 0 {
+		return common.ErrLastSuperAdmin
+	}
+
+	if err := audit.Append(ctx, tx, entry); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,27 @@
+package rbac
+
+import (
+	"trusioo_api/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterRoutes(router *gin.RouterGroup, handler *Handler) {
+	admin := router.Group("/admin")
+	admin.Use(middleware.AdminAuthMiddleware())
+	{
+		admin.GET("/profile/permissions", handler.GetMyPermissions) // 当前管理员的有效权限
+
+		// 角色管理 - 默认只有超级管理员拥有 roles.manage
+		manage := admin.Group("")
+		manage.Use(middleware.RequirePermission(PermRolesManage))
+		{
+			manage.GET("/permissions", handler.ListPermissions)
+			manage.GET("/roles", handler.ListRoles)
+			manage.POST("/roles", handler.CreateRole)
+			manage.PUT("/roles/:id", handler.UpdateRole)
+			manage.DELETE("/roles/:id", handler.DeleteRole)
+			manage.PUT("/admins/:id/role", handler.AssignRole)
+		}
+	}
+}
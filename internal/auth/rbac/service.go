@@ -0,0 +1,430 @@
+package rbac
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"trusioo_api/internal/audit"
+	auditEntities "trusioo_api/internal/audit/entities"
+	"trusioo_api/internal/auth/rbac/dto"
+	"trusioo_api/internal/auth/rbac/entities"
+	"trusioo_api/internal/common"
+	"trusioo_api/pkg/logger"
+)
+
+// 内置权限，与 migrations 中初始化的权限定义一致
+const (
+	PermUsersRead        = "users.read"
+	PermUsersSuspend     = "users.suspend"
+	PermUsersUpdate      = "users.update"
+	PermUsersImpersonate = "users.impersonate"
+	PermImagesRead       = "images.read"
+	PermImagesDelete     = "images.delete"
+	PermCardsReview      = "cards.review"
+	PermMonitoringRead   = "monitoring.read"
+	PermKYCReview        = "kyc.review"
+	PermFlagsManage      = "flags.manage"
+	PermReferralsManage  = "referrals.manage"
+	PermAuditRead        = "audit.read"
+	PermRolesManage      = "roles.manage"
+	PermAdminsManage     = "admins.manage"
+)
+
+// SuperAdminRole 超级管理员角色，权限固定为全部权限
+const SuperAdminRole = "super_admin"
+
+// roleNamePattern 角色名只允许小写字母、数字和下划线
+var roleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{1,49}$`)
+
+// Cache 权限缓存接口，由 redis.CacheService 实现
+type Cache interface {
+	GetJSON(ctx context.Context, key string, dest interface{}) error
+	Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error
+	Delete(ctx context.Context, key string) error
+}
+
+// Config 权限服务配置
+type Config struct {
+	CacheTTL time.Duration
+}
+
+// DefaultConfig 默认配置
+func DefaultConfig() *Config {
+	return &Config{CacheTTL: 5 * time.Minute}
+}
+
+// Service 管理员角色与权限
+// 权限按管理员ID缓存，角色变更时清除相关管理员的缓存；访问令牌中的 role 声明仅用于展示
+type Service struct {
+	repo  Repository
+	cache Cache
+	cfg   *Config
+}
+
+// NewService 创建服务，cache 为空时每次从数据库读取，cfg 为空时使用默认配置
+func NewService(repo Repository, cache Cache, cfg *Config) *Service {
+	if cfg == nil {
+		cfg = DefaultConfig()
+	}
+	return &Service{repo: repo, cache: cache, cfg: cfg}
+}
+
+// HasPermission 判断管理员是否拥有指定权限
+func (s *Service) HasPermission(ctx context.Context, adminID int64, permission string) (bool, error) {
+	permissions, err := s.Permissions(ctx, adminID)
+	if err != nil {
+		return false, err
+	}
+	for _, p := range permissions {
+		if p == permission {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Permissions 管理员的有效权限：超级管理员拥有全部权限，非激活状态的管理员没有任何权限
+func (s *Service) Permissions(ctx context.Context, adminID int64) ([]string, error) {
+	key := permissionsCacheKey(adminID)
+	if s.cache != nil {
+		var cached []string
+		if err := s.cache.GetJSON(ctx, key, &cached); err == nil {
+			return cached, nil
+		}
+	}
+
+	permissions, err := s.loadPermissions(ctx, adminID)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.cache != nil {
+		if err := s.cache.Set(ctx, key, permissions, s.cfg.CacheTTL); err != nil {
+			logger.WithError(err).Debugf("Failed to cache permissions for admin %d", adminID)
+		}
+	}
+	return permissions, nil
+}
+
+func (s *Service) loadPermissions(ctx context.Context, adminID int64) ([]string, error) {
+	access, err := s.repo.GetAdminAccess(ctx, adminID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return []string{}, nil
+		}
+		return nil, err
+	}
+	if access.Status != "active" {
+		return []string{}, nil
+	}
+
+	if access.IsSuper {
+		all, err := s.repo.ListPermissions(ctx)
+		if err != nil {
+			return nil, err
+		}
+		codes := make([]string, 0, len(all))
+		for _, p := range all {
+			codes = append(codes, p.Code)
+		}
+		return codes, nil
+	}
+
+	role, err := s.repo.GetRoleByName(ctx, access.Role)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return []string{}, nil
+		}
+		return nil, err
+	}
+	return role.Permissions, nil
+}
+
+// GetAdminPermissions 获取管理员的角色和有效权限
+func (s *Service) GetAdminPermissions(ctx context.Context, adminID int64) (*dto.AdminPermissionsResponse, error) {
+	access, err := s.repo.GetAdminAccess(ctx, adminID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, common.ErrAdminNotFound
+		}
+		return nil, err
+	}
+	permissions, err := s.Permissions(ctx, adminID)
+	if err != nil {
+		return nil, err
+	}
+	return &dto.AdminPermissionsResponse{
+		AdminID:     access.ID,
+		Role:        access.Role,
+		IsSuper:     access.IsSuper,
+		Permissions: permissions,
+	}, nil
+}
+
+// ListPermissions 获取全部权限定义
+func (s *Service) ListPermissions(ctx context.Context) ([]*entities.Permission, error) {
+	return s.repo.ListPermissions(ctx)
+}
+
+// ListRoles 获取全部角色及其权限
+func (s *Service) ListRoles(ctx context.Context) ([]*entities.Role, error) {
+	return s.repo.ListRoles(ctx)
+}
+
+// CreateRole 创建自定义角色，角色权限不能超出创建者自己的权限
+func (s *Service) CreateRole(ctx context.Context, actorID int64, req *dto.CreateRoleRequest) (*entities.Role, error) {
+	name := strings.TrimSpace(req.Name)
+	if !roleNamePattern.MatchString(name) {
+		return nil, common.ErrValidation
+	}
+	if err := s.validatePermissions(ctx, req.Permissions); err != nil {
+		return nil, err
+	}
+	if err := s.authorizePermissions(ctx, actorID, req.Permissions); err != nil {
+		return nil, err
+	}
+
+	_, err := s.repo.GetRoleByName(ctx, name)
+	if err == nil {
+		return nil, common.ErrRoleExists
+	}
+	if err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	role := &entities.Role{
+		Name:        name,
+		Description: strings.TrimSpace(req.Description),
+		Permissions: uniquePermissions(req.Permissions),
+	}
+	entry := audit.NewLog(ctx, actorID, "admin_role.create", auditEntities.TargetRole, "")
+	entry.Changes.Set("name", nil, role.Name)
+	entry.Changes.Set("description", nil, role.Description)
+	entry.Changes.Set("permissions", nil, role.Permissions)
+	if err := s.repo.CreateRole(ctx, role, entry); err != nil {
+		return nil, err
+	}
+	return role, nil
+}
+
+// UpdateRole 修改角色描述或权限，超级管理员角色不可修改
+// 新权限不能超出修改者自己的权限，非超级管理员不能修改自己当前的角色
+func (s *Service) UpdateRole(ctx context.Context, actorID, roleID int64, req *dto.UpdateRoleRequest) (*entities.Role, error) {
+	role, err := s.getRole(ctx, roleID)
+	if err != nil {
+		return nil, err
+	}
+	if role.Name == SuperAdminRole {
+		return nil, common.ErrRoleProtected
+	}
+	access, err := s.repo.GetAdminAccess(ctx, actorID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, common.ErrInsufficientPermissions
+		}
+		return nil, err
+	}
+	if !isSuperAdmin(access) && access.Role == role.Name {
+		return nil, common.ErrInsufficientPermissions
+	}
+	before := *role
+
+	if req.Description != nil {
+		role.Description = strings.TrimSpace(*req.Description)
+	}
+	if req.Permissions != nil {
+		if err := s.validatePermissions(ctx, req.Permissions); err != nil {
+			return nil, err
+		}
+		if err := s.authorizePermissions(ctx, actorID, req.Permissions); err != nil {
+			return nil, err
+		}
+		role.Permissions = uniquePermissions(req.Permissions)
+	}
+
+	entry := audit.NewLog(ctx, actorID, "admin_role.update", auditEntities.TargetRole, strconv.FormatInt(role.ID, 10))
+	entry.Changes.Set("description", before.Description, role.Description)
+	entry.Changes.Set("permissions", before.Permissions, role.Permissions)
+	if err := s.repo.UpdateRole(ctx, role, entry); err != nil {
+		return nil, err
+	}
+	s.invalidateRole(ctx, role.Name)
+	return role, nil
+}
+
+// DeleteRole 删除未被使用的自定义角色
+func (s *Service) DeleteRole(ctx context.Context, roleID int64) error {
+	role, err := s.getRole(ctx, roleID)
+	if err != nil {
+		return err
+	}
+	if role.IsSystem {
+		return common.ErrRoleProtected
+	}
+
+	adminIDs, err := s.repo.ListAdminIDsByRole(ctx, role.Name)
+	if err != nil {
+		return err
+	}
+	if len(adminIDs) > 0 {
+		return common.ErrRoleInUse
+	}
+
+	entry := audit.NewLog(ctx, audit.MetaFrom(ctx).AdminID, "admin_role.delete", auditEntities.TargetRole, strconv.FormatInt(role.ID, 10))
+	entry.Changes.Set("name", role.Name, nil)
+	entry.Changes.Set("permissions", role.Permissions, nil)
+	return s.repo.DeleteRole(ctx, role.ID, entry)
+}
+
+// AssignRole 为管理员分配角色，不能修改自己的角色以免失去角色管理权限
+// 只能分配自己权限范围内的角色，超级管理员角色只能由超级管理员分配
+func (s *Service) AssignRole(ctx context.Context, actorID, adminID int64, req *dto.AssignRoleRequest) (*dto.AdminPermissionsResponse, error) {
+	if actorID == adminID {
+		return nil, common.ErrValidation
+	}
+
+	if err := s.AuthorizeRoleGrant(ctx, actorID, req.Role); err != nil {
+		return nil, err
+	}
+	access, err := s.repo.GetAdminAccess(ctx, adminID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, common.ErrAdminNotFound
+		}
+		return nil, err
+	}
+
+	entry := audit.NewLog(ctx, actorID, "admin.assign_role", auditEntities.TargetAdmin, strconv.FormatInt(adminID, 10))
+	entry.Changes.Set("role", access.Role, req.Role)
+	// 仓库在同一事务中检查，不能通过改角色移除最后一个激活的超级管理员
+	if err := s.repo.UpdateAdminRole(ctx, adminID, req.Role, entry); err != nil {
+		return nil, err
+	}
+	s.InvalidateAdmin(ctx, adminID)
+
+	return s.GetAdminPermissions(ctx, adminID)
+}
+
+// AuthorizeRoleGrant 校验管理员可以授予指定角色，避免通过分配角色或邀请管理员提升权限
+// 超级管理员角色只能由超级管理员授予，其他角色的权限必须全部是授予者自己拥有的权限
+// 角色不存在时返回 common.ErrNotFound，超出授予者权限时返回 common.ErrInsufficientPermissions
+func (s *Service) AuthorizeRoleGrant(ctx context.Context, actorID int64, roleName string) error {
+	role, err := s.repo.GetRoleByName(ctx, roleName)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return common.ErrNotFound
+		}
+		return err
+	}
+
+	if role.Name == SuperAdminRole {
+		access, err := s.repo.GetAdminAccess(ctx, actorID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return common.ErrInsufficientPermissions
+			}
+			return err
+		}
+		if !isSuperAdmin(access) {
+			return common.ErrInsufficientPermissions
+		}
+		return nil
+	}
+
+	return s.authorizePermissions(ctx, actorID, role.Permissions)
+}
+
+// authorizePermissions 权限必须全部是管理员自己拥有的有效权限
+func (s *Service) authorizePermissions(ctx context.Context, actorID int64, codes []string) error {
+	held, err := s.Permissions(ctx, actorID)
+	if err != nil {
+		return err
+	}
+	granted := make(map[string]bool, len(held))
+	for _, p := range held {
+		granted[p] = true
+	}
+	for _, code := range codes {
+		if !granted[code] {
+			return common.ErrInsufficientPermissions
+		}
+	}
+	return nil
+}
+
+// isSuperAdmin 激活状态且带有超级管理员标记或角色
+func isSuperAdmin(access *entities.AdminAccess) bool {
+	return access.Status == "active" && (access.IsSuper || access.Role == SuperAdminRole)
+}
+
+// InvalidateAdmin 清除管理员的权限缓存，管理员角色、状态或超级管理员标记变化后调用
+func (s *Service) InvalidateAdmin(ctx context.Context, adminID int64) {
+	if s.cache == nil {
+		return
+	}
+	if err := s.cache.Delete(ctx, permissionsCacheKey(adminID)); err != nil {
+		logger.WithError(err).Warnf("Failed to invalidate permissions cache for admin %d", adminID)
+	}
+}
+
+// invalidateRole 清除使用该角色的全部管理员的权限缓存
+func (s *Service) invalidateRole(ctx context.Context, role string) {
+	adminIDs, err := s.repo.ListAdminIDsByRole(ctx, role)
+	if err != nil {
+		logger.WithError(err).Warnf("Failed to list admins with role %s", role)
+		return
+	}
+	for _, id := range adminIDs {
+		s.InvalidateAdmin(ctx, id)
+	}
+}
+
+func (s *Service) getRole(ctx context.Context, roleID int64) (*entities.Role, error) {
+	role, err := s.repo.GetRole(ctx, roleID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, common.ErrNotFound
+		}
+		return nil, err
+	}
+	return role, nil
+}
+
+// validatePermissions 权限必须是已定义的权限
+func (s *Service) validatePermissions(ctx context.Context, codes []string) error {
+	defined, err := s.repo.ListPermissions(ctx)
+	if err != nil {
+		return err
+	}
+	known := make(map[string]bool, len(defined))
+	for _, p := range defined {
+		known[p.Code] = true
+	}
+	for _, code := range codes {
+		if !known[code] {
+			return common.ErrValidation
+		}
+	}
+	return nil
+}
+
+func uniquePermissions(codes []string) []string {
+	seen := make(map[string]bool, len(codes))
+	unique := make([]string, 0, len(codes))
+	for _, code := range codes {
+		if !seen[code] {
+			seen[code] = true
+			unique = append(unique, code)
+		}
+	}
+	return unique
+}
+
+func permissionsCacheKey(adminID int64) string {
+	return fmt.Sprintf("permissions:%d", adminID)
+}
@@ -0,0 +1,387 @@
+package rbac
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"trusioo_api/internal/audit"
+	auditEntities "trusioo_api/internal/audit/entities"
+	"trusioo_api/internal/auth/rbac/dto"
+	"trusioo_api/internal/auth/rbac/entities"
+	"trusioo_api/internal/common"
+)
+
+// fakeRepository 内存版仓库
+type fakeRepository struct {
+	permissions []*entities.Permission
+	roles       []*entities.Role
+	admins      map[int64]*entities.AdminAccess
+	accessReads int
+	audits      []*auditEntities.Log
+}
+
+func newFakeRepository() *fakeRepository {
+	return &fakeRepository{
+		permissions: []*entities.Permission{
+			{Code: PermImagesDelete}, {Code: PermRolesManage}, {Code: PermUsersRead}, {Code: PermUsersSuspend},
+		},
+		roles: []*entities.Role{
+			{ID: 1, Name: SuperAdminRole, IsSystem: true, Permissions: []string{PermImagesDelete, PermRolesManage, PermUsersRead, PermUsersSuspend}},
+			{ID: 2, Name: "admin", IsSystem: true, Permissions: []string{PermImagesDelete, PermUsersRead, PermUsersSuspend}},
+			{ID: 3, Name: "support", Permissions: []string{PermUsersRead}},
+		},
+		admins: map[int64]*entities.AdminAccess{
+			1: {ID: 1, Role: SuperAdminRole, IsSuper: true, Status: "active"},
+			2: {ID: 2, Role: "support", Status: "active"},
+			3: {ID: 3, Role: "support", Status: "inactive"},
+			4: {ID: 4, Role: "retired", Status: "active"},
+			6: {ID: 6, Role: "admin", Status: "active"},
+		},
+	}
+}
+
+func (f *fakeRepository) ListPermissions(ctx context.Context) ([]*entities.Permission, error) {
+	return f.permissions, nil
+}
+
+func (f *fakeRepository) ListRoles(ctx context.Context) ([]*entities.Role, error) {
+	return f.roles, nil
+}
+
+func (f *fakeRepository) GetRole(ctx context.Context, id int64) (*entities.Role, error) {
+	for _, r := range f.roles {
+		if r.ID == id {
+			copied := *r
+			return &copied, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (f *fakeRepository) GetRoleByName(ctx context.Context, name string) (*entities.Role, error) {
+	for _, r := range f.roles {
+		if r.Name == name {
+			copied := *r
+			return &copied, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (f *fakeRepository) CreateRole(ctx context.Context, role *entities.Role, entry *auditEntities.Log) error {
+	role.ID = int64(len(f.roles) + 1)
+	f.roles = append(f.roles, role)
+	entry.TargetID = strconv.FormatInt(role.ID, 10)
+	f.audits = append(f.audits, entry)
+	return nil
+}
+
+func (f *fakeRepository) UpdateRole(ctx context.Context, role *entities.Role, entry *auditEntities.Log) error {
+	for i, r := range f.roles {
+		if r.ID == role.ID {
+			f.roles[i] = role
+			f.audits = append(f.audits, entry)
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
+
+func (f *fakeRepository) DeleteRole(ctx context.Context, id int64, entry *auditEntities.Log) error {
+	for i, r := range f.roles {
+		if r.ID == id {
+			f.roles = append(f.roles[:i], f.roles[i+1:]...)
+			f.audits = append(f.audits, entry)
+			return nil
+		}
+	}
+	return nil
+}
+
+func (f *fakeRepository) GetAdminAccess(ctx context.Context, adminID int64) (*entities.AdminAccess, error) {
+	f.accessReads++
+	access, ok := f.admins[adminID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return access, nil
+}
+
+func (f *fakeRepository) ListAdminIDsByRole(ctx context.Context, role string) ([]int64, error) {
+	var ids []int64
+	for id, a := range f.admins {
+		if a.Role == role {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+func (f *fakeRepository) UpdateAdminRole(ctx context.Context, adminID int64, role string, entry *auditEntities.Log) error {
+	before := f.countActiveSuperAdmins()
+	previous := f.admins[adminID].Role
+	f.admins[adminID].Role = role
+	if before > 0 && f.countActiveSuperAdmins() == 0 {
+		f.admins[adminID].Role = previous
+		return common.ErrLastSuperAdmin
+	}
+	f.audits = append(f.audits, entry)
+	return nil
+}
+
+func (f *fakeRepository) countActiveSuperAdmins() int {
+	count := 0
+	for _, a := range f.admins {
+		if a.Status == "active" && (a.IsSuper || a.Role == SuperAdminRole) {
+			count++
+		}
+	}
+	return count
+}
+
+// fakeCache 内存版缓存，按JSON保存与 redis.CacheService 行为一致
+type fakeCache struct {
+	values map[string]string
+}
+
+func newFakeCache() *fakeCache {
+	return &fakeCache{values: map[string]string{}}
+}
+
+func (f *fakeCache) GetJSON(ctx context.Context, key string, dest interface{}) error {
+	val, ok := f.values[key]
+	if !ok {
+		return errors.New("key does not exist")
+	}
+	return json.Unmarshal([]byte(val), dest)
+}
+
+func (f *fakeCache) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	f.values[key] = string(data)
+	return nil
+}
+
+func (f *fakeCache) Delete(ctx context.Context, key string) error {
+	delete(f.values, key)
+	return nil
+}
+
+func TestService_Permissions(t *testing.T) {
+	tests := []struct {
+		name     string
+		adminID  int64
+		expected []string
+	}{
+		{"超级管理员拥有全部权限", 1, []string{PermImagesDelete, PermRolesManage, PermUsersRead, PermUsersSuspend}},
+		{"按角色获取权限", 2, []string{PermUsersRead}},
+		{"未激活的管理员没有权限", 3, []string{}},
+		{"角色不存在时没有权限", 4, []string{}},
+		{"管理员不存在时没有权限", 99, []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := NewService(newFakeRepository(), nil, nil)
+			permissions, err := service.Permissions(context.Background(), tt.adminID)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, permissions)
+		})
+	}
+}
+
+func TestService_PermissionCache(t *testing.T) {
+	repo := newFakeRepository()
+	service := NewService(repo, newFakeCache(), nil)
+	ctx := context.Background()
+
+	allowed, err := service.HasPermission(ctx, 2, PermUsersSuspend)
+	require.NoError(t, err)
+	assert.False(t, allowed)
+
+	// 第二次查询命中缓存
+	_, err = service.HasPermission(ctx, 2, PermUsersRead)
+	require.NoError(t, err)
+	assert.Equal(t, 1, repo.accessReads)
+
+	// 修改角色权限后清除使用该角色的管理员缓存，新权限立即生效
+	_, err = service.UpdateRole(ctx, 1, 3, &dto.UpdateRoleRequest{Permissions: []string{PermUsersRead, PermUsersSuspend}})
+	require.NoError(t, err)
+	reads := repo.accessReads
+	allowed, err = service.HasPermission(ctx, 2, PermUsersSuspend)
+	require.NoError(t, err)
+	assert.True(t, allowed)
+	assert.Equal(t, reads+1, repo.accessReads)
+}
+
+func TestService_ManageRoles(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("创建角色", func(t *testing.T) {
+		service := NewService(newFakeRepository(), nil, nil)
+		role, err := service.CreateRole(ctx, 1, &dto.CreateRoleRequest{Name: "reviewer", Permissions: []string{PermUsersRead, PermUsersRead}})
+		require.NoError(t, err)
+		assert.Equal(t, []string{PermUsersRead}, role.Permissions)
+
+		_, err = service.CreateRole(ctx, 1, &dto.CreateRoleRequest{Name: "reviewer", Permissions: []string{PermUsersRead}})
+		assert.Equal(t, common.ErrRoleExists, err)
+		_, err = service.CreateRole(ctx, 1, &dto.CreateRoleRequest{Name: "Bad Name", Permissions: []string{PermUsersRead}})
+		assert.Equal(t, common.ErrValidation, err)
+		_, err = service.CreateRole(ctx, 1, &dto.CreateRoleRequest{Name: "auditor", Permissions: []string{"unknown.permission"}})
+		assert.Equal(t, common.ErrValidation, err)
+	})
+
+	t.Run("系统角色受保护", func(t *testing.T) {
+		service := NewService(newFakeRepository(), nil, nil)
+		_, err := service.UpdateRole(ctx, 1, 1, &dto.UpdateRoleRequest{Permissions: []string{PermUsersRead}})
+		assert.Equal(t, common.ErrRoleProtected, err)
+		assert.Equal(t, common.ErrRoleProtected, service.DeleteRole(ctx, 2))
+	})
+
+	t.Run("角色权限不能超出自己的权限", func(t *testing.T) {
+		repo := newFakeRepository()
+		service := NewService(repo, nil, nil)
+
+		// admin 角色没有 roles.manage 权限，不能创建或修改出包含该权限的角色
+		_, err := service.CreateRole(ctx, 6, &dto.CreateRoleRequest{Name: "escalator", Permissions: []string{PermUsersRead, PermRolesManage}})
+		assert.Equal(t, common.ErrInsufficientPermissions, err)
+		_, err = service.UpdateRole(ctx, 6, 3, &dto.UpdateRoleRequest{Permissions: []string{PermUsersRead, PermRolesManage}})
+		assert.Equal(t, common.ErrInsufficientPermissions, err)
+		assert.Equal(t, []string{PermUsersRead}, repo.roles[2].Permissions)
+		assert.Empty(t, repo.audits)
+
+		// 权限范围内可以创建和修改
+		role, err := service.CreateRole(ctx, 6, &dto.CreateRoleRequest{Name: "reviewer", Permissions: []string{PermUsersRead, PermUsersSuspend}})
+		require.NoError(t, err)
+		assert.Equal(t, int64(6), repo.audits[0].AdminID)
+		_, err = service.UpdateRole(ctx, 6, role.ID, &dto.UpdateRoleRequest{Permissions: []string{PermImagesDelete}})
+		require.NoError(t, err)
+	})
+
+	t.Run("不能修改自己当前的角色", func(t *testing.T) {
+		repo := newFakeRepository()
+		service := NewService(repo, nil, nil)
+
+		// 即使新权限在自己权限范围内，非超级管理员也不能修改自己持有的角色
+		description := "客服"
+		_, err := service.UpdateRole(ctx, 2, 3, &dto.UpdateRoleRequest{Description: &description})
+		assert.Equal(t, common.ErrInsufficientPermissions, err)
+		_, err = service.UpdateRole(ctx, 6, 2, &dto.UpdateRoleRequest{Permissions: []string{PermUsersRead}})
+		assert.Equal(t, common.ErrInsufficientPermissions, err)
+		assert.Empty(t, repo.audits)
+
+		// 超级管理员可以修改任意非保护角色，不存在的管理员不能修改
+		_, err = service.UpdateRole(ctx, 1, 3, &dto.UpdateRoleRequest{Description: &description})
+		require.NoError(t, err)
+		_, err = service.UpdateRole(ctx, 99, 3, &dto.UpdateRoleRequest{Description: &description})
+		assert.Equal(t, common.ErrInsufficientPermissions, err)
+	})
+
+	t.Run("使用中的角色不能删除", func(t *testing.T) {
+		service := NewService(newFakeRepository(), nil, nil)
+		assert.Equal(t, common.ErrRoleInUse, service.DeleteRole(ctx, 3))
+		assert.Equal(t, common.ErrNotFound, service.DeleteRole(ctx, 99))
+	})
+
+	t.Run("分配角色", func(t *testing.T) {
+		repo := newFakeRepository()
+		cache := newFakeCache()
+		service := NewService(repo, cache, nil)
+
+		_, err := service.Permissions(ctx, 2)
+		require.NoError(t, err)
+
+		resp, err := service.AssignRole(ctx, 1, 2, &dto.AssignRoleRequest{Role: "admin"})
+		require.NoError(t, err)
+		assert.Equal(t, "admin", resp.Role)
+		assert.Contains(t, resp.Permissions, PermUsersSuspend)
+		require.Len(t, repo.audits, 1)
+		assert.Equal(t, "admin.assign_role", repo.audits[0].Action)
+		assert.Equal(t, int64(1), repo.audits[0].AdminID)
+		assert.Equal(t, "2", repo.audits[0].TargetID)
+		assert.Equal(t, auditEntities.Change{Before: "support", After: "admin"}, repo.audits[0].Changes["role"])
+
+		// 不能修改自己的角色
+		_, err = service.AssignRole(ctx, 1, 1, &dto.AssignRoleRequest{Role: "admin"})
+		assert.Equal(t, common.ErrValidation, err)
+		_, err = service.AssignRole(ctx, 1, 2, &dto.AssignRoleRequest{Role: "missing"})
+		assert.Equal(t, common.ErrNotFound, err)
+		_, err = service.AssignRole(ctx, 1, 99, &dto.AssignRoleRequest{Role: "admin"})
+		assert.Equal(t, common.ErrAdminNotFound, err)
+	})
+
+	t.Run("不能分配超出自己权限的角色", func(t *testing.T) {
+		repo := newFakeRepository()
+		service := NewService(repo, nil, nil)
+
+		// 非超级管理员不能授予超级管理员角色，也不能授予包含自己没有的权限的角色
+		_, err := service.AssignRole(ctx, 6, 2, &dto.AssignRoleRequest{Role: SuperAdminRole})
+		assert.Equal(t, common.ErrInsufficientPermissions, err)
+		_, err = service.AssignRole(ctx, 2, 6, &dto.AssignRoleRequest{Role: "admin"})
+		assert.Equal(t, common.ErrInsufficientPermissions, err)
+		assert.Equal(t, "support", repo.admins[2].Role)
+		assert.Empty(t, repo.audits)
+
+		// 权限范围内的角色可以分配
+		resp, err := service.AssignRole(ctx, 6, 2, &dto.AssignRoleRequest{Role: "admin"})
+		require.NoError(t, err)
+		assert.Equal(t, "admin", resp.Role)
+		resp, err = service.AssignRole(ctx, 1, 6, &dto.AssignRoleRequest{Role: SuperAdminRole})
+		require.NoError(t, err)
+		assert.Equal(t, SuperAdminRole, resp.Role)
+	})
+
+	t.Run("不能移除最后一个超级管理员", func(t *testing.T) {
+		repo := newFakeRepository()
+		repo.admins[1] = &entities.AdminAccess{ID: 1, Role: SuperAdminRole, Status: "active"}
+		repo.admins[5] = &entities.AdminAccess{ID: 5, Role: SuperAdminRole, Status: "inactive"}
+		service := NewService(repo, nil, nil)
+
+		_, err := service.AssignRole(ctx, 6, 1, &dto.AssignRoleRequest{Role: "admin"})
+		assert.Equal(t, common.ErrLastSuperAdmin, err)
+
+		// 存在另一个激活的超级管理员时允许降级
+		repo.admins[5].Status = "active"
+		resp, err := service.AssignRole(ctx, 6, 1, &dto.AssignRoleRequest{Role: "admin"})
+		require.NoError(t, err)
+		assert.Equal(t, "admin", resp.Role)
+	})
+
+	t.Run("角色变更写入审计记录", func(t *testing.T) {
+		repo := newFakeRepository()
+		service := NewService(repo, nil, nil)
+		auditCtx := audit.WithMeta(ctx, audit.Meta{AdminID: 1, IP: "10.0.0.1", RequestID: "req-1"})
+
+		role, err := service.CreateRole(auditCtx, 1, &dto.CreateRoleRequest{Name: "reviewer", Permissions: []string{PermUsersRead}})
+		require.NoError(t, err)
+		description := "审核员"
+		_, err = service.UpdateRole(auditCtx, 1, role.ID, &dto.UpdateRoleRequest{Description: &description})
+		require.NoError(t, err)
+		require.NoError(t, service.DeleteRole(auditCtx, role.ID))
+
+		require.Len(t, repo.audits, 3)
+		for i, action := range []string{"admin_role.create", "admin_role.update", "admin_role.delete"} {
+			entry := repo.audits[i]
+			assert.Equal(t, action, entry.Action)
+			assert.Equal(t, int64(1), entry.AdminID)
+			assert.Equal(t, auditEntities.TargetRole, entry.TargetType)
+			assert.Equal(t, strconv.FormatInt(role.ID, 10), entry.TargetID)
+			assert.Equal(t, "req-1", entry.RequestID)
+		}
+		assert.Equal(t, auditEntities.Change{Before: nil, After: []string{PermUsersRead}}, repo.audits[0].Changes["permissions"])
+		// 只记录实际修改的字段
+		assert.Equal(t, auditEntities.Changes{"description": {Before: "", After: "审核员"}}, repo.audits[1].Changes)
+		assert.Equal(t, auditEntities.Change{Before: "reviewer", After: nil}, repo.audits[2].Changes["name"])
+	})
+}
@@ -0,0 +1,116 @@
+package sessionlimit
+
+import (
+	"sort"
+	"time"
+
+	"trusioo_api/config"
+	"trusioo_api/internal/common"
+)
+
+// 超过上限时的处理策略
+const (
+	PolicyEvictOldest = "evict_oldest" // 踢出最早创建的会话
+	PolicyReject      = "reject"       // 拒绝新登录
+)
+
+// 账户类型，与令牌中的 user_type 一致
+const (
+	UserTypeUser  = "user"
+	UserTypeAdmin = "admin"
+)
+
+// Session 一个有效的刷新令牌
+type Session struct {
+	ID         int64
+	DeviceInfo string
+	CreatedAt  time.Time
+}
+
+// Config 会话数量上限，0 表示不限制
+type Config struct {
+	MaxUserSessions  int
+	MaxAdminSessions int
+	Policy           string
+}
+
+// DefaultConfig 默认配置
+func DefaultConfig() *Config {
+	return &Config{
+		MaxUserSessions:  10,
+		MaxAdminSessions: 5,
+		Policy:           PolicyEvictOldest,
+	}
+}
+
+// NewConfigFromApp 从应用配置创建，未启用时返回 nil；策略无法识别时使用 evict_oldest
+func NewConfigFromApp(appConfig *config.Config) *Config {
+	cfg := DefaultConfig()
+	if appConfig == nil {
+		return cfg
+	}
+	if !appConfig.SessionLimit.Enabled {
+		return nil
+	}
+
+	sc := appConfig.SessionLimit
+	if sc.MaxUserSessions >= 0 {
+		cfg.MaxUserSessions = sc.MaxUserSessions
+	}
+	if sc.MaxAdminSessions >= 0 {
+		cfg.MaxAdminSessions = sc.MaxAdminSessions
+	}
+	if sc.Policy == PolicyReject {
+		cfg.Policy = PolicyReject
+	}
+	return cfg
+}
+
+// Limiter 签发新的刷新令牌前决定是否允许以及需要踢出哪些会话
+type Limiter struct {
+	cfg *Config
+}
+
+// NewLimiter 创建会话数量限制，cfg 为空时使用默认配置
+func NewLimiter(cfg *Config) *Limiter {
+	if cfg == nil {
+		cfg = DefaultConfig()
+	}
+	return &Limiter{cfg: cfg}
+}
+
+// Max 返回账户类型的会话上限
+func (l *Limiter) Max(userType string) int {
+	if userType == UserTypeAdmin {
+		return l.cfg.MaxAdminSessions
+	}
+	return l.cfg.MaxUserSessions
+}
+
+// Admit 根据当前有效会话判断新会话能否加入
+// 未达到上限时返回空；达到上限时按策略返回 common.ErrSessionLimitReached，或返回需要踢出的最早的会话，使加入后恰好等于上限
+func (l *Limiter) Admit(userType string, active []Session) ([]Session, error) {
+	max := l.Max(userType)
+	if max <= 0 || len(active) < max {
+		return nil, nil
+	}
+	if l.cfg.Policy == PolicyReject {
+		return nil, common.ErrSessionLimitReached
+	}
+
+	oldest := make([]Session, len(active))
+	copy(oldest, active)
+	sort.SliceStable(oldest, func(i, j int) bool {
+		return oldest[i].CreatedAt.Before(oldest[j].CreatedAt)
+	})
+	return oldest[:len(active)-max+1], nil
+}
+
+// IDs 返回会话ID
+func IDs(sessions []Session) []int64 {
+	ids := make([]int64, len(sessions))
+	for i, session := range sessions {
+		ids[i] = session.ID
+	}
+	return ids
+}
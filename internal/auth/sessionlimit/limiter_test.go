@@ -0,0 +1,63 @@
+package sessionlimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"trusioo_api/config"
+	"trusioo_api/internal/common"
+)
+
+func sessions(count int) []Session {
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	result := make([]Session, count)
+	// 按创建时间倒序传入，验证会按时间选出最早的会话
+	for i := range result {
+		result[i] = Session{ID: int64(count - i), CreatedAt: base.Add(time.Duration(count-i) * time.Hour)}
+	}
+	return result
+}
+
+func TestLimiter_Admit(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      *Config
+		userType string
+		active   int
+		evicted  []int64
+		err      error
+	}{
+		{"未达到上限", &Config{MaxUserSessions: 3, Policy: PolicyEvictOldest}, UserTypeUser, 2, nil, nil},
+		{"达到上限踢出最早的会话", &Config{MaxUserSessions: 3, Policy: PolicyEvictOldest}, UserTypeUser, 3, []int64{1}, nil},
+		{"上限调低后踢出多个会话", &Config{MaxUserSessions: 2, Policy: PolicyEvictOldest}, UserTypeUser, 4, []int64{1, 2, 3}, nil},
+		{"拒绝策略", &Config{MaxUserSessions: 3, Policy: PolicyReject}, UserTypeUser, 3, nil, common.ErrSessionLimitReached},
+		{"管理员使用单独的上限", &Config{MaxUserSessions: 10, MaxAdminSessions: 1, Policy: PolicyEvictOldest}, UserTypeAdmin, 1, []int64{1}, nil},
+		{"上限为 0 不限制", &Config{MaxUserSessions: 0, Policy: PolicyReject}, UserTypeUser, 100, nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			evicted, err := NewLimiter(tt.cfg).Admit(tt.userType, sessions(tt.active))
+			assert.Equal(t, tt.err, err)
+			if tt.evicted == nil {
+				assert.Empty(t, evicted)
+				return
+			}
+			assert.Equal(t, tt.evicted, IDs(evicted))
+		})
+	}
+}
+
+func TestNewConfigFromApp(t *testing.T) {
+	assert.Nil(t, NewConfigFromApp(&config.Config{SessionLimit: config.SessionLimitConfig{Enabled: false}}))
+
+	cfg := NewConfigFromApp(&config.Config{SessionLimit: config.SessionLimitConfig{
+		Enabled:          true,
+		MaxUserSessions:  3,
+		MaxAdminSessions: 1,
+		Policy:           "unknown",
+	}})
+	assert.Equal(t, &Config{MaxUserSessions: 3, MaxAdminSessions: 1, Policy: PolicyEvictOldest}, cfg)
+}
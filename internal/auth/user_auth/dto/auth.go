@@ -6,12 +6,20 @@ import "trusioo_api/internal/auth/user_auth/entities"
 type RegisterRequest struct {
 	Email    string `json:"email" binding:"required,email"`
 	Password string `json:"password" binding:"required,min=6"`
+
+	// 可选的邀请码，device_id 与受信任设备使用同一个客户端生成的ID，用于识别同一设备的批量注册
+	ReferralCode string `json:"referral_code,omitempty" binding:"max=16"`
+	DeviceID     string `json:"device_id,omitempty" binding:"max=128"`
 }
 
 // LoginRequest 登录请求 - 第一步：验证email+password并发送验证码
 type LoginRequest struct {
 	Email    string `json:"email" binding:"required,email"`
 	Password string `json:"password" binding:"required"`
+
+	// 受信任设备：同时提供设备ID和设备令牌且校验通过时跳过邮箱验证码
+	DeviceID    string `json:"device_id,omitempty" binding:"max=128"`
+	DeviceToken string `json:"device_token,omitempty"`
 }
 
 // VerificationType 返回用户登录的验证类型
@@ -24,6 +32,10 @@ func (r *LoginRequest) VerificationType() string {
 type LoginVerifyRequest struct {
 	Email string `json:"email" binding:"required,email"`
 	Code  string `json:"code" binding:"required,len=6"`
+
+	// 将当前设备标记为受信任设备，device_id 由客户端生成并持久保存
+	TrustDevice bool   `json:"trust_device,omitempty"`
+	DeviceID    string `json:"device_id,omitempty" binding:"required_if=TrustDevice true,max=128"`
 }
 
 // VerificationType 返回此DTO对应的验证类型
@@ -31,11 +43,141 @@ func (r *LoginVerifyRequest) VerificationType() string {
 	return "user_login"
 }
 
+// CodeLoginRequest 验证码登录请求 - 第一步：向邮箱发送验证码，无需密码
+// 邮箱未注册时同样发送验证码，验证通过后自动创建账户
+type CodeLoginRequest struct {
+	Email  string `json:"email" binding:"required,email"`
+	Locale string `json:"locale,omitempty"`
+}
+
+// VerificationType 返回验证码登录的验证类型
+func (r *CodeLoginRequest) VerificationType() string {
+	return "user_code_login"
+}
+
+// CodeLoginVerifyRequest 验证码登录请求 - 第二步：验证邮箱验证码
+type CodeLoginVerifyRequest struct {
+	Email string `json:"email" binding:"required,email"`
+	Code  string `json:"code" binding:"required,len=6"`
+
+	// 可选的邀请码，仅在新邮箱自动注册时使用
+	ReferralCode string `json:"referral_code,omitempty" binding:"max=16"`
+	DeviceID     string `json:"device_id,omitempty" binding:"max=128"`
+}
+
+// VerificationType 返回验证码登录的验证类型
+func (r *CodeLoginVerifyRequest) VerificationType() string {
+	return "user_code_login"
+}
+
+// LoginChallengeVerifyRequest 风险验证请求 - 登录被判定为需要额外验证时，提交发送到账户邮箱或已验证手机号的验证码
+type LoginChallengeVerifyRequest struct {
+	ChallengeID string `json:"challenge_id" binding:"required"`
+	Code        string `json:"code" binding:"required,len=6"`
+}
+
+// LoginChallengeResponse 需要额外验证时返回给客户端的信息
+type LoginChallengeResponse struct {
+	ChallengeID string   `json:"challenge_id"`
+	Method      string   `json:"method"`          // email_code 或 sms_code，与本次登录使用的渠道不同
+	Email       string   `json:"email,omitempty"` // 脱敏后的接收邮箱
+	Phone       string   `json:"phone,omitempty"` // 脱敏后的接收号码
+	Reasons     []string `json:"reasons"`
+	ExpiresIn   int      `json:"expires_in"` // 秒
+}
+
+// SetPasswordRequest 设置密码请求 - 用于验证码登录自动注册、尚未设置密码的账户
+type SetPasswordRequest struct {
+	Password string `json:"password" binding:"required,min=6"`
+}
+
+// CompleteProfileRequest 完善资料请求
+type CompleteProfileRequest struct {
+	Name string `json:"name" binding:"required,max=100"`
+}
+
+// OnboardingResponse 设置密码/完善资料后的响应
+type OnboardingResponse struct {
+	Message      string         `json:"message"`
+	User         *entities.User `json:"user"`
+	PendingSteps []string       `json:"pending_steps"`
+}
+
+// 待完成的引导步骤
+const (
+	OnboardingStepSetPassword     = "set_password"
+	OnboardingStepCompleteProfile = "complete_profile"
+)
+
+// PhoneLoginRequest 手机号登录请求 - 第一步：发送短信验证码
+type PhoneLoginRequest struct {
+	Phone  string `json:"phone" binding:"required"`
+	Locale string `json:"locale,omitempty"`
+}
+
+// VerificationType 返回手机号登录的验证类型
+func (r *PhoneLoginRequest) VerificationType() string {
+	return "user_phone_login"
+}
+
+// PhoneLoginVerifyRequest 手机号登录验证请求 - 第二步：验证短信验证码
+type PhoneLoginVerifyRequest struct {
+	Phone string `json:"phone" binding:"required"`
+	Code  string `json:"code" binding:"required,len=6"`
+}
+
+// VerificationType 返回手机号登录的验证类型
+func (r *PhoneLoginVerifyRequest) VerificationType() string {
+	return "user_phone_login"
+}
+
+// BindPhoneRequest 绑定手机号请求 - 向新号码发送短信验证码
+type BindPhoneRequest struct {
+	Phone  string `json:"phone" binding:"required"`
+	Locale string `json:"locale,omitempty"`
+}
+
+// VerifyPhoneRequest 验证手机号请求 - 验证通过后绑定到当前用户
+type VerifyPhoneRequest struct {
+	Phone string `json:"phone" binding:"required"`
+	Code  string `json:"code" binding:"required,len=6"`
+}
+
+// PhoneCodeResponse 短信验证码发送响应
+type PhoneCodeResponse struct {
+	Message   string `json:"message"`
+	Phone     string `json:"phone"`      // 脱敏后的E.164号码
+	ExpiresIn int    `json:"expires_in"` // 秒
+}
+
+// VerifyPhoneResponse 手机号绑定结果
+type VerifyPhoneResponse struct {
+	Message       string `json:"message"`
+	Phone         string `json:"phone"`
+	PhoneVerified bool   `json:"phone_verified"`
+}
+
 // LoginCodeResponse 登录验证码响应
 type LoginCodeResponse struct {
 	Message   string `json:"message"`
 	LoginCode string `json:"login_code"`
 	ExpiresIn int    `json:"expires_in"` // 秒
+
+	// 受信任设备跳过验证码时直接返回登录结果
+	Login *LoginResponse `json:"login,omitempty"`
+
+	// 登录失败次数过多时需要等待的秒数
+	RetryAfter int `json:"retry_after,omitempty"`
+}
+
+// UnlockAccountRequest 使用锁定通知邮件中的解锁链接解除账户锁定
+type UnlockAccountRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// UnlockAccountResponse 解锁响应
+type UnlockAccountResponse struct {
+	Message string `json:"message"`
 }
 
 // LoginResponse 登录响应
@@ -46,6 +188,15 @@ type LoginResponse struct {
 	TokenType    string            `json:"token_type"`
 	User         *entities.User    `json:"user"`
 	LoginSession *LoginSessionInfo `json:"login_session,omitempty"`
+	IsNewUser    bool              `json:"is_new_user,omitempty"` // 本次登录自动创建了账户
+	PendingSteps []string          `json:"pending_steps"`         // 尚未完成的引导步骤，见 OnboardingStep*
+
+	// 登录被判定为需要额外验证时设置，此时不返回令牌
+	Challenge *LoginChallengeResponse `json:"challenge,omitempty"`
+
+	// 请求标记受信任设备时返回，客户端需安全保存并在后续登录时提交
+	DeviceToken          string `json:"device_token,omitempty"`
+	DeviceTokenExpiresAt string `json:"device_token_expires_at,omitempty"`
 }
 
 // LoginSessionInfo 登录会话信息
@@ -58,6 +209,7 @@ type LoginSessionInfo struct {
 	Organization string `json:"organization"`
 	Location     string `json:"location"`
 	IsTrusted    bool   `json:"is_trusted"`
+	RiskDecision string `json:"risk_decision,omitempty"`
 }
 
 // RegisterResponse 注册响应
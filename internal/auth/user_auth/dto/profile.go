@@ -0,0 +1,61 @@
+package dto
+
+import "trusioo_api/internal/auth/user_auth/entities"
+
+// UpdateProfileRequest 修改资料请求，未提供的字段保持不变
+// 手机号通过 /phone/bind 验证后变更，邮箱通过 /email/change 验证后变更
+type UpdateProfileRequest struct {
+	Name    *string `json:"name,omitempty" binding:"omitempty,max=100"`
+	ImageID *int    `json:"image_id,omitempty" binding:"omitempty,min=1"` // 已上传图片的ID，用作头像
+}
+
+// ProfileResponse 修改资料后的响应
+type ProfileResponse struct {
+	Message string         `json:"message"`
+	User    *entities.User `json:"user"`
+}
+
+// ChangePasswordRequest 修改密码请求
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required,min=6,nefield=CurrentPassword"`
+}
+
+// ChangePasswordResponse 修改密码响应 - 其他会话已失效，返回当前设备的新令牌
+type ChangePasswordResponse struct {
+	Message      string `json:"message"`
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+// ChangeEmailRequest 修改邮箱第一步 - 向新邮箱发送验证码
+type ChangeEmailRequest struct {
+	NewEmail string `json:"new_email" binding:"required,email"`
+	Password string `json:"password,omitempty"` // 已设置密码的账户必填
+	Locale   string `json:"locale,omitempty"`
+}
+
+// VerificationType 返回修改邮箱的验证类型
+func (r *ChangeEmailRequest) VerificationType() string {
+	return "user_email_change"
+}
+
+// ChangeEmailVerifyRequest 修改邮箱第二步 - 验证新邮箱收到的验证码
+type ChangeEmailVerifyRequest struct {
+	NewEmail string `json:"new_email" binding:"required,email"`
+	Code     string `json:"code" binding:"required,len=6"`
+}
+
+// VerificationType 返回修改邮箱的验证类型
+func (r *ChangeEmailVerifyRequest) VerificationType() string {
+	return "user_email_change"
+}
+
+// ChangeEmailResponse 修改邮箱验证码发送响应
+type ChangeEmailResponse struct {
+	Message   string `json:"message"`
+	NewEmail  string `json:"new_email"`
+	ExpiresIn int    `json:"expires_in"` // 秒
+}
@@ -0,0 +1,38 @@
+package dto
+
+// ReauthCodeRequest 重新验证身份 - 向账户邮箱发送验证码，用于未设置密码或不想输入密码的用户
+type ReauthCodeRequest struct {
+	Locale string `json:"locale,omitempty"`
+}
+
+// VerificationType 返回重新验证身份的验证类型
+func (r *ReauthCodeRequest) VerificationType() string {
+	return "user_reauth"
+}
+
+// ReauthCodeResponse 重新验证身份验证码发送响应
+type ReauthCodeResponse struct {
+	Message   string `json:"message"`
+	ExpiresIn int    `json:"expires_in"` // 秒
+}
+
+// ReauthRequest 重新验证身份请求，password 与 code 二选一
+type ReauthRequest struct {
+	Password string `json:"password,omitempty"`
+	Code     string `json:"code,omitempty" binding:"omitempty,len=6"` // 账户邮箱收到的验证码
+}
+
+// VerificationType 返回重新验证身份的验证类型
+func (r *ReauthRequest) VerificationType() string {
+	return "user_reauth"
+}
+
+// ElevatedTokenResponse 提升令牌响应，调用修改密码等敏感接口时作为 Bearer 令牌使用
+type ElevatedTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+	AuthTime    int64  `json:"auth_time"`             // 验证身份的时间（Unix 秒）
+	ACR         string `json:"acr"`                   // 验证方式：password 或 email_code
+	RetryAfter  int    `json:"retry_after,omitempty"` // 密码错误次数过多时需要等待的秒数
+}
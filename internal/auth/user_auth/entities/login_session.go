@@ -22,5 +22,11 @@ type LoginSession struct {
 	Platform     string    `json:"platform" db:"platform"`
 	Status       string    `json:"status" db:"status"`
 	Reason       string    `json:"reason" db:"reason"`
+	RiskScore    int       `json:"risk_score" db:"risk_score"`
+	RiskDecision string    `json:"risk_decision" db:"risk_decision"` // allow | challenge | block，未评估时为空
+	RiskReasons  string    `json:"risk_reasons" db:"risk_reasons"`   // 逗号分隔的风险原因
 	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+
+	// ImpersonatorID 管理员代登录时为管理员ID，此时 login_method 为 impersonation
+	ImpersonatorID *int64 `json:"impersonator_id,omitempty" db:"impersonator_id"`
 }
\ No newline at end of file
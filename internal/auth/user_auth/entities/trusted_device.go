@@ -0,0 +1,18 @@
+package entities
+
+import "time"
+
+// TrustedDevice 受信任设备实体，持有有效设备令牌的设备登录时可跳过邮箱验证码
+type TrustedDevice struct {
+	ID            int64      `json:"id" db:"id"`
+	UserID        int64      `json:"user_id" db:"user_id"`
+	DeviceID      string     `json:"device_id" db:"device_id"`
+	Name          string     `json:"name" db:"name"` // 如 "Chrome on macOS"
+	TokenHash     string     `json:"-" db:"token_hash"`
+	UAFingerprint string     `json:"-" db:"ua_fingerprint"`
+	IP            string     `json:"ip" db:"ip"`
+	LastUsedAt    *time.Time `json:"last_used_at,omitempty" db:"last_used_at"`
+	ExpiresAt     time.Time  `json:"expires_at" db:"expires_at"`
+	RevokedAt     *time.Time `json:"-" db:"revoked_at"`
+	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
+}
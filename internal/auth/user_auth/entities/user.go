@@ -12,11 +12,13 @@ type User struct {
 	ImageKey         string     `json:"image_key" db:"image_key"`
 	Role             string     `json:"role" db:"role"`
 	Status           string     `json:"status" db:"status"`
+	SuspendedUntil   *time.Time `json:"suspended_until,omitempty" db:"suspended_until"`
 	EmailVerified    bool       `json:"email_verified" db:"email_verified"`
 	PhoneVerified    bool       `json:"phone_verified" db:"phone_verified"`
 	AutoRegistered   bool       `json:"auto_registered" db:"auto_registered"`
 	ProfileCompleted bool       `json:"profile_completed" db:"profile_completed"`
 	PasswordSet      bool       `json:"password_set" db:"password_set"`
+	KYCLevel         int        `json:"kyc_level" db:"kyc_level"`
 	LastLoginAt      *time.Time `json:"last_login_at,omitempty" db:"last_login_at"`
 	CreatedAt        time.Time  `json:"created_at" db:"created_at"`
 	UpdatedAt        time.Time  `json:"updated_at" db:"updated_at"`
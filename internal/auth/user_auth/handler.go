@@ -1,6 +1,9 @@
 package user_auth
 
 import (
+	"strconv"
+
+	"trusioo_api/internal/auth/password"
 	"trusioo_api/internal/auth/user_auth/dto"
 	"trusioo_api/internal/common"
 
@@ -17,13 +20,14 @@ func NewHandler(service *Service) *Handler {
 
 // Register 用户注册
 // @Summary 用户注册
-// @Description 创建新用户账户
+// @Description 创建新用户账户，可填写邀请码 referral_code
 // @Tags 认证
 // @Accept json
 // @Produce json
 // @Param request body RegisterRequest true "注册请求参数"
 // @Success 200 {object} common.Response{data=RegisterResponse} "注册成功"
-// @Failure 400 {object} common.Response "参数错误或邮箱已存在"
+// @Failure 400 {object} common.Response "参数错误、邮箱已存在、邀请码无效或密码不满足密码策略（password_rejected）"
+// @Failure 428 {object} common.Response "请求过多，需要完成挑战后重试，见 POST /api/v1/auth/challenge"
 // @Failure 500 {object} common.Response "服务器错误"
 // @Router /api/v1/auth/register [post]
 func (h *Handler) Register(c *gin.Context) {
@@ -33,15 +37,21 @@ func (h *Handler) Register(c *gin.Context) {
 		return
 	}
 
-	resp, err := h.service.Register(&req)
+	resp, err := h.service.Register(&req, c.ClientIP(), c.GetHeader("User-Agent"))
 	if err != nil {
 		switch err {
 		case common.ErrEmailExists:
 			common.ValidationError(c, "Email already registered")
+		case common.ErrReferralCodeInvalid:
+			common.ValidationError(c, "Invalid referral code")
 		case common.ErrPhoneExists:
 			common.ValidationError(c, "Phone number already registered")
 		default:
-			common.ServerError(c, err)
+			if reasons, ok := password.Violations(err); ok {
+				common.PasswordRejected(c, reasons)
+			} else {
+				common.ServerError(c, err)
+			}
 		}
 		return
 	}
@@ -58,6 +68,8 @@ func (h *Handler) Register(c *gin.Context) {
 // @Param request body LoginRequest true "登录请求参数"
 // @Success 200 {object} common.Response{data=LoginCodeResponse} "验证码发送成功"
 // @Failure 400 {object} common.Response "参数错误或验证失败"
+// @Failure 428 {object} common.Response "请求过多，需要完成挑战后重试，见 POST /api/v1/auth/challenge"
+// @Failure 429 {object} common.Response "登录失败次数过多，响应头 Retry-After 为需要等待的秒数"
 // @Failure 500 {object} common.Response "服务器错误"
 // @Router /api/v1/auth/login [post]
 func (h *Handler) Login(c *gin.Context) {
@@ -73,12 +85,55 @@ func (h *Handler) Login(c *gin.Context) {
 	resp, err := h.service.Login(&req, clientIP, userAgent)
 	if err != nil {
 		switch err {
-		case common.ErrUserNotFound:
-			common.ValidationError(c, "User does not exist")
 		case common.ErrInvalidCredentials:
+			// 账户不存在与密码错误返回相同的错误，避免枚举账户
 			common.ValidationError(c, "Email or password is incorrect")
+		case common.ErrLoginLocked:
+			common.TooManyRequestsRetryAfter(c, "Too many failed sign-in attempts, please try again later", resp.RetryAfter)
 		case common.ErrUserInactive:
 			common.ValidationError(c, "Account not activated, please verify your email first")
+		case common.ErrCodeTooFrequent:
+			common.TooManyRequests(c, "Verification code requested too frequently, please try again later")
+		case common.ErrCodeBlocked:
+			common.TooManyRequests(c, "Too many failed verification attempts, please try again later")
+		case common.ErrLoginBlocked:
+			common.Forbidden(c, "Login blocked due to suspicious activity, please check your email")
+		case common.ErrSessionLimitReached:
+			common.Forbidden(c, "Maximum number of signed-in devices reached, please sign out on another device first")
+		case common.ErrLoginChallenge:
+			common.ForbiddenWithData(c, "Additional verification required, a verification code has been sent to your email or phone", resp.Login.Challenge)
+		default:
+			common.ServerError(c, err)
+		}
+		return
+	}
+
+	common.Success(c, resp)
+}
+
+// UnlockAccount 解除登录锁定
+// @Summary 解除登录锁定
+// @Description 使用账户锁定通知邮件中的一次性解锁链接解除锁定，链接有效期与锁定时长相同
+// @Tags 认证
+// @Accept json
+// @Produce json
+// @Param request body UnlockAccountRequest true "解锁令牌"
+// @Success 200 {object} common.Response{data=UnlockAccountResponse} "解锁成功"
+// @Failure 400 {object} common.Response "解锁链接无效或已过期"
+// @Failure 500 {object} common.Response "服务器错误"
+// @Router /api/v1/auth/unlock [post]
+func (h *Handler) UnlockAccount(c *gin.Context) {
+	var req dto.UnlockAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ValidationError(c, err.Error())
+		return
+	}
+
+	resp, err := h.service.UnlockAccount(&req)
+	if err != nil {
+		switch err {
+		case common.ErrUnlockTokenInvalid:
+			common.ValidationError(c, "Invalid or expired unlock link")
 		default:
 			common.ServerError(c, err)
 		}
@@ -116,6 +171,262 @@ func (h *Handler) LoginVerify(c *gin.Context) {
 			common.ValidationError(c, "Invalid credentials")
 		case common.ErrInvalidCode:
 			common.ValidationError(c, "Invalid or expired verification code")
+		case common.ErrCodeExpired:
+			common.ValidationError(c, "Verification code expired, please request a new one")
+		case common.ErrCodeBlocked:
+			common.TooManyRequests(c, "Too many failed verification attempts, please try again later")
+		case common.ErrLoginBlocked:
+			common.Forbidden(c, "Login blocked due to suspicious activity, please check your email")
+		case common.ErrSessionLimitReached:
+			common.Forbidden(c, "Maximum number of signed-in devices reached, please sign out on another device first")
+		case common.ErrLoginChallenge:
+			common.ForbiddenWithData(c, "Additional verification required, a verification code has been sent to your email or phone", resp.Challenge)
+		default:
+			common.ServerError(c, err)
+		}
+		return
+	}
+
+	common.Success(c, resp)
+}
+
+// RequestLoginCode 验证码登录第一步 - 发送邮箱验证码
+// @Summary 验证码登录第一步
+// @Description 向邮箱发送登录验证码，无需密码；邮箱未注册时验证通过后将自动创建账户
+// @Tags 认证
+// @Accept json
+// @Produce json
+// @Param request body CodeLoginRequest true "验证码登录请求参数"
+// @Success 200 {object} common.Response{data=LoginCodeResponse} "验证码发送成功"
+// @Failure 400 {object} common.Response "参数错误"
+// @Failure 428 {object} common.Response "请求过多，需要完成挑战后重试，见 POST /api/v1/auth/challenge"
+// @Failure 429 {object} common.Response "发送过于频繁"
+// @Failure 500 {object} common.Response "服务器错误"
+// @Router /api/v1/auth/login/code [post]
+func (h *Handler) RequestLoginCode(c *gin.Context) {
+	var req dto.CodeLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ValidationError(c, err.Error())
+		return
+	}
+
+	clientIP := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+
+	resp, err := h.service.RequestLoginCode(&req, clientIP, userAgent)
+	if err != nil {
+		switch err {
+		case common.ErrUserInactive:
+			common.ValidationError(c, "Account not activated")
+		case common.ErrUserSuspended:
+			common.Forbidden(c, "Account suspended")
+		case common.ErrCodeTooFrequent:
+			common.TooManyRequests(c, "Verification code requested too frequently, please try again later")
+		case common.ErrCodeBlocked:
+			common.TooManyRequests(c, "Too many failed verification attempts, please try again later")
+		default:
+			common.ServerError(c, err)
+		}
+		return
+	}
+
+	common.Success(c, resp)
+}
+
+// VerifyLoginCode 验证码登录第二步 - 验证邮箱验证码
+// @Summary 验证码登录第二步
+// @Description 验证邮箱验证码并返回访问令牌，账户不存在时自动注册并记录邀请码 referral_code；pending_steps 列出待完成的引导步骤
+// @Tags 认证
+// @Accept json
+// @Produce json
+// @Param request body CodeLoginVerifyRequest true "验证码登录验证请求参数"
+// @Success 200 {object} common.Response{data=LoginResponse} "登录成功"
+// @Failure 400 {object} common.Response "参数错误、验证失败或邀请码无效"
+// @Failure 500 {object} common.Response "服务器错误"
+// @Router /api/v1/auth/login/code/verify [post]
+func (h *Handler) VerifyLoginCode(c *gin.Context) {
+	var req dto.CodeLoginVerifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ValidationError(c, err.Error())
+		return
+	}
+
+	clientIP := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+
+	resp, err := h.service.VerifyLoginCode(&req, clientIP, userAgent)
+	if err != nil {
+		switch err {
+		case common.ErrUserInactive:
+			common.ValidationError(c, "Account not activated")
+		case common.ErrUserSuspended:
+			common.Forbidden(c, "Account suspended")
+		case common.ErrReferralCodeInvalid:
+			common.ValidationError(c, "Invalid referral code")
+		case common.ErrInvalidCode:
+			common.ValidationError(c, "Invalid or expired verification code")
+		case common.ErrCodeExpired:
+			common.ValidationError(c, "Verification code expired, please request a new one")
+		case common.ErrCodeBlocked:
+			common.TooManyRequests(c, "Too many failed verification attempts, please try again later")
+		case common.ErrLoginBlocked:
+			common.Forbidden(c, "Login blocked due to suspicious activity, please check your email")
+		case common.ErrSessionLimitReached:
+			common.Forbidden(c, "Maximum number of signed-in devices reached, please sign out on another device first")
+		case common.ErrLoginChallenge:
+			common.ForbiddenWithData(c, "Additional verification required, a verification code has been sent to your email or phone", resp.Challenge)
+		default:
+			common.ServerError(c, err)
+		}
+		return
+	}
+
+	common.Success(c, resp)
+}
+
+// LoginByPhone 手机号登录第一步 - 发送短信验证码
+// @Summary 手机号登录第一步
+// @Description 向已绑定并验证的手机号发送登录验证码
+// @Tags 认证
+// @Accept json
+// @Produce json
+// @Param request body PhoneLoginRequest true "手机号登录请求参数"
+// @Success 200 {object} common.Response{data=PhoneCodeResponse} "验证码发送成功"
+// @Failure 400 {object} common.Response "参数错误"
+// @Failure 428 {object} common.Response "请求过多，需要完成挑战后重试，见 POST /api/v1/auth/challenge"
+// @Failure 429 {object} common.Response "发送过于频繁"
+// @Failure 500 {object} common.Response "服务器错误"
+// @Router /api/v1/auth/login/phone [post]
+func (h *Handler) LoginByPhone(c *gin.Context) {
+	var req dto.PhoneLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ValidationError(c, err.Error())
+		return
+	}
+
+	clientIP := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+
+	resp, err := h.service.LoginByPhone(&req, clientIP, userAgent)
+	if err != nil {
+		switch err {
+		case common.ErrInvalidPhone:
+			common.ValidationError(c, "Invalid phone number")
+		case common.ErrUserInactive:
+			common.ValidationError(c, "Account not activated")
+		case common.ErrUserSuspended:
+			common.Forbidden(c, "Account suspended")
+		case common.ErrCodeTooFrequent:
+			common.TooManyRequests(c, "Verification code requested too frequently, please try again later")
+		case common.ErrSendLimitExceeded:
+			common.TooManyRequests(c, "Too many verification codes sent to this number, please try again later")
+		case common.ErrCodeBlocked:
+			common.TooManyRequests(c, "Too many failed verification attempts, please try again later")
+		default:
+			common.ServerError(c, err)
+		}
+		return
+	}
+
+	common.Success(c, resp)
+}
+
+// LoginByPhoneVerify 手机号登录第二步 - 验证短信验证码
+// @Summary 手机号登录第二步
+// @Description 验证短信验证码并返回访问令牌
+// @Tags 认证
+// @Accept json
+// @Produce json
+// @Param request body PhoneLoginVerifyRequest true "手机号登录验证请求参数"
+// @Success 200 {object} common.Response{data=LoginResponse} "登录成功"
+// @Failure 400 {object} common.Response "参数错误或验证失败"
+// @Failure 500 {object} common.Response "服务器错误"
+// @Router /api/v1/auth/login/phone/verify [post]
+func (h *Handler) LoginByPhoneVerify(c *gin.Context) {
+	var req dto.PhoneLoginVerifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ValidationError(c, err.Error())
+		return
+	}
+
+	clientIP := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+
+	resp, err := h.service.LoginByPhoneVerify(&req, clientIP, userAgent)
+	if err != nil {
+		switch err {
+		case common.ErrInvalidPhone:
+			common.ValidationError(c, "Invalid phone number")
+		case common.ErrUserInactive:
+			common.ValidationError(c, "Account not activated")
+		case common.ErrUserSuspended:
+			common.Forbidden(c, "Account suspended")
+		case common.ErrUserNotFound, common.ErrInvalidCode:
+			// 号码未注册与验证码错误返回相同的响应，避免枚举账户
+			common.ValidationError(c, "Invalid or expired verification code")
+		case common.ErrCodeExpired:
+			common.ValidationError(c, "Verification code expired, please request a new one")
+		case common.ErrCodeBlocked:
+			common.TooManyRequests(c, "Too many failed verification attempts, please try again later")
+		case common.ErrLoginBlocked:
+			common.Forbidden(c, "Login blocked due to suspicious activity, please check your email")
+		case common.ErrSessionLimitReached:
+			common.Forbidden(c, "Maximum number of signed-in devices reached, please sign out on another device first")
+		case common.ErrLoginChallenge:
+			common.ForbiddenWithData(c, "Additional verification required, a verification code has been sent to your email or phone", resp.Challenge)
+		default:
+			common.ServerError(c, err)
+		}
+		return
+	}
+
+	common.Success(c, resp)
+}
+
+// LoginChallengeVerify 风险验证 - 提交发送到账户邮箱或已验证手机号的验证码完成登录
+// @Summary 登录风险验证
+// @Description 登录被判定为可疑时返回403及challenge_id，并向本次登录没有用过的渠道发送验证码：邮箱登录发往已验证的手机号，手机号或受信任设备登录发往账户邮箱；没有可用渠道时拒绝登录。提交验证码后完成登录
+// @Tags 认证
+// @Accept json
+// @Produce json
+// @Param request body LoginChallengeVerifyRequest true "风险验证请求参数"
+// @Success 200 {object} common.Response{data=LoginResponse} "登录成功"
+// @Failure 400 {object} common.Response "参数错误或验证失败"
+// @Failure 401 {object} common.Response "验证标识无效或已过期"
+// @Failure 403 {object} common.Response "登录被拦截或同时登录的设备数量已达上限"
+// @Failure 500 {object} common.Response "服务器错误"
+// @Router /api/v1/auth/login/challenge/verify [post]
+func (h *Handler) LoginChallengeVerify(c *gin.Context) {
+	var req dto.LoginChallengeVerifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ValidationError(c, err.Error())
+		return
+	}
+
+	clientIP := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+
+	resp, err := h.service.LoginChallengeVerify(&req, clientIP, userAgent)
+	if err != nil {
+		switch err {
+		case common.ErrTokenInvalid, common.ErrTokenExpired:
+			common.Unauthorized(c, "Invalid or expired challenge, please sign in again")
+		case common.ErrUserNotFound:
+			common.ValidationError(c, "User not found")
+		case common.ErrUserInactive:
+			common.ValidationError(c, "Account not activated")
+		case common.ErrUserSuspended:
+			common.Forbidden(c, "Account suspended")
+		case common.ErrInvalidCode:
+			common.ValidationError(c, "Invalid or expired verification code")
+		case common.ErrCodeExpired:
+			common.ValidationError(c, "Verification code expired, please request a new one")
+		case common.ErrCodeBlocked:
+			common.TooManyRequests(c, "Too many failed verification attempts, please try again later")
+		case common.ErrLoginBlocked:
+			common.Forbidden(c, "Login blocked due to suspicious activity, please check your email")
+		case common.ErrSessionLimitReached:
+			common.Forbidden(c, "Maximum number of signed-in devices reached, please sign out on another device first")
 		default:
 			common.ServerError(c, err)
 		}
@@ -134,6 +445,7 @@ func (h *Handler) LoginVerify(c *gin.Context) {
 // @Param request body RefreshTokenRequest true "刷新令牌请求参数"
 // @Success 200 {object} common.Response{data=LoginResponse} "刷新成功"
 // @Failure 400 {object} common.Response "参数错误或令牌无效"
+// @Failure 401 {object} common.Response "刷新令牌无效，或会话因其他设备登录超过上限已被踢出"
 // @Failure 500 {object} common.Response "服务器错误"
 // @Router /api/v1/auth/refresh [post]
 func (h *Handler) RefreshToken(c *gin.Context) {
@@ -148,10 +460,14 @@ func (h *Handler) RefreshToken(c *gin.Context) {
 		switch err {
 		case common.ErrTokenInvalid, common.ErrRefreshTokenInvalid:
 			common.Unauthorized(c, "Invalid refresh token")
+		case common.ErrSessionEvicted:
+			common.Unauthorized(c, "Signed out because the account signed in on another device")
 		case common.ErrUserNotFound:
 			common.ValidationError(c, "User not found")
 		case common.ErrUserInactive:
 			common.ValidationError(c, "Account not activated")
+		case common.ErrUserSuspended:
+			common.Forbidden(c, "Account suspended")
 		default:
 			common.ServerError(c, err)
 		}
@@ -193,58 +509,128 @@ func (h *Handler) GetProfile(c *gin.Context) {
 	common.Success(c, user)
 }
 
-// ForgotPassword 忘记密码第一步 - 发送重置密码验证码
-// @Summary 忘记密码第一步
-// @Description 发送重置密码验证码到用户邮箱
+// SetPassword 设置密码
+// @Summary 设置密码
+// @Description 为验证码登录自动注册、尚未设置密码的账户设置密码
 // @Tags 认证
 // @Accept json
 // @Produce json
-// @Param request body ForgotPasswordRequest true "忘记密码请求参数"
-// @Success 200 {object} common.Response{data=ForgotPasswordResponse} "验证码发送成功"
-// @Failure 400 {object} common.Response "参数错误"
+// @Security ApiKeyAuth
+// @Param request body SetPasswordRequest true "设置密码请求参数"
+// @Success 200 {object} common.Response{data=OnboardingResponse} "设置成功"
+// @Failure 400 {object} common.Response "参数错误、密码已设置或密码不满足密码策略（password_rejected）"
+// @Failure 401 {object} common.Response "未授权"
 // @Failure 500 {object} common.Response "服务器错误"
-// @Router /api/v1/auth/forgot-password [post]
-func (h *Handler) ForgotPassword(c *gin.Context) {
-	var req dto.ForgotPasswordRequest
+// @Router /api/v1/auth/password/set [post]
+func (h *Handler) SetPassword(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		common.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	var req dto.SetPasswordRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		common.ValidationError(c, err.Error())
 		return
 	}
 
-	resp, err := h.service.ForgotPassword(&req)
+	resp, err := h.service.SetPassword(userID.(int64), &req)
 	if err != nil {
-		common.ServerError(c, err)
+		switch err {
+		case common.ErrUserNotFound:
+			common.NotFound(c, "User not found")
+		case common.ErrPasswordAlreadySet:
+			common.ValidationError(c, "Password already set, use change password instead")
+		default:
+			if reasons, ok := password.Violations(err); ok {
+				common.PasswordRejected(c, reasons)
+			} else {
+				common.ServerError(c, err)
+			}
+		}
 		return
 	}
 
 	common.Success(c, resp)
 }
 
-// ResetPassword 忘记密码第二步 - 验证验证码并重置密码
-// @Summary 忘记密码第二步
-// @Description 验证重置密码验证码并设置新密码
+// UpdateProfile 修改资料
+// @Summary 修改资料
+// @Description 修改姓名或头像，头像需为当前用户已上传的图片；手机号和邮箱需通过各自的验证流程变更
 // @Tags 认证
 // @Accept json
 // @Produce json
-// @Param request body ResetPasswordRequest true "重置密码请求参数"
-// @Success 200 {object} common.Response{data=ResetPasswordResponse} "密码重置成功"
-// @Failure 400 {object} common.Response "参数错误或验证失败"
+// @Security ApiKeyAuth
+// @Param request body UpdateProfileRequest true "修改资料请求参数"
+// @Success 200 {object} common.Response{data=ProfileResponse} "修改成功"
+// @Failure 400 {object} common.Response "参数错误"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 404 {object} common.Response "图片不存在"
 // @Failure 500 {object} common.Response "服务器错误"
-// @Router /api/v1/auth/reset-password [post]
-func (h *Handler) ResetPassword(c *gin.Context) {
-	var req dto.ResetPasswordRequest
+// @Router /api/v1/auth/profile [patch]
+func (h *Handler) UpdateProfile(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		common.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	var req dto.UpdateProfileRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		common.ValidationError(c, err.Error())
 		return
 	}
 
-	resp, err := h.service.ResetPassword(&req)
+	resp, err := h.service.UpdateProfile(c.Request.Context(), userID.(int64), &req)
 	if err != nil {
 		switch err {
 		case common.ErrUserNotFound:
-			common.ValidationError(c, "User not found")
-		case common.ErrInvalidCode:
-			common.ValidationError(c, "Invalid or expired verification code")
+			common.NotFound(c, "User not found")
+		case common.ErrValidation:
+			common.ValidationError(c, "Name cannot be empty")
+		case common.ErrNotFound:
+			common.NotFound(c, "Image not found")
+		default:
+			common.ServerError(c, err)
+		}
+		return
+	}
+
+	common.Success(c, resp)
+}
+
+// UploadAvatar 上传头像
+// @Summary 上传头像
+// @Description 上传图片作为头像，图片通过图片服务保存并记录在图片表中
+// @Tags 认证
+// @Accept multipart/form-data
+// @Produce json
+// @Security ApiKeyAuth
+// @Param file formData file true "头像图片"
+// @Success 200 {object} common.Response{data=ProfileResponse} "上传成功"
+// @Failure 400 {object} common.Response "参数错误"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 500 {object} common.Response "服务器错误"
+// @Router /api/v1/auth/profile/avatar [post]
+func (h *Handler) UploadAvatar(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		common.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		common.ValidationError(c, "No file provided")
+		return
+	}
+
+	resp, err := h.service.UploadAvatar(c.Request.Context(), userID.(int64), file)
+	if err != nil {
+		switch err {
+		case common.ErrUserNotFound:
+			common.NotFound(c, "User not found")
 		default:
 			common.ServerError(c, err)
 		}
@@ -254,3 +640,568 @@ func (h *Handler) ResetPassword(c *gin.Context) {
 	common.Success(c, resp)
 }
 
+// ChangePassword 修改密码
+// @Summary 修改密码
+// @Description 校验当前密码后设置新密码，其他设备的会话和受信任设备全部失效，返回当前设备的新令牌
+// @Tags 认证
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body ChangePasswordRequest true "修改密码请求参数"
+// @Success 200 {object} common.Response{data=ChangePasswordResponse} "修改成功"
+// @Failure 400 {object} common.Response "参数错误、当前密码错误或新密码不满足密码策略（password_rejected）"
+// @Failure 401 {object} common.Response "未授权或需要重新验证身份（reauth_required）"
+// @Failure 500 {object} common.Response "服务器错误"
+// @Router /api/v1/auth/password/change [post]
+func (h *Handler) ChangePassword(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		common.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	var req dto.ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ValidationError(c, err.Error())
+		return
+	}
+
+	resp, err := h.service.ChangePassword(userID.(int64), &req, c.GetHeader("User-Agent"))
+	if err != nil {
+		switch err {
+		case common.ErrUserNotFound:
+			common.NotFound(c, "User not found")
+		case common.ErrPasswordNotSet:
+			common.ValidationError(c, "Password not set, use set password instead")
+		case common.ErrInvalidCredentials:
+			common.ValidationError(c, "Current password is incorrect")
+		default:
+			if reasons, ok := password.Violations(err); ok {
+				common.PasswordRejected(c, reasons)
+			} else {
+				common.ServerError(c, err)
+			}
+		}
+		return
+	}
+
+	common.Success(c, resp)
+}
+
+// RequestEmailChange 修改邮箱第一步 - 向新邮箱发送验证码
+// @Summary 修改邮箱第一步
+// @Description 校验当前密码（已设置密码的账户）并向新邮箱发送验证码
+// @Tags 认证
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body ChangeEmailRequest true "修改邮箱请求参数"
+// @Success 200 {object} common.Response{data=ChangeEmailResponse} "验证码发送成功"
+// @Failure 400 {object} common.Response "参数错误、密码错误或邮箱已被使用"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 429 {object} common.Response "请求过于频繁"
+// @Failure 500 {object} common.Response "服务器错误"
+// @Router /api/v1/auth/email/change [post]
+func (h *Handler) RequestEmailChange(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		common.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	var req dto.ChangeEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ValidationError(c, err.Error())
+		return
+	}
+
+	resp, err := h.service.RequestEmailChange(userID.(int64), &req)
+	if err != nil {
+		switch err {
+		case common.ErrUserNotFound:
+			common.NotFound(c, "User not found")
+		case common.ErrInvalidCredentials:
+			common.ValidationError(c, "Current password is incorrect")
+		case common.ErrValidation:
+			common.ValidationError(c, "New email must be different from the current email")
+		case common.ErrEmailExists:
+			common.ValidationError(c, "Email already registered")
+		case common.ErrCodeTooFrequent:
+			common.TooManyRequests(c, "Verification code requested too frequently, please try again later")
+		case common.ErrCodeBlocked:
+			common.TooManyRequests(c, "Too many failed verification attempts, please try again later")
+		default:
+			common.ServerError(c, err)
+		}
+		return
+	}
+
+	common.Success(c, resp)
+}
+
+// VerifyEmailChange 修改邮箱第二步 - 验证新邮箱验证码
+// @Summary 修改邮箱第二步
+// @Description 验证新邮箱收到的验证码后切换账户邮箱，并通知原邮箱
+// @Tags 认证
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body ChangeEmailVerifyRequest true "验证新邮箱请求参数"
+// @Success 200 {object} common.Response{data=ProfileResponse} "修改成功"
+// @Failure 400 {object} common.Response "参数错误或验证失败"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 500 {object} common.Response "服务器错误"
+// @Router /api/v1/auth/email/change/verify [post]
+func (h *Handler) VerifyEmailChange(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		common.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	var req dto.ChangeEmailVerifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ValidationError(c, err.Error())
+		return
+	}
+
+	resp, err := h.service.VerifyEmailChange(userID.(int64), &req)
+	if err != nil {
+		switch err {
+		case common.ErrUserNotFound:
+			common.NotFound(c, "User not found")
+		case common.ErrInvalidCode:
+			common.ValidationError(c, "Invalid or expired verification code")
+		case common.ErrCodeExpired:
+			common.ValidationError(c, "Verification code expired, please request a new one")
+		case common.ErrCodeBlocked:
+			common.TooManyRequests(c, "Too many failed verification attempts, please try again later")
+		case common.ErrValidation:
+			common.ValidationError(c, "New email must be different from the current email")
+		case common.ErrEmailExists:
+			common.ValidationError(c, "Email already registered")
+		default:
+			common.ServerError(c, err)
+		}
+		return
+	}
+
+	common.Success(c, resp)
+}
+
+// CompleteProfile 完善资料
+// @Summary 完善资料
+// @Description 填写必要的个人资料并标记资料已完善
+// @Tags 认证
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body CompleteProfileRequest true "完善资料请求参数"
+// @Success 200 {object} common.Response{data=OnboardingResponse} "保存成功"
+// @Failure 400 {object} common.Response "参数错误"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 500 {object} common.Response "服务器错误"
+// @Router /api/v1/auth/profile/complete [post]
+func (h *Handler) CompleteProfile(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		common.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	var req dto.CompleteProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ValidationError(c, err.Error())
+		return
+	}
+
+	resp, err := h.service.CompleteProfile(userID.(int64), &req)
+	if err != nil {
+		switch err {
+		case common.ErrUserNotFound:
+			common.NotFound(c, "User not found")
+		case common.ErrValidation:
+			common.ValidationError(c, "Name is required")
+		default:
+			common.ServerError(c, err)
+		}
+		return
+	}
+
+	common.Success(c, resp)
+}
+
+// BindPhone 绑定手机号第一步 - 发送短信验证码
+// @Summary 绑定手机号
+// @Description 向待绑定的手机号发送验证码，号码会归一为E.164格式，需要提升令牌
+// @Tags 认证
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body BindPhoneRequest true "绑定手机号请求参数"
+// @Success 200 {object} common.Response{data=PhoneCodeResponse} "验证码发送成功"
+// @Failure 400 {object} common.Response "参数错误或号码已被使用"
+// @Failure 401 {object} common.Response "未授权或需要重新验证身份（reauth_required）"
+// @Failure 429 {object} common.Response "发送过于频繁"
+// @Failure 500 {object} common.Response "服务器错误"
+// @Router /api/v1/auth/phone/bind [post]
+func (h *Handler) BindPhone(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		common.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	var req dto.BindPhoneRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ValidationError(c, err.Error())
+		return
+	}
+
+	resp, err := h.service.BindPhone(userID.(int64), &req)
+	if err != nil {
+		switch err {
+		case common.ErrInvalidPhone:
+			common.ValidationError(c, "Invalid phone number")
+		case common.ErrPhoneExists:
+			common.ValidationError(c, "Phone number already in use")
+		case common.ErrCodeTooFrequent:
+			common.TooManyRequests(c, "Verification code requested too frequently, please try again later")
+		case common.ErrSendLimitExceeded:
+			common.TooManyRequests(c, "Too many verification codes sent to this number, please try again later")
+		case common.ErrCodeBlocked:
+			common.TooManyRequests(c, "Too many failed verification attempts, please try again later")
+		default:
+			common.ServerError(c, err)
+		}
+		return
+	}
+
+	common.Success(c, resp)
+}
+
+// VerifyPhone 绑定手机号第二步 - 验证短信验证码
+// @Summary 验证手机号
+// @Description 验证当前用户请求的短信验证码，成功后将号码绑定到当前用户并标记为已验证，需要提升令牌
+// @Tags 认证
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body VerifyPhoneRequest true "验证手机号请求参数"
+// @Success 200 {object} common.Response{data=VerifyPhoneResponse} "绑定成功"
+// @Failure 400 {object} common.Response "参数错误或验证失败"
+// @Failure 401 {object} common.Response "未授权或需要重新验证身份（reauth_required）"
+// @Failure 500 {object} common.Response "服务器错误"
+// @Router /api/v1/auth/phone/verify [post]
+func (h *Handler) VerifyPhone(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		common.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	var req dto.VerifyPhoneRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ValidationError(c, err.Error())
+		return
+	}
+
+	resp, err := h.service.VerifyPhone(userID.(int64), &req)
+	if err != nil {
+		switch err {
+		case common.ErrInvalidPhone:
+			common.ValidationError(c, "Invalid phone number")
+		case common.ErrPhoneExists:
+			common.ValidationError(c, "Phone number already in use")
+		case common.ErrInvalidCode:
+			common.ValidationError(c, "Invalid or expired verification code")
+		case common.ErrCodeExpired:
+			common.ValidationError(c, "Verification code expired, please request a new one")
+		case common.ErrCodeBlocked:
+			common.TooManyRequests(c, "Too many failed verification attempts, please try again later")
+		default:
+			common.ServerError(c, err)
+		}
+		return
+	}
+
+	common.Success(c, resp)
+}
+
+// ForgotPassword 忘记密码第一步 - 发送重置密码验证码
+// @Summary 忘记密码第一步
+// @Description 发送重置密码验证码到用户邮箱
+// @Tags 认证
+// @Accept json
+// @Produce json
+// @Param request body ForgotPasswordRequest true "忘记密码请求参数"
+// @Success 200 {object} common.Response{data=ForgotPasswordResponse} "验证码发送成功"
+// @Failure 400 {object} common.Response "参数错误"
+// @Failure 428 {object} common.Response "请求过多，需要完成挑战后重试，见 POST /api/v1/auth/challenge"
+// @Failure 500 {object} common.Response "服务器错误"
+// @Router /api/v1/auth/forgot-password [post]
+func (h *Handler) ForgotPassword(c *gin.Context) {
+	var req dto.ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ValidationError(c, err.Error())
+		return
+	}
+
+	resp, err := h.service.ForgotPassword(&req)
+	if err != nil {
+		switch err {
+		case common.ErrCodeTooFrequent:
+			common.TooManyRequests(c, "Verification code requested too frequently, please try again later")
+		case common.ErrCodeBlocked:
+			common.TooManyRequests(c, "Too many failed verification attempts, please try again later")
+		default:
+			common.ServerError(c, err)
+		}
+		return
+	}
+
+	common.Success(c, resp)
+}
+
+// ResetPassword 忘记密码第二步 - 验证验证码并重置密码
+// @Summary 忘记密码第二步
+// @Description 验证重置密码验证码并设置新密码
+// @Tags 认证
+// @Accept json
+// @Produce json
+// @Param request body ResetPasswordRequest true "重置密码请求参数"
+// @Success 200 {object} common.Response{data=ResetPasswordResponse} "密码重置成功"
+// @Failure 400 {object} common.Response "参数错误、验证失败或新密码不满足密码策略（password_rejected）"
+// @Failure 500 {object} common.Response "服务器错误"
+// @Router /api/v1/auth/reset-password [post]
+func (h *Handler) ResetPassword(c *gin.Context) {
+	var req dto.ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ValidationError(c, err.Error())
+		return
+	}
+
+	resp, err := h.service.ResetPassword(&req)
+	if err != nil {
+		switch err {
+		case common.ErrUserNotFound:
+			common.ValidationError(c, "User not found")
+		case common.ErrInvalidCode:
+			common.ValidationError(c, "Invalid or expired verification code")
+		case common.ErrCodeExpired:
+			common.ValidationError(c, "Verification code expired, please request a new one")
+		case common.ErrCodeBlocked:
+			common.TooManyRequests(c, "Too many failed verification attempts, please try again later")
+		default:
+			if reasons, ok := password.Violations(err); ok {
+				common.PasswordRejected(c, reasons)
+			} else {
+				common.ServerError(c, err)
+			}
+		}
+		return
+	}
+
+	common.Success(c, resp)
+}
+
+// ListLoginSessions 获取登录记录
+// @Summary 获取登录记录
+// @Description 获取当前用户最近的成功登录记录，管理员代登录的记录包含 impersonator_id
+// @Tags 认证
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} common.Response{data=[]entities.LoginSession} "获取成功"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 500 {object} common.Response "服务器错误"
+// @Router /api/v1/auth/sessions [get]
+func (h *Handler) ListLoginSessions(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		common.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	sessions, err := h.service.ListLoginSessions(userID.(int64))
+	if err != nil {
+		common.ServerError(c, err)
+		return
+	}
+
+	common.Success(c, sessions)
+}
+
+// ListTrustedDevices 获取受信任设备列表
+// @Summary 获取受信任设备列表
+// @Description 获取当前用户有效的受信任设备，这些设备登录时无需输入邮箱验证码
+// @Tags 认证
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} common.Response{data=[]entities.TrustedDevice} "获取成功"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 500 {object} common.Response "服务器错误"
+// @Router /api/v1/auth/devices [get]
+func (h *Handler) ListTrustedDevices(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		common.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	devices, err := h.service.ListTrustedDevices(userID.(int64))
+	if err != nil {
+		common.ServerError(c, err)
+		return
+	}
+
+	common.Success(c, devices)
+}
+
+// RevokeTrustedDevice 撤销受信任设备
+// @Summary 撤销受信任设备
+// @Description 撤销后该设备下次登录需要重新输入邮箱验证码
+// @Tags 认证
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "设备记录ID"
+// @Success 200 {object} common.Response "撤销成功"
+// @Failure 400 {object} common.Response "参数错误"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 404 {object} common.Response "设备不存在"
+// @Failure 500 {object} common.Response "服务器错误"
+// @Router /api/v1/auth/devices/{id} [delete]
+func (h *Handler) RevokeTrustedDevice(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		common.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	deviceID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		common.ValidationError(c, "Invalid device ID")
+		return
+	}
+
+	if err := h.service.RevokeTrustedDevice(userID.(int64), deviceID); err != nil {
+		switch err {
+		case common.ErrNotFound:
+			common.NotFound(c, "Trusted device not found")
+		default:
+			common.ServerError(c, err)
+		}
+		return
+	}
+
+	common.SuccessWithMessage(c, "Trusted device revoked", nil)
+}
+
+// SendReauthCode 重新验证身份 - 发送邮箱验证码
+// @Summary 发送重新验证身份的验证码
+// @Description 向账户邮箱发送验证码，用于通过验证码重新验证身份
+// @Tags 认证
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body ReauthCodeRequest false "重新验证身份验证码请求参数"
+// @Success 200 {object} common.Response{data=ReauthCodeResponse} "验证码发送成功"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "账户已暂停或代登录令牌"
+// @Failure 429 {object} common.Response "请求过于频繁"
+// @Failure 500 {object} common.Response "服务器错误"
+// @Router /api/v1/auth/reauth/code [post]
+func (h *Handler) SendReauthCode(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		common.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	var req dto.ReauthCodeRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			common.ValidationError(c, err.Error())
+			return
+		}
+	}
+
+	resp, err := h.service.SendReauthCode(userID.(int64), &req)
+	if err != nil {
+		handleReauthError(c, err)
+		return
+	}
+
+	common.Success(c, resp)
+}
+
+// Reauthenticate 重新验证身份
+// @Summary 重新验证身份
+// @Description 校验当前密码或账户邮箱验证码（二选一），签发携带 auth_time 和 acr 的短期提升令牌；修改密码等敏感接口返回 401 和 reauth_required 时使用此令牌重试
+// @Tags 认证
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body ReauthRequest true "当前密码或验证码"
+// @Success 200 {object} common.Response{data=ElevatedTokenResponse} "验证成功"
+// @Failure 400 {object} common.Response "参数错误、密码错误或验证码错误"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "账户已暂停或代登录令牌"
+// @Failure 429 {object} common.Response "验证失败次数过多，密码错误次数过多时与登录共用锁定"
+// @Failure 500 {object} common.Response "服务器错误"
+// @Router /api/v1/auth/reauth [post]
+func (h *Handler) Reauthenticate(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		common.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	var req dto.ReauthRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ValidationError(c, err.Error())
+		return
+	}
+
+	resp, err := h.service.Reauthenticate(userID.(int64), &req, c.ClientIP())
+	if err != nil {
+		if err == common.ErrLoginLocked {
+			common.TooManyRequestsRetryAfter(c, "Too many failed sign-in attempts, please try again later", resp.RetryAfter)
+			return
+		}
+		handleReauthError(c, err)
+		return
+	}
+
+	common.Success(c, resp)
+}
+
+// handleReauthError 重新验证身份的通用错误响应
+func handleReauthError(c *gin.Context, err error) {
+	switch err {
+	case common.ErrValidation:
+		common.ValidationError(c, "Provide either password or code")
+	case common.ErrUserNotFound:
+		common.NotFound(c, "User not found")
+	case common.ErrUserInactive:
+		common.ValidationError(c, "Account not activated")
+	case common.ErrUserSuspended:
+		common.Forbidden(c, "Account suspended")
+	case common.ErrPasswordNotSet:
+		common.ValidationError(c, "Password not set, use email code instead")
+	case common.ErrInvalidCredentials:
+		common.ValidationError(c, "Current password is incorrect")
+	case common.ErrInvalidCode:
+		common.ValidationError(c, "Invalid or expired verification code")
+	case common.ErrCodeExpired:
+		common.ValidationError(c, "Verification code expired, please request a new one")
+	case common.ErrCodeTooFrequent:
+		common.TooManyRequests(c, "Verification code requested too frequently, please try again later")
+	case common.ErrCodeBlocked:
+		common.TooManyRequests(c, "Too many failed verification attempts, please try again later")
+	default:
+		common.ServerError(c, err)
+	}
+}
@@ -0,0 +1,162 @@
+package user_auth
+
+import (
+	"trusioo_api/internal/auth/identity"
+	"trusioo_api/internal/auth/user_auth/entities"
+)
+
+// identityCore 用户的认证核心，令牌、会话上限、IP定位和重置密码与管理员共用同一实现
+// 没有通过 NewService 创建的 Service 按当前依赖临时创建
+func (s *Service) identityCore() *identity.Core {
+	if s.identity != nil {
+		return s.identity
+	}
+	return s.newIdentityCore()
+}
+
+func (s *Service) newIdentityCore() *identity.Core {
+	return identity.New(identity.PrincipalUser, identityStore{repo: s.repo}, s.verificationService, s.ipinfoClient, identity.Options{
+		SessionLimit:    s.sessionLimiter,
+		LoginGuard:      s.loginGuard,
+		RiskEvaluator:   s.riskEvaluator,
+		RiskHistorySize: s.riskHistorySize,
+		PasswordPolicy:  s.passwordPolicy,
+	})
+}
+
+// identityAccount 签发令牌所需的账户信息
+func identityAccount(user *entities.User) identity.Account {
+	return identity.Account{ID: user.ID, Email: user.Email, Role: user.Role}
+}
+
+// identityStore 将用户仓储适配为 identity.Store
+type identityStore struct {
+	repo Repository
+}
+
+func (s identityStore) CreateRefreshToken(token *identity.RefreshToken) error {
+	return s.repo.CreateRefreshToken(&entities.RefreshToken{
+		UserID:     token.AccountID,
+		Token:      token.Token,
+		IsValid:    true,
+		ExpiresAt:  token.ExpiresAt,
+		DeviceInfo: token.DeviceInfo,
+		CreatedAt:  token.CreatedAt,
+	})
+}
+
+func (s identityStore) GetValidRefreshToken(token string) (*identity.RefreshToken, error) {
+	refreshToken, err := s.repo.GetValidRefreshToken(token)
+	if err != nil {
+		return nil, err
+	}
+	return toIdentityRefreshToken(refreshToken), nil
+}
+
+func (s identityStore) ListActiveRefreshTokens(userID int64) ([]*identity.RefreshToken, error) {
+	tokens, err := s.repo.ListActiveRefreshTokens(userID)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*identity.RefreshToken, len(tokens))
+	for i, token := range tokens {
+		result[i] = toIdentityRefreshToken(token)
+	}
+	return result, nil
+}
+
+func (s identityStore) EvictRefreshTokens(userID int64, ids []int64) error {
+	return s.repo.EvictRefreshTokens(userID, ids)
+}
+
+func (s identityStore) IsRefreshTokenEvicted(token string) (bool, error) {
+	return s.repo.IsRefreshTokenEvicted(token)
+}
+
+func (s identityStore) InvalidateAllRefreshTokens(userID int64) error {
+	return s.repo.InvalidateAllRefreshTokens(userID)
+}
+
+func (s identityStore) UpdatePassword(userID int64, passwordHash string) error {
+	return s.repo.UpdatePassword(userID, passwordHash)
+}
+
+func (s identityStore) ListPasswordHistory(userID int64, limit int) ([]string, error) {
+	return s.repo.ListPasswordHistory(userID, limit)
+}
+
+func (s identityStore) AddPasswordHistory(userID int64, passwordHash string, keep int) error {
+	return s.repo.AddPasswordHistory(userID, passwordHash, keep)
+}
+
+func (s identityStore) CreateLoginSession(session *identity.LoginSession) error {
+	return s.repo.CreateLoginSession(&entities.LoginSession{
+		UserID:       session.AccountID,
+		IP:           session.IP,
+		Country:      session.Country,
+		City:         session.City,
+		Region:       session.Region,
+		Timezone:     session.Timezone,
+		Organization: session.Organization,
+		Location:     session.Coordinates,
+		UserAgent:    session.UserAgent,
+		DeviceType:   session.DeviceType,
+		OS:           session.OS,
+		Browser:      session.Browser,
+		Platform:     session.Platform,
+		LoginMethod:  session.Method,
+		Status:       session.Status,
+		Reason:       session.Reason,
+		IsTrusted:    session.IsTrusted,
+		RiskScore:    session.RiskScore,
+		RiskDecision: session.RiskDecision,
+		RiskReasons:  session.RiskReasons,
+		CreatedAt:    session.CreatedAt,
+	})
+}
+
+func (s identityStore) GetRecentLoginSessions(userID int64, limit int) ([]*identity.LoginSession, error) {
+	sessions, err := s.repo.GetRecentLoginSessions(userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*identity.LoginSession, len(sessions))
+	for i, session := range sessions {
+		result[i] = &identity.LoginSession{
+			AccountID: session.UserID,
+			IP:        session.IP,
+			Location: identity.Location{
+				Country:      session.Country,
+				City:         session.City,
+				Region:       session.Region,
+				Timezone:     session.Timezone,
+				Organization: session.Organization,
+				Coordinates:  session.Location,
+			},
+			UserAgent: session.UserAgent,
+			Device: identity.Device{
+				DeviceType: session.DeviceType,
+				OS:         session.OS,
+				Browser:    session.Browser,
+				Platform:   session.Platform,
+			},
+			Method:    session.LoginMethod,
+			Status:    session.Status,
+			Reason:    session.Reason,
+			IsTrusted: session.IsTrusted,
+			CreatedAt: session.CreatedAt,
+		}
+	}
+	return result, nil
+}
+
+func toIdentityRefreshToken(token *entities.RefreshToken) *identity.RefreshToken {
+	return &identity.RefreshToken{
+		ID:         token.ID,
+		AccountID:  token.UserID,
+		Token:      token.Token,
+		ExpiresAt:  token.ExpiresAt,
+		DeviceInfo: token.DeviceInfo,
+		CreatedAt:  token.CreatedAt,
+	}
+}
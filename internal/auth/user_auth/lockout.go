@@ -0,0 +1,90 @@
+package user_auth
+
+import (
+	"context"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"trusioo_api/config"
+	"trusioo_api/internal/auth/identity"
+	"trusioo_api/internal/auth/user_auth/dto"
+	"trusioo_api/internal/auth/user_auth/entities"
+	"trusioo_api/internal/common"
+	"trusioo_api/pkg/mailer"
+)
+
+// LoginGuard 密码登录失败的退避与锁定，以及锁定邮件中的解锁链接，由 lockout.Guard 实现
+type LoginGuard interface {
+	identity.LoginGuard
+	IssueUnlockToken(ctx context.Context, account string) (string, error)
+	Unlock(ctx context.Context, token string) (string, error)
+	LockDuration() time.Duration
+}
+
+// loginRetryAfter 返回账户或IP需要等待的时间，Redis 不可用时不阻止登录
+func (s *Service) loginRetryAfter(email, clientIP string) time.Duration {
+	return s.identityCore().LoginRetryAfter(email, clientIP)
+}
+
+// recordLoginFailure 记录一次密码错误，账户因此被锁定时向账户邮箱发送解锁链接
+// user 为空表示账户不存在，同样计数但不发送邮件
+func (s *Service) recordLoginFailure(user *entities.User, email, clientIP string) {
+	if !s.identityCore().RecordLoginFailure(email, clientIP) || user == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	s.sendAccountLockedEmail(ctx, user, clientIP)
+}
+
+// resetLoginFailures 密码验证通过后清除账户的失败次数
+func (s *Service) resetLoginFailures(email string) {
+	s.identityCore().ResetLoginFailures(email)
+}
+
+// sendAccountLockedEmail 通知用户账户已被锁定，附带一次性解锁链接
+func (s *Service) sendAccountLockedEmail(ctx context.Context, user *entities.User, clientIP string) {
+	if s.mailOutbox == nil {
+		return
+	}
+
+	token, err := s.loginGuard.IssueUnlockToken(ctx, user.Email)
+	if err != nil {
+		log.Printf("生成解锁令牌失败 %d: %v", user.ID, err)
+		return
+	}
+	link := strings.TrimRight(config.AppConfig.Frontend.AppURL, "/") + "/unlock?token=" + url.QueryEscape(token)
+	msg, err := mailer.Render(user.Email, mailer.TemplateAccountLocked, "", s.mailLocale, mailer.TemplateData{
+		Email:            user.Email,
+		IP:               clientIP,
+		Time:             time.Now().UTC().Format("2006-01-02 15:04:05 UTC"),
+		Link:             link,
+		ExpiresInMinutes: int(s.loginGuard.LockDuration() / time.Minute),
+	})
+	if err != nil {
+		log.Printf("渲染账户锁定通知失败: %v", err)
+		return
+	}
+	if err := s.mailOutbox.Enqueue(ctx, msg); err != nil {
+		log.Printf("发送账户锁定通知失败 %d: %v", user.ID, err)
+	}
+}
+
+// UnlockAccount 使用锁定邮件中的解锁链接解除账户锁定
+func (s *Service) UnlockAccount(req *dto.UnlockAccountRequest) (*dto.UnlockAccountResponse, error) {
+	if s.loginGuard == nil {
+		return nil, common.ErrUnlockTokenInvalid
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := s.loginGuard.Unlock(ctx, req.Token); err != nil {
+		return nil, err
+	}
+
+	return &dto.UnlockAccountResponse{
+		Message: "账户已解锁，请重新登录",
+	}, nil
+}
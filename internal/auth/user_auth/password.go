@@ -0,0 +1,22 @@
+package user_auth
+
+import (
+	"trusioo_api/internal/auth/user_auth/entities"
+)
+
+// validatePassword 按密码策略校验新密码，user 为空表示注册，此时不比较历史密码
+func (s *Service) validatePassword(user *entities.User, email, plain string) error {
+	if user == nil {
+		return s.identityCore().ValidatePassword(0, "", email, plain)
+	}
+	currentHash := ""
+	if user.PasswordSet {
+		currentHash = user.Password
+	}
+	return s.identityCore().ValidatePassword(user.ID, currentHash, email, plain)
+}
+
+// recordPasswordHistory 保存新密码哈希，失败时只记录日志
+func (s *Service) recordPasswordHistory(userID int64, passwordHash string) {
+	s.identityCore().RecordPasswordHistory(userID, passwordHash)
+}
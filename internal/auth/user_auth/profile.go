@@ -0,0 +1,295 @@
+package user_auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"mime/multipart"
+	"strings"
+	"time"
+
+	"trusioo_api/config"
+	"trusioo_api/internal/auth/user_auth/dto"
+	"trusioo_api/internal/auth/user_auth/entities"
+	"trusioo_api/internal/auth/verification"
+	verificationDto "trusioo_api/internal/auth/verification/dto"
+	"trusioo_api/internal/common"
+	imagesDto "trusioo_api/internal/images/dto"
+	"trusioo_api/pkg/mailer"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// avatarFolder 头像在存储桶中的目录
+const avatarFolder = "avatars"
+
+// UpdateProfile 修改姓名或头像，头像必须是当前用户已上传的图片
+func (s *Service) UpdateProfile(ctx context.Context, userID int64, req *dto.UpdateProfileRequest) (*dto.ProfileResponse, error) {
+	user, err := s.getUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" {
+			return nil, common.ErrValidation
+		}
+		user.Name = name
+		user.ProfileCompleted = true
+	}
+
+	if req.ImageID != nil {
+		if s.imageService == nil {
+			return nil, common.ErrNotFound
+		}
+		image, err := s.imageService.GetUserImage(ctx, int(userID), *req.ImageID)
+		if err != nil {
+			if errors.Is(err, common.ErrNotFound) {
+				return nil, common.ErrNotFound
+			}
+			return nil, err
+		}
+		user.ImageKey = image.Key
+	}
+
+	if err := s.repo.Update(user); err != nil {
+		return nil, err
+	}
+
+	return &dto.ProfileResponse{
+		Message: "资料已更新",
+		User:    user,
+	}, nil
+}
+
+// UploadAvatar 通过图片服务上传公开头像并设置为当前头像
+func (s *Service) UploadAvatar(ctx context.Context, userID int64, file *multipart.FileHeader) (*dto.ProfileResponse, error) {
+	if s.imageService == nil {
+		return nil, common.ErrNotFound
+	}
+
+	user, err := s.getUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	ownerID := int(userID)
+	image, err := s.imageService.UploadImage(ctx, &ownerID, file, imagesDto.UploadImageRequest{
+		IsPublic: true,
+		Folder:   avatarFolder,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	user.ImageKey = image.Key
+	if err := s.repo.Update(user); err != nil {
+		return nil, err
+	}
+
+	return &dto.ProfileResponse{
+		Message: "头像已更新",
+		User:    user,
+	}, nil
+}
+
+// ChangePassword 校验当前密码后修改密码，使其他会话的刷新令牌和受信任设备失效，并为当前设备签发新令牌
+func (s *Service) ChangePassword(userID int64, req *dto.ChangePasswordRequest, userAgent string) (*dto.ChangePasswordResponse, error) {
+	user, err := s.getUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.PasswordSet {
+		return nil, common.ErrPasswordNotSet
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.CurrentPassword)); err != nil {
+		return nil, common.ErrInvalidCredentials
+	}
+	if err := s.validatePassword(user, user.Email, req.NewPassword); err != nil {
+		return nil, err
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdatePassword(user.ID, string(hashedPassword)); err != nil {
+		return nil, err
+	}
+	s.recordPasswordHistory(user.ID, string(hashedPassword))
+
+	// 其他会话失效是修改密码的一部分，失败时返回错误让用户重试
+	if err := s.repo.InvalidateAllRefreshTokens(user.ID); err != nil {
+		return nil, err
+	}
+	if err := s.repo.RevokeAllTrustedDevices(user.ID); err != nil {
+		log.Printf("Failed to revoke trusted devices for user %d: %v", user.ID, err)
+	}
+
+	accessToken, refreshToken, err := s.issueTokenPair(user, userAgent)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.ChangePasswordResponse{
+		Message:      "密码修改成功，其他设备已退出登录",
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(config.AppConfig.JWT.AccessExpire),
+	}, nil
+}
+
+// RequestEmailChange 修改邮箱第一步 - 校验密码并向新邮箱发送验证码
+func (s *Service) RequestEmailChange(userID int64, req *dto.ChangeEmailRequest) (*dto.ChangeEmailResponse, error) {
+	user, err := s.getUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 验证码登录自动注册、尚未设置密码的账户只能依赖登录态
+	if user.PasswordSet {
+		if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
+			return nil, common.ErrInvalidCredentials
+		}
+	}
+
+	if err := s.ensureEmailAvailable(user, req.NewEmail); err != nil {
+		return nil, err
+	}
+
+	// 验证码只对发起修改、通过密码校验的用户有效
+	sendReq := &verificationDto.SendVerificationRequest{
+		Target: req.NewEmail,
+		Type:   req.VerificationType(),
+		Locale: req.Locale,
+		UserID: user.ID,
+	}
+	if _, err := s.verificationService.SendVerificationCode(sendReq); err != nil {
+		return nil, err
+	}
+
+	return &dto.ChangeEmailResponse{
+		Message:   "验证码已发送到新邮箱",
+		NewEmail:  req.NewEmail,
+		ExpiresIn: verification.CodeExpiresIn(),
+	}, nil
+}
+
+// VerifyEmailChange 修改邮箱第二步 - 验证新邮箱验证码后切换邮箱，并通知原邮箱
+// 验证码与发起修改的用户绑定，其他账户请求的验证码无法使用
+func (s *Service) VerifyEmailChange(userID int64, req *dto.ChangeEmailVerifyRequest) (*dto.ProfileResponse, error) {
+	user, err := s.getUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	verifyReq := &verificationDto.VerifyCodeRequest{
+		Target: req.NewEmail,
+		Code:   req.Code,
+		Type:   req.VerificationType(),
+		UserID: user.ID,
+	}
+	verifyResp, err := s.verificationService.VerifyCode(verifyReq)
+	if err != nil {
+		return nil, err
+	}
+	if !verifyResp.Valid {
+		return nil, common.ErrInvalidCode
+	}
+
+	// 发送验证码后邮箱可能已被其他账户注册
+	if err := s.ensureEmailAvailable(user, req.NewEmail); err != nil {
+		return nil, err
+	}
+
+	oldEmail := user.Email
+	user.Email = req.NewEmail
+	user.EmailVerified = true
+	if err := s.repo.Update(user); err != nil {
+		return nil, err
+	}
+
+	s.sendEmailChangedNotice(user.ID, oldEmail, user.Email)
+
+	return &dto.ProfileResponse{
+		Message: "邮箱已更新",
+		User:    user,
+	}, nil
+}
+
+// getUser 按ID获取用户，不存在时返回 ErrUserNotFound
+func (s *Service) getUser(userID int64) (*entities.User, error) {
+	user, err := s.repo.GetByID(userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, common.ErrUserNotFound
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
+// userStatusError 账户状态不允许登录时返回对应错误，临时暂停到期后视为已恢复
+func userStatusError(user *entities.User) error {
+	switch user.Status {
+	case "active":
+		return nil
+	case "suspended":
+		if user.SuspendedUntil != nil && !user.SuspendedUntil.After(time.Now()) {
+			return nil
+		}
+		return common.ErrUserSuspended
+	default:
+		return common.ErrUserInactive
+	}
+}
+
+// statusFailureReason 登录记录中的失败原因
+func statusFailureReason(err error) string {
+	if err == common.ErrUserSuspended {
+		return "账户已暂停"
+	}
+	return "账户未激活"
+}
+
+// ensureEmailAvailable 新邮箱必须与当前邮箱不同且未被其他账户使用
+func (s *Service) ensureEmailAvailable(user *entities.User, email string) error {
+	if strings.EqualFold(user.Email, email) {
+		return common.ErrValidation
+	}
+
+	_, err := s.repo.GetByEmail(email)
+	if err == nil {
+		return common.ErrEmailExists
+	}
+	if err != sql.ErrNoRows {
+		return err
+	}
+	return nil
+}
+
+// sendEmailChangedNotice 通知原邮箱账户邮箱已变更
+func (s *Service) sendEmailChangedNotice(userID int64, oldEmail, newEmail string) {
+	if s.mailOutbox == nil {
+		return
+	}
+
+	msg, err := mailer.Render(oldEmail, mailer.TemplateEmailChanged, "", s.mailLocale, mailer.TemplateData{
+		Email:    oldEmail,
+		NewEmail: newEmail,
+		Time:     time.Now().UTC().Format("2006-01-02 15:04:05 UTC"),
+	})
+	if err != nil {
+		log.Printf("渲染邮箱变更通知失败: %v", err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.mailOutbox.Enqueue(ctx, msg); err != nil {
+		log.Printf("发送邮箱变更通知失败 %d: %v", userID, err)
+	}
+}
@@ -0,0 +1,99 @@
+package user_auth
+
+import (
+	"time"
+
+	"trusioo_api/internal/auth/identity"
+	"trusioo_api/internal/auth/user_auth/dto"
+	"trusioo_api/internal/auth/verification"
+	verificationDto "trusioo_api/internal/auth/verification/dto"
+	"trusioo_api/internal/common"
+	"trusioo_api/pkg/auth"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// SendReauthCode 向账户邮箱发送重新验证身份的验证码
+func (s *Service) SendReauthCode(userID int64, req *dto.ReauthCodeRequest) (*dto.ReauthCodeResponse, error) {
+	user, err := s.getUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := userStatusError(user); err != nil {
+		return nil, err
+	}
+
+	sendReq := &verificationDto.SendVerificationRequest{
+		Target: user.Email,
+		Type:   req.VerificationType(),
+		Locale: req.Locale,
+	}
+	if _, err := s.verificationService.SendVerificationCode(sendReq); err != nil {
+		return nil, err
+	}
+
+	return &dto.ReauthCodeResponse{
+		Message:   "验证码已发送到账户邮箱",
+		ExpiresIn: verification.CodeExpiresIn(),
+	}, nil
+}
+
+// Reauthenticate 校验当前密码或账户邮箱验证码，签发携带验证时间的短期提升令牌
+// 密码错误与密码登录共用失败计数和锁定，锁定期间返回 common.ErrLoginLocked 和需要等待的秒数
+func (s *Service) Reauthenticate(userID int64, req *dto.ReauthRequest, clientIP string) (*dto.ElevatedTokenResponse, error) {
+	if (req.Password == "") == (req.Code == "") {
+		return nil, common.ErrValidation
+	}
+
+	user, err := s.getUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := userStatusError(user); err != nil {
+		return nil, err
+	}
+
+	acr := auth.ACRPassword
+	if req.Password != "" {
+		if !user.PasswordSet {
+			return nil, common.ErrPasswordNotSet
+		}
+		if wait := s.loginRetryAfter(user.Email, clientIP); wait > 0 {
+			return &dto.ElevatedTokenResponse{RetryAfter: identity.RetryAfterSeconds(wait)}, common.ErrLoginLocked
+		}
+		if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
+			s.recordLoginFailure(user, user.Email, clientIP)
+			return nil, common.ErrInvalidCredentials
+		}
+		s.resetLoginFailures(user.Email)
+	} else {
+		verifyReq := &verificationDto.VerifyCodeRequest{
+			Target: user.Email,
+			Code:   req.Code,
+			Type:   req.VerificationType(),
+		}
+		verifyResp, err := s.verificationService.VerifyCode(verifyReq)
+		if err != nil {
+			return nil, err
+		}
+		if !verifyResp.Valid {
+			return nil, common.ErrInvalidCode
+		}
+		acr = auth.ACREmailCode
+	}
+
+	authTime := time.Now()
+	ttl := auth.ElevatedTokenTTL()
+	token, err := auth.GenerateElevatedToken(user.ID, user.Email, user.Role, "user", acr, authTime, ttl)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.ElevatedTokenResponse{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(ttl / time.Second),
+		AuthTime:    authTime.Unix(),
+		ACR:         acr,
+	}, nil
+}
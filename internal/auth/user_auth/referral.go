@@ -0,0 +1,51 @@
+package user_auth
+
+import (
+	"context"
+	"log"
+
+	"trusioo_api/internal/referral"
+	referralEntities "trusioo_api/internal/referral/entities"
+)
+
+// ReferralService 注册时记录邀请关系，完成发放奖励的操作时发放邀请奖励，由 referral.Service 实现
+type ReferralService interface {
+	Validate(ctx context.Context, code string) error
+	Attach(ctx context.Context, signup *referral.Signup) (*referralEntities.Referral, error)
+	Qualify(ctx context.Context, userID int64, action string) error
+}
+
+// validateReferralCode 校验注册时填写的邀请码，未填写或未启用邀请时不校验
+func (s *Service) validateReferralCode(code string) error {
+	if s.referrals == nil || code == "" {
+		return nil
+	}
+	return s.referrals.Validate(context.Background(), code)
+}
+
+// attachReferral 记录新用户的邀请关系，失败时只记录日志，不影响注册
+func (s *Service) attachReferral(userID int64, code, clientIP, deviceID, userAgent string) {
+	if s.referrals == nil || code == "" {
+		return
+	}
+	signup := &referral.Signup{
+		RefereeID: userID,
+		Code:      code,
+		IP:        clientIP,
+		DeviceID:  deviceID,
+		UserAgent: userAgent,
+	}
+	if _, err := s.referrals.Attach(context.Background(), signup); err != nil {
+		log.Printf("Failed to attach referral for user %d: %v", userID, err)
+	}
+}
+
+// qualifyReferral 用户完成可能发放邀请奖励的操作，失败时只记录日志
+func (s *Service) qualifyReferral(userID int64, action string) {
+	if s.referrals == nil {
+		return
+	}
+	if err := s.referrals.Qualify(context.Background(), userID, action); err != nil {
+		log.Printf("Failed to qualify referral for user %d: %v", userID, err)
+	}
+}
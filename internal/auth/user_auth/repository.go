@@ -6,6 +6,8 @@ import (
 
 	"trusioo_api/internal/auth/user_auth/entities"
 	"trusioo_api/pkg/database"
+
+	"github.com/lib/pq"
 )
 
 // UserRepository 用户数据访问接口
@@ -19,15 +21,34 @@ type UserRepository interface {
 	UpdateLastLogin(id int64) error
 	UpdatePassword(id int64, password string) error
 	UpdateEmailVerified(id int64, verified bool) error
+	UpdatePhone(id int64, phone string, verified bool) error
 
 	// RefreshToken相关
 	CreateRefreshToken(token *entities.RefreshToken) error
 	GetValidRefreshToken(token string) (*entities.RefreshToken, error)
 	InvalidateRefreshToken(token string) error
 	InvalidateAllRefreshTokens(userID int64) error
+	ListActiveRefreshTokens(userID int64) ([]*entities.RefreshToken, error)
+	EvictRefreshTokens(userID int64, ids []int64) error
+	IsRefreshTokenEvicted(token string) (bool, error)
 
 	// LoginSession相关
 	CreateLoginSession(session *entities.LoginSession) error
+	GetRecentLoginSessions(userID int64, limit int) ([]*entities.LoginSession, error)
+	ListLoginSessions(userID int64, limit int) ([]*entities.LoginSession, error)
+
+	// TrustedDevice相关
+	CreateTrustedDevice(device *entities.TrustedDevice) error
+	GetTrustedDeviceByTokenHash(tokenHash string) (*entities.TrustedDevice, error)
+	ListTrustedDevices(userID int64) ([]*entities.TrustedDevice, error)
+	TouchTrustedDevice(id int64) error
+	RevokeTrustedDevice(userID, id int64) error
+	RevokeTrustedDevicesByDeviceID(userID int64, deviceID string) error
+	RevokeAllTrustedDevices(userID int64) error
+
+	// PasswordHistory相关
+	ListPasswordHistory(userID int64, limit int) ([]string, error)
+	AddPasswordHistory(userID int64, passwordHash string, keep int) error
 }
 
 // userRepository Repository接口的实现
@@ -151,7 +172,7 @@ func (r *userRepository) UpdateLastLogin(id int64) error {
 }
 
 func (r *userRepository) UpdatePassword(id int64, password string) error {
-	_, err := database.DB.Exec("UPDATE users SET password = $2, updated_at = NOW() WHERE id = $1", id, password)
+	_, err := database.DB.Exec("UPDATE users SET password = $2, password_set = true, updated_at = NOW() WHERE id = $1", id, password)
 	return err
 }
 
@@ -160,6 +181,11 @@ func (r *userRepository) UpdateEmailVerified(id int64, verified bool) error {
 	return err
 }
 
+func (r *userRepository) UpdatePhone(id int64, phone string, verified bool) error {
+	_, err := database.DB.Exec("UPDATE users SET phone = $2, phone_verified = $3, updated_at = NOW() WHERE id = $1", id, phone, verified)
+	return err
+}
+
 // RefreshToken相关方法
 func (r *userRepository) CreateRefreshToken(token *entities.RefreshToken) error {
 	query := `
@@ -208,13 +234,48 @@ func (r *userRepository) InvalidateAllRefreshTokens(userID int64) error {
 	return err
 }
 
+// ListActiveRefreshTokens 按创建时间正序返回未失效且未过期的刷新令牌
+func (r *userRepository) ListActiveRefreshTokens(userID int64) ([]*entities.RefreshToken, error) {
+	tokens := []*entities.RefreshToken{}
+	query := `
+		SELECT * FROM user_refresh_tokens
+		WHERE user_id = $1 AND is_valid = true AND expires_at > NOW()
+		ORDER BY created_at ASC, id ASC`
+
+	err := database.DB.Select(&tokens, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// EvictRefreshTokens 使超过会话上限的刷新令牌失效并记录踢出时间
+func (r *userRepository) EvictRefreshTokens(userID int64, ids []int64) error {
+	_, err := database.DB.Exec(
+		"UPDATE user_refresh_tokens SET is_valid = false, evicted_at = NOW() WHERE user_id = $1 AND id = ANY($2) AND is_valid = true",
+		userID, pq.Array(ids),
+	)
+	return err
+}
+
+// IsRefreshTokenEvicted 刷新令牌是否因超过会话上限被踢出
+func (r *userRepository) IsRefreshTokenEvicted(token string) (bool, error) {
+	var evicted bool
+	err := database.DB.Get(&evicted,
+		"SELECT EXISTS (SELECT 1 FROM user_refresh_tokens WHERE token = $1 AND evicted_at IS NOT NULL)",
+		token,
+	)
+	return evicted, err
+}
+
 // LoginSession相关方法
 func (r *userRepository) CreateLoginSession(session *entities.LoginSession) error {
 	query := `
 		INSERT INTO user_login_sessions (
 			user_id, ip, country, city, region, timezone, organization, location,
-			user_agent, device_type, os, browser, is_trusted, login_method, platform, status, reason
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
+			user_agent, device_type, os, browser, is_trusted, login_method, platform, status, reason,
+			risk_score, risk_decision, risk_reasons
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20)
 		RETURNING id, created_at`
 	
 	return database.DB.QueryRow(query,
@@ -235,5 +296,170 @@ func (r *userRepository) CreateLoginSession(session *entities.LoginSession) erro
 		session.Platform,
 		session.Status,
 		session.Reason,
+		session.RiskScore,
+		session.RiskDecision,
+		session.RiskReasons,
 	).Scan(&session.ID, &session.CreatedAt)
-}
\ No newline at end of file
+}
+
+// GetRecentLoginSessions 获取用户最近的成功登录记录，按时间倒序，不包括管理员代登录
+func (r *userRepository) GetRecentLoginSessions(userID int64, limit int) ([]*entities.LoginSession, error) {
+	var sessions []*entities.LoginSession
+	query := `
+		SELECT * FROM user_login_sessions
+		WHERE user_id = $1 AND status = 'success' AND impersonator_id IS NULL
+		ORDER BY created_at DESC
+		LIMIT $2`
+
+	err := database.DB.Select(&sessions, query, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// ListLoginSessions 获取用户的成功登录记录，包括管理员代登录，按时间倒序
+func (r *userRepository) ListLoginSessions(userID int64, limit int) ([]*entities.LoginSession, error) {
+	sessions := []*entities.LoginSession{}
+	query := `
+		SELECT * FROM user_login_sessions
+		WHERE user_id = $1 AND status = 'success'
+		ORDER BY created_at DESC
+		LIMIT $2`
+
+	if err := database.DB.Select(&sessions, query, userID, limit); err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// TrustedDevice相关方法
+func (r *userRepository) CreateTrustedDevice(device *entities.TrustedDevice) error {
+	query := `
+		INSERT INTO user_trusted_devices (user_id, device_id, name, token_hash, ua_fingerprint, ip, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at`
+
+	return database.DB.QueryRow(query,
+		device.UserID,
+		device.DeviceID,
+		device.Name,
+		device.TokenHash,
+		device.UAFingerprint,
+		device.IP,
+		device.ExpiresAt,
+	).Scan(&device.ID, &device.CreatedAt)
+}
+
+// GetTrustedDeviceByTokenHash 获取未撤销且未过期的受信任设备
+func (r *userRepository) GetTrustedDeviceByTokenHash(tokenHash string) (*entities.TrustedDevice, error) {
+	var device entities.TrustedDevice
+	query := `
+		SELECT * FROM user_trusted_devices
+		WHERE token_hash = $1 AND revoked_at IS NULL AND expires_at > NOW()`
+
+	err := database.DB.Get(&device, query, tokenHash)
+	if err != nil {
+		return nil, err
+	}
+	return &device, nil
+}
+
+func (r *userRepository) ListTrustedDevices(userID int64) ([]*entities.TrustedDevice, error) {
+	devices := []*entities.TrustedDevice{}
+	query := `
+		SELECT * FROM user_trusted_devices
+		WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > NOW()
+		ORDER BY COALESCE(last_used_at, created_at) DESC`
+
+	err := database.DB.Select(&devices, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	return devices, nil
+}
+
+func (r *userRepository) TouchTrustedDevice(id int64) error {
+	_, err := database.DB.Exec("UPDATE user_trusted_devices SET last_used_at = NOW() WHERE id = $1", id)
+	return err
+}
+
+// RevokeTrustedDevice 撤销指定设备，设备不存在或不属于该用户时返回 sql.ErrNoRows
+func (r *userRepository) RevokeTrustedDevice(userID, id int64) error {
+	result, err := database.DB.Exec(
+		"UPDATE user_trusted_devices SET revoked_at = NOW() WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL",
+		id, userID,
+	)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *userRepository) RevokeTrustedDevicesByDeviceID(userID int64, deviceID string) error {
+	_, err := database.DB.Exec(
+		"UPDATE user_trusted_devices SET revoked_at = NOW() WHERE user_id = $1 AND device_id = $2 AND revoked_at IS NULL",
+		userID, deviceID,
+	)
+	return err
+}
+
+func (r *userRepository) RevokeAllTrustedDevices(userID int64) error {
+	_, err := database.DB.Exec(
+		"UPDATE user_trusted_devices SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL",
+		userID,
+	)
+	return err
+}
+
+// PasswordHistory相关方法
+
+// ListPasswordHistory 按时间倒序返回最近 limit 个密码哈希
+func (r *userRepository) ListPasswordHistory(userID int64, limit int) ([]string, error) {
+	hashes := []string{}
+	query := `
+		SELECT password_hash FROM user_password_history
+		WHERE user_id = $1
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2`
+
+	err := database.DB.Select(&hashes, query, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	return hashes, nil
+}
+
+// AddPasswordHistory 记录新密码哈希，只保留最近 keep 条
+func (r *userRepository) AddPasswordHistory(userID int64, passwordHash string, keep int) error {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(
+		"INSERT INTO user_password_history (user_id, password_hash) VALUES ($1, $2)",
+		userID, passwordHash,
+	); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`
+		DELETE FROM user_password_history
+		WHERE user_id = $1 AND id NOT IN (
+			SELECT id FROM user_password_history
+			WHERE user_id = $1
+			ORDER BY created_at DESC, id DESC
+			LIMIT $2
+		)`, userID, keep); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -1,6 +1,7 @@
 package user_auth
 
 import (
+	"trusioo_api/internal/auth/challenge"
 	"trusioo_api/internal/middleware"
 
 	"github.com/gin-gonic/gin"
@@ -8,12 +9,21 @@ import (
 
 func RegisterRoutes(router *gin.RouterGroup, handler *Handler) {
 	// 公开路由 - 不需要认证
-	router.POST("/register", handler.Register)        // 注册用户（未激活）
-	router.POST("/login", handler.Login)              // 登录第一步：验证email+password
-	router.POST("/login/verify", handler.LoginVerify) // 登录第二步：验证登录验证码
+	// 注册、登录（密码、邮箱验证码、手机号）、忘记密码在同一IP或网段请求过多时要求完成挑战，见 POST /auth/challenge
+	router.POST("/register", middleware.RequireChallenge(challenge.ActionRegister), handler.Register) // 注册用户（未激活）
+	router.POST("/login", middleware.RequireChallenge(challenge.ActionLogin), handler.Login)          // 登录第一步：验证email+password
+	router.POST("/login/verify", handler.LoginVerify)                                                 // 登录第二步：验证登录验证码
+	router.POST("/unlock", handler.UnlockAccount)                                                     // 使用锁定通知邮件中的链接解除登录锁定
 
-	router.POST("/forgot-password", handler.ForgotPassword) // 忘记密码：发送重置验证码
-	router.POST("/reset-password", handler.ResetPassword)   // 重置密码：验证码+新密码
+	router.POST("/login/challenge/verify", handler.LoginChallengeVerify) // 可疑登录：验证发送到账户邮箱的验证码
+
+	router.POST("/login/code", middleware.RequireChallenge(challenge.ActionLoginCode), handler.RequestLoginCode) // 验证码登录第一步：发送邮箱验证码（无需密码）
+	router.POST("/login/code/verify", handler.VerifyLoginCode)                                                   // 验证码登录第二步：验证通过后登录，新邮箱自动注册
+	router.POST("/login/phone", middleware.RequireChallenge(challenge.ActionLoginPhone), handler.LoginByPhone)   // 手机号登录第一步：发送短信验证码
+	router.POST("/login/phone/verify", handler.LoginByPhoneVerify)                                               // 手机号登录第二步：验证短信验证码
+
+	router.POST("/forgot-password", middleware.RequireChallenge(challenge.ActionForgotPassword), handler.ForgotPassword) // 忘记密码：发送重置验证码
+	router.POST("/reset-password", handler.ResetPassword)                                                                // 重置密码：验证码+新密码
 
 	router.POST("/refresh", handler.RefreshToken)
 
@@ -22,5 +32,30 @@ func RegisterRoutes(router *gin.RouterGroup, handler *Handler) {
 	authRoutes.Use(middleware.AuthMiddleware())
 	{
 		authRoutes.GET("/profile", handler.GetProfile)
+		authRoutes.GET("/sessions", handler.ListLoginSessions) // 登录记录，包括管理员代登录
+		authRoutes.GET("/devices", handler.ListTrustedDevices) // 受信任设备列表
+	}
+
+	// 账户资料与凭证的修改 - 拒绝管理员代登录令牌
+	sensitive := router.Group("")
+	sensitive.Use(middleware.AuthMiddleware(), middleware.RejectImpersonation())
+	{
+		recentAuth := middleware.RequireRecentAuth(middleware.StepUpMaxAge())
+
+		sensitive.POST("/profile/complete", handler.CompleteProfile)     // 完善资料
+		sensitive.POST("/password/set", handler.SetPassword)             // 自动注册账户设置密码
+		sensitive.POST("/phone/bind", recentAuth, handler.BindPhone)     // 绑定手机号：发送短信验证码，需要提升令牌
+		sensitive.POST("/phone/verify", recentAuth, handler.VerifyPhone) // 绑定手机号：验证短信验证码，需要提升令牌
+
+		sensitive.POST("/reauth/code", handler.SendReauthCode) // 重新验证身份：发送邮箱验证码
+		sensitive.POST("/reauth", handler.Reauthenticate)      // 重新验证身份：密码或验证码，签发提升令牌
+
+		sensitive.PATCH("/profile", handler.UpdateProfile)                     // 修改姓名或头像
+		sensitive.POST("/profile/avatar", handler.UploadAvatar)                // 上传头像
+		sensitive.POST("/password/change", recentAuth, handler.ChangePassword) // 修改密码，需要提升令牌，其他会话失效
+		sensitive.POST("/email/change", handler.RequestEmailChange)            // 修改邮箱：向新邮箱发送验证码
+		sensitive.POST("/email/change/verify", handler.VerifyEmailChange)      // 修改邮箱：验证后切换邮箱
+
+		sensitive.DELETE("/devices/:id", handler.RevokeTrustedDevice) // 撤销受信任设备
 	}
 }
@@ -4,17 +4,29 @@ import (
 	"context"
 	"database/sql"
 	"log"
+	"mime/multipart"
 	"strings"
 	"time"
 
 	"trusioo_api/config"
+	"trusioo_api/internal/auth/identity"
+	"trusioo_api/internal/auth/lockout"
+	"trusioo_api/internal/auth/loginrisk"
+	"trusioo_api/internal/auth/password"
+	"trusioo_api/internal/auth/sessionlimit"
 	"trusioo_api/internal/auth/user_auth/dto"
 	"trusioo_api/internal/auth/user_auth/entities"
 	"trusioo_api/internal/auth/verification"
 	verificationDto "trusioo_api/internal/auth/verification/dto"
+	verificationEntities "trusioo_api/internal/auth/verification/entities"
 	"trusioo_api/internal/common"
-	"trusioo_api/pkg/auth"
+	imagesDto "trusioo_api/internal/images/dto"
+	referralEntities "trusioo_api/internal/referral/entities"
+	"trusioo_api/pkg/database"
 	"trusioo_api/pkg/ipinfo"
+	"trusioo_api/pkg/mailer"
+	"trusioo_api/pkg/redis"
+	"trusioo_api/pkg/sms"
 
 	"golang.org/x/crypto/bcrypt"
 )
@@ -25,24 +37,79 @@ type VerificationService interface {
 	VerifyCode(req *verificationDto.VerifyCodeRequest) (*verificationDto.VerifyCodeResponse, error)
 }
 
+// EmailOutbox 邮件发件箱接口，由 mailer.Outbox 实现
+type EmailOutbox interface {
+	Enqueue(ctx context.Context, msg *mailer.Message) error
+}
+
+// ImageService 头像上传与图片归属校验，由 images.Service 实现
+type ImageService interface {
+	UploadImage(ctx context.Context, userID *int, file *multipart.FileHeader, req imagesDto.UploadImageRequest) (*imagesDto.UploadImageResponse, error)
+	GetUserImage(ctx context.Context, userID int, imageID int) (*imagesDto.GetImageResponse, error)
+}
+
 type Service struct {
 	repo                Repository
 	verificationService VerificationService
 	ipinfoClient        ipinfo.Client
+	defaultCountryCode  string // 手机号未带国家码时使用
+
+	// 登录失败退避与锁定，为空时不限制
+	loginGuard LoginGuard
+
+	// 注册、重置、修改、设置密码时的密码策略，为空时不校验
+	passwordPolicy *password.Policy
+
+	// 同时登录会话上限，为空时不限制
+	sessionLimiter *sessionlimit.Limiter
+
+	// 登录风险评估，为空时不评估
+	riskEvaluator   *loginrisk.Evaluator
+	riskHistorySize int
+	mailOutbox      EmailOutbox
+	mailLocale      string
+
+	trustedDeviceTTL time.Duration
+	imageService     ImageService
+
+	// 邀请关系与奖励，为空时忽略邀请码
+	referrals ReferralService
+
+	// 与管理员共用的认证核心，由 NewService 根据上面的依赖创建
+	identity *identity.Core
 }
 
-func NewService(repo Repository) *Service {
+func NewService(repo Repository, imageService ImageService, referrals ReferralService) *Service {
 	ipinfoConfig := ipinfo.LoadConfigFromEnv()
 	ipinfoClient := ipinfo.NewClient(ipinfoConfig)
 
-	return &Service{
+	service := &Service{
 		repo:                repo,
 		verificationService: verification.NewService(),
 		ipinfoClient:        ipinfoClient,
+		defaultCountryCode:  config.AppConfig.SMS.DefaultCountryCode,
+		riskHistorySize:     config.AppConfig.LoginRisk.HistorySize,
+		mailOutbox:          mailer.NewOutbox(database.DB),
+		mailLocale:          config.AppConfig.Mail.DefaultLocale,
+		trustedDeviceTTL:    time.Duration(config.AppConfig.TrustedDevice.TTLDays) * 24 * time.Hour,
+		imageService:        imageService,
+		passwordPolicy:      password.NewPolicyFromApp(config.AppConfig),
+		referrals:           referrals,
+	}
+	if riskConfig := loginrisk.NewConfigFromApp(config.AppConfig); riskConfig != nil {
+		service.riskEvaluator = loginrisk.NewEvaluator(riskConfig)
 	}
+	if lockoutConfig := lockout.NewConfigFromApp(config.AppConfig); lockoutConfig != nil {
+		service.loginGuard = lockout.NewGuard(redis.LockoutCache, lockoutConfig)
+	}
+	if sessionConfig := sessionlimit.NewConfigFromApp(config.AppConfig); sessionConfig != nil {
+		service.sessionLimiter = sessionlimit.NewLimiter(sessionConfig)
+	}
+	service.identity = service.newIdentityCore()
+	return service
 }
 
-func (s *Service) Register(req *dto.RegisterRequest) (*dto.RegisterResponse, error) {
+func (s *Service) Register(req *dto.RegisterRequest, clientIP, userAgent string) (*dto.RegisterResponse, error) {
 	// 检查邮箱是否已存在
 	_, err := s.repo.GetByEmail(req.Email)
 	if err != nil && err != sql.ErrNoRows {
@@ -52,6 +119,16 @@ func (s *Service) Register(req *dto.RegisterRequest) (*dto.RegisterResponse, err
 		return nil, common.ErrEmailExists
 	}
 
+	// 校验密码策略
+	if err := s.validatePassword(nil, req.Email, req.Password); err != nil {
+		return nil, err
+	}
+
+	// 校验邀请码
+	if err := s.validateReferralCode(req.ReferralCode); err != nil {
+		return nil, err
+	}
+
 	// 加密密码
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
@@ -80,6 +157,8 @@ func (s *Service) Register(req *dto.RegisterRequest) (*dto.RegisterResponse, err
 	if err != nil {
 		return nil, err
 	}
+	s.recordPasswordHistory(user.ID, user.Password)
+	s.attachReferral(user.ID, req.ReferralCode, clientIP, req.DeviceID, userAgent)
 
 	return &dto.RegisterResponse{
 		User: user,
@@ -88,30 +167,58 @@ func (s *Service) Register(req *dto.RegisterRequest) (*dto.RegisterResponse, err
 
 // Login 第一步登录 - 验证email+password并发送登录验证码
 func (s *Service) Login(req *dto.LoginRequest, clientIP, userAgent string) (*dto.LoginCodeResponse, error) {
-	// 1. 验证email+password
+	// 0. 失败次数过多的账户或IP需要等待，账户是否存在返回相同的结果
+	if wait := s.loginRetryAfter(req.Email, clientIP); wait > 0 {
+		s.recordLoginSession(0, clientIP, userAgent, "email", "failed", "登录失败次数过多")
+		return &dto.LoginCodeResponse{RetryAfter: identity.RetryAfterSeconds(wait)}, common.ErrLoginLocked
+	}
+
+	// 1. 验证email+password，账户不存在与密码错误返回相同的错误，避免枚举账户
 	user, err := s.repo.GetByEmail(req.Email)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			s.recordLoginSession(0, clientIP, userAgent, "email", "failed", "用户不存在")
-			return nil, common.ErrUserNotFound
-		}
+	if err != nil && err != sql.ErrNoRows {
 		return nil, err
 	}
-
-	// 验证密码
-	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password))
-	if err != nil {
-		s.recordLoginSession(user.ID, clientIP, userAgent, "email", "failed", "密码错误")
+	passwordHash := ""
+	if user != nil {
+		passwordHash = user.Password
+	}
+	if !identity.ComparePassword(passwordHash, req.Password) {
+		s.recordLoginFailure(user, req.Email, clientIP)
+		if user == nil {
+			s.recordLoginSession(0, clientIP, userAgent, "email", "failed", "用户不存在")
+		} else {
+			s.recordLoginSession(user.ID, clientIP, userAgent, "email", "failed", "密码错误")
+		}
 		return nil, common.ErrInvalidCredentials
 	}
+	s.resetLoginFailures(req.Email)
 
 	// 检查用户状态 - 必须是激活状态才能登录
-	if user.Status != "active" {
-		s.recordLoginSession(user.ID, clientIP, userAgent, "email", "failed", "账户未激活")
-		return nil, common.ErrUserInactive
+	if err := userStatusError(user); err != nil {
+		s.recordLoginSession(user.ID, clientIP, userAgent, "email", "failed", statusFailureReason(err))
+		return nil, err
 	}
 
-	// 2. 发送登录验证码
+	// 2. 受信任设备跳过验证码；令牌无效时回退到验证码流程
+	if req.DeviceToken != "" {
+		if device := s.matchTrustedDevice(user.ID, req.DeviceID, req.DeviceToken, userAgent); device != nil {
+			resp, err := s.completeLogin(user, clientIP, userAgent, "trusted_device")
+			if err != nil && err != common.ErrLoginChallenge {
+				return nil, err
+			}
+			if err == nil {
+				if touchErr := s.repo.TouchTrustedDevice(device.ID); touchErr != nil {
+					log.Printf("更新受信任设备使用时间失败 %d: %v", device.ID, touchErr)
+				}
+			}
+			return &dto.LoginCodeResponse{
+				Message: "受信任设备登录成功",
+				Login:   resp,
+			}, err
+		}
+	}
+
+	// 3. 发送登录验证码
 	sendReq := &verificationDto.SendVerificationRequest{
 		Target: req.Email,
 		Type:   req.VerificationType(),
@@ -124,7 +231,7 @@ func (s *Service) Login(req *dto.LoginRequest, clientIP, userAgent string) (*dto
 	return &dto.LoginCodeResponse{
 		Message:   "用户登录验证码已发送",
 		LoginCode: "已发送到邮箱",
-		ExpiresIn: 600, // 10分钟
+		ExpiresIn: verification.CodeExpiresIn(),
 	}, nil
 }
 
@@ -139,7 +246,80 @@ func (s *Service) LoginVerify(req *dto.LoginVerifyRequest, clientIP, userAgent s
 		return nil, err
 	}
 
-	// 2. 验证验证码
+	// 2. 验证验证码，未通过时记录失败的登录会话
+	attempt := identity.Attempt{IP: clientIP, UserAgent: userAgent, Method: "email"}
+	if err := s.identityCore().VerifyLoginCode(user.ID, attempt, req.Email, req.Code, req.VerificationType()); err != nil {
+		return nil, err
+	}
+
+	// 3. 标记邮箱为已验证（通过登录验证码验证了邮箱所有权）
+	err = s.repo.UpdateEmailVerified(user.ID, true)
+	if err != nil {
+		log.Printf("Failed to update email_verified for user %d: %v", user.ID, err)
+		// 不返回错误，因为验证码已经验证通过
+	} else if !user.EmailVerified {
+		s.qualifyReferral(user.ID, referralEntities.ActionEmailVerified)
+	}
+
+	// 4. 签发令牌并记录登录会话
+	resp, err := s.completeLogin(user, clientIP, userAgent, "email")
+	if err != nil {
+		return resp, err
+	}
+
+	// 5. 标记受信任设备，失败不影响本次登录
+	if req.TrustDevice {
+		if err := s.trustDevice(resp, user.ID, req.DeviceID, clientIP, userAgent); err != nil {
+			log.Printf("标记受信任设备失败 user=%d: %v", user.ID, err)
+		}
+	}
+
+	return resp, nil
+}
+
+// RequestLoginCode 验证码登录第一步 - 向邮箱发送登录验证码，邮箱未注册时同样发送
+func (s *Service) RequestLoginCode(req *dto.CodeLoginRequest, clientIP, userAgent string) (*dto.LoginCodeResponse, error) {
+	user, err := s.repo.GetByEmail(req.Email)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	if user != nil {
+		if err := userStatusError(user); err != nil {
+			s.recordLoginSession(user.ID, clientIP, userAgent, "email_code", "failed", statusFailureReason(err))
+			return nil, err
+		}
+	}
+
+	sendReq := &verificationDto.SendVerificationRequest{
+		Target: req.Email,
+		Type:   req.VerificationType(),
+		Locale: req.Locale,
+	}
+	if _, err := s.verificationService.SendVerificationCode(sendReq); err != nil {
+		return nil, err
+	}
+
+	return &dto.LoginCodeResponse{
+		Message:   "登录验证码已发送",
+		LoginCode: "已发送到邮箱",
+		ExpiresIn: verification.CodeExpiresIn(),
+	}, nil
+}
+
+// VerifyLoginCode 验证码登录第二步 - 验证通过后登录，账户不存在时自动注册
+// 自动注册的账户 AutoRegistered=true、PasswordSet=false，需后续设置密码并完善资料
+func (s *Service) VerifyLoginCode(req *dto.CodeLoginVerifyRequest, clientIP, userAgent string) (*dto.LoginResponse, error) {
+	user, err := s.repo.GetByEmail(req.Email)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	// 邀请码只在自动注册时使用，在消耗验证码之前校验
+	if user == nil {
+		if err := s.validateReferralCode(req.ReferralCode); err != nil {
+			return nil, err
+		}
+	}
+
 	verifyReq := &verificationDto.VerifyCodeRequest{
 		Target: req.Email,
 		Code:   req.Code,
@@ -147,56 +327,394 @@ func (s *Service) LoginVerify(req *dto.LoginVerifyRequest, clientIP, userAgent s
 	}
 	verifyResp, err := s.verificationService.VerifyCode(verifyReq)
 	if err != nil {
-		s.recordLoginSession(user.ID, clientIP, userAgent, "email", "failed", "验证码错误")
+		if user != nil {
+			s.recordLoginSession(user.ID, clientIP, userAgent, "email_code", "failed", "验证码错误")
+		}
 		return nil, err
 	}
 	if !verifyResp.Valid {
-		s.recordLoginSession(user.ID, clientIP, userAgent, "email", "failed", "验证码无效")
+		if user != nil {
+			s.recordLoginSession(user.ID, clientIP, userAgent, "email_code", "failed", "验证码无效")
+		}
 		return nil, common.ErrInvalidCode
 	}
 
-	// 3. 生成访问令牌
-	accessToken, err := auth.GenerateAccessToken(user.ID, user.Email, user.Role, "user")
+	isNewUser := false
+	if user == nil {
+		user, err = s.autoRegister(req.Email)
+		if err != nil {
+			return nil, err
+		}
+		isNewUser = true
+		s.attachReferral(user.ID, req.ReferralCode, clientIP, req.DeviceID, userAgent)
+		s.qualifyReferral(user.ID, referralEntities.ActionEmailVerified)
+	} else {
+		if err := userStatusError(user); err != nil {
+			s.recordLoginSession(user.ID, clientIP, userAgent, "email_code", "failed", statusFailureReason(err))
+			return nil, err
+		}
+		// 验证码证明了邮箱所有权
+		if !user.EmailVerified {
+			if err := s.repo.UpdateEmailVerified(user.ID, true); err != nil {
+				log.Printf("Failed to update email_verified for user %d: %v", user.ID, err)
+			} else {
+				s.qualifyReferral(user.ID, referralEntities.ActionEmailVerified)
+			}
+		}
+	}
+
+	resp, err := s.completeLogin(user, clientIP, userAgent, "email_code")
+	if err != nil {
+		return resp, err
+	}
+	resp.IsNewUser = isNewUser
+	return resp, nil
+}
+
+// autoRegister 为验证码登录的新邮箱创建账户
+func (s *Service) autoRegister(email string) (*entities.User, error) {
+	user := &entities.User{
+		Email:            email,
+		Password:         "", // 未设置密码，无法通过密码登录
+		Role:             "user",
+		Status:           "active",
+		EmailVerified:    true, // 验证码已证明邮箱所有权
+		AutoRegistered:   true,
+		ProfileCompleted: false,
+		PasswordSet:      false,
+		CreatedAt:        time.Now(),
+		UpdatedAt:        time.Now(),
+	}
+
+	if err := s.repo.Create(user); err != nil {
+		// 并发请求可能已创建同一邮箱的账户
+		if existing, getErr := s.repo.GetByEmail(email); getErr == nil {
+			return existing, nil
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
+// SetPassword 为尚未设置密码的账户设置密码
+func (s *Service) SetPassword(userID int64, req *dto.SetPasswordRequest) (*dto.OnboardingResponse, error) {
+	user, err := s.repo.GetByID(userID)
 	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, common.ErrUserNotFound
+		}
+		return nil, err
+	}
+	if user.PasswordSet {
+		return nil, common.ErrPasswordAlreadySet
+	}
+	if err := s.validatePassword(user, user.Email, req.Password); err != nil {
 		return nil, err
 	}
 
-	// 4. 生成刷新令牌
-	refreshTokenStr, err := auth.GenerateRefreshToken(user.ID, user.Email, user.Role, "user")
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
 		return nil, err
 	}
 
-	// 5. 保存刷新令牌
-	refreshToken := &entities.RefreshToken{
-		UserID:     user.ID,
-		Token:      refreshTokenStr,
-		IsValid:    true,
-		ExpiresAt:  time.Now().Add(time.Duration(config.AppConfig.JWT.RefreshExpire) * time.Second),
-		DeviceInfo: userAgent,
-		CreatedAt:  time.Now(),
+	if err := s.repo.UpdatePassword(user.ID, string(hashedPassword)); err != nil {
+		return nil, err
 	}
+	s.recordPasswordHistory(user.ID, string(hashedPassword))
+	user.PasswordSet = true
 
-	err = s.repo.CreateRefreshToken(refreshToken)
+	return &dto.OnboardingResponse{
+		Message:      "密码设置成功",
+		User:         user,
+		PendingSteps: pendingOnboardingSteps(user),
+	}, nil
+}
+
+// CompleteProfile 完善资料
+func (s *Service) CompleteProfile(userID int64, req *dto.CompleteProfileRequest) (*dto.OnboardingResponse, error) {
+	user, err := s.repo.GetByID(userID)
 	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, common.ErrUserNotFound
+		}
 		return nil, err
 	}
 
-	// 6. 更新最后登录时间
-	err = s.repo.UpdateLastLogin(user.ID)
+	user.Name = strings.TrimSpace(req.Name)
+	if user.Name == "" {
+		return nil, common.ErrValidation
+	}
+	user.ProfileCompleted = true
+
+	if err := s.repo.Update(user); err != nil {
+		return nil, err
+	}
+
+	return &dto.OnboardingResponse{
+		Message:      "资料已完善",
+		User:         user,
+		PendingSteps: pendingOnboardingSteps(user),
+	}, nil
+}
+
+// pendingOnboardingSteps 返回用户尚未完成的引导步骤
+func pendingOnboardingSteps(user *entities.User) []string {
+	steps := []string{}
+	if !user.PasswordSet {
+		steps = append(steps, dto.OnboardingStepSetPassword)
+	}
+	if !user.ProfileCompleted {
+		steps = append(steps, dto.OnboardingStepCompleteProfile)
+	}
+	return steps
+}
+
+// LoginByPhone 手机号登录第一步 - 向已验证的手机号发送短信验证码
+func (s *Service) LoginByPhone(req *dto.PhoneLoginRequest, clientIP, userAgent string) (*dto.PhoneCodeResponse, error) {
+	phone, err := s.normalizePhone(req.Phone)
 	if err != nil {
 		return nil, err
 	}
 
-	// 7. 标记邮箱为已验证（通过登录验证码验证了邮箱所有权）
-	err = s.repo.UpdateEmailVerified(user.ID, true)
+	// 只允许已验证的号码登录，未注册号码不发送短信，避免被用于短信轰炸
+	// 未注册与未验证的号码返回与发送成功相同的响应，避免枚举账户
+	user, err := s.repo.GetByPhone(phone)
 	if err != nil {
-		log.Printf("Failed to update email_verified for user %d: %v", user.ID, err)
-		// 不返回错误，因为登录流程已经成功
+		if err == sql.ErrNoRows {
+			s.recordLoginSession(0, clientIP, userAgent, "phone", "failed", "手机号未注册")
+			return phoneLoginCodeResponse(phone), nil
+		}
+		return nil, err
+	}
+	if !user.PhoneVerified {
+		s.recordLoginSession(user.ID, clientIP, userAgent, "phone", "failed", "手机号未验证")
+		return phoneLoginCodeResponse(phone), nil
+	}
+	if err := userStatusError(user); err != nil {
+		s.recordLoginSession(user.ID, clientIP, userAgent, "phone", "failed", statusFailureReason(err))
+		return nil, err
 	}
 
-	// 8. 记录登录会话并获取位置信息
-	sessionInfo := s.recordLoginSessionWithIPInfo(user.ID, clientIP, userAgent, "email", "success", "登录成功")
+	sendReq := &verificationDto.SendVerificationRequest{
+		Target: phone,
+		Type:   req.VerificationType(),
+		Locale: req.Locale,
+	}
+	if _, err := s.verificationService.SendVerificationCode(sendReq); err != nil {
+		return nil, err
+	}
+
+	return phoneLoginCodeResponse(phone), nil
+}
+
+// phoneLoginCodeResponse 手机号登录验证码已发送的响应
+func phoneLoginCodeResponse(phone string) *dto.PhoneCodeResponse {
+	return &dto.PhoneCodeResponse{
+		Message:   "登录验证码已发送",
+		Phone:     sms.MaskPhone(phone),
+		ExpiresIn: verification.CodeExpiresIn(),
+	}
+}
+
+// LoginByPhoneVerify 手机号登录第二步 - 验证短信验证码并返回token
+func (s *Service) LoginByPhoneVerify(req *dto.PhoneLoginVerifyRequest, clientIP, userAgent string) (*dto.LoginResponse, error) {
+	phone, err := s.normalizePhone(req.Phone)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.repo.GetByPhone(phone)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, common.ErrUserNotFound
+		}
+		return nil, err
+	}
+	if !user.PhoneVerified {
+		return nil, common.ErrUserNotFound
+	}
+
+	attempt := identity.Attempt{IP: clientIP, UserAgent: userAgent, Method: "phone"}
+	if err := s.identityCore().VerifyLoginCode(user.ID, attempt, phone, req.Code, req.VerificationType()); err != nil {
+		return nil, err
+	}
+
+	if err := userStatusError(user); err != nil {
+		s.recordLoginSession(user.ID, clientIP, userAgent, "phone", "failed", statusFailureReason(err))
+		return nil, err
+	}
+
+	return s.completeLogin(user, clientIP, userAgent, "phone")
+}
+
+// BindPhone 绑定手机号第一步 - 向新号码发送短信验证码
+func (s *Service) BindPhone(userID int64, req *dto.BindPhoneRequest) (*dto.PhoneCodeResponse, error) {
+	phone, err := s.normalizePhone(req.Phone)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.ensurePhoneAvailable(userID, phone); err != nil {
+		return nil, err
+	}
+
+	sendReq := &verificationDto.SendVerificationRequest{
+		Target: phone,
+		Type:   verificationEntities.TypePhoneBind,
+		Locale: req.Locale,
+		UserID: userID,
+	}
+	if _, err := s.verificationService.SendVerificationCode(sendReq); err != nil {
+		return nil, err
+	}
+
+	return &dto.PhoneCodeResponse{
+		Message:   "手机验证码已发送",
+		Phone:     sms.MaskPhone(phone),
+		ExpiresIn: verification.CodeExpiresIn(),
+	}, nil
+}
+
+// VerifyPhone 绑定手机号第二步 - 验证短信验证码并将号码标记为已验证
+func (s *Service) VerifyPhone(userID int64, req *dto.VerifyPhoneRequest) (*dto.VerifyPhoneResponse, error) {
+	phone, err := s.normalizePhone(req.Phone)
+	if err != nil {
+		return nil, err
+	}
+
+	// 验证码按用户隔离，只能验证当前用户自己请求的验证码
+	verifyReq := &verificationDto.VerifyCodeRequest{
+		Target: phone,
+		Code:   req.Code,
+		Type:   verificationEntities.TypePhoneBind,
+		UserID: userID,
+	}
+	verifyResp, err := s.verificationService.VerifyCode(verifyReq)
+	if err != nil {
+		return nil, err
+	}
+	if !verifyResp.Valid {
+		return nil, common.ErrInvalidCode
+	}
+
+	// 发送验证码后号码可能已被其他账户绑定，写入前再检查一次
+	if err := s.ensurePhoneAvailable(userID, phone); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.UpdatePhone(userID, phone, true); err != nil {
+		return nil, err
+	}
+
+	return &dto.VerifyPhoneResponse{
+		Message:       "手机号绑定成功",
+		Phone:         phone,
+		PhoneVerified: true,
+	}, nil
+}
+
+// normalizePhone 将号码归一为E.164格式
+func (s *Service) normalizePhone(raw string) (string, error) {
+	phone, err := sms.NormalizeE164(raw, s.defaultCountryCode)
+	if err != nil {
+		return "", common.ErrInvalidPhone
+	}
+	return phone, nil
+}
+
+// ensurePhoneAvailable 检查号码未被其他用户绑定，或当前用户已验证过该号码
+func (s *Service) ensurePhoneAvailable(userID int64, phone string) error {
+	existing, err := s.repo.GetByPhone(phone)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		return err
+	}
+	if existing.ID != userID || existing.PhoneVerified {
+		return common.ErrPhoneExists
+	}
+	return nil
+}
+
+// LoginChallengeVerify 风险验证 - 验证发送到账户邮箱或已验证手机号的验证码后完成登录
+func (s *Service) LoginChallengeVerify(req *dto.LoginChallengeVerifyRequest, clientIP, userAgent string) (*dto.LoginResponse, error) {
+	userID, channel, err := parseChallengeID(req.ChallengeID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.repo.GetByID(userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, common.ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	target := user.Email
+	if channel == challengeChannelSMS {
+		// 发送验证码后号码可能已被解绑
+		if !user.PhoneVerified || user.Phone == nil {
+			return nil, common.ErrTokenInvalid
+		}
+		target = *user.Phone
+	}
+
+	verifyReq := &verificationDto.VerifyCodeRequest{
+		Target: target,
+		Code:   req.Code,
+		Type:   challengeCodeType(channel),
+	}
+	verifyResp, err := s.verificationService.VerifyCode(verifyReq)
+	if err != nil {
+		s.recordLoginSession(user.ID, clientIP, userAgent, "challenge", "failed", "风险验证码错误")
+		return nil, err
+	}
+	if !verifyResp.Valid {
+		s.recordLoginSession(user.ID, clientIP, userAgent, "challenge", "failed", "风险验证码无效")
+		return nil, common.ErrInvalidCode
+	}
+	if err := userStatusError(user); err != nil {
+		return nil, err
+	}
+
+	return s.completeLogin(user, clientIP, userAgent, "challenge")
+}
+
+// completeLogin 验证通过后评估登录风险、签发令牌、更新登录时间并记录登录会话
+func (s *Service) completeLogin(user *entities.User, clientIP, userAgent, method string) (*dto.LoginResponse, error) {
+	ipInfo := s.identityCore().LookupIP(clientIP)
+
+	// 风险评估：高风险直接拒绝并通知用户，中风险要求通过登录以外的渠道再验证一次
+	assessment := s.assessLoginRisk(user.ID, ipInfo, userAgent)
+	if assessment != nil {
+		switch assessment.Decision {
+		case loginrisk.DecisionBlock:
+			s.writeLoginSession(user.ID, clientIP, userAgent, method, "failed", "可疑登录已拦截", ipInfo, assessment)
+			s.sendLoginBlockedEmail(user, clientIP, ipInfo)
+			return nil, common.ErrLoginBlocked
+		case loginrisk.DecisionChallenge:
+			if method != "challenge" {
+				return s.requireLoginChallenge(user, clientIP, userAgent, method, ipInfo, assessment)
+			}
+		}
+	}
+
+	// 1. 生成并保存访问令牌与刷新令牌
+	accessToken, refreshTokenStr, err := s.issueTokenPair(user, userAgent)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. 更新最后登录时间
+	err = s.repo.UpdateLastLogin(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 3. 记录登录会话
+	sessionInfo := s.writeLoginSession(user.ID, clientIP, userAgent, method, "success", "登录成功", ipInfo, assessment)
 
 	return &dto.LoginResponse{
 		AccessToken:  accessToken,
@@ -204,35 +722,55 @@ func (s *Service) LoginVerify(req *dto.LoginVerifyRequest, clientIP, userAgent s
 		TokenType:    "Bearer",
 		ExpiresIn:    int64(config.AppConfig.JWT.AccessExpire),
 		User: &entities.User{
-			ID:     user.ID,
-			Name:   user.Name,
-			Email:  user.Email,
-			Phone:  user.Phone,
-			Role:   user.Role,
-			Status: user.Status,
+			ID:               user.ID,
+			Name:             user.Name,
+			Email:            user.Email,
+			Phone:            user.Phone,
+			Role:             user.Role,
+			Status:           user.Status,
+			AutoRegistered:   user.AutoRegistered,
+			PasswordSet:      user.PasswordSet,
+			ProfileCompleted: user.ProfileCompleted,
 		},
 		LoginSession: sessionInfo,
+		PendingSteps: pendingOnboardingSteps(user),
 	}, nil
 }
 
+// issueTokenPair 生成访问令牌与刷新令牌，并保存刷新令牌
+func (s *Service) issueTokenPair(user *entities.User, userAgent string) (string, string, error) {
+	if err := s.admitSession(user, userAgent); err != nil {
+		return "", "", err
+	}
+
+	tokens, err := s.identityCore().IssueTokens(identityAccount(user), userAgent)
+	if err != nil {
+		return "", "", err
+	}
+
+	return tokens.AccessToken, tokens.RefreshToken, nil
+}
+
 func (s *Service) RefreshToken(req *dto.RefreshTokenRequest) (*dto.LoginResponse, error) {
 	// 验证刷新令牌
-	refreshToken, err := s.repo.GetValidRefreshToken(req.RefreshToken)
+	refreshToken, err := s.identityCore().ValidateRefreshToken(req.RefreshToken)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, common.ErrRefreshTokenInvalid
-		}
 		return nil, err
 	}
 
 	// 获取用户信息
-	user, err := s.repo.GetByID(refreshToken.UserID)
+	user, err := s.repo.GetByID(refreshToken.AccountID)
 	if err != nil {
 		return nil, err
 	}
 
+	// 被暂停或停用的账户不能续期访问令牌
+	if err := userStatusError(user); err != nil {
+		return nil, err
+	}
+
 	// 生成新的访问令牌
-	accessToken, err := auth.GenerateAccessToken(user.ID, user.Email, user.Role, "user")
+	accessToken, err := s.identityCore().AccessToken(identityAccount(user))
 	if err != nil {
 		return nil, err
 	}
@@ -250,6 +788,7 @@ func (s *Service) RefreshToken(req *dto.RefreshTokenRequest) (*dto.LoginResponse
 			Role:   user.Role,
 			Status: user.Status,
 		},
+		PendingSteps: pendingOnboardingSteps(user),
 	}, nil
 }
 
@@ -266,34 +805,29 @@ func (s *Service) ForgotPassword(req *dto.ForgotPasswordRequest) (*dto.ForgotPas
 			// 为了安全，即使用户不存在也返回成功，避免暴露用户是否存在
 			return &dto.ForgotPasswordResponse{
 				Message:   "如果该邮箱已注册，重置密码验证码已发送",
-				ExpiresIn: 600,
+				ExpiresIn: verification.CodeExpiresIn(),
 			}, nil
 		}
 		return nil, err
 	}
 
-	// 2. 检查用户状态
-	if user.Status != "active" {
+	// 2. 检查用户状态，暂停已到期的用户可以重置密码
+	if userStatusError(user) != nil {
 		// 为了安全，不暴露账户状态信息
 		return &dto.ForgotPasswordResponse{
 			Message:   "如果该邮箱已注册，重置密码验证码已发送",
-			ExpiresIn: 600,
+			ExpiresIn: verification.CodeExpiresIn(),
 		}, nil
 	}
 
 	// 3. 发送重置密码验证码
-	sendReq := &verificationDto.SendVerificationRequest{
-		Target: req.Email,
-		Type:   "forgot_password",
-	}
-	_, err = s.verificationService.SendVerificationCode(sendReq)
-	if err != nil {
+	if err := s.identityCore().SendPasswordReset(req.Email); err != nil {
 		return nil, err
 	}
 
 	return &dto.ForgotPasswordResponse{
 		Message:   "重置密码验证码已发送到您的邮箱",
-		ExpiresIn: 600, // 10分钟
+		ExpiresIn: verification.CodeExpiresIn(),
 	}, nil
 }
 
@@ -308,141 +842,232 @@ func (s *Service) ResetPassword(req *dto.ResetPasswordRequest) (*dto.ResetPasswo
 		return nil, err
 	}
 
-	// 2. 验证重置密码验证码
-	verifyReq := &verificationDto.VerifyCodeRequest{
-		Target: req.Email,
-		Code:   req.Code,
-		Type:   "forgot_password",
-	}
-	verifyResp, err := s.verificationService.VerifyCode(verifyReq)
+	// 2. 验证重置密码验证码，按密码策略校验后更新密码并使所有refresh token失效
+	hashedPassword, err := s.identityCore().ResetPassword(user.ID, req.Email, req.Code, req.Password, func(password string) error {
+		return s.validatePassword(user, user.Email, password)
+	})
 	if err != nil {
 		return nil, err
 	}
-	if !verifyResp.Valid {
-		return nil, common.ErrInvalidCode
+	s.recordPasswordHistory(user.ID, hashedPassword)
+
+	// 3. 密码变更后撤销所有受信任设备
+	if err := s.repo.RevokeAllTrustedDevices(user.ID); err != nil {
+		log.Printf("Failed to revoke trusted devices for user %d: %v", user.ID, err)
 	}
 
-	// 3. 加密新密码
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
-	if err != nil {
-		return nil, err
+	// 4. 通过邮箱验证码重置密码后解除登录锁定
+	s.resetLoginFailures(user.Email)
+
+	return &dto.ResetPasswordResponse{
+		Message: "密码重置成功，请使用新密码登录",
+	}, nil
+}
+
+// ListTrustedDevices 列出用户当前有效的受信任设备
+func (s *Service) ListTrustedDevices(userID int64) ([]*entities.TrustedDevice, error) {
+	return s.repo.ListTrustedDevices(userID)
+}
+
+// loginSessionListLimit 登录记录列表返回的最大条数
+const loginSessionListLimit = 50
+
+// ListLoginSessions 获取用户最近的登录记录，管理员代登录也会列出
+func (s *Service) ListLoginSessions(userID int64) ([]*entities.LoginSession, error) {
+	return s.repo.ListLoginSessions(userID, loginSessionListLimit)
+}
+
+// RevokeTrustedDevice 撤销受信任设备，该设备下次登录需重新输入验证码
+func (s *Service) RevokeTrustedDevice(userID, deviceID int64) error {
+	err := s.repo.RevokeTrustedDevice(userID, deviceID)
+	if err == sql.ErrNoRows {
+		return common.ErrNotFound
 	}
+	return err
+}
 
-	// 4. 更新密码
-	err = s.repo.UpdatePassword(user.ID, string(hashedPassword))
-	if err != nil {
-		return nil, err
+// RevokeAllTrustedDevices 撤销用户的全部受信任设备
+func (s *Service) RevokeAllTrustedDevices(userID int64) error {
+	return s.repo.RevokeAllTrustedDevices(userID)
+}
+
+// trustDevice 为当前设备签发设备令牌并保存其哈希
+func (s *Service) trustDevice(resp *dto.LoginResponse, userID int64, deviceID, clientIP, userAgent string) error {
+	device := identity.ParseUserAgent(userAgent)
+	fingerprint := uaFingerprint(device)
+
+	ttl := s.trustedDeviceTTL
+	if ttl <= 0 {
+		ttl = 30 * 24 * time.Hour
 	}
+	expiresAt := time.Now().Add(ttl)
 
-	// 5. 可选：使所有refresh token失效，强制重新登录
-	err = s.repo.InvalidateAllRefreshTokens(user.ID)
+	token, err := newDeviceToken(userID, deviceID, fingerprint, expiresAt)
 	if err != nil {
-		log.Printf("Failed to invalidate refresh tokens for user %d: %v", user.ID, err)
-		// 不返回错误，因为密码已经重置成功
+		return err
 	}
 
-	return &dto.ResetPasswordResponse{
-		Message: "密码重置成功，请使用新密码登录",
-	}, nil
+	// 同一设备重复标记时只保留最新的令牌
+	if err := s.repo.RevokeTrustedDevicesByDeviceID(userID, deviceID); err != nil {
+		return err
+	}
+	if err := s.repo.CreateTrustedDevice(&entities.TrustedDevice{
+		UserID:        userID,
+		DeviceID:      deviceID,
+		Name:          deviceName(device),
+		TokenHash:     hashDeviceToken(token),
+		UAFingerprint: fingerprint,
+		IP:            clientIP,
+		ExpiresAt:     expiresAt,
+	}); err != nil {
+		return err
+	}
+
+	resp.DeviceToken = token
+	resp.DeviceTokenExpiresAt = expiresAt.Format(time.RFC3339)
+	return nil
+}
+
+// matchTrustedDevice 校验设备令牌的签名、设备绑定与数据库记录，不匹配时返回nil
+func (s *Service) matchTrustedDevice(userID int64, deviceID, token, userAgent string) *entities.TrustedDevice {
+	if deviceID == "" {
+		return nil
+	}
+
+	fingerprint := uaFingerprint(identity.ParseUserAgent(userAgent))
+
+	tokenUserID, err := parseDeviceToken(token, deviceID, fingerprint, time.Now())
+	if err != nil || tokenUserID != userID {
+		return nil
+	}
+
+	device, err := s.repo.GetTrustedDeviceByTokenHash(hashDeviceToken(token))
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Printf("查询受信任设备失败 user=%d: %v", userID, err)
+		}
+		return nil
+	}
+	if device.UserID != userID || device.DeviceID != deviceID || device.UAFingerprint != fingerprint {
+		return nil
+	}
+	return device
 }
 
 func (s *Service) recordLoginSession(userID int64, ip, userAgent, method, status, reason string) {
-	s.recordLoginSessionWithIPInfo(userID, ip, userAgent, method, status, reason)
+	s.identityCore().RecordLoginSession(userID, identity.Attempt{IP: ip, UserAgent: userAgent, Method: method}, status, reason)
 }
 
-func (s *Service) recordLoginSessionWithIPInfo(userID int64, ip, userAgent, method, status, reason string) *dto.LoginSessionInfo {
-	session := &entities.LoginSession{
-		UserID:      userID,
-		IP:          ip,
-		UserAgent:   userAgent,
-		LoginMethod: method,
-		Status:      status,
-		Reason:      reason,
-		CreatedAt:   time.Now(),
+// writeLoginSession 记录带风险评估结果的登录会话，登录成功时返回会话信息给客户端
+func (s *Service) writeLoginSession(userID int64, ip, userAgent, method, status, reason string, ipInfo *ipinfo.IPInfo, assessment *loginrisk.Assessment) *dto.LoginSessionInfo {
+	session := identity.NewLoginSession(userID, identity.Attempt{IP: ip, UserAgent: userAgent, Method: method}, ipInfo, status, reason)
+	session.IsTrusted = method == "trusted_device"
+	session.SetRisk(assessment)
+
+	if !s.identityCore().SaveLoginSession(session) || status != "success" {
+		return nil
 	}
+	return &dto.LoginSessionInfo{
+		IP:           session.IP,
+		Country:      session.Country,
+		City:         session.City,
+		Region:       session.Region,
+		Timezone:     session.Timezone,
+		Organization: session.Organization,
+		Location:     session.Coordinates,
+		IsTrusted:    session.IsTrusted,
+		RiskDecision: session.RiskDecision,
+	}
+}
 
-	// 获取IP地理位置信息
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+// assessLoginRisk 将本次登录与用户最近的成功登录对比并评分，未启用或查询失败时返回nil
+func (s *Service) assessLoginRisk(userID int64, ipInfo *ipinfo.IPInfo, userAgent string) *loginrisk.Assessment {
+	return s.identityCore().AssessLoginRisk(userID, ipInfo, userAgent)
+}
 
-	if ipInfo, err := s.ipinfoClient.GetIPInfo(ctx, ip); err == nil {
-		session.Country = ipInfo.Country
-		session.City = ipInfo.City
-		session.Region = ipInfo.Region
-		session.Timezone = ipInfo.Timezone
-		session.Organization = ipInfo.Org
-		session.Location = ipInfo.Loc
-	} else {
-		log.Printf("获取IP信息失败 %s: %v", ip, err)
+// requireLoginChallenge 中风险登录要求额外验证：向登录本身没有用过的渠道发送验证码，
+// 返回验证标识供客户端提交验证码，同时以 common.ErrLoginChallenge 告知调用方登录未完成
+// 没有可用的额外渠道时（例如邮箱验证码登录但未绑定手机号）按拦截处理
+func (s *Service) requireLoginChallenge(user *entities.User, clientIP, userAgent, method string, ipInfo *ipinfo.IPInfo, assessment *loginrisk.Assessment) (*dto.LoginResponse, error) {
+	channel := challengeChannelFor(user, method)
+	if channel == "" {
+		s.writeLoginSession(user.ID, clientIP, userAgent, method, "failed", "需要额外验证但没有可用的验证渠道", ipInfo, assessment)
+		s.sendLoginBlockedEmail(user, clientIP, ipInfo)
+		return nil, common.ErrLoginBlocked
 	}
 
-	// 解析User-Agent获取设备信息
-	s.parseUserAgent(session, userAgent)
+	s.writeLoginSession(user.ID, clientIP, userAgent, method, "failed", "需要额外验证", ipInfo, assessment)
+	if err := s.sendLoginChallenge(user, channel); err != nil {
+		return nil, err
+	}
 
-	err := s.repo.CreateLoginSession(session)
-	if err != nil {
-		log.Printf("记录登录会话失败: %v", err)
-		return nil
+	challenge := &dto.LoginChallengeResponse{
+		ChallengeID: newChallengeID(user.ID, channel, time.Now()),
+		Reasons:     assessment.Reasons,
+		ExpiresIn:   int(challengeTTL.Seconds()),
+	}
+	if channel == challengeChannelSMS {
+		challenge.Method = "sms_code"
+		challenge.Phone = sms.MaskPhone(*user.Phone)
+	} else {
+		challenge.Method = "email_code"
+		challenge.Email = maskEmail(user.Email)
 	}
+	return &dto.LoginResponse{Challenge: challenge}, common.ErrLoginChallenge
+}
 
-	// 返回会话信息给客户端
-	if status == "success" {
-		return &dto.LoginSessionInfo{
-			IP:           session.IP,
-			Country:      session.Country,
-			City:         session.City,
-			Region:       session.Region,
-			Timezone:     session.Timezone,
-			Organization: session.Organization,
-			Location:     session.Location,
-			IsTrusted:    session.IsTrusted,
-		}
+// sendLoginChallenge 向账户邮箱或已验证的手机号发送风险验证码
+func (s *Service) sendLoginChallenge(user *entities.User, channel string) error {
+	target := user.Email
+	if channel == challengeChannelSMS {
+		target = *user.Phone
+	}
+	sendReq := &verificationDto.SendVerificationRequest{
+		Target: target,
+		Type:   challengeCodeType(channel),
+	}
+	_, err := s.verificationService.SendVerificationCode(sendReq)
+	// 冷却期内说明验证码刚发送过，客户端继续使用即可
+	if err != nil && err != common.ErrCodeTooFrequent {
+		return err
 	}
 	return nil
 }
 
-func (s *Service) parseUserAgent(session *entities.LoginSession, userAgent string) {
-	ua := strings.ToLower(userAgent)
+// maskEmail 隐藏邮箱用户名中间部分
+func maskEmail(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at <= 1 {
+		return email
+	}
+	return email[:1] + strings.Repeat("*", at-1) + email[at:]
+}
+
+// sendLoginBlockedEmail 通知用户可疑登录已被拦截
+func (s *Service) sendLoginBlockedEmail(user *entities.User, ip string, ipInfo *ipinfo.IPInfo) {
+	if s.mailOutbox == nil {
+		return
+	}
 
-	// 检测设备类型
-	if strings.Contains(ua, "mobile") || strings.Contains(ua, "android") || strings.Contains(ua, "iphone") {
-		session.DeviceType = "mobile"
-	} else if strings.Contains(ua, "tablet") || strings.Contains(ua, "ipad") {
-		session.DeviceType = "tablet"
-	} else {
-		session.DeviceType = "desktop"
-	}
-
-	// 检测操作系统
-	if strings.Contains(ua, "windows") {
-		session.OS = "Windows"
-	} else if strings.Contains(ua, "mac") || strings.Contains(ua, "darwin") {
-		session.OS = "macOS"
-	} else if strings.Contains(ua, "linux") {
-		session.OS = "Linux"
-	} else if strings.Contains(ua, "android") {
-		session.OS = "Android"
-	} else if strings.Contains(ua, "iphone") || strings.Contains(ua, "ipad") || strings.Contains(ua, "ios") {
-		session.OS = "iOS"
-	}
-
-	// 检测浏览器
-	if strings.Contains(ua, "chrome") && !strings.Contains(ua, "edge") {
-		session.Browser = "Chrome"
-	} else if strings.Contains(ua, "firefox") {
-		session.Browser = "Firefox"
-	} else if strings.Contains(ua, "safari") && !strings.Contains(ua, "chrome") {
-		session.Browser = "Safari"
-	} else if strings.Contains(ua, "edge") {
-		session.Browser = "Edge"
-	} else if strings.Contains(ua, "opera") {
-		session.Browser = "Opera"
-	}
-
-	// 检测平台
-	if strings.Contains(ua, "mobile") || strings.Contains(ua, "android") || strings.Contains(ua, "iphone") {
-		session.Platform = "mobile"
-	} else {
-		session.Platform = "web"
+	location := "Unknown"
+	if ipInfo != nil && ipInfo.Country != "" {
+		location = strings.Trim(ipInfo.City+", "+ipInfo.Country, ", ")
+	}
+
+	msg, err := mailer.Render(user.Email, mailer.TemplateSuspiciousLogin, "", s.mailLocale, mailer.TemplateData{
+		Email:    user.Email,
+		IP:       ip,
+		Location: location,
+		Time:     time.Now().UTC().Format("2006-01-02 15:04:05 UTC"),
+	})
+	if err != nil {
+		log.Printf("渲染可疑登录通知失败: %v", err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.mailOutbox.Enqueue(ctx, msg); err != nil {
+		log.Printf("发送可疑登录通知失败 %d: %v", user.ID, err)
 	}
 }
@@ -3,18 +3,33 @@ package user_auth
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"golang.org/x/crypto/bcrypt"
 
+	"trusioo_api/config"
+	"trusioo_api/internal/auth/lockout"
+	"trusioo_api/internal/auth/loginrisk"
+	"trusioo_api/internal/auth/password"
+	"trusioo_api/internal/auth/sessionlimit"
 	"trusioo_api/internal/auth/user_auth/dto"
 	"trusioo_api/internal/auth/user_auth/entities"
 	verificationDto "trusioo_api/internal/auth/verification/dto"
 	"trusioo_api/internal/common"
+	imagesDto "trusioo_api/internal/images/dto"
+	"trusioo_api/internal/referral"
+	referralEntities "trusioo_api/internal/referral/entities"
 	"trusioo_api/internal/testutil"
+	"trusioo_api/pkg/auth"
 	"trusioo_api/pkg/ipinfo"
+	"trusioo_api/pkg/mailer"
 )
 
 // MockUserRepository 模拟用户仓库
@@ -66,11 +81,29 @@ func (m *MockUserRepository) UpdatePassword(id int64, password string) error {
 	return args.Error(0)
 }
 
+func (m *MockUserRepository) ListPasswordHistory(userID int64, limit int) ([]string, error) {
+	args := m.Called(userID, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockUserRepository) AddPasswordHistory(userID int64, passwordHash string, keep int) error {
+	args := m.Called(userID, passwordHash, keep)
+	return args.Error(0)
+}
+
 func (m *MockUserRepository) UpdateEmailVerified(id int64, verified bool) error {
 	args := m.Called(id, verified)
 	return args.Error(0)
 }
 
+func (m *MockUserRepository) UpdatePhone(id int64, phone string, verified bool) error {
+	args := m.Called(id, phone, verified)
+	return args.Error(0)
+}
+
 func (m *MockUserRepository) CreateRefreshToken(token *entities.RefreshToken) error {
 	args := m.Called(token)
 	return args.Error(0)
@@ -94,11 +127,86 @@ func (m *MockUserRepository) InvalidateAllRefreshTokens(userID int64) error {
 	return args.Error(0)
 }
 
+func (m *MockUserRepository) ListActiveRefreshTokens(userID int64) ([]*entities.RefreshToken, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*entities.RefreshToken), args.Error(1)
+}
+
+func (m *MockUserRepository) EvictRefreshTokens(userID int64, ids []int64) error {
+	args := m.Called(userID, ids)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) IsRefreshTokenEvicted(token string) (bool, error) {
+	args := m.Called(token)
+	return args.Bool(0), args.Error(1)
+}
+
 func (m *MockUserRepository) CreateLoginSession(session *entities.LoginSession) error {
 	args := m.Called(session)
 	return args.Error(0)
 }
 
+func (m *MockUserRepository) GetRecentLoginSessions(userID int64, limit int) ([]*entities.LoginSession, error) {
+	args := m.Called(userID, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*entities.LoginSession), args.Error(1)
+}
+
+func (m *MockUserRepository) ListLoginSessions(userID int64, limit int) ([]*entities.LoginSession, error) {
+	args := m.Called(userID, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*entities.LoginSession), args.Error(1)
+}
+
+func (m *MockUserRepository) CreateTrustedDevice(device *entities.TrustedDevice) error {
+	args := m.Called(device)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) GetTrustedDeviceByTokenHash(tokenHash string) (*entities.TrustedDevice, error) {
+	args := m.Called(tokenHash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entities.TrustedDevice), args.Error(1)
+}
+
+func (m *MockUserRepository) ListTrustedDevices(userID int64) ([]*entities.TrustedDevice, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*entities.TrustedDevice), args.Error(1)
+}
+
+func (m *MockUserRepository) TouchTrustedDevice(id int64) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) RevokeTrustedDevice(userID, id int64) error {
+	args := m.Called(userID, id)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) RevokeTrustedDevicesByDeviceID(userID int64, deviceID string) error {
+	args := m.Called(userID, deviceID)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) RevokeAllTrustedDevices(userID int64) error {
+	args := m.Called(userID)
+	return args.Error(0)
+}
+
 // MockVerificationService 模拟验证服务，实现VerificationService接口
 type MockVerificationService struct {
 	mock.Mock
@@ -219,7 +327,7 @@ func TestService_Register(t *testing.T) {
 				ipinfoClient:        ipinfoClient,
 			}
 
-			resp, err := service.Register(tt.request)
+			resp, err := service.Register(tt.request, "127.0.0.1", "test-agent")
 
 			if tt.expectedError != nil {
 				assert.Error(t, err)
@@ -301,7 +409,7 @@ func TestService_Login(t *testing.T) {
 					Loc:     "0,0",
 				}, nil)
 			},
-			expectedError: common.ErrUserNotFound,
+			expectedError: common.ErrInvalidCredentials,
 			validateResult: func(t *testing.T, resp *dto.LoginCodeResponse) {
 				assert.Nil(t, resp)
 			},
@@ -366,4 +474,1253 @@ func TestService_Login(t *testing.T) {
 			verifyService.AssertExpectations(t)
 		})
 	}
-}
\ No newline at end of file
+}
+
+// fakeLoginGuard 记录调用的登录锁定
+type fakeLoginGuard struct {
+	wait       time.Duration
+	lockOnFail bool
+	fails      []string
+	resets     []string
+	tokens     map[string]string
+}
+
+func (f *fakeLoginGuard) Check(ctx context.Context, account, ip string) (time.Duration, error) {
+	return f.wait, nil
+}
+
+func (f *fakeLoginGuard) Fail(ctx context.Context, account, ip string) (*lockout.Result, error) {
+	f.fails = append(f.fails, account+"|"+ip)
+	if f.lockOnFail {
+		return &lockout.Result{AccountLocked: true, RetryAfter: 30 * time.Minute}, nil
+	}
+	return &lockout.Result{}, nil
+}
+
+func (f *fakeLoginGuard) Reset(ctx context.Context, account string) error {
+	f.resets = append(f.resets, account)
+	return nil
+}
+
+func (f *fakeLoginGuard) IssueUnlockToken(ctx context.Context, account string) (string, error) {
+	f.tokens = map[string]string{"unlock-token": account}
+	return "unlock-token", nil
+}
+
+func (f *fakeLoginGuard) Unlock(ctx context.Context, token string) (string, error) {
+	account, ok := f.tokens[token]
+	if !ok {
+		return "", common.ErrUnlockTokenInvalid
+	}
+	delete(f.tokens, token)
+	return account, f.Reset(ctx, account)
+}
+
+func (f *fakeLoginGuard) LockDuration() time.Duration {
+	return 30 * time.Minute
+}
+
+// 测试登录失败锁定
+func TestService_LoginLockout(t *testing.T) {
+	testutil.MockJWTConfig()
+	config.AppConfig.Frontend.AppURL = "https://app.example.com"
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.DefaultCost)
+	assert.NoError(t, err)
+	user := &entities.User{ID: 1, Email: "test@example.com", Password: string(hashedPassword), Status: "active"}
+
+	setup := func(guard *fakeLoginGuard) (*Service, *MockUserRepository, *MockVerificationService, *fakeOutbox) {
+		userRepo := &MockUserRepository{}
+		verifyService := &MockVerificationService{}
+		ipinfoClient := &MockIPInfoClient{}
+		outbox := &fakeOutbox{}
+		userRepo.On("CreateLoginSession", mock.AnythingOfType("*entities.LoginSession")).Return(nil).Maybe()
+		ipinfoClient.On("GetIPInfo", mock.Anything, mock.Anything).Return(&ipinfo.IPInfo{IP: "10.0.0.1"}, nil).Maybe()
+
+		service := &Service{
+			repo:                userRepo,
+			verificationService: verifyService,
+			ipinfoClient:        ipinfoClient,
+			loginGuard:          guard,
+			mailOutbox:          outbox,
+		}
+		return service, userRepo, verifyService, outbox
+	}
+
+	t.Run("锁定期间不校验密码，账户是否存在返回相同结果", func(t *testing.T) {
+		service, userRepo, _, _ := setup(&fakeLoginGuard{wait: 90500 * time.Millisecond})
+
+		resp, err := service.Login(&dto.LoginRequest{Email: "test@example.com", Password: "password123"}, "10.0.0.1", "test-agent")
+
+		assert.Equal(t, common.ErrLoginLocked, err)
+		assert.Equal(t, 91, resp.RetryAfter)
+		userRepo.AssertNotCalled(t, "GetByEmail", mock.Anything)
+	})
+
+	t.Run("密码错误计数，达到阈值时发送解锁邮件", func(t *testing.T) {
+		guard := &fakeLoginGuard{lockOnFail: true}
+		service, userRepo, _, outbox := setup(guard)
+		userRepo.On("GetByEmail", "test@example.com").Return(user, nil)
+
+		_, err := service.Login(&dto.LoginRequest{Email: "test@example.com", Password: "wrong"}, "10.0.0.1", "test-agent")
+
+		assert.Equal(t, common.ErrInvalidCredentials, err)
+		assert.Equal(t, []string{"test@example.com|10.0.0.1"}, guard.fails)
+		if assert.Len(t, outbox.messages, 1) {
+			assert.Equal(t, "test@example.com", outbox.messages[0].To)
+			assert.Contains(t, outbox.messages[0].TextBody, "https://app.example.com/unlock?token=unlock-token")
+		}
+
+		resp, err := service.UnlockAccount(&dto.UnlockAccountRequest{Token: "unlock-token"})
+		assert.NoError(t, err)
+		assert.NotEmpty(t, resp.Message)
+		assert.Equal(t, []string{"test@example.com"}, guard.resets)
+
+		_, err = service.UnlockAccount(&dto.UnlockAccountRequest{Token: "unlock-token"})
+		assert.Equal(t, common.ErrUnlockTokenInvalid, err)
+	})
+
+	t.Run("账户不存在同样计数但不发送邮件", func(t *testing.T) {
+		guard := &fakeLoginGuard{lockOnFail: true}
+		service, userRepo, _, outbox := setup(guard)
+		userRepo.On("GetByEmail", "nobody@example.com").Return(nil, sql.ErrNoRows)
+
+		_, err := service.Login(&dto.LoginRequest{Email: "nobody@example.com", Password: "wrong"}, "10.0.0.1", "test-agent")
+
+		assert.Equal(t, common.ErrInvalidCredentials, err)
+		assert.Equal(t, []string{"nobody@example.com|10.0.0.1"}, guard.fails)
+		assert.Empty(t, outbox.messages)
+	})
+
+	t.Run("密码正确清除失败次数", func(t *testing.T) {
+		guard := &fakeLoginGuard{}
+		service, userRepo, verifyService, _ := setup(guard)
+		userRepo.On("GetByEmail", "test@example.com").Return(user, nil)
+		verifyService.On("SendVerificationCode", mock.Anything).Return(&verificationDto.SendVerificationResponse{}, nil)
+
+		_, err := service.Login(&dto.LoginRequest{Email: "test@example.com", Password: "password123"}, "10.0.0.1", "test-agent")
+
+		assert.NoError(t, err)
+		assert.Empty(t, guard.fails)
+		assert.Equal(t, []string{"test@example.com"}, guard.resets)
+	})
+}
+
+// 测试手机号验证码登录
+func TestService_LoginByPhone(t *testing.T) {
+	testutil.MockJWTConfig()
+
+	phone := "+8613800138000"
+	verifiedUser := &entities.User{
+		ID:            1,
+		Email:         "test@example.com",
+		Phone:         &phone,
+		PhoneVerified: true,
+		Status:        "active",
+		Role:          "user",
+	}
+
+	newService := func(userRepo *MockUserRepository, verifyService *MockVerificationService, ipinfoClient *MockIPInfoClient) *Service {
+		return &Service{
+			repo:                userRepo,
+			verificationService: verifyService,
+			ipinfoClient:        ipinfoClient,
+			defaultCountryCode:  "86",
+		}
+	}
+
+	t.Run("本地号码归一化后发送短信验证码", func(t *testing.T) {
+		userRepo := &MockUserRepository{}
+		verifyService := &MockVerificationService{}
+		userRepo.On("GetByPhone", phone).Return(verifiedUser, nil)
+		verifyService.On("SendVerificationCode", mock.MatchedBy(func(req *verificationDto.SendVerificationRequest) bool {
+			return req.Target == phone && req.Type == "user_phone_login"
+		})).Return(&verificationDto.SendVerificationResponse{}, nil)
+
+		resp, err := newService(userRepo, verifyService, &MockIPInfoClient{}).
+			LoginByPhone(&dto.PhoneLoginRequest{Phone: "138-0013-8000"}, "127.0.0.1", "test-agent")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "+861******8000", resp.Phone)
+		userRepo.AssertExpectations(t)
+		verifyService.AssertExpectations(t)
+	})
+
+	t.Run("号码格式错误", func(t *testing.T) {
+		_, err := newService(&MockUserRepository{}, &MockVerificationService{}, &MockIPInfoClient{}).
+			LoginByPhone(&dto.PhoneLoginRequest{Phone: "abc"}, "127.0.0.1", "test-agent")
+		assert.Equal(t, common.ErrInvalidPhone, err)
+	})
+
+	t.Run("未验证的号码不发送短信", func(t *testing.T) {
+		userRepo := &MockUserRepository{}
+		verifyService := &MockVerificationService{}
+		ipinfoClient := &MockIPInfoClient{}
+		userRepo.On("GetByPhone", phone).Return(&entities.User{ID: 2, Phone: &phone, Status: "active"}, nil)
+		userRepo.On("CreateLoginSession", mock.AnythingOfType("*entities.LoginSession")).Return(nil)
+		ipinfoClient.On("GetIPInfo", mock.Anything, mock.Anything).Return(&ipinfo.IPInfo{}, nil)
+
+		resp, err := newService(userRepo, verifyService, ipinfoClient).
+			LoginByPhone(&dto.PhoneLoginRequest{Phone: phone}, "127.0.0.1", "test-agent")
+		// 与发送成功的响应相同，避免枚举账户
+		assert.NoError(t, err)
+		assert.Equal(t, phoneLoginCodeResponse(phone), resp)
+		verifyService.AssertNotCalled(t, "SendVerificationCode", mock.Anything)
+	})
+
+	t.Run("验证码正确后签发令牌", func(t *testing.T) {
+		userRepo := &MockUserRepository{}
+		verifyService := &MockVerificationService{}
+		ipinfoClient := &MockIPInfoClient{}
+		userRepo.On("GetByPhone", phone).Return(verifiedUser, nil)
+		verifyService.On("VerifyCode", mock.MatchedBy(func(req *verificationDto.VerifyCodeRequest) bool {
+			return req.Target == phone && req.Type == "user_phone_login" && req.Code == "123456"
+		})).Return(&verificationDto.VerifyCodeResponse{Valid: true}, nil)
+		userRepo.On("CreateRefreshToken", mock.AnythingOfType("*entities.RefreshToken")).Return(nil)
+		userRepo.On("UpdateLastLogin", int64(1)).Return(nil)
+		userRepo.On("CreateLoginSession", mock.MatchedBy(func(session *entities.LoginSession) bool {
+			return session.LoginMethod == "phone" && session.Status == "success"
+		})).Return(nil)
+		ipinfoClient.On("GetIPInfo", mock.Anything, mock.Anything).Return(&ipinfo.IPInfo{}, nil)
+
+		resp, err := newService(userRepo, verifyService, ipinfoClient).
+			LoginByPhoneVerify(&dto.PhoneLoginVerifyRequest{Phone: "+86 138 0013 8000", Code: "123456"}, "127.0.0.1", "test-agent")
+
+		assert.NoError(t, err)
+		assert.NotEmpty(t, resp.AccessToken)
+		assert.NotEmpty(t, resp.RefreshToken)
+		userRepo.AssertExpectations(t)
+	})
+}
+
+// 测试绑定手机号
+func TestService_VerifyPhone(t *testing.T) {
+	phone := "+8613800138000"
+
+	t.Run("验证通过后绑定号码", func(t *testing.T) {
+		userRepo := &MockUserRepository{}
+		verifyService := &MockVerificationService{}
+		verifyService.On("VerifyCode", mock.MatchedBy(func(req *verificationDto.VerifyCodeRequest) bool {
+			return req.Target == phone && req.Type == "phone_bind" && req.UserID == 1
+		})).Return(&verificationDto.VerifyCodeResponse{Valid: true}, nil)
+		userRepo.On("GetByPhone", phone).Return(nil, sql.ErrNoRows)
+		userRepo.On("UpdatePhone", int64(1), phone, true).Return(nil)
+
+		service := &Service{repo: userRepo, verificationService: verifyService, defaultCountryCode: "86"}
+		resp, err := service.VerifyPhone(1, &dto.VerifyPhoneRequest{Phone: "13800138000", Code: "123456"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, phone, resp.Phone)
+		assert.True(t, resp.PhoneVerified)
+		userRepo.AssertExpectations(t)
+	})
+
+	t.Run("验证码发送给当前用户", func(t *testing.T) {
+		userRepo := &MockUserRepository{}
+		verifyService := &MockVerificationService{}
+		userRepo.On("GetByPhone", phone).Return(nil, sql.ErrNoRows)
+		verifyService.On("SendVerificationCode", mock.MatchedBy(func(req *verificationDto.SendVerificationRequest) bool {
+			return req.Target == phone && req.Type == "phone_bind" && req.UserID == 1
+		})).Return(&verificationDto.SendVerificationResponse{}, nil)
+
+		service := &Service{repo: userRepo, verificationService: verifyService, defaultCountryCode: "86"}
+		_, err := service.BindPhone(1, &dto.BindPhoneRequest{Phone: "13800138000"})
+
+		assert.NoError(t, err)
+		verifyService.AssertExpectations(t)
+	})
+
+	t.Run("其他账户请求的验证码不能绑定到本账户", func(t *testing.T) {
+		userRepo := &MockUserRepository{}
+		verifyService := &MockVerificationService{}
+		// 验证码只对请求它的用户有效，其他用户验证时查不到验证码
+		verifyService.On("VerifyCode", mock.MatchedBy(func(req *verificationDto.VerifyCodeRequest) bool {
+			return req.UserID == 2
+		})).Return(&verificationDto.VerifyCodeResponse{Valid: false}, nil)
+
+		service := &Service{repo: userRepo, verificationService: verifyService, defaultCountryCode: "86"}
+		_, err := service.VerifyPhone(2, &dto.VerifyPhoneRequest{Phone: phone, Code: "123456"})
+
+		assert.Equal(t, common.ErrInvalidCode, err)
+		userRepo.AssertNotCalled(t, "UpdatePhone", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("号码已被其他用户绑定", func(t *testing.T) {
+		userRepo := &MockUserRepository{}
+		verifyService := &MockVerificationService{}
+		userRepo.On("GetByPhone", phone).Return(&entities.User{ID: 2, Phone: &phone, PhoneVerified: true}, nil)
+
+		service := &Service{repo: userRepo, verificationService: verifyService, defaultCountryCode: "86"}
+		_, err := service.BindPhone(1, &dto.BindPhoneRequest{Phone: phone})
+
+		assert.Equal(t, common.ErrPhoneExists, err)
+		verifyService.AssertNotCalled(t, "SendVerificationCode", mock.Anything)
+	})
+}
+
+// 测试验证码登录与自动注册
+func TestService_VerifyLoginCode(t *testing.T) {
+	testutil.MockJWTConfig()
+
+	validCode := func(verifyService *MockVerificationService) {
+		verifyService.On("VerifyCode", mock.MatchedBy(func(req *verificationDto.VerifyCodeRequest) bool {
+			return req.Type == "user_code_login" && req.Code == "123456"
+		})).Return(&verificationDto.VerifyCodeResponse{Valid: true}, nil)
+	}
+	loginSucceeds := func(userRepo *MockUserRepository, ipinfoClient *MockIPInfoClient) {
+		userRepo.On("CreateRefreshToken", mock.AnythingOfType("*entities.RefreshToken")).Return(nil)
+		userRepo.On("UpdateLastLogin", mock.Anything).Return(nil)
+		userRepo.On("CreateLoginSession", mock.AnythingOfType("*entities.LoginSession")).Return(nil)
+		ipinfoClient.On("GetIPInfo", mock.Anything, mock.Anything).Return(&ipinfo.IPInfo{}, nil)
+	}
+
+	t.Run("新邮箱自动注册", func(t *testing.T) {
+		userRepo := &MockUserRepository{}
+		verifyService := &MockVerificationService{}
+		ipinfoClient := &MockIPInfoClient{}
+
+		userRepo.On("GetByEmail", "new@example.com").Return(nil, sql.ErrNoRows)
+		validCode(verifyService)
+		userRepo.On("Create", mock.MatchedBy(func(user *entities.User) bool {
+			return user.AutoRegistered && !user.PasswordSet && user.EmailVerified && user.Status == "active"
+		})).Run(func(args mock.Arguments) {
+			args.Get(0).(*entities.User).ID = 10
+		}).Return(nil)
+		loginSucceeds(userRepo, ipinfoClient)
+
+		service := &Service{repo: userRepo, verificationService: verifyService, ipinfoClient: ipinfoClient}
+		resp, err := service.VerifyLoginCode(&dto.CodeLoginVerifyRequest{Email: "new@example.com", Code: "123456"}, "127.0.0.1", "test-agent")
+
+		assert.NoError(t, err)
+		assert.True(t, resp.IsNewUser)
+		assert.Equal(t, int64(10), resp.User.ID)
+		assert.Equal(t, []string{dto.OnboardingStepSetPassword, dto.OnboardingStepCompleteProfile}, resp.PendingSteps)
+		userRepo.AssertExpectations(t)
+	})
+
+	t.Run("已有账户直接登录", func(t *testing.T) {
+		userRepo := &MockUserRepository{}
+		verifyService := &MockVerificationService{}
+		ipinfoClient := &MockIPInfoClient{}
+
+		userRepo.On("GetByEmail", "test@example.com").Return(&entities.User{
+			ID:               1,
+			Email:            "test@example.com",
+			Status:           "active",
+			EmailVerified:    true,
+			PasswordSet:      true,
+			ProfileCompleted: true,
+		}, nil)
+		validCode(verifyService)
+		loginSucceeds(userRepo, ipinfoClient)
+
+		service := &Service{repo: userRepo, verificationService: verifyService, ipinfoClient: ipinfoClient}
+		resp, err := service.VerifyLoginCode(&dto.CodeLoginVerifyRequest{Email: "test@example.com", Code: "123456"}, "127.0.0.1", "test-agent")
+
+		assert.NoError(t, err)
+		assert.False(t, resp.IsNewUser)
+		assert.Empty(t, resp.PendingSteps)
+		userRepo.AssertNotCalled(t, "Create", mock.Anything)
+	})
+
+	t.Run("验证码错误不创建账户", func(t *testing.T) {
+		userRepo := &MockUserRepository{}
+		verifyService := &MockVerificationService{}
+
+		userRepo.On("GetByEmail", "new@example.com").Return(nil, sql.ErrNoRows)
+		verifyService.On("VerifyCode", mock.Anything).Return(nil, common.ErrInvalidCode)
+
+		service := &Service{repo: userRepo, verificationService: verifyService}
+		_, err := service.VerifyLoginCode(&dto.CodeLoginVerifyRequest{Email: "new@example.com", Code: "000000"}, "127.0.0.1", "test-agent")
+
+		assert.Equal(t, common.ErrInvalidCode, err)
+		userRepo.AssertNotCalled(t, "Create", mock.Anything)
+	})
+}
+
+// 测试引导步骤：设置密码与完善资料
+func TestService_Onboarding(t *testing.T) {
+	t.Run("自动注册账户设置密码", func(t *testing.T) {
+		userRepo := &MockUserRepository{}
+		userRepo.On("GetByID", int64(1)).Return(&entities.User{ID: 1, AutoRegistered: true}, nil)
+		userRepo.On("UpdatePassword", int64(1), mock.AnythingOfType("string")).Return(nil)
+
+		service := &Service{repo: userRepo}
+		resp, err := service.SetPassword(1, &dto.SetPasswordRequest{Password: "password123"})
+
+		assert.NoError(t, err)
+		assert.True(t, resp.User.PasswordSet)
+		assert.Equal(t, []string{dto.OnboardingStepCompleteProfile}, resp.PendingSteps)
+	})
+
+	t.Run("已设置密码不能重复设置", func(t *testing.T) {
+		userRepo := &MockUserRepository{}
+		userRepo.On("GetByID", int64(1)).Return(&entities.User{ID: 1, PasswordSet: true}, nil)
+
+		service := &Service{repo: userRepo}
+		_, err := service.SetPassword(1, &dto.SetPasswordRequest{Password: "password123"})
+
+		assert.Equal(t, common.ErrPasswordAlreadySet, err)
+		userRepo.AssertNotCalled(t, "UpdatePassword", mock.Anything, mock.Anything)
+	})
+
+	t.Run("完善资料", func(t *testing.T) {
+		userRepo := &MockUserRepository{}
+		userRepo.On("GetByID", int64(1)).Return(&entities.User{ID: 1, PasswordSet: true}, nil)
+		userRepo.On("Update", mock.MatchedBy(func(user *entities.User) bool {
+			return user.Name == "Alice" && user.ProfileCompleted
+		})).Return(nil)
+
+		service := &Service{repo: userRepo}
+		resp, err := service.CompleteProfile(1, &dto.CompleteProfileRequest{Name: "  Alice "})
+
+		assert.NoError(t, err)
+		assert.Empty(t, resp.PendingSteps)
+		userRepo.AssertExpectations(t)
+	})
+}
+
+// fakeOutbox 记录写入发件箱的邮件
+type fakeOutbox struct {
+	messages []*mailer.Message
+}
+
+func (f *fakeOutbox) Enqueue(ctx context.Context, msg *mailer.Message) error {
+	f.messages = append(f.messages, msg)
+	return nil
+}
+
+// 测试可疑登录检测
+func TestService_LoginRisk(t *testing.T) {
+	testutil.MockJWTConfig()
+
+	phone := "+8613800138000"
+	user := &entities.User{ID: 1, Email: "test@example.com", Phone: &phone, PhoneVerified: true, Status: "active"}
+	userAgent := "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 Chrome/120.0 Safari/537.36"
+	history := []*entities.LoginSession{{
+		UserID:     1,
+		Country:    "CN",
+		Location:   "31.2304,121.4737",
+		DeviceType: "desktop",
+		OS:         "macOS",
+		Browser:    "Chrome",
+		Status:     "success",
+		CreatedAt:  time.Now().Add(-time.Hour),
+	}}
+
+	setup := func(info *ipinfo.IPInfo) (*Service, *MockUserRepository, *MockVerificationService, *fakeOutbox) {
+		userRepo := &MockUserRepository{}
+		verifyService := &MockVerificationService{}
+		ipinfoClient := &MockIPInfoClient{}
+		outbox := &fakeOutbox{}
+
+		userRepo.On("GetByPhone", phone).Return(user, nil)
+		userRepo.On("GetRecentLoginSessions", int64(1), 20).Return(history, nil)
+		verifyService.On("VerifyCode", mock.Anything).Return(&verificationDto.VerifyCodeResponse{Valid: true}, nil)
+		ipinfoClient.On("GetIPInfo", mock.Anything, mock.Anything).Return(info, nil)
+
+		service := &Service{
+			repo:                userRepo,
+			verificationService: verifyService,
+			ipinfoClient:        ipinfoClient,
+			defaultCountryCode:  "86",
+			riskEvaluator:       loginrisk.NewEvaluator(nil),
+			mailOutbox:          outbox,
+		}
+		return service, userRepo, verifyService, outbox
+	}
+	verifyReq := &dto.PhoneLoginVerifyRequest{Phone: phone, Code: "123456"}
+
+	t.Run("不可能的移动直接拦截并邮件通知", func(t *testing.T) {
+		service, userRepo, _, outbox := setup(&ipinfo.IPInfo{Country: "US", City: "San Francisco", Loc: "37.7749,-122.4194"})
+		userRepo.On("CreateLoginSession", mock.MatchedBy(func(session *entities.LoginSession) bool {
+			return session.Status == "failed" && session.RiskDecision == loginrisk.DecisionBlock &&
+				session.RiskReasons == "new_country,impossible_travel"
+		})).Return(nil)
+
+		_, err := service.LoginByPhoneVerify(verifyReq, "8.8.8.8", userAgent)
+
+		assert.Equal(t, common.ErrLoginBlocked, err)
+		userRepo.AssertNotCalled(t, "CreateRefreshToken", mock.Anything)
+		if assert.Len(t, outbox.messages, 1) {
+			assert.Equal(t, "test@example.com", outbox.messages[0].To)
+			assert.Contains(t, outbox.messages[0].TextBody, "San Francisco, US")
+		}
+	})
+
+	t.Run("中风险要求邮箱验证码", func(t *testing.T) {
+		service, userRepo, verifyService, _ := setup(&ipinfo.IPInfo{Country: "CN", Loc: "31.2304,121.4737", Privacy: &ipinfo.Privacy{Tor: true}})
+		userRepo.On("CreateLoginSession", mock.AnythingOfType("*entities.LoginSession")).Return(nil)
+		verifyService.On("SendVerificationCode", mock.MatchedBy(func(req *verificationDto.SendVerificationRequest) bool {
+			return req.Target == "test@example.com" && req.Type == "user_login_challenge"
+		})).Return(&verificationDto.SendVerificationResponse{}, nil)
+
+		resp, err := service.LoginByPhoneVerify(verifyReq, "8.8.8.8", userAgent)
+
+		assert.Equal(t, common.ErrLoginChallenge, err)
+		if assert.NotNil(t, resp) && assert.NotNil(t, resp.Challenge) {
+			assert.Empty(t, resp.AccessToken)
+			assert.Equal(t, "t***@example.com", resp.Challenge.Email)
+			assert.Equal(t, []string{loginrisk.ReasonTor}, resp.Challenge.Reasons)
+
+			// 提交邮箱验证码后完成登录
+			userRepo.On("GetByID", int64(1)).Return(user, nil)
+			userRepo.On("CreateRefreshToken", mock.AnythingOfType("*entities.RefreshToken")).Return(nil)
+			userRepo.On("UpdateLastLogin", int64(1)).Return(nil)
+
+			loginResp, err := service.LoginChallengeVerify(&dto.LoginChallengeVerifyRequest{
+				ChallengeID: resp.Challenge.ChallengeID,
+				Code:        "654321",
+			}, "8.8.8.8", userAgent)
+			assert.NoError(t, err)
+			assert.NotEmpty(t, loginResp.AccessToken)
+			assert.Equal(t, loginrisk.DecisionChallenge, loginResp.LoginSession.RiskDecision)
+		}
+	})
+
+	t.Run("邮箱验证码登录的额外验证发往已验证的手机号", func(t *testing.T) {
+		service, userRepo, verifyService, _ := setup(&ipinfo.IPInfo{Country: "CN", Loc: "31.2304,121.4737", Privacy: &ipinfo.Privacy{Tor: true}})
+		verified := *user
+		verified.EmailVerified = true
+		userRepo.On("GetByEmail", "test@example.com").Return(&verified, nil)
+		userRepo.On("CreateLoginSession", mock.AnythingOfType("*entities.LoginSession")).Return(nil)
+		verifyService.On("SendVerificationCode", mock.MatchedBy(func(req *verificationDto.SendVerificationRequest) bool {
+			return req.Target == phone && req.Type == "user_login_challenge_sms"
+		})).Return(&verificationDto.SendVerificationResponse{}, nil)
+
+		resp, err := service.VerifyLoginCode(&dto.CodeLoginVerifyRequest{Email: "test@example.com", Code: "123456"}, "8.8.8.8", userAgent)
+
+		// 登录本身的邮箱验证码不能满足额外验证
+		assert.Equal(t, common.ErrLoginChallenge, err)
+		userRepo.AssertNotCalled(t, "CreateRefreshToken", mock.Anything)
+		if assert.NotNil(t, resp) && assert.NotNil(t, resp.Challenge) {
+			assert.Empty(t, resp.AccessToken)
+			assert.Equal(t, "sms_code", resp.Challenge.Method)
+			assert.Empty(t, resp.Challenge.Email)
+			assert.NotEmpty(t, resp.Challenge.Phone)
+
+			userRepo.On("GetByID", int64(1)).Return(&verified, nil)
+			userRepo.On("CreateRefreshToken", mock.AnythingOfType("*entities.RefreshToken")).Return(nil)
+			userRepo.On("UpdateLastLogin", int64(1)).Return(nil)
+
+			loginResp, err := service.LoginChallengeVerify(&dto.LoginChallengeVerifyRequest{
+				ChallengeID: resp.Challenge.ChallengeID,
+				Code:        "654321",
+			}, "8.8.8.8", userAgent)
+			assert.NoError(t, err)
+			assert.NotEmpty(t, loginResp.AccessToken)
+			verifyService.AssertCalled(t, "VerifyCode", mock.MatchedBy(func(req *verificationDto.VerifyCodeRequest) bool {
+				return req.Target == phone && req.Type == "user_login_challenge_sms"
+			}))
+		}
+	})
+
+	t.Run("邮箱验证码登录没有其他验证渠道时拒绝登录", func(t *testing.T) {
+		service, userRepo, verifyService, outbox := setup(&ipinfo.IPInfo{Country: "CN", Loc: "31.2304,121.4737", Privacy: &ipinfo.Privacy{Tor: true}})
+		emailOnly := &entities.User{ID: 1, Email: "test@example.com", EmailVerified: true, Status: "active"}
+		userRepo.On("GetByEmail", "test@example.com").Return(emailOnly, nil)
+		userRepo.On("CreateLoginSession", mock.MatchedBy(func(session *entities.LoginSession) bool {
+			return session.Status == "failed" && session.RiskDecision == loginrisk.DecisionChallenge
+		})).Return(nil)
+
+		resp, err := service.VerifyLoginCode(&dto.CodeLoginVerifyRequest{Email: "test@example.com", Code: "123456"}, "8.8.8.8", userAgent)
+
+		assert.Equal(t, common.ErrLoginBlocked, err)
+		assert.Nil(t, resp)
+		userRepo.AssertNotCalled(t, "CreateRefreshToken", mock.Anything)
+		verifyService.AssertNotCalled(t, "SendVerificationCode", mock.Anything)
+		assert.Len(t, outbox.messages, 1)
+	})
+
+	t.Run("篡改的验证标识", func(t *testing.T) {
+		service, _, _, _ := setup(nil)
+		id := newChallengeID(1, challengeChannelEmail, time.Now())
+		_, err := service.LoginChallengeVerify(&dto.LoginChallengeVerifyRequest{ChallengeID: "2" + id[1:], Code: "123456"}, "8.8.8.8", userAgent)
+		assert.Equal(t, common.ErrTokenInvalid, err)
+		_, err = service.LoginChallengeVerify(&dto.LoginChallengeVerifyRequest{ChallengeID: strings.Replace(id, ".email.", ".sms.", 1), Code: "123456"}, "8.8.8.8", userAgent)
+		assert.Equal(t, common.ErrTokenInvalid, err)
+
+		_, _, err = parseChallengeID(id, time.Now().Add(challengeTTL+time.Second))
+		assert.Equal(t, common.ErrTokenExpired, err)
+	})
+}
+
+// 测试受信任设备
+func TestService_TrustedDevice(t *testing.T) {
+	testutil.MockJWTConfig()
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.DefaultCost)
+	assert.NoError(t, err)
+
+	user := &entities.User{ID: 1, Email: "test@example.com", Password: string(hashedPassword), Status: "active", PasswordSet: true}
+	userAgent := "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 Chrome/120.0 Safari/537.36"
+	otherAgent := "Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:121.0) Gecko/20100101 Firefox/121.0"
+
+	newService := func() (*Service, *MockUserRepository, *MockVerificationService) {
+		userRepo := &MockUserRepository{}
+		verifyService := &MockVerificationService{}
+		ipinfoClient := &MockIPInfoClient{}
+		ipinfoClient.On("GetIPInfo", mock.Anything, mock.Anything).Return(&ipinfo.IPInfo{Country: "CN"}, nil)
+
+		service := &Service{
+			repo:                userRepo,
+			verificationService: verifyService,
+			ipinfoClient:        ipinfoClient,
+			trustedDeviceTTL:    30 * 24 * time.Hour,
+		}
+		return service, userRepo, verifyService
+	}
+
+	// 通过邮箱验证码登录并标记设备为受信任
+	trust := func(t *testing.T) (string, *entities.TrustedDevice) {
+		service, userRepo, verifyService := newService()
+		verifyService.On("VerifyCode", mock.Anything).Return(&verificationDto.VerifyCodeResponse{Valid: true}, nil)
+		userRepo.On("GetByEmail", "test@example.com").Return(user, nil)
+		userRepo.On("UpdateEmailVerified", int64(1), true).Return(nil)
+		userRepo.On("CreateRefreshToken", mock.AnythingOfType("*entities.RefreshToken")).Return(nil)
+		userRepo.On("UpdateLastLogin", int64(1)).Return(nil)
+		userRepo.On("CreateLoginSession", mock.AnythingOfType("*entities.LoginSession")).Return(nil)
+		userRepo.On("RevokeTrustedDevicesByDeviceID", int64(1), "device-1").Return(nil)
+
+		var stored *entities.TrustedDevice
+		userRepo.On("CreateTrustedDevice", mock.AnythingOfType("*entities.TrustedDevice")).Run(func(args mock.Arguments) {
+			stored = args.Get(0).(*entities.TrustedDevice)
+			stored.ID = 7
+		}).Return(nil)
+
+		resp, err := service.LoginVerify(&dto.LoginVerifyRequest{
+			Email:       "test@example.com",
+			Code:        "123456",
+			TrustDevice: true,
+			DeviceID:    "device-1",
+		}, "127.0.0.1", userAgent)
+
+		assert.NoError(t, err)
+		assert.NotEmpty(t, resp.AccessToken)
+		assert.NotEmpty(t, resp.DeviceTokenExpiresAt)
+		if assert.NotNil(t, stored) {
+			assert.Equal(t, hashDeviceToken(resp.DeviceToken), stored.TokenHash)
+			assert.Equal(t, "Chrome on macOS", stored.Name)
+		}
+		return resp.DeviceToken, stored
+	}
+
+	t.Run("受信任设备跳过邮箱验证码", func(t *testing.T) {
+		token, stored := trust(t)
+
+		service, userRepo, verifyService := newService()
+		userRepo.On("GetByEmail", "test@example.com").Return(user, nil)
+		userRepo.On("GetTrustedDeviceByTokenHash", hashDeviceToken(token)).Return(stored, nil)
+		userRepo.On("CreateRefreshToken", mock.AnythingOfType("*entities.RefreshToken")).Return(nil)
+		userRepo.On("UpdateLastLogin", int64(1)).Return(nil)
+		userRepo.On("CreateLoginSession", mock.MatchedBy(func(session *entities.LoginSession) bool {
+			return session.LoginMethod == "trusted_device" && session.IsTrusted
+		})).Return(nil)
+		userRepo.On("TouchTrustedDevice", int64(7)).Return(nil)
+
+		resp, err := service.Login(&dto.LoginRequest{
+			Email:       "test@example.com",
+			Password:    "password123",
+			DeviceID:    "device-1",
+			DeviceToken: token,
+		}, "127.0.0.1", userAgent)
+
+		assert.NoError(t, err)
+		if assert.NotNil(t, resp.Login) {
+			assert.NotEmpty(t, resp.Login.AccessToken)
+		}
+		verifyService.AssertNotCalled(t, "SendVerificationCode", mock.Anything)
+		userRepo.AssertExpectations(t)
+	})
+
+	t.Run("令牌复制到其他浏览器时回退到验证码", func(t *testing.T) {
+		token, _ := trust(t)
+
+		service, userRepo, verifyService := newService()
+		userRepo.On("GetByEmail", "test@example.com").Return(user, nil)
+		verifyService.On("SendVerificationCode", mock.Anything).Return(&verificationDto.SendVerificationResponse{}, nil)
+
+		resp, err := service.Login(&dto.LoginRequest{
+			Email:       "test@example.com",
+			Password:    "password123",
+			DeviceID:    "device-1",
+			DeviceToken: token,
+		}, "127.0.0.1", otherAgent)
+
+		assert.NoError(t, err)
+		assert.Nil(t, resp.Login)
+		assert.Equal(t, 600, resp.ExpiresIn)
+		userRepo.AssertNotCalled(t, "GetTrustedDeviceByTokenHash", mock.Anything)
+	})
+
+	t.Run("已撤销的设备回退到验证码", func(t *testing.T) {
+		token, _ := trust(t)
+
+		service, userRepo, verifyService := newService()
+		userRepo.On("GetByEmail", "test@example.com").Return(user, nil)
+		userRepo.On("GetTrustedDeviceByTokenHash", hashDeviceToken(token)).Return(nil, sql.ErrNoRows)
+		verifyService.On("SendVerificationCode", mock.Anything).Return(&verificationDto.SendVerificationResponse{}, nil)
+
+		resp, err := service.Login(&dto.LoginRequest{
+			Email:       "test@example.com",
+			Password:    "password123",
+			DeviceID:    "device-1",
+			DeviceToken: token,
+		}, "127.0.0.1", userAgent)
+
+		assert.NoError(t, err)
+		assert.Nil(t, resp.Login)
+		verifyService.AssertExpectations(t)
+	})
+
+	t.Run("撤销不存在的设备", func(t *testing.T) {
+		service, userRepo, _ := newService()
+		userRepo.On("RevokeTrustedDevice", int64(1), int64(99)).Return(sql.ErrNoRows)
+
+		assert.Equal(t, common.ErrNotFound, service.RevokeTrustedDevice(1, 99))
+	})
+
+	t.Run("设备令牌校验", func(t *testing.T) {
+		expiresAt := time.Now().Add(time.Hour)
+		token, err := newDeviceToken(1, "device-1", "fp", expiresAt)
+		assert.NoError(t, err)
+
+		userID, err := parseDeviceToken(token, "device-1", "fp", time.Now())
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), userID)
+
+		_, err = parseDeviceToken(token, "device-2", "fp", time.Now())
+		assert.Equal(t, common.ErrTokenInvalid, err)
+
+		_, err = parseDeviceToken("2"+token[1:], "device-1", "fp", time.Now())
+		assert.Equal(t, common.ErrTokenInvalid, err)
+
+		_, err = parseDeviceToken(token, "device-1", "fp", expiresAt.Add(time.Second))
+		assert.Equal(t, common.ErrTokenExpired, err)
+	})
+}
+
+// fakeImageService 模拟图片服务，images 中的图片属于 owner
+type fakeImageService struct {
+	owner  int
+	images map[int]string
+	err    error
+}
+
+func (f *fakeImageService) UploadImage(ctx context.Context, userID *int, file *multipart.FileHeader, req imagesDto.UploadImageRequest) (*imagesDto.UploadImageResponse, error) {
+	return &imagesDto.UploadImageResponse{ID: 99, Key: req.Folder + "/" + file.Filename, IsPublic: req.IsPublic}, nil
+}
+
+func (f *fakeImageService) GetUserImage(ctx context.Context, userID int, imageID int) (*imagesDto.GetImageResponse, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	key, ok := f.images[imageID]
+	if !ok {
+		return nil, fmt.Errorf("failed to get image: %w", common.ErrNotFound)
+	}
+	if userID != f.owner {
+		return nil, fmt.Errorf("image belongs to another user: %w", common.ErrNotFound)
+	}
+	return &imagesDto.GetImageResponse{ID: imageID, Key: key}, nil
+}
+
+// 测试修改资料与头像
+func TestService_UpdateProfile(t *testing.T) {
+	name := "  Bob "
+	imageID := 5
+	otherImageID := 6
+
+	newService := func(owner int) (*Service, *MockUserRepository) {
+		userRepo := &MockUserRepository{}
+		userRepo.On("GetByID", int64(1)).Return(&entities.User{ID: 1, Name: "Alice", ImageKey: "old.png"}, nil)
+		service := &Service{
+			repo:         userRepo,
+			imageService: &fakeImageService{owner: owner, images: map[int]string{5: "avatars/bob.png"}},
+		}
+		return service, userRepo
+	}
+
+	t.Run("修改姓名和头像", func(t *testing.T) {
+		service, userRepo := newService(1)
+		userRepo.On("Update", mock.MatchedBy(func(user *entities.User) bool {
+			return user.Name == "Bob" && user.ImageKey == "avatars/bob.png" && user.ProfileCompleted
+		})).Return(nil)
+
+		resp, err := service.UpdateProfile(context.Background(), 1, &dto.UpdateProfileRequest{Name: &name, ImageID: &imageID})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "avatars/bob.png", resp.User.ImageKey)
+		userRepo.AssertExpectations(t)
+	})
+
+	t.Run("不能使用他人的图片作为头像", func(t *testing.T) {
+		service, userRepo := newService(2)
+
+		_, err := service.UpdateProfile(context.Background(), 1, &dto.UpdateProfileRequest{ImageID: &imageID})
+		assert.Equal(t, common.ErrNotFound, err)
+
+		_, err = service.UpdateProfile(context.Background(), 1, &dto.UpdateProfileRequest{ImageID: &otherImageID})
+		assert.Equal(t, common.ErrNotFound, err)
+		userRepo.AssertNotCalled(t, "Update", mock.Anything)
+	})
+
+	t.Run("图片服务的其他错误原样返回", func(t *testing.T) {
+		service, userRepo := newService(1)
+		dbErr := errors.New("connection refused")
+		service.imageService = &fakeImageService{err: dbErr}
+
+		_, err := service.UpdateProfile(context.Background(), 1, &dto.UpdateProfileRequest{ImageID: &imageID})
+		assert.ErrorIs(t, err, dbErr)
+		assert.NotErrorIs(t, err, common.ErrNotFound)
+		userRepo.AssertNotCalled(t, "Update", mock.Anything)
+	})
+
+	t.Run("上传头像", func(t *testing.T) {
+		service, userRepo := newService(1)
+		userRepo.On("Update", mock.MatchedBy(func(user *entities.User) bool {
+			return user.ImageKey == "avatars/me.png"
+		})).Return(nil)
+
+		resp, err := service.UploadAvatar(context.Background(), 1, &multipart.FileHeader{Filename: "me.png"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "avatars/me.png", resp.User.ImageKey)
+	})
+}
+
+// 测试同时登录会话上限
+// fakeReferralService 记录邀请关系和发放奖励的调用，validCode 之外的邀请码无效
+type fakeReferralService struct {
+	validCode string
+	signups   []*referral.Signup
+	qualified []string
+}
+
+func (f *fakeReferralService) Validate(ctx context.Context, code string) error {
+	if code != f.validCode {
+		return common.ErrReferralCodeInvalid
+	}
+	return nil
+}
+
+func (f *fakeReferralService) Attach(ctx context.Context, signup *referral.Signup) (*referralEntities.Referral, error) {
+	f.signups = append(f.signups, signup)
+	return &referralEntities.Referral{RefereeID: signup.RefereeID}, nil
+}
+
+func (f *fakeReferralService) Qualify(ctx context.Context, userID int64, action string) error {
+	f.qualified = append(f.qualified, action)
+	return nil
+}
+
+func TestService_RegisterWithReferral(t *testing.T) {
+	testutil.MockJWTConfig()
+
+	t.Run("邀请码无效时不创建用户", func(t *testing.T) {
+		userRepo := &MockUserRepository{}
+		userRepo.On("GetByEmail", "new@example.com").Return(nil, sql.ErrNoRows)
+		referrals := &fakeReferralService{validCode: "ABCD2345"}
+		service := &Service{repo: userRepo, referrals: referrals}
+
+		_, err := service.Register(&dto.RegisterRequest{Email: "new@example.com", Password: "password123", ReferralCode: "WRONG"}, "127.0.0.1", "test-agent")
+		assert.Equal(t, common.ErrReferralCodeInvalid, err)
+		userRepo.AssertNotCalled(t, "Create", mock.Anything)
+		assert.Empty(t, referrals.signups)
+	})
+
+	t.Run("注册后记录邀请关系", func(t *testing.T) {
+		userRepo := &MockUserRepository{}
+		userRepo.On("GetByEmail", "new@example.com").Return(nil, sql.ErrNoRows)
+		userRepo.On("Create", mock.AnythingOfType("*entities.User")).Return(nil)
+		referrals := &fakeReferralService{validCode: "ABCD2345"}
+		service := &Service{repo: userRepo, referrals: referrals}
+
+		_, err := service.Register(&dto.RegisterRequest{Email: "new@example.com", Password: "password123", ReferralCode: "ABCD2345", DeviceID: "device-1"}, "127.0.0.1", "test-agent")
+		assert.NoError(t, err)
+		if !assert.Len(t, referrals.signups, 1) {
+			return
+		}
+		assert.Equal(t, "127.0.0.1", referrals.signups[0].IP)
+		assert.Equal(t, "device-1", referrals.signups[0].DeviceID)
+		assert.Empty(t, referrals.qualified, "邮箱验证前不发放奖励")
+	})
+}
+
+func TestService_SessionLimit(t *testing.T) {
+	testutil.MockJWTConfig()
+
+	user := &entities.User{ID: 1, Email: "test@example.com", Status: "active"}
+	active := []*entities.RefreshToken{
+		{ID: 11, UserID: 1, DeviceInfo: "Mozilla/5.0 (Windows NT 10.0) Chrome/120.0", CreatedAt: time.Now().Add(-48 * time.Hour)},
+		{ID: 12, UserID: 1, DeviceInfo: "okhttp/4.9", CreatedAt: time.Now().Add(-time.Hour)},
+	}
+
+	t.Run("超过上限踢出最早的会话并通知用户", func(t *testing.T) {
+		userRepo := &MockUserRepository{}
+		outbox := &fakeOutbox{}
+		userRepo.On("ListActiveRefreshTokens", int64(1)).Return(active, nil)
+		userRepo.On("EvictRefreshTokens", int64(1), []int64{11}).Return(nil)
+		userRepo.On("CreateRefreshToken", mock.AnythingOfType("*entities.RefreshToken")).Return(nil)
+
+		service := &Service{
+			repo:           userRepo,
+			mailOutbox:     outbox,
+			sessionLimiter: sessionlimit.NewLimiter(&sessionlimit.Config{MaxUserSessions: 2, Policy: sessionlimit.PolicyEvictOldest}),
+		}
+		_, _, err := service.issueTokenPair(user, "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0) Safari/604.1")
+
+		assert.NoError(t, err)
+		userRepo.AssertExpectations(t)
+		if assert.Len(t, outbox.messages, 1) {
+			assert.Contains(t, outbox.messages[0].TextBody, "Chrome / Windows")
+			assert.Contains(t, outbox.messages[0].TextBody, "at most 2 devices")
+		}
+	})
+
+	t.Run("拒绝策略不签发新令牌", func(t *testing.T) {
+		userRepo := &MockUserRepository{}
+		userRepo.On("ListActiveRefreshTokens", int64(1)).Return(active, nil)
+
+		service := &Service{
+			repo:           userRepo,
+			sessionLimiter: sessionlimit.NewLimiter(&sessionlimit.Config{MaxUserSessions: 2, Policy: sessionlimit.PolicyReject}),
+		}
+		_, _, err := service.issueTokenPair(user, "test-agent")
+
+		assert.Equal(t, common.ErrSessionLimitReached, err)
+		userRepo.AssertNotCalled(t, "CreateRefreshToken", mock.Anything)
+	})
+
+	t.Run("被踢出的会话刷新令牌时返回明确错误", func(t *testing.T) {
+		userRepo := &MockUserRepository{}
+		userRepo.On("GetValidRefreshToken", "evicted-token").Return(nil, sql.ErrNoRows)
+		userRepo.On("IsRefreshTokenEvicted", "evicted-token").Return(true, nil)
+		userRepo.On("GetValidRefreshToken", "unknown-token").Return(nil, sql.ErrNoRows)
+		userRepo.On("IsRefreshTokenEvicted", "unknown-token").Return(false, nil)
+
+		service := &Service{repo: userRepo, sessionLimiter: sessionlimit.NewLimiter(nil)}
+
+		_, err := service.RefreshToken(&dto.RefreshTokenRequest{RefreshToken: "evicted-token"})
+		assert.Equal(t, common.ErrSessionEvicted, err)
+		_, err = service.RefreshToken(&dto.RefreshTokenRequest{RefreshToken: "unknown-token"})
+		assert.Equal(t, common.ErrRefreshTokenInvalid, err)
+	})
+}
+
+// 测试修改密码
+func TestService_ChangePassword(t *testing.T) {
+	testutil.MockJWTConfig()
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.DefaultCost)
+	assert.NoError(t, err)
+
+	t.Run("修改成功并使其他会话失效", func(t *testing.T) {
+		userRepo := &MockUserRepository{}
+		userRepo.On("GetByID", int64(1)).Return(&entities.User{ID: 1, Email: "test@example.com", Password: string(hashedPassword), PasswordSet: true}, nil)
+		userRepo.On("UpdatePassword", int64(1), mock.MatchedBy(func(hash string) bool {
+			return bcrypt.CompareHashAndPassword([]byte(hash), []byte("newpassword")) == nil
+		})).Return(nil)
+		userRepo.On("InvalidateAllRefreshTokens", int64(1)).Return(nil)
+		userRepo.On("RevokeAllTrustedDevices", int64(1)).Return(nil)
+		userRepo.On("CreateRefreshToken", mock.AnythingOfType("*entities.RefreshToken")).Return(nil)
+
+		service := &Service{repo: userRepo}
+		resp, err := service.ChangePassword(1, &dto.ChangePasswordRequest{CurrentPassword: "password123", NewPassword: "newpassword"}, "test-agent")
+
+		assert.NoError(t, err)
+		assert.NotEmpty(t, resp.AccessToken)
+		assert.NotEmpty(t, resp.RefreshToken)
+		userRepo.AssertExpectations(t)
+	})
+
+	t.Run("当前密码错误", func(t *testing.T) {
+		userRepo := &MockUserRepository{}
+		userRepo.On("GetByID", int64(1)).Return(&entities.User{ID: 1, Password: string(hashedPassword), PasswordSet: true}, nil)
+
+		service := &Service{repo: userRepo}
+		_, err := service.ChangePassword(1, &dto.ChangePasswordRequest{CurrentPassword: "wrong", NewPassword: "newpassword"}, "test-agent")
+
+		assert.Equal(t, common.ErrInvalidCredentials, err)
+		userRepo.AssertNotCalled(t, "UpdatePassword", mock.Anything, mock.Anything)
+	})
+
+	t.Run("未设置密码的账户", func(t *testing.T) {
+		userRepo := &MockUserRepository{}
+		userRepo.On("GetByID", int64(1)).Return(&entities.User{ID: 1, AutoRegistered: true}, nil)
+
+		service := &Service{repo: userRepo}
+		_, err := service.ChangePassword(1, &dto.ChangePasswordRequest{CurrentPassword: "x", NewPassword: "newpassword"}, "test-agent")
+
+		assert.Equal(t, common.ErrPasswordNotSet, err)
+	})
+
+	t.Run("新密码不满足密码策略", func(t *testing.T) {
+		userRepo := &MockUserRepository{}
+		userRepo.On("GetByID", int64(1)).Return(&entities.User{ID: 1, Email: "test@example.com", Password: string(hashedPassword), PasswordSet: true}, nil)
+		userRepo.On("ListPasswordHistory", int64(1), 3).Return([]string{}, nil)
+
+		service := &Service{repo: userRepo, passwordPolicy: password.NewPolicy(&password.Config{MinLength: 8, RequireDigit: true, ForbidEmail: true, HistorySize: 3}, nil)}
+		_, err := service.ChangePassword(1, &dto.ChangePasswordRequest{CurrentPassword: "password123", NewPassword: "testpassword"}, "test-agent")
+
+		reasons, ok := password.Violations(err)
+		assert.True(t, ok)
+		assert.Equal(t, []password.Violation{
+			{Code: password.ReasonMissingDigit, Message: "Password must contain a digit"},
+			{Code: password.ReasonContainsEmail, Message: "Password must not contain your email address"},
+		}, reasons)
+		userRepo.AssertNotCalled(t, "UpdatePassword", mock.Anything, mock.Anything)
+	})
+
+	t.Run("拒绝历史密码并记录新密码", func(t *testing.T) {
+		oldHash, err := bcrypt.GenerateFromPassword([]byte("oldpassword1"), bcrypt.MinCost)
+		assert.NoError(t, err)
+
+		userRepo := &MockUserRepository{}
+		userRepo.On("GetByID", int64(1)).Return(&entities.User{ID: 1, Email: "test@example.com", Password: string(hashedPassword), PasswordSet: true}, nil)
+		userRepo.On("ListPasswordHistory", int64(1), 3).Return([]string{string(oldHash)}, nil)
+		userRepo.On("UpdatePassword", int64(1), mock.AnythingOfType("string")).Return(nil)
+		userRepo.On("AddPasswordHistory", int64(1), mock.MatchedBy(func(hash string) bool {
+			return bcrypt.CompareHashAndPassword([]byte(hash), []byte("newpassword1")) == nil
+		}), 3).Return(nil)
+		userRepo.On("InvalidateAllRefreshTokens", int64(1)).Return(nil)
+		userRepo.On("RevokeAllTrustedDevices", int64(1)).Return(nil)
+		userRepo.On("CreateRefreshToken", mock.AnythingOfType("*entities.RefreshToken")).Return(nil)
+
+		service := &Service{repo: userRepo, passwordPolicy: password.NewPolicy(&password.Config{MinLength: 8, HistorySize: 3}, nil)}
+		_, err = service.ChangePassword(1, &dto.ChangePasswordRequest{CurrentPassword: "password123", NewPassword: "oldpassword1"}, "test-agent")
+		reasons, _ := password.Violations(err)
+		assert.Equal(t, password.ReasonReused, reasons[0].Code)
+
+		_, err = service.ChangePassword(1, &dto.ChangePasswordRequest{CurrentPassword: "password123", NewPassword: "newpassword1"}, "test-agent")
+		assert.NoError(t, err)
+		userRepo.AssertExpectations(t)
+	})
+}
+
+// 测试重新验证身份
+func TestService_Reauthenticate(t *testing.T) {
+	testutil.MockJWTConfig()
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.DefaultCost)
+	assert.NoError(t, err)
+	user := &entities.User{ID: 1, Email: "test@example.com", Role: "user", Status: "active", Password: string(hashedPassword), PasswordSet: true}
+
+	t.Run("密码验证签发提升令牌", func(t *testing.T) {
+		userRepo := &MockUserRepository{}
+		userRepo.On("GetByID", int64(1)).Return(user, nil)
+
+		service := &Service{repo: userRepo}
+		resp, err := service.Reauthenticate(1, &dto.ReauthRequest{Password: "password123"}, "10.0.0.1")
+
+		assert.NoError(t, err)
+		assert.Equal(t, auth.ACRPassword, resp.ACR)
+		claims, err := auth.ValidateAccessToken(resp.AccessToken)
+		assert.NoError(t, err)
+		assert.Equal(t, resp.AuthTime, claims.AuthTime)
+		assert.Equal(t, "user", claims.UserType)
+	})
+
+	t.Run("验证码验证签发提升令牌", func(t *testing.T) {
+		userRepo := &MockUserRepository{}
+		userRepo.On("GetByID", int64(1)).Return(&entities.User{ID: 1, Email: "test@example.com", Status: "active", AutoRegistered: true}, nil)
+		verifyService := &MockVerificationService{}
+		verifyService.On("VerifyCode", &verificationDto.VerifyCodeRequest{Target: "test@example.com", Code: "123456", Type: "user_reauth"}).
+			Return(&verificationDto.VerifyCodeResponse{Valid: true}, nil)
+
+		service := &Service{repo: userRepo, verificationService: verifyService}
+		resp, err := service.Reauthenticate(1, &dto.ReauthRequest{Code: "123456"}, "10.0.0.1")
+
+		assert.NoError(t, err)
+		assert.Equal(t, auth.ACREmailCode, resp.ACR)
+		verifyService.AssertExpectations(t)
+	})
+
+	t.Run("密码错误", func(t *testing.T) {
+		userRepo := &MockUserRepository{}
+		userRepo.On("GetByID", int64(1)).Return(user, nil)
+
+		service := &Service{repo: userRepo}
+		_, err := service.Reauthenticate(1, &dto.ReauthRequest{Password: "wrong"}, "10.0.0.1")
+
+		assert.Equal(t, common.ErrInvalidCredentials, err)
+	})
+
+	t.Run("密码错误与登录共用失败计数和锁定", func(t *testing.T) {
+		userRepo := &MockUserRepository{}
+		userRepo.On("GetByID", int64(1)).Return(user, nil)
+		guard := &fakeLoginGuard{}
+
+		service := &Service{repo: userRepo, loginGuard: guard}
+		_, err := service.Reauthenticate(1, &dto.ReauthRequest{Password: "wrong"}, "10.0.0.1")
+		assert.Equal(t, common.ErrInvalidCredentials, err)
+		assert.Equal(t, []string{"test@example.com|10.0.0.1"}, guard.fails)
+
+		_, err = service.Reauthenticate(1, &dto.ReauthRequest{Password: "password123"}, "10.0.0.1")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"test@example.com"}, guard.resets)
+
+		// 锁定期间不校验密码
+		guard.wait = 90500 * time.Millisecond
+		resp, err := service.Reauthenticate(1, &dto.ReauthRequest{Password: "password123"}, "10.0.0.1")
+		assert.Equal(t, common.ErrLoginLocked, err)
+		assert.Equal(t, 91, resp.RetryAfter)
+		assert.Len(t, guard.fails, 1)
+	})
+
+	t.Run("密码和验证码必须二选一", func(t *testing.T) {
+		service := &Service{repo: &MockUserRepository{}}
+		_, err := service.Reauthenticate(1, &dto.ReauthRequest{}, "10.0.0.1")
+
+		assert.Equal(t, common.ErrValidation, err)
+	})
+
+	t.Run("账户已暂停", func(t *testing.T) {
+		userRepo := &MockUserRepository{}
+		userRepo.On("GetByID", int64(1)).Return(&entities.User{ID: 1, Status: "suspended", Password: string(hashedPassword), PasswordSet: true}, nil)
+
+		service := &Service{repo: userRepo}
+		_, err := service.Reauthenticate(1, &dto.ReauthRequest{Password: "password123"}, "10.0.0.1")
+
+		assert.Equal(t, common.ErrUserSuspended, err)
+	})
+}
+
+// 测试修改邮箱
+func TestService_EmailChange(t *testing.T) {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.DefaultCost)
+	assert.NoError(t, err)
+
+	newService := func() (*Service, *MockUserRepository, *MockVerificationService, *fakeOutbox) {
+		userRepo := &MockUserRepository{}
+		verifyService := &MockVerificationService{}
+		outbox := &fakeOutbox{}
+		userRepo.On("GetByID", int64(1)).Return(&entities.User{ID: 1, Email: "old@example.com", Password: string(hashedPassword), PasswordSet: true}, nil)
+		return &Service{repo: userRepo, verificationService: verifyService, mailOutbox: outbox}, userRepo, verifyService, outbox
+	}
+
+	t.Run("向新邮箱发送验证码", func(t *testing.T) {
+		service, userRepo, verifyService, _ := newService()
+		userRepo.On("GetByEmail", "new@example.com").Return(nil, sql.ErrNoRows)
+		verifyService.On("SendVerificationCode", mock.MatchedBy(func(req *verificationDto.SendVerificationRequest) bool {
+			return req.Target == "new@example.com" && req.Type == "user_email_change" && req.UserID == 1
+		})).Return(&verificationDto.SendVerificationResponse{}, nil)
+
+		resp, err := service.RequestEmailChange(1, &dto.ChangeEmailRequest{NewEmail: "new@example.com", Password: "password123"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "new@example.com", resp.NewEmail)
+		verifyService.AssertExpectations(t)
+	})
+
+	t.Run("密码错误不发送验证码", func(t *testing.T) {
+		service, _, verifyService, _ := newService()
+
+		_, err := service.RequestEmailChange(1, &dto.ChangeEmailRequest{NewEmail: "new@example.com", Password: "wrong"})
+
+		assert.Equal(t, common.ErrInvalidCredentials, err)
+		verifyService.AssertNotCalled(t, "SendVerificationCode", mock.Anything)
+	})
+
+	t.Run("邮箱已被占用", func(t *testing.T) {
+		service, userRepo, _, _ := newService()
+		userRepo.On("GetByEmail", "taken@example.com").Return(&entities.User{ID: 2}, nil)
+
+		_, err := service.RequestEmailChange(1, &dto.ChangeEmailRequest{NewEmail: "taken@example.com", Password: "password123"})
+
+		assert.Equal(t, common.ErrEmailExists, err)
+	})
+
+	t.Run("验证后切换邮箱并通知原邮箱", func(t *testing.T) {
+		service, userRepo, verifyService, outbox := newService()
+		userRepo.On("GetByEmail", "new@example.com").Return(nil, sql.ErrNoRows)
+		verifyService.On("VerifyCode", mock.MatchedBy(func(req *verificationDto.VerifyCodeRequest) bool {
+			return req.Target == "new@example.com" && req.Type == "user_email_change" && req.UserID == 1
+		})).Return(&verificationDto.VerifyCodeResponse{Valid: true}, nil)
+		userRepo.On("Update", mock.MatchedBy(func(user *entities.User) bool {
+			return user.Email == "new@example.com" && user.EmailVerified
+		})).Return(nil)
+
+		resp, err := service.VerifyEmailChange(1, &dto.ChangeEmailVerifyRequest{NewEmail: "new@example.com", Code: "123456"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "new@example.com", resp.User.Email)
+		if assert.Len(t, outbox.messages, 1) {
+			assert.Equal(t, "old@example.com", outbox.messages[0].To)
+			assert.Contains(t, outbox.messages[0].TextBody, "new@example.com")
+		}
+	})
+
+	t.Run("其他账户请求的验证码不能切换本账户邮箱", func(t *testing.T) {
+		service, userRepo, verifyService, _ := newService()
+		verifyService.On("VerifyCode", mock.MatchedBy(func(req *verificationDto.VerifyCodeRequest) bool {
+			return req.UserID == 1
+		})).Return(nil, common.ErrCodeExpired)
+
+		_, err := service.VerifyEmailChange(1, &dto.ChangeEmailVerifyRequest{NewEmail: "new@example.com", Code: "123456"})
+
+		assert.Equal(t, common.ErrCodeExpired, err)
+		userRepo.AssertNotCalled(t, "Update", mock.Anything)
+	})
+}
+
+// 测试忘记密码只向可登录的账户发送验证码
+func TestService_ForgotPassword(t *testing.T) {
+	past := time.Now().Add(-time.Minute)
+	future := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name string
+		user *entities.User
+		sent bool
+	}{
+		{"激活用户", &entities.User{ID: 1, Status: "active"}, true},
+		{"暂停已到期", &entities.User{ID: 1, Status: "suspended", SuspendedUntil: &past}, true},
+		{"暂停未到期", &entities.User{ID: 1, Status: "suspended", SuspendedUntil: &future}, false},
+		{"未激活", &entities.User{ID: 1, Status: "inactive"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userRepo := &MockUserRepository{}
+			verifyService := &MockVerificationService{}
+			userRepo.On("GetByEmail", "test@example.com").Return(tt.user, nil)
+			verifyService.On("SendVerificationCode", mock.MatchedBy(func(req *verificationDto.SendVerificationRequest) bool {
+				return req.Target == "test@example.com" && req.Type == "forgot_password"
+			})).Return(&verificationDto.SendVerificationResponse{}, nil)
+
+			service := &Service{repo: userRepo, verificationService: verifyService}
+			_, err := service.ForgotPassword(&dto.ForgotPasswordRequest{Email: "test@example.com"})
+
+			assert.NoError(t, err)
+			if tt.sent {
+				verifyService.AssertExpectations(t)
+			} else {
+				verifyService.AssertNotCalled(t, "SendVerificationCode", mock.Anything)
+			}
+		})
+	}
+}
+
+func TestUserStatusError(t *testing.T) {
+	past := time.Now().Add(-time.Minute)
+	future := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name     string
+		user     *entities.User
+		expected error
+	}{
+		{"激活用户", &entities.User{Status: "active"}, nil},
+		{"无限期暂停", &entities.User{Status: "suspended"}, common.ErrUserSuspended},
+		{"暂停未到期", &entities.User{Status: "suspended", SuspendedUntil: &future}, common.ErrUserSuspended},
+		{"暂停已到期", &entities.User{Status: "suspended", SuspendedUntil: &past}, nil},
+		{"未激活", &entities.User{Status: "inactive"}, common.ErrUserInactive},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, userStatusError(tt.user))
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package user_auth
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"trusioo_api/internal/auth/sessionlimit"
+	"trusioo_api/internal/auth/user_auth/entities"
+)
+
+// admitSession 签发新的刷新令牌前执行同时登录会话上限
+// 按策略拒绝新登录，或使最早的会话失效并邮件通知用户
+func (s *Service) admitSession(user *entities.User, userAgent string) error {
+	evicted, err := s.identityCore().AdmitSession(user.ID)
+	if err != nil {
+		return err
+	}
+	if len(evicted) > 0 {
+		s.sendSessionEvictedEmail(user, evicted, userAgent)
+	}
+	return nil
+}
+
+// sendSessionEvictedEmail 通知用户哪些设备因新登录被踢出
+func (s *Service) sendSessionEvictedEmail(user *entities.User, evicted []sessionlimit.Session, userAgent string) {
+	if s.mailOutbox == nil {
+		return
+	}
+
+	msg, err := s.identityCore().SessionEvictedMessage(user.Email, s.mailLocale, evicted, userAgent)
+	if err != nil {
+		log.Printf("渲染会话踢出通知失败: %v", err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.mailOutbox.Enqueue(ctx, msg); err != nil {
+		log.Printf("发送会话踢出通知失败 %d: %v", user.ID, err)
+	}
+}
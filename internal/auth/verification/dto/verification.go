@@ -3,14 +3,17 @@ package dto
 // SendVerificationRequest 发送验证码请求
 type SendVerificationRequest struct {
 	Target string `json:"target" binding:"required,email" validate:"required,email"`
-	Type   string `json:"type" binding:"required" validate:"required,oneof=admin_login user_login forgot_password admin_forgot_password buyer_login buyer_forgot_password"`
+	Type   string `json:"type" binding:"required" validate:"required,oneof=admin_login user_login user_code_login forgot_password admin_forgot_password buyer_login buyer_forgot_password"`
+	Locale string `json:"locale,omitempty"` // 邮件/短信语言，如 en、zh-CN；为空时使用默认语言
+	UserID int64  `json:"-"`                // 不为0时验证码只能由该用户验证，用于已登录用户修改邮箱、绑定手机号
 }
 
 // VerifyCodeRequest 验证验证码请求
 type VerifyCodeRequest struct {
 	Target string `json:"target" binding:"required,email" validate:"required,email"`
-	Type   string `json:"type" binding:"required" validate:"required,oneof=admin_login user_login forgot_password admin_forgot_password buyer_login buyer_forgot_password"`
+	Type   string `json:"type" binding:"required" validate:"required,oneof=admin_login user_login user_code_login forgot_password admin_forgot_password buyer_login buyer_forgot_password"`
 	Code   string `json:"code" binding:"required,len=6" validate:"required,len=6"`
+	UserID int64  `json:"-"` // 与发送时的 UserID 一致才能通过验证
 }
 
 // SendVerificationResponse 发送验证码响应
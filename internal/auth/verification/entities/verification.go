@@ -21,6 +21,11 @@ const (
 	TypeRegister       = "register"
 	TypeResetPassword  = "reset_password"
 	TypeForgotPassword = "forgot_password"
+	TypePhoneBind      = "phone_bind"       // 绑定手机号（短信）
+	TypePhoneLogin     = "user_phone_login" // 手机号验证码登录（短信）
+
+	TypeLoginChallenge    = "user_login_challenge"     // 可疑登录的额外验证（邮件）
+	TypeLoginChallengeSMS = "user_login_challenge_sms" // 可疑登录的额外验证（短信）
 )
 
 // VerificationAction 验证码动作常量
@@ -93,6 +93,16 @@ func (r *Repository) MarkVerificationAsUsed(id int64) error {
 	return nil
 }
 
+// InvalidateActiveVerifications 将目标下尚未使用的验证码记录全部标记为已使用
+func (r *Repository) InvalidateActiveVerifications(target, verifyType string) error {
+	query := `UPDATE verifications SET is_used = true WHERE target = $1 AND type = $2 AND is_used = false`
+	_, err := r.db.Exec(query, target, verifyType)
+	if err != nil {
+		return fmt.Errorf("failed to invalidate verifications: %w", err)
+	}
+	return nil
+}
+
 // DeleteExpiredVerifications 删除过期的验证码
 func (r *Repository) DeleteExpiredVerifications() error {
 	query := `DELETE FROM verifications WHERE expired_at < NOW()`
@@ -1,31 +1,123 @@
 package verification
 
 import (
+	"context"
 	"crypto/rand"
+	"crypto/subtle"
 	"fmt"
 	"log"
 	"math/big"
+	"strconv"
 	"time"
 
+	"trusioo_api/config"
 	"trusioo_api/internal/auth/verification/dto"
 	"trusioo_api/internal/auth/verification/entities"
+	"trusioo_api/internal/common"
+	"trusioo_api/pkg/database"
+	"trusioo_api/pkg/mailer"
+	"trusioo_api/pkg/redis"
+	"trusioo_api/pkg/sms"
 )
 
+// AuditRepository 验证码审计记录接口（Postgres仅用于留档，不参与校验）
+type AuditRepository interface {
+	CreateVerification(verification *entities.Verification) error
+	InvalidateActiveVerifications(target, verifyType string) error
+	DeleteExpiredVerifications() error
+}
+
+// CodeStore 验证码存储接口，由 redis.VerificationCache 实现
+type CodeStore interface {
+	StoreVerificationCode(target, vType, code string, expiry time.Duration) error
+	GetVerificationCode(target, vType string) (string, error)
+	DeleteVerificationCode(target, vType string) error
+	ClaimSendSlot(target, vType string, cooldown time.Duration) (bool, error)
+	ReleaseSendSlot(target, vType string) error
+	IncrementAttemptCount(target, vType string, expiry time.Duration) (int, error)
+	ClearAttemptCount(target, vType string) error
+	IsBlocked(target, vType string, maxAttempts int) (bool, error)
+	IncrementSendCount(target string, window time.Duration) (int, error)
+}
+
+// EmailOutbox 邮件发件箱接口，由 mailer.Outbox 实现
+type EmailOutbox interface {
+	Enqueue(ctx context.Context, msg *mailer.Message) error
+}
+
+// SMSSender 短信发送接口，由 sms.LogSender 等实现
+type SMSSender interface {
+	Send(ctx context.Context, msg *sms.Message) error
+}
+
 type Service struct {
-	repo *Repository
+	repo          AuditRepository
+	store         CodeStore
+	outbox        EmailOutbox
+	smsSender     SMSSender
+	smsCfg        *sms.Config
+	cfg           config.VerificationConfig
+	defaultLocale string
 }
 
 func NewService() *Service {
+	smsCfg := sms.NewConfigFromApp(config.AppConfig)
+	smsSender, err := sms.NewSender(smsCfg)
+	if err != nil {
+		log.Printf("短信驱动 %q 不可用，使用日志驱动: %v", smsCfg.Driver, err)
+		smsSender = sms.NewLogSender()
+	}
+
 	return &Service{
-		repo: NewRepository(),
+		repo:          NewRepository(),
+		store:         redis.NewVerificationCache(),
+		outbox:        mailer.NewOutbox(database.DB),
+		smsSender:     smsSender,
+		smsCfg:        smsCfg,
+		cfg:           config.AppConfig.Verification,
+		defaultLocale: config.AppConfig.Mail.DefaultLocale,
 	}
 }
 
-
-
 // SendVerificationCode 发送验证码
+// 同一目标同一类型在冷却期内不能重复发送；新验证码会覆盖并作废旧验证码
+// 短信类型的目标必须是 E.164 号码，并额外受单号码每小时/每天发送总量限制
 func (s *Service) SendVerificationCode(req *dto.SendVerificationRequest) (*dto.SendVerificationResponse, error) {
+	isSMS := IsSMSType(req.Type)
+	key := codeKey(req.Target, req.UserID)
+	if isSMS && !s.smsCfg.Allows(req.Target) {
+		return nil, common.ErrInvalidPhone
+	}
 
+	// 错误次数过多时不允许重新获取验证码，避免通过重发绕过锁定
+	blocked, err := s.store.IsBlocked(key, req.Type, s.cfg.MaxAttempts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check verification block: %w", err)
+	}
+	if blocked {
+		return nil, common.ErrCodeBlocked
+	}
+
+	// 原子地占用发送冷却期，之后任一步骤（包括短信发送或写入发件箱）失败都释放，让用户可以立即重试
+	claimed, err := s.store.ClaimSendSlot(key, req.Type, s.sendCooldown())
+	if err != nil {
+		return nil, fmt.Errorf("failed to check send frequency: %w", err)
+	}
+	if !claimed {
+		return nil, common.ErrCodeTooFrequent
+	}
+	sent := false
+	defer func() {
+		if !sent {
+			s.releaseSendSlot(key, req.Type)
+		}
+	}()
+
+	if isSMS {
+		if err := s.checkSMSSendLimit(req.Target); err != nil {
+			return nil, err
+		}
+	}
 
 	// 生成6位数字验证码
 	code, err := s.generateVerificationCode()
@@ -33,32 +125,49 @@ func (s *Service) SendVerificationCode(req *dto.SendVerificationRequest) (*dto.S
 		return nil, fmt.Errorf("failed to generate verification code: %w", err)
 	}
 
-	// 创建验证码记录
 	now := time.Now()
-	expiredAt := now.Add(10 * time.Minute) // 10分钟有效期
+	expiredAt := now.Add(s.codeExpire())
 
+	// 写入Redis，同一key覆盖即作废旧验证码
+	if err := s.store.StoreVerificationCode(key, req.Type, code, s.codeExpire()); err != nil {
+		return nil, fmt.Errorf("failed to store verification code: %w", err)
+	}
+
+	if isSMS {
+		if err := s.sendSMS(req.Target, code, req.Locale); err != nil {
+			return nil, fmt.Errorf("failed to send verification sms: %w", err)
+		}
+	} else {
+		// 写入发件箱，由后台投递器发送；投递失败会自动重试，不阻塞当前请求
+		if err := s.sendEmail(req.Target, code, req.Type, req.Locale); err != nil {
+			return nil, fmt.Errorf("failed to send verification email: %w", err)
+		}
+	}
+
+	// 发送成功后审计留档：作废旧记录并写入新记录，失败不影响验证码使用
+	if err := s.repo.InvalidateActiveVerifications(req.Target, req.Type); err != nil {
+		log.Printf("作废旧验证码记录失败 %s/%s: %v", req.Target, req.Type, err)
+	}
+	action := entities.ActionEmailVerification
+	if isSMS {
+		action = entities.ActionPhoneVerification
+	}
 	verification := &entities.Verification{
 		Target:    req.Target,
+		UserID:    userIDPtr(req.UserID),
 		Type:      req.Type,
-		Action:    entities.ActionEmailVerification,
+		Action:    action,
 		SentAt:    now,
 		Code:      code,
 		IsUsed:    false,
 		ExpiredAt: expiredAt,
 		CreatedAt: now,
 	}
-
-	err = s.repo.CreateVerification(verification)
-	if err != nil {
-		return nil, fmt.Errorf("failed to save verification: %w", err)
-	}
-
-	// 发送验证码邮件（这里暂时只记录日志，实际项目中需要集成邮件服务）
-	err = s.sendEmail(req.Target, code, req.Type)
-	if err != nil {
-		return nil, fmt.Errorf("failed to send verification email: %w", err)
+	if err := s.repo.CreateVerification(verification); err != nil {
+		log.Printf("保存验证码审计记录失败 %s/%s: %v", req.Target, req.Type, err)
 	}
 
+	sent = true
 	return &dto.SendVerificationResponse{
 		Message:   "Verification code sent successfully",
 		ExpiredAt: expiredAt.Format(time.RFC3339),
@@ -66,27 +175,59 @@ func (s *Service) SendVerificationCode(req *dto.SendVerificationRequest) (*dto.S
 	}, nil
 }
 
+// releaseSendSlot 发送失败时释放冷却期，释放失败只记录日志，冷却期到期后自动恢复
+func (s *Service) releaseSendSlot(target, verifyType string) {
+	if err := s.store.ReleaseSendSlot(target, verifyType); err != nil {
+		log.Printf("释放验证码发送冷却期失败 %s/%s: %v", target, verifyType, err)
+	}
+}
+
 // VerifyCode 验证验证码
+// 返回 common.ErrCodeBlocked（错误次数过多）、common.ErrCodeExpired（不存在或已过期）
+// 或 common.ErrInvalidCode（验证码错误）以便客户端展示对应提示
 func (s *Service) VerifyCode(req *dto.VerifyCodeRequest) (*dto.VerifyCodeResponse, error) {
-	// 获取有效的验证码
-	verification, err := s.repo.GetValidVerification(req.Target, req.Type, req.Code)
+	key := codeKey(req.Target, req.UserID)
+	blocked, err := s.store.IsBlocked(key, req.Type, s.cfg.MaxAttempts)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get verification: %w", err)
+		return nil, fmt.Errorf("failed to check verification block: %w", err)
 	}
-
-	if verification == nil {
-		return &dto.VerifyCodeResponse{
-			Message: "Invalid or expired verification code",
-			Valid:   false,
-		}, nil
+	if blocked {
+		return nil, common.ErrCodeBlocked
 	}
 
-	// 标记验证码为已使用
-	err = s.repo.MarkVerificationAsUsed(verification.ID)
+	stored, err := s.store.GetVerificationCode(key, req.Type)
 	if err != nil {
-		return nil, fmt.Errorf("failed to mark verification as used: %w", err)
+		return nil, fmt.Errorf("failed to get verification code: %w", err)
+	}
+	if stored == "" {
+		return nil, common.ErrCodeExpired
 	}
 
+	if subtle.ConstantTimeCompare([]byte(stored), []byte(req.Code)) != 1 {
+		count, err := s.store.IncrementAttemptCount(key, req.Type, s.blockDuration())
+		if err != nil {
+			return nil, fmt.Errorf("failed to record verification attempt: %w", err)
+		}
+		// 达到上限后作废当前验证码
+		if count >= s.cfg.MaxAttempts {
+			if err := s.store.DeleteVerificationCode(key, req.Type); err != nil {
+				log.Printf("作废验证码失败 %s/%s: %v", req.Target, req.Type, err)
+			}
+			return nil, common.ErrCodeBlocked
+		}
+		return nil, common.ErrInvalidCode
+	}
+
+	// 验证成功：验证码一次性使用
+	if err := s.store.DeleteVerificationCode(key, req.Type); err != nil {
+		return nil, fmt.Errorf("failed to consume verification code: %w", err)
+	}
+	if err := s.store.ClearAttemptCount(key, req.Type); err != nil {
+		log.Printf("清除验证失败次数失败 %s/%s: %v", req.Target, req.Type, err)
+	}
+	if err := s.repo.InvalidateActiveVerifications(req.Target, req.Type); err != nil {
+		log.Printf("更新验证码审计记录失败 %s/%s: %v", req.Target, req.Type, err)
+	}
 
 	return &dto.VerifyCodeResponse{
 		Message: "Verification code is valid",
@@ -107,27 +248,113 @@ func (s *Service) generateVerificationCode() (string, error) {
 	return code, nil
 }
 
-// sendEmail 发送验证码邮件（模拟实现）
-func (s *Service) sendEmail(target, code, verifyType string) error {
-	// 将验证码打印到控制台，方便测试
-	log.Printf("=== 验证码发送 ===")
-	log.Printf("邮箱: %s", target)
-	log.Printf("验证码: %s", code)
-	log.Printf("类型: %s", verifyType)
-	log.Printf("==================")
+// sendEmail 渲染验证码邮件并写入发件箱
+func (s *Service) sendEmail(target, code, verifyType, locale string) error {
+	msg, err := mailer.Render(target, emailTemplateFor(verifyType), locale, s.defaultLocale, mailer.TemplateData{
+		Email:            target,
+		Code:             code,
+		ExpiresInMinutes: int(s.codeExpire() / time.Minute),
+	})
+	if err != nil {
+		return err
+	}
 
-	// TODO: 集成真实的邮件服务
-	// 例如：SendGrid, AWS SES, 阿里云邮件推送等
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
 
-	// 模拟邮件发送可能的错误
-	if target == "" {
-		return fmt.Errorf("invalid email address")
-	}
+	return s.outbox.Enqueue(ctx, msg)
+}
 
+// checkSMSSendLimit 按号码统计所有类型的发送总量，防止短信轰炸
+func (s *Service) checkSMSSendLimit(phone string) error {
+	limits := []struct {
+		window time.Duration
+		max    int
+	}{
+		{time.Hour, s.smsCfg.HourlyLimit},
+		{24 * time.Hour, s.smsCfg.DailyLimit},
+	}
+	for _, l := range limits {
+		count, err := s.store.IncrementSendCount(phone, l.window)
+		if err != nil {
+			return fmt.Errorf("failed to check sms send limit: %w", err)
+		}
+		if count > l.max {
+			log.Printf("号码 %s 短信发送次数超限（%d/%s）", sms.MaskPhone(phone), count, l.window)
+			return common.ErrSendLimitExceeded
+		}
+	}
 	return nil
 }
 
+// sendSMS 同步发送验证码短信
+func (s *Service) sendSMS(phone, code, locale string) error {
+	msg := sms.CodeMessage(phone, code, int(s.codeExpire()/time.Minute), locale, s.defaultLocale)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	return s.smsSender.Send(ctx, msg)
+}
+
+// IsSMSType 判断验证码类型是否通过短信发送
+func IsSMSType(verifyType string) bool {
+	switch verifyType {
+	case entities.TypePhoneBind, entities.TypePhoneLogin, entities.TypeLoginChallengeSMS:
+		return true
+	default:
+		return false
+	}
+}
+
+// emailTemplateFor 根据验证码类型选择邮件模板
+func emailTemplateFor(verifyType string) string {
+	switch verifyType {
+	case "user_login", "user_code_login", entities.TypeLoginChallenge, "admin_login", "buyer_login":
+		return mailer.TemplateLoginCode
+	case entities.TypeForgotPassword, entities.TypeResetPassword, "admin_forgot_password", "buyer_forgot_password":
+		return mailer.TemplatePasswordReset
+	default:
+		return mailer.TemplateVerificationCode
+	}
+}
+
+func (s *Service) codeExpire() time.Duration {
+	return time.Duration(s.cfg.CodeExpire) * time.Second
+}
+
+// CodeExpiresIn 配置的验证码有效期（秒），发送验证码的接口以此返回 expires_in
+func CodeExpiresIn() int {
+	if config.AppConfig == nil || config.AppConfig.Verification.CodeExpire <= 0 {
+		return 600
+	}
+	return config.AppConfig.Verification.CodeExpire
+}
+
+func (s *Service) sendCooldown() time.Duration {
+	return time.Duration(s.cfg.SendCooldown) * time.Second
+}
+
+func (s *Service) blockDuration() time.Duration {
+	return time.Duration(s.cfg.BlockDuration) * time.Second
+}
+
 // CleanupExpiredVerifications 清理过期的验证码
 func (s *Service) CleanupExpiredVerifications() error {
 	return s.repo.DeleteExpiredVerifications()
 }
+
+// codeKey 验证码在 Redis 中的键，指定用户时加上用户ID，其他用户无法用同一目标的验证码通过验证
+func codeKey(target string, userID int64) string {
+	if userID == 0 {
+		return target
+	}
+	return strconv.FormatInt(userID, 10) + ":" + target
+}
+
+func userIDPtr(userID int64) *int64 {
+	if userID == 0 {
+		return nil
+	}
+	return &userID
+}
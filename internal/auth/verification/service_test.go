@@ -1,11 +1,16 @@
 package verification
 
 import (
+	"context"
 	"testing"
 	"time"
 
+	"trusioo_api/config"
 	"trusioo_api/internal/auth/verification/dto"
 	"trusioo_api/internal/auth/verification/entities"
+	"trusioo_api/internal/common"
+	"trusioo_api/pkg/mailer"
+	"trusioo_api/pkg/sms"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -32,6 +37,11 @@ func (m *MockRepository) MarkVerificationAsUsed(id int64) error {
 	return args.Error(0)
 }
 
+func (m *MockRepository) InvalidateActiveVerifications(target, verifyType string) error {
+	args := m.Called(target, verifyType)
+	return args.Error(0)
+}
+
 func (m *MockRepository) DeleteExpiredVerifications() error {
 	args := m.Called()
 	return args.Error(0)
@@ -303,4 +313,303 @@ func TestService_CleanupExpiredVerifications(t *testing.T) {
 			mockRepo.AssertExpectations(t)
 		})
 	}
-}
\ No newline at end of file
+}
+
+// fakeCodeStore 内存版验证码存储，模拟 redis.VerificationCache 的行为
+type fakeCodeStore struct {
+	storeErr error
+
+	codes    map[string]string
+	cooldown map[string]bool
+	attempts map[string]int
+	sends    map[string]int
+}
+
+func newFakeCodeStore() *fakeCodeStore {
+	return &fakeCodeStore{
+		codes:    map[string]string{},
+		cooldown: map[string]bool{},
+		attempts: map[string]int{},
+		sends:    map[string]int{},
+	}
+}
+
+func (f *fakeCodeStore) StoreVerificationCode(target, vType, code string, expiry time.Duration) error {
+	if f.storeErr != nil {
+		return f.storeErr
+	}
+	f.codes[target+":"+vType] = code
+	return nil
+}
+
+func (f *fakeCodeStore) GetVerificationCode(target, vType string) (string, error) {
+	return f.codes[target+":"+vType], nil
+}
+
+func (f *fakeCodeStore) DeleteVerificationCode(target, vType string) error {
+	delete(f.codes, target+":"+vType)
+	return nil
+}
+
+func (f *fakeCodeStore) ClaimSendSlot(target, vType string, cooldown time.Duration) (bool, error) {
+	if f.cooldown[target+":"+vType] {
+		return false, nil
+	}
+	f.cooldown[target+":"+vType] = true
+	return true, nil
+}
+
+func (f *fakeCodeStore) ReleaseSendSlot(target, vType string) error {
+	delete(f.cooldown, target+":"+vType)
+	return nil
+}
+
+func (f *fakeCodeStore) IncrementAttemptCount(target, vType string, expiry time.Duration) (int, error) {
+	f.attempts[target+":"+vType]++
+	return f.attempts[target+":"+vType], nil
+}
+
+func (f *fakeCodeStore) ClearAttemptCount(target, vType string) error {
+	delete(f.attempts, target+":"+vType)
+	return nil
+}
+
+func (f *fakeCodeStore) IsBlocked(target, vType string, maxAttempts int) (bool, error) {
+	return f.attempts[target+":"+vType] >= maxAttempts, nil
+}
+
+func (f *fakeCodeStore) IncrementSendCount(target string, window time.Duration) (int, error) {
+	key := target + ":" + window.String()
+	f.sends[key]++
+	return f.sends[key], nil
+}
+
+// fakeOutbox 记录写入发件箱的邮件
+type fakeOutbox struct {
+	err      error
+	messages []*mailer.Message
+}
+
+func (f *fakeOutbox) Enqueue(ctx context.Context, msg *mailer.Message) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.messages = append(f.messages, msg)
+	return nil
+}
+
+// setupRedisBackedService 创建使用内存验证码存储的真实服务
+func setupRedisBackedService() (*Service, *fakeCodeStore, *MockRepository) {
+	store := newFakeCodeStore()
+	mockRepo := new(MockRepository)
+	mockRepo.On("InvalidateActiveVerifications", mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("CreateVerification", mock.AnythingOfType("*entities.Verification")).Return(nil)
+	service := &Service{
+		repo:      mockRepo,
+		store:     store,
+		outbox:    &fakeOutbox{},
+		smsSender: sms.NewMemorySender(),
+		smsCfg:    &sms.Config{HourlyLimit: 2, DailyLimit: 3},
+		cfg: config.VerificationConfig{
+			CodeExpire:    600,
+			SendCooldown:  60,
+			MaxAttempts:   3,
+			BlockDuration: 900,
+		},
+	}
+	return service, store, mockRepo
+}
+
+func TestService_SendVerificationCode_Cooldown(t *testing.T) {
+	service, store, _ := setupRedisBackedService()
+	req := &dto.SendVerificationRequest{Target: "test@example.com", Type: "user_login"}
+
+	first, err := service.SendVerificationCode(req)
+	require.NoError(t, err)
+	assert.Equal(t, first.Code, store.codes["test@example.com:user_login"])
+
+	_, err = service.SendVerificationCode(req)
+	assert.Equal(t, common.ErrCodeTooFrequent, err)
+
+	// 冷却期结束后重新发送会覆盖旧验证码
+	store.cooldown = map[string]bool{}
+	second, err := service.SendVerificationCode(req)
+	require.NoError(t, err)
+	assert.Equal(t, second.Code, store.codes["test@example.com:user_login"])
+}
+
+func TestService_SendVerificationCode_ReleasesCooldownOnFailure(t *testing.T) {
+	service, store, _ := setupRedisBackedService()
+	req := &dto.SendVerificationRequest{Target: "test@example.com", Type: "user_login"}
+
+	store.storeErr = assert.AnError
+	_, err := service.SendVerificationCode(req)
+	require.Error(t, err)
+	assert.False(t, store.cooldown["test@example.com:user_login"])
+
+	// 失败后可以立即重试
+	store.storeErr = nil
+	_, err = service.SendVerificationCode(req)
+	require.NoError(t, err)
+	assert.True(t, store.cooldown["test@example.com:user_login"])
+}
+
+func TestService_SendVerificationCode_EnqueueFailure(t *testing.T) {
+	store := newFakeCodeStore()
+	mockRepo := new(MockRepository)
+	outbox := &fakeOutbox{err: assert.AnError}
+	service := &Service{
+		repo:   mockRepo,
+		store:  store,
+		outbox: outbox,
+		smsCfg: &sms.Config{},
+		cfg:    config.VerificationConfig{CodeExpire: 600, SendCooldown: 60, MaxAttempts: 3, BlockDuration: 900},
+	}
+	req := &dto.SendVerificationRequest{Target: "test@example.com", Type: "user_login"}
+
+	// 写入发件箱失败时不保留冷却期，也不写审计记录
+	_, err := service.SendVerificationCode(req)
+	require.Error(t, err)
+	assert.False(t, store.cooldown["test@example.com:user_login"])
+	mockRepo.AssertNotCalled(t, "CreateVerification", mock.Anything)
+
+	mockRepo.On("InvalidateActiveVerifications", mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("CreateVerification", mock.AnythingOfType("*entities.Verification")).Return(nil)
+	outbox.err = nil
+	_, err = service.SendVerificationCode(req)
+	require.NoError(t, err)
+	assert.Len(t, outbox.messages, 1)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestService_VerifyCode_Errors(t *testing.T) {
+	req := func(code string) *dto.VerifyCodeRequest {
+		return &dto.VerifyCodeRequest{Target: "test@example.com", Type: "user_login", Code: code}
+	}
+
+	t.Run("验证码不存在或已过期", func(t *testing.T) {
+		service, _, _ := setupRedisBackedService()
+		_, err := service.VerifyCode(req("123456"))
+		assert.Equal(t, common.ErrCodeExpired, err)
+	})
+
+	t.Run("验证码错误达到上限后锁定", func(t *testing.T) {
+		service, store, _ := setupRedisBackedService()
+		store.codes["test@example.com:user_login"] = "123456"
+
+		_, err := service.VerifyCode(req("000000"))
+		assert.Equal(t, common.ErrInvalidCode, err)
+		_, err = service.VerifyCode(req("000000"))
+		assert.Equal(t, common.ErrInvalidCode, err)
+		_, err = service.VerifyCode(req("000000"))
+		assert.Equal(t, common.ErrCodeBlocked, err)
+
+		// 锁定后正确验证码也无法使用，且不能重新发送
+		_, err = service.VerifyCode(req("123456"))
+		assert.Equal(t, common.ErrCodeBlocked, err)
+		_, err = service.SendVerificationCode(&dto.SendVerificationRequest{Target: "test@example.com", Type: "user_login"})
+		assert.Equal(t, common.ErrCodeBlocked, err)
+	})
+
+	t.Run("验证成功后验证码失效", func(t *testing.T) {
+		service, store, _ := setupRedisBackedService()
+		store.codes["test@example.com:user_login"] = "123456"
+		store.attempts["test@example.com:user_login"] = 1
+
+		resp, err := service.VerifyCode(req("123456"))
+		require.NoError(t, err)
+		assert.True(t, resp.Valid)
+		assert.Zero(t, store.attempts["test@example.com:user_login"])
+
+		_, err = service.VerifyCode(req("123456"))
+		assert.Equal(t, common.ErrCodeExpired, err)
+	})
+}
+
+func TestService_VerifyCode_UserScoped(t *testing.T) {
+	service, store, _ := setupRedisBackedService()
+	outbox := service.outbox.(*fakeOutbox)
+
+	sent, err := service.SendVerificationCode(&dto.SendVerificationRequest{Target: "new@example.com", Type: "user_email_change", UserID: 7})
+	require.NoError(t, err)
+	assert.Equal(t, sent.Code, store.codes["7:new@example.com:user_email_change"])
+	require.Len(t, outbox.messages, 1)
+	assert.Equal(t, "new@example.com", outbox.messages[0].To)
+
+	// 其他用户或未指定用户时无法使用该验证码
+	_, err = service.VerifyCode(&dto.VerifyCodeRequest{Target: "new@example.com", Type: "user_email_change", Code: sent.Code, UserID: 8})
+	assert.Equal(t, common.ErrCodeExpired, err)
+	_, err = service.VerifyCode(&dto.VerifyCodeRequest{Target: "new@example.com", Type: "user_email_change", Code: sent.Code})
+	assert.Equal(t, common.ErrCodeExpired, err)
+
+	resp, err := service.VerifyCode(&dto.VerifyCodeRequest{Target: "new@example.com", Type: "user_email_change", Code: sent.Code, UserID: 7})
+	require.NoError(t, err)
+	assert.True(t, resp.Valid)
+}
+
+func TestService_SendVerificationCode_EnqueuesLocalizedEmail(t *testing.T) {
+	service, _, _ := setupRedisBackedService()
+	outbox := service.outbox.(*fakeOutbox)
+
+	resp, err := service.SendVerificationCode(&dto.SendVerificationRequest{
+		Target: "test@example.com",
+		Type:   "forgot_password",
+		Locale: "zh-CN",
+	})
+	require.NoError(t, err)
+	require.Len(t, outbox.messages, 1)
+
+	msg := outbox.messages[0]
+	assert.Equal(t, "test@example.com", msg.To)
+	assert.Contains(t, msg.Subject, "重置密码")
+	assert.Contains(t, msg.TextBody, resp.Code)
+	assert.Contains(t, msg.HTMLBody, resp.Code)
+}
+
+func TestService_SendVerificationCode_SMS(t *testing.T) {
+	t.Run("短信类型通过短信发送", func(t *testing.T) {
+		service, _, mockRepo := setupRedisBackedService()
+		sender := service.smsSender.(*sms.MemorySender)
+
+		resp, err := service.SendVerificationCode(&dto.SendVerificationRequest{
+			Target: "+8613800138000",
+			Type:   entities.TypePhoneLogin,
+			Locale: "zh",
+		})
+		require.NoError(t, err)
+
+		msg, ok := sender.Last("+8613800138000")
+		require.True(t, ok)
+		assert.Contains(t, msg.Body, resp.Code)
+		assert.Contains(t, msg.Body, "验证码")
+		assert.Empty(t, service.outbox.(*fakeOutbox).messages)
+		mockRepo.AssertCalled(t, "CreateVerification", mock.MatchedBy(func(v *entities.Verification) bool {
+			return v.Action == entities.ActionPhoneVerification
+		}))
+	})
+
+	t.Run("单号码发送总量超限", func(t *testing.T) {
+		service, store, _ := setupRedisBackedService()
+		phone := "+8613800138000"
+
+		// 不同类型各自有冷却时间，但共享号码的每小时发送上限
+		_, err := service.SendVerificationCode(&dto.SendVerificationRequest{Target: phone, Type: entities.TypePhoneLogin})
+		require.NoError(t, err)
+		_, err = service.SendVerificationCode(&dto.SendVerificationRequest{Target: phone, Type: entities.TypePhoneBind})
+		require.NoError(t, err)
+
+		store.cooldown = map[string]bool{}
+		_, err = service.SendVerificationCode(&dto.SendVerificationRequest{Target: phone, Type: entities.TypePhoneLogin})
+		assert.Equal(t, common.ErrSendLimitExceeded, err)
+		assert.Len(t, service.smsSender.(*sms.MemorySender).Messages(), 2)
+	})
+
+	t.Run("不在允许国家列表中的号码", func(t *testing.T) {
+		service, _, _ := setupRedisBackedService()
+		service.smsCfg.AllowedCountries = []string{"86"}
+
+		_, err := service.SendVerificationCode(&dto.SendVerificationRequest{Target: "+2341234567890", Type: entities.TypePhoneLogin})
+		assert.Equal(t, common.ErrInvalidPhone, err)
+		assert.Empty(t, service.smsSender.(*sms.MemorySender).Messages())
+	})
+}
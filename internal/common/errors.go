@@ -10,29 +10,64 @@ var (
 	ErrInvalidCredentials = errors.New("invalid credentials")
 	ErrEmailNotVerified   = errors.New("email not verified")
 	ErrUserInactive       = errors.New("user inactive")
+	ErrUserSuspended      = errors.New("user suspended")
+	ErrInvalidPhone       = errors.New("invalid phone number")
+	ErrPasswordAlreadySet = errors.New("password already set")
+	ErrPasswordNotSet     = errors.New("password not set")
+	ErrLoginBlocked       = errors.New("login blocked due to suspicious activity")
+	ErrLoginChallenge     = errors.New("additional login verification required")
+	ErrLoginLocked        = errors.New("too many failed login attempts")
+	ErrUnlockTokenInvalid = errors.New("unlock token invalid")
+	ErrChallengeRequired  = errors.New("challenge required")
+	ErrChallengeInvalid   = errors.New("challenge invalid")
+	ErrExportTooFrequent  = errors.New("data export requested too frequently")
+	ErrKYCPending         = errors.New("kyc submission already pending")
+	ErrKYCAlreadyReviewed = errors.New("kyc submission already reviewed")
 
 	// 管理员相关错误
 	ErrAdminNotFound       = errors.New("admin not found")
 	ErrAdminEmailExists    = errors.New("admin email already exists")
 	ErrInvalidAdminCredentials = errors.New("invalid admin credentials")
 	ErrAdminInactive       = errors.New("admin inactive")
+	ErrInvitationInvalid   = errors.New("admin invitation invalid")
 
 	// 验证码相关错误
 	ErrCodeNotFound     = errors.New("verification code not found")
 	ErrCodeExpired      = errors.New("verification code expired")
 	ErrCodeAlreadyUsed  = errors.New("verification code already used")
 	ErrInvalidCode      = errors.New("invalid verification code")
+	ErrCodeTooFrequent  = errors.New("verification code requested too frequently")
+	ErrCodeBlocked      = errors.New("too many failed verification attempts")
+	ErrSendLimitExceeded = errors.New("verification send limit exceeded")
 
 	// 令牌相关错误
 	ErrTokenNotFound    = errors.New("token not found")
 	ErrTokenExpired     = errors.New("token expired")
 	ErrTokenInvalid     = errors.New("token invalid")
 	ErrRefreshTokenInvalid = errors.New("refresh token invalid")
+	ErrSessionLimitReached = errors.New("maximum number of active sessions reached")
+	ErrSessionEvicted      = errors.New("session evicted by a newer sign-in")
 
 	// 权限相关错误
 	ErrUnauthorized     = errors.New("unauthorized")
 	ErrForbidden        = errors.New("forbidden")
 	ErrInsufficientPermissions = errors.New("insufficient permissions")
+	ErrRoleExists       = errors.New("role already exists")
+	ErrRoleInUse        = errors.New("role is assigned to admins")
+	ErrRoleProtected    = errors.New("system role cannot be modified")
+	ErrLastSuperAdmin   = errors.New("cannot remove the last active super admin")
+
+	// API密钥相关错误
+	ErrAPIKeyInvalid       = errors.New("api key invalid")
+	ErrAPIKeyIPNotAllowed  = errors.New("api key not allowed from this ip")
+	ErrAPIKeyLimitExceeded = errors.New("api key limit exceeded")
+
+	// 功能开关相关错误
+	ErrFeatureFlagExists = errors.New("feature flag already exists")
+
+	// 邀请码相关错误
+	ErrReferralCodeInvalid     = errors.New("referral code invalid")
+	ErrReferralAlreadyReviewed = errors.New("referral already reviewed")
 
 	// 通用错误
 	ErrInternalServer   = errors.New("internal server error")
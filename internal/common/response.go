@@ -1,7 +1,10 @@
 package common
 
 import (
+	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -59,6 +62,64 @@ func Forbidden(c *gin.Context, message string) {
 	})
 }
 
+// ForbiddenWithData 返回403并附带客户端继续流程所需的数据
+func ForbiddenWithData(c *gin.Context, message string, data interface{}) {
+	c.JSON(http.StatusForbidden, Response{
+		Code:    403,
+		Message: message,
+		Data:    data,
+	})
+}
+
+// ReauthRequiredError 敏感操作要求近期重新验证身份时返回的错误码
+// 客户端收到后引导用户验证密码或邮箱验证码，使用签发的提升令牌重试
+const ReauthRequiredError = "reauth_required"
+
+// ReauthRequired 返回401，附带错误码和要求的最长验证时间（秒），并按 RFC 9470 设置 WWW-Authenticate
+func ReauthRequired(c *gin.Context, maxAge time.Duration) {
+	seconds := int64(maxAge / time.Second)
+	c.Header("WWW-Authenticate", fmt.Sprintf(`Bearer error="insufficient_user_authentication", max_age="%d"`, seconds))
+	c.JSON(http.StatusUnauthorized, Response{
+		Code:    401,
+		Message: "Recent authentication required",
+		Data: gin.H{
+			"error":   ReauthRequiredError,
+			"max_age": seconds,
+		},
+	})
+}
+
+// ChallengeRequiredError 注册、登录等接口要求完成工作量证明或验证码时返回的错误码
+// 客户端收到后申请挑战，完成后携带挑战令牌和答案重试
+const ChallengeRequiredError = "challenge_required"
+
+// ChallengeRequired 返回428，附带错误码和要求的工作量证明难度
+func ChallengeRequired(c *gin.Context, message string, difficulty int) {
+	c.JSON(http.StatusPreconditionRequired, Response{
+		Code:    428,
+		Message: message,
+		Data: gin.H{
+			"error":      ChallengeRequiredError,
+			"difficulty": difficulty,
+		},
+	})
+}
+
+// PasswordRejectedError 新密码不满足密码策略时返回的错误码
+const PasswordRejectedError = "password_rejected"
+
+// PasswordRejected 返回400，附带错误码和全部不满足的策略，reasons 为 []password.Violation
+func PasswordRejected(c *gin.Context, reasons interface{}) {
+	c.JSON(http.StatusBadRequest, Response{
+		Code:    400,
+		Message: "Password does not meet the password policy",
+		Data: gin.H{
+			"error":   PasswordRejectedError,
+			"reasons": reasons,
+		},
+	})
+}
+
 func NotFound(c *gin.Context, message string) {
 	c.JSON(http.StatusNotFound, Response{
 		Code:    404,
@@ -66,6 +127,23 @@ func NotFound(c *gin.Context, message string) {
 	})
 }
 
+func TooManyRequests(c *gin.Context, message string) {
+	c.JSON(http.StatusTooManyRequests, Response{
+		Code:    429,
+		Message: message,
+	})
+}
+
+// TooManyRequestsRetryAfter 返回 429 并通过 Retry-After 响应头和 retry_after 字段告知需要等待的秒数
+func TooManyRequestsRetryAfter(c *gin.Context, message string, retryAfter int) {
+	c.Header("Retry-After", strconv.Itoa(retryAfter))
+	c.JSON(http.StatusTooManyRequests, Response{
+		Code:    429,
+		Message: message,
+		Data:    gin.H{"retry_after": retryAfter},
+	})
+}
+
 func ServerError(c *gin.Context, err error) {
 	c.JSON(http.StatusInternalServerError, Response{
 		Code:    500,
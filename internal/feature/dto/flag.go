@@ -0,0 +1,23 @@
+package dto
+
+import "trusioo_api/internal/feature/entities"
+
+// CreateFlagRequest 创建功能开关，新开关默认关闭
+type CreateFlagRequest struct {
+	Key         string         `json:"key" binding:"required,max=100"`
+	Description string         `json:"description" binding:"omitempty,max=255"`
+	Enabled     bool           `json:"enabled"`
+	Rules       entities.Rules `json:"rules"`
+}
+
+// UpdateFlagRequest 修改功能开关，只更新提供的字段，rules 整体替换
+type UpdateFlagRequest struct {
+	Description *string         `json:"description" binding:"omitempty,max=255"`
+	Enabled     *bool           `json:"enabled"`
+	Rules       *entities.Rules `json:"rules"`
+}
+
+// EvaluatedFlagsResponse 当前请求的功能开关评估结果
+type EvaluatedFlagsResponse struct {
+	Flags map[string]bool `json:"flags"`
+}
@@ -0,0 +1,52 @@
+package entities
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Flag 功能开关
+// Enabled 为总开关，关闭时对所有请求关闭；开启时按 Rules 定向
+type Flag struct {
+	ID          int64     `json:"id" db:"id"`
+	Key         string    `json:"key" db:"key"`
+	Description string    `json:"description" db:"description"`
+	Enabled     bool      `json:"enabled" db:"enabled"`
+	Rules       Rules     `json:"rules" db:"rules"`
+	UpdatedBy   *int64    `json:"updated_by,omitempty" db:"updated_by"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// Rules 定向规则，以 JSONB 存储
+// 白名单用户始终开启；其余请求需要满足全部已设置的条件，再按灰度比例分桶
+type Rules struct {
+	Percentage *int     `json:"percentage,omitempty"`  // 灰度比例 0-100，为空表示全部
+	Countries  []string `json:"countries,omitempty"`   // 国家代码，按IP地理位置匹配
+	Platforms  []string `json:"platforms,omitempty"`   // X-Platform，例如 ios、android、web
+	MinVersion string   `json:"min_version,omitempty"` // X-App-Version 下限（含）
+	MaxVersion string   `json:"max_version,omitempty"` // X-App-Version 上限（含）
+	UserIDs    []int64  `json:"user_ids,omitempty"`    // 白名单用户ID
+}
+
+// Value 实现 driver.Valuer
+func (r Rules) Value() (driver.Value, error) {
+	return json.Marshal(r)
+}
+
+// Scan 实现 sql.Scanner
+func (r *Rules) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*r = Rules{}
+		return nil
+	case []byte:
+		return json.Unmarshal(v, r)
+	case string:
+		return json.Unmarshal([]byte(v), r)
+	default:
+		return fmt.Errorf("cannot scan %T into Rules", src)
+	}
+}
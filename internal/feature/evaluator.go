@@ -0,0 +1,122 @@
+package feature
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"strconv"
+	"strings"
+
+	"trusioo_api/internal/feature/entities"
+	"trusioo_api/internal/middleware"
+)
+
+// Evaluate 按定向规则判断功能开关对请求是否开启
+func Evaluate(flag *entities.Flag, evalCtx *middleware.EvalCtx) bool {
+	if flag == nil || !flag.Enabled {
+		return false
+	}
+	if evalCtx == nil {
+		evalCtx = &middleware.EvalCtx{}
+	}
+	rules := flag.Rules
+
+	if evalCtx.UserID > 0 && evalCtx.UserType == "user" {
+		for _, id := range rules.UserIDs {
+			if id == evalCtx.UserID {
+				return true
+			}
+		}
+	}
+
+	if len(rules.Countries) > 0 && !containsFold(rules.Countries, evalCtx.Country) {
+		return false
+	}
+	if len(rules.Platforms) > 0 && !containsFold(rules.Platforms, evalCtx.Platform) {
+		return false
+	}
+	if rules.MinVersion != "" || rules.MaxVersion != "" {
+		version, ok := parseVersion(evalCtx.AppVersion)
+		if !ok {
+			return false
+		}
+		if minVersion, ok := parseVersion(rules.MinVersion); ok && compareVersions(version, minVersion) < 0 {
+			return false
+		}
+		if maxVersion, ok := parseVersion(rules.MaxVersion); ok && compareVersions(version, maxVersion) > 0 {
+			return false
+		}
+	}
+
+	return inRollout(flag.Key, rules.Percentage, evalCtx.StickinessKey())
+}
+
+// inRollout 灰度分桶：同一开关下同一用户或设备的结果保持稳定，无法分桶的匿名请求不进入部分灰度
+func inRollout(key string, percentage *int, stickiness string) bool {
+	if percentage == nil || *percentage >= 100 {
+		return true
+	}
+	if *percentage <= 0 || stickiness == "" {
+		return false
+	}
+	return bucket(key, stickiness) < *percentage
+}
+
+// bucket 将开关和分桶依据哈希到 0-99
+func bucket(key, stickiness string) int {
+	sum := sha256.Sum256([]byte(key + ":" + stickiness))
+	return int(binary.BigEndian.Uint32(sum[:4]) % 100)
+}
+
+// parseVersion 解析 1.2.3 形式的版本号，允许 v 前缀，忽略 -beta 等预发布后缀
+func parseVersion(value string) ([]int, bool) {
+	value = strings.TrimPrefix(strings.TrimSpace(value), "v")
+	if i := strings.IndexAny(value, "-+"); i >= 0 {
+		value = value[:i]
+	}
+	if value == "" {
+		return nil, false
+	}
+
+	parts := strings.Split(value, ".")
+	version := make([]int, len(parts))
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return nil, false
+		}
+		version[i] = n
+	}
+	return version, true
+}
+
+// compareVersions 逐段比较版本号，缺少的段视为 0
+func compareVersions(a, b []int) int {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		var x, y int
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+func containsFold(values []string, value string) bool {
+	if value == "" {
+		return false
+	}
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
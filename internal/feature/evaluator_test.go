@@ -0,0 +1,169 @@
+package feature
+
+import (
+	"fmt"
+	"testing"
+
+	"trusioo_api/internal/feature/entities"
+	"trusioo_api/internal/middleware"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func percentage(p int) *int {
+	return &p
+}
+
+func TestEvaluate(t *testing.T) {
+	iosUS := &middleware.EvalCtx{UserID: 1, UserType: "user", Country: "US", Platform: "ios", AppVersion: "1.3.0"}
+
+	tests := []struct {
+		name     string
+		flag     *entities.Flag
+		evalCtx  *middleware.EvalCtx
+		expected bool
+	}{
+		{
+			name:     "总开关关闭",
+			flag:     &entities.Flag{Key: "f", Enabled: false, Rules: entities.Rules{UserIDs: []int64{1}}},
+			evalCtx:  iosUS,
+			expected: false,
+		},
+		{
+			name:     "无规则全部开启",
+			flag:     &entities.Flag{Key: "f", Enabled: true},
+			evalCtx:  &middleware.EvalCtx{},
+			expected: true,
+		},
+		{
+			name:     "国家匹配",
+			flag:     &entities.Flag{Key: "f", Enabled: true, Rules: entities.Rules{Countries: []string{"GB", "US"}}},
+			evalCtx:  iosUS,
+			expected: true,
+		},
+		{
+			name:     "国家未知",
+			flag:     &entities.Flag{Key: "f", Enabled: true, Rules: entities.Rules{Countries: []string{"US"}}},
+			evalCtx:  &middleware.EvalCtx{Platform: "ios"},
+			expected: false,
+		},
+		{
+			name:     "平台不匹配",
+			flag:     &entities.Flag{Key: "f", Enabled: true, Rules: entities.Rules{Platforms: []string{"android"}}},
+			evalCtx:  iosUS,
+			expected: false,
+		},
+		{
+			name:     "版本在范围内",
+			flag:     &entities.Flag{Key: "f", Enabled: true, Rules: entities.Rules{MinVersion: "1.2", MaxVersion: "1.3.0"}},
+			evalCtx:  iosUS,
+			expected: true,
+		},
+		{
+			name:     "版本低于下限",
+			flag:     &entities.Flag{Key: "f", Enabled: true, Rules: entities.Rules{MinVersion: "1.10.0"}},
+			evalCtx:  iosUS,
+			expected: false,
+		},
+		{
+			name:     "版本高于上限",
+			flag:     &entities.Flag{Key: "f", Enabled: true, Rules: entities.Rules{MaxVersion: "1.2.9"}},
+			evalCtx:  iosUS,
+			expected: false,
+		},
+		{
+			name:     "缺少版本号",
+			flag:     &entities.Flag{Key: "f", Enabled: true, Rules: entities.Rules{MinVersion: "1.0.0"}},
+			evalCtx:  &middleware.EvalCtx{},
+			expected: false,
+		},
+		{
+			name:     "白名单用户跳过其他条件",
+			flag:     &entities.Flag{Key: "f", Enabled: true, Rules: entities.Rules{Countries: []string{"GB"}, Percentage: percentage(0), UserIDs: []int64{1}}},
+			evalCtx:  iosUS,
+			expected: true,
+		},
+		{
+			name:     "白名单只匹配普通用户",
+			flag:     &entities.Flag{Key: "f", Enabled: true, Rules: entities.Rules{Percentage: percentage(0), UserIDs: []int64{1}}},
+			evalCtx:  &middleware.EvalCtx{UserID: 1, UserType: "admin"},
+			expected: false,
+		},
+		{
+			name:     "灰度比例为0",
+			flag:     &entities.Flag{Key: "f", Enabled: true, Rules: entities.Rules{Percentage: percentage(0)}},
+			evalCtx:  iosUS,
+			expected: false,
+		},
+		{
+			name:     "匿名请求无设备ID不进入部分灰度",
+			flag:     &entities.Flag{Key: "f", Enabled: true, Rules: entities.Rules{Percentage: percentage(99)}},
+			evalCtx:  &middleware.EvalCtx{},
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Evaluate(tt.flag, tt.evalCtx))
+		})
+	}
+}
+
+func TestEvaluate_Rollout(t *testing.T) {
+	flag := &entities.Flag{Key: "wallet.withdraw", Enabled: true, Rules: entities.Rules{Percentage: percentage(30)}}
+
+	t.Run("同一设备结果稳定", func(t *testing.T) {
+		evalCtx := &middleware.EvalCtx{DeviceID: "device-1"}
+		first := Evaluate(flag, evalCtx)
+		for i := 0; i < 10; i++ {
+			assert.Equal(t, first, Evaluate(flag, evalCtx))
+		}
+	})
+
+	t.Run("开启比例接近灰度比例", func(t *testing.T) {
+		enabled := 0
+		for i := 1; i <= 10000; i++ {
+			if Evaluate(flag, &middleware.EvalCtx{UserID: int64(i), UserType: "user"}) {
+				enabled++
+			}
+		}
+		assert.InDelta(t, 3000, enabled, 300)
+	})
+
+	t.Run("提高比例不影响已开启的用户", func(t *testing.T) {
+		wider := &entities.Flag{Key: flag.Key, Enabled: true, Rules: entities.Rules{Percentage: percentage(60)}}
+		for i := 1; i <= 1000; i++ {
+			evalCtx := &middleware.EvalCtx{DeviceID: fmt.Sprintf("device-%d", i)}
+			if Evaluate(flag, evalCtx) {
+				assert.True(t, Evaluate(wider, evalCtx))
+			}
+		}
+	})
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{"1.2.3", "1.2.3", 0},
+		{"1.2", "1.2.0", 0},
+		{"v1.10.0", "1.9.9", 1},
+		{"1.3.0-beta.1", "1.3.0", 0},
+		{"2.0", "10.0", -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.a+" 与 "+tt.b, func(t *testing.T) {
+			a, ok := parseVersion(tt.a)
+			assert.True(t, ok)
+			b, ok := parseVersion(tt.b)
+			assert.True(t, ok)
+			assert.Equal(t, tt.expected, compareVersions(a, b))
+		})
+	}
+
+	_, ok := parseVersion("latest")
+	assert.False(t, ok)
+}
@@ -0,0 +1,229 @@
+package feature
+
+import (
+	"strconv"
+
+	"trusioo_api/internal/audit"
+	"trusioo_api/internal/common"
+	"trusioo_api/internal/feature/dto"
+	"trusioo_api/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// GetFlags 获取功能开关评估结果
+// @Summary 获取功能开关
+// @Description 按当前请求的用户、X-Platform、X-App-Version、X-Device-Id、X-Locale 请求头和IP所在国家评估全部功能开关，无需登录，携带访问令牌时按用户定向
+// @Tags 功能开关
+// @Accept json
+// @Produce json
+// @Param X-Platform header string false "客户端平台，例如 ios、android、web"
+// @Param X-App-Version header string false "客户端版本，例如 1.3.0"
+// @Param X-Device-Id header string false "设备ID，未登录时用于灰度分桶"
+// @Param X-Locale header string false "语言，例如 zh-CN"
+// @Success 200 {object} common.Response{data=dto.EvaluatedFlagsResponse} "获取成功"
+// @Failure 500 {object} common.Response "服务器错误"
+// @Router /api/v1/flags [get]
+func (h *Handler) GetFlags(c *gin.Context) {
+	resp, err := h.service.EvaluateAll(c.Request.Context(), middleware.GetEvalCtx(c))
+	if err != nil {
+		common.ServerError(c, err)
+		return
+	}
+
+	common.Success(c, resp)
+}
+
+// ListFlags 获取功能开关列表
+// @Summary 获取功能开关列表
+// @Description 获取全部功能开关及其定向规则
+// @Tags 管理员-功能开关
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} common.Response{data=[]entities.Flag} "获取成功"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足"
+// @Failure 500 {object} common.Response "服务器错误"
+// @Router /api/v1/admin/feature-flags [get]
+func (h *Handler) ListFlags(c *gin.Context) {
+	flags, err := h.service.ListFlags(c.Request.Context())
+	if err != nil {
+		common.ServerError(c, err)
+		return
+	}
+
+	common.Success(c, flags)
+}
+
+// GetFlag 获取功能开关详情
+// @Summary 获取功能开关详情
+// @Description 获取单个功能开关及其定向规则
+// @Tags 管理员-功能开关
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "开关ID"
+// @Success 200 {object} common.Response{data=entities.Flag} "获取成功"
+// @Failure 400 {object} common.Response "参数错误"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足"
+// @Failure 404 {object} common.Response "开关不存在"
+// @Failure 500 {object} common.Response "服务器错误"
+// @Router /api/v1/admin/feature-flags/{id} [get]
+func (h *Handler) GetFlag(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		common.ValidationError(c, "Invalid feature flag ID")
+		return
+	}
+
+	flag, err := h.service.GetFlag(c.Request.Context(), id)
+	if err != nil {
+		handleFlagError(c, err)
+		return
+	}
+
+	common.Success(c, flag)
+}
+
+// CreateFlag 创建功能开关
+// @Summary 创建功能开关
+// @Description 创建功能开关及定向规则：白名单用户始终开启，其余请求需满足国家、平台、版本范围条件后按灰度比例分桶
+// @Tags 管理员-功能开关
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body dto.CreateFlagRequest true "开关信息"
+// @Success 200 {object} common.Response{data=entities.Flag} "创建成功"
+// @Failure 400 {object} common.Response "参数错误或开关已存在"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足"
+// @Failure 500 {object} common.Response "服务器错误"
+// @Router /api/v1/admin/feature-flags [post]
+func (h *Handler) CreateFlag(c *gin.Context) {
+	adminID, exists := c.Get("user_id")
+	if !exists {
+		common.Unauthorized(c, "Admin not authenticated")
+		return
+	}
+
+	var req dto.CreateFlagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ValidationError(c, err.Error())
+		return
+	}
+
+	flag, err := h.service.CreateFlag(audit.Context(c), adminID.(int64), &req)
+	if err != nil {
+		handleFlagError(c, err)
+		return
+	}
+
+	common.Success(c, flag)
+}
+
+// UpdateFlag 修改功能开关
+// @Summary 修改功能开关
+// @Description 修改描述、总开关或整体替换定向规则，修改后立即生效
+// @Tags 管理员-功能开关
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "开关ID"
+// @Param request body dto.UpdateFlagRequest true "开关信息"
+// @Success 200 {object} common.Response{data=entities.Flag} "修改成功"
+// @Failure 400 {object} common.Response "参数错误"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足"
+// @Failure 404 {object} common.Response "开关不存在"
+// @Failure 500 {object} common.Response "服务器错误"
+// @Router /api/v1/admin/feature-flags/{id} [put]
+func (h *Handler) UpdateFlag(c *gin.Context) {
+	adminID, id, ok := flagIDs(c)
+	if !ok {
+		return
+	}
+
+	var req dto.UpdateFlagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ValidationError(c, err.Error())
+		return
+	}
+
+	flag, err := h.service.UpdateFlag(audit.Context(c), adminID, id, &req)
+	if err != nil {
+		handleFlagError(c, err)
+		return
+	}
+
+	common.Success(c, flag)
+}
+
+// DeleteFlag 删除功能开关
+// @Summary 删除功能开关
+// @Description 删除功能开关，使用该开关的 Gate 按关闭处理
+// @Tags 管理员-功能开关
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "开关ID"
+// @Success 200 {object} common.Response "删除成功"
+// @Failure 400 {object} common.Response "参数错误"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足"
+// @Failure 404 {object} common.Response "开关不存在"
+// @Failure 500 {object} common.Response "服务器错误"
+// @Router /api/v1/admin/feature-flags/{id} [delete]
+func (h *Handler) DeleteFlag(c *gin.Context) {
+	adminID, id, ok := flagIDs(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteFlag(audit.Context(c), adminID, id); err != nil {
+		handleFlagError(c, err)
+		return
+	}
+
+	common.SuccessWithMessage(c, "Feature flag deleted", nil)
+}
+
+// flagIDs 获取当前管理员ID和路径中的开关ID，失败时已写入响应
+func flagIDs(c *gin.Context) (int64, int64, bool) {
+	adminID, exists := c.Get("user_id")
+	if !exists {
+		common.Unauthorized(c, "Admin not authenticated")
+		return 0, 0, false
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		common.ValidationError(c, "Invalid feature flag ID")
+		return 0, 0, false
+	}
+
+	return adminID.(int64), id, true
+}
+
+// handleFlagError 功能开关管理的通用错误响应
+func handleFlagError(c *gin.Context, err error) {
+	switch err {
+	case common.ErrValidation:
+		common.ValidationError(c, "Invalid feature flag key or rules")
+	case common.ErrFeatureFlagExists:
+		common.ValidationError(c, "Feature flag already exists")
+	case common.ErrNotFound:
+		common.NotFound(c, "Feature flag not found")
+	default:
+		common.ServerError(c, err)
+	}
+}
@@ -0,0 +1,123 @@
+package feature
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+
+	"trusioo_api/internal/audit"
+	auditEntities "trusioo_api/internal/audit/entities"
+	"trusioo_api/internal/feature/entities"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Repository 功能开关的数据访问接口
+type Repository interface {
+	ListFlags(ctx context.Context) ([]*entities.Flag, error)
+	GetFlag(ctx context.Context, id int64) (*entities.Flag, error)
+	GetFlagByKey(ctx context.Context, key string) (*entities.Flag, error)
+	CreateFlag(ctx context.Context, flag *entities.Flag, entry *auditEntities.Log) error
+	UpdateFlag(ctx context.Context, flag *entities.Flag, entry *auditEntities.Log) error
+	DeleteFlag(ctx context.Context, id int64, entry *auditEntities.Log) error
+}
+
+type repository struct {
+	db *sqlx.DB
+}
+
+// NewRepository 创建功能开关仓库
+func NewRepository(db *sqlx.DB) Repository {
+	return &repository{db: db}
+}
+
+// ListFlags 获取全部功能开关，按 key 排序
+func (r *repository) ListFlags(ctx context.Context) ([]*entities.Flag, error) {
+	flags := []*entities.Flag{}
+	if err := r.db.SelectContext(ctx, &flags, "SELECT * FROM feature_flags ORDER BY key"); err != nil {
+		return nil, err
+	}
+	return flags, nil
+}
+
+// GetFlag 按ID获取功能开关，不存在时返回 sql.ErrNoRows
+func (r *repository) GetFlag(ctx context.Context, id int64) (*entities.Flag, error) {
+	var flag entities.Flag
+	if err := r.db.GetContext(ctx, &flag, "SELECT * FROM feature_flags WHERE id = $1", id); err != nil {
+		return nil, err
+	}
+	return &flag, nil
+}
+
+// GetFlagByKey 按 key 获取功能开关，不存在时返回 sql.ErrNoRows
+func (r *repository) GetFlagByKey(ctx context.Context, key string) (*entities.Flag, error) {
+	var flag entities.Flag
+	if err := r.db.GetContext(ctx, &flag, "SELECT * FROM feature_flags WHERE key = $1", key); err != nil {
+		return nil, err
+	}
+	return &flag, nil
+}
+
+// CreateFlag 创建功能开关，回填ID和时间，审计记录在同一事务中写入
+func (r *repository) CreateFlag(ctx context.Context, flag *entities.Flag, entry *auditEntities.Log) error {
+	return r.withAudit(ctx, entry, func(tx *sqlx.Tx) error {
+		query := `
+			INSERT INTO feature_flags (key, description, enabled, rules, updated_by)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING id, created_at, updated_at`
+		if err := tx.QueryRowxContext(ctx, query, flag.Key, flag.Description, flag.Enabled, flag.Rules, flag.UpdatedBy).
+			Scan(&flag.ID, &flag.CreatedAt, &flag.UpdatedAt); err != nil {
+			return err
+		}
+		entry.TargetID = strconv.FormatInt(flag.ID, 10)
+		return nil
+	})
+}
+
+// UpdateFlag 保存功能开关的描述、总开关和规则，不存在时返回 sql.ErrNoRows
+func (r *repository) UpdateFlag(ctx context.Context, flag *entities.Flag, entry *auditEntities.Log) error {
+	return r.withAudit(ctx, entry, func(tx *sqlx.Tx) error {
+		query := `
+			UPDATE feature_flags
+			SET description = $2, enabled = $3, rules = $4, updated_by = $5, updated_at = NOW()
+			WHERE id = $1
+			RETURNING updated_at`
+		return tx.QueryRowxContext(ctx, query, flag.ID, flag.Description, flag.Enabled, flag.Rules, flag.UpdatedBy).
+			Scan(&flag.UpdatedAt)
+	})
+}
+
+// DeleteFlag 删除功能开关，不存在时返回 sql.ErrNoRows
+func (r *repository) DeleteFlag(ctx context.Context, id int64, entry *auditEntities.Log) error {
+	return r.withAudit(ctx, entry, func(tx *sqlx.Tx) error {
+		result, err := tx.ExecContext(ctx, "DELETE FROM feature_flags WHERE id = $1", id)
+		if err != nil {
+			return err
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			return sql.ErrNoRows
+		}
+		return nil
+	})
+}
+
+// withAudit 在事务中执行修改并追加审计记录
+func (r *repository) withAudit(ctx context.Context, entry *auditEntities.Log, fn func(tx *sqlx.Tx) error) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if err := audit.Append(ctx, tx, entry); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,28 @@
+package feature
+
+import (
+	"trusioo_api/internal/auth/rbac"
+	"trusioo_api/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterRoutes(router *gin.RouterGroup, handler *Handler) {
+	// 客户端开关评估 - 无需登录，携带访问令牌时按用户定向
+	flags := router.Group("/flags")
+	flags.Use(middleware.EvalCtxMiddleware())
+	{
+		flags.GET("", handler.GetFlags)
+	}
+
+	// 开关管理 - 默认只有超级管理员拥有 flags.manage
+	admin := router.Group("/admin/feature-flags")
+	admin.Use(middleware.AdminAuthMiddleware(), middleware.RequirePermission(rbac.PermFlagsManage))
+	{
+		admin.GET("", handler.ListFlags)
+		admin.POST("", handler.CreateFlag)
+		admin.GET("/:id", handler.GetFlag)
+		admin.PUT("/:id", handler.UpdateFlag)
+		admin.DELETE("/:id", handler.DeleteFlag)
+	}
+}
@@ -0,0 +1,286 @@
+package feature
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"trusioo_api/config"
+	"trusioo_api/internal/audit"
+	auditEntities "trusioo_api/internal/audit/entities"
+	"trusioo_api/internal/common"
+	"trusioo_api/internal/feature/dto"
+	"trusioo_api/internal/feature/entities"
+	"trusioo_api/internal/middleware"
+	"trusioo_api/pkg/logger"
+)
+
+// flagKeyPattern 开关 key 只允许小写字母、数字、点、下划线和连字符，例如 giftcard.trade
+var flagKeyPattern = regexp.MustCompile(`^[a-z][a-z0-9_.-]{1,99}$`)
+
+var countryPattern = regexp.MustCompile(`^[A-Z]{2}$`)
+
+// flagsCacheKey 全部开关作为一个缓存项，任一开关修改时整体失效
+const flagsCacheKey = "flags"
+
+// Cache 功能开关缓存接口，由 redis.CacheService 实现
+type Cache interface {
+	GetJSON(ctx context.Context, key string, dest interface{}) error
+	Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error
+	Delete(ctx context.Context, key string) error
+}
+
+// Config 功能开关配置
+type Config struct {
+	CacheTTL time.Duration
+}
+
+// DefaultConfig 默认配置
+func DefaultConfig() *Config {
+	return &Config{CacheTTL: time.Minute}
+}
+
+// NewConfigFromApp 从应用配置创建功能开关配置
+func NewConfigFromApp(appConfig *config.Config) *Config {
+	cfg := DefaultConfig()
+	if appConfig != nil && appConfig.Feature.CacheTTLSeconds > 0 {
+		cfg.CacheTTL = time.Duration(appConfig.Feature.CacheTTLSeconds) * time.Second
+	}
+	return cfg
+}
+
+// Service 功能开关的管理与评估
+// 评估时读取 Redis 中缓存的全部开关，缓存不可用时回退到数据库
+type Service struct {
+	repo  Repository
+	cache Cache
+	cfg   *Config
+}
+
+// NewService 创建服务，cache 为空时每次从数据库读取，cfg 为空时使用默认配置
+func NewService(repo Repository, cache Cache, cfg *Config) *Service {
+	if cfg == nil {
+		cfg = DefaultConfig()
+	}
+	return &Service{repo: repo, cache: cache, cfg: cfg}
+}
+
+// IsEnabled 判断开关对请求是否开启，不存在的开关视为关闭
+func (s *Service) IsEnabled(ctx context.Context, key string, evalCtx *middleware.EvalCtx) (bool, error) {
+	flags, err := s.flags(ctx)
+	if err != nil {
+		return false, err
+	}
+	for _, flag := range flags {
+		if flag.Key == key {
+			return Evaluate(flag, evalCtx), nil
+		}
+	}
+	return false, nil
+}
+
+// EvaluateAll 评估全部开关，供客户端决定展示哪些功能
+func (s *Service) EvaluateAll(ctx context.Context, evalCtx *middleware.EvalCtx) (*dto.EvaluatedFlagsResponse, error) {
+	flags, err := s.flags(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]bool, len(flags))
+	for _, flag := range flags {
+		result[flag.Key] = Evaluate(flag, evalCtx)
+	}
+	return &dto.EvaluatedFlagsResponse{Flags: result}, nil
+}
+
+// ListFlags 获取全部开关，管理端直接读取数据库
+func (s *Service) ListFlags(ctx context.Context) ([]*entities.Flag, error) {
+	return s.repo.ListFlags(ctx)
+}
+
+// GetFlag 获取开关详情
+func (s *Service) GetFlag(ctx context.Context, id int64) (*entities.Flag, error) {
+	flag, err := s.repo.GetFlag(ctx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, common.ErrNotFound
+		}
+		return nil, err
+	}
+	return flag, nil
+}
+
+// CreateFlag 创建开关
+func (s *Service) CreateFlag(ctx context.Context, adminID int64, req *dto.CreateFlagRequest) (*entities.Flag, error) {
+	key := strings.TrimSpace(req.Key)
+	if !flagKeyPattern.MatchString(key) {
+		return nil, common.ErrValidation
+	}
+	rules, err := normalizeRules(req.Rules)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.repo.GetFlagByKey(ctx, key)
+	if err == nil {
+		return nil, common.ErrFeatureFlagExists
+	}
+	if err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	flag := &entities.Flag{
+		Key:         key,
+		Description: strings.TrimSpace(req.Description),
+		Enabled:     req.Enabled,
+		Rules:       rules,
+		UpdatedBy:   &adminID,
+	}
+	entry := audit.NewLog(ctx, adminID, "feature_flag.create", auditEntities.TargetFeatureFlag, "")
+	entry.Changes.Set("key", nil, flag.Key)
+	entry.Changes.Set("description", nil, flag.Description)
+	entry.Changes.Set("enabled", nil, flag.Enabled)
+	entry.Changes.Set("rules", nil, flag.Rules)
+	if err := s.repo.CreateFlag(ctx, flag, entry); err != nil {
+		return nil, err
+	}
+	s.invalidate(ctx)
+	return flag, nil
+}
+
+// UpdateFlag 修改开关的描述、总开关或规则，修改后缓存立即失效
+func (s *Service) UpdateFlag(ctx context.Context, adminID, id int64, req *dto.UpdateFlagRequest) (*entities.Flag, error) {
+	flag, err := s.GetFlag(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	before := *flag
+
+	if req.Description != nil {
+		flag.Description = strings.TrimSpace(*req.Description)
+	}
+	if req.Enabled != nil {
+		flag.Enabled = *req.Enabled
+	}
+	if req.Rules != nil {
+		rules, err := normalizeRules(*req.Rules)
+		if err != nil {
+			return nil, err
+		}
+		flag.Rules = rules
+	}
+	flag.UpdatedBy = &adminID
+
+	entry := audit.NewLog(ctx, adminID, "feature_flag.update", auditEntities.TargetFeatureFlag, strconv.FormatInt(id, 10))
+	entry.Changes.Set("description", before.Description, flag.Description)
+	entry.Changes.Set("enabled", before.Enabled, flag.Enabled)
+	entry.Changes.Set("rules", before.Rules, flag.Rules)
+	if err := s.repo.UpdateFlag(ctx, flag, entry); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, common.ErrNotFound
+		}
+		return nil, err
+	}
+	s.invalidate(ctx)
+	return flag, nil
+}
+
+// DeleteFlag 删除开关，删除后 Gate 按关闭处理
+func (s *Service) DeleteFlag(ctx context.Context, adminID, id int64) error {
+	flag, err := s.GetFlag(ctx, id)
+	if err != nil {
+		return err
+	}
+	entry := audit.NewLog(ctx, adminID, "feature_flag.delete", auditEntities.TargetFeatureFlag, strconv.FormatInt(id, 10))
+	entry.Changes.Set("key", flag.Key, nil)
+	entry.Changes.Set("enabled", flag.Enabled, nil)
+	entry.Changes.Set("rules", flag.Rules, nil)
+	if err := s.repo.DeleteFlag(ctx, id, entry); err != nil {
+		if err == sql.ErrNoRows {
+			return common.ErrNotFound
+		}
+		return err
+	}
+	s.invalidate(ctx)
+	return nil
+}
+
+// flags 读取全部开关，优先使用缓存
+func (s *Service) flags(ctx context.Context) ([]*entities.Flag, error) {
+	if s.cache != nil {
+		var cached []*entities.Flag
+		if err := s.cache.GetJSON(ctx, flagsCacheKey, &cached); err == nil {
+			return cached, nil
+		}
+	}
+
+	flags, err := s.repo.ListFlags(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.cache != nil {
+		if err := s.cache.Set(ctx, flagsCacheKey, flags, s.cfg.CacheTTL); err != nil {
+			logger.WithError(err).Debug("Failed to cache feature flags")
+		}
+	}
+	return flags, nil
+}
+
+// invalidate 开关变更后清除缓存，变更内容已写入审计记录
+func (s *Service) invalidate(ctx context.Context) {
+	if s.cache == nil {
+		return
+	}
+	if err := s.cache.Delete(ctx, flagsCacheKey); err != nil {
+		logger.WithError(err).Warn("Failed to invalidate feature flag cache")
+	}
+}
+
+// normalizeRules 校验并规范化定向规则：国家代码大写，平台小写，版本号可解析
+func normalizeRules(rules entities.Rules) (entities.Rules, error) {
+	if rules.Percentage != nil && (*rules.Percentage < 0 || *rules.Percentage > 100) {
+		return rules, common.ErrValidation
+	}
+
+	countries := make([]string, 0, len(rules.Countries))
+	for _, country := range rules.Countries {
+		country = strings.ToUpper(strings.TrimSpace(country))
+		if !countryPattern.MatchString(country) {
+			return rules, common.ErrValidation
+		}
+		countries = append(countries, country)
+	}
+	rules.Countries = countries
+
+	platforms := make([]string, 0, len(rules.Platforms))
+	for _, platform := range rules.Platforms {
+		platform = strings.ToLower(strings.TrimSpace(platform))
+		if platform == "" {
+			return rules, common.ErrValidation
+		}
+		platforms = append(platforms, platform)
+	}
+	rules.Platforms = platforms
+
+	rules.MinVersion = strings.TrimSpace(rules.MinVersion)
+	rules.MaxVersion = strings.TrimSpace(rules.MaxVersion)
+	minVersion, minOK := parseVersion(rules.MinVersion)
+	maxVersion, maxOK := parseVersion(rules.MaxVersion)
+	if (rules.MinVersion != "" && !minOK) || (rules.MaxVersion != "" && !maxOK) {
+		return rules, common.ErrValidation
+	}
+	if minOK && maxOK && compareVersions(minVersion, maxVersion) > 0 {
+		return rules, common.ErrValidation
+	}
+
+	for _, id := range rules.UserIDs {
+		if id <= 0 {
+			return rules, common.ErrValidation
+		}
+	}
+	return rules, nil
+}
@@ -0,0 +1,276 @@
+package feature
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	auditEntities "trusioo_api/internal/audit/entities"
+	"trusioo_api/internal/common"
+	"trusioo_api/internal/feature/dto"
+	"trusioo_api/internal/feature/entities"
+	"trusioo_api/internal/middleware"
+	"trusioo_api/pkg/logger"
+)
+
+// fakeRepository 内存版仓库，记录 ListFlags 调用次数和写入的审计记录
+type fakeRepository struct {
+	flags     []*entities.Flag
+	listCalls int
+	audits    []*auditEntities.Log
+}
+
+func (f *fakeRepository) ListFlags(ctx context.Context) ([]*entities.Flag, error) {
+	f.listCalls++
+	flags := make([]*entities.Flag, 0, len(f.flags))
+	for _, flag := range f.flags {
+		copied := *flag
+		flags = append(flags, &copied)
+	}
+	return flags, nil
+}
+
+func (f *fakeRepository) GetFlag(ctx context.Context, id int64) (*entities.Flag, error) {
+	for _, flag := range f.flags {
+		if flag.ID == id {
+			copied := *flag
+			return &copied, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (f *fakeRepository) GetFlagByKey(ctx context.Context, key string) (*entities.Flag, error) {
+	for _, flag := range f.flags {
+		if flag.Key == key {
+			copied := *flag
+			return &copied, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (f *fakeRepository) CreateFlag(ctx context.Context, flag *entities.Flag, entry *auditEntities.Log) error {
+	flag.ID = int64(len(f.flags) + 1)
+	flag.CreatedAt = time.Now()
+	flag.UpdatedAt = flag.CreatedAt
+	copied := *flag
+	f.flags = append(f.flags, &copied)
+	entry.TargetID = strconv.FormatInt(flag.ID, 10)
+	f.audits = append(f.audits, entry)
+	return nil
+}
+
+func (f *fakeRepository) UpdateFlag(ctx context.Context, flag *entities.Flag, entry *auditEntities.Log) error {
+	for i, existing := range f.flags {
+		if existing.ID == flag.ID {
+			flag.UpdatedAt = time.Now()
+			copied := *flag
+			f.flags[i] = &copied
+			f.audits = append(f.audits, entry)
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
+
+func (f *fakeRepository) DeleteFlag(ctx context.Context, id int64, entry *auditEntities.Log) error {
+	for i, flag := range f.flags {
+		if flag.ID == id {
+			f.flags = append(f.flags[:i], f.flags[i+1:]...)
+			f.audits = append(f.audits, entry)
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
+
+// fakeCache 以 JSON 保存缓存值，与 Redis 的序列化行为一致
+type fakeCache struct {
+	items map[string][]byte
+}
+
+func newFakeCache() *fakeCache {
+	return &fakeCache{items: map[string][]byte{}}
+}
+
+func (f *fakeCache) GetJSON(ctx context.Context, key string, dest interface{}) error {
+	data, ok := f.items[key]
+	if !ok {
+		return errors.New("cache miss")
+	}
+	return json.Unmarshal(data, dest)
+}
+
+func (f *fakeCache) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	f.items[key] = data
+	return nil
+}
+
+func (f *fakeCache) Delete(ctx context.Context, key string) error {
+	delete(f.items, key)
+	return nil
+}
+
+// quietLogger 开关变更会写日志，测试中丢弃日志输出
+func quietLogger(t *testing.T) {
+	original := logger.Log
+	logger.Log = logrus.New()
+	logger.Log.SetOutput(&bytes.Buffer{})
+	t.Cleanup(func() { logger.Log = original })
+}
+
+func TestService_CreateFlag(t *testing.T) {
+	quietLogger(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		req     dto.CreateFlagRequest
+		wantErr error
+	}{
+		{
+			name: "规范化规则",
+			req: dto.CreateFlagRequest{
+				Key:   "wallet.withdraw",
+				Rules: entities.Rules{Countries: []string{" us"}, Platforms: []string{"iOS"}, MinVersion: "1.2.0", Percentage: percentage(10)},
+			},
+		},
+		{
+			name:    "key格式错误",
+			req:     dto.CreateFlagRequest{Key: "Wallet Withdraw"},
+			wantErr: common.ErrValidation,
+		},
+		{
+			name:    "灰度比例超出范围",
+			req:     dto.CreateFlagRequest{Key: "wallet.withdraw", Rules: entities.Rules{Percentage: percentage(101)}},
+			wantErr: common.ErrValidation,
+		},
+		{
+			name:    "国家代码错误",
+			req:     dto.CreateFlagRequest{Key: "wallet.withdraw", Rules: entities.Rules{Countries: []string{"USA"}}},
+			wantErr: common.ErrValidation,
+		},
+		{
+			name:    "版本号无法解析",
+			req:     dto.CreateFlagRequest{Key: "wallet.withdraw", Rules: entities.Rules{MaxVersion: "latest"}},
+			wantErr: common.ErrValidation,
+		},
+		{
+			name:    "版本下限高于上限",
+			req:     dto.CreateFlagRequest{Key: "wallet.withdraw", Rules: entities.Rules{MinVersion: "2.0", MaxVersion: "1.9"}},
+			wantErr: common.ErrValidation,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeRepository{}
+			service := NewService(repo, newFakeCache(), nil)
+
+			flag, err := service.CreateFlag(ctx, 9, &tt.req)
+			if tt.wantErr != nil {
+				assert.Equal(t, tt.wantErr, err)
+				assert.Empty(t, repo.flags)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, []string{"US"}, flag.Rules.Countries)
+			assert.Equal(t, []string{"ios"}, flag.Rules.Platforms)
+			assert.Equal(t, int64(9), *flag.UpdatedBy)
+		})
+	}
+
+	t.Run("key已存在", func(t *testing.T) {
+		service := NewService(&fakeRepository{}, nil, nil)
+		_, err := service.CreateFlag(ctx, 9, &dto.CreateFlagRequest{Key: "giftcard.trade"})
+		require.NoError(t, err)
+		_, err = service.CreateFlag(ctx, 9, &dto.CreateFlagRequest{Key: "giftcard.trade"})
+		assert.Equal(t, common.ErrFeatureFlagExists, err)
+	})
+}
+
+func TestService_Evaluate(t *testing.T) {
+	quietLogger(t)
+	ctx := context.Background()
+	ios := &middleware.EvalCtx{Platform: "ios", DeviceID: "device-1"}
+
+	repo := &fakeRepository{}
+	cache := newFakeCache()
+	service := NewService(repo, cache, nil)
+
+	flag, err := service.CreateFlag(ctx, 9, &dto.CreateFlagRequest{
+		Key:     "wallet.withdraw",
+		Enabled: true,
+		Rules:   entities.Rules{Platforms: []string{"ios"}},
+	})
+	require.NoError(t, err)
+	_, err = service.CreateFlag(ctx, 9, &dto.CreateFlagRequest{Key: "giftcard.trade"})
+	require.NoError(t, err)
+
+	t.Run("评估全部开关", func(t *testing.T) {
+		resp, err := service.EvaluateAll(ctx, ios)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]bool{"wallet.withdraw": true, "giftcard.trade": false}, resp.Flags)
+	})
+
+	t.Run("使用缓存", func(t *testing.T) {
+		calls := repo.listCalls
+		for i := 0; i < 3; i++ {
+			enabled, err := service.IsEnabled(ctx, "wallet.withdraw", ios)
+			require.NoError(t, err)
+			assert.True(t, enabled)
+		}
+		assert.Equal(t, calls, repo.listCalls)
+
+		enabled, err := service.IsEnabled(ctx, "unknown.flag", ios)
+		require.NoError(t, err)
+		assert.False(t, enabled)
+	})
+
+	t.Run("修改后立即生效", func(t *testing.T) {
+		disabled := false
+		updated, err := service.UpdateFlag(ctx, 10, flag.ID, &dto.UpdateFlagRequest{Enabled: &disabled})
+		require.NoError(t, err)
+		assert.Equal(t, int64(10), *updated.UpdatedBy)
+		assert.Equal(t, []string{"ios"}, updated.Rules.Platforms)
+
+		entry := repo.audits[len(repo.audits)-1]
+		assert.Equal(t, "feature_flag.update", entry.Action)
+		assert.Equal(t, int64(10), entry.AdminID)
+		assert.Equal(t, strconv.FormatInt(flag.ID, 10), entry.TargetID)
+		assert.Equal(t, auditEntities.Changes{"enabled": {Before: true, After: false}}, entry.Changes)
+
+		enabled, err := service.IsEnabled(ctx, "wallet.withdraw", ios)
+		require.NoError(t, err)
+		assert.False(t, enabled)
+	})
+
+	t.Run("删除后按关闭处理", func(t *testing.T) {
+		enabled := true
+		_, err := service.UpdateFlag(ctx, 10, flag.ID, &dto.UpdateFlagRequest{Enabled: &enabled})
+		require.NoError(t, err)
+		require.NoError(t, service.DeleteFlag(ctx, 10, flag.ID))
+
+		on, err := service.IsEnabled(ctx, "wallet.withdraw", ios)
+		require.NoError(t, err)
+		assert.False(t, on)
+
+		assert.Equal(t, common.ErrNotFound, service.DeleteFlag(ctx, 10, flag.ID))
+		_, err = service.UpdateFlag(ctx, 10, flag.ID, &dto.UpdateFlagRequest{Enabled: &enabled})
+		assert.Equal(t, common.ErrNotFound, err)
+	})
+}
@@ -1,12 +1,13 @@
 package images
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
-	"strings"
 
 	"github.com/gin-gonic/gin"
+	"trusioo_api/internal/audit"
 	"trusioo_api/internal/common"
 	"trusioo_api/internal/images/dto"
 )
@@ -26,12 +27,15 @@ func getUserID(c *gin.Context) (int, error) {
 		return 0, fmt.Errorf("user not authenticated")
 	}
 	
-	userID, ok := userIDValue.(int)
-	if !ok {
+	// AuthMiddleware 与 APIKeyAuthMiddleware 设置的用户ID为 int64
+	switch userID := userIDValue.(type) {
+	case int64:
+		return int(userID), nil
+	case int:
+		return userID, nil
+	default:
 		return 0, fmt.Errorf("invalid user ID format")
 	}
-	
-	return userID, nil
 }
 
 
@@ -103,7 +107,7 @@ func (h *Handler) GetImage(c *gin.Context) {
 
 	result, err := h.service.GetUserImage(c.Request.Context(), userID, imageID)
 	if err != nil {
-		if strings.Contains(err.Error(), "not found") || strings.Contains(err.Error(), "access denied") {
+		if errors.Is(err, common.ErrNotFound) {
 			c.JSON(http.StatusNotFound, common.ErrorResponse{
 				Error:   "IMAGE_NOT_FOUND",
 				Message: "Image not found or access denied",
@@ -204,7 +208,7 @@ func (h *Handler) DeleteImage(c *gin.Context) {
 
 	err = h.service.DeleteUserImage(c.Request.Context(), userID, imageID)
 	if err != nil {
-		if strings.Contains(err.Error(), "not found") || strings.Contains(err.Error(), "access denied") {
+		if errors.Is(err, common.ErrNotFound) {
 			c.JSON(http.StatusNotFound, common.ErrorResponse{
 				Error:   "IMAGE_NOT_FOUND",
 				Message: "Image not found or access denied",
@@ -246,7 +250,7 @@ func (h *Handler) RefreshURL(c *gin.Context) {
 
 	result, err := h.service.RefreshUserImageURL(c.Request.Context(), userID, imageID)
 	if err != nil {
-		if strings.Contains(err.Error(), "not found") || strings.Contains(err.Error(), "access denied") {
+		if errors.Is(err, common.ErrNotFound) {
 			c.JSON(http.StatusNotFound, common.ErrorResponse{
 				Error:   "IMAGE_NOT_FOUND",
 				Message: "Image not found or access denied",
@@ -332,7 +336,7 @@ func (h *Handler) AdminDeleteImage(c *gin.Context) {
 		return
 	}
 
-	err = h.service.AdminDeleteAnyImage(c.Request.Context(), imageID)
+	err = h.service.AdminDeleteAnyImage(audit.Context(c), imageID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, common.ErrorResponse{
 			Error:   "DELETE_FAILED",
@@ -346,7 +350,7 @@ func (h *Handler) AdminDeleteImage(c *gin.Context) {
 	})
 }
 
-// 管理员批量删除图片
+// 管理员批量删除图片，需要近期重新验证身份的提升令牌
 func (h *Handler) AdminBatchDeleteImages(c *gin.Context) {
 	type BatchDeleteRequest struct {
 		ImageIDs []int `json:"image_ids" binding:"required"`
@@ -369,7 +373,7 @@ func (h *Handler) AdminBatchDeleteImages(c *gin.Context) {
 		return
 	}
 
-	deletedCount, err := h.service.AdminBatchDeleteImages(c.Request.Context(), req.ImageIDs)
+	deletedCount, err := h.service.AdminBatchDeleteImages(audit.Context(c), req.ImageIDs)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, common.ErrorResponse{
 			Error:   "BATCH_DELETE_FAILED",
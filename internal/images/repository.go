@@ -2,9 +2,13 @@ package images
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 
 	"github.com/jmoiron/sqlx"
+	"trusioo_api/internal/audit"
+	auditEntities "trusioo_api/internal/audit/entities"
+	"trusioo_api/internal/common"
 	"trusioo_api/internal/images/entities"
 )
 
@@ -15,6 +19,7 @@ type Repository interface {
 	List(ctx context.Context, userID *int, folder string, isPublic *bool, offset, limit int) ([]*entities.Image, int64, error)
 	Update(ctx context.Context, image *entities.Image) error
 	Delete(ctx context.Context, id int) error
+	AdminDelete(ctx context.Context, id int, entry *auditEntities.Log) error
 }
 
 type repository struct {
@@ -61,6 +66,9 @@ func (r *repository) GetByID(ctx context.Context, id int) (*entities.Image, erro
 	image := &entities.Image{}
 	err := r.db.GetContext(ctx, image, query, id)
 	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, common.ErrNotFound
+		}
 		return nil, fmt.Errorf("failed to get image by ID: %w", err)
 	}
 	
@@ -76,6 +84,9 @@ func (r *repository) GetByKey(ctx context.Context, key string) (*entities.Image,
 	image := &entities.Image{}
 	err := r.db.GetContext(ctx, image, query, key)
 	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, common.ErrNotFound
+		}
 		return nil, fmt.Errorf("failed to get image by key: %w", err)
 	}
 	
@@ -167,8 +178,36 @@ func (r *repository) Delete(ctx context.Context, id int) error {
 	}
 	
 	if rowsAffected == 0 {
-		return fmt.Errorf("image not found")
+		return common.ErrNotFound
 	}
 	
 	return nil
-}
\ No newline at end of file
+}
+// AdminDelete 管理员删除图片，审计记录与删除在同一事务中提交
+func (r *repository) AdminDelete(ctx context.Context, id int, entry *auditEntities.Log) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM images WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete image: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return common.ErrNotFound
+	}
+
+	if err := audit.Append(ctx, tx, entry); err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+
+	return tx.Commit()
+}
@@ -1,8 +1,14 @@
 package images
 
 import (
+	"time"
+
 	"github.com/gin-gonic/gin"
+	"trusioo_api/config"
+	"trusioo_api/internal/apikeys"
+	"trusioo_api/internal/auth/rbac"
 	"trusioo_api/internal/middleware"
+	"trusioo_api/pkg/redis"
 )
 
 func RegisterRoutes(r *gin.RouterGroup, handler *Handler) {
@@ -11,25 +17,33 @@ func RegisterRoutes(r *gin.RouterGroup, handler *Handler) {
 		// Public routes - 完全公开，无需认证
 		images.GET("/public/:key", handler.GetImageByKey)
 		
-		// User routes - 需要用户认证
+		// User routes - 需要用户认证，合作方后端可以使用带对应范围的API密钥调用
+		// API密钥请求可以携带 X-Signature 签名，要求签名的密钥不签名会被拒绝
+		maxSkew := time.Duration(config.AppConfig.APIKey.SignatureMaxSkewSeconds) * time.Second
 		userRoutes := images.Group("")
-		userRoutes.Use(middleware.AuthMiddleware()) // 必须登录
+		userRoutes.Use(middleware.AuthOrAPIKeyMiddleware()) // 必须登录或使用API密钥
+		userRoutes.Use(middleware.SignatureMiddleware(redis.NonceCache, maxSkew))
 		{
-			userRoutes.POST("/upload", handler.UploadImage)
-			userRoutes.GET("/", handler.ListImages)              // 只显示用户自己的图片
-			userRoutes.GET("/:id", handler.GetImage)             // 只能查看自己的图片
-			userRoutes.PUT("/:id/refresh", handler.RefreshURL)   // 只能刷新自己的图片URL
-			userRoutes.DELETE("/:id", handler.DeleteImage)       // 只能删除自己的图片
+			canRead := middleware.RequireScope(apikeys.ScopeImagesRead)
+			canWrite := middleware.RequireScope(apikeys.ScopeImagesWrite)
+			userRoutes.POST("/upload", canWrite, handler.UploadImage)
+			userRoutes.GET("/", canRead, handler.ListImages)              // 只显示用户自己的图片
+			userRoutes.GET("/:id", canRead, handler.GetImage)             // 只能查看自己的图片
+			userRoutes.PUT("/:id/refresh", canRead, handler.RefreshURL)   // 只能刷新自己的图片URL
+			userRoutes.DELETE("/:id", canWrite, handler.DeleteImage)      // 只能删除自己的图片
 		}
 		
 		// Admin routes - 需要管理员权限
 		adminRoutes := images.Group("/admin")
 		adminRoutes.Use(middleware.AdminAuthMiddleware()) // 必须是管理员
 		{
-			adminRoutes.GET("/", handler.AdminListImages)        // 管理员查看所有图片
-			adminRoutes.GET("/:id", handler.AdminGetImage)       // 管理员查看任意图片
-			adminRoutes.DELETE("/:id", handler.AdminDeleteImage) // 管理员删除任意图片
-			adminRoutes.POST("/batch-delete", handler.AdminBatchDeleteImages) // 批量删除
+			canRead := middleware.RequirePermission(rbac.PermImagesRead)
+			canDelete := middleware.RequirePermission(rbac.PermImagesDelete)
+			recentAuth := middleware.RequireRecentAuth(middleware.StepUpMaxAge())
+			adminRoutes.GET("/", canRead, handler.AdminListImages)          // 管理员查看所有图片
+			adminRoutes.GET("/:id", canRead, handler.AdminGetImage)         // 管理员查看任意图片
+			adminRoutes.DELETE("/:id", canDelete, handler.AdminDeleteImage) // 管理员删除任意图片
+			adminRoutes.POST("/batch-delete", canDelete, recentAuth, handler.AdminBatchDeleteImages) // 批量删除，需要提升令牌
 		}
 	}
 }
\ No newline at end of file
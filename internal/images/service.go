@@ -5,8 +5,12 @@ import (
 	"fmt"
 	"mime/multipart"
 	"path/filepath"
+	"strconv"
 	"time"
 
+	"trusioo_api/internal/audit"
+	auditEntities "trusioo_api/internal/audit/entities"
+	"trusioo_api/internal/common"
 	"trusioo_api/internal/images/dto"
 	"trusioo_api/internal/images/entities"
 	"trusioo_api/pkg/r2storage"
@@ -103,12 +107,12 @@ func (s *service) UploadImage(ctx context.Context, userID *int, file *multipart.
 func (s *service) GetUserImage(ctx context.Context, userID int, imageID int) (*dto.GetImageResponse, error) {
 	image, err := s.repo.GetByID(ctx, imageID)
 	if err != nil {
-		return nil, fmt.Errorf("image not found: %w", err)
+		return nil, fmt.Errorf("failed to get image: %w", err)
 	}
 
 	// 验证所有权
 	if image.UserID == nil || *image.UserID != userID {
-		return nil, fmt.Errorf("access denied: image belongs to another user")
+		return nil, fmt.Errorf("image belongs to another user: %w", common.ErrNotFound)
 	}
 
 	if !image.IsPublic && image.PublicURL == nil {
@@ -138,12 +142,12 @@ func (s *service) GetUserImage(ctx context.Context, userID int, imageID int) (*d
 func (s *service) GetPublicImageByKey(ctx context.Context, key string) (*dto.GetImageResponse, error) {
 	image, err := s.repo.GetByKey(ctx, key)
 	if err != nil {
-		return nil, fmt.Errorf("image not found: %w", err)
+		return nil, fmt.Errorf("failed to get image: %w", err)
 	}
 
 	// 只允许访问公开图片
 	if !image.IsPublic {
-		return nil, fmt.Errorf("access denied: this is a private image")
+		return nil, fmt.Errorf("image is private: %w", common.ErrNotFound)
 	}
 
 	return &dto.GetImageResponse{
@@ -215,12 +219,12 @@ func (s *service) ListImages(ctx context.Context, userID *int, req dto.ListImage
 func (s *service) DeleteUserImage(ctx context.Context, userID int, imageID int) error {
 	image, err := s.repo.GetByID(ctx, imageID)
 	if err != nil {
-		return fmt.Errorf("image not found: %w", err)
+		return fmt.Errorf("failed to get image: %w", err)
 	}
 
 	// 验证所有权
 	if image.UserID == nil || *image.UserID != userID {
-		return fmt.Errorf("access denied: image belongs to another user")
+		return fmt.Errorf("image belongs to another user: %w", common.ErrNotFound)
 	}
 
 	err = s.r2Client.DeleteFile(ctx, image.Bucket, image.Key)
@@ -239,12 +243,12 @@ func (s *service) DeleteUserImage(ctx context.Context, userID int, imageID int)
 func (s *service) RefreshUserImageURL(ctx context.Context, userID int, imageID int) (*dto.GetImageResponse, error) {
 	image, err := s.repo.GetByID(ctx, imageID)
 	if err != nil {
-		return nil, fmt.Errorf("image not found: %w", err)
+		return nil, fmt.Errorf("failed to get image: %w", err)
 	}
 
 	// 验证所有权
 	if image.UserID == nil || *image.UserID != userID {
-		return nil, fmt.Errorf("access denied: image belongs to another user")
+		return nil, fmt.Errorf("image belongs to another user: %w", common.ErrNotFound)
 	}
 
 	if !image.IsPublic {
@@ -330,7 +334,7 @@ func (s *service) AdminListAllImages(ctx context.Context, req dto.ListImagesRequ
 func (s *service) AdminGetAnyImage(ctx context.Context, imageID int) (*dto.GetImageResponse, error) {
 	image, err := s.repo.GetByID(ctx, imageID)
 	if err != nil {
-		return nil, fmt.Errorf("image not found: %w", err)
+		return nil, fmt.Errorf("failed to get image: %w", err)
 	}
 
 	if !image.IsPublic && image.PublicURL == nil {
@@ -357,10 +361,30 @@ func (s *service) AdminGetAnyImage(ctx context.Context, imageID int) (*dto.GetIm
 	}, nil
 }
 
+// AdminDeleteAnyImage 管理员删除图片，操作的管理员取自 audit.Context 写入的审计信息
 func (s *service) AdminDeleteAnyImage(ctx context.Context, imageID int) error {
+	return s.adminDelete(ctx, imageID, "image.delete")
+}
+
+// AdminBatchDeleteImages 管理员批量删除图片，每张图片单独记录审计
+func (s *service) AdminBatchDeleteImages(ctx context.Context, imageIDs []int) (int, error) {
+	deletedCount := 0
+	
+	for _, imageID := range imageIDs {
+		err := s.adminDelete(ctx, imageID, "image.batch_delete")
+		if err == nil {
+			deletedCount++
+		}
+		// 继续删除其他图片，即使某个删除失败
+	}
+
+	return deletedCount, nil
+}
+
+func (s *service) adminDelete(ctx context.Context, imageID int, action string) error {
 	image, err := s.repo.GetByID(ctx, imageID)
 	if err != nil {
-		return fmt.Errorf("image not found: %w", err)
+		return fmt.Errorf("failed to get image: %w", err)
 	}
 
 	err = s.r2Client.DeleteFile(ctx, image.Bucket, image.Key)
@@ -368,24 +392,20 @@ func (s *service) AdminDeleteAnyImage(ctx context.Context, imageID int) error {
 		return fmt.Errorf("failed to delete file from R2: %w", err)
 	}
 
-	err = s.repo.Delete(ctx, imageID)
+	entry := audit.NewLog(ctx, audit.MetaFrom(ctx).AdminID, action, auditEntities.TargetImage, strconv.Itoa(imageID))
+	entry.Changes.Set("image", map[string]interface{}{
+		"user_id":   image.UserID,
+		"file_name": image.OriginalName,
+		"bucket":    image.Bucket,
+		"key":       image.Key,
+		"size":      image.Size,
+		"is_public": image.IsPublic,
+	}, nil)
+
+	err = s.repo.AdminDelete(ctx, imageID, entry)
 	if err != nil {
 		return fmt.Errorf("failed to delete image from database: %w", err)
 	}
 
 	return nil
 }
-
-func (s *service) AdminBatchDeleteImages(ctx context.Context, imageIDs []int) (int, error) {
-	deletedCount := 0
-	
-	for _, imageID := range imageIDs {
-		err := s.AdminDeleteAnyImage(ctx, imageID)
-		if err == nil {
-			deletedCount++
-		}
-		// 继续删除其他图片，即使某个删除失败
-	}
-
-	return deletedCount, nil
-}
\ No newline at end of file
@@ -8,6 +8,7 @@ import (
 	"sync"
 	"time"
 
+	"trusioo_api/internal/common"
 	"trusioo_api/internal/images/dto"
 	"trusioo_api/internal/images/entities"
 	"trusioo_api/pkg/cache"
@@ -221,12 +222,12 @@ func (s *OptimizedImageService) GetUserImage(ctx context.Context, userID int, im
 	
 	image, err := s.repo.GetByID(ctx, imageID)
 	if err != nil {
-		return nil, fmt.Errorf("image not found: %w", err)
+		return nil, fmt.Errorf("failed to get image: %w", err)
 	}
 	
 	// 验证所有权
 	if image.UserID == nil || *image.UserID != userID {
-		return nil, fmt.Errorf("access denied: image belongs to another user")
+		return nil, fmt.Errorf("image belongs to another user: %w", common.ErrNotFound)
 	}
 	
 	// 更新缓存
@@ -261,12 +262,12 @@ func (s *OptimizedImageService) GetPublicImageByKey(ctx context.Context, key str
 	
 	image, err := s.repo.GetByKey(ctx, key)
 	if err != nil {
-		return nil, fmt.Errorf("image not found: %w", err)
+		return nil, fmt.Errorf("failed to get image: %w", err)
 	}
 	
 	// 只允许访问公开图片
 	if !image.IsPublic {
-		return nil, fmt.Errorf("access denied: this is a private image")
+		return nil, fmt.Errorf("image is private: %w", common.ErrNotFound)
 	}
 	
 	// 更新缓存
@@ -0,0 +1,95 @@
+package images
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	auditEntities "trusioo_api/internal/audit/entities"
+	"trusioo_api/internal/common"
+	"trusioo_api/internal/images/entities"
+)
+
+// fakeRepository 内存版仓库，不存在的图片与真实仓库一样返回 common.ErrNotFound
+type fakeRepository struct {
+	images map[int]*entities.Image
+}
+
+func (f *fakeRepository) Create(ctx context.Context, image *entities.Image) error {
+	f.images[image.ID] = image
+	return nil
+}
+
+func (f *fakeRepository) GetByID(ctx context.Context, id int) (*entities.Image, error) {
+	image, ok := f.images[id]
+	if !ok {
+		return nil, common.ErrNotFound
+	}
+	return image, nil
+}
+
+func (f *fakeRepository) GetByKey(ctx context.Context, key string) (*entities.Image, error) {
+	for _, image := range f.images {
+		if image.Key == key {
+			return image, nil
+		}
+	}
+	return nil, common.ErrNotFound
+}
+
+func (f *fakeRepository) List(ctx context.Context, userID *int, folder string, isPublic *bool, offset, limit int) ([]*entities.Image, int64, error) {
+	return nil, 0, nil
+}
+
+func (f *fakeRepository) Update(ctx context.Context, image *entities.Image) error {
+	return nil
+}
+
+func (f *fakeRepository) Delete(ctx context.Context, id int) error {
+	if _, ok := f.images[id]; !ok {
+		return common.ErrNotFound
+	}
+	delete(f.images, id)
+	return nil
+}
+
+func (f *fakeRepository) AdminDelete(ctx context.Context, id int, entry *auditEntities.Log) error {
+	return f.Delete(ctx, id)
+}
+
+// 测试图片不存在或不属于当前用户时返回 common.ErrNotFound
+func TestService_NotFound(t *testing.T) {
+	owner := 1
+	publicURL := "https://cdn.example.com/avatars/me.png"
+	repo := &fakeRepository{images: map[int]*entities.Image{
+		3: {ID: 3, UserID: &owner, Key: "avatars/me.png", IsPublic: true, PublicURL: &publicURL},
+		4: {ID: 4, UserID: &owner, Key: "docs/private.pdf"},
+	}}
+	service := NewService(repo, nil)
+	ctx := context.Background()
+
+	image, err := service.GetUserImage(ctx, owner, 3)
+	require.NoError(t, err)
+	assert.Equal(t, "avatars/me.png", image.Key)
+
+	_, err = service.GetUserImage(ctx, owner, 99)
+	assert.ErrorIs(t, err, common.ErrNotFound)
+
+	_, err = service.GetUserImage(ctx, 2, 3)
+	assert.ErrorIs(t, err, common.ErrNotFound)
+
+	_, err = service.RefreshUserImageURL(ctx, 2, 3)
+	assert.ErrorIs(t, err, common.ErrNotFound)
+
+	err = service.DeleteUserImage(ctx, 2, 3)
+	assert.ErrorIs(t, err, common.ErrNotFound)
+	assert.Contains(t, repo.images, 3)
+
+	_, err = service.GetPublicImageByKey(ctx, "docs/private.pdf")
+	assert.ErrorIs(t, err, common.ErrNotFound)
+
+	_, err = service.AdminGetAnyImage(ctx, 99)
+	assert.ErrorIs(t, err, common.ErrNotFound)
+}
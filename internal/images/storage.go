@@ -0,0 +1,59 @@
+package images
+
+import (
+	"context"
+	"mime/multipart"
+	"time"
+
+	"trusioo_api/pkg/r2storage"
+)
+
+// FileStorage 图片文件的对象存储，由 r2storage.Client 实现
+type FileStorage interface {
+	ValidateFile(fileHeader *multipart.FileHeader) error
+	UploadFile(ctx context.Context, fileHeader *multipart.FileHeader, options r2storage.UploadOptions) (*r2storage.UploadResult, error)
+	GeneratePresignedURL(ctx context.Context, bucket, key string, expiration time.Duration) (string, error)
+	DeleteFile(ctx context.Context, bucket, key string) error
+}
+
+// StoredFile 已写入存储桶的文件
+type StoredFile struct {
+	Bucket string
+	Key    string
+	Size   int64
+}
+
+// PrivateStorage 只写入私有存储桶、不保存图片记录的图片存储
+// 供自行管理元数据的模块保存敏感照片（如KYC证件），照片不会出现在用户的图片列表中
+type PrivateStorage struct {
+	files FileStorage
+}
+
+// NewPrivateStorage 创建私有图片存储
+func NewPrivateStorage(files FileStorage) *PrivateStorage {
+	return &PrivateStorage{files: files}
+}
+
+// Validate 按图片上传的大小和类型限制校验文件
+func (s *PrivateStorage) Validate(file *multipart.FileHeader) error {
+	return s.files.ValidateFile(file)
+}
+
+// Upload 上传到私有存储桶的 folder 目录，文件名随机生成
+func (s *PrivateStorage) Upload(ctx context.Context, file *multipart.FileHeader, folder string) (*StoredFile, error) {
+	result, err := s.files.UploadFile(ctx, file, r2storage.UploadOptions{IsPublic: false, Folder: folder})
+	if err != nil {
+		return nil, err
+	}
+	return &StoredFile{Bucket: result.Bucket, Key: result.Key, Size: result.Size}, nil
+}
+
+// PresignedURL 生成限时查看链接
+func (s *PrivateStorage) PresignedURL(ctx context.Context, bucket, key string, ttl time.Duration) (string, error) {
+	return s.files.GeneratePresignedURL(ctx, bucket, key, ttl)
+}
+
+// Delete 删除文件
+func (s *PrivateStorage) Delete(ctx context.Context, bucket, key string) error {
+	return s.files.DeleteFile(ctx, bucket, key)
+}
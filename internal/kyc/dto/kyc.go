@@ -0,0 +1,58 @@
+package dto
+
+import (
+	"time"
+
+	"trusioo_api/internal/kyc/entities"
+)
+
+// SubmitRequest 提交身份认证申请，multipart 表单，证件照片字段为 id_front、id_back、selfie
+// 等级1需要证件正反面（护照只需正面），等级2另外需要自拍
+type SubmitRequest struct {
+	Level          int    `form:"level" binding:"required,oneof=1 2"`
+	FullName       string `form:"full_name" binding:"required,max=100"`
+	DateOfBirth    string `form:"date_of_birth" binding:"required,datetime=2006-01-02"`
+	Nationality    string `form:"nationality" binding:"required,len=2,alpha"`
+	DocumentType   string `form:"document_type" binding:"required,oneof=id_card passport driving_license"`
+	DocumentNumber string `form:"document_number" binding:"required,max=50"`
+	Address        string `form:"address" binding:"omitempty,max=255"`
+}
+
+// StatusResponse 当前用户的认证等级和最近一次申请
+type StatusResponse struct {
+	Level      int                  `json:"level"`
+	Submission *entities.Submission `json:"submission,omitempty"`
+}
+
+// QueueRequest 审核队列请求，默认返回待审核的申请，按提交时间从早到晚排序
+type QueueRequest struct {
+	Status   string `form:"status" binding:"omitempty,oneof=pending approved rejected"`
+	Page     int    `form:"page" binding:"omitempty,min=1"`
+	PageSize int    `form:"page_size" binding:"omitempty,min=1,max=100"`
+}
+
+// QueueResponse 审核队列
+type QueueResponse struct {
+	Total       int64                  `json:"total"`
+	Page        int                    `json:"page"`
+	Size        int                    `json:"size"`
+	Submissions []*entities.Submission `json:"submissions"`
+}
+
+// DocumentView 证件照片的限时查看链接
+type DocumentView struct {
+	*entities.Document
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// SubmissionDetail 审核详情
+type SubmissionDetail struct {
+	Submission *entities.Submission `json:"submission"`
+	Documents  []*DocumentView      `json:"documents"`
+}
+
+// RejectRequest 拒绝申请，原因会展示给用户
+type RejectRequest struct {
+	Reason string `json:"reason" binding:"required,max=255"`
+}
@@ -0,0 +1,62 @@
+package entities
+
+import "time"
+
+// 认证等级，路由通过 middleware.RequireKYCLevel 要求最低等级
+const (
+	LevelNone     = 0 // 未认证
+	LevelDocument = 1 // 证件认证：个人信息与证件照片
+	LevelSelfie   = 2 // 证件与自拍认证
+)
+
+// 申请状态
+const (
+	StatusPending  = "pending"
+	StatusApproved = "approved"
+	StatusRejected = "rejected"
+)
+
+// 证件类型
+const (
+	DocumentTypeIDCard         = "id_card"
+	DocumentTypePassport       = "passport"
+	DocumentTypeDrivingLicense = "driving_license"
+)
+
+// 证件照片类型
+const (
+	DocumentIDFront = "id_front"
+	DocumentIDBack  = "id_back"
+	DocumentSelfie  = "selfie"
+)
+
+// Submission 身份认证申请
+type Submission struct {
+	ID              int64      `json:"id" db:"id"`
+	UserID          int64      `json:"user_id" db:"user_id"`
+	Level           int        `json:"level" db:"level"`
+	Status          string     `json:"status" db:"status"`
+	FullName        string     `json:"full_name" db:"full_name"`
+	DateOfBirth     time.Time  `json:"date_of_birth" db:"date_of_birth"`
+	Nationality     string     `json:"nationality" db:"nationality"`
+	DocumentType    string     `json:"document_type" db:"document_type"`
+	DocumentNumber  string     `json:"document_number" db:"document_number"`
+	Address         string     `json:"address" db:"address"`
+	RejectionReason string     `json:"rejection_reason,omitempty" db:"rejection_reason"`
+	ReviewedBy      *int64     `json:"reviewed_by,omitempty" db:"reviewed_by"`
+	ReviewedAt      *time.Time `json:"reviewed_at,omitempty" db:"reviewed_at"`
+	CreatedAt       time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// Document 证件照片，保存在私有存储桶
+type Document struct {
+	ID           int64     `json:"id" db:"id"`
+	SubmissionID int64     `json:"submission_id" db:"submission_id"`
+	Type         string    `json:"type" db:"type"`
+	Bucket       string    `json:"-" db:"bucket"`
+	ObjectKey    string    `json:"-" db:"object_key"`
+	ContentType  string    `json:"content_type" db:"content_type"`
+	Size         int64     `json:"size" db:"size"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+}
@@ -0,0 +1,274 @@
+package kyc
+
+import (
+	"mime/multipart"
+	"strconv"
+
+	"trusioo_api/internal/audit"
+	"trusioo_api/internal/common"
+	"trusioo_api/internal/kyc/dto"
+	"trusioo_api/internal/kyc/entities"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// GetStatus 获取身份认证状态
+// @Summary 获取身份认证状态
+// @Description 获取当前用户的认证等级和最近一次申请，被拒绝时包含拒绝原因
+// @Tags 身份认证
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} common.Response{data=dto.StatusResponse} "获取成功"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 500 {object} common.Response "服务器错误"
+// @Router /api/v1/kyc [get]
+func (h *Handler) GetStatus(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		common.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	resp, err := h.service.Status(c.Request.Context(), userID.(int64))
+	if err != nil {
+		switch err {
+		case common.ErrUserNotFound:
+			common.NotFound(c, "User not found")
+		default:
+			common.ServerError(c, err)
+		}
+		return
+	}
+
+	common.Success(c, resp)
+}
+
+// Submit 提交身份认证申请
+// @Summary 提交身份认证申请
+// @Description 提交个人信息和证件照片（multipart），照片保存在私有存储桶。等级1需要 id_front 和 id_back（护照只需 id_front），等级2另外需要 selfie
+// @Tags 身份认证
+// @Accept multipart/form-data
+// @Produce json
+// @Security ApiKeyAuth
+// @Param level formData int true "申请等级 1 或 2"
+// @Param full_name formData string true "证件姓名"
+// @Param date_of_birth formData string true "出生日期 YYYY-MM-DD"
+// @Param nationality formData string true "国籍 ISO 3166-1 两位代码"
+// @Param document_type formData string true "证件类型 id_card/passport/driving_license"
+// @Param document_number formData string true "证件号码"
+// @Param address formData string false "地址"
+// @Param id_front formData file true "证件正面"
+// @Param id_back formData file false "证件背面"
+// @Param selfie formData file false "手持证件自拍"
+// @Success 200 {object} common.Response{data=entities.Submission} "提交成功"
+// @Failure 400 {object} common.Response "参数错误或已有待审核的申请"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "代登录令牌不能提交"
+// @Failure 500 {object} common.Response "服务器错误"
+// @Router /api/v1/kyc/submissions [post]
+func (h *Handler) Submit(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		common.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	var req dto.SubmitRequest
+	if err := c.ShouldBind(&req); err != nil {
+		common.ValidationError(c, err.Error())
+		return
+	}
+
+	files := make(map[string]*multipart.FileHeader)
+	for _, docType := range []string{entities.DocumentIDFront, entities.DocumentIDBack, entities.DocumentSelfie} {
+		if file, err := c.FormFile(docType); err == nil {
+			files[docType] = file
+		}
+	}
+
+	submission, err := h.service.Submit(c.Request.Context(), userID.(int64), &req, files)
+	if err != nil {
+		switch err {
+		case common.ErrValidation:
+			common.ValidationError(c, "Level must be above the current level, date of birth must be in the past and the required documents must be images within the size limit")
+		case common.ErrKYCPending:
+			common.ValidationError(c, "A KYC submission is already pending review")
+		case common.ErrUserNotFound:
+			common.NotFound(c, "User not found")
+		default:
+			common.ServerError(c, err)
+		}
+		return
+	}
+
+	common.Success(c, submission)
+}
+
+// ListSubmissions 身份认证审核队列
+// @Summary 身份认证审核队列
+// @Description 按状态分页获取身份认证申请，默认返回待审核的申请，按提交时间从早到晚排序
+// @Tags 管理员-身份认证
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param status query string false "状态 pending/approved/rejected"
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Success 200 {object} common.Response{data=dto.QueueResponse} "获取成功"
+// @Failure 400 {object} common.Response "参数错误"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足"
+// @Failure 500 {object} common.Response "服务器错误"
+// @Router /api/v1/admin/kyc/submissions [get]
+func (h *Handler) ListSubmissions(c *gin.Context) {
+	var req dto.QueueRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		common.ValidationError(c, err.Error())
+		return
+	}
+
+	resp, err := h.service.Queue(c.Request.Context(), &req)
+	if err != nil {
+		common.ServerError(c, err)
+		return
+	}
+
+	common.Success(c, resp)
+}
+
+// GetSubmission 身份认证申请详情
+// @Summary 身份认证申请详情
+// @Description 获取申请详情，证件照片返回限时查看链接，每次查看都写入审计记录
+// @Tags 管理员-身份认证
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "申请ID"
+// @Success 200 {object} common.Response{data=dto.SubmissionDetail} "获取成功"
+// @Failure 400 {object} common.Response "参数错误"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足"
+// @Failure 404 {object} common.Response "申请不存在"
+// @Failure 500 {object} common.Response "服务器错误"
+// @Router /api/v1/admin/kyc/submissions/{id} [get]
+func (h *Handler) GetSubmission(c *gin.Context) {
+	adminID, id, ok := reviewIDs(c)
+	if !ok {
+		return
+	}
+
+	resp, err := h.service.GetSubmissionDetail(audit.Context(c), adminID, id)
+	if err != nil {
+		handleReviewError(c, err)
+		return
+	}
+
+	common.Success(c, resp)
+}
+
+// ApproveSubmission 审核通过身份认证申请
+// @Summary 审核通过身份认证申请
+// @Description 审核通过待审核的申请，用户的认证等级提升到申请的等级
+// @Tags 管理员-身份认证
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "申请ID"
+// @Success 200 {object} common.Response{data=entities.Submission} "审核成功"
+// @Failure 400 {object} common.Response "参数错误或申请已审核"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足"
+// @Failure 404 {object} common.Response "申请不存在"
+// @Failure 500 {object} common.Response "服务器错误"
+// @Router /api/v1/admin/kyc/submissions/{id}/approve [post]
+func (h *Handler) ApproveSubmission(c *gin.Context) {
+	adminID, id, ok := reviewIDs(c)
+	if !ok {
+		return
+	}
+
+	submission, err := h.service.Approve(audit.Context(c), adminID, id)
+	if err != nil {
+		handleReviewError(c, err)
+		return
+	}
+
+	common.Success(c, submission)
+}
+
+// RejectSubmission 拒绝身份认证申请
+// @Summary 拒绝身份认证申请
+// @Description 拒绝待审核的申请，拒绝原因展示给用户，用户可以重新提交
+// @Tags 管理员-身份认证
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "申请ID"
+// @Param request body dto.RejectRequest true "拒绝原因"
+// @Success 200 {object} common.Response{data=entities.Submission} "审核成功"
+// @Failure 400 {object} common.Response "参数错误或申请已审核"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足"
+// @Failure 404 {object} common.Response "申请不存在"
+// @Failure 500 {object} common.Response "服务器错误"
+// @Router /api/v1/admin/kyc/submissions/{id}/reject [post]
+func (h *Handler) RejectSubmission(c *gin.Context) {
+	adminID, id, ok := reviewIDs(c)
+	if !ok {
+		return
+	}
+
+	var req dto.RejectRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ValidationError(c, err.Error())
+		return
+	}
+
+	submission, err := h.service.Reject(audit.Context(c), adminID, id, &req)
+	if err != nil {
+		handleReviewError(c, err)
+		return
+	}
+
+	common.Success(c, submission)
+}
+
+// reviewIDs 获取当前管理员ID和路径中的申请ID，失败时已写入响应
+func reviewIDs(c *gin.Context) (int64, int64, bool) {
+	adminID, exists := c.Get("user_id")
+	if !exists {
+		common.Unauthorized(c, "Admin not authenticated")
+		return 0, 0, false
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		common.ValidationError(c, "Invalid submission ID")
+		return 0, 0, false
+	}
+
+	return adminID.(int64), id, true
+}
+
+// handleReviewError 审核操作的通用错误响应
+func handleReviewError(c *gin.Context, err error) {
+	switch err {
+	case common.ErrNotFound:
+		common.NotFound(c, "KYC submission not found")
+	case common.ErrKYCAlreadyReviewed:
+		common.ValidationError(c, "KYC submission has already been reviewed")
+	case common.ErrValidation:
+		common.ValidationError(c, "Rejection reason is required")
+	default:
+		common.ServerError(c, err)
+	}
+}
@@ -0,0 +1,198 @@
+package kyc
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"trusioo_api/internal/audit"
+	auditEntities "trusioo_api/internal/audit/entities"
+	"trusioo_api/internal/kyc/entities"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Repository 身份认证的数据访问接口
+type Repository interface {
+	GetUserLevel(ctx context.Context, userID int64) (int, error)
+	GetLatestSubmission(ctx context.Context, userID int64) (*entities.Submission, error)
+	CreateSubmission(ctx context.Context, submission *entities.Submission, documents []*entities.Document) error
+
+	ListSubmissions(ctx context.Context, status string, limit, offset int) ([]*entities.Submission, int64, error)
+	GetSubmission(ctx context.Context, id int64) (*entities.Submission, error)
+	ListDocuments(ctx context.Context, submissionID int64) ([]*entities.Document, error)
+	ApproveSubmission(ctx context.Context, id, adminID int64, entry *auditEntities.Log) error
+	RejectSubmission(ctx context.Context, id, adminID int64, reason string, entry *auditEntities.Log) error
+	RecordAudit(ctx context.Context, entry *auditEntities.Log) error
+}
+
+type repository struct {
+	db *sqlx.DB
+}
+
+// NewRepository 创建身份认证仓库
+func NewRepository(db *sqlx.DB) Repository {
+	return &repository{db: db}
+}
+
+// GetUserLevel 获取用户的认证等级，用户不存在时返回 sql.ErrNoRows
+func (r *repository) GetUserLevel(ctx context.Context, userID int64) (int, error) {
+	var level int
+	if err := r.db.GetContext(ctx, &level, "SELECT kyc_level FROM users WHERE id = $1", userID); err != nil {
+		return 0, err
+	}
+	return level, nil
+}
+
+// GetLatestSubmission 获取用户最近一次申请，没有申请时返回 sql.ErrNoRows
+func (r *repository) GetLatestSubmission(ctx context.Context, userID int64) (*entities.Submission, error) {
+	var submission entities.Submission
+	query := `SELECT * FROM kyc_submissions WHERE user_id = $1 ORDER BY created_at DESC, id DESC LIMIT 1`
+	if err := r.db.GetContext(ctx, &submission, query, userID); err != nil {
+		return nil, err
+	}
+	return &submission, nil
+}
+
+// CreateSubmission 在一个事务中保存申请和证件照片记录
+func (r *repository) CreateSubmission(ctx context.Context, submission *entities.Submission, documents []*entities.Document) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO kyc_submissions (user_id, level, status, full_name, date_of_birth, nationality,
+			document_type, document_number, address)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id, created_at, updated_at`
+	if err := tx.QueryRowContext(ctx, query,
+		submission.UserID, submission.Level, submission.Status, submission.FullName, submission.DateOfBirth,
+		submission.Nationality, submission.DocumentType, submission.DocumentNumber, submission.Address,
+	).Scan(&submission.ID, &submission.CreatedAt, &submission.UpdatedAt); err != nil {
+		return fmt.Errorf("failed to create kyc submission: %w", err)
+	}
+
+	for _, doc := range documents {
+		doc.SubmissionID = submission.ID
+		docQuery := `
+			INSERT INTO kyc_documents (submission_id, type, bucket, object_key, content_type, size)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			RETURNING id, created_at`
+		if err := tx.QueryRowContext(ctx, docQuery,
+			doc.SubmissionID, doc.Type, doc.Bucket, doc.ObjectKey, doc.ContentType, doc.Size,
+		).Scan(&doc.ID, &doc.CreatedAt); err != nil {
+			return fmt.Errorf("failed to save kyc document %s: %w", doc.Type, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// ListSubmissions 按状态分页获取申请，按提交时间从早到晚排序
+func (r *repository) ListSubmissions(ctx context.Context, status string, limit, offset int) ([]*entities.Submission, int64, error) {
+	var total int64
+	if err := r.db.GetContext(ctx, &total, "SELECT COUNT(*) FROM kyc_submissions WHERE status = $1", status); err != nil {
+		return nil, 0, err
+	}
+
+	submissions := []*entities.Submission{}
+	query := `SELECT * FROM kyc_submissions WHERE status = $1 ORDER BY created_at ASC, id ASC LIMIT $2 OFFSET $3`
+	if err := r.db.SelectContext(ctx, &submissions, query, status, limit, offset); err != nil {
+		return nil, 0, fmt.Errorf("failed to list kyc submissions: %w", err)
+	}
+	return submissions, total, nil
+}
+
+// GetSubmission 获取申请，不存在时返回 sql.ErrNoRows
+func (r *repository) GetSubmission(ctx context.Context, id int64) (*entities.Submission, error) {
+	var submission entities.Submission
+	if err := r.db.GetContext(ctx, &submission, "SELECT * FROM kyc_submissions WHERE id = $1", id); err != nil {
+		return nil, err
+	}
+	return &submission, nil
+}
+
+func (r *repository) ListDocuments(ctx context.Context, submissionID int64) ([]*entities.Document, error) {
+	documents := []*entities.Document{}
+	query := `SELECT * FROM kyc_documents WHERE submission_id = $1 ORDER BY id`
+	if err := r.db.SelectContext(ctx, &documents, query, submissionID); err != nil {
+		return nil, fmt.Errorf("failed to list kyc documents: %w", err)
+	}
+	return documents, nil
+}
+
+// ApproveSubmission 审核通过待审核的申请并提升用户的认证等级，等级只升不降，审计记录在同一事务中写入
+// 申请不是待审核状态时返回 sql.ErrNoRows
+func (r *repository) ApproveSubmission(ctx context.Context, id, adminID int64, entry *auditEntities.Log) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var userID int64
+	var level, previousLevel int
+	if err := tx.GetContext(ctx, &previousLevel, "SELECT u.kyc_level FROM users u JOIN kyc_submissions s ON s.user_id = u.id WHERE s.id = $1", id); err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE kyc_submissions
+		SET status = 'approved', reviewed_by = $2, reviewed_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND status = 'pending'
+		RETURNING user_id, level`
+	if err := tx.QueryRowContext(ctx, query, id, adminID).Scan(&userID, &level); err != nil {
+		return err
+	}
+
+	userQuery := `UPDATE users SET kyc_level = GREATEST(kyc_level, $2), updated_at = NOW() WHERE id = $1`
+	if _, err := tx.ExecContext(ctx, userQuery, userID, level); err != nil {
+		return fmt.Errorf("failed to update kyc level of user %d: %w", userID, err)
+	}
+
+	if level > previousLevel {
+		entry.Changes.Set("user_kyc_level", previousLevel, level)
+	}
+	if err := audit.Append(ctx, tx, entry); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// RejectSubmission 拒绝待审核的申请，审计记录在同一事务中写入
+// 申请不是待审核状态时返回 sql.ErrNoRows
+func (r *repository) RejectSubmission(ctx context.Context, id, adminID int64, reason string, entry *auditEntities.Log) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE kyc_submissions
+		SET status = 'rejected', rejection_reason = $3, reviewed_by = $2, reviewed_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND status = 'pending'`
+	result, err := tx.ExecContext(ctx, query, id, adminID, reason)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+
+	if err := audit.Append(ctx, tx, entry); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// RecordAudit 写入不伴随数据修改的审计记录，如查看证件
+func (r *repository) RecordAudit(ctx context.Context, entry *auditEntities.Log) error {
+	return audit.Record(ctx, r.db, entry)
+}
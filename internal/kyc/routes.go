@@ -0,0 +1,33 @@
+package kyc
+
+import (
+	"trusioo_api/internal/auth/rbac"
+	"trusioo_api/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterRoutes(router *gin.RouterGroup, handler *Handler) {
+	kycRoutes := router.Group("/kyc")
+	kycRoutes.Use(middleware.AuthMiddleware())
+	{
+		kycRoutes.GET("", handler.GetStatus) // 认证等级和最近一次申请
+	}
+
+	// 提交申请 - 拒绝管理员代登录令牌
+	sensitive := router.Group("/kyc")
+	sensitive.Use(middleware.AuthMiddleware(), middleware.RejectImpersonation())
+	{
+		sensitive.POST("/submissions", handler.Submit) // 提交个人信息和证件照片
+	}
+
+	// 审核队列 - 默认只有超级管理员拥有 kyc.review
+	admin := router.Group("/admin/kyc")
+	admin.Use(middleware.AdminAuthMiddleware(), middleware.RequirePermission(rbac.PermKYCReview))
+	{
+		admin.GET("/submissions", handler.ListSubmissions)
+		admin.GET("/submissions/:id", handler.GetSubmission) // 证件照片返回限时链接
+		admin.POST("/submissions/:id/approve", handler.ApproveSubmission)
+		admin.POST("/submissions/:id/reject", handler.RejectSubmission)
+	}
+}
@@ -0,0 +1,332 @@
+package kyc
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"mime/multipart"
+	"strconv"
+	"strings"
+	"time"
+
+	"trusioo_api/config"
+	"trusioo_api/internal/audit"
+	auditEntities "trusioo_api/internal/audit/entities"
+	"trusioo_api/internal/common"
+	"trusioo_api/internal/images"
+	"trusioo_api/internal/kyc/dto"
+	"trusioo_api/internal/kyc/entities"
+	referralEntities "trusioo_api/internal/referral/entities"
+	"trusioo_api/pkg/logger"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DocumentStorage 证件照片存储接口，由 images.PrivateStorage 实现，照片只写入私有存储桶
+type DocumentStorage interface {
+	Validate(file *multipart.FileHeader) error
+	Upload(ctx context.Context, file *multipart.FileHeader, folder string) (*images.StoredFile, error)
+	PresignedURL(ctx context.Context, bucket, key string, ttl time.Duration) (string, error)
+	Delete(ctx context.Context, bucket, key string) error
+}
+
+// ReferralQualifier 审核通过后判断是否发放邀请奖励，由 referral.Service 实现
+type ReferralQualifier interface {
+	Qualify(ctx context.Context, userID int64, action string) error
+}
+
+// Config 身份认证配置
+type Config struct {
+	DocumentURLTTL time.Duration
+}
+
+// DefaultConfig 默认配置
+func DefaultConfig() *Config {
+	return &Config{DocumentURLTTL: 10 * time.Minute}
+}
+
+// NewConfigFromApp 从应用配置创建身份认证配置
+func NewConfigFromApp(appConfig *config.Config) *Config {
+	cfg := DefaultConfig()
+	if appConfig != nil && appConfig.KYC.DocumentURLTTLMinutes > 0 {
+		cfg.DocumentURLTTL = time.Duration(appConfig.KYC.DocumentURLTTLMinutes) * time.Minute
+	}
+	return cfg
+}
+
+// Service 身份认证申请与审核
+type Service struct {
+	repo      Repository
+	storage   DocumentStorage
+	referrals ReferralQualifier
+	cfg       *Config
+}
+
+// NewService 创建服务，referrals 为空时不处理邀请奖励，cfg 为空时使用默认配置
+func NewService(repo Repository, storage DocumentStorage, referrals ReferralQualifier, cfg *Config) *Service {
+	if cfg == nil {
+		cfg = DefaultConfig()
+	}
+	return &Service{repo: repo, storage: storage, referrals: referrals, cfg: cfg}
+}
+
+// Submit 提交身份认证申请，files 按照片类型索引
+// 申请的等级必须高于当前等级，同时只能有一个待审核的申请
+func (s *Service) Submit(ctx context.Context, userID int64, req *dto.SubmitRequest, files map[string]*multipart.FileHeader) (*entities.Submission, error) {
+	dateOfBirth, err := time.Parse("2006-01-02", req.DateOfBirth)
+	if err != nil || !dateOfBirth.Before(time.Now()) {
+		return nil, common.ErrValidation
+	}
+	for _, docType := range requiredDocuments(req.Level, req.DocumentType) {
+		if files[docType] == nil {
+			return nil, common.ErrValidation
+		}
+	}
+
+	level, err := s.KYCLevel(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if req.Level <= level {
+		return nil, common.ErrValidation
+	}
+
+	latest, err := s.repo.GetLatestSubmission(ctx, userID)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	if latest != nil && latest.Status == entities.StatusPending {
+		return nil, common.ErrKYCPending
+	}
+
+	for _, file := range files {
+		if err := s.storage.Validate(file); err != nil {
+			return nil, common.ErrValidation
+		}
+	}
+
+	documents, err := s.uploadDocuments(ctx, userID, files)
+	if err != nil {
+		return nil, err
+	}
+
+	submission := &entities.Submission{
+		UserID:         userID,
+		Level:          req.Level,
+		Status:         entities.StatusPending,
+		FullName:       strings.TrimSpace(req.FullName),
+		DateOfBirth:    dateOfBirth,
+		Nationality:    strings.ToUpper(req.Nationality),
+		DocumentType:   req.DocumentType,
+		DocumentNumber: strings.TrimSpace(req.DocumentNumber),
+		Address:        strings.TrimSpace(req.Address),
+	}
+	if err := s.repo.CreateSubmission(ctx, submission, documents); err != nil {
+		s.deleteDocuments(ctx, documents)
+		return nil, err
+	}
+	return submission, nil
+}
+
+// Status 获取用户的认证等级和最近一次申请
+func (s *Service) Status(ctx context.Context, userID int64) (*dto.StatusResponse, error) {
+	level, err := s.KYCLevel(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &dto.StatusResponse{Level: level}
+	latest, err := s.repo.GetLatestSubmission(ctx, userID)
+	if err == nil {
+		resp.Submission = latest
+	} else if err != sql.ErrNoRows {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// KYCLevel 用户当前的认证等级，实现 middleware.KYCLevelProvider
+func (s *Service) KYCLevel(ctx context.Context, userID int64) (int, error) {
+	level, err := s.repo.GetUserLevel(ctx, userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, common.ErrUserNotFound
+		}
+		return 0, err
+	}
+	return level, nil
+}
+
+// Queue 审核队列，默认返回待审核的申请
+func (s *Service) Queue(ctx context.Context, req *dto.QueueRequest) (*dto.QueueResponse, error) {
+	status := req.Status
+	if status == "" {
+		status = entities.StatusPending
+	}
+	page := req.Page
+	if page < 1 {
+		page = 1
+	}
+	size := req.PageSize
+	if size < 1 {
+		size = 20
+	}
+
+	submissions, total, err := s.repo.ListSubmissions(ctx, status, size, (page-1)*size)
+	if err != nil {
+		return nil, err
+	}
+	return &dto.QueueResponse{
+		Total:       total,
+		Page:        page,
+		Size:        size,
+		Submissions: submissions,
+	}, nil
+}
+
+// GetSubmissionDetail 获取申请详情并记录查看操作，证件照片返回限时查看链接
+// 审计记录写入失败时不返回证件链接
+func (s *Service) GetSubmissionDetail(ctx context.Context, adminID, id int64) (*dto.SubmissionDetail, error) {
+	submission, err := s.repo.GetSubmission(ctx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, common.ErrNotFound
+		}
+		return nil, err
+	}
+
+	documents, err := s.repo.ListDocuments(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := audit.NewLog(ctx, adminID, "kyc.view", auditEntities.TargetKYC, strconv.FormatInt(id, 10))
+	if err := s.repo.RecordAudit(ctx, entry); err != nil {
+		return nil, err
+	}
+
+	expiresAt := time.Now().Add(s.cfg.DocumentURLTTL)
+	views := make([]*dto.DocumentView, 0, len(documents))
+	for _, doc := range documents {
+		url, err := s.storage.PresignedURL(ctx, doc.Bucket, doc.ObjectKey, s.cfg.DocumentURLTTL)
+		if err != nil {
+			return nil, err
+		}
+		views = append(views, &dto.DocumentView{Document: doc, URL: url, ExpiresAt: expiresAt})
+	}
+
+	return &dto.SubmissionDetail{Submission: submission, Documents: views}, nil
+}
+
+// Approve 审核通过，用户的认证等级提升到申请的等级
+func (s *Service) Approve(ctx context.Context, adminID, id int64) (*entities.Submission, error) {
+	entry := audit.NewLog(ctx, adminID, "kyc.approve", auditEntities.TargetKYC, strconv.FormatInt(id, 10))
+	entry.Changes.Set("status", entities.StatusPending, entities.StatusApproved)
+	if err := s.repo.ApproveSubmission(ctx, id, adminID, entry); err != nil {
+		return nil, s.reviewError(ctx, id, err)
+	}
+
+	submission, err := s.reviewed(ctx, adminID, id)
+	if err != nil {
+		return nil, err
+	}
+	// 邀请奖励发放失败不影响审核结果
+	if s.referrals != nil {
+		if err := s.referrals.Qualify(ctx, submission.UserID, referralEntities.ActionKYCApproved); err != nil {
+			logger.WithFields(logrus.Fields{
+				"submission_id": submission.ID,
+				"user_id":       submission.UserID,
+			}).WithError(err).Error("Failed to qualify referral")
+		}
+	}
+	return submission, nil
+}
+
+// Reject 拒绝申请，原因展示给用户，用户可以重新提交
+func (s *Service) Reject(ctx context.Context, adminID, id int64, req *dto.RejectRequest) (*entities.Submission, error) {
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" {
+		return nil, common.ErrValidation
+	}
+	entry := audit.WithReason(audit.NewLog(ctx, adminID, "kyc.reject", auditEntities.TargetKYC, strconv.FormatInt(id, 10)), reason)
+	entry.Changes.Set("status", entities.StatusPending, entities.StatusRejected)
+	if err := s.repo.RejectSubmission(ctx, id, adminID, reason, entry); err != nil {
+		return nil, s.reviewError(ctx, id, err)
+	}
+	return s.reviewed(ctx, adminID, id)
+}
+
+// reviewed 记录审核结果并返回更新后的申请
+func (s *Service) reviewed(ctx context.Context, adminID, id int64) (*entities.Submission, error) {
+	submission, err := s.repo.GetSubmission(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	logger.WithFields(logrus.Fields{
+		"submission_id": submission.ID,
+		"user_id":       submission.UserID,
+		"admin_id":      adminID,
+		"status":        submission.Status,
+		"level":         submission.Level,
+	}).Info("KYC submission reviewed")
+	return submission, nil
+}
+
+// reviewError 审核更新失败时区分申请不存在和已审核
+func (s *Service) reviewError(ctx context.Context, id int64, err error) error {
+	if err != sql.ErrNoRows {
+		return err
+	}
+	if _, err := s.repo.GetSubmission(ctx, id); err != nil {
+		if err == sql.ErrNoRows {
+			return common.ErrNotFound
+		}
+		return err
+	}
+	return common.ErrKYCAlreadyReviewed
+}
+
+// uploadDocuments 上传证件照片到私有存储桶，失败时删除已上传的照片
+func (s *Service) uploadDocuments(ctx context.Context, userID int64, files map[string]*multipart.FileHeader) ([]*entities.Document, error) {
+	documents := make([]*entities.Document, 0, len(files))
+	for _, docType := range []string{entities.DocumentIDFront, entities.DocumentIDBack, entities.DocumentSelfie} {
+		file := files[docType]
+		if file == nil {
+			continue
+		}
+
+		stored, err := s.storage.Upload(ctx, file, fmt.Sprintf("kyc/%d", userID))
+		if err != nil {
+			s.deleteDocuments(ctx, documents)
+			return nil, fmt.Errorf("failed to upload kyc document %s: %w", docType, err)
+		}
+		documents = append(documents, &entities.Document{
+			Type:        docType,
+			Bucket:      stored.Bucket,
+			ObjectKey:   stored.Key,
+			ContentType: file.Header.Get("Content-Type"),
+			Size:        stored.Size,
+		})
+	}
+	return documents, nil
+}
+
+func (s *Service) deleteDocuments(ctx context.Context, documents []*entities.Document) {
+	for _, doc := range documents {
+		if err := s.storage.Delete(ctx, doc.Bucket, doc.ObjectKey); err != nil {
+			logger.WithError(err).Warnf("Failed to delete kyc document %s", doc.ObjectKey)
+		}
+	}
+}
+
+// requiredDocuments 申请等级需要的证件照片，护照没有背面
+func requiredDocuments(level int, documentType string) []string {
+	docs := []string{entities.DocumentIDFront}
+	if documentType != entities.DocumentTypePassport {
+		docs = append(docs, entities.DocumentIDBack)
+	}
+	if level >= entities.LevelSelfie {
+		docs = append(docs, entities.DocumentSelfie)
+	}
+	return docs
+}
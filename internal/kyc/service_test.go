@@ -0,0 +1,429 @@
+package kyc
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"mime/multipart"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	auditEntities "trusioo_api/internal/audit/entities"
+	"trusioo_api/internal/common"
+	"trusioo_api/internal/images"
+	"trusioo_api/internal/kyc/dto"
+	"trusioo_api/internal/kyc/entities"
+	"trusioo_api/pkg/logger"
+	"trusioo_api/pkg/r2storage"
+)
+
+// fakeRepository 内存版仓库
+type fakeRepository struct {
+	levels      map[int64]int
+	submissions []*entities.Submission
+	documents   []*entities.Document
+	createErr   error
+	auditErr    error
+	audits      []*auditEntities.Log
+}
+
+func newFakeRepository() *fakeRepository {
+	return &fakeRepository{levels: map[int64]int{1: 0}}
+}
+
+func (f *fakeRepository) GetUserLevel(ctx context.Context, userID int64) (int, error) {
+	level, ok := f.levels[userID]
+	if !ok {
+		return 0, sql.ErrNoRows
+	}
+	return level, nil
+}
+
+func (f *fakeRepository) GetLatestSubmission(ctx context.Context, userID int64) (*entities.Submission, error) {
+	for i := len(f.submissions) - 1; i >= 0; i-- {
+		if f.submissions[i].UserID == userID {
+			return f.submissions[i], nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (f *fakeRepository) CreateSubmission(ctx context.Context, submission *entities.Submission, documents []*entities.Document) error {
+	if f.createErr != nil {
+		return f.createErr
+	}
+	submission.ID = int64(len(f.submissions) + 1)
+	submission.CreatedAt = time.Now()
+	f.submissions = append(f.submissions, submission)
+	for _, doc := range documents {
+		doc.SubmissionID = submission.ID
+		f.documents = append(f.documents, doc)
+	}
+	return nil
+}
+
+func (f *fakeRepository) ListSubmissions(ctx context.Context, status string, limit, offset int) ([]*entities.Submission, int64, error) {
+	matched := []*entities.Submission{}
+	for _, s := range f.submissions {
+		if s.Status == status {
+			matched = append(matched, s)
+		}
+	}
+	return matched, int64(len(matched)), nil
+}
+
+func (f *fakeRepository) GetSubmission(ctx context.Context, id int64) (*entities.Submission, error) {
+	for _, s := range f.submissions {
+		if s.ID == id {
+			return s, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (f *fakeRepository) ListDocuments(ctx context.Context, submissionID int64) ([]*entities.Document, error) {
+	docs := []*entities.Document{}
+	for _, d := range f.documents {
+		if d.SubmissionID == submissionID {
+			docs = append(docs, d)
+		}
+	}
+	return docs, nil
+}
+
+func (f *fakeRepository) review(id, adminID int64, status string) (*entities.Submission, error) {
+	s, err := f.GetSubmission(context.Background(), id)
+	if err != nil || s.Status != entities.StatusPending {
+		return nil, sql.ErrNoRows
+	}
+	now := time.Now()
+	s.Status = status
+	s.ReviewedBy = &adminID
+	s.ReviewedAt = &now
+	return s, nil
+}
+
+func (f *fakeRepository) RecordAudit(ctx context.Context, entry *auditEntities.Log) error {
+	if f.auditErr != nil {
+		return f.auditErr
+	}
+	f.audits = append(f.audits, entry)
+	return nil
+}
+
+func (f *fakeRepository) ApproveSubmission(ctx context.Context, id, adminID int64, entry *auditEntities.Log) error {
+	s, err := f.review(id, adminID, entities.StatusApproved)
+	if err != nil {
+		return err
+	}
+	if s.Level > f.levels[s.UserID] {
+		entry.Changes.Set("user_kyc_level", f.levels[s.UserID], s.Level)
+		f.levels[s.UserID] = s.Level
+	}
+	f.audits = append(f.audits, entry)
+	return nil
+}
+
+func (f *fakeRepository) RejectSubmission(ctx context.Context, id, adminID int64, reason string, entry *auditEntities.Log) error {
+	s, err := f.review(id, adminID, entities.StatusRejected)
+	if err != nil {
+		return err
+	}
+	s.RejectionReason = reason
+	f.audits = append(f.audits, entry)
+	return nil
+}
+
+// fakeStorage 内存版对象存储，文件名为 bad.txt 的文件校验失败，写入公开存储桶时报错
+type fakeStorage struct {
+	objects   map[string]bool
+	uploadErr error
+}
+
+func newFakeStorage() *fakeStorage {
+	return &fakeStorage{objects: map[string]bool{}}
+}
+
+func (f *fakeStorage) ValidateFile(fileHeader *multipart.FileHeader) error {
+	if fileHeader.Filename == "bad.txt" {
+		return errors.New("file type text/plain is not allowed")
+	}
+	return nil
+}
+
+func (f *fakeStorage) UploadFile(ctx context.Context, fileHeader *multipart.FileHeader, options r2storage.UploadOptions) (*r2storage.UploadResult, error) {
+	if f.uploadErr != nil && len(f.objects) > 0 {
+		return nil, f.uploadErr
+	}
+	if options.IsPublic {
+		return nil, errors.New("kyc documents must not be public")
+	}
+	key := options.Folder + "/" + fileHeader.Filename
+	f.objects[key] = true
+	return &r2storage.UploadResult{Key: key, Bucket: "private", Size: fileHeader.Size}, nil
+}
+
+func (f *fakeStorage) GeneratePresignedURL(ctx context.Context, bucket, key string, expiration time.Duration) (string, error) {
+	return "https://r2.example.com/" + key + "?expires=" + expiration.String(), nil
+}
+
+func (f *fakeStorage) DeleteFile(ctx context.Context, bucket, key string) error {
+	delete(f.objects, key)
+	return nil
+}
+
+func fileHeader(name string) *multipart.FileHeader {
+	return &multipart.FileHeader{Filename: name, Size: 1024}
+}
+
+func documentFiles(types ...string) map[string]*multipart.FileHeader {
+	files := make(map[string]*multipart.FileHeader)
+	for _, t := range types {
+		files[t] = fileHeader(t + ".jpg")
+	}
+	return files
+}
+
+func submitRequest(level int, documentType string) *dto.SubmitRequest {
+	return &dto.SubmitRequest{
+		Level:          level,
+		FullName:       " Jane Doe ",
+		DateOfBirth:    "1990-05-01",
+		Nationality:    "gb",
+		DocumentType:   documentType,
+		DocumentNumber: "X1234567",
+	}
+}
+
+// quietLogger 审核操作会写日志，测试中丢弃日志输出
+func quietLogger(t *testing.T) {
+	original := logger.Log
+	logger.Log = logrus.New()
+	logger.Log.SetOutput(&bytes.Buffer{})
+	t.Cleanup(func() { logger.Log = original })
+}
+
+func TestService_Submit(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		req     *dto.SubmitRequest
+		files   map[string]*multipart.FileHeader
+		level   int
+		wantErr error
+		wantDoc int
+	}{
+		{
+			name:    "证件认证",
+			req:     submitRequest(1, entities.DocumentTypeIDCard),
+			files:   documentFiles(entities.DocumentIDFront, entities.DocumentIDBack),
+			wantDoc: 2,
+		},
+		{
+			name:    "护照不需要背面",
+			req:     submitRequest(1, entities.DocumentTypePassport),
+			files:   documentFiles(entities.DocumentIDFront),
+			wantDoc: 1,
+		},
+		{
+			name:    "自拍认证",
+			req:     submitRequest(2, entities.DocumentTypeDrivingLicense),
+			files:   documentFiles(entities.DocumentIDFront, entities.DocumentIDBack, entities.DocumentSelfie),
+			wantDoc: 3,
+		},
+		{
+			name:    "缺少证件背面",
+			req:     submitRequest(1, entities.DocumentTypeIDCard),
+			files:   documentFiles(entities.DocumentIDFront),
+			wantErr: common.ErrValidation,
+		},
+		{
+			name:    "等级2缺少自拍",
+			req:     submitRequest(2, entities.DocumentTypePassport),
+			files:   documentFiles(entities.DocumentIDFront),
+			wantErr: common.ErrValidation,
+		},
+		{
+			name:    "已达到申请的等级",
+			req:     submitRequest(1, entities.DocumentTypePassport),
+			files:   documentFiles(entities.DocumentIDFront),
+			level:   1,
+			wantErr: common.ErrValidation,
+		},
+		{
+			name: "出生日期在未来",
+			req: func() *dto.SubmitRequest {
+				req := submitRequest(1, entities.DocumentTypePassport)
+				req.DateOfBirth = time.Now().AddDate(1, 0, 0).Format("2006-01-02")
+				return req
+			}(),
+			files:   documentFiles(entities.DocumentIDFront),
+			wantErr: common.ErrValidation,
+		},
+		{
+			name:    "文件类型不允许",
+			req:     submitRequest(1, entities.DocumentTypePassport),
+			files:   map[string]*multipart.FileHeader{entities.DocumentIDFront: fileHeader("bad.txt")},
+			wantErr: common.ErrValidation,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newFakeRepository()
+			repo.levels[1] = tt.level
+			storage := newFakeStorage()
+			service := NewService(repo, images.NewPrivateStorage(storage), nil, nil)
+
+			submission, err := service.Submit(ctx, 1, tt.req, tt.files)
+			if tt.wantErr != nil {
+				assert.Equal(t, tt.wantErr, err)
+				assert.Empty(t, repo.submissions)
+				assert.Empty(t, storage.objects)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, entities.StatusPending, submission.Status)
+			assert.Equal(t, "Jane Doe", submission.FullName)
+			assert.Equal(t, "GB", submission.Nationality)
+			assert.Len(t, repo.documents, tt.wantDoc)
+			for _, doc := range repo.documents {
+				assert.Equal(t, "private", doc.Bucket)
+				assert.Contains(t, doc.ObjectKey, "kyc/1/")
+			}
+		})
+	}
+
+	t.Run("已有待审核的申请", func(t *testing.T) {
+		service := NewService(newFakeRepository(), images.NewPrivateStorage(newFakeStorage()), nil, nil)
+		_, err := service.Submit(ctx, 1, submitRequest(1, entities.DocumentTypePassport), documentFiles(entities.DocumentIDFront))
+		require.NoError(t, err)
+		_, err = service.Submit(ctx, 1, submitRequest(2, entities.DocumentTypePassport), documentFiles(entities.DocumentIDFront, entities.DocumentSelfie))
+		assert.Equal(t, common.ErrKYCPending, err)
+	})
+
+	t.Run("保存失败时删除已上传的照片", func(t *testing.T) {
+		repo := newFakeRepository()
+		repo.createErr = errors.New("db down")
+		storage := newFakeStorage()
+		service := NewService(repo, images.NewPrivateStorage(storage), nil, nil)
+		_, err := service.Submit(ctx, 1, submitRequest(1, entities.DocumentTypeIDCard), documentFiles(entities.DocumentIDFront, entities.DocumentIDBack))
+		assert.Error(t, err)
+		assert.Empty(t, storage.objects)
+	})
+
+	t.Run("上传失败时删除已上传的照片", func(t *testing.T) {
+		storage := newFakeStorage()
+		storage.uploadErr = errors.New("r2 down")
+		service := NewService(newFakeRepository(), images.NewPrivateStorage(storage), nil, nil)
+		_, err := service.Submit(ctx, 1, submitRequest(1, entities.DocumentTypeIDCard), documentFiles(entities.DocumentIDFront, entities.DocumentIDBack))
+		assert.Error(t, err)
+		assert.Empty(t, storage.objects)
+	})
+}
+
+func TestService_Review(t *testing.T) {
+	quietLogger(t)
+	ctx := context.Background()
+
+	setup := func(t *testing.T, level int) (*fakeRepository, *Service, *entities.Submission) {
+		repo := newFakeRepository()
+		service := NewService(repo, images.NewPrivateStorage(newFakeStorage()), nil, &Config{DocumentURLTTL: 5 * time.Minute})
+		files := documentFiles(entities.DocumentIDFront, entities.DocumentSelfie)
+		submission, err := service.Submit(ctx, 1, submitRequest(level, entities.DocumentTypePassport), files)
+		require.NoError(t, err)
+		return repo, service, submission
+	}
+
+	t.Run("审核队列与证件链接", func(t *testing.T) {
+		repo, service, submission := setup(t, 2)
+
+		queue, err := service.Queue(ctx, &dto.QueueRequest{})
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), queue.Total)
+		assert.Equal(t, 1, queue.Page)
+		assert.Equal(t, 20, queue.Size)
+
+		detail, err := service.GetSubmissionDetail(ctx, 9, submission.ID)
+		require.NoError(t, err)
+		require.Len(t, detail.Documents, 2)
+		assert.Contains(t, detail.Documents[0].URL, "expires=5m0s")
+		assert.WithinDuration(t, time.Now().Add(5*time.Minute), detail.Documents[0].ExpiresAt, time.Second)
+
+		// 每次查看证件都写入审计记录
+		require.Len(t, repo.audits, 1)
+		assert.Equal(t, "kyc.view", repo.audits[0].Action)
+		assert.Equal(t, int64(9), repo.audits[0].AdminID)
+		assert.Equal(t, strconv.FormatInt(submission.ID, 10), repo.audits[0].TargetID)
+
+		_, err = service.GetSubmissionDetail(ctx, 9, 99)
+		assert.Equal(t, common.ErrNotFound, err)
+		assert.Len(t, repo.audits, 1)
+
+		// 审计记录写入失败时不返回证件链接
+		repo.auditErr = errors.New("db down")
+		_, err = service.GetSubmissionDetail(ctx, 9, submission.ID)
+		assert.Equal(t, repo.auditErr, err)
+	})
+
+	t.Run("审核通过提升认证等级", func(t *testing.T) {
+		repo, service, submission := setup(t, 2)
+
+		approved, err := service.Approve(ctx, 9, submission.ID)
+		require.NoError(t, err)
+		assert.Equal(t, entities.StatusApproved, approved.Status)
+		assert.Equal(t, int64(9), *approved.ReviewedBy)
+		assert.Equal(t, 2, repo.levels[1])
+		require.Len(t, repo.audits, 1)
+		assert.Equal(t, "kyc.approve", repo.audits[0].Action)
+		assert.Equal(t, auditEntities.Changes{
+			"status":         {Before: entities.StatusPending, After: entities.StatusApproved},
+			"user_kyc_level": {Before: 0, After: 2},
+		}, repo.audits[0].Changes)
+
+		level, err := service.KYCLevel(ctx, 1)
+		require.NoError(t, err)
+		assert.Equal(t, 2, level)
+
+		_, err = service.Approve(ctx, 9, submission.ID)
+		assert.Equal(t, common.ErrKYCAlreadyReviewed, err)
+		_, err = service.Approve(ctx, 9, 99)
+		assert.Equal(t, common.ErrNotFound, err)
+	})
+
+	t.Run("拒绝后可以重新提交", func(t *testing.T) {
+		repo, service, submission := setup(t, 1)
+
+		_, err := service.Reject(ctx, 9, submission.ID, &dto.RejectRequest{Reason: "  "})
+		assert.Equal(t, common.ErrValidation, err)
+
+		rejected, err := service.Reject(ctx, 9, submission.ID, &dto.RejectRequest{Reason: "证件照片模糊"})
+		require.NoError(t, err)
+		assert.Equal(t, entities.StatusRejected, rejected.Status)
+		assert.Equal(t, 0, repo.levels[1])
+		require.Len(t, repo.audits, 1)
+		assert.Equal(t, "kyc.reject", repo.audits[0].Action)
+		assert.Equal(t, "证件照片模糊", repo.audits[0].Reason)
+
+		status, err := service.Status(ctx, 1)
+		require.NoError(t, err)
+		assert.Equal(t, 0, status.Level)
+		assert.Equal(t, "证件照片模糊", status.Submission.RejectionReason)
+
+		_, err = service.Submit(ctx, 1, submitRequest(1, entities.DocumentTypePassport), documentFiles(entities.DocumentIDFront))
+		assert.NoError(t, err)
+	})
+
+	t.Run("用户不存在", func(t *testing.T) {
+		service := NewService(newFakeRepository(), images.NewPrivateStorage(newFakeStorage()), nil, nil)
+		_, err := service.Status(ctx, 99)
+		assert.Equal(t, common.ErrUserNotFound, err)
+	})
+}
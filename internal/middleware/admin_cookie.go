@@ -0,0 +1,194 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"net/http"
+	"strings"
+
+	"trusioo_api/config"
+	"trusioo_api/internal/common"
+	"trusioo_api/pkg/auth"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 管理后台网页会话使用的 Cookie 和请求头
+const (
+	AdminAccessCookie   = "admin_access_token"   // HttpOnly，只发送给 /api/v1/admin
+	AdminRefreshCookie  = "admin_refresh_token"  // HttpOnly，只发送给 /api/v1/admin/auth
+	AdminElevatedCookie = "admin_elevated_token" // HttpOnly，只发送给 /api/v1/admin，重新验证身份后写入
+	AdminCSRFCookie     = "admin_csrf_token"     // 前端可读，请求时放入 X-CSRF-Token 请求头
+	CSRFTokenHeader     = "X-CSRF-Token"
+
+	adminCookiePath        = "/api/v1/admin"
+	adminRefreshCookiePath = "/api/v1/admin/auth"
+)
+
+// cookieSettings 从应用配置读取 Cookie 属性，未配置时使用 Secure + SameSite=Strict
+func cookieSettings() (domain string, secure bool, sameSite http.SameSite) {
+	secure, sameSite = true, http.SameSiteStrictMode
+	if config.AppConfig == nil {
+		return
+	}
+
+	cc := config.AppConfig.AdminCookie
+	domain, secure = cc.Domain, cc.Secure
+	switch strings.ToLower(cc.SameSite) {
+	case "lax":
+		sameSite = http.SameSiteLaxMode
+	case "none":
+		sameSite = http.SameSiteNoneMode
+		secure = true
+	}
+	return
+}
+
+func setAdminCookie(c *gin.Context, name, value, path string, maxAge int, httpOnly bool) {
+	domain, secure, sameSite := cookieSettings()
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		Domain:   domain,
+		MaxAge:   maxAge,
+		Secure:   secure,
+		HttpOnly: httpOnly,
+		SameSite: sameSite,
+	})
+}
+
+func tokenMaxAges() (access, refresh int) {
+	access, refresh = 3600, 7*24*3600
+	if config.AppConfig != nil {
+		if config.AppConfig.JWT.AccessExpire > 0 {
+			access = config.AppConfig.JWT.AccessExpire
+		}
+		if config.AppConfig.JWT.RefreshExpire > 0 {
+			refresh = config.AppConfig.JWT.RefreshExpire
+		}
+	}
+	return
+}
+
+// SetAdminSessionCookies 登录成功后写入访问令牌、刷新令牌和 CSRF 令牌 Cookie，返回 CSRF 令牌
+func SetAdminSessionCookies(c *gin.Context, accessToken, refreshToken string) (string, error) {
+	csrfToken, err := newCSRFToken()
+	if err != nil {
+		return "", err
+	}
+
+	accessAge, refreshAge := tokenMaxAges()
+	setAdminCookie(c, AdminAccessCookie, accessToken, adminCookiePath, accessAge, true)
+	setAdminCookie(c, AdminRefreshCookie, refreshToken, adminRefreshCookiePath, refreshAge, true)
+	// CSRF Cookie 需要前端脚本读取，路径为根路径以便后台页面访问
+	setAdminCookie(c, AdminCSRFCookie, csrfToken, "/", refreshAge, false)
+	return csrfToken, nil
+}
+
+// SetAdminAccessCookie 刷新后写入新的访问令牌
+func SetAdminAccessCookie(c *gin.Context, accessToken string) {
+	accessAge, _ := tokenMaxAges()
+	setAdminCookie(c, AdminAccessCookie, accessToken, adminCookiePath, accessAge, true)
+}
+
+// SetAdminElevatedCookie 重新验证身份后写入提升令牌，maxAge 与提升令牌的有效期一致
+func SetAdminElevatedCookie(c *gin.Context, elevatedToken string, maxAge int) {
+	setAdminCookie(c, AdminElevatedCookie, elevatedToken, adminCookiePath, maxAge, true)
+}
+
+// ClearAdminSessionCookies 退出登录时删除全部会话 Cookie
+func ClearAdminSessionCookies(c *gin.Context) {
+	setAdminCookie(c, AdminAccessCookie, "", adminCookiePath, -1, true)
+	setAdminCookie(c, AdminElevatedCookie, "", adminCookiePath, -1, true)
+	setAdminCookie(c, AdminRefreshCookie, "", adminRefreshCookiePath, -1, true)
+	setAdminCookie(c, AdminCSRFCookie, "", "/", -1, false)
+}
+
+// AdminRefreshCookieValue 读取刷新令牌 Cookie，不存在时返回空字符串
+func AdminRefreshCookieValue(c *gin.Context) string {
+	value, err := c.Cookie(AdminRefreshCookie)
+	if err != nil {
+		return ""
+	}
+	return value
+}
+
+// UsingAdminCookieSession 请求没有 Authorization 请求头并携带访问令牌 Cookie 时，使用的是管理后台的 Cookie 会话
+func UsingAdminCookieSession(c *gin.Context) bool {
+	if c.GetHeader("Authorization") != "" {
+		return false
+	}
+	value, err := c.Cookie(AdminAccessCookie)
+	return err == nil && value != ""
+}
+
+// adminCookieToken Cookie 会话中提升令牌属于同一管理员时使用提升令牌，使 RequireRecentAuth 保护的接口可以通过；
+// 提升令牌不存在、已过期或属于其他账户时使用访问令牌
+func adminCookieToken(c *gin.Context, accessToken string) string {
+	elevatedToken, err := c.Cookie(AdminElevatedCookie)
+	if err != nil || elevatedToken == "" {
+		return accessToken
+	}
+
+	elevated, err := auth.ValidateAccessToken(elevatedToken)
+	if err != nil || elevated.AuthTime <= 0 {
+		return accessToken
+	}
+	access, err := auth.ValidateAccessToken(accessToken)
+	if err != nil || access.UserID != elevated.UserID || access.UserType != elevated.UserType {
+		return accessToken
+	}
+	return elevatedToken
+}
+
+// AdminCSRFMiddleware 使用 Cookie 会话时校验修改数据的请求携带的 CSRF 令牌（double-submit）
+// 没有会话 Cookie 的请求使用 Authorization 请求头，浏览器不会自动附带，不需要校验
+func AdminCSRFMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !hasAdminSessionCookie(c) {
+			c.Next()
+			return
+		}
+		if !checkAdminCSRF(c) {
+			return
+		}
+		c.Next()
+	}
+}
+
+func hasAdminSessionCookie(c *gin.Context) bool {
+	for _, name := range []string{AdminAccessCookie, AdminRefreshCookie} {
+		if value, err := c.Cookie(name); err == nil && value != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// checkAdminCSRF 安全方法直接通过；其他方法要求请求头与 Cookie 中的 CSRF 令牌一致，不一致时返回 403 并中止
+func checkAdminCSRF(c *gin.Context) bool {
+	switch c.Request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+
+	cookieToken, _ := c.Cookie(AdminCSRFCookie)
+	headerToken := c.GetHeader(CSRFTokenHeader)
+	if cookieToken == "" || headerToken == "" ||
+		subtle.ConstantTimeCompare([]byte(cookieToken), []byte(headerToken)) != 1 {
+		common.Forbidden(c, "Invalid CSRF token")
+		c.Abort()
+		return false
+	}
+	return true
+}
+
+func newCSRFToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,266 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"trusioo_api/config"
+	"trusioo_api/pkg/auth"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetAdminSessionCookies(t *testing.T) {
+	setupTestConfig()
+	config.AppConfig.AdminCookie = config.AdminCookieConfig{Secure: true, SameSite: "strict"}
+	gin.SetMode(gin.TestMode)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/v1/admin/auth/login/verify", nil)
+
+	csrfToken, err := SetAdminSessionCookies(c, "access", "refresh")
+	require.NoError(t, err)
+	assert.Len(t, csrfToken, 64)
+
+	cookies := map[string]*http.Cookie{}
+	for _, cookie := range w.Result().Cookies() {
+		cookies[cookie.Name] = cookie
+	}
+	require.Len(t, cookies, 3)
+
+	access := cookies[AdminAccessCookie]
+	assert.Equal(t, "access", access.Value)
+	assert.Equal(t, "/api/v1/admin", access.Path)
+	assert.True(t, access.HttpOnly)
+	assert.True(t, access.Secure)
+	assert.Equal(t, http.SameSiteStrictMode, access.SameSite)
+	assert.Equal(t, 3600, access.MaxAge)
+
+	refresh := cookies[AdminRefreshCookie]
+	assert.Equal(t, "/api/v1/admin/auth", refresh.Path)
+	assert.True(t, refresh.HttpOnly)
+
+	csrf := cookies[AdminCSRFCookie]
+	assert.Equal(t, csrfToken, csrf.Value)
+	assert.False(t, csrf.HttpOnly, "前端需要读取 CSRF 令牌")
+}
+
+func TestAdminAuthMiddleware_Cookie(t *testing.T) {
+	setupTestConfig()
+
+	token, err := generateTestToken(1, "admin@example.com", "admin", "admin")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name           string
+		method         string
+		cookies        map[string]string
+		csrfHeader     string
+		authHeader     string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "Cookie 会话的 GET 请求不需要 CSRF 令牌",
+			method:         http.MethodGet,
+			cookies:        map[string]string{AdminAccessCookie: token},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Cookie 会话的 POST 请求携带正确的 CSRF 令牌",
+			method:         http.MethodPost,
+			cookies:        map[string]string{AdminAccessCookie: token, AdminCSRFCookie: "csrf"},
+			csrfHeader:     "csrf",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Cookie 会话的 POST 请求缺少 CSRF 令牌",
+			method:         http.MethodPost,
+			cookies:        map[string]string{AdminAccessCookie: token, AdminCSRFCookie: "csrf"},
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   "Invalid CSRF token",
+		},
+		{
+			name:           "CSRF 令牌不一致",
+			method:         http.MethodDelete,
+			cookies:        map[string]string{AdminAccessCookie: token, AdminCSRFCookie: "csrf"},
+			csrfHeader:     "other",
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   "Invalid CSRF token",
+		},
+		{
+			name:           "Authorization 请求头优先且不需要 CSRF 令牌",
+			method:         http.MethodPost,
+			cookies:        map[string]string{AdminAccessCookie: "invalid"},
+			authHeader:     "Bearer " + token,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Cookie 中的令牌无效",
+			method:         http.MethodGet,
+			cookies:        map[string]string{AdminAccessCookie: "invalid"},
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   "Invalid or expired token",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := setupTestRouter()
+			router.Use(AdminAuthMiddleware())
+			router.Handle(tt.method, "/test", func(c *gin.Context) {
+				c.JSON(http.StatusOK, gin.H{"message": "success"})
+			})
+
+			req := httptest.NewRequest(tt.method, "/test", nil)
+			for name, value := range tt.cookies {
+				req.AddCookie(&http.Cookie{Name: name, Value: value})
+			}
+			if tt.csrfHeader != "" {
+				req.Header.Set(CSRFTokenHeader, tt.csrfHeader)
+			}
+			if tt.authHeader != "" {
+				req.Header.Set("Authorization", tt.authHeader)
+			}
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedBody != "" {
+				assert.Contains(t, w.Body.String(), tt.expectedBody)
+			}
+		})
+	}
+}
+
+func TestAdminAuthMiddleware_ElevatedCookie(t *testing.T) {
+	setupTestConfig()
+
+	token, err := generateTestToken(1, "admin@example.com", "admin", "admin")
+	require.NoError(t, err)
+	elevated, err := auth.GenerateElevatedToken(1, "admin@example.com", "admin", "admin", auth.ACRPassword, time.Now(), 10*time.Minute)
+	require.NoError(t, err)
+	otherElevated, err := auth.GenerateElevatedToken(2, "other@example.com", "admin", "admin", auth.ACRPassword, time.Now(), 10*time.Minute)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name           string
+		cookies        map[string]string
+		expectedStatus int
+	}{
+		{
+			name:           "提升令牌 Cookie 满足最近验证身份的要求",
+			cookies:        map[string]string{AdminAccessCookie: token, AdminElevatedCookie: elevated},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "没有提升令牌 Cookie",
+			cookies:        map[string]string{AdminAccessCookie: token},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "其他管理员的提升令牌被忽略",
+			cookies:        map[string]string{AdminAccessCookie: token, AdminElevatedCookie: otherElevated},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "无效的提升令牌被忽略",
+			cookies:        map[string]string{AdminAccessCookie: token, AdminElevatedCookie: "invalid"},
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := setupTestRouter()
+			router.GET("/test", AdminAuthMiddleware(), RequireRecentAuth(5*time.Minute), func(c *gin.Context) {
+				c.JSON(http.StatusOK, gin.H{"user_id": c.GetInt64("user_id")})
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/test", nil)
+			for name, value := range tt.cookies {
+				req.AddCookie(&http.Cookie{Name: name, Value: value})
+			}
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+		})
+	}
+}
+
+func TestSetAdminElevatedCookie(t *testing.T) {
+	setupTestConfig()
+	gin.SetMode(gin.TestMode)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/v1/admin/auth/reauth", nil)
+	c.Request.AddCookie(&http.Cookie{Name: AdminAccessCookie, Value: "access"})
+	assert.True(t, UsingAdminCookieSession(c))
+
+	SetAdminElevatedCookie(c, "elevated", 600)
+	cookies := w.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.Equal(t, AdminElevatedCookie, cookies[0].Name)
+	assert.Equal(t, "/api/v1/admin", cookies[0].Path)
+	assert.Equal(t, 600, cookies[0].MaxAge)
+	assert.True(t, cookies[0].HttpOnly)
+
+	c.Request.Header.Set("Authorization", "Bearer access")
+	assert.False(t, UsingAdminCookieSession(c), "Authorization 请求头优先")
+}
+
+func TestAdminCSRFMiddleware(t *testing.T) {
+	tests := []struct {
+		name           string
+		cookies        map[string]string
+		csrfHeader     string
+		expectedStatus int
+	}{
+		{
+			name:           "没有会话 Cookie 时跳过校验",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "刷新令牌 Cookie 缺少 CSRF 令牌",
+			cookies:        map[string]string{AdminRefreshCookie: "refresh", AdminCSRFCookie: "csrf"},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "刷新令牌 Cookie 携带正确的 CSRF 令牌",
+			cookies:        map[string]string{AdminRefreshCookie: "refresh", AdminCSRFCookie: "csrf"},
+			csrfHeader:     "csrf",
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := setupTestRouter()
+			router.POST("/refresh", AdminCSRFMiddleware(), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/refresh", nil)
+			for name, value := range tt.cookies {
+				req.AddCookie(&http.Cookie{Name: name, Value: value})
+			}
+			if tt.csrfHeader != "" {
+				req.Header.Set(CSRFTokenHeader, tt.csrfHeader)
+			}
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package middleware
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+
+	apiKeyEntities "trusioo_api/internal/apikeys/entities"
+	"trusioo_api/internal/common"
+
+	"github.com/gin-gonic/gin"
+)
+
+// apiKeyPrincipalKey 上下文中保存 *apiKeyEntities.Principal 的键
+const apiKeyPrincipalKey = "api_key_principal"
+
+// APIKeyValidator API密钥校验接口，由 apikeys.Service 实现
+// 密钥不存在、已撤销或已过期时返回 common.ErrAPIKeyInvalid，来源IP不在白名单时返回 common.ErrAPIKeyIPNotAllowed
+type APIKeyValidator interface {
+	ValidateAPIKey(ctx context.Context, key, clientIP string) (*apiKeyEntities.Principal, error)
+}
+
+var (
+	apiKeyValidator   APIKeyValidator
+	apiKeyLimiter     *RateLimiter
+	apiKeyLimiterOnce sync.Once
+)
+
+// SetAPIKeyValidator 注册API密钥校验实现，路由初始化时调用
+func SetAPIKeyValidator(validator APIKeyValidator) {
+	apiKeyValidator = validator
+}
+
+// APIKeyAuthMiddleware API密钥认证中间件，接受 "Authorization: ApiKey <key>"
+// 设置与 AuthMiddleware 相同的上下文键，另外设置 api_key_id 和 api_key_scopes
+func APIKeyAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key, ok := apiKeyFromHeader(c.GetHeader("Authorization"))
+		if !ok {
+			common.Unauthorized(c, "API key required")
+			c.Abort()
+			return
+		}
+		authenticateAPIKey(c, key)
+	}
+}
+
+// AuthOrAPIKeyMiddleware 同时接受用户访问令牌和API密钥，用于开放给合作方后端调用的用户接口
+func AuthOrAPIKeyMiddleware() gin.HandlerFunc {
+	userAuth := AuthMiddleware()
+	return func(c *gin.Context) {
+		if key, ok := apiKeyFromHeader(c.GetHeader("Authorization")); ok {
+			authenticateAPIKey(c, key)
+			return
+		}
+		userAuth(c)
+	}
+}
+
+// RequireScope 要求API密钥拥有指定范围，用户访问令牌的请求不受限制
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, isAPIKey := c.Get("api_key_id"); !isAPIKey {
+			c.Next()
+			return
+		}
+
+		for _, s := range c.GetStringSlice("api_key_scopes") {
+			if s == scope {
+				c.Next()
+				return
+			}
+		}
+		common.ForbiddenWithData(c, "API key scope not granted", gin.H{"required_scope": scope})
+		c.Abort()
+	}
+}
+
+func authenticateAPIKey(c *gin.Context, key string) {
+	if apiKeyValidator == nil {
+		common.Unauthorized(c, "Invalid or expired API key")
+		c.Abort()
+		return
+	}
+
+	principal, err := apiKeyValidator.ValidateAPIKey(c.Request.Context(), key, c.ClientIP())
+	if err != nil {
+		switch err {
+		case common.ErrAPIKeyInvalid:
+			common.Unauthorized(c, "Invalid or expired API key")
+		case common.ErrAPIKeyIPNotAllowed:
+			common.Forbidden(c, "API key is not allowed from this IP address")
+		default:
+			common.ServerError(c, err)
+		}
+		c.Abort()
+		return
+	}
+
+	// 每个密钥使用独立的令牌桶，与按IP的全局限流互不影响
+	apiKeyLimiterOnce.Do(func() { apiKeyLimiter = NewRateLimiter() })
+	if !apiKeyLimiter.allow("apikey:"+strconv.FormatInt(principal.KeyID, 10), principal.RateLimitPerMinute) {
+		common.TooManyRequests(c, "API key rate limit exceeded. Please try again later.")
+		c.Abort()
+		return
+	}
+
+	c.Set("user_id", principal.UserID)
+	c.Set("user_email", principal.Email)
+	c.Set("user_role", "user")
+	c.Set("user_type", "user")
+	c.Set("api_key_id", principal.KeyID)
+	c.Set("api_key_scopes", principal.Scopes)
+	c.Set(apiKeyPrincipalKey, principal)
+
+	c.Next()
+}
+
+func apiKeyFromHeader(header string) (string, bool) {
+	parts := strings.Split(header, " ")
+	if len(parts) != 2 || parts[0] != "ApiKey" || parts[1] == "" {
+		return "", false
+	}
+	return parts[1], true
+}
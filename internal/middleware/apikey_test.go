@@ -0,0 +1,146 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	apiKeyEntities "trusioo_api/internal/apikeys/entities"
+	"trusioo_api/internal/common"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubAPIKeyValidator "good" 为有效密钥，"office" 只允许 10.0.0.1 调用，"tight" 每分钟只允许一次请求
+type stubAPIKeyValidator struct{}
+
+func (stubAPIKeyValidator) ValidateAPIKey(ctx context.Context, key, clientIP string) (*apiKeyEntities.Principal, error) {
+	switch key {
+	case "good":
+		return &apiKeyEntities.Principal{KeyID: 1, UserID: 42, Email: "partner@example.com", Scopes: []string{"images.read"}, RateLimitPerMinute: 100}, nil
+	case "office":
+		if clientIP != "10.0.0.1" {
+			return nil, common.ErrAPIKeyIPNotAllowed
+		}
+		return &apiKeyEntities.Principal{KeyID: 2, UserID: 42, RateLimitPerMinute: 100}, nil
+	case "tight":
+		return &apiKeyEntities.Principal{KeyID: 3, UserID: 42, Scopes: []string{"images.read"}, RateLimitPerMinute: 1}, nil
+	default:
+		return nil, common.ErrAPIKeyInvalid
+	}
+}
+
+func TestAPIKeyAuthMiddleware(t *testing.T) {
+	setupTestConfig()
+	SetAPIKeyValidator(stubAPIKeyValidator{})
+	defer SetAPIKeyValidator(nil)
+
+	router := setupTestRouter()
+	router.GET("/test", APIKeyAuthMiddleware(), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"user_id":    c.GetInt64("user_id"),
+			"user_type":  c.GetString("user_type"),
+			"api_key_id": c.GetInt64("api_key_id"),
+		})
+	})
+
+	tests := []struct {
+		name           string
+		authHeader     string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "有效密钥",
+			authHeader:     "ApiKey good",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"user_id":42`,
+		},
+		{
+			name:           "缺少密钥",
+			authHeader:     "",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "Bearer令牌不被接受",
+			authHeader:     "Bearer good",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "无效密钥",
+			authHeader:     "ApiKey bad",
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   "Invalid or expired API key",
+		},
+		{
+			name:           "来源IP不在白名单",
+			authHeader:     "ApiKey office",
+			expectedStatus: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/test", nil)
+			if tt.authHeader != "" {
+				req.Header.Set("Authorization", tt.authHeader)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedBody != "" {
+				assert.Contains(t, w.Body.String(), tt.expectedBody)
+			}
+		})
+	}
+
+	t.Run("超过密钥限流", func(t *testing.T) {
+		for i, expected := range []int{http.StatusOK, http.StatusTooManyRequests} {
+			req := httptest.NewRequest("GET", "/test", nil)
+			req.Header.Set("Authorization", "ApiKey tight")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, expected, w.Code, "request %d", i+1)
+		}
+	})
+}
+
+func TestRequireScope(t *testing.T) {
+	setupTestConfig()
+	SetAPIKeyValidator(stubAPIKeyValidator{})
+	defer SetAPIKeyValidator(nil)
+
+	userToken, err := generateTestToken(42, "user@example.com", "user", "user")
+	assert.NoError(t, err)
+
+	router := setupTestRouter()
+	ok := func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"message": "success"}) }
+	router.GET("/read", AuthOrAPIKeyMiddleware(), RequireScope("images.read"), ok)
+	router.GET("/write", AuthOrAPIKeyMiddleware(), RequireScope("images.write"), ok)
+
+	tests := []struct {
+		name           string
+		path           string
+		authHeader     string
+		expectedStatus int
+	}{
+		{name: "拥有范围", path: "/read", authHeader: "ApiKey good", expectedStatus: http.StatusOK},
+		{name: "缺少范围", path: "/write", authHeader: "ApiKey good", expectedStatus: http.StatusForbidden},
+		{name: "用户令牌不受范围限制", path: "/write", authHeader: "Bearer " + userToken, expectedStatus: http.StatusOK},
+		{name: "无效用户令牌", path: "/read", authHeader: "Bearer invalid", expectedStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.path, nil)
+			req.Header.Set("Authorization", tt.authHeader)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+		})
+	}
+}
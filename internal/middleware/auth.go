@@ -46,6 +46,12 @@ func AuthMiddleware() gin.HandlerFunc {
 		c.Set("user_email", claims.Email)
 		c.Set("user_role", claims.Role)
 		c.Set("user_type", claims.UserType)
+		setRecentAuth(c, claims)
+
+		if claims.IsImpersonation() {
+			serveImpersonated(c, claims)
+			return
+		}
 
 		c.Next()
 	}
@@ -83,6 +89,11 @@ func OptionalAuth() gin.HandlerFunc {
 		c.Set("user_role", claims.Role)
 		c.Set("user_type", claims.UserType)
 
+		if claims.IsImpersonation() {
+			serveImpersonated(c, claims)
+			return
+		}
+
 		c.Next()
 	}
 }
@@ -90,21 +101,11 @@ func OptionalAuth() gin.HandlerFunc {
 // AdminAuthMiddleware 管理员认证中间件
 func AdminAuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			common.Unauthorized(c, "Authorization header required")
-			c.Abort()
+		token, ok := adminAccessToken(c)
+		if !ok {
 			return
 		}
 
-		tokenParts := strings.Split(authHeader, " ")
-		if len(tokenParts) != 2 || tokenParts[0] != "Bearer" {
-			common.Unauthorized(c, "Invalid authorization header format")
-			c.Abort()
-			return
-		}
-
-		token := tokenParts[1]
 		claims, err := auth.ValidateAccessToken(token)
 		if err != nil {
 			common.Unauthorized(c, "Invalid or expired token")
@@ -124,6 +125,7 @@ func AdminAuthMiddleware() gin.HandlerFunc {
 		c.Set("user_email", claims.Email)
 		c.Set("user_role", claims.Role)
 		c.Set("user_type", claims.UserType)
+		setRecentAuth(c, claims)
 
 		c.Next()
 	}
@@ -132,21 +134,11 @@ func AdminAuthMiddleware() gin.HandlerFunc {
 // SuperAdminMiddleware 超级管理员认证中间件
 func SuperAdminMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			common.Unauthorized(c, "Authorization header required")
-			c.Abort()
-			return
-		}
-
-		tokenParts := strings.Split(authHeader, " ")
-		if len(tokenParts) != 2 || tokenParts[0] != "Bearer" {
-			common.Unauthorized(c, "Invalid authorization header format")
-			c.Abort()
+		token, ok := adminAccessToken(c)
+		if !ok {
 			return
 		}
 
-		token := tokenParts[1]
 		claims, err := auth.ValidateAccessToken(token)
 		if err != nil {
 			common.Unauthorized(c, "Invalid or expired token")
@@ -166,7 +158,34 @@ func SuperAdminMiddleware() gin.HandlerFunc {
 		c.Set("user_email", claims.Email)
 		c.Set("user_role", claims.Role)
 		c.Set("user_type", claims.UserType)
+		setRecentAuth(c, claims)
 
 		c.Next()
 	}
-}
\ No newline at end of file
+}
+
+// adminAccessToken 读取管理员访问令牌：优先使用 Authorization 请求头，没有时使用管理后台的 Cookie 会话（有效的提升令牌 Cookie 优先）
+// Cookie 会话的修改数据请求需要通过 CSRF 校验；失败时已写入响应并中止
+func adminAccessToken(c *gin.Context) (string, bool) {
+	authHeader := c.GetHeader("Authorization")
+	if authHeader == "" {
+		cookieToken, err := c.Cookie(AdminAccessCookie)
+		if err != nil || cookieToken == "" {
+			common.Unauthorized(c, "Authorization header required")
+			c.Abort()
+			return "", false
+		}
+		if !checkAdminCSRF(c) {
+			return "", false
+		}
+		return adminCookieToken(c, cookieToken), true
+	}
+
+	tokenParts := strings.Split(authHeader, " ")
+	if len(tokenParts) != 2 || tokenParts[0] != "Bearer" {
+		common.Unauthorized(c, "Invalid authorization header format")
+		c.Abort()
+		return "", false
+	}
+	return tokenParts[1], true
+}
@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"context"
+	"time"
+
+	"trusioo_api/internal/common"
+	"trusioo_api/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 挑战请求头
+const (
+	HeaderChallengeToken    = "X-Challenge-Token"
+	HeaderChallengeSolution = "X-Challenge-Solution"
+	HeaderCaptchaResponse   = "X-Captcha-Response"
+)
+
+// ChallengeVerifier 按IP评估挑战难度并校验挑战，由 challenge.Service 实现
+type ChallengeVerifier interface {
+	Difficulty(ctx context.Context, ip string) (int, error)
+	Verify(ctx context.Context, action, ip string, minDifficulty int, token, solution, captcha string) error
+	Record(ctx context.Context, ip string, failed bool) error
+}
+
+var challengeVerifier ChallengeVerifier
+
+// SetChallengeVerifier 注册挑战校验实现，路由初始化时调用；未注册时不要求挑战
+func SetChallengeVerifier(verifier ChallengeVerifier) {
+	challengeVerifier = verifier
+}
+
+// RequireChallenge 客户端IP或所在网段最近请求或失败过多时，要求请求携带已完成的 action 挑战
+// 不满足时返回 428 和错误码 challenge_required；每个请求处理后按响应状态计分，计数失败时不阻止请求
+func RequireChallenge(action string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if challengeVerifier == nil {
+			c.Next()
+			return
+		}
+		ip := c.ClientIP()
+
+		difficulty, err := challengeVerifier.Difficulty(c.Request.Context(), ip)
+		if err != nil {
+			logger.Warnf("Failed to evaluate challenge difficulty for %s: %v", ip, err)
+			difficulty = 0
+		}
+		if difficulty > 0 {
+			err := challengeVerifier.Verify(c.Request.Context(), action, ip, difficulty,
+				c.GetHeader(HeaderChallengeToken), c.GetHeader(HeaderChallengeSolution), c.GetHeader(HeaderCaptchaResponse))
+			switch err {
+			case nil:
+			case common.ErrChallengeRequired:
+				common.ChallengeRequired(c, "Challenge required", difficulty)
+			case common.ErrChallengeInvalid:
+				common.ChallengeRequired(c, "Invalid or expired challenge", difficulty)
+			default:
+				common.ServerError(c, err)
+			}
+			if err != nil {
+				c.Abort()
+				recordChallenge(ip, true)
+				return
+			}
+		}
+
+		c.Next()
+		recordChallenge(ip, c.Writer.Status() >= 400)
+	}
+}
+
+// recordChallenge 请求处理后计分，不使用已结束的请求上下文
+func recordChallenge(ip string, failed bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := challengeVerifier.Record(ctx, ip, failed); err != nil {
+		logger.Warnf("Failed to record challenge score for %s: %v", ip, err)
+	}
+}
@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"trusioo_api/internal/common"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeChallengeVerifier struct {
+	difficulty int
+	recorded   []bool
+}
+
+func (f *fakeChallengeVerifier) Difficulty(ctx context.Context, ip string) (int, error) {
+	return f.difficulty, nil
+}
+
+func (f *fakeChallengeVerifier) Verify(ctx context.Context, action, ip string, minDifficulty int, token, solution, captcha string) error {
+	if token == "" {
+		return common.ErrChallengeRequired
+	}
+	if action != "login" || token != "solved" {
+		return common.ErrChallengeInvalid
+	}
+	return nil
+}
+
+func (f *fakeChallengeVerifier) Record(ctx context.Context, ip string, failed bool) error {
+	f.recorded = append(f.recorded, failed)
+	return nil
+}
+
+func TestRequireChallenge(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	defer SetChallengeVerifier(nil)
+
+	tests := []struct {
+		name           string
+		difficulty     int
+		token          string
+		handlerStatus  int
+		expectedStatus int
+		expectedBody   string
+		recorded       []bool
+	}{
+		{"请求不多时不要求挑战", 0, "", http.StatusOK, http.StatusOK, "ok", []bool{false}},
+		{"处理失败的请求计为失败", 0, "", http.StatusUnauthorized, http.StatusUnauthorized, "ok", []bool{true}},
+		{"需要挑战但未提交", 16, "", http.StatusOK, http.StatusPreconditionRequired, `"difficulty":16`, []bool{true}},
+		{"挑战无效", 16, "forged", http.StatusOK, http.StatusPreconditionRequired, `"error":"challenge_required"`, []bool{true}},
+		{"挑战已完成", 16, "solved", http.StatusOK, http.StatusOK, "ok", []bool{false}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verifier := &fakeChallengeVerifier{difficulty: tt.difficulty}
+			SetChallengeVerifier(verifier)
+
+			router := gin.New()
+			router.POST("/login", RequireChallenge("login"), func(c *gin.Context) {
+				c.String(tt.handlerStatus, "ok")
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/login", nil)
+			if tt.token != "" {
+				req.Header.Set(HeaderChallengeToken, tt.token)
+				req.Header.Set(HeaderChallengeSolution, "1")
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Contains(t, w.Body.String(), tt.expectedBody)
+			assert.Equal(t, tt.recorded, verifier.recorded)
+		})
+	}
+
+	t.Run("未注册校验实现时直接放行", func(t *testing.T) {
+		SetChallengeVerifier(nil)
+		router := gin.New()
+		router.POST("/login", RequireChallenge("login"), func(c *gin.Context) {
+			c.String(http.StatusOK, "ok")
+		})
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/login", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+}
@@ -0,0 +1,156 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"trusioo_api/pkg/auth"
+	"trusioo_api/pkg/ipinfo"
+	"trusioo_api/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 客户端上报的评估请求头
+const (
+	HeaderPlatform   = "X-Platform"
+	HeaderAppVersion = "X-App-Version"
+	HeaderDeviceID   = "X-Device-Id"
+	HeaderLocale     = "X-Locale"
+)
+
+const (
+	evalCtxKey       = "eval_ctx"
+	geoLookupTimeout = 2 * time.Second
+)
+
+// EvalCtx 请求评估上下文，功能开关按它匹配定向规则
+type EvalCtx struct {
+	UserID     int64  `json:"user_id,omitempty"`
+	UserType   string `json:"user_type,omitempty"`
+	Role       string `json:"role,omitempty"`
+	Country    string `json:"country,omitempty"`
+	Platform   string `json:"platform,omitempty"`
+	AppVersion string `json:"app_version,omitempty"`
+	DeviceID   string `json:"device_id,omitempty"`
+	Locale     string `json:"locale,omitempty"`
+	IP         string `json:"ip,omitempty"`
+}
+
+// StickinessKey 灰度分桶依据：登录后使用用户类型和ID，匿名请求使用设备ID，都没有时返回空
+func (e *EvalCtx) StickinessKey() string {
+	if e.UserID > 0 {
+		return e.UserType + ":" + strconv.FormatInt(e.UserID, 10)
+	}
+	if e.DeviceID != "" {
+		return "device:" + e.DeviceID
+	}
+	return ""
+}
+
+// GeoLocator 根据IP查询地理位置，由 ipinfo.Client 实现
+type GeoLocator interface {
+	GetIPInfo(ctx context.Context, ip string) (*ipinfo.IPInfo, error)
+}
+
+var geoLocator GeoLocator
+
+// SetGeoLocator 注册IP地理位置查询实现，路由初始化时调用；未注册时 EvalCtx 不包含国家
+func SetGeoLocator(locator GeoLocator) {
+	geoLocator = locator
+}
+
+// EvalCtxMiddleware 组装请求评估上下文并存入 gin.Context
+// 不要求认证：已通过认证中间件时沿用其用户信息，否则尝试解析 Bearer 令牌，令牌无效时按匿名处理
+func EvalCtxMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		GetEvalCtx(c)
+		c.Next()
+	}
+}
+
+// GetEvalCtx 获取当前请求的评估上下文，未经过 EvalCtxMiddleware 时现场组装
+func GetEvalCtx(c *gin.Context) *EvalCtx {
+	if value, exists := c.Get(evalCtxKey); exists {
+		if evalCtx, ok := value.(*EvalCtx); ok {
+			return evalCtx
+		}
+	}
+
+	evalCtx := BuildEvalCtx(c)
+	c.Set(evalCtxKey, evalCtx)
+	return evalCtx
+}
+
+// BuildEvalCtx 从认证信息、客户端请求头和IP地理位置组装评估上下文
+func BuildEvalCtx(c *gin.Context) *EvalCtx {
+	evalCtx := &EvalCtx{
+		Platform:   strings.ToLower(headerValue(c, HeaderPlatform, 32)),
+		AppVersion: headerValue(c, HeaderAppVersion, 32),
+		DeviceID:   headerValue(c, HeaderDeviceID, 128),
+		Locale:     headerValue(c, HeaderLocale, 35),
+		IP:         c.ClientIP(),
+	}
+
+	if userID, exists := c.Get("user_id"); exists {
+		evalCtx.UserID, _ = userID.(int64)
+		evalCtx.UserType = c.GetString("user_type")
+		evalCtx.Role = c.GetString("user_role")
+	} else if claims := bearerClaims(c); claims != nil {
+		evalCtx.UserID = claims.UserID
+		evalCtx.UserType = claims.UserType
+		evalCtx.Role = claims.Role
+	}
+
+	evalCtx.Country = lookupCountry(c.Request.Context(), evalCtx.IP)
+	return evalCtx
+}
+
+// bearerClaims 解析 Authorization: Bearer 令牌，缺失或无效时返回 nil
+func bearerClaims(c *gin.Context) *auth.Claims {
+	tokenParts := strings.Split(c.GetHeader("Authorization"), " ")
+	if len(tokenParts) != 2 || tokenParts[0] != "Bearer" {
+		return nil
+	}
+	claims, err := auth.ValidateAccessToken(tokenParts[1])
+	if err != nil {
+		return nil
+	}
+	return claims
+}
+
+// lookupCountry 查询IP所在国家，内网地址不查询，查询失败时返回空
+func lookupCountry(ctx context.Context, ip string) string {
+	if geoLocator == nil {
+		return ""
+	}
+	parsed := net.ParseIP(ip)
+	if parsed == nil || parsed.IsLoopback() || parsed.IsPrivate() || parsed.IsUnspecified() {
+		return ""
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, geoLookupTimeout)
+	defer cancel()
+
+	info, err := geoLocator.GetIPInfo(ctx, ip)
+	if err != nil {
+		logger.WithError(err).Debugf("Failed to resolve country for %s", ip)
+		return ""
+	}
+	if info == nil {
+		return ""
+	}
+	return strings.ToUpper(info.Country)
+}
+
+// headerValue 读取请求头并限制长度，超长的客户端值直接截断
+func headerValue(c *gin.Context, name string, maxLen int) string {
+	value := strings.TrimSpace(c.GetHeader(name))
+	if len(value) > maxLen {
+		return value[:maxLen]
+	}
+	return value
+}
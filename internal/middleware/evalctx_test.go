@@ -0,0 +1,163 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"trusioo_api/pkg/ipinfo"
+	"trusioo_api/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubGeoLocator 记录查询次数，返回固定国家
+type stubGeoLocator struct {
+	country string
+	err     error
+	calls   int
+}
+
+func (s *stubGeoLocator) GetIPInfo(ctx context.Context, ip string) (*ipinfo.IPInfo, error) {
+	s.calls++
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &ipinfo.IPInfo{IP: ip, Country: s.country}, nil
+}
+
+func TestBuildEvalCtx(t *testing.T) {
+	setupTestConfig()
+	var logs bytes.Buffer
+	original := logger.Log
+	logger.Log = logrus.New()
+	logger.Log.SetOutput(&logs)
+	defer func() { logger.Log = original }()
+	defer SetGeoLocator(nil)
+
+	userToken, err := generateTestToken(42, "user@example.com", "user", "user")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		headers    map[string]string
+		remoteAddr string
+		locator    *stubGeoLocator
+		expected   EvalCtx
+		geoCalls   int
+	}{
+		{
+			name: "匿名请求读取客户端请求头",
+			headers: map[string]string{
+				HeaderPlatform:   " iOS ",
+				HeaderAppVersion: "1.3.0",
+				HeaderDeviceID:   "device-1",
+				HeaderLocale:     "zh-CN",
+			},
+			remoteAddr: "10.0.0.1:1234",
+			expected: EvalCtx{
+				Platform:   "ios",
+				AppVersion: "1.3.0",
+				DeviceID:   "device-1",
+				Locale:     "zh-CN",
+				IP:         "10.0.0.1",
+			},
+		},
+		{
+			name:       "解析访问令牌中的用户",
+			headers:    map[string]string{"Authorization": "Bearer " + userToken},
+			remoteAddr: "10.0.0.1:1234",
+			expected:   EvalCtx{UserID: 42, UserType: "user", Role: "user", IP: "10.0.0.1"},
+		},
+		{
+			name:       "无效令牌按匿名处理",
+			headers:    map[string]string{"Authorization": "Bearer invalid"},
+			remoteAddr: "10.0.0.1:1234",
+			expected:   EvalCtx{IP: "10.0.0.1"},
+		},
+		{
+			name:       "公网IP查询国家",
+			remoteAddr: "8.8.8.8:1234",
+			locator:    &stubGeoLocator{country: "us"},
+			expected:   EvalCtx{Country: "US", IP: "8.8.8.8"},
+			geoCalls:   1,
+		},
+		{
+			name:       "内网IP不查询国家",
+			remoteAddr: "192.168.1.10:1234",
+			locator:    &stubGeoLocator{country: "US"},
+			expected:   EvalCtx{IP: "192.168.1.10"},
+		},
+		{
+			name:       "国家查询失败",
+			remoteAddr: "8.8.8.8:1234",
+			locator:    &stubGeoLocator{err: errors.New("ipinfo unavailable")},
+			expected:   EvalCtx{IP: "8.8.8.8"},
+			geoCalls:   1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.locator != nil {
+				SetGeoLocator(tt.locator)
+			} else {
+				SetGeoLocator(nil)
+			}
+
+			var got *EvalCtx
+			router := setupTestRouter()
+			router.GET("/test", EvalCtxMiddleware(), func(c *gin.Context) {
+				got = GetEvalCtx(c)
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/test", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			router.ServeHTTP(httptest.NewRecorder(), req)
+
+			require.NotNil(t, got)
+			assert.Equal(t, tt.expected, *got)
+			if tt.locator != nil {
+				assert.Equal(t, tt.geoCalls, tt.locator.calls)
+			}
+		})
+	}
+
+	t.Run("沿用认证中间件的用户信息", func(t *testing.T) {
+		SetGeoLocator(nil)
+		adminToken, err := generateTestToken(7, "admin@example.com", "super_admin", "admin")
+		require.NoError(t, err)
+
+		var got *EvalCtx
+		router := setupTestRouter()
+		router.GET("/test", AdminAuthMiddleware(), EvalCtxMiddleware(), func(c *gin.Context) {
+			got = GetEvalCtx(c)
+			c.Status(http.StatusOK)
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/test", nil)
+		req.Header.Set("Authorization", "Bearer "+adminToken)
+		router.ServeHTTP(httptest.NewRecorder(), req)
+
+		require.NotNil(t, got)
+		assert.Equal(t, int64(7), got.UserID)
+		assert.Equal(t, "admin", got.UserType)
+		assert.Equal(t, "admin:7", got.StickinessKey())
+	})
+}
+
+func TestEvalCtx_StickinessKey(t *testing.T) {
+	assert.Equal(t, "user:42", (&EvalCtx{UserID: 42, UserType: "user", DeviceID: "device-1"}).StickinessKey())
+	assert.Equal(t, "device:device-1", (&EvalCtx{DeviceID: "device-1"}).StickinessKey())
+	assert.Equal(t, "", (&EvalCtx{}).StickinessKey())
+}
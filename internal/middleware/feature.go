@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"context"
+
+	"trusioo_api/internal/common"
+	"trusioo_api/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FlagEvaluator 评估功能开关，由 feature.Service 实现
+type FlagEvaluator interface {
+	IsEnabled(ctx context.Context, key string, evalCtx *EvalCtx) (bool, error)
+}
+
+var flagEvaluator FlagEvaluator
+
+// SetFlagEvaluator 注册功能开关评估实现，路由初始化时调用
+func SetFlagEvaluator(evaluator FlagEvaluator) {
+	flagEvaluator = evaluator
+}
+
+// Gate 功能开关门禁：开关对当前请求关闭时返回 403
+// 需要按用户定向时放在认证中间件之后；未注册评估实现或评估失败时按关闭处理
+func Gate(key string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		enabled := false
+		if flagEvaluator != nil {
+			var err error
+			enabled, err = flagEvaluator.IsEnabled(c.Request.Context(), key, GetEvalCtx(c))
+			if err != nil {
+				logger.WithError(err).Warnf("Failed to evaluate feature flag %s", key)
+				enabled = false
+			}
+		}
+
+		if !enabled {
+			common.ForbiddenWithData(c, "Feature not available", gin.H{"feature": key})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,106 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"trusioo_api/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubFlagEvaluator 只对指定平台开启开关
+type stubFlagEvaluator struct {
+	platform string
+	err      error
+}
+
+func (s stubFlagEvaluator) IsEnabled(ctx context.Context, key string, evalCtx *EvalCtx) (bool, error) {
+	if s.err != nil {
+		return false, s.err
+	}
+	return key == "wallet.withdraw" && evalCtx.Platform == s.platform, nil
+}
+
+func TestGate(t *testing.T) {
+	setupTestConfig()
+	var logs bytes.Buffer
+	original := logger.Log
+	logger.Log = logrus.New()
+	logger.Log.SetOutput(&logs)
+	defer func() { logger.Log = original }()
+	defer SetFlagEvaluator(nil)
+
+	tests := []struct {
+		name           string
+		evaluator      FlagEvaluator
+		flag           string
+		platform       string
+		expectedStatus int
+	}{
+		{
+			name:           "开关开启",
+			evaluator:      stubFlagEvaluator{platform: "ios"},
+			flag:           "wallet.withdraw",
+			platform:       "ios",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "平台不匹配",
+			evaluator:      stubFlagEvaluator{platform: "ios"},
+			flag:           "wallet.withdraw",
+			platform:       "android",
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "未知开关",
+			evaluator:      stubFlagEvaluator{platform: "ios"},
+			flag:           "giftcard.trade",
+			platform:       "ios",
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "评估失败按关闭处理",
+			evaluator:      stubFlagEvaluator{err: errors.New("redis down")},
+			flag:           "wallet.withdraw",
+			platform:       "ios",
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "未注册评估实现",
+			flag:           "wallet.withdraw",
+			platform:       "ios",
+			expectedStatus: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetFlagEvaluator(tt.evaluator)
+
+			router := setupTestRouter()
+			router.POST("/test", Gate(tt.flag), func(c *gin.Context) {
+				c.JSON(http.StatusOK, gin.H{"ok": true})
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/test", nil)
+			req.Header.Set(HeaderPlatform, tt.platform)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.evaluator != nil && tt.evaluator.(stubFlagEvaluator).err != nil {
+				assert.Contains(t, logs.String(), "Failed to evaluate feature flag")
+			}
+			if tt.expectedStatus == http.StatusForbidden {
+				assert.Contains(t, w.Body.String(), `"feature":"`+tt.flag+`"`)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"net/http"
+
+	"trusioo_api/internal/common"
+	"trusioo_api/pkg/auth"
+	"trusioo_api/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// serveImpersonated 处理管理员代登录令牌的请求
+// 只读令牌只允许 GET/HEAD/OPTIONS 请求，每个请求都记录用户和代登录管理员两个身份
+func serveImpersonated(c *gin.Context, claims *auth.Claims) {
+	c.Set("impersonator_id", claims.ImpersonatorID)
+	c.Set("impersonation_read_only", claims.ReadOnly)
+	defer logImpersonatedRequest(c, claims)
+
+	if claims.ReadOnly && !isSafeMethod(c.Request.Method) {
+		common.Forbidden(c, "Impersonation token is read-only")
+		c.Abort()
+		return
+	}
+
+	c.Next()
+}
+
+// RejectImpersonation 拒绝管理员代登录令牌，用于修改密码、资料等敏感接口
+// 需要在 AuthMiddleware 之后使用，即使代登录令牌允许写操作也会被拒绝
+func RejectImpersonation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetInt64("impersonator_id") != 0 {
+			common.Forbidden(c, "Not allowed with an impersonation token")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+func isSafeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+func logImpersonatedRequest(c *gin.Context, claims *auth.Claims) {
+	requestID, _ := c.Get("request_id")
+	logger.WithFields(logrus.Fields{
+		"request_id":      requestID,
+		"user_id":         claims.UserID,
+		"impersonator_id": claims.ImpersonatorID,
+		"read_only":       claims.ReadOnly,
+		"method":          c.Request.Method,
+		"path":            c.Request.URL.Path,
+		"status":          c.Writer.Status(),
+		"ip":              c.ClientIP(),
+	}).Info("Impersonated request")
+}
@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"trusioo_api/pkg/auth"
+	"trusioo_api/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthMiddleware_Impersonation(t *testing.T) {
+	setupTestConfig()
+
+	// 记录日志到内存，检查请求日志包含两个身份
+	var logs bytes.Buffer
+	original := logger.Log
+	logger.Log = logrus.New()
+	logger.Log.SetOutput(&logs)
+	logger.Log.SetFormatter(&logrus.JSONFormatter{})
+	defer func() { logger.Log = original }()
+
+	readOnly, err := auth.GenerateImpersonationToken(10, "user@example.com", 2, true, time.Minute)
+	require.NoError(t, err)
+	writable, err := auth.GenerateImpersonationToken(10, "user@example.com", 2, false, time.Minute)
+	require.NoError(t, err)
+	normal, err := generateTestToken(10, "user@example.com", "user", "user")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		token          string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{"只读令牌允许查询", "GET", "/profile", readOnly, http.StatusOK, `"impersonator_id":2`},
+		{"只读令牌拒绝写操作", "POST", "/profile", readOnly, http.StatusForbidden, "read-only"},
+		{"可写令牌允许写操作", "POST", "/profile", writable, http.StatusOK, "success"},
+		{"敏感接口拒绝代登录令牌", "POST", "/password/change", writable, http.StatusForbidden, "impersonation token"},
+		{"敏感接口允许普通令牌", "POST", "/password/change", normal, http.StatusOK, "success"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := setupTestRouter()
+			router.Use(AuthMiddleware())
+			router.GET("/profile", func(c *gin.Context) {
+				c.JSON(http.StatusOK, gin.H{"impersonator_id": c.GetInt64("impersonator_id")})
+			})
+			router.POST("/profile", func(c *gin.Context) {
+				c.JSON(http.StatusOK, gin.H{"message": "success"})
+			})
+			router.POST("/password/change", RejectImpersonation(), func(c *gin.Context) {
+				c.JSON(http.StatusOK, gin.H{"message": "success"})
+			})
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Contains(t, w.Body.String(), tt.expectedBody)
+		})
+	}
+
+	// 每个代登录请求都记录用户和管理员，普通令牌的请求不记录
+	assert.Equal(t, 4, bytes.Count(logs.Bytes(), []byte("Impersonated request")))
+	assert.Contains(t, logs.String(), `"impersonator_id":2`)
+	assert.Contains(t, logs.String(), `"user_id":10`)
+}
@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"context"
+
+	"trusioo_api/internal/common"
+
+	"github.com/gin-gonic/gin"
+)
+
+// KYCLevelProvider 查询用户认证等级，由 kyc.Service 实现
+type KYCLevelProvider interface {
+	KYCLevel(ctx context.Context, userID int64) (int, error)
+}
+
+var kycLevelProvider KYCLevelProvider
+
+// SetKYCLevelProvider 注册认证等级查询实现，路由初始化时调用
+func SetKYCLevelProvider(provider KYCLevelProvider) {
+	kycLevelProvider = provider
+}
+
+// RequireKYCLevel 要求当前用户的认证等级不低于 minLevel，必须在 AuthMiddleware 之后使用
+// 等级每次从数据库读取，审核通过后立即生效；通过时在上下文中设置 kyc_level
+func RequireKYCLevel(minLevel int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := c.Get("user_id")
+		if !exists || c.GetString("user_type") != "user" {
+			common.Forbidden(c, "User access required")
+			c.Abort()
+			return
+		}
+
+		if kycLevelProvider == nil {
+			common.Forbidden(c, "Identity verification required")
+			c.Abort()
+			return
+		}
+
+		level, err := kycLevelProvider.KYCLevel(c.Request.Context(), userID.(int64))
+		if err != nil {
+			common.ServerError(c, err)
+			c.Abort()
+			return
+		}
+		c.Set("kyc_level", level)
+
+		if level < minLevel {
+			common.ForbiddenWithData(c, "Identity verification required", gin.H{
+				"kyc_level":          level,
+				"required_kyc_level": minLevel,
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,99 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubKYCLevelProvider 用户ID即认证等级
+type stubKYCLevelProvider struct {
+	err error
+}
+
+func (s stubKYCLevelProvider) KYCLevel(ctx context.Context, userID int64) (int, error) {
+	if s.err != nil {
+		return 0, s.err
+	}
+	return int(userID), nil
+}
+
+func TestRequireKYCLevel(t *testing.T) {
+	setupTestConfig()
+	defer SetKYCLevelProvider(nil)
+
+	tests := []struct {
+		name           string
+		provider       KYCLevelProvider
+		userID         int64
+		userType       string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "等级满足",
+			provider:       stubKYCLevelProvider{},
+			userID:         2,
+			userType:       "user",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"kyc_level":2`,
+		},
+		{
+			name:           "等级不足",
+			provider:       stubKYCLevelProvider{},
+			userID:         1,
+			userType:       "user",
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   `"required_kyc_level":2`,
+		},
+		{
+			name:           "管理员令牌",
+			provider:       stubKYCLevelProvider{},
+			userID:         2,
+			userType:       "admin",
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "未注册查询实现",
+			userID:         2,
+			userType:       "user",
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "查询失败",
+			provider:       stubKYCLevelProvider{err: errors.New("db down")},
+			userID:         2,
+			userType:       "user",
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetKYCLevelProvider(tt.provider)
+
+			router := setupTestRouter()
+			router.Use(func(c *gin.Context) {
+				c.Set("user_id", tt.userID)
+				c.Set("user_type", tt.userType)
+				c.Next()
+			})
+			router.GET("/test", RequireKYCLevel(2), func(c *gin.Context) {
+				c.JSON(http.StatusOK, gin.H{"kyc_level": c.GetInt("kyc_level")})
+			})
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest("GET", "/test", nil))
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedBody != "" {
+				assert.Contains(t, w.Body.String(), tt.expectedBody)
+			}
+		})
+	}
+}
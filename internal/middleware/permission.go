@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"context"
+
+	"trusioo_api/internal/common"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PermissionChecker 管理员权限查询接口，由 rbac.Service 实现
+type PermissionChecker interface {
+	HasPermission(ctx context.Context, adminID int64, permission string) (bool, error)
+}
+
+var permissionChecker PermissionChecker
+
+// SetPermissionChecker 注册权限查询实现，路由初始化时调用
+func SetPermissionChecker(checker PermissionChecker) {
+	permissionChecker = checker
+}
+
+// RequirePermission 要求当前管理员拥有指定权限，必须在 AdminAuthMiddleware 之后使用
+// 未注册权限查询实现时拒绝访问
+func RequirePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		adminID, exists := c.Get("user_id")
+		if !exists || c.GetString("user_type") != "admin" {
+			common.Forbidden(c, "Admin access required")
+			c.Abort()
+			return
+		}
+
+		if permissionChecker == nil {
+			common.Forbidden(c, "Insufficient permissions")
+			c.Abort()
+			return
+		}
+
+		allowed, err := permissionChecker.HasPermission(c.Request.Context(), adminID.(int64), permission)
+		if err != nil {
+			common.ServerError(c, err)
+			c.Abort()
+			return
+		}
+		if !allowed {
+			common.ForbiddenWithData(c, "Insufficient permissions", gin.H{"permission": permission})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,102 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubPermissionChecker 管理员1拥有 users.read
+type stubPermissionChecker struct {
+	err error
+}
+
+func (s stubPermissionChecker) HasPermission(ctx context.Context, adminID int64, permission string) (bool, error) {
+	if s.err != nil {
+		return false, s.err
+	}
+	return adminID == 1 && permission == "users.read", nil
+}
+
+func TestRequirePermission(t *testing.T) {
+	setupTestConfig()
+	defer SetPermissionChecker(nil)
+
+	tests := []struct {
+		name           string
+		checker        PermissionChecker
+		userID         int64
+		userType       string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "拥有权限",
+			checker:        stubPermissionChecker{},
+			userID:         1,
+			userType:       "admin",
+			expectedStatus: http.StatusOK,
+			expectedBody:   "success",
+		},
+		{
+			name:           "缺少权限",
+			checker:        stubPermissionChecker{},
+			userID:         2,
+			userType:       "admin",
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   "users.read",
+		},
+		{
+			name:           "普通用户令牌",
+			checker:        stubPermissionChecker{},
+			userID:         1,
+			userType:       "user",
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   "Admin access required",
+		},
+		{
+			name:           "未注册权限查询时拒绝",
+			checker:        nil,
+			userID:         1,
+			userType:       "admin",
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   "Insufficient permissions",
+		},
+		{
+			name:           "权限查询失败",
+			checker:        stubPermissionChecker{err: errors.New("database unavailable")},
+			userID:         1,
+			userType:       "admin",
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetPermissionChecker(tt.checker)
+
+			router := setupTestRouter()
+			router.Use(func(c *gin.Context) {
+				c.Set("user_id", tt.userID)
+				c.Set("user_type", tt.userType)
+				c.Next()
+			})
+			router.GET("/test", RequirePermission("users.read"), func(c *gin.Context) {
+				c.JSON(http.StatusOK, gin.H{"message": "success"})
+			})
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest("GET", "/test", nil))
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedBody != "" {
+				assert.Contains(t, w.Body.String(), tt.expectedBody)
+			}
+		})
+	}
+}
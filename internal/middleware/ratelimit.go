@@ -110,6 +110,26 @@ func (rl *RateLimiter) getVisitor(ip string) *Visitor {
 	return visitor
 }
 
+// allow 按 key 使用每分钟 perMinute 个请求的令牌桶，限额变化时重建令牌桶
+func (rl *RateLimiter) allow(key string, perMinute int) bool {
+	if perMinute <= 0 {
+		return true
+	}
+
+	rl.mutex.Lock()
+	visitor, exists := rl.visitors[key]
+	if !exists || visitor.limiter.capacity != perMinute {
+		visitor = &Visitor{
+			limiter: NewTokenBucket(perMinute, time.Minute/time.Duration(perMinute)),
+		}
+		rl.visitors[key] = visitor
+	}
+	visitor.lastSeen = time.Now()
+	rl.mutex.Unlock()
+
+	return visitor.limiter.Allow()
+}
+
 // RateLimitMiddleware 速率限制中间件
 func RateLimitMiddleware(rateLimiter *RateLimiter) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -97,7 +97,8 @@ func CORSMiddleware() gin.HandlerFunc {
 		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, PATCH, OPTIONS")
 		c.Header("Access-Control-Allow-Headers", 
 			"Origin, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, " +
-			"Authorization, X-Requested-With, X-Request-ID, X-Correlation-ID")
+			"Authorization, X-Requested-With, X-Request-ID, X-Correlation-ID, " +
+			"X-Platform, X-App-Version, X-Device-Id, X-Locale")
 		c.Header("Access-Control-Expose-Headers", "X-Request-ID, X-Correlation-ID")
 		c.Header("Access-Control-Allow-Credentials", "true")
 		c.Header("Access-Control-Max-Age", "86400") // 24小时
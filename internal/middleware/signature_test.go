@@ -0,0 +1,154 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	apiKeyEntities "trusioo_api/internal/apikeys/entities"
+	"trusioo_api/pkg/requestsign"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryNonceStore 内存版随机数存储
+type memoryNonceStore struct {
+	seen map[string]bool
+	err  error
+}
+
+func (m *memoryNonceStore) SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) (bool, error) {
+	if m.err != nil {
+		return false, m.err
+	}
+	if m.seen[key] {
+		return false, nil
+	}
+	m.seen[key] = true
+	return true, nil
+}
+
+func TestSignatureMiddleware(t *testing.T) {
+	setupTestConfig()
+
+	const secret = "signing-secret"
+	body := `{"name":"card.png"}`
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	stale := strconv.FormatInt(time.Now().Add(-10*time.Minute).Unix(), 10)
+
+	signed := func(timestamp, nonce string) map[string]string {
+		return map[string]string{
+			requestsign.HeaderTimestamp: timestamp,
+			requestsign.HeaderNonce:     nonce,
+			requestsign.HeaderSignature: requestsign.Sign(secret, "POST", "/upload?tag=a", []byte(body), timestamp, nonce),
+		}
+	}
+
+	tests := []struct {
+		name           string
+		principal      *apiKeyEntities.Principal
+		headers        map[string]string
+		store          *memoryNonceStore
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "用户令牌不校验签名",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "密钥未要求签名且未签名",
+			principal:      &apiKeyEntities.Principal{KeyID: 1, SigningSecret: secret},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "密钥要求签名但未签名",
+			principal:      &apiKeyEntities.Principal{KeyID: 1, SigningSecret: secret, RequireSignature: true},
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   "Request signature required",
+		},
+		{
+			name:           "签名有效",
+			principal:      &apiKeyEntities.Principal{KeyID: 1, SigningSecret: secret, RequireSignature: true},
+			headers:        signed(now, "n1"),
+			expectedStatus: http.StatusOK,
+			expectedBody:   body,
+		},
+		{
+			name:           "未要求签名时也校验携带的签名",
+			principal:      &apiKeyEntities.Principal{KeyID: 1, SigningSecret: "other"},
+			headers:        signed(now, "n1"),
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   "Invalid request signature",
+		},
+		{
+			name:           "时间戳过期",
+			principal:      &apiKeyEntities.Principal{KeyID: 1, SigningSecret: secret, RequireSignature: true},
+			headers:        signed(stale, "n1"),
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   "outside the allowed window",
+		},
+		{
+			name:           "随机数重复使用",
+			principal:      &apiKeyEntities.Principal{KeyID: 1, SigningSecret: secret, RequireSignature: true},
+			headers:        signed(now, "n1"),
+			store:          &memoryNonceStore{seen: map[string]bool{"signature:1:n1": true}},
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   "already been used",
+		},
+		{
+			name:           "其他密钥的随机数不冲突",
+			principal:      &apiKeyEntities.Principal{KeyID: 2, SigningSecret: secret, RequireSignature: true},
+			headers:        signed(now, "n1"),
+			store:          &memoryNonceStore{seen: map[string]bool{"signature:1:n1": true}},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "随机数存储不可用",
+			principal:      &apiKeyEntities.Principal{KeyID: 1, SigningSecret: secret, RequireSignature: true},
+			headers:        signed(now, "n1"),
+			store:          &memoryNonceStore{err: errors.New("redis down")},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := tt.store
+			if store == nil {
+				store = &memoryNonceStore{seen: map[string]bool{}}
+			}
+
+			router := setupTestRouter()
+			router.Use(func(c *gin.Context) {
+				if tt.principal != nil {
+					c.Set(apiKeyPrincipalKey, tt.principal)
+				}
+				c.Next()
+			})
+			router.POST("/upload", SignatureMiddleware(store, 5*time.Minute), func(c *gin.Context) {
+				data, _ := io.ReadAll(c.Request.Body)
+				c.String(http.StatusOK, string(data))
+			})
+
+			req := httptest.NewRequest("POST", "/upload?tag=a", strings.NewReader(body))
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedBody != "" {
+				assert.Contains(t, w.Body.String(), tt.expectedBody)
+			}
+		})
+	}
+}
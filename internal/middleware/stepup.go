@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"time"
+
+	"trusioo_api/config"
+	"trusioo_api/internal/common"
+	"trusioo_api/pkg/auth"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultStepUpMaxAge 未配置 STEP_UP_MAX_AGE_MINUTES 时使用
+const defaultStepUpMaxAge = 5 * time.Minute
+
+// StepUpMaxAge 敏感操作默认要求的最近验证身份时间
+func StepUpMaxAge() time.Duration {
+	if config.AppConfig != nil && config.AppConfig.StepUp.MaxAgeMinutes > 0 {
+		return time.Duration(config.AppConfig.StepUp.MaxAgeMinutes) * time.Minute
+	}
+	return defaultStepUpMaxAge
+}
+
+// setRecentAuth 提升令牌的验证时间和验证方式存入上下文，普通令牌不设置
+func setRecentAuth(c *gin.Context, claims *auth.Claims) {
+	if claims.AuthTime > 0 {
+		c.Set("auth_time", claims.AuthTime)
+		c.Set("acr", claims.ACR)
+	}
+}
+
+// RequireRecentAuth 要求请求使用 maxAge 内重新验证身份后签发的提升令牌，必须在认证中间件之后使用
+// 不满足时返回 401 和错误码 reauth_required，客户端重新验证后携带提升令牌重试
+func RequireRecentAuth(maxAge time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authTime := c.GetInt64("auth_time")
+		if authTime <= 0 || time.Since(time.Unix(authTime, 0)) > maxAge {
+			common.ReauthRequired(c, maxAge)
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"trusioo_api/pkg/auth"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequireRecentAuth(t *testing.T) {
+	setupTestConfig()
+
+	tests := []struct {
+		name           string
+		token          func() (string, error)
+		auth           gin.HandlerFunc
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "普通令牌",
+			token: func() (string, error) {
+				return generateTestToken(1, "user@example.com", "user", "user")
+			},
+			auth:           AuthMiddleware(),
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   `"error":"reauth_required"`,
+		},
+		{
+			name: "提升令牌已超过有效验证时间",
+			token: func() (string, error) {
+				return auth.GenerateElevatedToken(1, "user@example.com", "user", "user", auth.ACRPassword, time.Now().Add(-10*time.Minute), time.Hour)
+			},
+			auth:           AuthMiddleware(),
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   `"max_age":300`,
+		},
+		{
+			name: "近期验证的用户提升令牌",
+			token: func() (string, error) {
+				return auth.GenerateElevatedToken(1, "user@example.com", "user", "user", auth.ACREmailCode, time.Now(), time.Minute)
+			},
+			auth:           AuthMiddleware(),
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"acr":"email_code"`,
+		},
+		{
+			name: "近期验证的管理员提升令牌",
+			token: func() (string, error) {
+				return auth.GenerateElevatedToken(1, "admin@example.com", "admin", "admin", auth.ACRPassword, time.Now(), time.Minute)
+			},
+			auth:           AdminAuthMiddleware(),
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"acr":"password"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := tt.token()
+			require.NoError(t, err)
+
+			router := setupTestRouter()
+			router.GET("/test", tt.auth, RequireRecentAuth(5*time.Minute), func(c *gin.Context) {
+				c.JSON(http.StatusOK, gin.H{"acr": c.GetString("acr")})
+			})
+
+			req := httptest.NewRequest("GET", "/test", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Contains(t, w.Body.String(), tt.expectedBody)
+			if tt.expectedStatus == http.StatusUnauthorized {
+				assert.Contains(t, w.Header().Get("WWW-Authenticate"), `error="insufficient_user_authentication"`)
+			}
+		})
+	}
+}
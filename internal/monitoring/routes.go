@@ -2,6 +2,7 @@ package monitoring
 
 import (
 	"github.com/gin-gonic/gin"
+	"trusioo_api/internal/auth/rbac"
 	"trusioo_api/internal/middleware"
 )
 
@@ -9,6 +10,7 @@ func SetupRoutes(r *gin.RouterGroup, handler *Handler) {
 	// 所有监控接口都需要管理员权限
 	monitoringRoutes := r.Group("/monitoring")
 	monitoringRoutes.Use(middleware.AdminAuthMiddleware())
+	monitoringRoutes.Use(middleware.RequirePermission(rbac.PermMonitoringRead))
 	
 	{
 		// 基础指标
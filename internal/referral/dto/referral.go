@@ -0,0 +1,64 @@
+package dto
+
+import (
+	"time"
+
+	"trusioo_api/internal/referral/entities"
+)
+
+// StatsResponse 当前用户的邀请码和邀请统计
+type StatsResponse struct {
+	Code       string             `json:"code"`
+	Invited    int                `json:"invited"`  // 全部被邀请人
+	Pending    int                `json:"pending"`  // 尚未完成发放奖励的操作
+	Rewarded   int                `json:"rewarded"` // 已发放奖励
+	Earned     int                `json:"earned"`   // 作为邀请人获得的奖励合计
+	RewardUnit string             `json:"reward_unit"`
+	Recent     []*ReferralSummary `json:"recent"`
+	Rules      RewardRules        `json:"rules"`
+	ReferredBy *ReferralSummary   `json:"referred_by,omitempty"` // 当前用户注册时填写的邀请
+	Rewards    []*entities.Reward `json:"rewards"`               // 当前用户获得的奖励
+}
+
+// ReferralSummary 用户可见的邀请记录，不包含对方的账户信息
+type ReferralSummary struct {
+	ID          int64      `json:"id"`
+	Status      string     `json:"status"`
+	QualifiedAt *time.Time `json:"qualified_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// RewardRules 当前的奖励规则
+type RewardRules struct {
+	QualifyingAction string `json:"qualifying_action"`
+	ReferrerReward   int    `json:"referrer_reward"`
+	RefereeReward    int    `json:"referee_reward"`
+	Unit             string `json:"unit"`
+}
+
+// ListRequest 邀请关系列表请求，按创建时间从新到旧排序
+type ListRequest struct {
+	Status     string `form:"status" binding:"omitempty,oneof=pending held rewarded qualified rejected"`
+	ReferrerID int64  `form:"referrer_id" binding:"omitempty,min=1"`
+	Page       int    `form:"page" binding:"omitempty,min=1"`
+	PageSize   int    `form:"page_size" binding:"omitempty,min=1,max=100"`
+}
+
+// ListResponse 邀请关系列表
+type ListResponse struct {
+	Total     int64                `json:"total"`
+	Page      int                  `json:"page"`
+	Size      int                  `json:"size"`
+	Referrals []*entities.Referral `json:"referrals"`
+}
+
+// ClustersRequest 可疑聚集请求，by 为 ip 或 device，min 默认为 REFERRAL_CLUSTER_THRESHOLD
+type ClustersRequest struct {
+	By  string `form:"by" binding:"required,oneof=ip device"`
+	Min int    `form:"min" binding:"omitempty,min=2"`
+}
+
+// ReviewRequest 审核暂缓发放的邀请奖励
+type ReviewRequest struct {
+	Reason string `json:"reason" binding:"required,max=255"`
+}
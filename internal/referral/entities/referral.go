@@ -0,0 +1,86 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// 邀请关系状态
+const (
+	StatusPending   = "pending"   // 被邀请人尚未完成发放奖励的操作
+	StatusHeld      = "held"      // 已完成但被标记为可疑，等待管理员审核
+	StatusRewarded  = "rewarded"  // 已发放奖励
+	StatusQualified = "qualified" // 已完成但未发放奖励：奖励为 0 或邀请人已达到奖励次数上限
+	StatusRejected  = "rejected"  // 管理员拒绝发放
+)
+
+// 可疑标记原因
+const (
+	FlagSameIPAsReferrer = "same_ip_as_referrer" // 注册IP与邀请人近期登录IP相同
+	FlagIPCluster        = "ip_cluster"          // 同一IP注册的被邀请人达到阈值
+	FlagDeviceCluster    = "device_cluster"      // 同一设备注册的被邀请人达到阈值
+)
+
+// 奖励对象
+const (
+	RoleReferrer = "referrer"
+	RoleReferee  = "referee"
+)
+
+// 发放奖励的操作
+const (
+	ActionKYCApproved   = "kyc_approved"
+	ActionEmailVerified = "email_verified"
+)
+
+// Code 用户的邀请码
+type Code struct {
+	UserID    int64     `json:"user_id" db:"user_id"`
+	Code      string    `json:"code" db:"code"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// Referral 邀请关系，DeviceHash 为设备ID（未提供时为 User-Agent）的 SHA-256
+type Referral struct {
+	ID           int64          `json:"id" db:"id"`
+	ReferrerID   int64          `json:"referrer_id" db:"referrer_id"`
+	RefereeID    int64          `json:"referee_id" db:"referee_id"`
+	Code         string         `json:"code" db:"code"`
+	SignupIP     string         `json:"signup_ip" db:"signup_ip"`
+	DeviceHash   string         `json:"device_hash" db:"device_hash"`
+	Status       string         `json:"status" db:"status"`
+	FlagReasons  pq.StringArray `json:"flag_reasons" db:"flag_reasons"`
+	QualifiedAt  *time.Time     `json:"qualified_at,omitempty" db:"qualified_at"`
+	ReviewedBy   *int64         `json:"reviewed_by,omitempty" db:"reviewed_by"`
+	ReviewReason string         `json:"review_reason,omitempty" db:"review_reason"`
+	CreatedAt    time.Time      `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at" db:"updated_at"`
+}
+
+// Flagged 是否被标记为可疑
+func (r *Referral) Flagged() bool {
+	return len(r.FlagReasons) > 0
+}
+
+// Reward 已发放的邀请奖励
+type Reward struct {
+	ID         int64     `json:"id" db:"id"`
+	ReferralID int64     `json:"referral_id" db:"referral_id"`
+	UserID     int64     `json:"user_id" db:"user_id"`
+	Role       string    `json:"role" db:"role"`
+	Amount     int       `json:"amount" db:"amount"`
+	Unit       string    `json:"unit" db:"unit"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+}
+
+// Cluster 同一注册IP或设备的被邀请人聚集
+type Cluster struct {
+	Key         string        `json:"key" db:"key"`
+	Referrals   int           `json:"referrals" db:"referrals"`
+	Referrers   int           `json:"referrers" db:"referrers"`
+	ReferrerIDs pq.Int64Array `json:"referrer_ids" db:"referrer_ids"`
+	RefereeIDs  pq.Int64Array `json:"referee_ids" db:"referee_ids"`
+	FirstAt     time.Time     `json:"first_at" db:"first_at"`
+	LastAt      time.Time     `json:"last_at" db:"last_at"`
+}
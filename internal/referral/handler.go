@@ -0,0 +1,207 @@
+package referral
+
+import (
+	"strconv"
+
+	"trusioo_api/internal/audit"
+	"trusioo_api/internal/common"
+	"trusioo_api/internal/referral/dto"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// GetStats 获取邀请统计
+// @Summary 获取邀请统计
+// @Description 获取当前用户的邀请码（首次获取时生成）、邀请人数、已发放的奖励和奖励规则
+// @Tags 邀请
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} common.Response{data=dto.StatsResponse} "获取成功"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 500 {object} common.Response "服务器错误"
+// @Router /api/v1/referrals [get]
+func (h *Handler) GetStats(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		common.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	resp, err := h.service.Stats(c.Request.Context(), userID.(int64))
+	if err != nil {
+		common.ServerError(c, err)
+		return
+	}
+
+	common.Success(c, resp)
+}
+
+// ListReferrals 获取邀请关系列表
+// @Summary 获取邀请关系列表
+// @Description 按状态和邀请人筛选邀请关系，包含注册IP、设备哈希和可疑标记原因
+// @Tags 管理员-邀请
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param status query string false "状态：pending、held、rewarded、qualified、rejected"
+// @Param referrer_id query int false "邀请人ID"
+// @Param page query int false "页码，默认1"
+// @Param page_size query int false "每页数量，默认20，最大100"
+// @Success 200 {object} common.Response{data=dto.ListResponse} "获取成功"
+// @Failure 400 {object} common.Response "参数错误"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足"
+// @Failure 500 {object} common.Response "服务器错误"
+// @Router /api/v1/admin/referrals [get]
+func (h *Handler) ListReferrals(c *gin.Context) {
+	var req dto.ListRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		common.ValidationError(c, err.Error())
+		return
+	}
+
+	resp, err := h.service.List(c.Request.Context(), &req)
+	if err != nil {
+		common.ServerError(c, err)
+		return
+	}
+
+	common.Success(c, resp)
+}
+
+// ListClusters 获取可疑聚集
+// @Summary 获取可疑聚集
+// @Description 按注册IP或设备分组，返回被邀请人数量不少于 min 的聚集及涉及的邀请人和被邀请人
+// @Tags 管理员-邀请
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param by query string true "分组方式：ip 或 device"
+// @Param min query int false "最少被邀请人数量，默认为 REFERRAL_CLUSTER_THRESHOLD"
+// @Success 200 {object} common.Response{data=[]entities.Cluster} "获取成功"
+// @Failure 400 {object} common.Response "参数错误"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足"
+// @Failure 500 {object} common.Response "服务器错误"
+// @Router /api/v1/admin/referrals/clusters [get]
+func (h *Handler) ListClusters(c *gin.Context) {
+	var req dto.ClustersRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		common.ValidationError(c, err.Error())
+		return
+	}
+
+	clusters, err := h.service.Clusters(c.Request.Context(), &req)
+	if err != nil {
+		common.ServerError(c, err)
+		return
+	}
+
+	common.Success(c, clusters)
+}
+
+// ReleaseReferral 发放暂缓的邀请奖励
+// @Summary 发放暂缓的邀请奖励
+// @Description 审核通过被标记为可疑的邀请关系并发放奖励，邀请人已达到奖励次数上限时不发放
+// @Tags 管理员-邀请
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "邀请关系ID"
+// @Param request body dto.ReviewRequest true "审核原因"
+// @Success 200 {object} common.Response{data=entities.Referral} "发放成功"
+// @Failure 400 {object} common.Response "参数错误或邀请关系不是暂缓状态"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足"
+// @Failure 404 {object} common.Response "邀请关系不存在"
+// @Failure 500 {object} common.Response "服务器错误"
+// @Router /api/v1/admin/referrals/{id}/release [post]
+func (h *Handler) ReleaseReferral(c *gin.Context) {
+	adminID, id, req, ok := reviewParams(c)
+	if !ok {
+		return
+	}
+
+	referral, err := h.service.Release(audit.Context(c), adminID, id, req)
+	if err != nil {
+		handleReviewError(c, err)
+		return
+	}
+
+	common.Success(c, referral)
+}
+
+// RejectReferral 拒绝发放邀请奖励
+// @Summary 拒绝发放邀请奖励
+// @Description 拒绝为待完成或暂缓发放的邀请关系发放奖励
+// @Tags 管理员-邀请
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "邀请关系ID"
+// @Param request body dto.ReviewRequest true "拒绝原因"
+// @Success 200 {object} common.Response{data=entities.Referral} "拒绝成功"
+// @Failure 400 {object} common.Response "参数错误或邀请关系已审核"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足"
+// @Failure 404 {object} common.Response "邀请关系不存在"
+// @Failure 500 {object} common.Response "服务器错误"
+// @Router /api/v1/admin/referrals/{id}/reject [post]
+func (h *Handler) RejectReferral(c *gin.Context) {
+	adminID, id, req, ok := reviewParams(c)
+	if !ok {
+		return
+	}
+
+	referral, err := h.service.Reject(audit.Context(c), adminID, id, req)
+	if err != nil {
+		handleReviewError(c, err)
+		return
+	}
+
+	common.Success(c, referral)
+}
+
+// reviewParams 获取当前管理员ID、路径中的邀请关系ID和审核原因，失败时已写入响应
+func reviewParams(c *gin.Context) (int64, int64, *dto.ReviewRequest, bool) {
+	adminID, exists := c.Get("user_id")
+	if !exists {
+		common.Unauthorized(c, "Admin not authenticated")
+		return 0, 0, nil, false
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		common.ValidationError(c, "Invalid referral ID")
+		return 0, 0, nil, false
+	}
+
+	var req dto.ReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ValidationError(c, err.Error())
+		return 0, 0, nil, false
+	}
+
+	return adminID.(int64), id, &req, true
+}
+
+// handleReviewError 审核操作的通用错误响应
+func handleReviewError(c *gin.Context, err error) {
+	switch err {
+	case common.ErrNotFound:
+		common.NotFound(c, "Referral not found")
+	case common.ErrReferralAlreadyReviewed:
+		common.ValidationError(c, "Referral has already been reviewed")
+	default:
+		common.ServerError(c, err)
+	}
+}
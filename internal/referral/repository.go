@@ -0,0 +1,289 @@
+package referral
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"trusioo_api/internal/audit"
+	auditEntities "trusioo_api/internal/audit/entities"
+	"trusioo_api/internal/referral/entities"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// Repository 邀请码与邀请关系的数据访问接口
+type Repository interface {
+	GetCodeByUser(ctx context.Context, userID int64) (*entities.Code, error)
+	GetCode(ctx context.Context, code string) (*entities.Code, error)
+	CreateCode(ctx context.Context, code *entities.Code) error
+	RecentLoginIPs(ctx context.Context, userID int64, since time.Time) ([]string, error)
+	CountReferralsBy(ctx context.Context, column, value string) (int, error)
+	CreateReferral(ctx context.Context, referral *entities.Referral) error
+	GetReferral(ctx context.Context, id int64) (*entities.Referral, error)
+	GetReferralByReferee(ctx context.Context, refereeID int64) (*entities.Referral, error)
+	ListReferralsByReferrer(ctx context.Context, referrerID int64, limit int) ([]*entities.Referral, error)
+	CountReferralsByStatus(ctx context.Context, referrerID int64) (map[string]int, error)
+	ListRewards(ctx context.Context, userID int64) ([]*entities.Reward, error)
+	ListReferrals(ctx context.Context, status string, referrerID int64, limit, offset int) ([]*entities.Referral, int64, error)
+	ListClusters(ctx context.Context, column string, min int) ([]*entities.Cluster, error)
+	QualifyReferral(ctx context.Context, id int64, status string, rewards []*entities.Reward) error
+	ReviewReferral(ctx context.Context, id, adminID int64, from []string, status, reason string, rewards []*entities.Reward, entry *auditEntities.Log) error
+}
+
+// 可用于统计和聚集的列
+const (
+	ColumnSignupIP   = "signup_ip"
+	ColumnDeviceHash = "device_hash"
+)
+
+type repository struct {
+	db *sqlx.DB
+}
+
+// NewRepository 创建邀请仓库
+func NewRepository(db *sqlx.DB) Repository {
+	return &repository{db: db}
+}
+
+// GetCodeByUser 获取用户的邀请码，未生成时返回 sql.ErrNoRows
+func (r *repository) GetCodeByUser(ctx context.Context, userID int64) (*entities.Code, error) {
+	var code entities.Code
+	if err := r.db.GetContext(ctx, &code, "SELECT * FROM referral_codes WHERE user_id = $1", userID); err != nil {
+		return nil, err
+	}
+	return &code, nil
+}
+
+// GetCode 按邀请码查询，不存在时返回 sql.ErrNoRows
+func (r *repository) GetCode(ctx context.Context, code string) (*entities.Code, error) {
+	var result entities.Code
+	if err := r.db.GetContext(ctx, &result, "SELECT * FROM referral_codes WHERE code = $1", code); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// CreateCode 保存邀请码，邀请码或用户已存在时返回唯一约束错误
+func (r *repository) CreateCode(ctx context.Context, code *entities.Code) error {
+	query := `INSERT INTO referral_codes (user_id, code) VALUES ($1, $2) RETURNING created_at`
+	return r.db.QueryRowxContext(ctx, query, code.UserID, code.Code).Scan(&code.CreatedAt)
+}
+
+// RecentLoginIPs 用户在 since 之后成功登录使用过的IP
+func (r *repository) RecentLoginIPs(ctx context.Context, userID int64, since time.Time) ([]string, error) {
+	ips := []string{}
+	query := `SELECT DISTINCT ip FROM user_login_sessions WHERE user_id = $1 AND status = 'success' AND created_at > $2`
+	if err := r.db.SelectContext(ctx, &ips, query, userID, since); err != nil {
+		return nil, err
+	}
+	return ips, nil
+}
+
+// CountReferralsBy 统计注册IP或设备相同的邀请关系数量
+func (r *repository) CountReferralsBy(ctx context.Context, column, value string) (int, error) {
+	if err := checkColumn(column); err != nil {
+		return 0, err
+	}
+	var count int
+	query := fmt.Sprintf("SELECT COUNT(*) FROM referrals WHERE %s = $1", column)
+	if err := r.db.GetContext(ctx, &count, query, value); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// CreateReferral 保存邀请关系，回填ID和时间；被邀请人已有邀请关系时返回唯一约束错误
+func (r *repository) CreateReferral(ctx context.Context, referral *entities.Referral) error {
+	query := `
+		INSERT INTO referrals (referrer_id, referee_id, code, signup_ip, device_hash, status, flag_reasons)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at, updated_at`
+	return r.db.QueryRowxContext(ctx, query,
+		referral.ReferrerID, referral.RefereeID, referral.Code, referral.SignupIP, referral.DeviceHash,
+		referral.Status, referral.FlagReasons,
+	).Scan(&referral.ID, &referral.CreatedAt, &referral.UpdatedAt)
+}
+
+// GetReferral 按ID获取邀请关系，不存在时返回 sql.ErrNoRows
+func (r *repository) GetReferral(ctx context.Context, id int64) (*entities.Referral, error) {
+	var referral entities.Referral
+	if err := r.db.GetContext(ctx, &referral, "SELECT * FROM referrals WHERE id = $1", id); err != nil {
+		return nil, err
+	}
+	return &referral, nil
+}
+
+// GetReferralByReferee 获取被邀请人的邀请关系，不存在时返回 sql.ErrNoRows
+func (r *repository) GetReferralByReferee(ctx context.Context, refereeID int64) (*entities.Referral, error) {
+	var referral entities.Referral
+	if err := r.db.GetContext(ctx, &referral, "SELECT * FROM referrals WHERE referee_id = $1", refereeID); err != nil {
+		return nil, err
+	}
+	return &referral, nil
+}
+
+// ListReferralsByReferrer 邀请人最近的邀请关系
+func (r *repository) ListReferralsByReferrer(ctx context.Context, referrerID int64, limit int) ([]*entities.Referral, error) {
+	referrals := []*entities.Referral{}
+	query := `SELECT * FROM referrals WHERE referrer_id = $1 ORDER BY created_at DESC, id DESC LIMIT $2`
+	if err := r.db.SelectContext(ctx, &referrals, query, referrerID, limit); err != nil {
+		return nil, err
+	}
+	return referrals, nil
+}
+
+// CountReferralsByStatus 邀请人各状态的邀请关系数量
+func (r *repository) CountReferralsByStatus(ctx context.Context, referrerID int64) (map[string]int, error) {
+	rows := []struct {
+		Status string `db:"status"`
+		Count  int    `db:"count"`
+	}{}
+	query := `SELECT status, COUNT(*) AS count FROM referrals WHERE referrer_id = $1 GROUP BY status`
+	if err := r.db.SelectContext(ctx, &rows, query, referrerID); err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int, len(rows))
+	for _, row := range rows {
+		counts[row.Status] = row.Count
+	}
+	return counts, nil
+}
+
+// ListRewards 用户获得的全部奖励
+func (r *repository) ListRewards(ctx context.Context, userID int64) ([]*entities.Reward, error) {
+	rewards := []*entities.Reward{}
+	query := `SELECT * FROM referral_rewards WHERE user_id = $1 ORDER BY created_at DESC, id DESC`
+	if err := r.db.SelectContext(ctx, &rewards, query, userID); err != nil {
+		return nil, err
+	}
+	return rewards, nil
+}
+
+// ListReferrals 按状态和邀请人筛选邀请关系，空值表示不筛选
+func (r *repository) ListReferrals(ctx context.Context, status string, referrerID int64, limit, offset int) ([]*entities.Referral, int64, error) {
+	where := `WHERE ($1 = '' OR status = $1) AND ($2 = 0 OR referrer_id = $2)`
+
+	var total int64
+	if err := r.db.GetContext(ctx, &total, "SELECT COUNT(*) FROM referrals "+where, status, referrerID); err != nil {
+		return nil, 0, err
+	}
+
+	referrals := []*entities.Referral{}
+	query := `SELECT * FROM referrals ` + where + ` ORDER BY created_at DESC, id DESC LIMIT $3 OFFSET $4`
+	if err := r.db.SelectContext(ctx, &referrals, query, status, referrerID, limit, offset); err != nil {
+		return nil, 0, fmt.Errorf("failed to list referrals: %w", err)
+	}
+	return referrals, total, nil
+}
+
+// ListClusters 注册IP或设备相同的被邀请人不少于 min 个的聚集，按数量从多到少排序，最多 100 个
+func (r *repository) ListClusters(ctx context.Context, column string, min int) ([]*entities.Cluster, error) {
+	if err := checkColumn(column); err != nil {
+		return nil, err
+	}
+
+	clusters := []*entities.Cluster{}
+	query := fmt.Sprintf(`
+		SELECT %[1]s AS key,
+			COUNT(*) AS referrals,
+			COUNT(DISTINCT referrer_id) AS referrers,
+			ARRAY_AGG(DISTINCT referrer_id) AS referrer_ids,
+			ARRAY_AGG(referee_id ORDER BY created_at) AS referee_ids,
+			MIN(created_at) AS first_at,
+			MAX(created_at) AS last_at
+		FROM referrals
+		WHERE %[1]s <> ''
+		GROUP BY %[1]s
+		HAVING COUNT(*) >= $1
+		ORDER BY COUNT(*) DESC, MAX(created_at) DESC
+		LIMIT 100`, column)
+	if err := r.db.SelectContext(ctx, &clusters, query, min); err != nil {
+		return nil, err
+	}
+	return clusters, nil
+}
+
+// QualifyReferral 被邀请人完成发放奖励的操作，更新待完成的邀请关系并在同一事务中写入奖励
+// 邀请关系不是 pending 时返回 sql.ErrNoRows
+func (r *repository) QualifyReferral(ctx context.Context, id int64, status string, rewards []*entities.Reward) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE referrals SET status = $2, qualified_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND status = 'pending'`
+	if err := execOne(ctx, tx, query, id, status); err != nil {
+		return err
+	}
+	if err := insertRewards(ctx, tx, rewards); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ReviewReferral 管理员审核邀请关系，当前状态不在 from 中时返回 sql.ErrNoRows；奖励和审计记录在同一事务中写入
+func (r *repository) ReviewReferral(ctx context.Context, id, adminID int64, from []string, status, reason string, rewards []*entities.Reward, entry *auditEntities.Log) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE referrals SET status = $2, reviewed_by = $3, review_reason = $4, updated_at = NOW()
+		WHERE id = $1 AND status = ANY($5)`
+	if err := execOne(ctx, tx, query, id, status, adminID, reason, pq.Array(from)); err != nil {
+		return err
+	}
+	if err := insertRewards(ctx, tx, rewards); err != nil {
+		return err
+	}
+	if err := audit.Append(ctx, tx, entry); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// execOne 执行更新，没有更新任何行时返回 sql.ErrNoRows
+func execOne(ctx context.Context, tx *sqlx.Tx, query string, args ...interface{}) error {
+	result, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func insertRewards(ctx context.Context, tx *sqlx.Tx, rewards []*entities.Reward) error {
+	query := `
+		INSERT INTO referral_rewards (referral_id, user_id, role, amount, unit)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at`
+	for _, reward := range rewards {
+		if err := tx.QueryRowxContext(ctx, query, reward.ReferralID, reward.UserID, reward.Role, reward.Amount, reward.Unit).
+			Scan(&reward.ID, &reward.CreatedAt); err != nil {
+			return fmt.Errorf("failed to insert referral reward: %w", err)
+		}
+	}
+	return nil
+}
+
+func checkColumn(column string) error {
+	if column != ColumnSignupIP && column != ColumnDeviceHash {
+		return fmt.Errorf("invalid referral column %q", column)
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+package referral
+
+import (
+	"trusioo_api/internal/auth/rbac"
+	"trusioo_api/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterRoutes(router *gin.RouterGroup, handler *Handler) {
+	referrals := router.Group("/referrals")
+	referrals.Use(middleware.AuthMiddleware())
+	{
+		referrals.GET("", handler.GetStats) // 邀请码和邀请统计
+	}
+
+	// 邀请关系与可疑聚集 - 默认只有超级管理员拥有 referrals.manage
+	admin := router.Group("/admin/referrals")
+	admin.Use(middleware.AdminAuthMiddleware(), middleware.RequirePermission(rbac.PermReferralsManage))
+	{
+		admin.GET("", handler.ListReferrals)
+		admin.GET("/clusters", handler.ListClusters) // 同一注册IP或设备的被邀请人聚集
+		admin.POST("/:id/release", handler.ReleaseReferral)
+		admin.POST("/:id/reject", handler.RejectReferral)
+	}
+}
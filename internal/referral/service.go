@@ -0,0 +1,504 @@
+package referral
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"trusioo_api/config"
+	"trusioo_api/internal/audit"
+	auditEntities "trusioo_api/internal/audit/entities"
+	"trusioo_api/internal/common"
+	"trusioo_api/internal/referral/dto"
+	"trusioo_api/internal/referral/entities"
+	"trusioo_api/pkg/logger"
+
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+)
+
+// codeAlphabet 邀请码字符，去掉了容易混淆的 0、O、1、I、L
+const codeAlphabet = "23456789ABCDEFGHJKMNPQRSTUVWXYZ"
+
+const (
+	codeLength = 8
+	// codeAttempts 生成的邀请码与已有邀请码冲突时的重试次数
+	codeAttempts = 5
+	// referrerIPWindow 与邀请人近期登录IP比较的时间范围
+	referrerIPWindow = 30 * 24 * time.Hour
+	// recentLimit 邀请统计中返回的最近邀请数量
+	recentLimit = 20
+)
+
+// Config 邀请奖励配置
+type Config struct {
+	QualifyingAction      string
+	ReferrerReward        int
+	RefereeReward         int
+	RewardUnit            string
+	MaxRewardsPerReferrer int // 0 表示不限制
+	ClusterThreshold      int // 0 表示不检查
+}
+
+// DefaultConfig 默认配置
+func DefaultConfig() *Config {
+	return &Config{
+		QualifyingAction:      entities.ActionKYCApproved,
+		ReferrerReward:        100,
+		RefereeReward:         50,
+		RewardUnit:            "points",
+		MaxRewardsPerReferrer: 50,
+		ClusterThreshold:      3,
+	}
+}
+
+// NewConfigFromApp 从应用配置创建，操作无法识别时使用 kyc_approved
+func NewConfigFromApp(appConfig *config.Config) *Config {
+	cfg := DefaultConfig()
+	if appConfig == nil {
+		return cfg
+	}
+
+	rc := appConfig.Referral
+	if rc.QualifyingAction == entities.ActionEmailVerified {
+		cfg.QualifyingAction = entities.ActionEmailVerified
+	}
+	if rc.ReferrerReward >= 0 {
+		cfg.ReferrerReward = rc.ReferrerReward
+	}
+	if rc.RefereeReward >= 0 {
+		cfg.RefereeReward = rc.RefereeReward
+	}
+	if rc.RewardUnit != "" {
+		cfg.RewardUnit = rc.RewardUnit
+	}
+	if rc.MaxRewardsPerReferrer >= 0 {
+		cfg.MaxRewardsPerReferrer = rc.MaxRewardsPerReferrer
+	}
+	if rc.ClusterThreshold >= 0 {
+		cfg.ClusterThreshold = rc.ClusterThreshold
+	}
+	return cfg
+}
+
+// Signup 使用邀请码注册的新用户
+type Signup struct {
+	RefereeID int64
+	Code      string
+	IP        string
+	DeviceID  string // 客户端提供的设备ID，为空时使用 User-Agent 识别设备
+	UserAgent string
+}
+
+// Service 邀请码、邀请关系与奖励发放
+// 注册时记录邀请关系，与邀请人近期登录IP相同或同一IP、设备的被邀请人达到阈值时标记为可疑，
+// 被邀请人完成发放奖励的操作后，可疑的邀请关系暂缓发放等待管理员审核，其余直接发放
+type Service struct {
+	repo Repository
+	cfg  *Config
+}
+
+// NewService 创建服务，cfg 为空时使用默认配置
+func NewService(repo Repository, cfg *Config) *Service {
+	if cfg == nil {
+		cfg = DefaultConfig()
+	}
+	return &Service{repo: repo, cfg: cfg}
+}
+
+// CodeFor 获取用户的邀请码，没有时生成
+func (s *Service) CodeFor(ctx context.Context, userID int64) (*entities.Code, error) {
+	code, err := s.repo.GetCodeByUser(ctx, userID)
+	if err == nil {
+		return code, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	for attempt := 0; attempt < codeAttempts; attempt++ {
+		value, err := generateCode()
+		if err != nil {
+			return nil, err
+		}
+		code = &entities.Code{UserID: userID, Code: value}
+		err = s.repo.CreateCode(ctx, code)
+		if err == nil {
+			return code, nil
+		}
+		if !isUniqueViolation(err) {
+			return nil, err
+		}
+		// 并发请求可能已为同一用户生成邀请码
+		if existing, getErr := s.repo.GetCodeByUser(ctx, userID); getErr == nil {
+			return existing, nil
+		}
+	}
+	return nil, common.ErrInternalServer
+}
+
+// Validate 校验注册时填写的邀请码，不存在时返回 common.ErrReferralCodeInvalid
+func (s *Service) Validate(ctx context.Context, code string) error {
+	_, err := s.lookup(ctx, code)
+	return err
+}
+
+// Attach 记录新用户的邀请关系，邀请码不存在时返回 common.ErrReferralCodeInvalid
+func (s *Service) Attach(ctx context.Context, signup *Signup) (*entities.Referral, error) {
+	code, err := s.lookup(ctx, signup.Code)
+	if err != nil {
+		return nil, err
+	}
+	if code.UserID == signup.RefereeID {
+		return nil, common.ErrReferralCodeInvalid
+	}
+
+	referral := &entities.Referral{
+		ReferrerID:  code.UserID,
+		RefereeID:   signup.RefereeID,
+		Code:        code.Code,
+		SignupIP:    signup.IP,
+		DeviceHash:  deviceHash(signup.DeviceID, signup.UserAgent),
+		Status:      entities.StatusPending,
+		FlagReasons: pq.StringArray{},
+	}
+	reasons, err := s.flagReasons(ctx, referral)
+	if err != nil {
+		return nil, err
+	}
+	referral.FlagReasons = reasons
+
+	if err := s.repo.CreateReferral(ctx, referral); err != nil {
+		return nil, err
+	}
+	if referral.Flagged() {
+		logger.WithFields(logrus.Fields{
+			"referral_id": referral.ID,
+			"referrer_id": referral.ReferrerID,
+			"referee_id":  referral.RefereeID,
+			"reasons":     strings.Join(referral.FlagReasons, ","),
+		}).Warn("Referral flagged as suspicious")
+	}
+	return referral, nil
+}
+
+// Qualify 用户完成某个操作，操作为发放奖励的操作且用户有待完成的邀请关系时发放奖励或暂缓等待审核
+func (s *Service) Qualify(ctx context.Context, userID int64, action string) error {
+	if action != s.cfg.QualifyingAction {
+		return nil
+	}
+
+	referral, err := s.repo.GetReferralByReferee(ctx, userID)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if referral.Status != entities.StatusPending {
+		return nil
+	}
+
+	status, rewards := entities.StatusHeld, []*entities.Reward(nil)
+	if !referral.Flagged() {
+		status, rewards, err = s.rewards(ctx, referral)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := s.repo.QualifyReferral(ctx, referral.ID, status, rewards); err != nil {
+		// 并发的操作已处理该邀请关系
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		return err
+	}
+	logger.WithFields(logrus.Fields{
+		"referral_id": referral.ID,
+		"referrer_id": referral.ReferrerID,
+		"referee_id":  referral.RefereeID,
+		"status":      status,
+	}).Info("Referral qualified")
+	return nil
+}
+
+// Stats 当前用户的邀请码和邀请统计
+func (s *Service) Stats(ctx context.Context, userID int64) (*dto.StatsResponse, error) {
+	code, err := s.CodeFor(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	counts, err := s.repo.CountReferralsByStatus(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	recent, err := s.repo.ListReferralsByReferrer(ctx, userID, recentLimit)
+	if err != nil {
+		return nil, err
+	}
+	rewards, err := s.repo.ListRewards(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &dto.StatsResponse{
+		Code:       code.Code,
+		Pending:    counts[entities.StatusPending],
+		Rewarded:   counts[entities.StatusRewarded],
+		RewardUnit: s.cfg.RewardUnit,
+		Recent:     make([]*dto.ReferralSummary, 0, len(recent)),
+		Rules: dto.RewardRules{
+			QualifyingAction: s.cfg.QualifyingAction,
+			ReferrerReward:   s.cfg.ReferrerReward,
+			RefereeReward:    s.cfg.RefereeReward,
+			Unit:             s.cfg.RewardUnit,
+		},
+		Rewards: rewards,
+	}
+	for _, count := range counts {
+		resp.Invited += count
+	}
+	for _, referral := range recent {
+		resp.Recent = append(resp.Recent, summarize(referral))
+	}
+	for _, reward := range rewards {
+		if reward.Role == entities.RoleReferrer {
+			resp.Earned += reward.Amount
+		}
+	}
+
+	referredBy, err := s.repo.GetReferralByReferee(ctx, userID)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	if referredBy != nil {
+		resp.ReferredBy = summarize(referredBy)
+	}
+	return resp, nil
+}
+
+// List 邀请关系列表
+func (s *Service) List(ctx context.Context, req *dto.ListRequest) (*dto.ListResponse, error) {
+	page := req.Page
+	if page < 1 {
+		page = 1
+	}
+	size := req.PageSize
+	if size < 1 {
+		size = 20
+	}
+
+	referrals, total, err := s.repo.ListReferrals(ctx, req.Status, req.ReferrerID, size, (page-1)*size)
+	if err != nil {
+		return nil, err
+	}
+	return &dto.ListResponse{
+		Total:     total,
+		Page:      page,
+		Size:      size,
+		Referrals: referrals,
+	}, nil
+}
+
+// Clusters 注册IP或设备相同的被邀请人聚集
+func (s *Service) Clusters(ctx context.Context, req *dto.ClustersRequest) ([]*entities.Cluster, error) {
+	column := ColumnSignupIP
+	if req.By == "device" {
+		column = ColumnDeviceHash
+	}
+	min := req.Min
+	if min < 2 {
+		min = s.cfg.ClusterThreshold
+	}
+	if min < 2 {
+		min = 2
+	}
+	return s.repo.ListClusters(ctx, column, min)
+}
+
+// Release 审核通过暂缓发放的邀请关系并发放奖励
+func (s *Service) Release(ctx context.Context, adminID, id int64, req *dto.ReviewRequest) (*entities.Referral, error) {
+	referral, err := s.getReferral(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if referral.Status != entities.StatusHeld {
+		return nil, common.ErrReferralAlreadyReviewed
+	}
+
+	status, rewards, err := s.rewards(ctx, referral)
+	if err != nil {
+		return nil, err
+	}
+	entry := audit.WithReason(audit.NewLog(ctx, adminID, "referral.release", auditEntities.TargetReferral, strconv.FormatInt(id, 10)), req.Reason)
+	entry.Changes.Set("status", referral.Status, status)
+	return s.review(ctx, adminID, referral, []string{entities.StatusHeld}, status, req.Reason, rewards, entry)
+}
+
+// Reject 拒绝为待完成或暂缓发放的邀请关系发放奖励
+func (s *Service) Reject(ctx context.Context, adminID, id int64, req *dto.ReviewRequest) (*entities.Referral, error) {
+	referral, err := s.getReferral(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if referral.Status != entities.StatusPending && referral.Status != entities.StatusHeld {
+		return nil, common.ErrReferralAlreadyReviewed
+	}
+
+	entry := audit.WithReason(audit.NewLog(ctx, adminID, "referral.reject", auditEntities.TargetReferral, strconv.FormatInt(id, 10)), req.Reason)
+	entry.Changes.Set("status", referral.Status, entities.StatusRejected)
+	from := []string{entities.StatusPending, entities.StatusHeld}
+	return s.review(ctx, adminID, referral, from, entities.StatusRejected, req.Reason, nil, entry)
+}
+
+func (s *Service) review(ctx context.Context, adminID int64, referral *entities.Referral, from []string, status, reason string, rewards []*entities.Reward, entry *auditEntities.Log) (*entities.Referral, error) {
+	if err := s.repo.ReviewReferral(ctx, referral.ID, adminID, from, status, reason, rewards, entry); err != nil {
+		// 状态已被并发的审核或操作修改
+		if err == sql.ErrNoRows {
+			return nil, common.ErrReferralAlreadyReviewed
+		}
+		return nil, err
+	}
+	return s.repo.GetReferral(ctx, referral.ID)
+}
+
+func (s *Service) getReferral(ctx context.Context, id int64) (*entities.Referral, error) {
+	referral, err := s.repo.GetReferral(ctx, id)
+	if err == sql.ErrNoRows {
+		return nil, common.ErrNotFound
+	}
+	return referral, err
+}
+
+// rewards 计算应发放的奖励，奖励都为 0 或邀请人已达到奖励次数上限时不发放，状态为 qualified
+func (s *Service) rewards(ctx context.Context, referral *entities.Referral) (string, []*entities.Reward, error) {
+	if s.cfg.ReferrerReward <= 0 && s.cfg.RefereeReward <= 0 {
+		return entities.StatusQualified, nil, nil
+	}
+	if s.cfg.MaxRewardsPerReferrer > 0 {
+		counts, err := s.repo.CountReferralsByStatus(ctx, referral.ReferrerID)
+		if err != nil {
+			return "", nil, err
+		}
+		if counts[entities.StatusRewarded] >= s.cfg.MaxRewardsPerReferrer {
+			return entities.StatusQualified, nil, nil
+		}
+	}
+
+	var rewards []*entities.Reward
+	if s.cfg.ReferrerReward > 0 {
+		rewards = append(rewards, &entities.Reward{
+			ReferralID: referral.ID, UserID: referral.ReferrerID, Role: entities.RoleReferrer,
+			Amount: s.cfg.ReferrerReward, Unit: s.cfg.RewardUnit,
+		})
+	}
+	if s.cfg.RefereeReward > 0 {
+		rewards = append(rewards, &entities.Reward{
+			ReferralID: referral.ID, UserID: referral.RefereeID, Role: entities.RoleReferee,
+			Amount: s.cfg.RefereeReward, Unit: s.cfg.RewardUnit,
+		})
+	}
+	return entities.StatusRewarded, rewards, nil
+}
+
+// flagReasons 检查新的邀请关系是否可疑：注册IP与邀请人近期登录IP相同，或同一IP、设备的被邀请人加上本次达到阈值
+func (s *Service) flagReasons(ctx context.Context, referral *entities.Referral) (pq.StringArray, error) {
+	reasons := pq.StringArray{}
+
+	if referral.SignupIP != "" {
+		ips, err := s.repo.RecentLoginIPs(ctx, referral.ReferrerID, time.Now().Add(-referrerIPWindow))
+		if err != nil {
+			return nil, err
+		}
+		for _, ip := range ips {
+			if ip == referral.SignupIP {
+				reasons = append(reasons, entities.FlagSameIPAsReferrer)
+				break
+			}
+		}
+	}
+
+	if s.cfg.ClusterThreshold > 0 {
+		checks := []struct{ column, value, reason string }{
+			{ColumnSignupIP, referral.SignupIP, entities.FlagIPCluster},
+			{ColumnDeviceHash, referral.DeviceHash, entities.FlagDeviceCluster},
+		}
+		for _, check := range checks {
+			if check.value == "" {
+				continue
+			}
+			count, err := s.repo.CountReferralsBy(ctx, check.column, check.value)
+			if err != nil {
+				return nil, err
+			}
+			if count+1 >= s.cfg.ClusterThreshold {
+				reasons = append(reasons, check.reason)
+			}
+		}
+	}
+	return reasons, nil
+}
+
+// lookup 按邀请码查询，不区分大小写
+func (s *Service) lookup(ctx context.Context, value string) (*entities.Code, error) {
+	value = normalizeCode(value)
+	if value == "" {
+		return nil, common.ErrReferralCodeInvalid
+	}
+	code, err := s.repo.GetCode(ctx, value)
+	if err == sql.ErrNoRows {
+		return nil, common.ErrReferralCodeInvalid
+	}
+	return code, err
+}
+
+func summarize(referral *entities.Referral) *dto.ReferralSummary {
+	return &dto.ReferralSummary{
+		ID:          referral.ID,
+		Status:      referral.Status,
+		QualifiedAt: referral.QualifiedAt,
+		CreatedAt:   referral.CreatedAt,
+	}
+}
+
+func normalizeCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// deviceHash 设备ID优先，没有时使用 User-Agent；都为空时返回空字符串，不参与设备聚集
+func deviceHash(deviceID, userAgent string) string {
+	key := strings.TrimSpace(deviceID)
+	if key == "" {
+		key = strings.TrimSpace(userAgent)
+	}
+	if key == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func generateCode() (string, error) {
+	buf := make([]byte, codeLength)
+	max := big.NewInt(int64(len(codeAlphabet)))
+	for i := range buf {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		buf[i] = codeAlphabet[n.Int64()]
+	}
+	return string(buf), nil
+}
+
+func isUniqueViolation(err error) bool {
+	pqErr, ok := err.(*pq.Error)
+	return ok && pqErr.Code == "23505"
+}
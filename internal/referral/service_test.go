@@ -0,0 +1,383 @@
+package referral
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	auditEntities "trusioo_api/internal/audit/entities"
+	"trusioo_api/internal/common"
+	"trusioo_api/internal/referral/dto"
+	"trusioo_api/internal/referral/entities"
+	"trusioo_api/internal/testutil"
+)
+
+func TestMain(m *testing.M) {
+	testutil.DiscardLogs()
+	os.Exit(m.Run())
+}
+
+// fakeRepository 内存版仓库，loginIPs 为各用户近期登录IP
+type fakeRepository struct {
+	codes     []*entities.Code
+	referrals []*entities.Referral
+	rewards   []*entities.Reward
+	loginIPs  map[int64][]string
+	audits    []*auditEntities.Log
+}
+
+func newFakeRepository() *fakeRepository {
+	return &fakeRepository{loginIPs: map[int64][]string{}}
+}
+
+func (f *fakeRepository) GetCodeByUser(ctx context.Context, userID int64) (*entities.Code, error) {
+	for _, code := range f.codes {
+		if code.UserID == userID {
+			return code, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (f *fakeRepository) GetCode(ctx context.Context, value string) (*entities.Code, error) {
+	for _, code := range f.codes {
+		if code.Code == value {
+			return code, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (f *fakeRepository) CreateCode(ctx context.Context, code *entities.Code) error {
+	for _, existing := range f.codes {
+		if existing.UserID == code.UserID || existing.Code == code.Code {
+			return &pq.Error{Code: "23505"}
+		}
+	}
+	code.CreatedAt = time.Now()
+	f.codes = append(f.codes, code)
+	return nil
+}
+
+func (f *fakeRepository) RecentLoginIPs(ctx context.Context, userID int64, since time.Time) ([]string, error) {
+	return f.loginIPs[userID], nil
+}
+
+func (f *fakeRepository) CountReferralsBy(ctx context.Context, column, value string) (int, error) {
+	count := 0
+	for _, referral := range f.referrals {
+		if (column == ColumnSignupIP && referral.SignupIP == value) || (column == ColumnDeviceHash && referral.DeviceHash == value) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (f *fakeRepository) CreateReferral(ctx context.Context, referral *entities.Referral) error {
+	referral.ID = int64(len(f.referrals) + 1)
+	referral.CreatedAt = time.Now()
+	referral.UpdatedAt = referral.CreatedAt
+	copied := *referral
+	f.referrals = append(f.referrals, &copied)
+	return nil
+}
+
+func (f *fakeRepository) GetReferral(ctx context.Context, id int64) (*entities.Referral, error) {
+	for _, referral := range f.referrals {
+		if referral.ID == id {
+			copied := *referral
+			return &copied, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (f *fakeRepository) GetReferralByReferee(ctx context.Context, refereeID int64) (*entities.Referral, error) {
+	for _, referral := range f.referrals {
+		if referral.RefereeID == refereeID {
+			copied := *referral
+			return &copied, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (f *fakeRepository) ListReferralsByReferrer(ctx context.Context, referrerID int64, limit int) ([]*entities.Referral, error) {
+	referrals := []*entities.Referral{}
+	for _, referral := range f.referrals {
+		if referral.ReferrerID == referrerID && len(referrals) < limit {
+			referrals = append(referrals, referral)
+		}
+	}
+	return referrals, nil
+}
+
+func (f *fakeRepository) CountReferralsByStatus(ctx context.Context, referrerID int64) (map[string]int, error) {
+	counts := map[string]int{}
+	for _, referral := range f.referrals {
+		if referral.ReferrerID == referrerID {
+			counts[referral.Status]++
+		}
+	}
+	return counts, nil
+}
+
+func (f *fakeRepository) ListRewards(ctx context.Context, userID int64) ([]*entities.Reward, error) {
+	rewards := []*entities.Reward{}
+	for _, reward := range f.rewards {
+		if reward.UserID == userID {
+			rewards = append(rewards, reward)
+		}
+	}
+	return rewards, nil
+}
+
+func (f *fakeRepository) ListReferrals(ctx context.Context, status string, referrerID int64, limit, offset int) ([]*entities.Referral, int64, error) {
+	return f.referrals, int64(len(f.referrals)), nil
+}
+
+func (f *fakeRepository) ListClusters(ctx context.Context, column string, min int) ([]*entities.Cluster, error) {
+	return []*entities.Cluster{}, nil
+}
+
+func (f *fakeRepository) QualifyReferral(ctx context.Context, id int64, status string, rewards []*entities.Reward) error {
+	return f.update(id, []string{entities.StatusPending}, func(referral *entities.Referral) {
+		now := time.Now()
+		referral.Status = status
+		referral.QualifiedAt = &now
+		f.rewards = append(f.rewards, rewards...)
+	})
+}
+
+func (f *fakeRepository) ReviewReferral(ctx context.Context, id, adminID int64, from []string, status, reason string, rewards []*entities.Reward, entry *auditEntities.Log) error {
+	return f.update(id, from, func(referral *entities.Referral) {
+		referral.Status = status
+		referral.ReviewedBy = &adminID
+		referral.ReviewReason = reason
+		f.rewards = append(f.rewards, rewards...)
+		f.audits = append(f.audits, entry)
+	})
+}
+
+func (f *fakeRepository) update(id int64, from []string, fn func(*entities.Referral)) error {
+	for _, referral := range f.referrals {
+		if referral.ID != id {
+			continue
+		}
+		for _, status := range from {
+			if referral.Status == status {
+				fn(referral)
+				return nil
+			}
+		}
+	}
+	return sql.ErrNoRows
+}
+
+func testConfig() *Config {
+	return &Config{
+		QualifyingAction:      entities.ActionKYCApproved,
+		ReferrerReward:        100,
+		RefereeReward:         50,
+		RewardUnit:            "points",
+		MaxRewardsPerReferrer: 2,
+		ClusterThreshold:      3,
+	}
+}
+
+// newServiceWithCode 创建服务，用户1拥有邀请码 ABCD2345
+func newServiceWithCode() (*Service, *fakeRepository) {
+	repo := newFakeRepository()
+	repo.codes = append(repo.codes, &entities.Code{UserID: 1, Code: "ABCD2345"})
+	return NewService(repo, testConfig()), repo
+}
+
+func TestService_CodeFor(t *testing.T) {
+	service := NewService(newFakeRepository(), nil)
+
+	code, err := service.CodeFor(context.Background(), 7)
+	require.NoError(t, err)
+	assert.Len(t, code.Code, codeLength)
+	for _, r := range code.Code {
+		assert.Contains(t, codeAlphabet, string(r))
+	}
+
+	again, err := service.CodeFor(context.Background(), 7)
+	require.NoError(t, err)
+	assert.Equal(t, code.Code, again.Code, "每个用户只有一个邀请码")
+
+	other, err := service.CodeFor(context.Background(), 8)
+	require.NoError(t, err)
+	assert.NotEqual(t, code.Code, other.Code)
+}
+
+func TestService_Attach(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("邀请码不区分大小写", func(t *testing.T) {
+		service, _ := newServiceWithCode()
+		referral, err := service.Attach(ctx, &Signup{RefereeID: 2, Code: " abcd2345 ", IP: "203.0.113.1", DeviceID: "device-1"})
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), referral.ReferrerID)
+		assert.Equal(t, entities.StatusPending, referral.Status)
+		assert.False(t, referral.Flagged())
+		assert.Len(t, referral.DeviceHash, 64)
+	})
+
+	t.Run("邀请码不存在", func(t *testing.T) {
+		service, _ := newServiceWithCode()
+		_, err := service.Attach(ctx, &Signup{RefereeID: 2, Code: "ZZZZ9999"})
+		assert.Equal(t, common.ErrReferralCodeInvalid, err)
+		assert.Equal(t, common.ErrReferralCodeInvalid, service.Validate(ctx, ""))
+	})
+
+	t.Run("注册IP与邀请人近期登录IP相同", func(t *testing.T) {
+		service, repo := newServiceWithCode()
+		repo.loginIPs[1] = []string{"198.51.100.7"}
+		referral, err := service.Attach(ctx, &Signup{RefereeID: 2, Code: "ABCD2345", IP: "198.51.100.7"})
+		require.NoError(t, err)
+		assert.Equal(t, pq.StringArray{entities.FlagSameIPAsReferrer}, referral.FlagReasons)
+	})
+
+	t.Run("同一设备的被邀请人达到阈值", func(t *testing.T) {
+		service, _ := newServiceWithCode()
+		for refereeID := int64(2); refereeID <= 4; refereeID++ {
+			referral, err := service.Attach(ctx, &Signup{RefereeID: refereeID, Code: "ABCD2345", IP: fmt.Sprintf("203.0.113.%d", refereeID), UserAgent: "same-agent"})
+			require.NoError(t, err)
+			if refereeID < 4 {
+				assert.False(t, referral.Flagged(), "第 %d 个被邀请人未达到阈值", refereeID-1)
+			} else {
+				assert.Equal(t, pq.StringArray{entities.FlagDeviceCluster}, referral.FlagReasons)
+			}
+		}
+	})
+}
+
+func TestService_Qualify(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("完成发放奖励的操作后给双方发放奖励", func(t *testing.T) {
+		service, repo := newServiceWithCode()
+		_, err := service.Attach(ctx, &Signup{RefereeID: 2, Code: "ABCD2345", IP: "203.0.113.1"})
+		require.NoError(t, err)
+
+		require.NoError(t, service.Qualify(ctx, 2, entities.ActionEmailVerified))
+		assert.Equal(t, entities.StatusPending, repo.referrals[0].Status, "其他操作不发放奖励")
+
+		require.NoError(t, service.Qualify(ctx, 2, entities.ActionKYCApproved))
+		assert.Equal(t, entities.StatusRewarded, repo.referrals[0].Status)
+		require.Len(t, repo.rewards, 2)
+		assert.Equal(t, entities.RoleReferrer, repo.rewards[0].Role)
+		assert.Equal(t, 100, repo.rewards[0].Amount)
+		assert.Equal(t, int64(2), repo.rewards[1].UserID)
+
+		require.NoError(t, service.Qualify(ctx, 2, entities.ActionKYCApproved))
+		assert.Len(t, repo.rewards, 2, "同一邀请关系只发放一次")
+	})
+
+	t.Run("没有邀请关系的用户", func(t *testing.T) {
+		service, _ := newServiceWithCode()
+		assert.NoError(t, service.Qualify(ctx, 9, entities.ActionKYCApproved))
+	})
+
+	t.Run("可疑的邀请关系暂缓发放", func(t *testing.T) {
+		service, repo := newServiceWithCode()
+		repo.loginIPs[1] = []string{"198.51.100.7"}
+		_, err := service.Attach(ctx, &Signup{RefereeID: 2, Code: "ABCD2345", IP: "198.51.100.7"})
+		require.NoError(t, err)
+
+		require.NoError(t, service.Qualify(ctx, 2, entities.ActionKYCApproved))
+		assert.Equal(t, entities.StatusHeld, repo.referrals[0].Status)
+		assert.Empty(t, repo.rewards)
+	})
+
+	t.Run("邀请人达到奖励次数上限后不再发放", func(t *testing.T) {
+		service, repo := newServiceWithCode()
+		for refereeID := int64(2); refereeID <= 4; refereeID++ {
+			_, err := service.Attach(ctx, &Signup{RefereeID: refereeID, Code: "ABCD2345"})
+			require.NoError(t, err)
+			require.NoError(t, service.Qualify(ctx, refereeID, entities.ActionKYCApproved))
+		}
+		assert.Equal(t, entities.StatusRewarded, repo.referrals[1].Status)
+		assert.Equal(t, entities.StatusQualified, repo.referrals[2].Status)
+		assert.Len(t, repo.rewards, 4)
+	})
+}
+
+func TestService_Review(t *testing.T) {
+	ctx := context.Background()
+	heldService := func() (*Service, *fakeRepository) {
+		service, repo := newServiceWithCode()
+		repo.loginIPs[1] = []string{"198.51.100.7"}
+		_, err := service.Attach(ctx, &Signup{RefereeID: 2, Code: "ABCD2345", IP: "198.51.100.7"})
+		require.NoError(t, err)
+		require.NoError(t, service.Qualify(ctx, 2, entities.ActionKYCApproved))
+		return service, repo
+	}
+
+	t.Run("发放暂缓的奖励并记录审计", func(t *testing.T) {
+		service, repo := heldService()
+		referral, err := service.Release(ctx, 10, 1, &dto.ReviewRequest{Reason: "同一家庭网络"})
+		require.NoError(t, err)
+		assert.Equal(t, entities.StatusRewarded, referral.Status)
+		assert.Len(t, repo.rewards, 2)
+		require.Len(t, repo.audits, 1)
+		assert.Equal(t, "referral.release", repo.audits[0].Action)
+		assert.Equal(t, "同一家庭网络", repo.audits[0].Reason)
+
+		_, err = service.Reject(ctx, 10, 1, &dto.ReviewRequest{Reason: "重复审核"})
+		assert.Equal(t, common.ErrReferralAlreadyReviewed, err)
+	})
+
+	t.Run("拒绝暂缓的奖励", func(t *testing.T) {
+		service, repo := heldService()
+		referral, err := service.Reject(ctx, 10, 1, &dto.ReviewRequest{Reason: "批量注册"})
+		require.NoError(t, err)
+		assert.Equal(t, entities.StatusRejected, referral.Status)
+		assert.Empty(t, repo.rewards)
+
+		_, err = service.Release(ctx, 10, 1, &dto.ReviewRequest{Reason: "重复审核"})
+		assert.Equal(t, common.ErrReferralAlreadyReviewed, err)
+	})
+
+	t.Run("邀请关系不存在", func(t *testing.T) {
+		service, _ := newServiceWithCode()
+		_, err := service.Release(ctx, 10, 99, &dto.ReviewRequest{Reason: "不存在"})
+		assert.Equal(t, common.ErrNotFound, err)
+	})
+}
+
+func TestService_Stats(t *testing.T) {
+	ctx := context.Background()
+	service, _ := newServiceWithCode()
+	for refereeID := int64(2); refereeID <= 3; refereeID++ {
+		_, err := service.Attach(ctx, &Signup{RefereeID: refereeID, Code: "ABCD2345"})
+		require.NoError(t, err)
+	}
+	require.NoError(t, service.Qualify(ctx, 2, entities.ActionKYCApproved))
+
+	stats, err := service.Stats(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "ABCD2345", stats.Code)
+	assert.Equal(t, 2, stats.Invited)
+	assert.Equal(t, 1, stats.Pending)
+	assert.Equal(t, 1, stats.Rewarded)
+	assert.Equal(t, 100, stats.Earned)
+	assert.Len(t, stats.Recent, 2)
+	assert.Nil(t, stats.ReferredBy)
+
+	refereeStats, err := service.Stats(ctx, 2)
+	require.NoError(t, err)
+	require.NotNil(t, refereeStats.ReferredBy)
+	assert.Equal(t, entities.StatusRewarded, refereeStats.ReferredBy.Status)
+	require.Len(t, refereeStats.Rewards, 1)
+	assert.Equal(t, 50, refereeStats.Rewards[0].Amount)
+	assert.Zero(t, refereeStats.Earned)
+}
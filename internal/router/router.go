@@ -4,13 +4,23 @@ import (
 	"time"
 
 	"trusioo_api/config"
+	"trusioo_api/internal/account"
+	"trusioo_api/internal/apikeys"
+	"trusioo_api/internal/audit"
 	admin_auth "trusioo_api/internal/auth/admin_auth"
+	"trusioo_api/internal/auth/challenge"
+	"trusioo_api/internal/auth/rbac"
 	user_auth "trusioo_api/internal/auth/user_auth"
+	"trusioo_api/internal/feature"
 	"trusioo_api/internal/health"
 	"trusioo_api/internal/images"
+	"trusioo_api/internal/kyc"
 	"trusioo_api/internal/middleware"
+	"trusioo_api/internal/referral"
 	"trusioo_api/pkg/database"
+	"trusioo_api/pkg/ipinfo"
 	"trusioo_api/pkg/r2storage"
+	"trusioo_api/pkg/redis"
 
 	"github.com/gin-gonic/gin"
 )
@@ -84,37 +94,68 @@ func SetupRouter() *gin.Engine {
 		healthGroup.GET("/redis", health.RedisHealthCheck)       // /api/v1/health/redis
 	}
 
-	// 认证相关路由（特殊速率限制）
+	// 认证相关路由（特殊速率限制），用户与管理员认证共用同一个限制
 	authGroup := api.Group("/auth")
+	adminAuthGroup := api.Group("/admin/auth")
 	if config.AppConfig.RateLimit.Enabled {
-		authGroup.Use(middleware.AuthRateLimitMiddleware())
+		authRateLimit := middleware.AuthRateLimitMiddleware()
+		authGroup.Use(authRateLimit)
+		adminAuthGroup.Use(authRateLimit)
 	}
 
+	// 初始化管理员权限服务，RequirePermission 中间件与用户管理操作共用
+	rbacService := rbac.NewService(rbac.NewRepository(database.DB), redis.AdminCache, rbac.DefaultConfig())
+	middleware.SetPermissionChecker(rbacService)
+	rbacHandler := rbac.NewHandler(rbacService)
+
 	// 初始化服务
-	userRepo := user_auth.NewRepository()
-	authService := user_auth.NewService(userRepo)
-	adminService := admin_auth.NewService()
+	adminService := admin_auth.NewService(rbacService)
 
 	// 初始化R2存储客户端
-	r2Client := r2storage.NewClient(
-		config.AppConfig.R2Storage.AccessKeyID,
-		config.AppConfig.R2Storage.SecretAccessKey,
-		config.AppConfig.R2Storage.Endpoint,
-		config.AppConfig.R2Storage.Region,
-		config.AppConfig.R2Storage.PublicBucket,
-		config.AppConfig.R2Storage.PrivateBucket,
-		config.AppConfig.R2Storage.PublicCDNURL,
-		config.AppConfig.R2Storage.PrivateCDNURL,
-		config.AppConfig.R2Storage.MaxFileSize,
-		config.AppConfig.R2Storage.AllowedMimeTypes,
-	)
+	r2Client := r2storage.NewClientFromApp(config.AppConfig)
 
 	// 初始化图片服务
 	imageRepo := images.NewRepository(database.DB)
 	imageService := images.NewService(imageRepo, r2Client)
 	imageHandler := images.NewHandler(imageService)
 
+	// 初始化邀请服务，注册时记录邀请关系，邮箱验证或身份认证通过后发放奖励
+	referralService := referral.NewService(referral.NewRepository(database.DB), referral.NewConfigFromApp(config.AppConfig))
+	referralHandler := referral.NewHandler(referralService)
+
+	userRepo := user_auth.NewRepository()
+	authService := user_auth.NewService(userRepo, imageService, referralService)
+
+	// 初始化账户数据服务（导出与注销）
+	accountService := account.NewService(account.NewRepository(database.DB), r2Client, account.NewConfigFromApp(config.AppConfig))
+	accountHandler := account.NewHandler(accountService)
+
+	// 初始化API密钥服务，APIKeyAuthMiddleware 使用它校验密钥
+	apiKeyService := apikeys.NewService(apikeys.NewRepository(database.DB), apikeys.NewConfigFromApp(config.AppConfig))
+	middleware.SetAPIKeyValidator(apiKeyService)
+	apiKeyHandler := apikeys.NewHandler(apiKeyService)
+
+	// 初始化身份认证服务，RequireKYCLevel 中间件使用它查询认证等级
+	kycService := kyc.NewService(kyc.NewRepository(database.DB), images.NewPrivateStorage(r2Client), referralService, kyc.NewConfigFromApp(config.AppConfig))
+	middleware.SetKYCLevelProvider(kycService)
+	kycHandler := kyc.NewHandler(kycService)
+
+	// 初始化功能开关服务，Gate 中间件使用它评估开关，EvalCtx 按IP查询国家
+	middleware.SetGeoLocator(ipinfo.NewClient(ipinfo.LoadConfigFromEnv()))
+	featureService := feature.NewService(feature.NewRepository(database.DB), redis.FeatureCache, feature.NewConfigFromApp(config.AppConfig))
+	middleware.SetFlagEvaluator(featureService)
+	featureHandler := feature.NewHandler(featureService)
+
+	// 初始化注册与登录挑战服务，RequireChallenge 中间件使用它评估难度和校验挑战，未启用时不要求挑战
+	var challengeHandler *challenge.Handler
+	if challengeConfig := challenge.NewConfigFromApp(config.AppConfig); challengeConfig != nil {
+		challengeService := challenge.NewService(redis.ChallengeCache, challenge.NewCaptchaVerifierFromApp(config.AppConfig), challengeConfig)
+		middleware.SetChallengeVerifier(challengeService)
+		challengeHandler = challenge.NewHandler(challengeService)
+	}
 
+	// 初始化审计服务，审计记录由各模块在修改数据的事务中写入
+	auditHandler := audit.NewHandler(audit.NewService(audit.NewRepository(database.DB), audit.NewConfigFromApp(config.AppConfig)))
 
 	// 初始化处理器
 	authHandler := user_auth.NewHandler(authService)
@@ -122,8 +163,18 @@ func SetupRouter() *gin.Engine {
 
 	// 注册路由
 	user_auth.RegisterRoutes(authGroup, authHandler)
-	admin_auth.RegisterRoutes(api, adminHandler)
+	if challengeHandler != nil {
+		challenge.RegisterRoutes(authGroup, challengeHandler)
+	}
+	admin_auth.RegisterRoutes(api, adminAuthGroup, adminHandler)
+	rbac.RegisterRoutes(api, rbacHandler)
 	images.RegisterRoutes(api, imageHandler)
+	account.RegisterRoutes(api, accountHandler)
+	apikeys.RegisterRoutes(api, apiKeyHandler)
+	kyc.RegisterRoutes(api, kycHandler)
+	feature.RegisterRoutes(api, featureHandler)
+	referral.RegisterRoutes(api, referralHandler)
+	audit.RegisterRoutes(api, auditHandler)
 
 	return r
 }
\ No newline at end of file
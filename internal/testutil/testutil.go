@@ -2,15 +2,18 @@ package testutil
 
 import (
 	"fmt"
+	"io"
 	"testing"
 	"time"
 
 	"trusioo_api/config"
 	"trusioo_api/pkg/auth"
+	"trusioo_api/pkg/logger"
 
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
 	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/require"
 	"golang.org/x/crypto/bcrypt"
 )
@@ -159,4 +162,10 @@ func MockJWTConfig() {
 func GenerateTestToken(userID int64, email, role, userType string) (string, error) {
 	MockJWTConfig()
 	return auth.GenerateAccessToken(userID, email, role, userType)
-}
\ No newline at end of file
+}
+// DiscardLogs 将结构化日志写入 io.Discard，避免测试在包目录下生成 logs/app.log
+func DiscardLogs() {
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	logger.Log = log
+}
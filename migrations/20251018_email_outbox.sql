@@ -0,0 +1,19 @@
+-- 邮件发件箱：验证码等邮件先落库，再由后台投递器异步发送并按指数退避重试
+CREATE TABLE IF NOT EXISTS email_outbox (
+    id              BIGSERIAL PRIMARY KEY,
+    recipient       VARCHAR(255) NOT NULL,
+    subject         TEXT NOT NULL,
+    html_body       TEXT NOT NULL DEFAULT '',
+    text_body       TEXT NOT NULL DEFAULT '',
+    status          VARCHAR(20) NOT NULL DEFAULT 'pending', -- pending | sent | failed
+    attempts        INTEGER NOT NULL DEFAULT 0,
+    last_error      TEXT NOT NULL DEFAULT '',
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    sent_at         TIMESTAMP WITH TIME ZONE,
+    created_at      TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at      TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_email_outbox_pending
+    ON email_outbox (next_attempt_at)
+    WHERE status = 'pending';
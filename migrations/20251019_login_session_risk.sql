@@ -0,0 +1,9 @@
+-- 登录风险评估结果
+ALTER TABLE user_login_sessions
+    ADD COLUMN IF NOT EXISTS risk_score INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS risk_decision VARCHAR(20) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS risk_reasons TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_user_login_sessions_user_success
+    ON user_login_sessions (user_id, created_at DESC)
+    WHERE status = 'success';
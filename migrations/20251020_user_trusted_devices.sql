@@ -0,0 +1,18 @@
+-- 受信任设备：设备令牌仅保存SHA-256哈希，并绑定客户端设备ID与UA指纹
+CREATE TABLE IF NOT EXISTS user_trusted_devices (
+    id             BIGSERIAL PRIMARY KEY,
+    user_id        BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    device_id      VARCHAR(128) NOT NULL,
+    name           VARCHAR(100) NOT NULL DEFAULT '',
+    token_hash     CHAR(64) NOT NULL UNIQUE,
+    ua_fingerprint CHAR(64) NOT NULL,
+    ip             VARCHAR(45) NOT NULL DEFAULT '',
+    last_used_at   TIMESTAMP WITH TIME ZONE,
+    expires_at     TIMESTAMP WITH TIME ZONE NOT NULL,
+    revoked_at     TIMESTAMP WITH TIME ZONE,
+    created_at     TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_trusted_devices_user_active
+    ON user_trusted_devices (user_id)
+    WHERE revoked_at IS NULL;
@@ -0,0 +1,43 @@
+-- 个人数据导出：ZIP保存在私有存储桶，过期后由清理任务删除对象
+CREATE TABLE IF NOT EXISTS user_data_exports (
+    id          BIGSERIAL PRIMARY KEY,
+    user_id     BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    bucket      VARCHAR(100) NOT NULL,
+    object_key  VARCHAR(500) NOT NULL,
+    size        BIGINT NOT NULL DEFAULT 0,
+    expires_at  TIMESTAMP WITH TIME ZONE NOT NULL,
+    deleted_at  TIMESTAMP WITH TIME ZONE,
+    created_at  TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_data_exports_user
+    ON user_data_exports (user_id, created_at DESC);
+
+CREATE INDEX IF NOT EXISTS idx_user_data_exports_expired
+    ON user_data_exports (expires_at)
+    WHERE deleted_at IS NULL;
+
+-- 账户注销请求：冷静期结束后由清理任务匿名化账户，请求记录本身永久保留
+CREATE TABLE IF NOT EXISTS user_deletion_requests (
+    id              BIGSERIAL PRIMARY KEY,
+    user_id         BIGINT NOT NULL REFERENCES users(id),
+    status          VARCHAR(20) NOT NULL DEFAULT 'pending',
+    reason          TEXT NOT NULL DEFAULT '',
+    purge_after     TIMESTAMP WITH TIME ZONE NOT NULL,
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    attempts        INTEGER NOT NULL DEFAULT 0,
+    last_error      TEXT NOT NULL DEFAULT '',
+    cancelled_at    TIMESTAMP WITH TIME ZONE,
+    completed_at    TIMESTAMP WITH TIME ZONE,
+    created_at      TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at      TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- 每个用户同时只能有一个进行中的注销请求
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_deletion_requests_active
+    ON user_deletion_requests (user_id)
+    WHERE status IN ('pending', 'processing');
+
+CREATE INDEX IF NOT EXISTS idx_user_deletion_requests_due
+    ON user_deletion_requests (purge_after)
+    WHERE status IN ('pending', 'processing');
@@ -0,0 +1,17 @@
+-- 管理员用户管理：临时暂停到期时间，以及每个用户的状态变更与管理操作记录
+ALTER TABLE users ADD COLUMN IF NOT EXISTS suspended_until TIMESTAMP WITH TIME ZONE;
+
+CREATE TABLE IF NOT EXISTS user_status_history (
+    id          BIGSERIAL PRIMARY KEY,
+    user_id     BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    admin_id    BIGINT REFERENCES admins(id) ON DELETE SET NULL,
+    action      VARCHAR(32) NOT NULL,
+    from_status VARCHAR(20) NOT NULL DEFAULT '',
+    to_status   VARCHAR(20) NOT NULL DEFAULT '',
+    reason      VARCHAR(500) NOT NULL,
+    expires_at  TIMESTAMP WITH TIME ZONE,
+    created_at  TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_status_history_user
+    ON user_status_history (user_id, created_at DESC);
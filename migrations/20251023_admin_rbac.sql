@@ -0,0 +1,48 @@
+-- 管理员角色与权限：admins.role 保存角色名，is_super 的管理员拥有全部权限
+CREATE TABLE IF NOT EXISTS admin_permissions (
+    code        VARCHAR(64) PRIMARY KEY,
+    description VARCHAR(255) NOT NULL DEFAULT '',
+    created_at  TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS admin_roles (
+    id          BIGSERIAL PRIMARY KEY,
+    name        VARCHAR(50) NOT NULL UNIQUE,
+    description VARCHAR(255) NOT NULL DEFAULT '',
+    is_system   BOOLEAN NOT NULL DEFAULT false,
+    created_at  TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at  TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS admin_role_permissions (
+    role_id         BIGINT NOT NULL REFERENCES admin_roles(id) ON DELETE CASCADE,
+    permission_code VARCHAR(64) NOT NULL REFERENCES admin_permissions(code) ON DELETE CASCADE,
+    PRIMARY KEY (role_id, permission_code)
+);
+
+INSERT INTO admin_permissions (code, description) VALUES
+    ('users.read',      '查看用户统计、列表、详情和状态变更记录'),
+    ('users.suspend',   '暂停、恢复用户并强制退出登录'),
+    ('users.update',    '发送重置密码验证码、手动标记邮箱或手机号已验证'),
+    ('images.read',     '查看所有用户的图片'),
+    ('images.delete',   '删除任意图片'),
+    ('cards.review',    '查看和审核卡密检测结果'),
+    ('monitoring.read', '查看监控指标与告警'),
+    ('roles.manage',    '管理角色权限并为管理员分配角色')
+ON CONFLICT (code) DO NOTHING;
+
+INSERT INTO admin_roles (name, description, is_system) VALUES
+    ('super_admin', '超级管理员，拥有全部权限', true),
+    ('admin',       '管理员，拥有除角色管理外的全部权限', true)
+ON CONFLICT (name) DO NOTHING;
+
+-- 保持迁移前的行为：已有管理员使用的其他角色同样拥有除角色管理外的全部权限
+INSERT INTO admin_roles (name, description)
+SELECT DISTINCT role, '迁移前已存在的角色' FROM admins WHERE role <> ''
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO admin_role_permissions (role_id, permission_code)
+SELECT r.id, p.code
+FROM admin_roles r CROSS JOIN admin_permissions p
+WHERE r.name = 'super_admin' OR p.code <> 'roles.manage'
+ON CONFLICT DO NOTHING;
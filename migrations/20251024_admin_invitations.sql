@@ -0,0 +1,26 @@
+-- 管理员邀请：邀请令牌仅保存SHA-256哈希，接受或撤销后失效
+CREATE TABLE IF NOT EXISTS admin_invitations (
+    id          BIGSERIAL PRIMARY KEY,
+    email       VARCHAR(255) NOT NULL,
+    name        VARCHAR(100) NOT NULL DEFAULT '',
+    role        VARCHAR(50) NOT NULL,
+    token_hash  CHAR(64) NOT NULL UNIQUE,
+    invited_by  BIGINT REFERENCES admins(id) ON DELETE SET NULL,
+    expires_at  TIMESTAMP WITH TIME ZONE NOT NULL,
+    accepted_at TIMESTAMP WITH TIME ZONE,
+    revoked_at  TIMESTAMP WITH TIME ZONE,
+    created_at  TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_admin_invitations_pending
+    ON admin_invitations (email)
+    WHERE accepted_at IS NULL AND revoked_at IS NULL;
+
+-- 管理员账户管理权限，默认仅超级管理员拥有
+INSERT INTO admin_permissions (code, description) VALUES
+    ('admins.manage', '邀请管理员、停用和恢复管理员账户')
+ON CONFLICT (code) DO NOTHING;
+
+INSERT INTO admin_role_permissions (role_id, permission_code)
+SELECT id, 'admins.manage' FROM admin_roles WHERE name = 'super_admin'
+ON CONFLICT DO NOTHING;
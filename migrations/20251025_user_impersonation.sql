@@ -0,0 +1,12 @@
+-- 管理员代登录：记录写入用户登录记录，用户可以在自己的登录记录中看到
+ALTER TABLE user_login_sessions
+    ADD COLUMN IF NOT EXISTS impersonator_id BIGINT REFERENCES admins(id) ON DELETE SET NULL;
+
+-- 代登录权限，默认仅超级管理员拥有
+INSERT INTO admin_permissions (code, description) VALUES
+    ('users.impersonate', '以用户身份签发短期访问令牌，用于排查问题')
+ON CONFLICT (code) DO NOTHING;
+
+INSERT INTO admin_role_permissions (role_id, permission_code)
+SELECT id, 'users.impersonate' FROM admin_roles WHERE name = 'super_admin'
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,19 @@
+-- 合作方与机器客户端的API密钥：密钥只在创建时返回一次，数据库仅保存前缀和密钥哈希
+CREATE TABLE IF NOT EXISTS api_keys (
+    id                    BIGSERIAL PRIMARY KEY,
+    user_id               BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name                  VARCHAR(100) NOT NULL,
+    prefix                VARCHAR(16) NOT NULL UNIQUE,
+    secret_hash           CHAR(64) NOT NULL,
+    scopes                TEXT[] NOT NULL DEFAULT '{}',
+    allowed_ips           TEXT[] NOT NULL DEFAULT '{}',
+    rate_limit_per_minute INT NOT NULL DEFAULT 60,
+    expires_at            TIMESTAMP WITH TIME ZONE,
+    last_used_at          TIMESTAMP WITH TIME ZONE,
+    last_used_ip          VARCHAR(45) NOT NULL DEFAULT '',
+    created_by_admin_id   BIGINT REFERENCES admins(id) ON DELETE SET NULL,
+    revoked_at            TIMESTAMP WITH TIME ZONE,
+    created_at            TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_user_id ON api_keys(user_id);
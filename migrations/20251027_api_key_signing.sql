@@ -0,0 +1,4 @@
+-- API密钥请求签名：签名密钥只在创建密钥时返回一次，校验签名需要原文因此不能只保存哈希
+ALTER TABLE api_keys
+    ADD COLUMN IF NOT EXISTS signing_secret VARCHAR(64) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS require_signature BOOLEAN NOT NULL DEFAULT false;
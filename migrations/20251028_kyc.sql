@@ -0,0 +1,49 @@
+-- 身份认证（KYC）：用户提交个人信息和证件照片，管理员审核通过后提升用户的 kyc_level
+-- 0 未认证，1 证件认证，2 证件与自拍认证
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS kyc_level SMALLINT NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS kyc_submissions (
+    id               BIGSERIAL PRIMARY KEY,
+    user_id          BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    level            SMALLINT NOT NULL,
+    status           VARCHAR(20) NOT NULL DEFAULT 'pending',
+    full_name        VARCHAR(100) NOT NULL,
+    date_of_birth    DATE NOT NULL,
+    nationality      CHAR(2) NOT NULL,
+    document_type    VARCHAR(20) NOT NULL,
+    document_number  VARCHAR(50) NOT NULL,
+    address          VARCHAR(255) NOT NULL DEFAULT '',
+    rejection_reason VARCHAR(255) NOT NULL DEFAULT '',
+    reviewed_by      BIGINT REFERENCES admins(id) ON DELETE SET NULL,
+    reviewed_at      TIMESTAMP WITH TIME ZONE,
+    created_at       TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at       TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_kyc_submissions_user_id ON kyc_submissions(user_id);
+CREATE INDEX IF NOT EXISTS idx_kyc_submissions_status ON kyc_submissions(status, created_at);
+-- 每个用户同时只能有一个待审核的申请
+CREATE UNIQUE INDEX IF NOT EXISTS uniq_kyc_submissions_pending ON kyc_submissions(user_id) WHERE status = 'pending';
+
+-- 证件照片保存在私有存储桶，只通过限时链接查看
+CREATE TABLE IF NOT EXISTS kyc_documents (
+    id            BIGSERIAL PRIMARY KEY,
+    submission_id BIGINT NOT NULL REFERENCES kyc_submissions(id) ON DELETE CASCADE,
+    type          VARCHAR(20) NOT NULL,
+    bucket        VARCHAR(100) NOT NULL,
+    object_key    VARCHAR(500) NOT NULL,
+    content_type  VARCHAR(100) NOT NULL DEFAULT '',
+    size          BIGINT NOT NULL DEFAULT 0,
+    created_at    TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    UNIQUE (submission_id, type)
+);
+
+-- 审核权限，默认仅超级管理员拥有
+INSERT INTO admin_permissions (code, description) VALUES
+    ('kyc.review', '查看身份认证申请和证件照片，审核通过或拒绝')
+ON CONFLICT (code) DO NOTHING;
+
+INSERT INTO admin_role_permissions (role_id, permission_code)
+SELECT id, 'kyc.review' FROM admin_roles WHERE name = 'super_admin'
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,21 @@
+-- 功能开关：enabled 为总开关，rules 为定向规则（灰度比例、国家、平台、版本范围、白名单用户）
+-- 新开关默认关闭，客户端通过 GET /api/v1/flags 获取评估结果，路由通过 middleware.Gate 门禁
+CREATE TABLE IF NOT EXISTS feature_flags (
+    id           BIGSERIAL PRIMARY KEY,
+    key          VARCHAR(100) NOT NULL UNIQUE,
+    description  VARCHAR(255) NOT NULL DEFAULT '',
+    enabled      BOOLEAN NOT NULL DEFAULT FALSE,
+    rules        JSONB NOT NULL DEFAULT '{}'::jsonb,
+    updated_by   BIGINT REFERENCES admins(id) ON DELETE SET NULL,
+    created_at   TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at   TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- 开关管理权限，默认仅超级管理员拥有
+INSERT INTO admin_permissions (code, description) VALUES
+    ('flags.manage', '创建、修改和删除功能开关')
+ON CONFLICT (code) DO NOTHING;
+
+INSERT INTO admin_role_permissions (role_id, permission_code)
+SELECT id, 'flags.manage' FROM admin_roles WHERE name = 'super_admin'
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,47 @@
+-- 管理员审计记录：只允许追加，每条记录的 hash 覆盖上一条的 hash，修改或删除任一记录都会使后续校验失败
+-- admin_id 不设外键，管理员被删除后审计记录仍然保留
+CREATE TABLE IF NOT EXISTS admin_audit_logs (
+    id           BIGSERIAL PRIMARY KEY,
+    admin_id     BIGINT NOT NULL,
+    action       VARCHAR(64) NOT NULL,
+    target_type  VARCHAR(32) NOT NULL,
+    target_id    VARCHAR(64) NOT NULL DEFAULT '',
+    changes      JSONB NOT NULL DEFAULT '{}'::jsonb,
+    ip           VARCHAR(45) NOT NULL DEFAULT '',
+    request_id   VARCHAR(64) NOT NULL DEFAULT '',
+    reason       TEXT NOT NULL DEFAULT '',
+    prev_hash    CHAR(64) NOT NULL,
+    hash         CHAR(64) NOT NULL UNIQUE,
+    created_at   TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_admin_audit_logs_admin ON admin_audit_logs (admin_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_admin_audit_logs_target ON admin_audit_logs (target_type, target_id);
+CREATE INDEX IF NOT EXISTS idx_admin_audit_logs_action ON admin_audit_logs (action);
+CREATE INDEX IF NOT EXISTS idx_admin_audit_logs_created_at ON admin_audit_logs (created_at);
+
+-- 禁止修改、删除和清空审计记录
+CREATE OR REPLACE FUNCTION admin_audit_logs_append_only() RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'admin_audit_logs is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_admin_audit_logs_no_update ON admin_audit_logs;
+CREATE TRIGGER trg_admin_audit_logs_no_update
+    BEFORE UPDATE OR DELETE ON admin_audit_logs
+    FOR EACH ROW EXECUTE FUNCTION admin_audit_logs_append_only();
+
+DROP TRIGGER IF EXISTS trg_admin_audit_logs_no_truncate ON admin_audit_logs;
+CREATE TRIGGER trg_admin_audit_logs_no_truncate
+    BEFORE TRUNCATE ON admin_audit_logs
+    FOR EACH STATEMENT EXECUTE FUNCTION admin_audit_logs_append_only();
+
+-- 审计记录查询权限，默认仅超级管理员拥有
+INSERT INTO admin_permissions (code, description) VALUES
+    ('audit.read', '查询、导出和校验管理员审计记录')
+ON CONFLICT (code) DO NOTHING;
+
+INSERT INTO admin_role_permissions (role_id, permission_code)
+SELECT id, 'audit.read' FROM admin_roles WHERE name = 'super_admin'
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,10 @@
+-- 密码历史：保存最近使用过的密码 bcrypt 哈希，用于拒绝重复使用
+CREATE TABLE IF NOT EXISTS user_password_history (
+    id            BIGSERIAL PRIMARY KEY,
+    user_id       BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    password_hash VARCHAR(255) NOT NULL,
+    created_at    TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_password_history_user
+    ON user_password_history (user_id, created_at DESC);
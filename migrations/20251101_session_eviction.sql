@@ -0,0 +1,10 @@
+-- 同时登录会话数量限制：记录因超过上限被踢出的刷新令牌，被踢出的设备刷新时返回明确的错误
+ALTER TABLE user_refresh_tokens ADD COLUMN IF NOT EXISTS evicted_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE admin_refresh_tokens ADD COLUMN IF NOT EXISTS evicted_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_user_refresh_tokens_user_active
+    ON user_refresh_tokens (user_id, created_at)
+    WHERE is_valid = true;
+CREATE INDEX IF NOT EXISTS idx_admin_refresh_tokens_admin_active
+    ON admin_refresh_tokens (admin_id, created_at)
+    WHERE is_valid = true;
@@ -0,0 +1,52 @@
+-- 邀请码：每个用户一个唯一邀请码，首次查看邀请统计时生成
+CREATE TABLE IF NOT EXISTS referral_codes (
+    user_id    BIGINT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    code       VARCHAR(16) NOT NULL UNIQUE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- 邀请关系：注册或验证码登录自动注册时填写邀请码，每个被邀请人只有一个邀请人
+-- 被邀请人完成 REFERRAL_QUALIFYING_ACTION 后发放奖励，flag_reasons 不为空时暂缓发放（held）等待管理员审核
+CREATE TABLE IF NOT EXISTS referrals (
+    id            BIGSERIAL PRIMARY KEY,
+    referrer_id   BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    referee_id    BIGINT NOT NULL UNIQUE REFERENCES users(id) ON DELETE CASCADE,
+    code          VARCHAR(16) NOT NULL,
+    signup_ip     VARCHAR(45) NOT NULL DEFAULT '',
+    device_hash   CHAR(64) NOT NULL DEFAULT '',
+    status        VARCHAR(20) NOT NULL DEFAULT 'pending',
+    flag_reasons  TEXT[] NOT NULL DEFAULT '{}',
+    qualified_at  TIMESTAMP WITH TIME ZONE,
+    reviewed_by   BIGINT REFERENCES admins(id) ON DELETE SET NULL,
+    review_reason VARCHAR(255) NOT NULL DEFAULT '',
+    created_at    TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at    TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_referrals_referrer_id ON referrals(referrer_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_referrals_status ON referrals(status, created_at);
+CREATE INDEX IF NOT EXISTS idx_referrals_signup_ip ON referrals(signup_ip);
+CREATE INDEX IF NOT EXISTS idx_referrals_device_hash ON referrals(device_hash);
+
+-- 已发放的奖励，每个邀请关系的邀请人和被邀请人各最多一条
+CREATE TABLE IF NOT EXISTS referral_rewards (
+    id          BIGSERIAL PRIMARY KEY,
+    referral_id BIGINT NOT NULL REFERENCES referrals(id) ON DELETE CASCADE,
+    user_id     BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role        VARCHAR(20) NOT NULL,
+    amount      INT NOT NULL,
+    unit        VARCHAR(20) NOT NULL,
+    created_at  TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    UNIQUE (referral_id, role)
+);
+
+CREATE INDEX IF NOT EXISTS idx_referral_rewards_user_id ON referral_rewards(user_id);
+
+-- 邀请管理权限，默认仅超级管理员拥有
+INSERT INTO admin_permissions (code, description) VALUES
+    ('referrals.manage', '查看邀请关系和可疑聚集，审核暂缓发放的邀请奖励')
+ON CONFLICT (code) DO NOTHING;
+
+INSERT INTO admin_role_permissions (role_id, permission_code)
+SELECT id, 'referrals.manage' FROM admin_roles WHERE name = 'super_admin'
+ON CONFLICT DO NOTHING;
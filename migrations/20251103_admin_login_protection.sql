@@ -0,0 +1,20 @@
+-- 管理员登录风险评估结果，与 user_login_sessions 一致
+ALTER TABLE admin_login_sessions
+    ADD COLUMN IF NOT EXISTS risk_score INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS risk_decision VARCHAR(20) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS risk_reasons TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_admin_login_sessions_admin_success
+    ON admin_login_sessions (admin_id, created_at DESC)
+    WHERE status = 'success';
+
+-- 管理员密码历史：保存最近使用过的密码 bcrypt 哈希，用于拒绝重复使用
+CREATE TABLE IF NOT EXISTS admin_password_history (
+    id            BIGSERIAL PRIMARY KEY,
+    admin_id      BIGINT NOT NULL REFERENCES admins(id) ON DELETE CASCADE,
+    password_hash VARCHAR(255) NOT NULL,
+    created_at    TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_admin_password_history_admin
+    ON admin_password_history (admin_id, created_at DESC);
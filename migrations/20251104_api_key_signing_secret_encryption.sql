@@ -0,0 +1,4 @@
+-- API密钥的签名密钥使用应用密钥（APP_KEY）加密保存，密文比原来的64位十六进制更长
+-- 已有的明文签名密钥在服务启动时由 apikeys.Service.EncryptSigningSecrets 加密
+ALTER TABLE api_keys
+    ALTER COLUMN signing_secret TYPE TEXT;
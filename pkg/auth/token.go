@@ -14,9 +14,36 @@ type Claims struct {
 	Email    string `json:"email"`
 	Role     string `json:"role"`
 	UserType string `json:"user_type"` // "user" or "admin"
+
+	// 管理员代登录令牌：ImpersonatorID 为代登录的管理员ID，普通令牌为0
+	ImpersonatorID int64 `json:"impersonator_id,omitempty"`
+	ReadOnly       bool  `json:"read_only,omitempty"`
+
+	// 提升令牌：AuthTime 为重新验证身份的时间（Unix 秒），ACR 为验证方式，普通令牌为空
+	AuthTime int64  `json:"auth_time,omitempty"`
+	ACR      string `json:"acr,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// 重新验证身份的方式
+const (
+	ACRPassword  = "password"
+	ACREmailCode = "email_code"
+)
+
+// IsImpersonation 是否为管理员代登录令牌
+func (c *Claims) IsImpersonation() bool {
+	return c.ImpersonatorID != 0
+}
+
+// AuthenticatedWithin 最近一次验证身份是否在 maxAge 之内，普通令牌始终返回 false
+func (c *Claims) AuthenticatedWithin(maxAge time.Duration) bool {
+	if c.AuthTime <= 0 {
+		return false
+	}
+	return time.Since(time.Unix(c.AuthTime, 0)) <= maxAge
+}
+
 func GenerateAccessToken(userID int64, email, role, userType string) (string, error) {
 	claims := Claims{
 		UserID:   userID,
@@ -35,6 +62,60 @@ func GenerateAccessToken(userID int64, email, role, userType string) (string, er
 	return token.SignedString([]byte(config.AppConfig.JWT.Secret))
 }
 
+// GenerateImpersonationToken 生成管理员代登录用户的访问令牌，不签发刷新令牌，有效期由调用方指定
+func GenerateImpersonationToken(userID int64, email string, adminID int64, readOnly bool, ttl time.Duration) (string, error) {
+	claims := Claims{
+		UserID:         userID,
+		Email:          email,
+		Role:           "user",
+		UserType:       "user",
+		ImpersonatorID: adminID,
+		ReadOnly:       readOnly,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			NotBefore: jwt.NewNumericDate(time.Now()),
+			Issuer:    "trusioo_api",
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(config.AppConfig.JWT.Secret))
+}
+
+// defaultElevatedTokenTTL 未配置 STEP_UP_TOKEN_TTL_MINUTES 时使用
+const defaultElevatedTokenTTL = 10 * time.Minute
+
+// ElevatedTokenTTL 提升令牌的有效期
+func ElevatedTokenTTL() time.Duration {
+	if config.AppConfig != nil && config.AppConfig.StepUp.TokenTTLMinutes > 0 {
+		return time.Duration(config.AppConfig.StepUp.TokenTTLMinutes) * time.Minute
+	}
+	return defaultElevatedTokenTTL
+}
+
+// GenerateElevatedToken 重新验证身份后生成短期提升令牌，携带验证时间和验证方式，不签发刷新令牌
+func GenerateElevatedToken(userID int64, email, role, userType, acr string, authTime time.Time, ttl time.Duration) (string, error) {
+	now := time.Now()
+	claims := Claims{
+		UserID:   userID,
+		Email:    email,
+		Role:     role,
+		UserType: userType,
+		AuthTime: authTime.Unix(),
+		ACR:      acr,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			Issuer:    "trusioo_api",
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(config.AppConfig.JWT.Secret))
+}
+
 func GenerateRefreshToken(userID int64, email, role, userType string) (string, error) {
 	claims := Claims{
 		UserID:   userID,
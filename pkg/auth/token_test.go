@@ -398,6 +398,62 @@ func TestTokenIntegration(t *testing.T) {
 	assert.Contains(t, err.Error(), "signature is invalid")
 }
 
+func TestGenerateImpersonationToken(t *testing.T) {
+	setupTestConfig()
+
+	token, err := GenerateImpersonationToken(10, "user@example.com", 2, true, 15*time.Minute)
+	require.NoError(t, err)
+
+	claims, err := ValidateAccessToken(token)
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), claims.UserID)
+	assert.Equal(t, "user", claims.UserType)
+	assert.Equal(t, int64(2), claims.ImpersonatorID)
+	assert.True(t, claims.ReadOnly)
+	assert.True(t, claims.IsImpersonation())
+	assert.WithinDuration(t, time.Now().Add(15*time.Minute), claims.ExpiresAt.Time, 5*time.Second)
+
+	// 普通访问令牌不是代登录令牌
+	token, err = GenerateAccessToken(10, "user@example.com", "user", "user")
+	require.NoError(t, err)
+	claims, err = ValidateAccessToken(token)
+	require.NoError(t, err)
+	assert.False(t, claims.IsImpersonation())
+	assert.False(t, claims.ReadOnly)
+}
+
+func TestGenerateElevatedToken(t *testing.T) {
+	setupTestConfig()
+
+	token, err := GenerateElevatedToken(7, "admin@example.com", "admin", "admin", ACRPassword, time.Now(), 10*time.Minute)
+	require.NoError(t, err)
+
+	claims, err := ValidateAccessToken(token)
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), claims.UserID)
+	assert.Equal(t, "admin", claims.UserType)
+	assert.Equal(t, ACRPassword, claims.ACR)
+	assert.WithinDuration(t, time.Now(), time.Unix(claims.AuthTime, 0), 5*time.Second)
+	assert.WithinDuration(t, time.Now().Add(10*time.Minute), claims.ExpiresAt.Time, 5*time.Second)
+	assert.True(t, claims.AuthenticatedWithin(5*time.Minute))
+
+	t.Run("验证时间过久", func(t *testing.T) {
+		old := &Claims{AuthTime: time.Now().Add(-6 * time.Minute).Unix()}
+		assert.False(t, old.AuthenticatedWithin(5*time.Minute))
+		assert.True(t, old.AuthenticatedWithin(10*time.Minute))
+	})
+
+	t.Run("普通令牌没有验证时间", func(t *testing.T) {
+		token, err := GenerateAccessToken(7, "admin@example.com", "admin", "admin")
+		require.NoError(t, err)
+		claims, err := ValidateAccessToken(token)
+		require.NoError(t, err)
+		assert.Zero(t, claims.AuthTime)
+		assert.Empty(t, claims.ACR)
+		assert.False(t, claims.AuthenticatedWithin(time.Hour))
+	})
+}
+
 // TestConfigMissing 测试配置缺失的情况
 func TestConfigMissing(t *testing.T) {
 	// 保存原始配置
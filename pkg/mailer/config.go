@@ -0,0 +1,79 @@
+package mailer
+
+import (
+	"time"
+
+	"trusioo_api/config"
+)
+
+// NewConfigFromApp 从应用配置创建邮件配置
+func NewConfigFromApp(appConfig *config.Config) *Config {
+	if appConfig == nil {
+		return DefaultConfig()
+	}
+
+	mc := appConfig.Mail
+	cfg := &Config{
+		Driver:        mc.Driver,
+		SMTPHost:      mc.SMTPHost,
+		SMTPPort:      mc.SMTPPort,
+		SMTPUsername:  mc.SMTPUsername,
+		SMTPPassword:  mc.SMTPPassword,
+		From:          mc.From,
+		FromName:      mc.FromName,
+		FileDir:       mc.FileDir,
+		DefaultLocale: mc.DefaultLocale,
+		PollInterval:  time.Duration(mc.OutboxPollInterval) * time.Second,
+		BatchSize:     mc.OutboxBatchSize,
+		MaxAttempts:   mc.OutboxMaxAttempts,
+	}
+	cfg.applyDefaults()
+	return cfg
+}
+
+// DefaultConfig 返回开发环境默认配置（写入本地文件）
+func DefaultConfig() *Config {
+	cfg := &Config{
+		Driver:   DriverFile,
+		From:     "noreply@trusioo.com",
+		FromName: "Trusioo",
+		FileDir:  "tmp/mail",
+	}
+	cfg.applyDefaults()
+	return cfg
+}
+
+func (c *Config) applyDefaults() {
+	if c.Driver == "" {
+		c.Driver = DriverFile
+	}
+	if c.SMTPPort == "" {
+		c.SMTPPort = "587"
+	}
+	if c.DefaultLocale == "" {
+		c.DefaultLocale = LocaleEN
+	}
+	if c.PollInterval <= 0 {
+		c.PollInterval = 5 * time.Second
+	}
+	if c.BatchSize <= 0 {
+		c.BatchSize = 20
+	}
+	if c.MaxAttempts <= 0 {
+		c.MaxAttempts = 8
+	}
+}
+
+// NewSender 根据配置创建对应驱动的发送器
+func NewSender(cfg *Config) (Sender, error) {
+	switch cfg.Driver {
+	case DriverSMTP:
+		return NewSMTPSender(cfg)
+	case DriverFile:
+		return NewFileSender(cfg.FileDir, cfg.From, cfg.FromName), nil
+	case DriverMemory:
+		return NewMemorySender(), nil
+	default:
+		return nil, ErrUnknownDriver
+	}
+}
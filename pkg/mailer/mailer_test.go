@@ -0,0 +1,194 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeLocale(t *testing.T) {
+	tests := map[string]string{
+		"zh-CN":  LocaleZH,
+		"zh_TW":  LocaleZH,
+		"EN-us":  LocaleEN,
+		"en":     LocaleEN,
+		"fr-FR":  "",
+		"":       "",
+		"  zh  ": LocaleZH,
+	}
+	for input, expected := range tests {
+		assert.Equal(t, expected, NormalizeLocale(input), input)
+	}
+}
+
+func TestRender(t *testing.T) {
+	data := TemplateData{Email: "user@example.com", Code: "123456", ExpiresInMinutes: 10}
+
+	t.Run("渲染中文登录验证码", func(t *testing.T) {
+		msg, err := Render("user@example.com", TemplateLoginCode, "zh-CN", LocaleEN, data)
+		require.NoError(t, err)
+		assert.Equal(t, "user@example.com", msg.To)
+		assert.Equal(t, "Trusioo 登录验证码", msg.Subject)
+		assert.Contains(t, msg.HTMLBody, "123456")
+		assert.Contains(t, msg.TextBody, "10 分钟")
+	})
+
+	t.Run("不支持的语言回退到默认语言", func(t *testing.T) {
+		msg, err := Render("user@example.com", TemplatePasswordReset, "fr", LocaleZH, data)
+		require.NoError(t, err)
+		assert.Contains(t, msg.Subject, "重置密码")
+	})
+
+	t.Run("默认语言也不支持时回退到英文", func(t *testing.T) {
+		msg, err := Render("user@example.com", TemplatePasswordReset, "", "", data)
+		require.NoError(t, err)
+		assert.Equal(t, "Reset your Trusioo password", msg.Subject)
+		assert.Contains(t, msg.TextBody, "user@example.com")
+	})
+
+	t.Run("HTML模板转义用户数据", func(t *testing.T) {
+		msg, err := Render("user@example.com", TemplatePasswordReset, LocaleEN, "", TemplateData{Email: "<script>@example.com", Code: "1"})
+		require.NoError(t, err)
+		assert.NotContains(t, msg.HTMLBody, "<script>")
+	})
+
+	t.Run("模板不存在", func(t *testing.T) {
+		_, err := Render("user@example.com", "unknown", LocaleEN, "", data)
+		assert.ErrorIs(t, err, ErrTemplateNotFound)
+	})
+}
+
+func TestBuildMIMEMessage(t *testing.T) {
+	body, err := buildMIMEMessage(mail.Address{Name: "Trusioo", Address: "noreply@trusioo.com"}, &Message{
+		To:       "user@example.com",
+		Subject:  "登录验证码",
+		HTMLBody: "<p>123456</p>",
+		TextBody: "123456",
+	})
+	require.NoError(t, err)
+
+	raw := string(body)
+	assert.Contains(t, raw, "To: user@example.com\r\n")
+	assert.Contains(t, raw, "Subject: =?utf-8?q?")
+	assert.Contains(t, raw, "multipart/alternative")
+	assert.Contains(t, raw, "text/plain; charset=utf-8")
+	assert.Contains(t, raw, "text/html; charset=utf-8")
+}
+
+func TestNewSender(t *testing.T) {
+	sender, err := NewSender(&Config{Driver: DriverMemory})
+	require.NoError(t, err)
+	assert.IsType(t, &MemorySender{}, sender)
+
+	_, err = NewSender(&Config{Driver: DriverSMTP, From: "noreply@trusioo.com"})
+	assert.ErrorIs(t, err, ErrMissingSMTPHost)
+
+	_, err = NewSender(&Config{Driver: "carrier-pigeon"})
+	assert.ErrorIs(t, err, ErrUnknownDriver)
+}
+
+// fakeOutboxStore 内存版发件箱
+type fakeOutboxStore struct {
+	entries []*OutboxEntry
+}
+
+func (f *fakeOutboxStore) Enqueue(ctx context.Context, msg *Message) error {
+	f.entries = append(f.entries, &OutboxEntry{
+		ID:            int64(len(f.entries) + 1),
+		Recipient:     msg.To,
+		Subject:       msg.Subject,
+		TextBody:      msg.TextBody,
+		Status:        OutboxStatusPending,
+		NextAttemptAt: time.Now(),
+	})
+	return nil
+}
+
+func (f *fakeOutboxStore) Claim(ctx context.Context, limit int) ([]*OutboxEntry, error) {
+	var claimed []*OutboxEntry
+	for _, e := range f.entries {
+		if e.Status == OutboxStatusPending && !e.NextAttemptAt.After(time.Now()) && len(claimed) < limit {
+			e.Attempts++
+			e.NextAttemptAt = time.Now().Add(claimLease)
+			copied := *e
+			claimed = append(claimed, &copied)
+		}
+	}
+	return claimed, nil
+}
+
+func (f *fakeOutboxStore) MarkSent(ctx context.Context, id int64) error {
+	f.entries[id-1].Status = OutboxStatusSent
+	return nil
+}
+
+func (f *fakeOutboxStore) MarkRetry(ctx context.Context, id int64, lastErr string, nextAttemptAt time.Time) error {
+	f.entries[id-1].LastError = lastErr
+	f.entries[id-1].NextAttemptAt = nextAttemptAt
+	return nil
+}
+
+func (f *fakeOutboxStore) MarkFailed(ctx context.Context, id int64, lastErr string) error {
+	f.entries[id-1].Status = OutboxStatusFailed
+	f.entries[id-1].LastError = lastErr
+	return nil
+}
+
+// flakySender 前 failures 次发送失败，之后委托给 MemorySender
+type flakySender struct {
+	failures int
+	inner    *MemorySender
+}
+
+func (f *flakySender) Send(ctx context.Context, msg *Message) error {
+	if f.failures > 0 {
+		f.failures--
+		return errors.New("smtp: connection refused")
+	}
+	return f.inner.Send(ctx, msg)
+}
+
+func TestDispatcher_RetriesUntilDelivered(t *testing.T) {
+	store := &fakeOutboxStore{}
+	sender := &flakySender{failures: 1, inner: NewMemorySender()}
+	d := NewDispatcher(store, sender, &Config{BatchSize: 10, MaxAttempts: 3, PollInterval: time.Second})
+
+	require.NoError(t, store.Enqueue(context.Background(), &Message{To: "user@example.com", Subject: "code", TextBody: "123456"}))
+
+	// 第一次失败：记录错误并安排退避重试
+	sent, err := d.DispatchOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, sent)
+	assert.Equal(t, OutboxStatusPending, store.entries[0].Status)
+	assert.True(t, strings.Contains(store.entries[0].LastError, "connection refused"))
+	assert.True(t, store.entries[0].NextAttemptAt.After(time.Now()))
+
+	// 未到重试时间不会被领取
+	sent, err = d.DispatchOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, sent)
+
+	// 到期后重试成功
+	store.entries[0].NextAttemptAt = time.Now()
+	sent, err = d.DispatchOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent)
+	assert.Equal(t, OutboxStatusSent, store.entries[0].Status)
+
+	msg, ok := sender.inner.Last("user@example.com")
+	require.True(t, ok)
+	assert.Equal(t, "123456", msg.TextBody)
+}
+
+func TestRetryBackoff(t *testing.T) {
+	assert.Equal(t, 30*time.Second, retryBackoff(1))
+	assert.Equal(t, time.Minute, retryBackoff(2))
+	assert.Equal(t, 2*time.Minute, retryBackoff(3))
+	assert.Equal(t, time.Hour, retryBackoff(20))
+}
@@ -0,0 +1,97 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemorySender 将邮件保存在内存中，用于测试
+type MemorySender struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+// NewMemorySender 创建内存发送器
+func NewMemorySender() *MemorySender {
+	return &MemorySender{}
+}
+
+// Send 记录邮件
+func (m *MemorySender) Send(ctx context.Context, msg *Message) error {
+	if msg.To == "" {
+		return ErrMissingRecipient
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = append(m.messages, *msg)
+	return nil
+}
+
+// Messages 返回已发送邮件的副本
+func (m *MemorySender) Messages() []Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]Message, len(m.messages))
+	copy(out, m.messages)
+	return out
+}
+
+// Last 返回最后一封发送给指定收件人的邮件
+func (m *MemorySender) Last(to string) (*Message, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := len(m.messages) - 1; i >= 0; i-- {
+		if m.messages[i].To == to {
+			msg := m.messages[i]
+			return &msg, true
+		}
+	}
+	return nil, false
+}
+
+// Reset 清空已记录的邮件
+func (m *MemorySender) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = nil
+}
+
+// FileSender 将邮件以 .eml 文件写入本地目录，用于开发环境
+type FileSender struct {
+	dir  string
+	from mail.Address
+}
+
+// NewFileSender 创建文件发送器
+func NewFileSender(dir, from, fromName string) *FileSender {
+	return &FileSender{
+		dir:  dir,
+		from: mail.Address{Name: fromName, Address: from},
+	}
+}
+
+// Send 写入 .eml 文件
+func (f *FileSender) Send(ctx context.Context, msg *Message) error {
+	if msg.To == "" {
+		return ErrMissingRecipient
+	}
+
+	body, err := buildMIMEMessage(f.from, msg)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(f.dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create mail directory: %w", err)
+	}
+
+	recipient := strings.NewReplacer("@", "_at_", "/", "_", "\\", "_").Replace(msg.To)
+	name := fmt.Sprintf("%s_%s.eml", time.Now().Format("20060102T150405.000000000"), recipient)
+	return os.WriteFile(filepath.Join(f.dir, name), body, 0o600)
+}
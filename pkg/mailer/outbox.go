@@ -0,0 +1,246 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"trusioo_api/pkg/logger"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// OutboxEntry 发件箱记录
+type OutboxEntry struct {
+	ID            int64      `db:"id"`
+	Recipient     string     `db:"recipient"`
+	Subject       string     `db:"subject"`
+	HTMLBody      string     `db:"html_body"`
+	TextBody      string     `db:"text_body"`
+	Status        string     `db:"status"`
+	Attempts      int        `db:"attempts"`
+	LastError     string     `db:"last_error"`
+	NextAttemptAt time.Time  `db:"next_attempt_at"`
+	SentAt        *time.Time `db:"sent_at"`
+	CreatedAt     time.Time  `db:"created_at"`
+	UpdatedAt     time.Time  `db:"updated_at"`
+}
+
+// 发件箱状态
+const (
+	OutboxStatusPending = "pending"
+	OutboxStatusSent    = "sent"
+	OutboxStatusFailed  = "failed"
+)
+
+// claimLease 领取后的租约时长，进程在投递中崩溃时记录会在租约到期后被重新领取
+const claimLease = 5 * time.Minute
+
+// OutboxStore 发件箱存储接口
+type OutboxStore interface {
+	Enqueue(ctx context.Context, msg *Message) error
+	Claim(ctx context.Context, limit int) ([]*OutboxEntry, error)
+	MarkSent(ctx context.Context, id int64) error
+	MarkRetry(ctx context.Context, id int64, lastErr string, nextAttemptAt time.Time) error
+	MarkFailed(ctx context.Context, id int64, lastErr string) error
+}
+
+// Outbox 基于Postgres的发件箱
+type Outbox struct {
+	db *sqlx.DB
+}
+
+// NewOutbox 创建发件箱
+func NewOutbox(db *sqlx.DB) *Outbox {
+	return &Outbox{db: db}
+}
+
+// Enqueue 写入待发送邮件，由 Dispatcher 异步投递
+func (o *Outbox) Enqueue(ctx context.Context, msg *Message) error {
+	if o.db == nil {
+		return ErrOutboxUnavailable
+	}
+	if msg.To == "" {
+		return ErrMissingRecipient
+	}
+
+	query := `
+		INSERT INTO email_outbox (recipient, subject, html_body, text_body, status, attempts, next_attempt_at)
+		VALUES ($1, $2, $3, $4, $5, 0, NOW())`
+	if _, err := o.db.ExecContext(ctx, query, msg.To, msg.Subject, msg.HTMLBody, msg.TextBody, OutboxStatusPending); err != nil {
+		return fmt.Errorf("failed to enqueue email: %w", err)
+	}
+	return nil
+}
+
+// Claim 领取到期的待发送邮件，并顺延其下次尝试时间作为租约
+func (o *Outbox) Claim(ctx context.Context, limit int) ([]*OutboxEntry, error) {
+	if o.db == nil {
+		return nil, ErrOutboxUnavailable
+	}
+
+	query := `
+		UPDATE email_outbox
+		SET attempts = attempts + 1, next_attempt_at = $2, updated_at = NOW()
+		WHERE id IN (
+			SELECT id FROM email_outbox
+			WHERE status = $3 AND next_attempt_at <= NOW()
+			ORDER BY next_attempt_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, recipient, subject, html_body, text_body, status, attempts, last_error,
+			next_attempt_at, sent_at, created_at, updated_at`
+
+	var entries []*OutboxEntry
+	if err := o.db.SelectContext(ctx, &entries, query, limit, time.Now().Add(claimLease), OutboxStatusPending); err != nil {
+		return nil, fmt.Errorf("failed to claim outbox entries: %w", err)
+	}
+	return entries, nil
+}
+
+// MarkSent 标记已发送，并清空正文以免验证码等敏感内容长期留存
+func (o *Outbox) MarkSent(ctx context.Context, id int64) error {
+	query := `
+		UPDATE email_outbox
+		SET status = $2, sent_at = NOW(), html_body = '', text_body = '', last_error = '', updated_at = NOW()
+		WHERE id = $1`
+	_, err := o.db.ExecContext(ctx, query, id, OutboxStatusSent)
+	return err
+}
+
+// MarkRetry 记录失败原因并安排下次重试
+func (o *Outbox) MarkRetry(ctx context.Context, id int64, lastErr string, nextAttemptAt time.Time) error {
+	query := `UPDATE email_outbox SET last_error = $2, next_attempt_at = $3, updated_at = NOW() WHERE id = $1`
+	_, err := o.db.ExecContext(ctx, query, id, lastErr, nextAttemptAt)
+	return err
+}
+
+// MarkFailed 超过最大重试次数后标记为失败
+func (o *Outbox) MarkFailed(ctx context.Context, id int64, lastErr string) error {
+	query := `
+		UPDATE email_outbox
+		SET status = $2, last_error = $3, html_body = '', text_body = '', updated_at = NOW()
+		WHERE id = $1`
+	_, err := o.db.ExecContext(ctx, query, id, OutboxStatusFailed, lastErr)
+	return err
+}
+
+// Dispatcher 后台轮询发件箱并投递邮件
+type Dispatcher struct {
+	store       OutboxStore
+	sender      Sender
+	interval    time.Duration
+	batchSize   int
+	maxAttempts int
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewDispatcher 创建发件箱投递器
+func NewDispatcher(store OutboxStore, sender Sender, cfg *Config) *Dispatcher {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Dispatcher{
+		store:       store,
+		sender:      sender,
+		interval:    cfg.PollInterval,
+		batchSize:   cfg.BatchSize,
+		maxAttempts: cfg.MaxAttempts,
+		ctx:         ctx,
+		cancel:      cancel,
+	}
+}
+
+// Start 启动后台投递
+func (d *Dispatcher) Start() {
+	d.wg.Add(1)
+	go d.run()
+}
+
+// Stop 停止后台投递并等待当前批次完成
+func (d *Dispatcher) Stop() {
+	d.cancel()
+	d.wg.Wait()
+}
+
+func (d *Dispatcher) run() {
+	defer d.wg.Done()
+
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := d.DispatchOnce(d.ctx); err != nil {
+			logger.WithError(err).Warn("Email outbox dispatch failed")
+		}
+
+		select {
+		case <-d.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DispatchOnce 投递一批到期邮件，返回成功发送的数量
+func (d *Dispatcher) DispatchOnce(ctx context.Context) (int, error) {
+	entries, err := d.store.Claim(ctx, d.batchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, entry := range entries {
+		msg := &Message{
+			To:       entry.Recipient,
+			Subject:  entry.Subject,
+			HTMLBody: entry.HTMLBody,
+			TextBody: entry.TextBody,
+		}
+
+		sendCtx, cancel := context.WithTimeout(ctx, time.Minute)
+		sendErr := d.sender.Send(sendCtx, msg)
+		cancel()
+
+		if sendErr == nil {
+			if err := d.store.MarkSent(ctx, entry.ID); err != nil {
+				logger.WithError(err).Errorf("Failed to mark email %d as sent", entry.ID)
+			}
+			sent++
+			continue
+		}
+
+		if entry.Attempts >= d.maxAttempts {
+			logger.WithError(sendErr).Errorf("Email %d to %s failed permanently after %d attempts", entry.ID, entry.Recipient, entry.Attempts)
+			if err := d.store.MarkFailed(ctx, entry.ID, sendErr.Error()); err != nil {
+				logger.WithError(err).Errorf("Failed to mark email %d as failed", entry.ID)
+			}
+			continue
+		}
+
+		next := time.Now().Add(retryBackoff(entry.Attempts))
+		if err := d.store.MarkRetry(ctx, entry.ID, sendErr.Error(), next); err != nil {
+			logger.WithError(err).Errorf("Failed to schedule retry for email %d", entry.ID)
+		}
+	}
+
+	return sent, nil
+}
+
+// retryBackoff 指数退避：30s、1m、2m …… 最长1小时
+func retryBackoff(attempts int) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+	backoff := 30 * time.Second
+	for i := 1; i < attempts && backoff < time.Hour; i++ {
+		backoff *= 2
+	}
+	if backoff > time.Hour {
+		backoff = time.Hour
+	}
+	return backoff
+}
@@ -0,0 +1,150 @@
+package mailer
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+)
+
+//go:embed templates
+var templateFS embed.FS
+
+// 模板名称
+const (
+	TemplateLoginCode        = "login_code"
+	TemplatePasswordReset    = "password_reset"
+	TemplateVerificationCode = "verification_code"
+	TemplateSuspiciousLogin  = "suspicious_login"
+	TemplateEmailChanged     = "email_changed"
+	TemplateAdminInvitation  = "admin_invitation"
+	TemplateAccountLocked    = "account_locked"
+	TemplateSessionEvicted   = "session_evicted"
+)
+
+// 支持的语言
+const (
+	LocaleEN = "en"
+	LocaleZH = "zh"
+)
+
+// TemplateData 模板渲染数据
+type TemplateData struct {
+	AppName          string
+	Email            string
+	Code             string
+	ExpiresInMinutes int
+
+	// 登录提醒
+	IP       string
+	Location string
+	Time     string
+
+	// 邮箱变更通知
+	NewEmail string
+
+	// 管理员邀请、账户解锁
+	Link           string
+	Role           string
+	ExpiresInHours int
+
+	// 会话被踢出通知
+	Device      string // 被踢出的设备
+	NewDevice   string // 新登录的设备
+	MaxSessions int
+}
+
+// subjects 各语言的邮件标题
+var subjects = map[string]map[string]string{
+	LocaleEN: {
+		TemplateLoginCode:        "Your %s sign-in code",
+		TemplatePasswordReset:    "Reset your %s password",
+		TemplateVerificationCode: "Your %s verification code",
+		TemplateSuspiciousLogin:  "Suspicious sign-in to your %s account was blocked",
+		TemplateEmailChanged:     "The email address on your %s account was changed",
+		TemplateAdminInvitation:  "You have been invited to the %s admin console",
+		TemplateAccountLocked:    "Your %s account has been temporarily locked",
+		TemplateSessionEvicted:   "A device was signed out of your %s account",
+	},
+	LocaleZH: {
+		TemplateLoginCode:        "%s 登录验证码",
+		TemplatePasswordReset:    "%s 重置密码验证码",
+		TemplateVerificationCode: "%s 验证码",
+		TemplateSuspiciousLogin:  "%s 已拦截一次可疑登录",
+		TemplateEmailChanged:     "%s 账户邮箱已变更",
+		TemplateAdminInvitation:  "邀请您加入 %s 管理后台",
+		TemplateAccountLocked:    "%s 账户已被暂时锁定",
+		TemplateSessionEvicted:   "%s 账户的一台设备已退出登录",
+	},
+}
+
+type localizedTemplate struct {
+	html *htmltemplate.Template
+	text *texttemplate.Template
+}
+
+// templates 以 locale/name 为键缓存已解析的模板
+var templates = mustParseTemplates()
+
+func mustParseTemplates() map[string]*localizedTemplate {
+	parsed := make(map[string]*localizedTemplate)
+	for locale, names := range subjects {
+		for name := range names {
+			htmlPath := fmt.Sprintf("templates/%s/%s.html", locale, name)
+			textPath := fmt.Sprintf("templates/%s/%s.txt", locale, name)
+			parsed[locale+"/"+name] = &localizedTemplate{
+				html: htmltemplate.Must(htmltemplate.ParseFS(templateFS, "templates/layout.html", htmlPath)),
+				text: texttemplate.Must(texttemplate.ParseFS(templateFS, textPath)),
+			}
+		}
+	}
+	return parsed
+}
+
+// NormalizeLocale 将 zh-CN、en_US 等语言标签归一为支持的语言，未知语言返回空字符串
+func NormalizeLocale(locale string) string {
+	locale = strings.ToLower(strings.TrimSpace(locale))
+	if i := strings.IndexAny(locale, "-_"); i >= 0 {
+		locale = locale[:i]
+	}
+	if _, ok := subjects[locale]; ok {
+		return locale
+	}
+	return ""
+}
+
+// Render 使用指定语言渲染模板，语言不受支持时回退到 fallbackLocale，再回退到英文
+func Render(to, name, locale, fallbackLocale string, data TemplateData) (*Message, error) {
+	loc := NormalizeLocale(locale)
+	if loc == "" {
+		loc = NormalizeLocale(fallbackLocale)
+	}
+	if loc == "" {
+		loc = LocaleEN
+	}
+
+	tpl, ok := templates[loc+"/"+name]
+	if !ok {
+		return nil, ErrTemplateNotFound
+	}
+	if data.AppName == "" {
+		data.AppName = "Trusioo"
+	}
+
+	var htmlBuf, textBuf bytes.Buffer
+	if err := tpl.html.ExecuteTemplate(&htmlBuf, name+".html", data); err != nil {
+		return nil, fmt.Errorf("failed to render %s html template: %w", name, err)
+	}
+	if err := tpl.text.Execute(&textBuf, data); err != nil {
+		return nil, fmt.Errorf("failed to render %s text template: %w", name, err)
+	}
+
+	return &Message{
+		To:       to,
+		Subject:  fmt.Sprintf(subjects[loc][name], data.AppName),
+		HTMLBody: htmlBuf.String(),
+		TextBody: textBuf.String(),
+	}, nil
+}
@@ -0,0 +1,53 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Message 待发送的邮件
+type Message struct {
+	To       string `json:"to"`
+	Subject  string `json:"subject"`
+	HTMLBody string `json:"html_body"`
+	TextBody string `json:"text_body"`
+}
+
+// Sender 邮件发送接口，SMTP、文件和内存实现均满足此接口
+type Sender interface {
+	Send(ctx context.Context, msg *Message) error
+}
+
+// Driver 邮件发送驱动
+const (
+	DriverSMTP   = "smtp"
+	DriverFile   = "file"
+	DriverMemory = "memory"
+)
+
+// Config 邮件服务配置
+type Config struct {
+	Driver        string
+	SMTPHost      string
+	SMTPPort      string
+	SMTPUsername  string
+	SMTPPassword  string
+	From          string
+	FromName      string
+	FileDir       string
+	DefaultLocale string
+
+	PollInterval time.Duration
+	BatchSize    int
+	MaxAttempts  int
+}
+
+var (
+	ErrMissingSMTPHost   = errors.New("mailer: SMTP host is required")
+	ErrMissingFrom       = errors.New("mailer: from address is required")
+	ErrMissingRecipient  = errors.New("mailer: recipient is required")
+	ErrUnknownDriver     = errors.New("mailer: unknown driver")
+	ErrTemplateNotFound  = errors.New("mailer: template not found")
+	ErrOutboxUnavailable = errors.New("mailer: outbox database is not initialized")
+)
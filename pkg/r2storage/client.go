@@ -175,6 +175,27 @@ func (c *Client) UploadFile(ctx context.Context, fileHeader *multipart.FileHeade
 	return result, nil
 }
 
+// PutObject 直接写入字节内容，用于服务端生成的文件（如数据导出）
+func (c *Client) PutObject(ctx context.Context, bucket, key string, body []byte, contentType string) error {
+	_, err := c.s3Client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:        aws.String(bucket),
+		Key:           aws.String(key),
+		Body:          bytes.NewReader(body),
+		ContentLength: aws.Int64(int64(len(body))),
+		ContentType:   aws.String(contentType),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to put object to R2: %w", err)
+	}
+
+	return nil
+}
+
+// PrivateBucket 私有存储桶名称
+func (c *Client) PrivateBucket() string {
+	return c.privateBucket
+}
+
 func (c *Client) GeneratePresignedURL(ctx context.Context, bucket, key string, expiration time.Duration) (string, error) {
 	presignClient := s3.NewPresignClient(c.s3Client)
 	
@@ -0,0 +1,20 @@
+package r2storage
+
+import "trusioo_api/config"
+
+// NewClientFromApp 从应用配置创建R2客户端
+func NewClientFromApp(appConfig *config.Config) *Client {
+	rc := appConfig.R2Storage
+	return NewClient(
+		rc.AccessKeyID,
+		rc.SecretAccessKey,
+		rc.Endpoint,
+		rc.Region,
+		rc.PublicBucket,
+		rc.PrivateBucket,
+		rc.PublicCDNURL,
+		rc.PrivateCDNURL,
+		rc.MaxFileSize,
+		rc.AllowedMimeTypes,
+	)
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"time"
+
+	"github.com/redis/go-redis/v9"
 )
 
 // CacheService Redis缓存服务
@@ -56,6 +58,38 @@ func (c *CacheService) SetNX(ctx context.Context, key string, value interface{},
 	return SetNX(ctx, c.buildKey(key), value, expiration)
 }
 
+// Increment 原子递增计数，计数第一次创建时设置过期时间
+func (c *CacheService) Increment(ctx context.Context, key string, expiration time.Duration) (int64, error) {
+	fullKey := c.buildKey(key)
+	count, err := Increment(ctx, fullKey)
+	if err != nil {
+		return 0, err
+	}
+	if count == 1 {
+		if err := Expire(ctx, fullKey, expiration); err != nil {
+			return count, err
+		}
+	}
+	return count, nil
+}
+
+// Count 读取计数，键不存在时返回 0
+func (c *CacheService) Count(ctx context.Context, key string) (int64, error) {
+	if Client == nil {
+		return 0, fmt.Errorf("Redis client is not initialized")
+	}
+	count, err := Client.Get(ctx, c.buildKey(key)).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	return count, err
+}
+
+// TTL 获取缓存的剩余生存时间，键不存在或没有过期时间时返回非正数
+func (c *CacheService) TTL(ctx context.Context, key string) (time.Duration, error) {
+	return TTL(ctx, c.buildKey(key))
+}
+
 // Remember 记忆模式：如果缓存存在则返回，否则执行函数并缓存结果
 func (c *CacheService) Remember(ctx context.Context, key string, expiration time.Duration, fn func() (interface{}, error)) (interface{}, error) {
 	// 尝试从缓存获取
@@ -141,6 +175,16 @@ var (
 	TokenCache = NewCacheService("token")
 	// RateLimitCache 限流相关缓存
 	RateLimitCache = NewCacheService("ratelimit")
+	// NonceCache 请求签名随机数，防止重放
+	NonceCache = NewCacheService("nonce")
+	// FeatureCache 功能开关缓存
+	FeatureCache = NewCacheService("feature")
+	// LockoutCache 登录失败计数与锁定
+	LockoutCache = NewCacheService("lockout")
+	// AdminLockoutCache 管理员重新验证身份的失败计数与锁定，与用户分开计数
+	AdminLockoutCache = NewCacheService("admin_lockout")
+	// ChallengeCache 注册与登录挑战的请求计数和已使用的挑战
+	ChallengeCache = NewCacheService("challenge")
 	// TempCache 临时缓存
 	TempCache = NewCacheService("temp")
 )
\ No newline at end of file
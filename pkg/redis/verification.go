@@ -29,13 +29,17 @@ func (vc *VerificationCache) StoreVerificationCode(target, vType, code string, e
 	return vc.client.SetEx(ctx, key, code, expiry).Err()
 }
 
-// GetVerificationCode 从Redis获取验证码
+// GetVerificationCode 从Redis获取验证码，验证码不存在或已过期时返回空字符串
 func (vc *VerificationCache) GetVerificationCode(target, vType string) (string, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
 	key := fmt.Sprintf("vc:%s:%s", target, vType)
-	return vc.client.Get(ctx, key).Result()
+	code, err := vc.client.Get(ctx, key).Result()
+	if err == redis.Nil {
+		return "", nil
+	}
+	return code, err
 }
 
 // DeleteVerificationCode 删除验证码（验证成功后）
@@ -47,27 +51,43 @@ func (vc *VerificationCache) DeleteVerificationCode(target, vType string) error
 	return vc.client.Del(ctx, key).Err()
 }
 
-// CheckSendFrequency 检查发送频率限制
-func (vc *VerificationCache) CheckSendFrequency(target, vType string, cooldown time.Duration) (bool, error) {
+// ClaimSendSlot 使用 SET NX PX 原子地占用发送冷却期，返回 false 表示仍在冷却期内
+// 并发请求中只有一个能占用成功，避免同时通过冷却检查后重复发送
+func (vc *VerificationCache) ClaimSendSlot(target, vType string, cooldown time.Duration) (bool, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
 	key := fmt.Sprintf("vc_rate:%s:%s", target, vType)
-	exists, err := vc.client.Exists(ctx, key).Result()
-	if err != nil {
-		return false, err
-	}
-
-	return exists > 0, nil // true表示在冷却期内，不能发送
+	return vc.client.SetNX(ctx, key, "1", cooldown).Result()
 }
 
-// SetSendFrequency 设置发送频率限制
-func (vc *VerificationCache) SetSendFrequency(target, vType string, cooldown time.Duration) error {
+// ReleaseSendSlot 释放发送冷却期，发送失败时调用以允许立即重试
+func (vc *VerificationCache) ReleaseSendSlot(target, vType string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
 	key := fmt.Sprintf("vc_rate:%s:%s", target, vType)
-	return vc.client.SetEx(ctx, key, "1", cooldown).Err()
+	return vc.client.Del(ctx, key).Err()
+}
+
+// IncrementSendCount 增加目标在固定时间窗口内的发送次数，返回当前窗口内的累计次数
+// 与按类型的冷却时间不同，此计数不区分验证码类型，用于限制单个号码的总发送量
+func (vc *VerificationCache) IncrementSendCount(target string, window time.Duration) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	key := fmt.Sprintf("vc_sends:%s:%d", target, int64(window.Seconds()))
+
+	count, err := vc.client.Incr(ctx, key).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	if count == 1 {
+		vc.client.Expire(ctx, key, window)
+	}
+
+	return int(count), nil
 }
 
 // GetAttemptCount 获取验证失败次数
@@ -0,0 +1,119 @@
+// Package requestsign 合作方请求签名
+//
+// 签名覆盖时间戳、随机数、请求方法、路径（含查询字符串）与请求体的 SHA-256 哈希：
+//
+//	timestamp \n nonce \n METHOD \n /path?query \n hex(sha256(body))
+//
+// 使用 HMAC-SHA256 和API密钥的签名密钥计算，十六进制编码后放入 X-Signature 请求头。
+package requestsign
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 签名请求头
+const (
+	HeaderSignature = "X-Signature"
+	HeaderTimestamp = "X-Timestamp"
+	HeaderNonce     = "X-Nonce"
+)
+
+// StringToSign 构造待签名字符串
+func StringToSign(method, path string, body []byte, timestamp, nonce string) string {
+	bodyHash := sha256.Sum256(body)
+	return strings.Join([]string{
+		timestamp,
+		nonce,
+		strings.ToUpper(method),
+		path,
+		hex.EncodeToString(bodyHash[:]),
+	}, "\n")
+}
+
+// Sign 计算签名
+func Sign(secret, method, path string, body []byte, timestamp, nonce string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(StringToSign(method, path, body, timestamp, nonce)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify 以常量时间比较签名
+func Verify(secret, method, path string, body []byte, timestamp, nonce, signature string) bool {
+	expected := Sign(secret, method, path, body, timestamp, nonce)
+	return hmac.Equal([]byte(expected), []byte(strings.ToLower(signature)))
+}
+
+// RequestPath 参与签名的路径，包含查询字符串
+func RequestPath(req *http.Request) string {
+	return req.URL.RequestURI()
+}
+
+// SignRequest 为请求设置签名请求头，使用当前时间和随机数
+// 请求体会被读出并重新放回，调用后仍可正常发送
+func SignRequest(req *http.Request, secret string) error {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		if err != nil {
+			return err
+		}
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	nonce, err := NewNonce()
+	if err != nil {
+		return err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderNonce, nonce)
+	req.Header.Set(HeaderSignature, Sign(secret, req.Method, RequestPath(req), body, timestamp, nonce))
+	return nil
+}
+
+// NewNonce 生成随机数，每个请求必须不同
+func NewNonce() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// Transport 为每个请求附加API密钥和签名的 http.RoundTripper
+//
+//	client := &http.Client{Transport: &requestsign.Transport{APIKey: key, Secret: secret}}
+type Transport struct {
+	APIKey string
+	Secret string
+	Base   http.RoundTripper // 为空时使用 http.DefaultTransport
+}
+
+// RoundTrip 实现 http.RoundTripper，不修改调用方传入的请求
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	signed := req.Clone(req.Context())
+	if t.APIKey != "" {
+		signed.Header.Set("Authorization", "ApiKey "+t.APIKey)
+	}
+	if err := SignRequest(signed, t.Secret); err != nil {
+		return nil, err
+	}
+
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return base.RoundTrip(signed)
+}
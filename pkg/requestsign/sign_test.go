@@ -0,0 +1,67 @@
+package requestsign
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	body := []byte(`{"name":"card.png"}`)
+	signature := Sign("secret", "post", "/api/v1/images/?page=1", body, "1700000000", "abc")
+
+	tests := []struct {
+		name      string
+		secret    string
+		method    string
+		path      string
+		body      []byte
+		timestamp string
+		nonce     string
+		signature string
+		valid     bool
+	}{
+		{"签名一致", "secret", "POST", "/api/v1/images/?page=1", body, "1700000000", "abc", signature, true},
+		{"签名大小写不敏感", "secret", "POST", "/api/v1/images/?page=1", body, "1700000000", "abc", strings.ToUpper(signature), true},
+		{"密钥不同", "other", "POST", "/api/v1/images/?page=1", body, "1700000000", "abc", signature, false},
+		{"方法不同", "secret", "DELETE", "/api/v1/images/?page=1", body, "1700000000", "abc", signature, false},
+		{"查询字符串不同", "secret", "POST", "/api/v1/images/?page=2", body, "1700000000", "abc", signature, false},
+		{"请求体被修改", "secret", "POST", "/api/v1/images/?page=1", []byte(`{"name":"x.png"}`), "1700000000", "abc", signature, false},
+		{"时间戳不同", "secret", "POST", "/api/v1/images/?page=1", body, "1700000001", "abc", signature, false},
+		{"随机数不同", "secret", "POST", "/api/v1/images/?page=1", body, "1700000000", "abd", signature, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.valid, Verify(tt.secret, tt.method, tt.path, tt.body, tt.timestamp, tt.nonce, tt.signature))
+		})
+	}
+}
+
+func TestTransport(t *testing.T) {
+	var verified bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		assert.Equal(t, "ApiKey tk_key", r.Header.Get("Authorization"))
+		assert.Equal(t, `{"a":1}`, string(body))
+		verified = Verify("secret", r.Method, RequestPath(r), body,
+			r.Header.Get(HeaderTimestamp), r.Header.Get(HeaderNonce), r.Header.Get(HeaderSignature))
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: &Transport{APIKey: "tk_key", Secret: "secret"}}
+	req, err := http.NewRequest(http.MethodPost, server.URL+"/api/v1/images/?page=1", strings.NewReader(`{"a":1}`))
+	require.NoError(t, err)
+
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	assert.True(t, verified)
+	assert.Empty(t, req.Header.Get(HeaderSignature), "调用方的请求不应被修改")
+}
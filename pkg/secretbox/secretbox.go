@@ -0,0 +1,73 @@
+// Package secretbox 使用应用密钥加密需要静态保存在数据库中、之后还要读取原文的敏感字段
+//
+// 使用 AES-256-GCM，密钥为应用密钥的 SHA-256，密文格式为：
+//
+//	v1:base64(nonce || ciphertext)
+package secretbox
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+// sealedPrefix 密文的版本前缀，用于区分旧版本保存的明文
+const sealedPrefix = "v1:"
+
+// ErrMalformed 密文格式错误或无法用当前应用密钥解密
+var ErrMalformed = errors.New("secretbox: malformed or tampered ciphertext")
+
+// Box 加密与解密
+type Box struct {
+	aead cipher.AEAD
+}
+
+// New 由应用密钥创建 Box
+func New(appKey string) *Box {
+	key := sha256.Sum256([]byte(appKey))
+	block, _ := aes.NewCipher(key[:])
+	aead, _ := cipher.NewGCM(block)
+	return &Box{aead: aead}
+}
+
+// Seal 加密明文，空字符串原样返回
+func (b *Box) Seal(plain string) (string, error) {
+	if plain == "" {
+		return "", nil
+	}
+	nonce := make([]byte, b.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := b.aead.Seal(nonce, nonce, []byte(plain), nil)
+	return sealedPrefix + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// Open 解密 Seal 生成的密文，空字符串原样返回
+func (b *Box) Open(sealed string) (string, error) {
+	if sealed == "" {
+		return "", nil
+	}
+	if !IsSealed(sealed) {
+		return "", ErrMalformed
+	}
+	data, err := base64.RawStdEncoding.DecodeString(strings.TrimPrefix(sealed, sealedPrefix))
+	if err != nil || len(data) < b.aead.NonceSize() {
+		return "", ErrMalformed
+	}
+	nonce, ciphertext := data[:b.aead.NonceSize()], data[b.aead.NonceSize():]
+	plain, err := b.aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", ErrMalformed
+	}
+	return string(plain), nil
+}
+
+// IsSealed 是否为 Seal 生成的密文
+func IsSealed(value string) bool {
+	return strings.HasPrefix(value, sealedPrefix)
+}
@@ -0,0 +1,59 @@
+package secretbox
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSealAndOpen(t *testing.T) {
+	box := New("app-key")
+
+	sealed, err := box.Seal("signing-secret")
+	require.NoError(t, err)
+	assert.True(t, IsSealed(sealed))
+	assert.NotContains(t, sealed, "signing-secret")
+
+	// 每次加密使用新的随机数，相同明文的密文不同
+	again, err := box.Seal("signing-secret")
+	require.NoError(t, err)
+	assert.NotEqual(t, sealed, again)
+
+	plain, err := box.Open(sealed)
+	require.NoError(t, err)
+	assert.Equal(t, "signing-secret", plain)
+
+	empty, err := box.Seal("")
+	require.NoError(t, err)
+	assert.Empty(t, empty)
+	plain, err = box.Open("")
+	require.NoError(t, err)
+	assert.Empty(t, plain)
+}
+
+func TestOpen_Rejects(t *testing.T) {
+	box := New("app-key")
+	sealed, err := box.Seal("signing-secret")
+	require.NoError(t, err)
+	tampered := []byte(sealed)
+	tampered[len(tampered)-5] ^= 1
+
+	tests := []struct {
+		name   string
+		box    *Box
+		sealed string
+	}{
+		{"明文", box, "signing-secret"},
+		{"非base64", box, sealedPrefix + "!!!"},
+		{"过短", box, sealedPrefix + "AAAA"},
+		{"被篡改", box, string(tampered)},
+		{"应用密钥不同", New("other-key"), sealed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.box.Open(tt.sealed)
+			assert.ErrorIs(t, err, ErrMalformed)
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package sms
+
+import (
+	"strings"
+
+	"trusioo_api/config"
+)
+
+// NewConfigFromApp 从应用配置创建短信配置
+func NewConfigFromApp(appConfig *config.Config) *Config {
+	if appConfig == nil {
+		return DefaultConfig()
+	}
+
+	sc := appConfig.SMS
+	cfg := &Config{
+		Driver:             sc.Driver,
+		DefaultCountryCode: sc.DefaultCountryCode,
+		HourlyLimit:        sc.HourlyLimit,
+		DailyLimit:         sc.DailyLimit,
+	}
+	for _, code := range strings.Split(sc.AllowedCountries, ",") {
+		if code = strings.TrimPrefix(strings.TrimSpace(code), "+"); code != "" {
+			cfg.AllowedCountries = append(cfg.AllowedCountries, code)
+		}
+	}
+	cfg.applyDefaults()
+	return cfg
+}
+
+// DefaultConfig 返回开发环境默认配置（短信内容仅写入日志）
+func DefaultConfig() *Config {
+	cfg := &Config{Driver: DriverLog}
+	cfg.applyDefaults()
+	return cfg
+}
+
+func (c *Config) applyDefaults() {
+	if c.Driver == "" {
+		c.Driver = DriverLog
+	}
+	c.DefaultCountryCode = strings.TrimPrefix(strings.TrimSpace(c.DefaultCountryCode), "+")
+	if c.HourlyLimit <= 0 {
+		c.HourlyLimit = 5
+	}
+	if c.DailyLimit <= 0 {
+		c.DailyLimit = 10
+	}
+}
+
+// Allows 检查号码所属国家是否允许发送，用于防止短信轰炸高价地区号码
+func (c *Config) Allows(phone string) bool {
+	if len(c.AllowedCountries) == 0 {
+		return true
+	}
+	for _, code := range c.AllowedCountries {
+		if strings.HasPrefix(phone, "+"+code) {
+			return true
+		}
+	}
+	return false
+}
+
+// NewSender 根据配置创建对应驱动的发送器
+func NewSender(cfg *Config) (Sender, error) {
+	switch cfg.Driver {
+	case DriverLog:
+		return NewLogSender(), nil
+	case DriverMemory:
+		return NewMemorySender(), nil
+	default:
+		return nil, ErrUnknownDriver
+	}
+}
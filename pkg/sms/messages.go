@@ -0,0 +1,44 @@
+package sms
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 支持的语言
+const (
+	LocaleEN = "en"
+	LocaleZH = "zh"
+)
+
+// codeMessages 各语言的验证码短信内容：应用名、验证码、有效分钟数
+var codeMessages = map[string]string{
+	LocaleEN: "[%s] Your verification code is %s. It expires in %d minutes. Never share this code with anyone.",
+	LocaleZH: "【%s】您的验证码为 %s，%d 分钟内有效。请勿将验证码告知他人。",
+}
+
+// CodeMessage 生成验证码短信，语言不受支持时回退到 fallbackLocale，再回退到英文
+func CodeMessage(to, code string, expiresInMinutes int, locale, fallbackLocale string) *Message {
+	loc := normalizeLocale(locale)
+	if loc == "" {
+		loc = normalizeLocale(fallbackLocale)
+	}
+	if loc == "" {
+		loc = LocaleEN
+	}
+	return &Message{
+		To:   to,
+		Body: fmt.Sprintf(codeMessages[loc], "Trusioo", code, expiresInMinutes),
+	}
+}
+
+func normalizeLocale(locale string) string {
+	locale = strings.ToLower(strings.TrimSpace(locale))
+	if i := strings.IndexAny(locale, "-_"); i >= 0 {
+		locale = locale[:i]
+	}
+	if _, ok := codeMessages[locale]; ok {
+		return locale
+	}
+	return ""
+}
@@ -0,0 +1,58 @@
+package sms
+
+import "strings"
+
+// NormalizeE164 将用户输入的号码归一为 E.164 格式（+国家码+号码）
+// 支持 "+86 138-0013-8000"、"0086 13800138000" 等写法；未带国家码时使用 defaultCountryCode，
+// 并去掉本地长途前缀 0。无法确定国家码或长度不合法时返回 ErrInvalidPhone
+func NormalizeE164(raw, defaultCountryCode string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", ErrInvalidPhone
+	}
+
+	international := false
+	switch {
+	case strings.HasPrefix(raw, "+"):
+		international = true
+		raw = raw[1:]
+	case strings.HasPrefix(raw, "00"):
+		international = true
+		raw = raw[2:]
+	}
+
+	var digits strings.Builder
+	for _, r := range raw {
+		switch {
+		case r >= '0' && r <= '9':
+			digits.WriteRune(r)
+		case r == ' ' || r == '-' || r == '.' || r == '(' || r == ')':
+			// 常见分隔符
+		default:
+			return "", ErrInvalidPhone
+		}
+	}
+	number := digits.String()
+
+	if !international {
+		countryCode := strings.TrimPrefix(defaultCountryCode, "+")
+		if countryCode == "" {
+			return "", ErrInvalidPhone
+		}
+		number = countryCode + strings.TrimLeft(number, "0")
+	}
+
+	// E.164 最长15位，国家码不以0开头；最短按8位校验以排除明显错误的输入
+	if len(number) < 8 || len(number) > 15 || number[0] == '0' {
+		return "", ErrInvalidPhone
+	}
+	return "+" + number, nil
+}
+
+// MaskPhone 隐藏号码中间部分，用于日志和响应展示
+func MaskPhone(phone string) string {
+	if len(phone) <= 8 {
+		return phone
+	}
+	return phone[:4] + strings.Repeat("*", len(phone)-8) + phone[len(phone)-4:]
+}
@@ -0,0 +1,72 @@
+package sms
+
+import (
+	"context"
+	"sync"
+
+	"trusioo_api/pkg/logger"
+)
+
+// LogSender 仅将短信写入日志，用于开发环境或尚未接入服务商时
+type LogSender struct{}
+
+// NewLogSender 创建日志发送器
+func NewLogSender() *LogSender {
+	return &LogSender{}
+}
+
+// Send 记录短信内容
+func (l *LogSender) Send(ctx context.Context, msg *Message) error {
+	if msg.To == "" {
+		return ErrMissingRecipient
+	}
+	logger.WithFields(map[string]interface{}{
+		"to":   MaskPhone(msg.To),
+		"body": msg.Body,
+	}).Info("SMS message (log driver)")
+	return nil
+}
+
+// MemorySender 将短信保存在内存中，用于测试
+type MemorySender struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+// NewMemorySender 创建内存发送器
+func NewMemorySender() *MemorySender {
+	return &MemorySender{}
+}
+
+// Send 记录短信
+func (m *MemorySender) Send(ctx context.Context, msg *Message) error {
+	if msg.To == "" {
+		return ErrMissingRecipient
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = append(m.messages, *msg)
+	return nil
+}
+
+// Messages 返回已发送短信的副本
+func (m *MemorySender) Messages() []Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]Message, len(m.messages))
+	copy(out, m.messages)
+	return out
+}
+
+// Last 返回最后一条发送给指定号码的短信
+func (m *MemorySender) Last(to string) (*Message, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := len(m.messages) - 1; i >= 0; i-- {
+		if m.messages[i].To == to {
+			msg := m.messages[i]
+			return &msg, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,61 @@
+package sms
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeE164(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		country  string
+		expected string
+		err      error
+	}{
+		{"国际格式", "+86 138-0013-8000", "", "+8613800138000", nil},
+		{"00前缀", "0044 20 7946 0958", "86", "+442079460958", nil},
+		{"本地号码使用默认国家码", "138 0013 8000", "86", "+8613800138000", nil},
+		{"去掉本地长途前缀0", "(020) 7946-0958", "+44", "+442079460958", nil},
+		{"缺少国家码", "13800138000", "", "", ErrInvalidPhone},
+		{"包含字母", "+86 138abc", "", "", ErrInvalidPhone},
+		{"号码过短", "+86123", "", "", ErrInvalidPhone},
+		{"号码过长", "+1234567890123456", "", "", ErrInvalidPhone},
+		{"国家码以0开头", "+0123456789", "", "", ErrInvalidPhone},
+		{"空号码", "  ", "86", "", ErrInvalidPhone},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NormalizeE164(tt.input, tt.country)
+			assert.Equal(t, tt.err, err)
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+}
+
+func TestMaskPhone(t *testing.T) {
+	assert.Equal(t, "+861******8000", MaskPhone("+8613800138000"))
+	assert.Equal(t, "+123", MaskPhone("+123"))
+}
+
+func TestConfigAllows(t *testing.T) {
+	cfg := NewConfigFromApp(nil)
+	assert.True(t, cfg.Allows("+2341234567890"))
+
+	cfg.AllowedCountries = []string{"86", "1"}
+	assert.True(t, cfg.Allows("+8613800138000"))
+	assert.True(t, cfg.Allows("+14155552671"))
+	assert.False(t, cfg.Allows("+2341234567890"))
+}
+
+func TestCodeMessage(t *testing.T) {
+	msg := CodeMessage("+8613800138000", "123456", 10, "zh-CN", LocaleEN)
+	assert.Equal(t, "+8613800138000", msg.To)
+	assert.Contains(t, msg.Body, "123456")
+	assert.Contains(t, msg.Body, "10 分钟")
+
+	msg = CodeMessage("+14155552671", "654321", 5, "fr", "")
+	assert.Contains(t, msg.Body, "expires in 5 minutes")
+}
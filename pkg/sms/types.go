@@ -0,0 +1,39 @@
+package sms
+
+import (
+	"context"
+	"errors"
+)
+
+// Message 待发送的短信
+type Message struct {
+	To   string `json:"to"` // E.164 格式号码
+	Body string `json:"body"`
+}
+
+// Sender 短信发送接口，日志和内存实现均满足此接口，接入真实服务商时实现此接口即可
+type Sender interface {
+	Send(ctx context.Context, msg *Message) error
+}
+
+// Driver 短信发送驱动
+const (
+	DriverLog    = "log"
+	DriverMemory = "memory"
+)
+
+// Config 短信服务配置
+type Config struct {
+	Driver             string
+	DefaultCountryCode string   // 未带国家码的号码使用的默认国家码，如 86
+	AllowedCountries   []string // 允许发送的国家码，为空表示不限制
+	HourlyLimit        int      // 每个号码每小时最多发送条数
+	DailyLimit         int      // 每个号码每天最多发送条数
+}
+
+var (
+	ErrInvalidPhone      = errors.New("sms: invalid phone number")
+	ErrCountryNotAllowed = errors.New("sms: destination country is not allowed")
+	ErrMissingRecipient  = errors.New("sms: recipient is required")
+	ErrUnknownDriver     = errors.New("sms: unknown driver")
+)